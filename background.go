@@ -0,0 +1,100 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jsutil"
+	"github.com/syumai/workers/internal/runtimecontext"
+)
+
+// ErrTooManyBackgroundTasks is returned by Go when the request already started the maximum number of tasks.
+var ErrTooManyBackgroundTasks = errors.New("workers: too many background tasks for the request")
+
+// defaultMaxBackgroundTasks is the default maximum number of background tasks per request.
+const defaultMaxBackgroundTasks = 32
+
+var (
+	maxBackgroundTasksMu sync.RWMutex
+	maxBackgroundTasks   = defaultMaxBackgroundTasks
+)
+
+// SetMaxBackgroundTasks sets the maximum number of tasks started by Go per request. Defaults to 32.
+//   - if n <= 0, the number is not limited.
+func SetMaxBackgroundTasks(n int) {
+	maxBackgroundTasksMu.Lock()
+	defer maxBackgroundTasksMu.Unlock()
+	maxBackgroundTasks = n
+}
+
+type backgroundTasksKey struct{}
+
+// backgroundTasks holds background tasks started while handling the request.
+type backgroundTasks struct {
+	req *http.Request
+
+	mu    sync.Mutex
+	count int
+}
+
+// withBackgroundTasks returns a context which counts background tasks started for the request.
+func withBackgroundTasks(ctx context.Context, req *http.Request) context.Context {
+	return context.WithValue(ctx, backgroundTasksKey{}, &backgroundTasks{req: req})
+}
+
+// acquire reports whether a new task can be started, and counts it.
+func (t *backgroundTasks) acquire() bool {
+	maxBackgroundTasksMu.RLock()
+	limit := maxBackgroundTasks
+	maxBackgroundTasksMu.RUnlock()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if limit > 0 && t.count >= limit {
+		return false
+	}
+	t.count++
+	return true
+}
+
+// Go runs the task in a new goroutine as post-response work, extending the lifetime of the event by waitUntil.
+//   - https://developers.cloudflare.com/workers/runtime-apis/context/#waituntil
+//   - ctx given to the task is not canceled when the response is returned.
+//   - the teardown of the Worker waits for the task to finish.
+//   - errors returned by the task and panics in the task are reported to hooks registered by OnError.
+//   - if the request already started the maximum number of tasks, returns ErrTooManyBackgroundTasks without running the task.
+//   - This function panics when a runtime context is not found.
+func Go(ctx context.Context, task func(ctx context.Context) error) error {
+	var req *http.Request
+	if tasks, ok := ctx.Value(backgroundTasksKey{}).(*backgroundTasks); ok {
+		if !tasks.acquire() {
+			return ErrTooManyBackgroundTasks
+		}
+		req = tasks.req
+	}
+	exCtx := runtimecontext.MustExtract(ctx).Get("ctx")
+	taskCtx := context.WithoutCancel(ctx)
+	release := runtimecontext.Hold()
+	var cb js.Func
+	cb = js.FuncOf(func(_ js.Value, pArgs []js.Value) any {
+		defer cb.Release()
+		resolve := pArgs[0]
+		go func() {
+			defer release()
+			defer resolve.Invoke(js.Undefined())
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					ReportError(taskCtx, runtimecontext.NewPanicError(recovered), req)
+				}
+			}()
+			if err := task(taskCtx); err != nil {
+				ReportError(taskCtx, err, req)
+			}
+		}()
+		return js.Undefined()
+	})
+	exCtx.Call("waitUntil", jsutil.NewPromise(cb))
+	return nil
+}
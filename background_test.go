@@ -0,0 +1,151 @@
+//go:build js && wasm
+
+package workers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/runtimecontext"
+)
+
+// newTestEvent returns the context of an event whose requests start background tasks, and settles the event.
+func newTestEvent(req *http.Request) (context.Context, func()) {
+	exCtx := js.Global().Get("Object").New()
+	exCtx.Set("waitUntil", js.FuncOf(func(js.Value, []js.Value) any { return js.Undefined() }))
+	runtimeCtxObj := js.Global().Get("Object").New()
+	runtimeCtxObj.Set("ctx", exCtx)
+	ctx, settle := runtimecontext.NewEvent(js.Undefined(), runtimeCtxObj)
+	return withBackgroundTasks(ctx, req), settle
+}
+
+type reportedError struct {
+	err error
+	req *http.Request
+}
+
+// captureErrors registers a hook of OnError which sends reported errors to the returned channel.
+func captureErrors() <-chan reportedError {
+	reported := make(chan reportedError, 8)
+	OnError(func(ctx context.Context, err error, req *http.Request) {
+		select {
+		case reported <- reportedError{err: err, req: req}:
+		default:
+		}
+	})
+	return reported
+}
+
+func TestGo(t *testing.T) {
+	reported := captureErrors()
+	errFailed := errors.New("failed")
+	tests := map[string]struct {
+		task    func(ctx context.Context) error
+		wantErr func(err error) bool
+	}{
+		"error": {
+			task: func(ctx context.Context) error {
+				return errFailed
+			},
+			wantErr: func(err error) bool {
+				return errors.Is(err, errFailed)
+			},
+		},
+		"panic": {
+			task: func(ctx context.Context) error {
+				panic("boom")
+			},
+			wantErr: func(err error) bool {
+				var panicErr *PanicError
+				return errors.As(err, &panicErr) && panicErr.Value == "boom"
+			},
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/"+name, nil)
+			ctx, settle := newTestEvent(req)
+			if err := Go(ctx, tc.task); err != nil {
+				t.Fatal(err)
+			}
+			settle()
+			runtimecontext.Wait()
+			select {
+			case r := <-reported:
+				if !tc.wantErr(r.err) {
+					t.Errorf("want the error of the task reported, got %v", r.err)
+				}
+				if r.req != req {
+					t.Error("want the request reported with the error")
+				}
+			default:
+				t.Error("want the error reported")
+			}
+		})
+	}
+}
+
+func TestGo_Teardown(t *testing.T) {
+	ctx, settle := newTestEvent(httptest.NewRequest(http.MethodGet, "/", nil))
+	unblock := make(chan struct{})
+	err := Go(ctx, func(ctx context.Context) error {
+		<-unblock
+		return ctx.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	settle()
+
+	waited := make(chan struct{})
+	go func() {
+		runtimecontext.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+		t.Fatal("want the teardown to wait for the task")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(unblock)
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		t.Fatal("want the teardown to finish after the task")
+	}
+}
+
+func TestGo_TooManyTasks(t *testing.T) {
+	SetMaxBackgroundTasks(2)
+	t.Cleanup(func() { SetMaxBackgroundTasks(defaultMaxBackgroundTasks) })
+	ctx, settle := newTestEvent(httptest.NewRequest(http.MethodGet, "/", nil))
+	defer func() {
+		settle()
+		runtimecontext.Wait()
+	}()
+	task := func(ctx context.Context) error {
+		return nil
+	}
+	for i := 0; i < 2; i++ {
+		if err := Go(ctx, task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Go(ctx, task); !errors.Is(err, ErrTooManyBackgroundTasks) {
+		t.Errorf("want ErrTooManyBackgroundTasks, got %v", err)
+	}
+
+	// tasks are counted per request.
+	other, settleOther := newTestEvent(httptest.NewRequest(http.MethodGet, "/", nil))
+	if err := Go(other, task); err != nil {
+		t.Errorf("want a task of another request started, got %v", err)
+	}
+	settleOther()
+}
@@ -0,0 +1,197 @@
+// Package cachecontrol builds Cache-Control, CDN-Cache-Control and Vary headers.
+//   - directives are formatted in a fixed order, and durations are formatted in seconds.
+//   - https://developers.cloudflare.com/cache/concepts/cache-control/
+//   - https://developers.cloudflare.com/cache/concepts/cdn-cache-control/
+package cachecontrol
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Names of headers holding cache directives.
+const (
+	// CacheControl is respected by browsers and CDNs.
+	CacheControl = "Cache-Control"
+	// CDNCacheControl is respected by CDNs including Cloudflare, and takes precedence over Cache-Control.
+	CDNCacheControl = "CDN-Cache-Control"
+	// CloudflareCDNCacheControl is respected only by Cloudflare, and takes precedence over CDN-Cache-Control.
+	CloudflareCDNCacheControl = "Cloudflare-CDN-Cache-Control"
+	// SurrogateControl is respected by some CDNs other than Cloudflare.
+	SurrogateControl = "Surrogate-Control"
+)
+
+// Builder builds cache directives.
+// The zero value is usable, but New is preferred for chaining.
+type Builder struct {
+	public, private        bool
+	noCache, noStore       bool
+	mustRevalidate         bool
+	proxyRevalidate        bool
+	immutable, noTransform bool
+	maxAge, sMaxAge        *time.Duration
+	staleWhileRevalidate   *time.Duration
+	staleIfError           *time.Duration
+}
+
+// New returns a new Builder.
+func New() *Builder {
+	return &Builder{}
+}
+
+// Public sets "public" directive, and unsets "private".
+func (b *Builder) Public() *Builder {
+	b.public, b.private = true, false
+	return b
+}
+
+// Private sets "private" directive, and unsets "public".
+func (b *Builder) Private() *Builder {
+	b.private, b.public = true, false
+	return b
+}
+
+// NoCache sets "no-cache" directive. Caches must revalidate responses before using them.
+func (b *Builder) NoCache() *Builder {
+	b.noCache = true
+	return b
+}
+
+// NoStore sets "no-store" directive. Responses are never stored, and other directives are not written.
+func (b *Builder) NoStore() *Builder {
+	b.noStore = true
+	return b
+}
+
+// MustRevalidate sets "must-revalidate" directive.
+func (b *Builder) MustRevalidate() *Builder {
+	b.mustRevalidate = true
+	return b
+}
+
+// ProxyRevalidate sets "proxy-revalidate" directive.
+func (b *Builder) ProxyRevalidate() *Builder {
+	b.proxyRevalidate = true
+	return b
+}
+
+// Immutable sets "immutable" directive. It is used with a long MaxAge for files whose names contain hashes.
+func (b *Builder) Immutable() *Builder {
+	b.immutable = true
+	return b
+}
+
+// NoTransform sets "no-transform" directive.
+func (b *Builder) NoTransform() *Builder {
+	b.noTransform = true
+	return b
+}
+
+// MaxAge sets "max-age" directive.
+func (b *Builder) MaxAge(d time.Duration) *Builder {
+	b.maxAge = &d
+	return b
+}
+
+// SMaxAge sets "s-maxage" directive, which overrides "max-age" for shared caches.
+func (b *Builder) SMaxAge(d time.Duration) *Builder {
+	b.sMaxAge = &d
+	return b
+}
+
+// StaleWhileRevalidate sets "stale-while-revalidate" directive.
+func (b *Builder) StaleWhileRevalidate(d time.Duration) *Builder {
+	b.staleWhileRevalidate = &d
+	return b
+}
+
+// StaleIfError sets "stale-if-error" directive.
+func (b *Builder) StaleIfError(d time.Duration) *Builder {
+	b.staleIfError = &d
+	return b
+}
+
+// seconds formats the duration in seconds. Negative durations are formatted as 0.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(max(d, 0)/time.Second), 10)
+}
+
+// String returns the value of the header.
+func (b *Builder) String() string {
+	if b.noStore {
+		return "no-store"
+	}
+	var directives []string
+	add := func(ok bool, directive string) {
+		if ok {
+			directives = append(directives, directive)
+		}
+	}
+	addDuration := func(d *time.Duration, directive string) {
+		if d != nil {
+			directives = append(directives, directive+"="+seconds(*d))
+		}
+	}
+	add(b.public, "public")
+	add(b.private, "private")
+	add(b.noCache, "no-cache")
+	addDuration(b.maxAge, "max-age")
+	addDuration(b.sMaxAge, "s-maxage")
+	add(b.mustRevalidate, "must-revalidate")
+	add(b.proxyRevalidate, "proxy-revalidate")
+	add(b.immutable, "immutable")
+	add(b.noTransform, "no-transform")
+	addDuration(b.staleWhileRevalidate, "stale-while-revalidate")
+	addDuration(b.staleIfError, "stale-if-error")
+	return strings.Join(directives, ", ")
+}
+
+// Set sets Cache-Control header.
+func (b *Builder) Set(h http.Header) {
+	b.SetHeader(h, CacheControl)
+}
+
+// SetHeader sets the header of the name (e.g. CDNCacheControl).
+func (b *Builder) SetHeader(h http.Header, name string) {
+	if v := b.String(); v != "" {
+		h.Set(name, v)
+	}
+}
+
+// AddVary adds the header names to Vary header.
+//   - names are canonicalized, and names already contained are not added.
+//   - if Vary header is "*", it is not changed.
+func AddVary(h http.Header, names ...string) {
+	var values []string
+	seen := map[string]bool{}
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			if name == "*" {
+				return
+			}
+			if name == "" || seen[http.CanonicalHeaderKey(name)] {
+				continue
+			}
+			seen[http.CanonicalHeaderKey(name)] = true
+			values = append(values, name)
+		}
+	}
+	for _, name := range names {
+		if name == "*" {
+			h.Set("Vary", "*")
+			return
+		}
+		name = http.CanonicalHeaderKey(name)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		values = append(values, name)
+	}
+	if len(values) > 0 {
+		h.Set("Vary", strings.Join(values, ", "))
+	}
+}
@@ -0,0 +1,87 @@
+package cachecontrol
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBuilder_String(t *testing.T) {
+	tests := map[string]struct {
+		builder *Builder
+		want    string
+	}{
+		"empty": {
+			builder: New(),
+			want:    "",
+		},
+		"immutable asset": {
+			builder: New().Public().MaxAge(365 * 24 * time.Hour).Immutable(),
+			want:    "public, max-age=31536000, immutable",
+		},
+		"edge cache with stale": {
+			builder: New().Public().MaxAge(0).SMaxAge(time.Minute).StaleWhileRevalidate(30 * time.Second).StaleIfError(time.Hour),
+			want:    "public, max-age=0, s-maxage=60, stale-while-revalidate=30, stale-if-error=3600",
+		},
+		"private overrides public": {
+			builder: New().Public().Private().NoCache(),
+			want:    "private, no-cache",
+		},
+		"no-store ignores others": {
+			builder: New().Public().MaxAge(time.Hour).NoStore(),
+			want:    "no-store",
+		},
+		"negative and fractional durations": {
+			builder: New().MaxAge(-time.Second).SMaxAge(1500 * time.Millisecond),
+			want:    "max-age=0, s-maxage=1",
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if got := tc.builder.String(); got != tc.want {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestAddVary(t *testing.T) {
+	tests := map[string]struct {
+		vary  []string
+		names []string
+		want  string
+	}{
+		"new": {
+			names: []string{"accept-encoding", "Origin"},
+			want:  "Accept-Encoding, Origin",
+		},
+		"merged without duplicates": {
+			vary:  []string{"Accept-Encoding, origin"},
+			names: []string{"Origin", "Accept"},
+			want:  "Accept-Encoding, origin, Accept",
+		},
+		"wildcard": {
+			vary:  []string{"*"},
+			names: []string{"Origin"},
+			want:  "*",
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			h := http.Header{}
+			for _, v := range tc.vary {
+				h.Add("Vary", v)
+			}
+			AddVary(h, tc.names...)
+			if got := h.Get("Vary"); got != tc.want {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package workers
+
+import (
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/syumai/workers/internal/jshttp"
+)
+
+// TeeBody splits the body into two bodies which can be read independently, e.g. to hash a body while forwarding it.
+//   - bodies of incoming requests and responses of fetch are split by ReadableStream.tee(), so forwarding one of
+//     the bodies to fetch or as the response doesn't copy bytes through Go.
+//   - other bodies are split in Go. Bytes read by only one of the bodies are buffered in memory.
+//   - the original body must not be used after it is split. Closing both bodies closes the original body.
+func TeeBody(body io.ReadCloser) (io.ReadCloser, io.ReadCloser) {
+	if body == nil || body == http.NoBody {
+		return body, body
+	}
+	if a, b, ok := jshttp.TeeStreamBody(body); ok {
+		return a, b
+	}
+	src := &teeSource{src: body}
+	return &teeBranch{src: src, index: 0}, &teeBranch{src: src, index: 1}
+}
+
+// CloneRequest returns a deep copy of the request including its body.
+// The body of req is replaced with one of the split bodies, so both requests can be read independently.
+func CloneRequest(req *http.Request) *http.Request {
+	clone := req.Clone(req.Context())
+	req.Body, clone.Body = TeeBody(req.Body)
+	return clone
+}
+
+// CloneResponse returns a copy of the response including its body.
+// The body of res is replaced with one of the split bodies, so both responses can be read independently.
+func CloneResponse(res *http.Response) *http.Response {
+	clone := *res
+	clone.Header = res.Header.Clone()
+	clone.Trailer = res.Trailer.Clone()
+	res.Body, clone.Body = TeeBody(res.Body)
+	return &clone
+}
+
+// teeSource is the source of bodies split in Go.
+type teeSource struct {
+	mu  sync.Mutex
+	src io.ReadCloser
+	// buf holds bytes from base which are not read by one of the branches.
+	buf  []byte
+	base int64
+	// offsets are offsets of the branches from the start of src.
+	offsets [2]int64
+	closed  [2]bool
+	err     error
+}
+
+type teeBranch struct {
+	src   *teeSource
+	index int
+}
+
+func (b *teeBranch) Read(p []byte) (int, error) {
+	s := b.src
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed[b.index] {
+		return 0, io.ErrClosedPipe
+	}
+	end := s.base + int64(len(s.buf))
+	if s.offsets[b.index] == end {
+		if s.err != nil {
+			return 0, s.err
+		}
+		// read from the source directly into p, and keep the bytes for the other branch if it is open.
+		n, err := s.src.Read(p)
+		s.err = err
+		if !s.closed[1-b.index] {
+			s.buf = append(s.buf, p[:n]...)
+		} else {
+			s.base += int64(n)
+		}
+		s.offsets[b.index] += int64(n)
+		s.trim()
+		if n > 0 {
+			return n, nil
+		}
+		return 0, err
+	}
+	n := copy(p, s.buf[s.offsets[b.index]-s.base:])
+	s.offsets[b.index] += int64(n)
+	s.trim()
+	return n, nil
+}
+
+// trim drops bytes read by all open branches. s.mu must be held.
+func (s *teeSource) trim() {
+	min := s.base + int64(len(s.buf))
+	for i, off := range s.offsets {
+		if !s.closed[i] && off < min {
+			min = off
+		}
+	}
+	s.buf = s.buf[min-s.base:]
+	s.base = min
+	if len(s.buf) == 0 {
+		s.buf = nil
+	}
+}
+
+func (b *teeBranch) Close() error {
+	s := b.src
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed[b.index] {
+		return nil
+	}
+	s.closed[b.index] = true
+	s.trim()
+	if s.closed[1-b.index] {
+		return s.src.Close()
+	}
+	return nil
+}
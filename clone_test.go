@@ -0,0 +1,92 @@
+package workers
+
+import (
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestTeeBody(t *testing.T) {
+	src := strings.Repeat("0123456789", 1000)
+	tests := map[string]struct {
+		// readSizes are sizes of reads alternating between the bodies.
+		readSizes [2]int
+	}{
+		"same size": {
+			readSizes: [2]int{100, 100},
+		},
+		"different sizes": {
+			readSizes: [2]int{7, 1000},
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			body := &closeRecorder{Reader: iotest.HalfReader(strings.NewReader(src))}
+			a, b := TeeBody(body)
+			var got [2]bytes.Buffer
+			done := [2]bool{}
+			for !done[0] || !done[1] {
+				for i, r := range []io.Reader{a, b} {
+					if done[i] {
+						continue
+					}
+					p := make([]byte, tc.readSizes[i])
+					n, err := r.Read(p)
+					got[i].Write(p[:n])
+					if err == io.EOF {
+						done[i] = true
+					} else if err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			for i := range got {
+				if got[i].String() != src {
+					t.Errorf("body %d: want %d bytes, got %d bytes", i, len(src), got[i].Len())
+				}
+			}
+			a.Close()
+			if body.closed {
+				t.Error("want the body to be open until both bodies are closed")
+			}
+			b.Close()
+			if !body.closed {
+				t.Error("want the body to be closed")
+			}
+		})
+	}
+}
+
+func TestCloneRequest(t *testing.T) {
+	req := httptest.NewRequest("POST", "/", strings.NewReader("body"))
+	clone := CloneRequest(req)
+	clone.Header.Set("X-Test", "1")
+	if req.Header.Get("X-Test") != "" {
+		t.Error("want headers to be copied")
+	}
+	for _, r := range []io.Reader{clone.Body, req.Body} {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != "body" {
+			t.Errorf("want body, got %q", b)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Package ai runs models of Workers AI by the AI binding.
+//   - inputs are encoded by the codec of the jsoncodec package, and outputs are returned as JSON.
+//   - outputs of text generation models can be streamed token by token (StreamText, Tokens, AllTokens on Go 1.23+),
+//     or passed through to clients as Server-Sent Events (Serve).
+//
+// Example of an AI proxy streaming the output to the client:
+//
+//	model, err := ai.NewAI(req.Context(), "AI")
+//	stream, err := model.RunStream("@cf/meta/llama-3.1-8b-instruct", &ai.TextGenerationInput{
+//	  Messages: []ai.Message{{Role: "user", Content: prompt}},
+//	})
+//	ai.Serve(w, req, stream)
+package ai
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/syumai/workers/cloudflare/internal/cfruntimecontext"
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jshttp"
+	"github.com/syumai/workers/internal/jsutil"
+	"github.com/syumai/workers/jsoncodec"
+)
+
+// Binding is the interface implemented by AI.
+type Binding interface {
+	Run(model string, input any) ([]byte, error)
+	RunStream(model string, input any) (io.ReadCloser, error)
+}
+
+var _ Binding = (*AI)(nil)
+
+// AI represents interface of Cloudflare Workers AI binding.
+//   - https://developers.cloudflare.com/workers-ai/configuration/bindings/
+type AI struct {
+	instance js.Value
+}
+
+// NewAI returns AI for given variable name.
+//   - variable name must be defined in wrangler.toml as ai binding.
+//   - if the given variable name doesn't exist on runtime context, returns error.
+//   - This function panics when a runtime context is not found.
+func NewAI(ctx context.Context, varName string) (*AI, error) {
+	inst := cfruntimecontext.GetRuntimeContextEnv(ctx).Get(varName)
+	if inst.IsUndefined() {
+		return nil, fmt.Errorf("%s is undefined", varName)
+	}
+	return &AI{instance: inst}, nil
+}
+
+// toJSInput converts the input into a JavaScript object by the codec of the jsoncodec package.
+func toJSInput(input any) (js.Value, error) {
+	b, err := jsoncodec.Marshal(input)
+	if err != nil {
+		return js.Value{}, fmt.Errorf("ai: error encoding input: %w", err)
+	}
+	v := js.Global().Get("JSON").Call("parse", string(b))
+	if v.Type() != js.TypeObject {
+		return js.Value{}, fmt.Errorf("ai: input must be a JSON object, got %s", b)
+	}
+	return v, nil
+}
+
+// Run runs the model with the input, and returns the output as JSON.
+//   - input must be encoded as a JSON object (e.g. *TextGenerationInput, or a map).
+//   - outputs of models returning binary data (e.g. text-to-image models) are not supported.
+func (a *AI) Run(model string, input any) ([]byte, error) {
+	in, err := toJSInput(input)
+	if err != nil {
+		return nil, err
+	}
+	v, err := jsutil.AwaitPromise(a.instance.Call("run", model, in))
+	if err != nil {
+		return nil, fmt.Errorf("ai: error running %s: %w", model, err)
+	}
+	return []byte(js.Global().Get("JSON").Call("stringify", v).String()), nil
+}
+
+// RunStream runs the text generation model with the input, and returns the output streamed as Server-Sent Events.
+//   - "stream": true is set to the input.
+//   - the output can be decoded by Tokens, or passed through to the client by Serve.
+func (a *AI) RunStream(model string, input any) (io.ReadCloser, error) {
+	in, err := toJSInput(input)
+	if err != nil {
+		return nil, err
+	}
+	in.Set("stream", true)
+	v, err := jsutil.AwaitPromise(a.instance.Call("run", model, in))
+	if err != nil {
+		return nil, fmt.Errorf("ai: error running %s: %w", model, err)
+	}
+	return jshttp.ToBody(v), nil
+}
@@ -0,0 +1,47 @@
+package ai
+
+import (
+	"fmt"
+
+	"github.com/syumai/workers/jsoncodec"
+)
+
+// DefaultEmbeddingModel is the text embedding model used by default (768 dimensions).
+const DefaultEmbeddingModel = "@cf/baai/bge-base-en-v1.5"
+
+// maxEmbeddingBatch is the maximum number of texts embedded by a single run.
+const maxEmbeddingBatch = 100
+
+type embeddingInput struct {
+	Text []string `json:"text"`
+}
+
+type embeddingOutput struct {
+	Data [][]float32 `json:"data"`
+}
+
+// Embed returns embeddings of the texts by the text embedding model, in the order of the texts.
+//   - if model is empty, DefaultEmbeddingModel is used.
+//   - texts are split into batches of 100, and the model is run for each batch.
+func Embed(ai Binding, model string, texts []string) ([][]float32, error) {
+	if model == "" {
+		model = DefaultEmbeddingModel
+	}
+	embeddings := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += maxEmbeddingBatch {
+		batch := texts[start:min(start+maxEmbeddingBatch, len(texts))]
+		b, err := ai.Run(model, &embeddingInput{Text: batch})
+		if err != nil {
+			return nil, err
+		}
+		var out embeddingOutput
+		if err := jsoncodec.Unmarshal(b, &out); err != nil {
+			return nil, fmt.Errorf("ai: error decoding embeddings: %w", err)
+		}
+		if len(out.Data) != len(batch) {
+			return nil, fmt.Errorf("ai: want %d embeddings, got %d", len(batch), len(out.Data))
+		}
+		embeddings = append(embeddings, out.Data...)
+	}
+	return embeddings, nil
+}
@@ -0,0 +1,30 @@
+//go:build go1.23
+
+package ai
+
+import (
+	"errors"
+	"io"
+	"iter"
+)
+
+// AllTokens returns an iterator over tokens of the streamed output of a text generation model.
+//   - the stream is read as the iteration proceeds, without goroutines. It is closed when the iteration stops.
+//   - if reading or decoding the stream fails, the error is yielded and the iteration stops.
+func AllTokens(stream io.ReadCloser) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		defer stream.Close()
+		err := eachToken(stream, func(token string) error {
+			if !yield(token, nil) {
+				return errStopped
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errStopped) {
+			yield("", err)
+		}
+	}
+}
+
+// errStopped stops reading the stream when the iteration is stopped.
+var errStopped = errors.New("ai: iteration stopped")
@@ -0,0 +1,179 @@
+package ai
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/syumai/workers/jsoncodec"
+)
+
+// Message is a message of a chat with a text generation model.
+type Message struct {
+	// Role is "system", "user" or "assistant".
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// TextGenerationInput is the input of text generation models.
+//   - either Prompt or Messages must be set.
+//   - zero values of optional fields are omitted, so the defaults of the model are used.
+type TextGenerationInput struct {
+	Prompt      string    `json:"prompt,omitempty"`
+	Messages    []Message `json:"messages,omitempty"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+	Temperature float64   `json:"temperature,omitempty"`
+	TopP        float64   `json:"top_p,omitempty"`
+	Seed        int       `json:"seed,omitempty"`
+}
+
+// textGenerationOutput is the output of text generation models, and each event of their streamed outputs.
+type textGenerationOutput struct {
+	Response string `json:"response"`
+}
+
+// Generate runs the text generation model, and returns the whole response.
+func Generate(ai Binding, model string, input *TextGenerationInput) (string, error) {
+	b, err := ai.Run(model, input)
+	if err != nil {
+		return "", err
+	}
+	var out textGenerationOutput
+	if err := jsoncodec.Unmarshal(b, &out); err != nil {
+		return "", fmt.Errorf("ai: error decoding output: %w", err)
+	}
+	return out.Response, nil
+}
+
+// StreamText runs the text generation model, and sends tokens of the response to the returned channel as they are generated.
+//   - the channels behave as the ones of Tokens. An error of running the model is sent to the channel of errors.
+func StreamText(ctx context.Context, ai Binding, model string, input *TextGenerationInput) (<-chan string, <-chan error) {
+	stream, err := ai.RunStream(model, input)
+	if err != nil {
+		ch := make(chan string)
+		close(ch)
+		errc := make(chan error, 1)
+		errc <- err
+		close(errc)
+		return ch, errc
+	}
+	return Tokens(ctx, stream)
+}
+
+// Tokens decodes the streamed output of a text generation model into tokens sent to the returned channel.
+//   - the stream consists of Server-Sent Events whose data is {"response":"<token>"}, and ends with "data: [DONE]".
+//   - the channel of tokens is closed at the end of the stream, or on an error. Empty tokens are skipped.
+//   - the channel of errors receives exactly one value after the channel of tokens is closed: nil, or the error.
+//   - if ctx is done, reading stops and ctx.Err() is sent. Callers should cancel ctx when they stop receiving tokens.
+//   - stream is closed when reading stops.
+func Tokens(ctx context.Context, stream io.ReadCloser) (<-chan string, <-chan error) {
+	ch := make(chan string)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(errc)
+		defer stream.Close()
+		errc <- readTokens(ctx, stream, ch)
+	}()
+	return ch, errc
+}
+
+// errDone stops reading events at "data: [DONE]".
+var errDone = errors.New("ai: done")
+
+func readTokens(ctx context.Context, r io.Reader, ch chan<- string) error {
+	defer close(ch)
+	return eachToken(r, func(token string) error {
+		select {
+		case ch <- token:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// eachToken calls fn with each non-empty token of the streamed output in r until "data: [DONE]".
+func eachToken(r io.Reader, fn func(token string) error) error {
+	err := readEvents(r, func(data string) error {
+		if data == "[DONE]" {
+			return errDone
+		}
+		var out textGenerationOutput
+		if err := jsoncodec.Unmarshal([]byte(data), &out); err != nil {
+			return fmt.Errorf("ai: error decoding event: %w", err)
+		}
+		if out.Response == "" {
+			return nil
+		}
+		return fn(out.Response)
+	})
+	if errors.Is(err, errDone) {
+		return nil
+	}
+	return err
+}
+
+// readEvents reads Server-Sent Events in r, and calls fn with the data of each event.
+//   - data of multiple "data:" lines of an event are joined by newlines. Other fields and comments are ignored.
+//   - https://html.spec.whatwg.org/multipage/server-sent-events.html#event-stream-interpretation
+func readEvents(r io.Reader, fn func(data string) error) error {
+	br := bufio.NewReader(r)
+	var data []string
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		eof := err != nil
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			if len(data) > 0 {
+				if err := fn(strings.Join(data, "\n")); err != nil {
+					return err
+				}
+				data = data[:0]
+			}
+		} else if value, ok := strings.CutPrefix(line, "data:"); ok {
+			data = append(data, strings.TrimPrefix(value, " "))
+		}
+		if eof {
+			// an incomplete event at the end of the stream is discarded.
+			return nil
+		}
+	}
+}
+
+// Serve passes the streamed output of a model through to the client as Server-Sent Events.
+//   - Content-Type and Cache-Control headers of SSE are set, and the output is flushed as it arrives.
+//   - copying stops at the end of the stream, or when the context of req is done. stream is closed when copying stops.
+func Serve(w http.ResponseWriter, req *http.Request, stream io.ReadCloser) error {
+	defer stream.Close()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 4096)
+	for {
+		if err := req.Context().Err(); err != nil {
+			return err
+		}
+		n, err := stream.Read(buf)
+		if n > 0 {
+			if _, err := w.Write(buf[:n]); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package ai_test
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/syumai/workers/cloudflare/ai"
+	"github.com/syumai/workers/workerstest"
+)
+
+func TestTokens(t *testing.T) {
+	tests := map[string]struct {
+		stream  string
+		want    []string
+		wantErr bool
+	}{
+		"tokens": {
+			stream: "data: {\"response\":\"Hello\"}\n\ndata: {\"response\":\", world\"}\n\ndata: [DONE]\n\n",
+			want:   []string{"Hello", ", world"},
+		},
+		"CRLF and comments": {
+			stream: ": keepalive\r\n\r\ndata:{\"response\":\"a\"}\r\n\r\nevent: message\r\ndata: {\"response\":\"b\"}\r\n\r\n",
+			want:   []string{"a", "b"},
+		},
+		"empty tokens and usage are skipped": {
+			stream: "data: {\"response\":\"a\"}\n\ndata: {\"response\":\"\",\"usage\":{\"total_tokens\":3}}\n\ndata: [DONE]\n\n",
+			want:   []string{"a"},
+		},
+		"events after DONE are ignored": {
+			stream: "data: {\"response\":\"a\"}\n\ndata: [DONE]\n\ndata: {\"response\":\"b\"}\n\n",
+			want:   []string{"a"},
+		},
+		"incomplete event at the end": {
+			stream: "data: {\"response\":\"a\"}\n\ndata: {\"respon",
+			want:   []string{"a"},
+		},
+		"malformed": {
+			stream:  "data: {\"response\":\"a\"}\n\ndata: not json\n\n",
+			want:    []string{"a"},
+			wantErr: true,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			ch, errc := ai.Tokens(context.Background(), io.NopCloser(strings.NewReader(tc.stream)))
+			var got []string
+			for token := range ch {
+				got = append(got, token)
+			}
+			err := <-errc
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("want error %v, got %v", tc.wantErr, err)
+			}
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestStreamText(t *testing.T) {
+	var gotInput any
+	fake := &workerstest.AI{
+		RunStreamFunc: func(model string, input any) (io.ReadCloser, error) {
+			gotInput = input
+			return io.NopCloser(strings.NewReader("data: {\"response\":\"Hi\"}\n\ndata: [DONE]\n\n")), nil
+		},
+	}
+	input := &ai.TextGenerationInput{Prompt: "Say hi"}
+	ch, errc := ai.StreamText(context.Background(), fake, "@cf/meta/llama-3.1-8b-instruct", input)
+	var got []string
+	for token := range ch {
+		got = append(got, token)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual([]string{"Hi"}, got) {
+		t.Errorf("want tokens [Hi], got %q", got)
+	}
+	if gotInput != input {
+		t.Errorf("want input %v, got %v", input, gotInput)
+	}
+
+	ch, errc = ai.StreamText(context.Background(), &workerstest.AI{}, "model", input)
+	if _, ok := <-ch; ok {
+		t.Error("want no tokens on error")
+	}
+	if err := <-errc; err != workerstest.ErrNotImplemented {
+		t.Errorf("want ErrNotImplemented, got %v", err)
+	}
+}
+
+func TestServe(t *testing.T) {
+	stream := "data: {\"response\":\"Hello\"}\n\ndata: [DONE]\n\n"
+	rec := httptest.NewRecorder()
+	if err := ai.Serve(rec, httptest.NewRequest("POST", "/", nil), io.NopCloser(strings.NewReader(stream))); err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("want Content-Type text/event-stream, got %q", got)
+	}
+	if got := rec.Body.String(); got != stream {
+		t.Errorf("want body %q, got %q", stream, got)
+	}
+	if !rec.Flushed {
+		t.Error("want response to be flushed")
+	}
+}
@@ -0,0 +1,76 @@
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/syumai/workers/cloudflare/internal/cfruntimecontext"
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jsutil"
+)
+
+// AnalyticsEngineDatasetBinding is the interface implemented by AnalyticsEngineDataset.
+type AnalyticsEngineDatasetBinding interface {
+	WriteDataPoint(p *AnalyticsEngineDataPoint)
+}
+
+var _ AnalyticsEngineDatasetBinding = (*AnalyticsEngineDataset)(nil)
+
+// AnalyticsEngineDataset represents interface of Cloudflare Worker's Analytics Engine dataset binding.
+//   - https://developers.cloudflare.com/analytics/analytics-engine/get-started/
+type AnalyticsEngineDataset struct {
+	instance js.Value
+}
+
+// NewAnalyticsEngineDataset returns AnalyticsEngineDataset for given variable name.
+//   - variable name must be defined in wrangler.toml as analytics_engine_datasets' binding.
+//   - if the given variable name doesn't exist on runtime context, returns error.
+//   - This function panics when a runtime context is not found.
+func NewAnalyticsEngineDataset(ctx context.Context, varName string) (*AnalyticsEngineDataset, error) {
+	inst := cfruntimecontext.GetRuntimeContextEnv(ctx).Get(varName)
+	if inst.IsUndefined() {
+		return nil, fmt.Errorf("%s is undefined", varName)
+	}
+	return &AnalyticsEngineDataset{instance: inst}, nil
+}
+
+// AnalyticsEngineDataPoint represents a data point of Analytics Engine.
+//   - https://developers.cloudflare.com/analytics/analytics-engine/get-started/#4-write-data-from-your-worker
+type AnalyticsEngineDataPoint struct {
+	// Indexes is used as a sampling key. Only one index is currently supported.
+	Indexes []string
+	Blobs   []string
+	Doubles []float64
+}
+
+func (p *AnalyticsEngineDataPoint) toJS() js.Value {
+	obj := jsutil.NewObject()
+	if len(p.Indexes) > 0 {
+		indexes := make([]any, len(p.Indexes))
+		for i, v := range p.Indexes {
+			indexes[i] = v
+		}
+		obj.Set("indexes", indexes)
+	}
+	if len(p.Blobs) > 0 {
+		blobs := make([]any, len(p.Blobs))
+		for i, v := range p.Blobs {
+			blobs[i] = v
+		}
+		obj.Set("blobs", blobs)
+	}
+	if len(p.Doubles) > 0 {
+		doubles := make([]any, len(p.Doubles))
+		for i, v := range p.Doubles {
+			doubles[i] = v
+		}
+		obj.Set("doubles", doubles)
+	}
+	return obj
+}
+
+// WriteDataPoint writes the data point to the dataset.
+// Writing is non-blocking, and the data point is sent after the response is returned.
+func (d *AnalyticsEngineDataset) WriteDataPoint(p *AnalyticsEngineDataPoint) {
+	d.instance.Call("writeDataPoint", p.toJS())
+}
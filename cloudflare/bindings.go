@@ -0,0 +1,182 @@
+package cloudflare
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/syumai/workers/cloudflare/internal/cfruntimecontext"
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/runtimecontext"
+)
+
+// BindingType is the type of a binding, named after its section of wrangler.toml.
+type BindingType string
+
+// Types of bindings detected by ListBindings.
+const (
+	// BindingTypeVar is the type of vars and secrets of strings.
+	BindingTypeVar             BindingType = "var"
+	BindingTypeKVNamespace     BindingType = "kv_namespace"
+	BindingTypeR2Bucket        BindingType = "r2_bucket"
+	BindingTypeD1Database      BindingType = "d1_database"
+	BindingTypeDurableObject   BindingType = "durable_object_namespace"
+	BindingTypeQueue           BindingType = "queue"
+	BindingTypeService         BindingType = "service"
+	BindingTypeAI              BindingType = "ai"
+	BindingTypeVectorize       BindingType = "vectorize"
+	BindingTypeAnalyticsEngine BindingType = "analytics_engine"
+	BindingTypeRateLimiter     BindingType = "ratelimit"
+	BindingTypeImages          BindingType = "images"
+	BindingTypeHyperdrive      BindingType = "hyperdrive"
+	// BindingTypeUnknown is the type of other bindings, and vars of JSON values.
+	BindingTypeUnknown BindingType = "unknown"
+)
+
+// bindingMethods are methods identifying types of bindings. They are checked in order.
+var bindingMethods = []struct {
+	typ     BindingType
+	methods []string
+}{
+	{BindingTypeDurableObject, []string{"idFromName", "get"}},
+	{BindingTypeD1Database, []string{"prepare", "batch"}},
+	{BindingTypeR2Bucket, []string{"createMultipartUpload", "head"}},
+	{BindingTypeKVNamespace, []string{"getWithMetadata", "list"}},
+	{BindingTypeQueue, []string{"send", "sendBatch"}},
+	{BindingTypeAnalyticsEngine, []string{"writeDataPoint"}},
+	{BindingTypeRateLimiter, []string{"limit"}},
+	{BindingTypeVectorize, []string{"query", "upsert"}},
+	{BindingTypeAI, []string{"run"}},
+	{BindingTypeImages, []string{"info", "input"}},
+	{BindingTypeService, []string{"fetch"}},
+}
+
+// ErrEnvNotFound is returned when the env is not found in the context, and the entry point has not given it at startup.
+var ErrEnvNotFound = errors.New("cloudflare: env is not found")
+
+// Binding represents a binding of the worker.
+type Binding struct {
+	Name string
+	Type BindingType
+}
+
+// lookupEnv returns the env of the event in ctx, or the env given by the entry point at startup.
+//   - the entry point generated by workers-init sets `globalThis.env` before the instance is started,
+//     so the env can be used by OnReady hooks of the workers package.
+func lookupEnv(ctx context.Context) (js.Value, error) {
+	if ctx != nil {
+		if env, ok := cfruntimecontext.LookupRuntimeContextEnv(ctx); ok {
+			return env, nil
+		}
+	}
+	env := js.Global().Get("env")
+	if env.Type() != js.TypeObject {
+		return js.Value{}, ErrEnvNotFound
+	}
+	return env, nil
+}
+
+// bindingType detects the type of the binding by its methods.
+func bindingType(v js.Value) BindingType {
+	switch v.Type() {
+	case js.TypeString:
+		return BindingTypeVar
+	case js.TypeObject, js.TypeFunction:
+	default:
+		return BindingTypeUnknown
+	}
+	// RPC stubs of service bindings return a method for any property.
+	if v.Get("__workersBindingProbe").Type() == js.TypeFunction {
+		return BindingTypeService
+	}
+	for _, b := range bindingMethods {
+		if hasMethods(v, b.methods) {
+			return b.typ
+		}
+	}
+	if v.Get("connectionString").Type() == js.TypeString {
+		return BindingTypeHyperdrive
+	}
+	return BindingTypeUnknown
+}
+
+func hasMethods(v js.Value, methods []string) bool {
+	for _, m := range methods {
+		if v.Get(m).Type() != js.TypeFunction {
+			return false
+		}
+	}
+	return true
+}
+
+// ListBindings returns bindings of the worker sorted by names, with their types detected by their methods.
+//   - the env of the event in ctx is used. If ctx has no event (e.g. in OnReady hooks), the env given by the entry point
+//     at startup is used. If neither is found, returns ErrEnvNotFound.
+func ListBindings(ctx context.Context) ([]*Binding, error) {
+	env, err := lookupEnv(ctx)
+	if err != nil {
+		return nil, err
+	}
+	keys := js.Global().Get("Object").Call("keys", env)
+	bindings := make([]*Binding, keys.Length())
+	for i := range bindings {
+		name := keys.Index(i).String()
+		bindings[i] = &Binding{Name: name, Type: bindingType(env.Get(name))}
+	}
+	sort.Slice(bindings, func(i, j int) bool {
+		return bindings[i].Name < bindings[j].Name
+	})
+	return bindings, nil
+}
+
+// ValidateBindings returns error naming all bindings of the specs which are missing or of unexpected types.
+//   - a spec is the name of a binding, optionally followed by ":" and its type (e.g. "DB", "MY_KV:kv_namespace").
+//   - the env is looked up as ListBindings does.
+//
+// Example:
+//
+//	err := cloudflare.ValidateBindings(ctx, "MY_KV:kv_namespace", "MY_BUCKET:r2_bucket", "API_TOKEN")
+func ValidateBindings(ctx context.Context, specs ...string) error {
+	env, err := lookupEnv(ctx)
+	if err != nil {
+		return err
+	}
+	var problems []string
+	for _, spec := range specs {
+		name, want, _ := strings.Cut(spec, ":")
+		v := env.Get(name)
+		if v.IsUndefined() {
+			problems = append(problems, fmt.Sprintf("%s is undefined", name))
+			continue
+		}
+		if want == "" {
+			continue
+		}
+		if got := bindingType(v); got != BindingType(want) {
+			problems = append(problems, fmt.Sprintf("%s is %s binding, want %s", name, got, want))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid bindings: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// MustBindings registers the hook validating bindings by ValidateBindings before the instance is signaled ready (see workers.OnReady).
+// Missing or mistyped bindings fail the startup of the instance with an error naming all of them,
+// instead of failing the first request using them.
+//   - the hook fails if the entry point doesn't set `globalThis.env` (e.g. entry points not generated by workers-init).
+//
+// Example:
+//
+//	func main() {
+//	  cloudflare.MustBindings("MY_KV:kv_namespace", "MY_BUCKET:r2_bucket", "DB")
+//	  workers.Serve(handler)
+//	}
+func MustBindings(specs ...string) {
+	runtimecontext.OnReady(func() error {
+		return ValidateBindings(context.Background(), specs...)
+	})
+}
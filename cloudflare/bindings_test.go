@@ -0,0 +1,101 @@
+package cloudflare_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/internal/js"
+)
+
+// setEnv sets the env given by the entry point at startup.
+func setEnv(t *testing.T, env map[string]any) {
+	t.Helper()
+	js.Global().Set("env", js.ValueOf(env))
+	t.Cleanup(func() { js.Global().Delete("env") })
+}
+
+// methods returns an object having the methods.
+func methods(names ...string) map[string]any {
+	obj := make(map[string]any, len(names))
+	for _, name := range names {
+		obj[name] = js.FuncOf(func(js.Value, []js.Value) any { return nil })
+	}
+	return obj
+}
+
+func TestListBindings(t *testing.T) {
+	setEnv(t, map[string]any{
+		"API_TOKEN": "secret",
+		"CONFIG":    map[string]any{"debug": true},
+		"MY_KV":     methods("get", "getWithMetadata", "put", "list", "delete"),
+		"BUCKET":    methods("head", "get", "put", "createMultipartUpload"),
+		"DB":        methods("prepare", "batch", "exec"),
+		"COUNTER":   methods("idFromName", "newUniqueId", "get"),
+		"AI":        methods("run"),
+		"AUTH":      methods("fetch", "connect"),
+	})
+	got, err := cloudflare.ListBindings(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*cloudflare.Binding{
+		{Name: "AI", Type: cloudflare.BindingTypeAI},
+		{Name: "API_TOKEN", Type: cloudflare.BindingTypeVar},
+		{Name: "AUTH", Type: cloudflare.BindingTypeService},
+		{Name: "BUCKET", Type: cloudflare.BindingTypeR2Bucket},
+		{Name: "CONFIG", Type: cloudflare.BindingTypeUnknown},
+		{Name: "COUNTER", Type: cloudflare.BindingTypeDurableObject},
+		{Name: "DB", Type: cloudflare.BindingTypeD1Database},
+		{Name: "MY_KV", Type: cloudflare.BindingTypeKVNamespace},
+	}
+	if !reflect.DeepEqual(want, got) {
+		for _, b := range got {
+			t.Logf("%s: %s", b.Name, b.Type)
+		}
+		t.Errorf("unexpected bindings")
+	}
+}
+
+func TestValidateBindings(t *testing.T) {
+	setEnv(t, map[string]any{
+		"API_TOKEN": "secret",
+		"MY_KV":     methods("get", "getWithMetadata", "put", "list", "delete"),
+	})
+	tests := map[string]struct {
+		specs   []string
+		wantErr string
+	}{
+		"valid": {
+			specs: []string{"API_TOKEN", "MY_KV:kv_namespace", "API_TOKEN:var"},
+		},
+		"missing and mistyped": {
+			specs:   []string{"MY_KV:r2_bucket", "DB", "API_TOKEN"},
+			wantErr: "invalid bindings: MY_KV is kv_namespace binding, want r2_bucket; DB is undefined",
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			err := cloudflare.ValidateBindings(context.Background(), tc.specs...)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("want error %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateBindings_envNotFound(t *testing.T) {
+	if err := cloudflare.ValidateBindings(context.Background(), "DB"); !errors.Is(err, cloudflare.ErrEnvNotFound) {
+		t.Errorf("want ErrEnvNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jshttp"
+	"github.com/syumai/workers/internal/jsutil"
+	"github.com/syumai/workers/internal/runtimecontext"
+)
+
+// Store is the interface implemented by Cache.
+type Store interface {
+	Put(req *http.Request, res *http.Response) error
+	Match(req *http.Request, opts *MatchOptions) (*http.Response, error)
+	Delete(req *http.Request, opts *MatchOptions) (bool, error)
+}
+
+var _ Store = (*Cache)(nil)
+
+// Cache represents Cloudflare Worker's Cache API.
+//   - https://developers.cloudflare.com/workers/runtime-apis/cache/
+type Cache struct {
+	instance js.Value
+}
+
+// New returns the default cache (`caches.default`).
+func New() *Cache {
+	return &Cache{instance: jsutil.Global.Get("caches").Get("default")}
+}
+
+// Open opens the cache with the given name.
+//   - if an error happens, returns error.
+func Open(name string) (*Cache, error) {
+	p := jsutil.Global.Get("caches").Call("open", name)
+	v, err := jsutil.AwaitPromise(p)
+	if err != nil {
+		return nil, err
+	}
+	return &Cache{instance: v}, nil
+}
+
+// Put stores the response for the request into the cache.
+//   - The response body is consumed by this method.
+//   - https://developers.cloudflare.com/workers/runtime-apis/cache/#put
+//   - if an error happens, returns error.
+func (c *Cache) Put(req *http.Request, res *http.Response) error {
+	runtimecontext.SubrequestsFrom(req.Context()).Add()
+	jsReq, release := jshttp.ToJSRequest(req)
+	defer release()
+	p := c.instance.Call("put", jsReq, jshttp.ToJSResponse(res))
+	_, err := jsutil.AwaitPromise(p)
+	return err
+}
+
+// MatchOptions represents options of Match and Delete.
+//   - https://developers.cloudflare.com/workers/runtime-apis/cache/#match
+type MatchOptions struct {
+	// IgnoreMethod treats the request as a GET request regardless of its actual value.
+	IgnoreMethod bool
+}
+
+func (opts *MatchOptions) toJS() js.Value {
+	if opts == nil {
+		return js.Undefined()
+	}
+	obj := jsutil.NewObject()
+	obj.Set("ignoreMethod", opts.IgnoreMethod)
+	return obj
+}
+
+// ErrNotFound is returned by Match when the response is not cached.
+var ErrNotFound = errors.New("cache: response not found")
+
+// Match returns the cached response for the request.
+//   - https://developers.cloudflare.com/workers/runtime-apis/cache/#match
+//   - if the response is not cached, returns ErrNotFound.
+func (c *Cache) Match(req *http.Request, opts *MatchOptions) (*http.Response, error) {
+	runtimecontext.SubrequestsFrom(req.Context()).Add()
+	jsReq, release := jshttp.ToJSRequest(req)
+	defer release()
+	p := c.instance.Call("match", jsReq, opts.toJS())
+	v, err := jsutil.AwaitPromise(p)
+	if err != nil {
+		return nil, err
+	}
+	if v.IsUndefined() {
+		return nil, ErrNotFound
+	}
+	return jshttp.ToResponse(v)
+}
+
+// Delete deletes the cached response for the request.
+//   - https://developers.cloudflare.com/workers/runtime-apis/cache/#delete
+//   - returns true if the response was deleted.
+func (c *Cache) Delete(req *http.Request, opts *MatchOptions) (bool, error) {
+	runtimecontext.SubrequestsFrom(req.Context()).Add()
+	jsReq, release := jshttp.ToJSRequest(req)
+	defer release()
+	p := c.instance.Call("delete", jsReq, opts.toJS())
+	v, err := jsutil.AwaitPromise(p)
+	if err != nil {
+		return false, err
+	}
+	return v.Bool(), nil
+}
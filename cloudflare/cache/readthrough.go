@@ -0,0 +1,197 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/syumai/workers/cloudflare"
+)
+
+// readThroughURL is the base of URLs of cache keys of values in the Cache API. It is never fetched.
+const readThroughURL = "https://read-through-cache.invalid/"
+
+// kvMinTTL is the minimum of expirationTtl and cacheTtl of KV.
+const kvMinTTL = 60 * time.Second
+
+// FillFunc returns the value of the key on a cache miss (e.g. by fetching the origin).
+type FillFunc func(ctx context.Context) ([]byte, error)
+
+// ReadThrough is a read-through cache of values backed by KV.
+//   - concurrent fills of the same key within the isolate are coalesced into a single call of FillFunc.
+//   - filled values are stored in KV, and reads of KV are cached in the data center by cacheTtl.
+//   - optionally, the Cache API is layered in front of KV to avoid reads of KV.
+type ReadThrough struct {
+	kv     cloudflare.KVNamespaceBinding
+	edge   Store
+	prefix string
+
+	mu    sync.Mutex
+	calls map[string]*fillCall
+}
+
+type fillCall struct {
+	done  chan struct{}
+	value []byte
+	err   error
+}
+
+// ReadThroughOptions represents options of NewReadThrough.
+type ReadThroughOptions struct {
+	// Prefix is prepended to keys of KV and the Cache API.
+	Prefix string
+	// Edge is the cache layered in front of KV (e.g. cache.New()). If nil, the Cache API is not used.
+	Edge Store
+}
+
+// NewReadThrough returns ReadThrough storing values in the KV namespace.
+func NewReadThrough(kv cloudflare.KVNamespaceBinding, opts *ReadThroughOptions) *ReadThrough {
+	c := &ReadThrough{kv: kv}
+	if opts != nil {
+		c.edge = opts.Edge
+		c.prefix = opts.Prefix
+	}
+	return c
+}
+
+// GetOrFill returns the cached value of the key, or calls fill and caches its value for ttl.
+//   - the value is looked up in the Cache API (if Edge is given), then in KV.
+//   - on a miss, fill is called with ctx of the first caller. Other callers in the isolate wait for the result.
+//   - ttl shorter than 60 seconds is extended to 60 seconds in KV, since it is the minimum of KV.
+//   - if fill returns error, the error is returned to all waiting callers and nothing is cached.
+//   - if fill panics, waiting callers get an error, and the next call fills the key again.
+//   - errors of storing the filled value are ignored, since the value is returned anyway.
+func (c *ReadThrough) GetOrFill(ctx context.Context, key string, ttl time.Duration, fill FillFunc) ([]byte, error) {
+	key = c.prefix + key
+	if v, ok := c.matchEdge(ctx, key); ok {
+		return v, nil
+	}
+
+	c.mu.Lock()
+	if c.calls == nil {
+		c.calls = map[string]*fillCall{}
+	}
+	call, ok := c.calls[key]
+	if !ok {
+		call = &fillCall{done: make(chan struct{})}
+		c.calls[key] = call
+		c.mu.Unlock()
+		c.fill(ctx, key, ttl, call, fill)
+		return call.value, call.err
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Forget deletes the cached value of the key from KV and the Cache API.
+//   - the value may still be served by KV in other data centers until cacheTtl expires.
+func (c *ReadThrough) Forget(ctx context.Context, key string) error {
+	key = c.prefix + key
+	if err := c.kv.Delete(key); err != nil {
+		return err
+	}
+	if c.edge == nil {
+		return nil
+	}
+	req, err := c.request(ctx, key)
+	if err != nil {
+		return err
+	}
+	_, err = c.edge.Delete(req, nil)
+	return err
+}
+
+// fill loads the value for the call, and releases the waiting callers even if fill panics.
+//   - the panic is returned to the waiting callers as an error, and propagated to the caller of fill.
+func (c *ReadThrough) fill(ctx context.Context, key string, ttl time.Duration, call *fillCall, fill FillFunc) {
+	defer func() {
+		r := recover()
+		if r != nil {
+			call.err = fmt.Errorf("cache: fill of the key panicked: %v", r)
+		}
+		c.mu.Lock()
+		delete(c.calls, key)
+		close(call.done)
+		c.mu.Unlock()
+		if r != nil {
+			panic(r)
+		}
+	}()
+	call.value, call.err = c.load(ctx, key, ttl, fill)
+}
+
+// load reads the value from KV, or fills and stores it.
+func (c *ReadThrough) load(ctx context.Context, key string, ttl time.Duration, fill FillFunc) ([]byte, error) {
+	kvTTL := max(ttl, kvMinTTL)
+	v, err := c.kv.GetWithMetadata(key, &cloudflare.KVNamespaceGetOptions{CacheTTL: int(kvTTL / time.Second)})
+	if err != nil {
+		return nil, err
+	}
+	if v != nil {
+		c.putEdge(ctx, key, v.Value, ttl)
+		return v.Value, nil
+	}
+	value, err := fill(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_ = c.kv.PutReader(key, bytes.NewReader(value), &cloudflare.KVNamespacePutOptions{ExpirationTTL: int(kvTTL / time.Second)})
+	c.putEdge(ctx, key, value, ttl)
+	return value, nil
+}
+
+func (c *ReadThrough) request(ctx context.Context, key string) (*http.Request, error) {
+	return http.NewRequestWithContext(ctx, http.MethodGet, readThroughURL+url.PathEscape(key), nil)
+}
+
+func (c *ReadThrough) matchEdge(ctx context.Context, key string) ([]byte, bool) {
+	if c.edge == nil {
+		return nil, false
+	}
+	req, err := c.request(ctx, key)
+	if err != nil {
+		return nil, false
+	}
+	res, err := c.edge.Match(req, nil)
+	if err != nil {
+		return nil, false
+	}
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+func (c *ReadThrough) putEdge(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	if c.edge == nil || ttl < time.Second {
+		return
+	}
+	req, err := c.request(ctx, key)
+	if err != nil {
+		return
+	}
+	res := &http.Response{
+		StatusCode: http.StatusOK,
+		Header: http.Header{
+			"Content-Type":  {"application/octet-stream"},
+			"Cache-Control": {"public, max-age=" + strconv.Itoa(int(ttl/time.Second))},
+		},
+		Body:          io.NopCloser(bytes.NewReader(value)),
+		ContentLength: int64(len(value)),
+	}
+	_ = c.edge.Put(req, res)
+}
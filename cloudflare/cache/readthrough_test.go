@@ -0,0 +1,101 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/cloudflare/cache"
+	"github.com/syumai/workers/workerstest"
+)
+
+func TestReadThrough_GetOrFill(t *testing.T) {
+	ctx := context.Background()
+	kv := &workerstest.KVNamespace{}
+	c := cache.NewReadThrough(kv, &cache.ReadThroughOptions{Prefix: "origin:"})
+
+	var fills atomic.Int32
+	release := make(chan struct{})
+	fill := func(ctx context.Context) ([]byte, error) {
+		fills.Add(1)
+		<-release
+		return []byte("value"), nil
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := c.GetOrFill(ctx, "key", time.Minute, fill)
+			if err != nil || string(v) != "value" {
+				t.Errorf("want value, got %q, %v", v, err)
+			}
+		}()
+	}
+	close(release)
+	wg.Wait()
+	if n := fills.Load(); n != 1 {
+		t.Errorf("want 1 fill, got %d", n)
+	}
+	if v, _ := kv.GetWithMetadata("origin:key", nil); v == nil || string(v.Value) != "value" {
+		t.Errorf("want the value to be stored in KV, got %v", v)
+	}
+}
+
+func TestReadThrough_GetOrFillPanic(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewReadThrough(&workerstest.KVNamespace{}, nil)
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("want the panic to be propagated, got %v", r)
+			}
+		}()
+		c.GetOrFill(ctx, "key", time.Minute, func(ctx context.Context) ([]byte, error) {
+			panic("boom")
+		})
+	}()
+	// the key is not stuck by the panicked fill.
+	v, err := c.GetOrFill(ctx, "key", time.Minute, func(ctx context.Context) ([]byte, error) {
+		return []byte("value"), nil
+	})
+	if err != nil || string(v) != "value" {
+		t.Errorf("want value, got %q, %v", v, err)
+	}
+}
+
+func TestReadThrough_Edge(t *testing.T) {
+	ctx := context.Background()
+	kv := &workerstest.KVNamespace{}
+	c := cache.NewReadThrough(kv, &cache.ReadThroughOptions{Edge: &workerstest.Cache{}})
+
+	var fills int
+	fill := func(ctx context.Context) ([]byte, error) {
+		fills++
+		if fills == 1 {
+			return nil, errors.New("origin is down")
+		}
+		return []byte("value"), nil
+	}
+	if _, err := c.GetOrFill(ctx, "key", time.Minute, fill); err == nil {
+		t.Fatal("want the error of fill")
+	}
+	if _, err := c.GetOrFill(ctx, "key", time.Minute, fill); err != nil {
+		t.Fatal(err)
+	}
+	// the value is served by the Cache API without KV.
+	kv.GetWithMetadataFunc = func(key string, opts *cloudflare.KVNamespaceGetOptions) (*cloudflare.KVNamespaceValueWithMetadata, error) {
+		return nil, errors.New("unexpected read of KV")
+	}
+	v, err := c.GetOrFill(ctx, "key", time.Minute, fill)
+	if err != nil || string(v) != "value" {
+		t.Errorf("want value, got %q, %v", v, err)
+	}
+	if fills != 2 {
+		t.Errorf("want 2 fills, got %d", fills)
+	}
+}
@@ -0,0 +1,203 @@
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jshttp"
+	"github.com/syumai/workers/internal/jsutil"
+)
+
+// ContainerNamespaceBinding is the interface implemented by ContainerNamespace.
+type ContainerNamespaceBinding interface {
+	Get(name string) (*Container, error)
+	GetByID(id *DurableObjectId) (*Container, error)
+	GetRandom(instances int) (*Container, error)
+}
+
+var _ ContainerNamespaceBinding = (*ContainerNamespace)(nil)
+
+// ContainerNamespace represents the namespace of containers.
+//
+// Containers are backed by Durable Objects of a class extending `Container` of the
+// `@cloudflare/containers` package, so the binding is a Durable Object binding of the class.
+//
+// https://developers.cloudflare.com/containers/
+type ContainerNamespace struct {
+	ns *DurableObjectNamespace
+}
+
+// NewContainerNamespace returns the namespace for the `varName` binding.
+//
+// This binding must be defined in the `wrangler.toml` file as a Durable Object binding of
+// the container class. The method will return an `error` when there is no binding defined by `varName`.
+func NewContainerNamespace(ctx context.Context, varName string) (*ContainerNamespace, error) {
+	ns, err := NewDurableObjectNamespace(ctx, varName)
+	if err != nil {
+		return nil, err
+	}
+	return &ContainerNamespace{ns: ns}, nil
+}
+
+// Get returns the container instance for the given `name`.
+//
+// The same name always routes to the same instance.
+func (c *ContainerNamespace) Get(name string) (*Container, error) {
+	return c.GetByID(c.ns.IdFromName(name))
+}
+
+// GetByID returns the container instance for the given `id`.
+func (c *ContainerNamespace) GetByID(id *DurableObjectId) (*Container, error) {
+	stub, err := c.ns.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return &Container{stub: stub}, nil
+}
+
+// GetRandom returns one of `instances` container instances chosen at random.
+//
+// This is a simple load balancing across stateless instances, compatible with
+// `getRandom` of the `@cloudflare/containers` package.
+func (c *ContainerNamespace) GetRandom(instances int) (*Container, error) {
+	if instances <= 0 {
+		return nil, fmt.Errorf("instances must be positive, got %d", instances)
+	}
+	return c.Get(fmt.Sprintf("instance-%d", rand.Intn(instances)))
+}
+
+// Container represents a container instance.
+type Container struct {
+	stub *DurableObjectStub
+}
+
+// ContainerStartOptions represents options to start a container.
+type ContainerStartOptions struct {
+	// EnvVars are environment variables of the container.
+	EnvVars map[string]string
+	// Entrypoint overrides the entrypoint of the image.
+	Entrypoint []string
+	// EnableInternet allows the container to access the internet.
+	EnableInternet bool
+}
+
+func (opts *ContainerStartOptions) toJS() js.Value {
+	if opts == nil {
+		return js.Undefined()
+	}
+	obj := jsutil.NewObject()
+	if len(opts.EnvVars) > 0 {
+		envVars := jsutil.NewObject()
+		for k, v := range opts.EnvVars {
+			envVars.Set(k, v)
+		}
+		obj.Set("envVars", envVars)
+	}
+	if len(opts.Entrypoint) > 0 {
+		entrypoint := make([]any, len(opts.Entrypoint))
+		for i, s := range opts.Entrypoint {
+			entrypoint[i] = s
+		}
+		obj.Set("entrypoint", entrypoint)
+	}
+	obj.Set("enableInternet", opts.EnableInternet)
+	return obj
+}
+
+// ContainerStatus is the status of a container.
+type ContainerStatus string
+
+const (
+	ContainerStatusRunning         ContainerStatus = "running"
+	ContainerStatusHealthy         ContainerStatus = "healthy"
+	ContainerStatusStopping        ContainerStatus = "stopping"
+	ContainerStatusStopped         ContainerStatus = "stopped"
+	ContainerStatusStoppedWithCode ContainerStatus = "stopped_with_code"
+)
+
+// ContainerState represents the state of a container.
+type ContainerState struct {
+	Status ContainerStatus
+	// LastChange is the time the status changed last.
+	LastChange time.Time
+	// ExitCode is the exit code of the container if the status is ContainerStatusStoppedWithCode.
+	ExitCode int
+}
+
+// call calls the RPC method of the container class.
+//   - stubs of test doubles (NewDurableObjectStubFunc) only support Fetch, so it returns error.
+func (c *Container) call(method string, args ...any) (js.Value, error) {
+	if c.stub.fetch != nil {
+		return js.Value{}, fmt.Errorf("%s is not supported by the stub", method)
+	}
+	return jsutil.AwaitPromise(c.stub.val.Call(method, args...))
+}
+
+// Fetch sends the request to the default port of the container, starting the container if needed.
+func (c *Container) Fetch(req *http.Request) (*http.Response, error) {
+	return c.stub.Fetch(req)
+}
+
+// FetchPort sends the request to the given port of the container.
+func (c *Container) FetchPort(req *http.Request, port int) (*http.Response, error) {
+	jsReq, release := jshttp.ToJSRequest(req)
+	res, err := c.call("containerFetch", jsReq, port)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return jshttp.ToFetchResponse(res, release)
+}
+
+// Start starts the container without waiting for its ports to be ready.
+func (c *Container) Start(opts *ContainerStartOptions) error {
+	_, err := c.call("start", opts.toJS())
+	return err
+}
+
+// StartAndWaitForPorts starts the container and waits until the given ports accept connections.
+// If no ports are given, the ports declared by the container class are waited.
+func (c *Container) StartAndWaitForPorts(ports ...int) error {
+	args := []any{}
+	if len(ports) > 0 {
+		jsPorts := make([]any, len(ports))
+		for i, p := range ports {
+			jsPorts[i] = p
+		}
+		args = append(args, jsPorts)
+	}
+	_, err := c.call("startAndWaitForPorts", args...)
+	return err
+}
+
+// Stop sends SIGTERM to the container.
+func (c *Container) Stop() error {
+	_, err := c.call("stop")
+	return err
+}
+
+// Destroy kills the container immediately.
+func (c *Container) Destroy() error {
+	_, err := c.call("destroy")
+	return err
+}
+
+// State returns the current state of the container.
+func (c *Container) State() (*ContainerState, error) {
+	v, err := c.call("getState")
+	if err != nil {
+		return nil, err
+	}
+	state := &ContainerState{
+		Status:   ContainerStatus(v.Get("status").String()),
+		ExitCode: jsutil.MaybeInt(v.Get("exitCode")),
+	}
+	if lastChange := v.Get("lastChange"); !lastChange.IsUndefined() && !lastChange.IsNull() {
+		state.LastChange = time.UnixMilli(int64(lastChange.Float()))
+	}
+	return state, nil
+}
@@ -0,0 +1,207 @@
+//go:build js && wasm
+
+package cloudflare_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/runtimecontext"
+)
+
+// fakeContainers is a fake of the Durable Object namespace of a container class.
+// It records the name of the instance, the method and the JSON of the arguments of each call.
+type fakeContainers struct {
+	instance js.Value
+}
+
+func newFakeContainers() *fakeContainers {
+	return &fakeContainers{instance: js.Global().Get("Function").New(`
+const ns = {calls: [], state: {}};
+ns.idFromName = (name) => ({name});
+ns.get = (id) => {
+  const stub = {};
+  for (const method of ["start", "startAndWaitForPorts", "stop", "destroy", "getState"]) {
+    stub[method] = async (...args) => {
+      ns.calls.push([id.name, method, ...args.map((arg) => String(JSON.stringify(arg)))].join(" "));
+      return method === "getState" ? ns.state : undefined;
+    };
+  }
+  stub.containerFetch = async (req, port) => new Response([id.name, req.method, req.url, port].join(" "));
+  return stub;
+};
+return ns;`).Invoke()}
+}
+
+func (c *fakeContainers) calls() []string {
+	calls := c.instance.Get("calls")
+	got := make([]string, calls.Length())
+	for i := range got {
+		got[i] = calls.Index(i).String()
+	}
+	return got
+}
+
+func (c *fakeContainers) setState(state map[string]any) {
+	c.instance.Set("state", js.ValueOf(state))
+}
+
+func newContainerNamespace(t *testing.T, fake *fakeContainers) *cloudflare.ContainerNamespace {
+	t.Helper()
+	runtimeCtxObj := js.Global().Get("Object").New()
+	runtimeCtxObj.Set("env", js.ValueOf(map[string]any{"CONTAINER": fake.instance}))
+	ctx := runtimecontext.New(context.Background(), js.Undefined(), runtimeCtxObj)
+	ns, err := cloudflare.NewContainerNamespace(ctx, "CONTAINER")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ns
+}
+
+func TestContainer(t *testing.T) {
+	tests := map[string]struct {
+		call func(c *cloudflare.Container) error
+		want string
+	}{
+		"start": {
+			call: func(c *cloudflare.Container) error {
+				return c.Start(&cloudflare.ContainerStartOptions{
+					EnvVars:        map[string]string{"MODE": "test"},
+					Entrypoint:     []string{"/app", "serve"},
+					EnableInternet: true,
+				})
+			},
+			want: `a start {"envVars":{"MODE":"test"},"entrypoint":["/app","serve"],"enableInternet":true}`,
+		},
+		"start without options": {
+			call: func(c *cloudflare.Container) error {
+				return c.Start(nil)
+			},
+			want: `a start undefined`,
+		},
+		"start and wait for ports": {
+			call: func(c *cloudflare.Container) error {
+				return c.StartAndWaitForPorts(8080, 9090)
+			},
+			want: `a startAndWaitForPorts [8080,9090]`,
+		},
+		"start and wait for declared ports": {
+			call: func(c *cloudflare.Container) error {
+				return c.StartAndWaitForPorts()
+			},
+			want: `a startAndWaitForPorts`,
+		},
+		"stop": {
+			call: func(c *cloudflare.Container) error {
+				return c.Stop()
+			},
+			want: `a stop`,
+		},
+		"destroy": {
+			call: func(c *cloudflare.Container) error {
+				return c.Destroy()
+			},
+			want: `a destroy`,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			fake := newFakeContainers()
+			c, err := newContainerNamespace(t, fake).Get("a")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tc.call(c); err != nil {
+				t.Fatal(err)
+			}
+			if want := []string{tc.want}; !reflect.DeepEqual(fake.calls(), want) {
+				t.Errorf("want calls %q, got %q", want, fake.calls())
+			}
+		})
+	}
+}
+
+func TestContainer_State(t *testing.T) {
+	fake := newFakeContainers()
+	fake.setState(map[string]any{"status": "stopped_with_code", "lastChange": 1700000000000, "exitCode": 1})
+	c, err := newContainerNamespace(t, fake).Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &cloudflare.ContainerState{
+		Status:     cloudflare.ContainerStatusStoppedWithCode,
+		LastChange: time.UnixMilli(1700000000000),
+		ExitCode:   1,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+
+	fake.setState(map[string]any{"status": "running"})
+	got, err = c.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != cloudflare.ContainerStatusRunning || !got.LastChange.IsZero() || got.ExitCode != 0 {
+		t.Errorf("want the running state without the last change, got %+v", got)
+	}
+}
+
+func TestContainerNamespace_GetRandom(t *testing.T) {
+	fake := newFakeContainers()
+	ns := newContainerNamespace(t, fake)
+	for i := 0; i < 10; i++ {
+		c, err := ns.GetRandom(3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, call := range fake.calls() {
+		switch call {
+		case "instance-0 stop", "instance-1 stop", "instance-2 stop":
+		default:
+			t.Errorf("want one of 3 instances, got %q", call)
+		}
+	}
+	if _, err := ns.GetRandom(0); err == nil {
+		t.Error("want error for no instances, got nil")
+	}
+}
+
+func TestContainer_FetchPort(t *testing.T) {
+	c, err := newContainerNamespace(t, newFakeContainers()).Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodGet, "https://example.com/health", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.FetchPort(req, 8080)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a GET https://example.com/health 8080"; string(b) != want {
+		t.Errorf("want %q, got %q", want, b)
+	}
+}
@@ -0,0 +1,44 @@
+// Package coordination provides locks, counters, rate limiters and values backed by Durable Objects.
+//   - the primitives are served by the Coordinator class written in Go, which is registered by Register.
+//   - each primitive is a Durable Object named by its kind and name, so one namespace can hold all of them.
+package coordination
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/syumai/workers/cloudflare"
+)
+
+// call sends the request to the object of the primitive, and decodes the JSON response into res.
+func call(ns cloudflare.DurableObjectNamespaceBinding, objectName, path string, req, res any) error {
+	stub, err := ns.Get(ns.IdFromName(objectName))
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	httpReq, err := http.NewRequest(http.MethodPost, "https://coordinator"+path, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpRes, err := stub.Fetch(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpRes.Body.Close()
+	if httpRes.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(httpRes.Body, 1024))
+		return fmt.Errorf("coordination: %s failed with status %d: %s", path, httpRes.StatusCode, msg)
+	}
+	if err := json.NewDecoder(httpRes.Body).Decode(res); err != nil {
+		return fmt.Errorf("coordination: error decoding response of %s: %w", path, err)
+	}
+	return nil
+}
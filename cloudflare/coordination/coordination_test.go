@@ -0,0 +1,93 @@
+package coordination_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/syumai/workers/cloudflare/coordination"
+	"github.com/syumai/workers/workerstest"
+)
+
+func TestLock(t *testing.T) {
+	ns := &workerstest.DurableObjectNamespace{NewObject: workerstest.NewCoordinatorObject}
+	a := coordination.NewLock(ns, "job", nil)
+	b := coordination.NewLock(ns, "job", &coordination.LockOptions{PollInterval: time.Millisecond})
+	if ok, err := a.TryLock(); err != nil || !ok {
+		t.Fatalf("want a to acquire the lock, got %v, %v", ok, err)
+	}
+	if ok, err := b.TryLock(); err != nil || ok {
+		t.Fatalf("want b not to acquire the lock, got %v, %v", ok, err)
+	}
+	if err := b.Unlock(); !errors.Is(err, coordination.ErrLockNotHeld) {
+		t.Errorf("want ErrLockNotHeld, got %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := b.Lock(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want DeadlineExceeded, got %v", err)
+	}
+	if err := a.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// locks of other names are independent.
+	if ok, err := coordination.NewLock(ns, "other", nil).TryLock(); err != nil || !ok {
+		t.Errorf("want the other lock to be acquired, got %v, %v", ok, err)
+	}
+}
+
+func TestCounter(t *testing.T) {
+	ns := &workerstest.DurableObjectNamespace{NewObject: workerstest.NewCoordinatorObject}
+	c := coordination.NewCounter(ns, "views")
+	for _, delta := range []int64{1, 2, -1} {
+		if _, err := c.Add(delta); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := coordination.NewCounter(ns, "views").Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 2 {
+		t.Errorf("want 2, got %d", got)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	ns := &workerstest.DurableObjectNamespace{NewObject: workerstest.NewCoordinatorObject}
+	r := coordination.NewRateLimiter(ns, "api", 3, time.Minute)
+	want := []bool{true, true, true, false}
+	for i, w := range want {
+		res, err := r.Allow()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Allowed != w {
+			t.Errorf("request %d: want allowed %v, got %v", i, w, res.Allowed)
+		}
+	}
+}
+
+func TestValue(t *testing.T) {
+	ns := &workerstest.DurableObjectNamespace{NewObject: workerstest.NewCoordinatorObject}
+	v := coordination.NewValue(ns, "config")
+	if _, found, err := v.Get(); err != nil || found {
+		t.Fatalf("want no value, got %v, %v", found, err)
+	}
+	if err := v.Set([]byte("data"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if data, found, err := v.Get(); err != nil || !found || string(data) != "data" {
+		t.Fatalf("want data, got %q, %v, %v", data, found, err)
+	}
+	if err := v.Delete(); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, err := v.Get(); err != nil || found {
+		t.Errorf("want no value after delete, got %v, %v", found, err)
+	}
+}
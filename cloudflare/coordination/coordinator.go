@@ -0,0 +1,271 @@
+package coordination
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/cloudflare/durableobject"
+)
+
+// ClassName is the name of the Durable Object class registered by Register.
+const ClassName = "Coordinator"
+
+// Register registers the Coordinator class serving the primitives of this package by the durableobject package.
+// It must be called before workers.Serve or workers.Start.
+// The class must be declared in the JavaScript entry point as described in the durableobject package, and bound in wrangler.toml:
+//
+//	[durable_objects]
+//	bindings = [{name = "COORDINATOR", class_name = "Coordinator"}]
+//
+//	[[migrations]]
+//	tag = "v1"
+//	new_classes = ["Coordinator"]
+func Register() {
+	durableobject.Handle(ClassName, http.HandlerFunc(serveObject))
+	durableobject.HandleAlarm(ClassName, deleteExpiredValue)
+}
+
+// objectLocks serializes requests to each object, since the state is read and written by separate storage operations.
+var objectLocks = &keyedMutex{locks: map[string]*refMutex{}}
+
+// serveObject serves the request to the object with its storage.
+func serveObject(w http.ResponseWriter, req *http.Request) {
+	storage, err := cloudflare.NewDurableObjectStorage(req.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	unlock := objectLocks.lock(durableobject.ID(req.Context()))
+	defer unlock()
+	serveCoordinator(w, req, storage)
+}
+
+// deleteExpiredValue is the alarm handler, which deletes the expired value.
+func deleteExpiredValue(ctx context.Context) error {
+	storage, err := cloudflare.NewDurableObjectStorage(ctx)
+	if err != nil {
+		return err
+	}
+	unlock := objectLocks.lock(durableobject.ID(ctx))
+	defer unlock()
+	var v storedValue
+	found, err := storage.Get(valueKey, &v)
+	if err != nil || !found {
+		return err
+	}
+	if v.ExpiresAt != 0 && v.ExpiresAt <= time.Now().UnixMilli() {
+		_, err = storage.Delete(valueKey)
+	}
+	return err
+}
+
+// NewObjectHandler returns the handler of an object of the Coordinator class which holds its state in the storage.
+// It is intended for fakes of the namespace (see workerstest.NewCoordinatorObject). Values don't expire by alarms,
+// but expired values are never returned.
+func NewObjectHandler(storage cloudflare.DurableObjectStorageBinding) http.Handler {
+	var mu sync.Mutex
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		serveCoordinator(w, req, storage)
+	})
+}
+
+// Keys of the storage. They are the same as the former JavaScript implementation, so stored states are kept.
+const (
+	lockKey    = "lock"
+	counterKey = "counter"
+	windowKey  = "window"
+	valueKey   = "value"
+)
+
+type storedLock struct {
+	Owner     string `json:"owner"`
+	ExpiresAt int64  `json:"expiresAt"`
+}
+
+type storedWindow struct {
+	Count   int   `json:"count"`
+	ResetAt int64 `json:"resetAt"`
+}
+
+type storedValue struct {
+	Data      []byte `json:"data"`
+	ExpiresAt int64  `json:"expiresAt"`
+}
+
+// coordinatorRequest is the union of request bodies of the primitives.
+type coordinatorRequest struct {
+	Owner    string `json:"owner"`
+	TTLMs    int64  `json:"ttlMs"`
+	Delta    int64  `json:"delta"`
+	Limit    int    `json:"limit"`
+	PeriodMs int64  `json:"periodMs"`
+	Cost     int    `json:"cost"`
+	Data     []byte `json:"data"`
+}
+
+// serveCoordinator serves the request of the primitive on the storage. The caller must serialize calls for the object.
+func serveCoordinator(w http.ResponseWriter, req *http.Request, storage cloudflare.DurableObjectStorageBinding) {
+	var body coordinatorRequest
+	if req.Method == http.MethodPost {
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	handle, ok := coordinatorHandlers[req.URL.Path]
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	res, err := handle(storage, &body, time.Now().UnixMilli())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+var coordinatorHandlers = map[string]func(storage cloudflare.DurableObjectStorageBinding, body *coordinatorRequest, now int64) (any, error){
+	"/lock/acquire": func(storage cloudflare.DurableObjectStorageBinding, body *coordinatorRequest, now int64) (any, error) {
+		var lock storedLock
+		found, err := storage.Get(lockKey, &lock)
+		if err != nil {
+			return nil, err
+		}
+		if found && lock.Owner != body.Owner && lock.ExpiresAt > now {
+			return &lockAcquireResponse{Acquired: false, ExpiresAt: lock.ExpiresAt}, nil
+		}
+		lock = storedLock{Owner: body.Owner, ExpiresAt: now + body.TTLMs}
+		if err := storage.Put(lockKey, &lock); err != nil {
+			return nil, err
+		}
+		return &lockAcquireResponse{Acquired: true, ExpiresAt: lock.ExpiresAt}, nil
+	},
+	"/lock/release": func(storage cloudflare.DurableObjectStorageBinding, body *coordinatorRequest, now int64) (any, error) {
+		var lock storedLock
+		found, err := storage.Get(lockKey, &lock)
+		if err != nil {
+			return nil, err
+		}
+		if !found || lock.Owner != body.Owner || lock.ExpiresAt <= now {
+			return &lockReleaseResponse{Released: false}, nil
+		}
+		if _, err := storage.Delete(lockKey); err != nil {
+			return nil, err
+		}
+		return &lockReleaseResponse{Released: true}, nil
+	},
+	"/counter/add": func(storage cloudflare.DurableObjectStorageBinding, body *coordinatorRequest, now int64) (any, error) {
+		var value int64
+		if _, err := storage.Get(counterKey, &value); err != nil {
+			return nil, err
+		}
+		value += body.Delta
+		if err := storage.Put(counterKey, value); err != nil {
+			return nil, err
+		}
+		return &counterResponse{Value: value}, nil
+	},
+	"/counter/get": func(storage cloudflare.DurableObjectStorageBinding, body *coordinatorRequest, now int64) (any, error) {
+		var value int64
+		if _, err := storage.Get(counterKey, &value); err != nil {
+			return nil, err
+		}
+		return &counterResponse{Value: value}, nil
+	},
+	"/ratelimit": func(storage cloudflare.DurableObjectStorageBinding, body *coordinatorRequest, now int64) (any, error) {
+		var window storedWindow
+		found, err := storage.Get(windowKey, &window)
+		if err != nil {
+			return nil, err
+		}
+		if !found || window.ResetAt <= now {
+			window = storedWindow{ResetAt: now + body.PeriodMs}
+		}
+		allowed := window.Count+body.Cost <= body.Limit
+		if allowed {
+			window.Count += body.Cost
+			if err := storage.Put(windowKey, &window); err != nil {
+				return nil, err
+			}
+		}
+		return &rateLimitResponse{
+			Allowed:   allowed,
+			Remaining: max(body.Limit-window.Count, 0),
+			ResetAt:   window.ResetAt,
+		}, nil
+	},
+	"/value/get": func(storage cloudflare.DurableObjectStorageBinding, body *coordinatorRequest, now int64) (any, error) {
+		var value storedValue
+		found, err := storage.Get(valueKey, &value)
+		if err != nil {
+			return nil, err
+		}
+		if !found || (value.ExpiresAt != 0 && value.ExpiresAt <= now) {
+			return &valueGetResponse{Found: false}, nil
+		}
+		return &valueGetResponse{Found: true, Data: value.Data}, nil
+	},
+	"/value/put": func(storage cloudflare.DurableObjectStorageBinding, body *coordinatorRequest, now int64) (any, error) {
+		value := storedValue{Data: body.Data}
+		if body.TTLMs > 0 {
+			value.ExpiresAt = now + body.TTLMs
+		}
+		if err := storage.Put(valueKey, &value); err != nil {
+			return nil, err
+		}
+		if value.ExpiresAt != 0 {
+			// expired values are deleted by the alarm.
+			if err := storage.SetAlarm(time.UnixMilli(value.ExpiresAt)); err != nil {
+				return nil, err
+			}
+		}
+		return struct{}{}, nil
+	},
+	"/value/delete": func(storage cloudflare.DurableObjectStorageBinding, body *coordinatorRequest, now int64) (any, error) {
+		if _, err := storage.Delete(valueKey); err != nil {
+			return nil, err
+		}
+		return struct{}{}, nil
+	},
+}
+
+// keyedMutex holds a mutex per key, which is deleted when no one holds or waits for it.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*refMutex
+}
+
+type refMutex struct {
+	sync.Mutex
+	refs int
+}
+
+// lock locks the mutex of the key, and returns the function to unlock it.
+func (m *keyedMutex) lock(key string) (unlock func()) {
+	m.mu.Lock()
+	l, ok := m.locks[key]
+	if !ok {
+		l = &refMutex{}
+		m.locks[key] = l
+	}
+	l.refs++
+	m.mu.Unlock()
+	l.Lock()
+	return func() {
+		l.Unlock()
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		l.refs--
+		if l.refs == 0 {
+			delete(m.locks, key)
+		}
+	}
+}
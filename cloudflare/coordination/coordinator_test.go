@@ -0,0 +1,34 @@
+package coordination
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestKeyedMutex(t *testing.T) {
+	m := &keyedMutex{locks: map[string]*refMutex{}}
+	var (
+		wg      sync.WaitGroup
+		a, b    int
+		counter = map[string]*int{"a": &a, "b": &b}
+	)
+	for i := 0; i < 100; i++ {
+		key := []string{"a", "b"}[i%2]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := m.lock(key)
+			defer unlock()
+			// read-modify-write guarded only by the mutex of the key.
+			v := *counter[key]
+			*counter[key] = v + 1
+		}()
+	}
+	wg.Wait()
+	if a != 50 || b != 50 {
+		t.Errorf("want 50 for each key, got %d and %d", a, b)
+	}
+	if len(m.locks) != 0 {
+		t.Errorf("want mutexes deleted after unlocked, got %d", len(m.locks))
+	}
+}
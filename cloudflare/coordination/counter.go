@@ -0,0 +1,42 @@
+package coordination
+
+import (
+	"github.com/syumai/workers/cloudflare"
+)
+
+// Counter is a distributed counter, which is consistent across all workers.
+type Counter struct {
+	ns   cloudflare.DurableObjectNamespaceBinding
+	name string
+}
+
+// NewCounter returns Counter of the name in the namespace bound to the Coordinator class.
+func NewCounter(ns cloudflare.DurableObjectNamespaceBinding, name string) *Counter {
+	return &Counter{ns: ns, name: "counter:" + name}
+}
+
+type counterAddRequest struct {
+	Delta int64 `json:"delta"`
+}
+
+type counterResponse struct {
+	Value int64 `json:"value"`
+}
+
+// Add adds delta to the counter, and returns the new value.
+func (c *Counter) Add(delta int64) (int64, error) {
+	var res counterResponse
+	if err := call(c.ns, c.name, "/counter/add", &counterAddRequest{Delta: delta}, &res); err != nil {
+		return 0, err
+	}
+	return res.Value, nil
+}
+
+// Get returns the current value of the counter. The initial value is 0.
+func (c *Counter) Get() (int64, error) {
+	var res counterResponse
+	if err := call(c.ns, c.name, "/counter/get", struct{}{}, &res); err != nil {
+		return 0, err
+	}
+	return res.Value, nil
+}
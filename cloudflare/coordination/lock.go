@@ -0,0 +1,122 @@
+package coordination
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/syumai/workers/cloudflare"
+)
+
+// ErrLockNotHeld is returned by Unlock when the lock is not held by the Lock, including when it expired.
+var ErrLockNotHeld = errors.New("coordination: lock is not held")
+
+const (
+	defaultLockTTL          = 30 * time.Second
+	defaultLockPollInterval = 100 * time.Millisecond
+)
+
+// LockOptions represents options of NewLock.
+type LockOptions struct {
+	// TTL is the duration after which the lock is released automatically. Defaults to 30 seconds.
+	// It prevents the lock from being held forever when the holder fails to unlock.
+	TTL time.Duration
+	// PollInterval is the interval of retries of Lock. Defaults to 100 milliseconds.
+	PollInterval time.Duration
+}
+
+// Lock is a distributed mutual exclusion lock.
+// Each Lock has its own owner token, so a Lock must not be shared by holders which exclude each other.
+type Lock struct {
+	ns           cloudflare.DurableObjectNamespaceBinding
+	name         string
+	owner        string
+	ttl          time.Duration
+	pollInterval time.Duration
+}
+
+// NewLock returns Lock of the name in the namespace bound to the Coordinator class.
+func NewLock(ns cloudflare.DurableObjectNamespaceBinding, name string, opts *LockOptions) *Lock {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	l := &Lock{
+		ns:           ns,
+		name:         "lock:" + name,
+		owner:        hex.EncodeToString(b[:]),
+		ttl:          defaultLockTTL,
+		pollInterval: defaultLockPollInterval,
+	}
+	if opts != nil {
+		if opts.TTL > 0 {
+			l.ttl = opts.TTL
+		}
+		if opts.PollInterval > 0 {
+			l.pollInterval = opts.PollInterval
+		}
+	}
+	return l
+}
+
+type lockAcquireRequest struct {
+	Owner string `json:"owner"`
+	TTLMs int64  `json:"ttlMs"`
+}
+
+type lockAcquireResponse struct {
+	Acquired  bool  `json:"acquired"`
+	ExpiresAt int64 `json:"expiresAt"`
+}
+
+// TryLock tries to acquire the lock without waiting, and reports whether it succeeded.
+//   - if the lock is already held by the Lock, it extends the TTL and succeeds.
+func (l *Lock) TryLock() (bool, error) {
+	var res lockAcquireResponse
+	err := call(l.ns, l.name, "/lock/acquire", &lockAcquireRequest{Owner: l.owner, TTLMs: l.ttl.Milliseconds()}, &res)
+	if err != nil {
+		return false, err
+	}
+	return res.Acquired, nil
+}
+
+// Lock acquires the lock, waiting until it is released or ctx is done.
+func (l *Lock) Lock(ctx context.Context) error {
+	for {
+		ok, err := l.TryLock()
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		t := time.NewTimer(l.pollInterval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+type lockReleaseRequest struct {
+	Owner string `json:"owner"`
+}
+
+type lockReleaseResponse struct {
+	Released bool `json:"released"`
+}
+
+// Unlock releases the lock.
+//   - if the lock is not held by the Lock, returns ErrLockNotHeld.
+func (l *Lock) Unlock() error {
+	var res lockReleaseResponse
+	if err := call(l.ns, l.name, "/lock/release", &lockReleaseRequest{Owner: l.owner}, &res); err != nil {
+		return err
+	}
+	if !res.Released {
+		return ErrLockNotHeld
+	}
+	return nil
+}
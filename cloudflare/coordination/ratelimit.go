@@ -0,0 +1,67 @@
+package coordination
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/syumai/workers/cloudflare"
+)
+
+// RateLimiter is a distributed fixed window rate limiter.
+// Unlike the rate limiting binding (cloudflare.RateLimiter), the limit is exact across all locations,
+// in exchange for a round trip to the Durable Object per call.
+type RateLimiter struct {
+	ns     cloudflare.DurableObjectNamespaceBinding
+	name   string
+	limit  int
+	period time.Duration
+}
+
+// NewRateLimiter returns RateLimiter of the name in the namespace bound to the Coordinator class,
+// which allows limit requests per period.
+func NewRateLimiter(ns cloudflare.DurableObjectNamespaceBinding, name string, limit int, period time.Duration) *RateLimiter {
+	return &RateLimiter{ns: ns, name: "ratelimit:" + name, limit: limit, period: period}
+}
+
+// RateLimitResult represents the result of Allow.
+type RateLimitResult struct {
+	Allowed bool
+	// Remaining is the number of requests allowed in the current window.
+	Remaining int
+	// ResetAt is the time the current window ends.
+	ResetAt time.Time
+}
+
+type rateLimitRequest struct {
+	Limit    int   `json:"limit"`
+	PeriodMs int64 `json:"periodMs"`
+	Cost     int   `json:"cost"`
+}
+
+type rateLimitResponse struct {
+	Allowed   bool  `json:"allowed"`
+	Remaining int   `json:"remaining"`
+	ResetAt   int64 `json:"resetAt"`
+}
+
+// Allow reports whether a request is allowed, and counts it if allowed.
+func (r *RateLimiter) Allow() (*RateLimitResult, error) {
+	return r.AllowN(1)
+}
+
+// AllowN reports whether n requests are allowed at once, and counts them if allowed.
+func (r *RateLimiter) AllowN(n int) (*RateLimitResult, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("coordination: n must be positive, got %d", n)
+	}
+	var res rateLimitResponse
+	req := &rateLimitRequest{Limit: r.limit, PeriodMs: r.period.Milliseconds(), Cost: n}
+	if err := call(r.ns, r.name, "/ratelimit", req, &res); err != nil {
+		return nil, err
+	}
+	return &RateLimitResult{
+		Allowed:   res.Allowed,
+		Remaining: res.Remaining,
+		ResetAt:   time.UnixMilli(res.ResetAt),
+	}, nil
+}
@@ -0,0 +1,47 @@
+package coordination
+
+import (
+	"time"
+
+	"github.com/syumai/workers/cloudflare"
+)
+
+// Value is a value with expiration, which is strongly consistent unlike KV.
+type Value struct {
+	ns   cloudflare.DurableObjectNamespaceBinding
+	name string
+}
+
+// NewValue returns Value of the name in the namespace bound to the Coordinator class.
+func NewValue(ns cloudflare.DurableObjectNamespaceBinding, name string) *Value {
+	return &Value{ns: ns, name: "value:" + name}
+}
+
+type valuePutRequest struct {
+	Data  []byte `json:"data"`
+	TTLMs int64  `json:"ttlMs"`
+}
+
+type valueGetResponse struct {
+	Found bool   `json:"found"`
+	Data  []byte `json:"data"`
+}
+
+// Get returns the value. found is false if the value is not set or expired.
+func (v *Value) Get() (data []byte, found bool, err error) {
+	var res valueGetResponse
+	if err := call(v.ns, v.name, "/value/get", struct{}{}, &res); err != nil {
+		return nil, false, err
+	}
+	return res.Data, res.Found, nil
+}
+
+// Set sets the value. The value expires after ttl, or never expires if ttl is 0.
+func (v *Value) Set(data []byte, ttl time.Duration) error {
+	return call(v.ns, v.name, "/value/put", &valuePutRequest{Data: data, TTLMs: ttl.Milliseconds()}, &struct{}{})
+}
+
+// Delete deletes the value.
+func (v *Value) Delete() error {
+	return call(v.ns, v.name, "/value/delete", struct{}{}, &struct{}{})
+}
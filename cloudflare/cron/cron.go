@@ -0,0 +1,122 @@
+// Package cron handles scheduled events of Cron Triggers.
+//   - https://developers.cloudflare.com/workers/configuration/cron-triggers/
+//   - Handlers are registered per cron expression by OnCron, and dispatched by the cron of the event.
+package cron
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/runtimecontext"
+)
+
+// Event represents a scheduled event.
+//   - https://developers.cloudflare.com/workers/runtime-apis/handlers/scheduled/
+type Event struct {
+	// Cron is the cron expression of the trigger as written in wrangler.toml (e.g. "0 3 * * *").
+	Cron string
+	// ScheduledTime is the time the event was scheduled to run, in UTC.
+	ScheduledTime time.Time
+}
+
+// Handler handles a scheduled event.
+//   - if the handler returns error, the invocation is reported as failed.
+//   - bindings can be obtained from ctx as well as handlers of requests (e.g. cloudflare.NewKVNamespace(ctx, ...)).
+type Handler func(ctx context.Context, event *Event) error
+
+// Mux dispatches scheduled events to handlers registered for their cron expressions.
+// The zero value is not usable. Use NewMux.
+type Mux struct {
+	mu       sync.RWMutex
+	handlers map[string]Handler
+	fallback Handler
+}
+
+// NewMux returns a new Mux.
+func NewMux() *Mux {
+	return &Mux{handlers: map[string]Handler{}}
+}
+
+// DefaultMux is the Mux used by OnCron and Schedule(nil).
+var DefaultMux = NewMux()
+
+// normalize normalizes whitespaces of the cron expression, so "0  3 * * *" matches "0 3 * * *".
+func normalize(expr string) string {
+	return strings.Join(strings.Fields(expr), " ")
+}
+
+// OnCron registers the handler for the cron expression.
+//   - the expression must be the same as the trigger in wrangler.toml, except whitespaces.
+//   - if a handler is already registered for the expression, it panics.
+func (m *Mux) OnCron(expr string, h Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := normalize(expr)
+	if _, ok := m.handlers[key]; ok {
+		panic(fmt.Sprintf("cron: multiple handlers registered for %q", expr))
+	}
+	m.handlers[key] = h
+}
+
+// Fallback registers the handler for events whose cron expressions have no handlers.
+// Without the fallback, such events fail with error.
+func (m *Mux) Fallback(h Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fallback = h
+}
+
+// Handle dispatches the event to the handler for its cron expression.
+func (m *Mux) Handle(ctx context.Context, event *Event) error {
+	m.mu.RLock()
+	h, ok := m.handlers[normalize(event.Cron)]
+	if !ok {
+		h = m.fallback
+	}
+	m.mu.RUnlock()
+	if h == nil {
+		return fmt.Errorf("cron: no handler registered for %q", event.Cron)
+	}
+	return h(ctx, event)
+}
+
+// RegisterScheduled registers the mux by Schedule, so it can be given to workers.Options as the Scheduled handler.
+func (m *Mux) RegisterScheduled() {
+	Schedule(m.Handle)
+}
+
+// RegisterScheduled registers the handler by Schedule, so it can be given to workers.Options as the Scheduled handler.
+func (h Handler) RegisterScheduled() {
+	Schedule(h)
+}
+
+// OnCron registers the handler for the cron expression to DefaultMux.
+func OnCron(expr string, h Handler) {
+	DefaultMux.OnCron(expr, h)
+}
+
+// Schedule registers the handler of scheduled events. It must be called before workers.Serve or workers.Start.
+// If the handler is nil, DefaultMux.Handle is used.
+// The JavaScript entry point must export the scheduled handler calling `handleScheduled`:
+//
+//	async scheduled(event, env, ctx) {
+//	  await load;
+//	  await readyPromise;
+//	  return handleScheduled(event, { env, ctx });
+//	}
+func Schedule(h Handler) {
+	if h == nil {
+		h = DefaultMux.Handle
+	}
+	runtimecontext.RegisterHandler("handleScheduled", func(ctx context.Context, eventObj js.Value) error {
+		event := &Event{
+			Cron:          eventObj.Get("cron").String(),
+			ScheduledTime: time.UnixMilli(int64(eventObj.Get("scheduledTime").Float())).UTC(),
+		}
+		return h(ctx, event)
+	})
+}
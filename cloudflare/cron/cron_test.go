@@ -0,0 +1,61 @@
+package cron
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMux_Handle(t *testing.T) {
+	tests := map[string]struct {
+		cron     string
+		fallback bool
+		want     string
+		wantErr  bool
+	}{
+		"exact match": {
+			cron: "0 3 * * *",
+			want: "daily",
+		},
+		"whitespaces are normalized": {
+			cron: " */5  * * * * ",
+			want: "every5min",
+		},
+		"fallback": {
+			cron:     "0 0 1 * *",
+			fallback: true,
+			want:     "fallback",
+		},
+		"no handler": {
+			cron:    "0 0 1 * *",
+			wantErr: true,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var got string
+			handler := func(name string) Handler {
+				return func(ctx context.Context, event *Event) error {
+					got = name
+					return nil
+				}
+			}
+			m := NewMux()
+			m.OnCron("0 3 * * *", handler("daily"))
+			m.OnCron("*/5 * * * *", handler("every5min"))
+			if tc.fallback {
+				m.Fallback(handler("fallback"))
+			}
+			err := m.Handle(context.Background(), &Event{Cron: tc.cron, ScheduledTime: time.Now().UTC()})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("want error: %v, got: %v", tc.wantErr, err)
+			}
+			if got != tc.want {
+				t.Errorf("want handler %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package cloudflare
+
+import (
+	"context"
+
+	"github.com/syumai/workers/cloudflare/internal/cfruntimecontext"
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jsutil"
+	"github.com/syumai/workers/internal/runtimecontext"
+)
+
+// WaitUntil extends the lifetime of the event until the task finishes.
+// The task runs in a new goroutine, so it can continue after the response is returned.
+//   - https://developers.cloudflare.com/workers/runtime-apis/context/#waituntil
+//   - ctx given to the task is not canceled when the event settles, so subrequests made with it aren't aborted
+//     after the response is returned. The context of the event must not be used in the task.
+//   - the teardown of the Worker waits for the task to finish.
+//   - panics in the task are recovered and reported to hooks registered by workers.OnError.
+//   - This function panics when a runtime context is not found.
+func WaitUntil(ctx context.Context, task func(ctx context.Context)) {
+	exCtx := cfruntimecontext.GetExecutionContext(ctx)
+	taskCtx := context.WithoutCancel(ctx)
+	release := runtimecontext.Hold()
+	var cb js.Func
+	cb = js.FuncOf(func(_ js.Value, pArgs []js.Value) any {
+		defer cb.Release()
+		resolve := pArgs[0]
+		go func() {
+			defer release()
+			defer resolve.Invoke(js.Undefined())
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					runtimecontext.ReportError(taskCtx, runtimecontext.NewPanicError(recovered), nil)
+				}
+			}()
+			task(taskCtx)
+		}()
+		return js.Undefined()
+	})
+	exCtx.Call("waitUntil", jsutil.NewPromise(cb))
+}
+
+// RayID returns the Ray ID of the incoming request, which identifies the request in Cloudflare logs.
+//   - returns an empty string for events other than requests, or when the cf-ray header is not set (e.g. in wrangler dev).
+//   - This function panics when a runtime context is not found.
+func RayID(ctx context.Context) string {
+	reqObj := cfruntimecontext.GetIncomingRequest(ctx)
+	if reqObj.Type() != js.TypeObject {
+		return ""
+	}
+	ray := reqObj.Get("headers").Call("get", "cf-ray")
+	if ray.IsNull() {
+		return ""
+	}
+	return ray.String()
+}
@@ -0,0 +1,77 @@
+//go:build js && wasm
+
+package cloudflare
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/runtimecontext"
+)
+
+func newTestEvent() (context.Context, func()) {
+	exCtx := js.Global().Get("Object").New()
+	exCtx.Set("waitUntil", js.FuncOf(func(js.Value, []js.Value) any { return js.Undefined() }))
+	runtimeCtxObj := js.Global().Get("Object").New()
+	runtimeCtxObj.Set("ctx", exCtx)
+	return runtimecontext.NewEvent(js.Undefined(), runtimeCtxObj)
+}
+
+func TestWaitUntil_Teardown(t *testing.T) {
+	ctx, settle := newTestEvent()
+	unblock := make(chan struct{})
+	finished := make(chan struct{})
+	WaitUntil(ctx, func(ctx context.Context) {
+		<-unblock
+		close(finished)
+	})
+	settle()
+
+	waited := make(chan struct{})
+	go func() {
+		runtimecontext.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+		t.Fatal("want the teardown to wait for the task")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(unblock)
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		t.Fatal("want the teardown to finish after the task")
+	}
+	select {
+	case <-finished:
+	default:
+		t.Error("want the task to finish before the teardown")
+	}
+}
+
+func TestWaitUntil_Panic(t *testing.T) {
+	reported := make(chan error, 1)
+	runtimecontext.OnError(func(ctx context.Context, err error, req *http.Request) {
+		reported <- err
+	})
+	ctx, settle := newTestEvent()
+	WaitUntil(ctx, func(ctx context.Context) {
+		panic("failed")
+	})
+	settle()
+	runtimecontext.Wait()
+	select {
+	case err := <-reported:
+		var panicErr *runtimecontext.PanicError
+		if !errors.As(err, &panicErr) || panicErr.Value != "failed" {
+			t.Errorf("want PanicError of the task, got %v", err)
+		}
+	default:
+		t.Error("want the panic to be reported")
+	}
+}
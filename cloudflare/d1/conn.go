@@ -4,7 +4,8 @@ import (
 	"context"
 	"database/sql/driver"
 	"errors"
-	"syscall/js"
+
+	"github.com/syumai/workers/internal/js"
 )
 
 type Conn struct {
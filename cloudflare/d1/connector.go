@@ -3,9 +3,9 @@ package d1
 import (
 	"context"
 	"database/sql/driver"
-	"syscall/js"
 
 	"github.com/syumai/workers/cloudflare/internal/cfruntimecontext"
+	"github.com/syumai/workers/internal/js"
 )
 
 type Connector struct {
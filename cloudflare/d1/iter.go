@@ -0,0 +1,31 @@
+//go:build go1.23
+
+package d1
+
+import (
+	"database/sql"
+	"iter"
+)
+
+// All returns an iterator over rows of the query result, converting each row by scan.
+//   - rows are closed when the iteration ends, so All must not be iterated twice.
+//   - if scan or iterating rows fails, the error is yielded and the iteration stops.
+func All[T any](rows *sql.Rows, scan func(rows *sql.Rows) (T, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		defer rows.Close()
+		var zero T
+		for rows.Next() {
+			v, err := scan(rows)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
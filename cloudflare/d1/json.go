@@ -0,0 +1,46 @@
+package d1
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/syumai/workers/jsoncodec"
+)
+
+// JSON is a value of T stored in a TEXT column as JSON, encoded by the codec of the jsoncodec package.
+//   - JSON implements sql.Scanner and driver.Valuer, so it can be given to Scan, and as arguments of queries.
+//   - NULL is scanned as the zero value of T.
+type JSON[T any] struct {
+	V T
+}
+
+var (
+	_ sql.Scanner   = (*JSON[any])(nil)
+	_ driver.Valuer = JSON[any]{}
+)
+
+// Scan implements sql.Scanner.
+func (j *JSON[T]) Scan(src any) error {
+	var zero T
+	j.V = zero
+	switch src := src.(type) {
+	case nil:
+		return nil
+	case string:
+		return jsoncodec.Unmarshal([]byte(src), &j.V)
+	case []byte:
+		return jsoncodec.Unmarshal(src, &j.V)
+	default:
+		return fmt.Errorf("d1: cannot scan %T into JSON", src)
+	}
+}
+
+// Value implements driver.Valuer.
+func (j JSON[T]) Value() (driver.Value, error) {
+	b, err := jsoncodec.Marshal(j.V)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
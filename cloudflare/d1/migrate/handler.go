@@ -0,0 +1,58 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/syumai/workers"
+)
+
+// Handler returns a handler applying migrations to the database opened by openDB on POST requests.
+//   - the response is a JSON object holding versions of applied and pending migrations (GET only reports pending ones).
+//   - responds 409 Conflict if another caller is applying migrations concurrently.
+//   - the handler must be guarded by authentication (e.g. middleware.BasicAuth).
+func Handler(openDB func(ctx context.Context) (*sql.DB, error), migrations []*Migration, opts *Options) http.Handler {
+	return workers.HandlerFunc(func(w http.ResponseWriter, req *http.Request) error {
+		if req.Method != http.MethodGet && req.Method != http.MethodPost {
+			w.Header().Set("Allow", "GET, POST")
+			return workers.NewHTTPError(http.StatusMethodNotAllowed, "", nil)
+		}
+		db, err := openDB(req.Context())
+		if err != nil {
+			return err
+		}
+		result := struct {
+			Applied []int64 `json:"applied"`
+			Pending []int64 `json:"pending"`
+		}{Applied: []int64{}, Pending: []int64{}}
+		if req.Method == http.MethodPost {
+			applied, err := Migrate(req.Context(), db, migrations, opts)
+			for _, m := range applied {
+				result.Applied = append(result.Applied, m.Version)
+			}
+			if errors.Is(err, ErrConflict) {
+				return workers.NewHTTPError(http.StatusConflict, err.Error(), err)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		pending, err := Pending(req.Context(), db, migrations, opts)
+		if err != nil {
+			return err
+		}
+		for _, m := range pending {
+			result.Pending = append(result.Pending, m.Version)
+		}
+		b, err := json.Marshal(&result)
+		if err != nil {
+			return err
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b)
+		return nil
+	})
+}
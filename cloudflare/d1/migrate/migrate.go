@@ -0,0 +1,253 @@
+// Package migrate applies SQL migrations to D1 databases, so the schema can be managed in the repository of the Worker.
+//   - migrations are SQL files named "<version>_<name>.sql" (e.g. "0001_create_users.sql"), typically embedded by embed.FS.
+//   - applied versions are recorded in the migrations table, and only pending migrations are applied in order of versions.
+//   - Migrate can be called from a scheduled handler, or from an admin route guarded by authentication (see Handler).
+//   - a run of Migrate holds a lease recorded in the "<table>_lease" table, so concurrent callers don't apply migrations at once.
+//
+// D1 doesn't support transactions by the database/sql driver, so statements of a migration are executed one by one,
+// and a migration failed halfway is not rolled back. Write migrations which can be retried (e.g. CREATE TABLE IF NOT EXISTS).
+package migrate
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migration is a migration loaded from a SQL file.
+type Migration struct {
+	Version int64
+	Name    string
+	// SQL is the content of the file, which can contain multiple statements separated by semicolons.
+	SQL string
+}
+
+// Load loads migrations from SQL files in the directory of the file system, sorted by versions.
+//   - files whose names don't start with a version (e.g. "README.md") are ignored.
+//   - returns error if versions are duplicated.
+func Load(fsys fs.FS, dir string) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	var migrations []*Migration
+	seen := map[int64]string{}
+	for _, e := range entries {
+		if e.IsDir() || path.Ext(e.Name()) != ".sql" {
+			continue
+		}
+		stem := strings.TrimSuffix(e.Name(), ".sql")
+		versionStr, name, _ := strings.Cut(stem, "_")
+		version, err := strconv.ParseInt(versionStr, 10, 64)
+		if err != nil {
+			continue
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrate: duplicate version %d: %s and %s", version, other, e.Name())
+		}
+		seen[version] = e.Name()
+		b, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, &Migration{Version: version, Name: name, SQL: string(b)})
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Options represents options of Migrate and Pending.
+type Options struct {
+	// Table is the name of the table recording applied migrations. Defaults to "schema_migrations".
+	Table string
+	// LeaseTTL is the duration after which the lease of Migrate expires if it is not renewed. Defaults to 5 minutes.
+	// The lease is renewed before each migration, so it must be longer than the longest migration.
+	// It lets another caller apply migrations when the holder was aborted without releasing the lease.
+	LeaseTTL time.Duration
+}
+
+const defaultLeaseTTL = 5 * time.Minute
+
+func (opts *Options) leaseTTL() time.Duration {
+	if opts == nil || opts.LeaseTTL <= 0 {
+		return defaultLeaseTTL
+	}
+	return opts.LeaseTTL
+}
+
+func (opts *Options) table() string {
+	if opts == nil || opts.Table == "" {
+		return "schema_migrations"
+	}
+	return opts.Table
+}
+
+// ensureTable creates the migrations table if it doesn't exist.
+func ensureTable(ctx context.Context, db *sql.DB, table string) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+table+` (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TEXT NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("migrate: error creating the migrations table: %w", err)
+	}
+	return nil
+}
+
+// ensureLeaseTable creates the table holding the lease of Migrate if it doesn't exist. The table has one row at most.
+func ensureLeaseTable(ctx context.Context, db *sql.DB, table string) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+table+`_lease (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		owner TEXT NOT NULL,
+		expires_at INTEGER NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("migrate: error creating the lease table: %w", err)
+	}
+	return nil
+}
+
+// acquireLease acquires or renews the lease for the owner, and reports whether the owner holds it.
+//   - the lease is taken over if it is expired. The upsert returns the row only if it is written,
+//     so acquiring and checking the lease is a single atomic statement.
+func acquireLease(ctx context.Context, db *sql.DB, table, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	var holder string
+	err := db.QueryRowContext(ctx, `INSERT INTO `+table+`_lease (id, owner, expires_at) VALUES (1, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET owner = excluded.owner, expires_at = excluded.expires_at
+		WHERE `+table+`_lease.owner = excluded.owner OR `+table+`_lease.expires_at <= ?
+		RETURNING owner`,
+		owner, now.Add(ttl).UnixMilli(), now.UnixMilli()).Scan(&holder)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("migrate: error acquiring the lease: %w", err)
+	}
+	return holder == owner, nil
+}
+
+// releaseLease releases the lease if the owner holds it.
+func releaseLease(ctx context.Context, db *sql.DB, table, owner string) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM `+table+`_lease WHERE id = 1 AND owner = ?`, owner); err != nil {
+		return fmt.Errorf("migrate: error releasing the lease: %w", err)
+	}
+	return nil
+}
+
+// Pending returns migrations which have not been applied yet.
+func Pending(ctx context.Context, db *sql.DB, migrations []*Migration, opts *Options) ([]*Migration, error) {
+	table := opts.table()
+	if err := ensureTable(ctx, db, table); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `SELECT version FROM `+table)
+	if err != nil {
+		return nil, fmt.Errorf("migrate: error reading the migrations table: %w", err)
+	}
+	defer rows.Close()
+	applied := map[int64]bool{}
+	for rows.Next() {
+		var v int64
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		applied[v] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	var pending []*Migration
+	for _, m := range migrations {
+		if !applied[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// ErrConflict is returned by Migrate when another caller is applying migrations concurrently.
+//   - the other caller also applies following migrations, so it can be ignored by a scheduled handler.
+var ErrConflict = errors.New("migrate: migrations are being applied by another caller")
+
+// Migrate applies pending migrations in order, and returns applied migrations.
+//   - the whole run holds the lease of the migrations table, so concurrent callers don't apply migrations at once.
+//     if another caller holds the lease, returns ErrConflict without applying migrations.
+//   - the lease is renewed before each migration. If it was taken over after it expired,
+//     returns migrations applied before and ErrConflict.
+//   - a migration is recorded in the migrations table after all of its statements are executed,
+//     so a migration failed or aborted halfway is never regarded as applied.
+//   - if a migration fails, returns migrations applied before it and the error. Following migrations are not applied.
+func Migrate(ctx context.Context, db *sql.DB, migrations []*Migration, opts *Options) (applied []*Migration, err error) {
+	table := opts.table()
+	if err := ensureTable(ctx, db, table); err != nil {
+		return nil, err
+	}
+	if err := ensureLeaseTable(ctx, db, table); err != nil {
+		return nil, err
+	}
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	owner := hex.EncodeToString(b[:])
+	ttl := opts.leaseTTL()
+	held, err := acquireLease(ctx, db, table, owner, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if !held {
+		return nil, ErrConflict
+	}
+	defer func() {
+		if relErr := releaseLease(context.WithoutCancel(ctx), db, table, owner); relErr != nil {
+			err = errors.Join(err, relErr)
+		}
+	}()
+
+	pending, err := Pending(ctx, db, migrations, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range pending {
+		held, err := acquireLease(ctx, db, table, owner, ttl)
+		if err != nil {
+			return applied, err
+		}
+		if !held {
+			return applied, ErrConflict
+		}
+		for _, stmt := range SplitStatements(m.SQL) {
+			if _, err := db.ExecContext(ctx, stmt); err != nil {
+				return applied, fmt.Errorf("migrate: error applying %d_%s: %w", m.Version, m.Name, err)
+			}
+		}
+		_, err = db.ExecContext(ctx, `INSERT INTO `+table+` (version, name, applied_at) VALUES (?, ?, ?)`,
+			m.Version, m.Name, time.Now().UTC().Format(time.RFC3339))
+		if err != nil {
+			if isUniqueViolation(err) {
+				// another caller took over the expired lease and applied the migration.
+				return applied, ErrConflict
+			}
+			return applied, fmt.Errorf("migrate: error recording %d_%s: %w", m.Version, m.Name, err)
+		}
+		applied = append(applied, m)
+	}
+	return applied, nil
+}
+
+// isUniqueViolation reports whether the error is caused by a conflict of the primary key.
+//   - D1 doesn't expose error codes, so the message of SQLite is checked.
+func isUniqueViolation(err error) bool {
+	return strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
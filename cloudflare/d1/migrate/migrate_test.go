@@ -0,0 +1,184 @@
+//go:build !(js && wasm)
+
+package migrate_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/syumai/workers/cloudflare/d1/migrate"
+	"github.com/syumai/workers/workerstest"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := map[string]struct {
+		sql  string
+		want []string
+	}{
+		"statements": {
+			sql:  "CREATE TABLE a (id INTEGER);\n\nINSERT INTO a VALUES (1);",
+			want: []string{"CREATE TABLE a (id INTEGER)", "INSERT INTO a VALUES (1)"},
+		},
+		"quotes and comments": {
+			sql:  "-- comment;\nINSERT INTO a VALUES ('a;''b', \"c;\"); /* d; */",
+			want: []string{"-- comment;\nINSERT INTO a VALUES ('a;''b', \"c;\")"},
+		},
+		"trigger": {
+			sql: "CREATE TRIGGER t AFTER INSERT ON a BEGIN UPDATE b SET n = CASE WHEN n > 0 THEN n + 1 ELSE 1 END; DELETE FROM c; END;",
+			want: []string{
+				"CREATE TRIGGER t AFTER INSERT ON a BEGIN UPDATE b SET n = CASE WHEN n > 0 THEN n + 1 ELSE 1 END; DELETE FROM c; END",
+			},
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if got := migrate.SplitStatements(tc.sql); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0001_create_users.sql": {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);")},
+		"migrations/0002_add_email.sql":    {Data: []byte("ALTER TABLE users ADD COLUMN email TEXT;\nCREATE INDEX users_email ON users (email);")},
+		"migrations/README.md":             {Data: []byte("ignored")},
+	}
+	migrations, err := migrate.Load(fsys, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 || migrations[0].Version != 1 || migrations[1].Name != "add_email" {
+		t.Fatalf("unexpected migrations: %+v", migrations)
+	}
+	db, err := workerstest.NewD1Database()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	applied, err := migrate.Migrate(ctx, db, migrations[:1], nil)
+	if err != nil || len(applied) != 1 {
+		t.Fatalf("want 1 migration applied, got %v, %v", applied, err)
+	}
+	applied, err = migrate.Migrate(ctx, db, migrations, nil)
+	if err != nil || len(applied) != 1 || applied[0].Version != 2 {
+		t.Fatalf("want the second migration applied, got %v, %v", applied, err)
+	}
+	if pending, err := migrate.Pending(ctx, db, migrations, nil); err != nil || len(pending) != 0 {
+		t.Errorf("want no pending migrations, got %v, %v", pending, err)
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO users (name, email) VALUES ('gopher', 'gopher@example.com')"); err != nil {
+		t.Errorf("want the schema migrated, got %v", err)
+	}
+
+	failing := append(migrations, &migrate.Migration{Version: 3, Name: "broken", SQL: "CREATE TABLE users (id INTEGER);"})
+	if applied, err := migrate.Migrate(ctx, db, failing, nil); err == nil || len(applied) != 0 {
+		t.Errorf("want the broken migration to fail, got %v, %v", applied, err)
+	}
+	if pending, err := migrate.Pending(ctx, db, failing, nil); err != nil || len(pending) != 1 || pending[0].Version != 3 {
+		t.Errorf("want the broken migration not to be recorded, got %v, %v", pending, err)
+	}
+
+	// another caller holds the lease while it is applying the third migration.
+	more := append(migrations,
+		&migrate.Migration{Version: 3, Name: "create_posts", SQL: "CREATE TABLE posts (id INTEGER);"},
+		&migrate.Migration{Version: 4, Name: "create_tags", SQL: "CREATE TABLE tags (id INTEGER);"},
+	)
+	if _, err := db.ExecContext(ctx, "INSERT INTO schema_migrations_lease VALUES (1, 'other', ?)", time.Now().Add(time.Minute).UnixMilli()); err != nil {
+		t.Fatal(err)
+	}
+	applied, err = migrate.Migrate(ctx, db, more, nil)
+	if !errors.Is(err, migrate.ErrConflict) || len(applied) != 0 {
+		t.Errorf("want ErrConflict without applying migrations, got %v, %v", applied, err)
+	}
+	if _, err := db.ExecContext(ctx, "SELECT * FROM tags"); err == nil {
+		t.Errorf("want the following migration not to be applied while another caller holds the lease")
+	}
+
+	// the lease of an aborted caller expires.
+	if _, err := db.ExecContext(ctx, "UPDATE schema_migrations_lease SET expires_at = ?", time.Now().Add(-time.Second).UnixMilli()); err != nil {
+		t.Fatal(err)
+	}
+	applied, err = migrate.Migrate(ctx, db, more, nil)
+	if err != nil || len(applied) != 2 {
+		t.Errorf("want the expired lease to be taken over, got %v, %v", applied, err)
+	}
+	var leases int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM schema_migrations_lease").Scan(&leases); err != nil || leases != 0 {
+		t.Errorf("want the lease released, got %d, %v", leases, err)
+	}
+}
+
+func TestMigrate_Concurrent(t *testing.T) {
+	db, err := workerstest.NewD1Database()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	// migrations fail if they are applied twice.
+	var migrations []*migrate.Migration
+	for i := 1; i <= 5; i++ {
+		migrations = append(migrations, &migrate.Migration{
+			Version: int64(i),
+			Name:    fmt.Sprintf("create_t%d", i),
+			SQL:     fmt.Sprintf("CREATE TABLE t%d (id INTEGER); INSERT INTO log VALUES (%d);", i, i),
+		})
+	}
+	if _, err := db.ExecContext(ctx, "CREATE TABLE log (version INTEGER)"); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// callers start while others are in the middle of applying migrations.
+			for {
+				_, err := migrate.Migrate(ctx, db, migrations, nil)
+				if !errors.Is(err, migrate.ErrConflict) {
+					errs[i] = err
+					return
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Errorf("want no errors other than ErrConflict, got %v", err)
+		}
+	}
+	var got []int
+	rows, err := db.QueryContext(ctx, "SELECT version FROM log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, v)
+	}
+	if want := []int{1, 2, 3, 4, 5}; !reflect.DeepEqual(want, got) {
+		t.Errorf("want each migration applied once in order, got %v", got)
+	}
+}
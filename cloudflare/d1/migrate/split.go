@@ -0,0 +1,98 @@
+package migrate
+
+import "strings"
+
+// SplitStatements splits SQL into statements separated by semicolons, since D1 prepares a single statement at a time.
+//   - semicolons in quotes and comments are ignored.
+//   - semicolons in BEGIN ... END blocks of CREATE TRIGGER and CASE ... END expressions are ignored.
+//   - empty statements and statements only of comments are omitted.
+func SplitStatements(sql string) []string {
+	var stmts []string
+	var b strings.Builder
+	// depth is the depth of BEGIN / CASE ... END blocks.
+	depth := 0
+	// hasCode reports whether the current statement has anything other than comments and spaces.
+	hasCode := false
+	flush := func() {
+		if hasCode {
+			stmts = append(stmts, strings.TrimSpace(b.String()))
+		}
+		b.Reset()
+		hasCode = false
+	}
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			end := byte(c)
+			if c == '[' {
+				end = ']'
+			}
+			j := i + 1
+			for j < len(sql) {
+				if sql[j] == end {
+					// quotes are escaped by doubling them.
+					if end != ']' && j+1 < len(sql) && sql[j+1] == end {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			j = min(j+1, len(sql))
+			b.WriteString(sql[i:j])
+			hasCode = true
+			i = j
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			j := strings.IndexByte(sql[i:], '\n')
+			if j < 0 {
+				j = len(sql) - i
+			}
+			b.WriteString(sql[i : i+j])
+			i += j
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			j := strings.Index(sql[i+2:], "*/")
+			if j < 0 {
+				j = len(sql) - i
+			} else {
+				j += 4
+			}
+			b.WriteString(sql[i : i+j])
+			i += j
+		case c == ';' && depth == 0:
+			flush()
+			i++
+		case isIdentStart(c):
+			j := i
+			for j < len(sql) && isIdentPart(sql[j]) {
+				j++
+			}
+			switch strings.ToUpper(sql[i:j]) {
+			case "BEGIN", "CASE":
+				depth++
+			case "END":
+				depth = max(depth-1, 0)
+			}
+			b.WriteString(sql[i:j])
+			hasCode = true
+			i = j
+		default:
+			if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+				hasCode = true
+			}
+			b.WriteByte(c)
+			i++
+		}
+	}
+	flush()
+	return stmts
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || '0' <= c && c <= '9' || c == '$'
+}
@@ -3,7 +3,8 @@ package d1
 import (
 	"database/sql"
 	"errors"
-	"syscall/js"
+
+	"github.com/syumai/workers/internal/js"
 )
 
 type result struct {
@@ -6,8 +6,8 @@ import (
 	"io"
 	"math"
 	"sync"
-	"syscall/js"
 
+	"github.com/syumai/workers/internal/js"
 	"github.com/syumai/workers/internal/jsutil"
 )
 
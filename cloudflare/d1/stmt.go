@@ -4,9 +4,10 @@ import (
 	"context"
 	"database/sql/driver"
 	"errors"
-	"syscall/js"
 
+	"github.com/syumai/workers/internal/js"
 	"github.com/syumai/workers/internal/jsutil"
+	"github.com/syumai/workers/internal/runtimecontext"
 )
 
 type stmt struct {
@@ -35,11 +36,12 @@ func (s *stmt) Exec([]driver.Value) (driver.Result, error) {
 
 // ExecContext executes prepared statement.
 // Given []drier.NamedValue's `Name` field will be ignored because Cloudflare D1 client doesn't support it.
-func (s *stmt) ExecContext(_ context.Context, args []driver.NamedValue) (driver.Result, error) {
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
 	argValues := make([]any, len(args))
 	for i, arg := range args {
 		argValues[i] = arg.Value
 	}
+	runtimecontext.SubrequestsFrom(ctx).Add()
 	resultPromise := s.stmtObj.Call("bind", argValues...).Call("run")
 	resultObj, err := jsutil.AwaitPromise(resultPromise)
 	if err != nil {
@@ -54,11 +56,12 @@ func (s *stmt) Query([]driver.Value) (driver.Rows, error) {
 	return nil, errors.New("d1: Query is deprecated and not implemented")
 }
 
-func (s *stmt) QueryContext(_ context.Context, args []driver.NamedValue) (driver.Rows, error) {
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
 	argValues := make([]any, len(args))
 	for i, arg := range args {
 		argValues[i] = arg
 	}
+	runtimecontext.SubrequestsFrom(ctx).Add()
 	resultPromise := s.stmtObj.Call("bind", argValues...).Call("all")
 	rowsObj, err := jsutil.AwaitPromise(resultPromise)
 	if err != nil {
@@ -0,0 +1,285 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/syumai/workers/cloudflare/internal/cfruntimecontext"
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jsutil"
+	"github.com/syumai/workers/jsoncodec"
+)
+
+// DurableObjectStorageBinding is the interface implemented by DurableObjectStorage.
+type DurableObjectStorageBinding interface {
+	Get(key string, v any) (bool, error)
+	GetMulti(keys []string) (map[string]json.RawMessage, error)
+	Put(key string, v any) error
+	PutMulti(entries map[string]any) error
+	Delete(key string) (bool, error)
+	DeleteMulti(keys []string) (int, error)
+	DeleteAll() error
+	List(opts *DurableObjectStorageListOptions) ([]*DurableObjectStorageEntry, error)
+	GetAlarm() (time.Time, bool, error)
+	SetAlarm(t time.Time) error
+	DeleteAlarm() error
+}
+
+var _ DurableObjectStorageBinding = (*DurableObjectStorage)(nil)
+
+// durableObjectStorageMaxKeys is the maximum number of keys given to a single call of get, put and delete.
+//   - https://developers.cloudflare.com/durable-objects/api/storage-api/#get
+const durableObjectStorageMaxKeys = 128
+
+// DurableObjectStorage represents the transactional storage of the durable object.
+//   - https://developers.cloudflare.com/durable-objects/api/storage-api/
+//   - values are encoded by the codec of the jsoncodec package, and stored as JavaScript values parsed from the JSON,
+//     so they can be shared with durable objects written in JavaScript.
+//   - an object lives in a single isolate, so read-modify-write of keys can be serialized by a mutex of the Go program
+//     (e.g. keyed by durableobject.ID), since goroutines handling requests may interleave between storage operations.
+type DurableObjectStorage struct {
+	instance js.Value
+}
+
+// NewDurableObjectStorage returns the storage of the durable object handling the event of ctx.
+//   - if ctx is not of an event of a durable object (see the durableobject package), returns error.
+func NewDurableObjectStorage(ctx context.Context) (*DurableObjectStorage, error) {
+	storage := cfruntimecontext.GetExecutionContext(ctx).Get("storage")
+	if storage.IsUndefined() {
+		return nil, errors.New("storage is undefined: the context is not of a durable object")
+	}
+	return &DurableObjectStorage{instance: storage}, nil
+}
+
+// toStorageValue encodes v by the codec of the jsoncodec package, and parses it into a JavaScript value.
+func toStorageValue(key string, v any) (js.Value, error) {
+	b, err := jsoncodec.Marshal(v)
+	if err != nil {
+		return js.Value{}, fmt.Errorf("error encoding value of %s: %w", key, err)
+	}
+	return jsutil.Global.Get("JSON").Call("parse", string(b)), nil
+}
+
+// fromStorageValue returns the JSON of the stored JavaScript value.
+func fromStorageValue(v js.Value) json.RawMessage {
+	return json.RawMessage(jsutil.Global.Get("JSON").Call("stringify", v).String())
+}
+
+// forEachEntry calls fn with entries of the Map returned by the storage in its order.
+func forEachEntry(m js.Value, fn func(key string, value js.Value)) {
+	cb := js.FuncOf(func(_ js.Value, args []js.Value) any {
+		fn(args[1].String(), args[0])
+		return js.Undefined()
+	})
+	defer cb.Release()
+	m.Call("forEach", cb)
+}
+
+// chunkKeys splits keys into chunks which can be given to a single call of the storage.
+func chunkKeys(keys []string) [][]string {
+	var chunks [][]string
+	for len(keys) > 0 {
+		n := min(len(keys), durableObjectStorageMaxKeys)
+		chunks = append(chunks, keys[:n])
+		keys = keys[n:]
+	}
+	return chunks
+}
+
+// toKeysArray converts keys into an array of JavaScript.
+func toKeysArray(keys []string) js.Value {
+	a := make([]any, len(keys))
+	for i, key := range keys {
+		a[i] = key
+	}
+	return js.ValueOf(a)
+}
+
+// Get gets the value of the key, and decodes it into v by the codec of the jsoncodec package.
+//   - if the key doesn't exist, returns false without touching v.
+func (s *DurableObjectStorage) Get(key string, v any) (bool, error) {
+	value, err := jsutil.AwaitPromise(s.instance.Call("get", key))
+	if err != nil {
+		return false, err
+	}
+	if value.IsUndefined() {
+		return false, nil
+	}
+	if err := jsoncodec.Unmarshal(fromStorageValue(value), v); err != nil {
+		return false, fmt.Errorf("error decoding value of %s: %w", key, err)
+	}
+	return true, nil
+}
+
+// GetMulti gets the values of the keys as JSON. Keys which don't exist are not included in the result.
+//   - keys are split into chunks of 128 keys, which is the limit of the storage, and the chunks are read one by one.
+func (s *DurableObjectStorage) GetMulti(keys []string) (map[string]json.RawMessage, error) {
+	values := make(map[string]json.RawMessage, len(keys))
+	for _, chunk := range chunkKeys(keys) {
+		m, err := jsutil.AwaitPromise(s.instance.Call("get", toKeysArray(chunk)))
+		if err != nil {
+			return nil, err
+		}
+		forEachEntry(m, func(key string, value js.Value) {
+			values[key] = fromStorageValue(value)
+		})
+	}
+	return values, nil
+}
+
+// Put encodes v by the codec of the jsoncodec package, and puts it to the key.
+func (s *DurableObjectStorage) Put(key string, v any) error {
+	value, err := toStorageValue(key, v)
+	if err != nil {
+		return err
+	}
+	_, err = jsutil.AwaitPromise(s.instance.Call("put", key, value))
+	return err
+}
+
+// PutMulti puts the entries as well as Put.
+//   - entries are split into chunks of 128 keys, which is the limit of the storage. Each chunk is written atomically,
+//     but if writing a chunk fails, the previous chunks remain written.
+func (s *DurableObjectStorage) PutMulti(entries map[string]any) error {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	for _, chunk := range chunkKeys(keys) {
+		obj := make(map[string]any, len(chunk))
+		for _, key := range chunk {
+			value, err := toStorageValue(key, entries[key])
+			if err != nil {
+				return err
+			}
+			obj[key] = value
+		}
+		if _, err := jsutil.AwaitPromise(s.instance.Call("put", js.ValueOf(obj))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete deletes the key, and reports whether it existed.
+func (s *DurableObjectStorage) Delete(key string) (bool, error) {
+	v, err := jsutil.AwaitPromise(s.instance.Call("delete", key))
+	if err != nil {
+		return false, err
+	}
+	return v.Truthy(), nil
+}
+
+// DeleteMulti deletes the keys, and returns the number of keys which existed.
+//   - keys are split into chunks of 128 keys as well as GetMulti.
+func (s *DurableObjectStorage) DeleteMulti(keys []string) (int, error) {
+	var deleted int
+	for _, chunk := range chunkKeys(keys) {
+		v, err := jsutil.AwaitPromise(s.instance.Call("delete", toKeysArray(chunk)))
+		if err != nil {
+			return deleted, err
+		}
+		deleted += v.Int()
+	}
+	return deleted, nil
+}
+
+// DeleteAll deletes all keys of the storage. The alarm is not deleted.
+//   - https://developers.cloudflare.com/durable-objects/api/storage-api/#deleteall
+func (s *DurableObjectStorage) DeleteAll() error {
+	_, err := jsutil.AwaitPromise(s.instance.Call("deleteAll"))
+	return err
+}
+
+// DurableObjectStorageListOptions represents options of List.
+//   - https://developers.cloudflare.com/durable-objects/api/storage-api/#list
+type DurableObjectStorageListOptions struct {
+	// Start is the key to start listing from, inclusive.
+	Start string
+	// StartAfter is the key to start listing after, exclusive. It can't be used with Start.
+	StartAfter string
+	// End is the key to stop listing at, exclusive.
+	End string
+	// Prefix restricts keys to those starting with the prefix.
+	Prefix string
+	// Reverse lists keys in descending order. Start and End still bound the range.
+	Reverse bool
+	// Limit is the maximum number of entries. All entries in the range are listed if it is 0.
+	Limit int
+}
+
+func (opts *DurableObjectStorageListOptions) toJS() js.Value {
+	if opts == nil {
+		return js.Undefined()
+	}
+	obj := map[string]any{}
+	if opts.Start != "" {
+		obj["start"] = opts.Start
+	}
+	if opts.StartAfter != "" {
+		obj["startAfter"] = opts.StartAfter
+	}
+	if opts.End != "" {
+		obj["end"] = opts.End
+	}
+	if opts.Prefix != "" {
+		obj["prefix"] = opts.Prefix
+	}
+	if opts.Reverse {
+		obj["reverse"] = true
+	}
+	if opts.Limit != 0 {
+		obj["limit"] = opts.Limit
+	}
+	return js.ValueOf(obj)
+}
+
+// DurableObjectStorageEntry represents a key and its value listed by List.
+type DurableObjectStorageEntry struct {
+	Key string
+	// Value is the JSON of the value.
+	Value json.RawMessage
+}
+
+// List lists entries of the storage in the order of keys.
+//   - without Limit, all entries in the range are loaded into memory. Use ListDurableObjectStorage to iterate over pages.
+func (s *DurableObjectStorage) List(opts *DurableObjectStorageListOptions) ([]*DurableObjectStorageEntry, error) {
+	m, err := jsutil.AwaitPromise(s.instance.Call("list", opts.toJS()))
+	if err != nil {
+		return nil, err
+	}
+	var entries []*DurableObjectStorageEntry
+	forEachEntry(m, func(key string, value js.Value) {
+		entries = append(entries, &DurableObjectStorageEntry{Key: key, Value: fromStorageValue(value)})
+	})
+	return entries, nil
+}
+
+// GetAlarm returns the time of the alarm. If no alarm is set, returns false.
+//   - https://developers.cloudflare.com/durable-objects/api/alarms/
+func (s *DurableObjectStorage) GetAlarm() (time.Time, bool, error) {
+	v, err := jsutil.AwaitPromise(s.instance.Call("getAlarm"))
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if v.IsNull() || v.IsUndefined() {
+		return time.Time{}, false, nil
+	}
+	return time.UnixMilli(int64(v.Float())), true, nil
+}
+
+// SetAlarm sets the alarm to t, replacing the previous one.
+// The alarm handler of the object (see durableobject.HandleAlarm) is called at t.
+func (s *DurableObjectStorage) SetAlarm(t time.Time) error {
+	_, err := jsutil.AwaitPromise(s.instance.Call("setAlarm", t.UnixMilli()))
+	return err
+}
+
+// DeleteAlarm deletes the alarm if it is set.
+func (s *DurableObjectStorage) DeleteAlarm() error {
+	_, err := jsutil.AwaitPromise(s.instance.Call("deleteAlarm"))
+	return err
+}
@@ -0,0 +1,315 @@
+//go:build !(js && wasm)
+
+package cloudflare_test
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/runtimecontext"
+)
+
+// resolved returns a thenable which is fulfilled with v synchronously.
+func resolved(v any) js.Value {
+	return js.ValueOf(map[string]any{
+		"then": js.FuncOf(func(_ js.Value, args []js.Value) any {
+			args[0].Invoke(v)
+			return js.Undefined()
+		}),
+	})
+}
+
+// jsMap returns an object iterating over the entries in the order of keys by forEach, as well as Map returned by the storage.
+func jsMap(keys []string, values map[string]js.Value) js.Value {
+	return js.ValueOf(map[string]any{
+		"forEach": js.FuncOf(func(_ js.Value, args []js.Value) any {
+			for _, key := range keys {
+				args[0].Invoke(values[key], key)
+			}
+			return js.Undefined()
+		}),
+	})
+}
+
+// fakeStorage is a fake of the storage object of the runtime. It records the number of keys of each call.
+type fakeStorage struct {
+	values   map[string]js.Value
+	alarm    js.Value
+	keyCalls []int
+}
+
+func (s *fakeStorage) keys(arg js.Value) []string {
+	keys := make([]string, arg.Length())
+	for i := range keys {
+		keys[i] = arg.Index(i).String()
+	}
+	s.keyCalls = append(s.keyCalls, len(keys))
+	return keys
+}
+
+func (s *fakeStorage) object() js.Value {
+	method := func(fn func(args []js.Value) any) js.Func {
+		return js.FuncOf(func(_ js.Value, args []js.Value) any { return resolved(fn(args)) })
+	}
+	return js.ValueOf(map[string]any{
+		"get": method(func(args []js.Value) any {
+			if args[0].Type() == js.TypeString {
+				v, ok := s.values[args[0].String()]
+				if !ok {
+					return js.Undefined()
+				}
+				return v
+			}
+			var found []string
+			for _, key := range s.keys(args[0]) {
+				if _, ok := s.values[key]; ok {
+					found = append(found, key)
+				}
+			}
+			return jsMap(found, s.values)
+		}),
+		"put": method(func(args []js.Value) any {
+			if args[0].Type() == js.TypeString {
+				s.values[args[0].String()] = args[1]
+				return js.Undefined()
+			}
+			keys := s.keys(js.Global().Get("Object").Call("keys", args[0]))
+			for _, key := range keys {
+				s.values[key] = args[0].Get(key)
+			}
+			return js.Undefined()
+		}),
+		"delete": method(func(args []js.Value) any {
+			if args[0].Type() == js.TypeString {
+				_, ok := s.values[args[0].String()]
+				delete(s.values, args[0].String())
+				return ok
+			}
+			var n int
+			for _, key := range s.keys(args[0]) {
+				if _, ok := s.values[key]; ok {
+					delete(s.values, key)
+					n++
+				}
+			}
+			return n
+		}),
+		"deleteAll": method(func(args []js.Value) any {
+			s.values = map[string]js.Value{}
+			return js.Undefined()
+		}),
+		"list": method(func(args []js.Value) any {
+			opts := args[0]
+			var keys []string
+			for key := range s.values {
+				if p := opts.Get("prefix"); !p.IsUndefined() && !strings.HasPrefix(key, p.String()) {
+					continue
+				}
+				if v := opts.Get("start"); !v.IsUndefined() && key < v.String() {
+					continue
+				}
+				if v := opts.Get("startAfter"); !v.IsUndefined() && key <= v.String() {
+					continue
+				}
+				if v := opts.Get("end"); !v.IsUndefined() && key >= v.String() {
+					continue
+				}
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			if opts.Get("reverse").Truthy() {
+				sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+			}
+			if limit := opts.Get("limit"); !limit.IsUndefined() && len(keys) > limit.Int() {
+				keys = keys[:limit.Int()]
+			}
+			return jsMap(keys, s.values)
+		}),
+		"getAlarm": method(func(args []js.Value) any { return s.alarm }),
+		"setAlarm": method(func(args []js.Value) any {
+			s.alarm = args[0]
+			return js.Undefined()
+		}),
+		"deleteAlarm": method(func(args []js.Value) any {
+			s.alarm = js.Null()
+			return js.Undefined()
+		}),
+	})
+}
+
+func newTestStorage(t *testing.T) (*cloudflare.DurableObjectStorage, *fakeStorage) {
+	t.Helper()
+	fake := &fakeStorage{values: map[string]js.Value{}, alarm: js.Null()}
+	runtimeCtxObj := js.ValueOf(map[string]any{
+		"ctx": map[string]any{"storage": fake.object()},
+	})
+	ctx, settle := runtimecontext.NewEvent(js.Undefined(), runtimeCtxObj)
+	t.Cleanup(settle)
+	storage, err := cloudflare.NewDurableObjectStorage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return storage, fake
+}
+
+func TestNewDurableObjectStorage(t *testing.T) {
+	runtimeCtxObj := js.ValueOf(map[string]any{"ctx": map[string]any{}})
+	ctx, settle := runtimecontext.NewEvent(js.Undefined(), runtimeCtxObj)
+	defer settle()
+	if _, err := cloudflare.NewDurableObjectStorage(ctx); err == nil {
+		t.Error("want error for the context of a worker")
+	}
+}
+
+func TestDurableObjectStorage_GetPutDelete(t *testing.T) {
+	storage, fake := newTestStorage(t)
+	type value struct {
+		N    int    `json:"n"`
+		Name string `json:"name"`
+	}
+	var v value
+	if found, err := storage.Get("a", &v); err != nil || found {
+		t.Fatalf("want not found, got %v, %v", found, err)
+	}
+	if err := storage.Put("a", value{N: 1, Name: "one"}); err != nil {
+		t.Fatal(err)
+	}
+	// values are stored as JavaScript values, so objects written in JavaScript can read them.
+	if got := fake.values["a"].Get("name").String(); got != "one" {
+		t.Errorf("want a JavaScript object stored, got name %q", got)
+	}
+	if found, err := storage.Get("a", &v); err != nil || !found || v != (value{N: 1, Name: "one"}) {
+		t.Errorf("want the stored value, got %+v, %v, %v", v, found, err)
+	}
+	if deleted, err := storage.Delete("a"); err != nil || !deleted {
+		t.Errorf("want deleted, got %v, %v", deleted, err)
+	}
+	if deleted, err := storage.Delete("a"); err != nil || deleted {
+		t.Errorf("want not deleted, got %v, %v", deleted, err)
+	}
+}
+
+func TestDurableObjectStorage_Multi(t *testing.T) {
+	storage, fake := newTestStorage(t)
+	const n = 300
+	entries := make(map[string]any, n)
+	keys := make([]string, 0, n+1)
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("key%03d", i)
+		entries[key] = i
+		keys = append(keys, key)
+	}
+	if err := storage.PutMulti(entries); err != nil {
+		t.Fatal(err)
+	}
+	keys = append(keys, "missing")
+	values, err := storage.GetMulti(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != n {
+		t.Errorf("want %d values, got %d", n, len(values))
+	}
+	if got := string(values["key123"]); got != "123" {
+		t.Errorf("want 123, got %s", got)
+	}
+	deleted, err := storage.DeleteMulti(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != n {
+		t.Errorf("want %d deleted, got %d", n, deleted)
+	}
+	want := []int{128, 128, 44, 128, 128, 45, 128, 128, 45}
+	if !reflect.DeepEqual(want, fake.keyCalls) {
+		t.Errorf("want calls chunked by 128 keys %v, got %v", want, fake.keyCalls)
+	}
+}
+
+func TestDurableObjectStorage_List(t *testing.T) {
+	tests := map[string]struct {
+		opts *cloudflare.DurableObjectStorageListOptions
+		want []string
+	}{
+		"all": {
+			want: []string{"a", "b1", "b2", "c"},
+		},
+		"prefix": {
+			opts: &cloudflare.DurableObjectStorageListOptions{Prefix: "b"},
+			want: []string{"b1", "b2"},
+		},
+		"range": {
+			opts: &cloudflare.DurableObjectStorageListOptions{Start: "b1", End: "c"},
+			want: []string{"b1", "b2"},
+		},
+		"start after": {
+			opts: &cloudflare.DurableObjectStorageListOptions{StartAfter: "b1"},
+			want: []string{"b2", "c"},
+		},
+		"reverse with limit": {
+			opts: &cloudflare.DurableObjectStorageListOptions{Reverse: true, Limit: 2},
+			want: []string{"c", "b2"},
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			storage, _ := newTestStorage(t)
+			if err := storage.PutMulti(map[string]any{"a": 1, "b1": 2, "b2": 3, "c": 4}); err != nil {
+				t.Fatal(err)
+			}
+			entries, err := storage.List(tc.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Key)
+			}
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDurableObjectStorage_DeleteAll(t *testing.T) {
+	storage, fake := newTestStorage(t)
+	if err := storage.PutMulti(map[string]any{"a": 1, "b": 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.DeleteAll(); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.values) != 0 {
+		t.Errorf("want no values, got %d", len(fake.values))
+	}
+}
+
+func TestDurableObjectStorage_Alarm(t *testing.T) {
+	storage, _ := newTestStorage(t)
+	if _, ok, err := storage.GetAlarm(); err != nil || ok {
+		t.Fatalf("want no alarm, got %v, %v", ok, err)
+	}
+	at := time.UnixMilli(1700000000000)
+	if err := storage.SetAlarm(at); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok, err := storage.GetAlarm(); err != nil || !ok || !got.Equal(at) {
+		t.Errorf("want alarm at %v, got %v, %v, %v", at, got, ok, err)
+	}
+	if err := storage.DeleteAlarm(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := storage.GetAlarm(); err != nil || ok {
+		t.Errorf("want no alarm, got %v, %v", ok, err)
+	}
+}
@@ -4,12 +4,25 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	"syscall/js"
 
+	"github.com/syumai/workers/cloudflare/internal/cfruntimecontext"
+	"github.com/syumai/workers/internal/js"
 	"github.com/syumai/workers/internal/jshttp"
 	"github.com/syumai/workers/internal/jsutil"
+	"github.com/syumai/workers/internal/runtimecontext"
 )
 
+// DurableObjectNamespaceBinding is the interface implemented by DurableObjectNamespace.
+type DurableObjectNamespaceBinding interface {
+	IdFromName(name string) *DurableObjectId
+	IdFromString(id string) (*DurableObjectId, error)
+	NewUniqueId(opts *DurableObjectNewUniqueIdOptions) *DurableObjectId
+	Get(id *DurableObjectId) (*DurableObjectStub, error)
+	GetWithOptions(id *DurableObjectId, opts *DurableObjectGetOptions) (*DurableObjectStub, error)
+}
+
+var _ DurableObjectNamespaceBinding = (*DurableObjectNamespace)(nil)
+
 // DurableObjectNamespace represents the namespace of the durable object.
 type DurableObjectNamespace struct {
 	instance js.Value
@@ -20,7 +33,7 @@ type DurableObjectNamespace struct {
 // This binding must be defined in the `wrangler.toml` file. The method will
 // return an `error` when there is no binding defined by `varName`.
 func NewDurableObjectNamespace(ctx context.Context, varName string) (*DurableObjectNamespace, error) {
-	inst := getRuntimeContextEnv(ctx).Get(varName)
+	inst := cfruntimecontext.GetRuntimeContextEnv(ctx).Get(varName)
 	if inst.IsUndefined() {
 		return nil, fmt.Errorf("%s is undefined", varName)
 	}
@@ -35,38 +48,198 @@ func (ns *DurableObjectNamespace) IdFromName(name string) *DurableObjectId {
 	return &DurableObjectId{val: id}
 }
 
+// IdFromString parses the string representation of a `DurableObjectId`.
+//
+// Returns an error if the string is not a valid ID of the namespace.
+//
+// https://developers.cloudflare.com/durable-objects/api/namespace/#idfromstring
+func (ns *DurableObjectNamespace) IdFromString(id string) (val *DurableObjectId, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			jsErr, ok := r.(js.Error)
+			if !ok {
+				panic(r)
+			}
+			val, err = nil, fmt.Errorf("invalid durable object id: %s", jsErr.Error())
+		}
+	}()
+	return &DurableObjectId{val: ns.instance.Call("idFromString", id)}, nil
+}
+
+// Jurisdictions restrict durable objects to run and store data only within the region.
+//
+// https://developers.cloudflare.com/durable-objects/reference/data-location/#restrict-durable-objects-to-a-jurisdiction
+const (
+	JurisdictionEU      = "eu"
+	JurisdictionFedRAMP = "fedramp"
+)
+
+// DurableObjectNewUniqueIdOptions represents options of NewUniqueId.
+type DurableObjectNewUniqueIdOptions struct {
+	// Jurisdiction restricts the object to the jurisdiction (e.g. JurisdictionEU).
+	Jurisdiction string
+}
+
+func (opts *DurableObjectNewUniqueIdOptions) toJS() js.Value {
+	if opts == nil {
+		return js.Undefined()
+	}
+	obj := jsutil.NewObject()
+	if opts.Jurisdiction != "" {
+		obj.Set("jurisdiction", opts.Jurisdiction)
+	}
+	return obj
+}
+
+// NewUniqueId returns a new random `DurableObjectId`.
+//
+// https://developers.cloudflare.com/durable-objects/api/namespace/#newuniqueid
+func (ns *DurableObjectNamespace) NewUniqueId(opts *DurableObjectNewUniqueIdOptions) *DurableObjectId {
+	id := ns.instance.Call("newUniqueId", opts.toJS())
+	return &DurableObjectId{val: id}
+}
+
+// Jurisdiction returns the subnamespace restricted to the jurisdiction (e.g. JurisdictionEU).
+//
+// IDs created by the subnamespace, including IdFromName, belong to the jurisdiction.
+//
+// https://developers.cloudflare.com/durable-objects/api/namespace/#jurisdiction
+func (ns *DurableObjectNamespace) Jurisdiction(jurisdiction string) *DurableObjectNamespace {
+	return &DurableObjectNamespace{instance: ns.instance.Call("jurisdiction", jurisdiction)}
+}
+
+// Location hints of durable objects. The object is created near the location on the first access.
+//
+// https://developers.cloudflare.com/durable-objects/reference/data-location/#provide-a-location-hint
+const (
+	LocationHintWesternNorthAmerica = "wnam"
+	LocationHintEasternNorthAmerica = "enam"
+	LocationHintSouthAmerica        = "sam"
+	LocationHintWesternEurope       = "weur"
+	LocationHintEasternEurope       = "eeur"
+	LocationHintAsiaPacific         = "apac"
+	LocationHintOceania             = "oc"
+	LocationHintAfrica              = "afr"
+	LocationHintMiddleEast          = "me"
+)
+
+// DurableObjectGetOptions represents options of GetWithOptions.
+type DurableObjectGetOptions struct {
+	// LocationHint is the location where the object is created (e.g. LocationHintWesternEurope).
+	// It is ignored if the object already exists.
+	LocationHint string
+}
+
+func (opts *DurableObjectGetOptions) toJS() js.Value {
+	if opts == nil {
+		return js.Undefined()
+	}
+	obj := jsutil.NewObject()
+	if opts.LocationHint != "" {
+		obj.Set("locationHint", opts.LocationHint)
+	}
+	return obj
+}
+
 // Get obtains the durable object stub for `id`.
 //
 // https://developers.cloudflare.com/workers/runtime-apis/durable-objects/#obtaining-an-object-stub
 func (ns *DurableObjectNamespace) Get(id *DurableObjectId) (*DurableObjectStub, error) {
+	return ns.GetWithOptions(id, nil)
+}
+
+// GetWithOptions obtains the durable object stub for `id` with options such as the location hint.
+//
+// https://developers.cloudflare.com/durable-objects/api/namespace/#get
+func (ns *DurableObjectNamespace) GetWithOptions(id *DurableObjectId, opts *DurableObjectGetOptions) (*DurableObjectStub, error) {
 	if id == nil || id.val.IsUndefined() {
 		return nil, fmt.Errorf("invalid UniqueGlobalId")
 	}
-	stub := ns.instance.Call("get", id.val)
+	stub := ns.instance.Call("get", id.val, opts.toJS())
 	return &DurableObjectStub{val: stub}, nil
 }
 
 // DurableObjectId represents an identifier for a durable object.
 type DurableObjectId struct {
 	val js.Value
+	// str is the string representation of the id created by NewDurableObjectIdString.
+	str string
+}
+
+// NewDurableObjectIdString returns an id represented by the given string instead of an id of the runtime.
+//
+// This is intended for test doubles of DurableObjectNamespaceBinding.
+func NewDurableObjectIdString(s string) *DurableObjectId {
+	return &DurableObjectId{val: js.Undefined(), str: s}
+}
+
+// String returns the string representation of the id, which can be parsed by IdFromString.
+//
+// https://developers.cloudflare.com/durable-objects/api/id/#tostring
+func (id *DurableObjectId) String() string {
+	if id.val.IsUndefined() {
+		return id.str
+	}
+	return id.val.Call("toString").String()
+}
+
+// Equals reports whether the ids identify the same durable object.
+//
+// https://developers.cloudflare.com/durable-objects/api/id/#equals
+func (id *DurableObjectId) Equals(other *DurableObjectId) bool {
+	if other == nil {
+		return false
+	}
+	if id.val.IsUndefined() || other.val.IsUndefined() {
+		return id.String() == other.String()
+	}
+	return id.val.Call("equals", other.val).Bool()
+}
+
+// Name returns the name of the id created by IdFromName. Otherwise, returns an empty string.
+//
+// https://developers.cloudflare.com/durable-objects/api/id/#name
+func (id *DurableObjectId) Name() string {
+	if id.val.IsUndefined() {
+		return ""
+	}
+	name := id.val.Get("name")
+	if name.IsUndefined() || name.IsNull() {
+		return ""
+	}
+	return name.String()
 }
 
 // DurableObjectStub represents the stub to communicate with the durable object.
 type DurableObjectStub struct {
 	val js.Value
+	// fetch is called instead of the durable object if it is set.
+	fetch func(req *http.Request) (*http.Response, error)
+}
+
+// NewDurableObjectStubFunc returns a stub calling the given function instead of a durable object.
+//
+// This is intended for test doubles of DurableObjectNamespaceBinding.
+func NewDurableObjectStubFunc(fetch func(req *http.Request) (*http.Response, error)) *DurableObjectStub {
+	return &DurableObjectStub{fetch: fetch}
 }
 
 // Fetch calls the durable objects `fetch()` method.
 //
 // https://developers.cloudflare.com/workers/runtime-apis/durable-objects/#sending-http-requests
 func (s *DurableObjectStub) Fetch(req *http.Request) (*http.Response, error) {
-	jsReq := jshttp.ToJSRequest(req)
+	if s.fetch != nil {
+		return s.fetch(req)
+	}
+	jsReq, release := jshttp.ToJSRequest(req)
+	runtimecontext.SubrequestsFrom(req.Context()).Add()
 
 	promise := s.val.Call("fetch", jsReq)
 	jsRes, err := jsutil.AwaitPromise(promise)
 	if err != nil {
+		release()
 		return nil, err
 	}
 
-	return jshttp.ToResponse(jsRes)
+	return jshttp.ToFetchResponse(jsRes, release)
 }
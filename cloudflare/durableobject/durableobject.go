@@ -0,0 +1,130 @@
+// Package durableobject serves Durable Object classes written in Go.
+//   - https://developers.cloudflare.com/durable-objects/
+//   - all objects of the class in the isolate share the Go program, so their state must be kept in
+//     cloudflare.NewDurableObjectStorage(ctx), or keyed by ID(ctx).
+//   - requests are handled in goroutines which may interleave between storage operations,
+//     so read-modify-write must be guarded by the handler (e.g. a mutex keyed by ID(ctx)).
+//
+// The class is declared in the JavaScript entry point, and forwards requests and alarms to the handlers registered by Handle and HandleAlarm:
+//
+//	export class Counter {
+//	  constructor(state, env) {
+//	    this.state = state;
+//	    this.env = env;
+//	  }
+//	  async fetch(req) {
+//	    await load;
+//	    return handleDurableObjectRequest("Counter", req, { env: this.env, ctx: this.state });
+//	  }
+//	  async alarm() {
+//	    await load;
+//	    return handleDurableObjectAlarm("Counter", { env: this.env, ctx: this.state });
+//	  }
+//	}
+package durableobject
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/syumai/workers/cloudflare/internal/cfruntimecontext"
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jshttp"
+	"github.com/syumai/workers/internal/jsutil"
+	"github.com/syumai/workers/internal/runtimecontext"
+)
+
+// AlarmHandler handles the alarm of an object, which is set by cloudflare.DurableObjectStorage.SetAlarm.
+//   - if the handler returns error, the runtime retries the alarm.
+//   - https://developers.cloudflare.com/durable-objects/api/alarms/
+type AlarmHandler func(ctx context.Context) error
+
+var (
+	mu       sync.RWMutex
+	handlers = map[string]http.Handler{}
+	alarms   = map[string]AlarmHandler{}
+)
+
+func init() {
+	var handleRequestCallback js.Func
+	handleRequestCallback = js.FuncOf(func(_ js.Value, args []js.Value) any {
+		if len(args) != 3 {
+			panic(fmt.Errorf("handleDurableObjectRequest takes 3 args, but %d given", len(args)))
+		}
+		className, reqObj, runtimeCtxObj := args[0].String(), args[1], args[2]
+		var cb js.Func
+		cb = js.FuncOf(func(_ js.Value, pArgs []js.Value) any {
+			defer cb.Release()
+			resolve := pArgs[0]
+			reject := pArgs[1]
+			go func() {
+				res, err := handleRequest(className, reqObj, runtimeCtxObj)
+				if err != nil {
+					reject.Invoke(jsutil.ErrorClass.New(err.Error()))
+					return
+				}
+				resolve.Invoke(res)
+			}()
+			return js.Undefined()
+		})
+		return jsutil.NewPromise(cb)
+	})
+	jsutil.Global.Set("handleDurableObjectRequest", handleRequestCallback)
+	runtimecontext.RegisterHandler("handleDurableObjectAlarm", handleAlarm)
+}
+
+// Handle registers the handler of requests to objects of the class.
+// It must be called before workers.Serve or workers.Start.
+//   - the storage of the object can be obtained by cloudflare.NewDurableObjectStorage with the context of the request.
+//   - if a handler is already registered for the class, it panics.
+func Handle(className string, h http.Handler) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := handlers[className]; ok {
+		panic(fmt.Sprintf("durableobject: multiple handlers registered for %q", className))
+	}
+	handlers[className] = h
+}
+
+// HandleAlarm registers the handler of alarms of objects of the class.
+// It must be called before workers.Serve or workers.Start.
+//   - if a handler is already registered for the class, it panics.
+func HandleAlarm(className string, h AlarmHandler) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := alarms[className]; ok {
+		panic(fmt.Sprintf("durableobject: multiple alarm handlers registered for %q", className))
+	}
+	alarms[className] = h
+}
+
+// handleRequest serves the Request object by the handler of the class, and returns the Response object.
+func handleRequest(className string, reqObj, runtimeCtxObj js.Value) (js.Value, error) {
+	mu.RLock()
+	h, ok := handlers[className]
+	mu.RUnlock()
+	if !ok {
+		return js.Value{}, fmt.Errorf("durableobject: no handler registered for %q", className)
+	}
+	return jshttp.Serve(h, reqObj, runtimeCtxObj, nil)
+}
+
+// handleAlarm calls the alarm handler of the class given as the event.
+func handleAlarm(ctx context.Context, eventObj js.Value) error {
+	className := eventObj.String()
+	mu.RLock()
+	h, ok := alarms[className]
+	mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("durableobject: no alarm handler registered for %q", className)
+	}
+	return h(ctx)
+}
+
+// ID returns the string representation of the id of the object handling the event of ctx.
+//   - This function panics when a runtime context is not found.
+func ID(ctx context.Context) string {
+	return cfruntimecontext.GetExecutionContext(ctx).Get("id").Call("toString").String()
+}
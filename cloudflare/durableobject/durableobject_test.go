@@ -0,0 +1,68 @@
+package durableobject
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/runtimecontext"
+)
+
+// newState returns the runtime context of an object whose id is id.
+func newState(id string) js.Value {
+	return js.ValueOf(map[string]any{
+		"env": map[string]any{},
+		"ctx": map[string]any{
+			"id": map[string]any{
+				"toString": js.FuncOf(func(js.Value, []js.Value) any { return id }),
+			},
+		},
+	})
+}
+
+func TestHandleAlarm(t *testing.T) {
+	errFailed := errors.New("failed")
+	var ids []string
+	HandleAlarm("TestAlarm", func(ctx context.Context) error {
+		ids = append(ids, ID(ctx))
+		return nil
+	})
+	HandleAlarm("TestAlarmFailure", func(ctx context.Context) error {
+		return errFailed
+	})
+	defer func() {
+		delete(alarms, "TestAlarm")
+		delete(alarms, "TestAlarmFailure")
+	}()
+
+	if err := runtimecontext.Dispatch("handleDurableObjectAlarm", js.ValueOf("TestAlarm"), newState("abc")); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != "abc" {
+		t.Errorf("want the alarm of abc handled, got %v", ids)
+	}
+	if err := runtimecontext.Dispatch("handleDurableObjectAlarm", js.ValueOf("TestAlarmFailure"), newState("abc")); !errors.Is(err, errFailed) {
+		t.Errorf("want %v, got %v", errFailed, err)
+	}
+	if err := runtimecontext.Dispatch("handleDurableObjectAlarm", js.ValueOf("Unknown"), newState("abc")); err == nil {
+		t.Error("want error for the class without an alarm handler")
+	}
+}
+
+func TestHandle_Duplicate(t *testing.T) {
+	Handle("TestDuplicate", nil)
+	defer delete(handlers, "TestDuplicate")
+	defer func() {
+		if recover() == nil {
+			t.Error("want panic for the class already registered")
+		}
+	}()
+	Handle("TestDuplicate", nil)
+}
+
+func TestHandleRequest_Unknown(t *testing.T) {
+	if _, err := handleRequest("Unknown", js.Undefined(), newState("abc")); err == nil {
+		t.Error("want error for the class without a handler")
+	}
+}
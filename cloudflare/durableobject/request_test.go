@@ -0,0 +1,37 @@
+//go:build js && wasm
+
+package durableobject
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jsutil"
+)
+
+func TestHandle(t *testing.T) {
+	Handle("TestCounter", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-Object", ID(req.Context()))
+		io.WriteString(w, req.URL.Path)
+	}))
+	defer delete(handlers, "TestCounter")
+
+	reqObj := jsutil.RequestClass.New("https://example.com/increment")
+	promise := js.Global().Call("handleDurableObjectRequest", "TestCounter", reqObj, newState("abc"))
+	res, err := jsutil.AwaitPromise(promise)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.Get("headers").Call("get", "X-Object").String(); got != "abc" {
+		t.Errorf("want the id of the object, got %q", got)
+	}
+	body, err := jsutil.AwaitPromise(res.Call("text"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := body.String(); got != "/increment" {
+		t.Errorf("want the path of the request, got %q", got)
+	}
+}
@@ -0,0 +1,84 @@
+// Package email handles incoming emails of Email Routing.
+//   - https://developers.cloudflare.com/email-routing/email-workers/runtime-api/
+//   - raw messages are parsed by ParseMessage, and MIME messages with attachments are built by Message.WriteTo
+//     using only the standard library.
+package email
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jshttp"
+	"github.com/syumai/workers/internal/jsutil"
+	"github.com/syumai/workers/internal/runtimecontext"
+)
+
+// ForwardableEmailMessage represents an incoming email.
+//   - https://developers.cloudflare.com/email-routing/email-workers/runtime-api/#forwardableemailmessage-definition
+type ForwardableEmailMessage struct {
+	// From is the envelope From address.
+	From string
+	// To is the envelope To address.
+	To string
+	// Header is the header of the message.
+	Header http.Header
+	// Raw is the raw content of the message. It can be read only once.
+	Raw io.ReadCloser
+	// RawSize is the size of the raw content in bytes.
+	RawSize int
+
+	instance js.Value
+}
+
+func toForwardableEmailMessage(v js.Value) *ForwardableEmailMessage {
+	return &ForwardableEmailMessage{
+		From:     v.Get("from").String(),
+		To:       v.Get("to").String(),
+		Header:   jshttp.ToHeader(v.Get("headers")),
+		Raw:      jshttp.ToBody(v.Get("raw")),
+		RawSize:  v.Get("rawSize").Int(),
+		instance: v,
+	}
+}
+
+// SetReject rejects the message with the reason, which is returned to the sender as a permanent SMTP error.
+func (m *ForwardableEmailMessage) SetReject(reason string) {
+	m.instance.Call("setReject", reason)
+}
+
+// Forward forwards the message to the verified destination address.
+//   - header holds extra headers added to the message. Only X-* headers are allowed.
+func (m *ForwardableEmailMessage) Forward(rcptTo string, header http.Header) error {
+	headers := js.Undefined()
+	if header != nil {
+		headers = jshttp.ToJSHeader(header)
+	}
+	_, err := jsutil.AwaitPromise(m.instance.Call("forward", rcptTo, headers))
+	return err
+}
+
+// Handler handles an incoming email.
+//   - if the handler returns error, the message is rejected by the error.
+//   - bindings can be obtained from ctx as well as handlers of requests (e.g. cloudflare.NewKVNamespace(ctx, ...)).
+type Handler func(ctx context.Context, msg *ForwardableEmailMessage) error
+
+// RegisterEmail registers the handler by Handle, so it can be given to workers.Options as the Email handler.
+func (h Handler) RegisterEmail() {
+	Handle(h)
+}
+
+// Handle registers the handler of incoming emails. It must be called before workers.Serve or workers.Start.
+// The JavaScript entry point must export the email handler calling `handleEmail`:
+//
+//	async email(message, env, ctx) {
+//	  await load;
+//	  await readyPromise;
+//	  return handleEmail(message, { env, ctx });
+//	}
+func Handle(h Handler) {
+	runtimecontext.RegisterHandler("handleEmail", func(ctx context.Context, msgObj js.Value) error {
+		return h(ctx, toForwardableEmailMessage(msgObj))
+	})
+}
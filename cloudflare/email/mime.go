@@ -0,0 +1,370 @@
+package email
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+// Message is a MIME message, parsed by ParseMessage or built by WriteTo.
+//   - text/html bodies and attachments are decoded from transfer encodings (base64 and quoted-printable).
+type Message struct {
+	// Header is the header of the message. Addresses can be parsed by mail.ParseAddressList(Header.Get("To")).
+	//   - in WriteTo, MIME headers (Content-Type, Content-Transfer-Encoding and MIME-Version) are replaced.
+	Header http.Header
+	// Subject is the subject decoded from encoded-words. In WriteTo, it overrides Subject header.
+	Subject string
+	// Text is the text/plain body.
+	Text string
+	// HTML is the text/html body.
+	HTML string
+	// Attachments are non-body parts, including inline images and nested messages.
+	Attachments []*Attachment
+}
+
+// Attachment is an attached file of a message.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	// ContentID is the ID of inline attachments referred from HTML as "cid:" URLs, without angle brackets.
+	ContentID string
+	// Inline reports whether the attachment is displayed inline (Content-Disposition: inline).
+	Inline bool
+	// Content is the decoded content of the attachment.
+	Content io.Reader
+	// Size is the size of the decoded content in parsed messages.
+	Size int
+}
+
+var wordDecoder = &mime.WordDecoder{}
+
+// ParseMessage parses the raw message (RFC 5322 and MIME).
+//   - the first text/plain and text/html parts which are not attachments are the bodies. Other parts are attachments.
+//   - bodies in UTF-8, US-ASCII and ISO-8859-1 are converted into UTF-8. Bodies in other charsets are returned as they are.
+func ParseMessage(r io.Reader) (*Message, error) {
+	msg, err := mail.ReadMessage(bufio.NewReader(r))
+	if err != nil {
+		return nil, fmt.Errorf("email: error reading message: %w", err)
+	}
+	header := http.Header(msg.Header)
+	m := &Message{Header: header, Subject: decodeWords(header.Get("Subject"))}
+	if err := m.parsePart(textproto.MIMEHeader(header), msg.Body); err != nil {
+		return nil, fmt.Errorf("email: error parsing message: %w", err)
+	}
+	return m, nil
+}
+
+// Parse reads the raw content of the incoming message, and parses it by ParseMessage.
+func (m *ForwardableEmailMessage) Parse() (*Message, error) {
+	defer m.Raw.Close()
+	return ParseMessage(m.Raw)
+}
+
+// parsePart parses the part into bodies and attachments of the message.
+func (m *Message) parsePart(h textproto.MIMEHeader, body io.Reader) error {
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := m.parsePart(p.Header, p); err != nil {
+				return err
+			}
+		}
+	}
+	body = decodeTransfer(h.Get("Content-Transfer-Encoding"), body)
+	disposition, dispParams, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+	filename := dispParams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+	if disposition != "attachment" && filename == "" {
+		switch {
+		case mediaType == "text/plain" && m.Text == "":
+			text, err := readText(body, params["charset"])
+			m.Text = text
+			return err
+		case mediaType == "text/html" && m.HTML == "":
+			html, err := readText(body, params["charset"])
+			m.HTML = html
+			return err
+		}
+	}
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	m.Attachments = append(m.Attachments, &Attachment{
+		Filename:    decodeWords(filename),
+		ContentType: mediaType,
+		ContentID:   strings.Trim(h.Get("Content-ID"), "<>"),
+		Inline:      disposition == "inline",
+		Content:     bytes.NewReader(content),
+		Size:        len(content),
+	})
+	return nil
+}
+
+func decodeTransfer(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
+
+// readText reads the text in the charset as UTF-8.
+func readText(r io.Reader, charset string) (string, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	if strings.EqualFold(charset, "iso-8859-1") || strings.EqualFold(charset, "latin1") {
+		runes := make([]rune, len(b))
+		for i, c := range b {
+			runes[i] = rune(c)
+		}
+		return string(runes), nil
+	}
+	return string(b), nil
+}
+
+// decodeWords decodes encoded-words (RFC 2047) of the header value. If it fails, the value is returned as it is.
+func decodeWords(s string) string {
+	decoded, err := wordDecoder.DecodeHeader(s)
+	if err != nil {
+		return s
+	}
+	return decoded
+}
+
+// NewReply returns a message replying to the parent message.
+//   - To is Reply-To (or From) of the parent, and Subject is prefixed with "Re: ".
+//   - In-Reply-To and References headers thread the reply to the parent.
+func NewReply(parent *Message) *Message {
+	h := http.Header{}
+	to := parent.Header.Get("Reply-To")
+	if to == "" {
+		to = parent.Header.Get("From")
+	}
+	h.Set("To", to)
+	if id := parent.Header.Get("Message-Id"); id != "" {
+		h.Set("In-Reply-To", id)
+		h.Set("References", strings.TrimSpace(parent.Header.Get("References")+" "+id))
+	}
+	subject := parent.Subject
+	if !strings.HasPrefix(strings.ToLower(subject), "re:") {
+		subject = "Re: " + subject
+	}
+	return &Message{Header: h, Subject: subject}
+}
+
+// WriteTo writes the message in MIME format to w.
+//   - text and HTML bodies are encoded by quoted-printable in UTF-8. If both are set, they are sent as multipart/alternative.
+//   - attachments are encoded by base64, and sent in multipart/mixed with the bodies.
+//   - Date and Message-ID headers are not generated. The runtime requires them for sending messages.
+func (m *Message) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+	header := m.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	if m.Subject != "" {
+		header.Set("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	}
+	body := m.bodyPart()
+	header.Del("Content-Transfer-Encoding")
+	for k, v := range body.header {
+		header[k] = v
+	}
+	header.Set("MIME-Version", "1.0")
+	if err := header.Write(cw); err != nil {
+		return cw.n, err
+	}
+	if _, err := io.WriteString(cw, "\r\n"); err != nil {
+		return cw.n, err
+	}
+	err := body.write(cw)
+	return cw.n, err
+}
+
+// Bytes returns the message in MIME format. See WriteTo.
+func (m *Message) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := m.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// part is a MIME entity to be written.
+type part struct {
+	header textproto.MIMEHeader
+	write  func(w io.Writer) error
+}
+
+// bodyPart returns the part of the body including the attachments.
+func (m *Message) bodyPart() *part {
+	if len(m.Attachments) == 0 {
+		return m.alternativePart()
+	}
+	parts := make([]*part, 0, len(m.Attachments)+1)
+	if m.Text != "" || m.HTML != "" {
+		parts = append(parts, m.alternativePart())
+	}
+	for _, a := range m.Attachments {
+		parts = append(parts, a.part())
+	}
+	return multipartPart("multipart/mixed", parts)
+}
+
+// alternativePart returns the part of the text and HTML bodies.
+func (m *Message) alternativePart() *part {
+	switch {
+	case m.HTML == "":
+		return textPart("text/plain", m.Text)
+	case m.Text == "":
+		return textPart("text/html", m.HTML)
+	}
+	return multipartPart("multipart/alternative", []*part{
+		textPart("text/plain", m.Text),
+		textPart("text/html", m.HTML),
+	})
+}
+
+func multipartPart(mediaType string, parts []*part) *part {
+	boundary := multipart.NewWriter(io.Discard).Boundary()
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", mime.FormatMediaType(mediaType, map[string]string{"boundary": boundary}))
+	return &part{header: h, write: func(w io.Writer) error {
+		mw := multipart.NewWriter(w)
+		if err := mw.SetBoundary(boundary); err != nil {
+			return err
+		}
+		for _, p := range parts {
+			pw, err := mw.CreatePart(p.header)
+			if err != nil {
+				return err
+			}
+			if err := p.write(pw); err != nil {
+				return err
+			}
+		}
+		return mw.Close()
+	}}
+}
+
+func textPart(contentType, body string) *part {
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", contentType+"; charset=utf-8")
+	h.Set("Content-Transfer-Encoding", "quoted-printable")
+	return &part{header: h, write: func(w io.Writer) error {
+		qw := quotedprintable.NewWriter(w)
+		if _, err := io.WriteString(qw, body); err != nil {
+			return err
+		}
+		return qw.Close()
+	}}
+}
+
+func (a *Attachment) part() *part {
+	contentType := a.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	disposition := "attachment"
+	if a.Inline {
+		disposition = "inline"
+	}
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", contentType)
+	h.Set("Content-Transfer-Encoding", "base64")
+	if a.Filename != "" {
+		h.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": a.Filename}))
+	} else {
+		h.Set("Content-Disposition", disposition)
+	}
+	if a.ContentID != "" {
+		h.Set("Content-ID", "<"+a.ContentID+">")
+	}
+	return &part{header: h, write: func(w io.Writer) error {
+		lw := &lineWriter{w: w}
+		enc := base64.NewEncoder(base64.StdEncoding, lw)
+		if a.Content != nil {
+			if _, err := io.Copy(enc, a.Content); err != nil {
+				return fmt.Errorf("email: error reading attachment %s: %w", a.Filename, err)
+			}
+		}
+		if err := enc.Close(); err != nil {
+			return err
+		}
+		return lw.Close()
+	}}
+}
+
+// lineWriter wraps lines of base64 at 76 characters (RFC 2045).
+type lineWriter struct {
+	w   io.Writer
+	col int
+}
+
+const maxLineLength = 76
+
+func (w *lineWriter) Write(b []byte) (int, error) {
+	n := 0
+	for len(b) > 0 {
+		chunk := b[:min(len(b), maxLineLength-w.col)]
+		if _, err := w.w.Write(chunk); err != nil {
+			return n, err
+		}
+		n += len(chunk)
+		w.col += len(chunk)
+		b = b[len(chunk):]
+		if w.col == maxLineLength {
+			if _, err := io.WriteString(w.w, "\r\n"); err != nil {
+				return n, err
+			}
+			w.col = 0
+		}
+	}
+	return n, nil
+}
+
+func (w *lineWriter) Close() error {
+	if w.col == 0 {
+		return nil
+	}
+	_, err := io.WriteString(w.w, "\r\n")
+	return err
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countWriter) Write(b []byte) (int, error) {
+	n, err := w.w.Write(b)
+	w.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,126 @@
+package email
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParseMessage(t *testing.T) {
+	tests := map[string]struct {
+		raw             string
+		wantSubject     string
+		wantText        string
+		wantHTML        string
+		wantAttachments []string
+	}{
+		"plain text": {
+			raw:         "From: a@example.com\r\nSubject: hello\r\n\r\nhi there\r\n",
+			wantSubject: "hello",
+			wantText:    "hi there\r\n",
+		},
+		"encoded subject and latin1 quoted-printable": {
+			raw: "Subject: =?UTF-8?Q?caf=C3=A9?=\r\n" +
+				"Content-Type: text/plain; charset=iso-8859-1\r\n" +
+				"Content-Transfer-Encoding: quoted-printable\r\n\r\n" +
+				"caf=E9 au lait",
+			wantSubject: "café",
+			wantText:    "café au lait",
+		},
+		"alternative with attachment": {
+			raw: "Subject: report\r\n" +
+				"Content-Type: multipart/mixed; boundary=outer\r\n\r\n" +
+				"--outer\r\n" +
+				"Content-Type: multipart/alternative; boundary=inner\r\n\r\n" +
+				"--inner\r\n" +
+				"Content-Type: text/plain\r\n\r\n" +
+				"see attached\r\n" +
+				"--inner\r\n" +
+				"Content-Type: text/html\r\n\r\n" +
+				"<p>see attached</p>\r\n" +
+				"--inner--\r\n" +
+				"--outer\r\n" +
+				"Content-Type: text/csv\r\n" +
+				"Content-Disposition: attachment; filename*=UTF-8''r%C3%A9sum%C3%A9.csv\r\n" +
+				"Content-Transfer-Encoding: base64\r\n\r\n" +
+				"YSxiCjEs\r\nMgo=\r\n" +
+				"--outer--\r\n",
+			wantSubject:     "report",
+			wantText:        "see attached",
+			wantHTML:        "<p>see attached</p>",
+			wantAttachments: []string{"résumé.csv text/csv a,b\n1,2\n"},
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			m, err := ParseMessage(strings.NewReader(tc.raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.Subject != tc.wantSubject {
+				t.Errorf("want subject %q, got %q", tc.wantSubject, m.Subject)
+			}
+			if m.Text != tc.wantText {
+				t.Errorf("want text %q, got %q", tc.wantText, m.Text)
+			}
+			if m.HTML != tc.wantHTML {
+				t.Errorf("want HTML %q, got %q", tc.wantHTML, m.HTML)
+			}
+			var got []string
+			for _, a := range m.Attachments {
+				b, _ := io.ReadAll(a.Content)
+				got = append(got, a.Filename+" "+a.ContentType+" "+string(b))
+			}
+			if strings.Join(got, "|") != strings.Join(tc.wantAttachments, "|") {
+				t.Errorf("want attachments %q, got %q", tc.wantAttachments, got)
+			}
+		})
+	}
+}
+
+func TestMessage_WriteTo(t *testing.T) {
+	parent := &Message{
+		Header:  http.Header{"From": {"a@example.com"}, "Message-Id": {"<1@example.com>"}},
+		Subject: "question",
+	}
+	reply := NewReply(parent)
+	reply.Header.Set("From", "b@example.com")
+	reply.Text = "answer with a long line " + strings.Repeat("é", 80)
+	reply.HTML = "<p>answer</p>"
+	content := bytes.Repeat([]byte{0, 1, 2, 255}, 100)
+	reply.Attachments = []*Attachment{{Filename: "data.bin", Content: bytes.NewReader(content)}}
+
+	raw, err := reply.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(string(raw), "\r\n") {
+		if len(line) > 998 {
+			t.Fatalf("line exceeds 998 characters: %q", line)
+		}
+	}
+	got, err := ParseMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Subject != "Re: question" || got.Header.Get("To") != "a@example.com" ||
+		got.Header.Get("In-Reply-To") != "<1@example.com>" || got.Header.Get("References") != "<1@example.com>" {
+		t.Errorf("unexpected header: %q %v", got.Subject, got.Header)
+	}
+	if got.Text != reply.Text || got.HTML != reply.HTML {
+		t.Errorf("want bodies %q %q, got %q %q", reply.Text, reply.HTML, got.Text, got.HTML)
+	}
+	if len(got.Attachments) != 1 {
+		t.Fatalf("want 1 attachment, got %d", len(got.Attachments))
+	}
+	a := got.Attachments[0]
+	b, _ := io.ReadAll(a.Content)
+	if a.Filename != "data.bin" || a.ContentType != "application/octet-stream" || !bytes.Equal(b, content) {
+		t.Errorf("unexpected attachment %q %q %v", a.Filename, a.ContentType, b)
+	}
+}
@@ -12,3 +12,15 @@ import (
 func Getenv(ctx context.Context, name string) string {
 	return cfruntimecontext.GetRuntimeContextEnv(ctx).Get(name).String()
 }
+
+// LookupEnv gets a value of an environment variable.
+// If the variable is not bound to the worker, the returned bool is false.
+//   - https://developers.cloudflare.com/workers/platform/environment-variables/
+//   - This function panics when a runtime context is not found.
+func LookupEnv(ctx context.Context, name string) (string, bool) {
+	v := cfruntimecontext.GetRuntimeContextEnv(ctx).Get(name)
+	if v.IsUndefined() || v.IsNull() {
+		return "", false
+	}
+	return v.String(), true
+}
@@ -0,0 +1,223 @@
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Origin is a backend of Balancer.
+type Origin struct {
+	// URL is the base URL of the origin (e.g. "https://eu.example.com/api").
+	// The scheme and the host of requests are replaced by it, and its path is prepended to paths of requests.
+	URL string
+	// Weight is the relative weight of the origin in weighted distribution. Defaults to 1.
+	Weight int
+}
+
+// BalancerOptions represents options of NewBalancer.
+type BalancerOptions struct {
+	// Weighted distributes requests to origins randomly by their weights.
+	// Otherwise, origins are tried in the given order, so the first healthy origin receives all requests.
+	Weighted bool
+	// FailureThreshold is the number of consecutive failures marking the origin unhealthy. Defaults to 3.
+	FailureThreshold int
+	// Cooldown is how long unhealthy origins are skipped. After that, a single trial request is sent. Defaults to 30s.
+	Cooldown time.Duration
+	// FailoverStatuses are status codes counted as failures and failed over. Defaults to 502, 503 and 504.
+	FailoverStatuses []int
+	// MaxAttempts is the maximum number of origins tried for a request. Defaults to the number of origins.
+	MaxAttempts int
+}
+
+// Balancer is Fetcher distributing requests to origins, failing over to the next origin when one fails.
+//   - failures are network errors and FailoverStatuses. Origins are marked unhealthy after FailureThreshold
+//     consecutive failures, and skipped for Cooldown.
+//   - the health is tracked in memory of the isolate, so the Balancer should be shared by requests (e.g. a package level variable).
+//   - only idempotent requests (see RetryPolicy) are failed over. Other requests are sent to a single origin.
+//   - if all origins are unhealthy, they are tried anyway in order.
+type Balancer struct {
+	fetcher  Fetcher
+	origins  []*balancerOrigin
+	opts     BalancerOptions
+	statuses []int
+}
+
+type balancerOrigin struct {
+	url     *url.URL
+	weight  int
+	breaker *breaker
+}
+
+var (
+	_ Fetcher           = (*Balancer)(nil)
+	_ http.RoundTripper = (*Balancer)(nil)
+)
+
+// NewBalancer returns Balancer sending requests to the origins by the fetcher. If fetcher is nil, NewClient() is used.
+//   - if no origin is given, or a URL of the origins is invalid, returns error.
+func NewBalancer(fetcher Fetcher, origins []*Origin, opts *BalancerOptions) (*Balancer, error) {
+	if len(origins) == 0 {
+		return nil, errors.New("fetch: no origin is given")
+	}
+	if fetcher == nil {
+		fetcher = NewClient()
+	}
+	b := &Balancer{fetcher: fetcher}
+	if opts != nil {
+		b.opts = *opts
+	}
+	breakerOpts := &BreakerOptions{FailureThreshold: b.opts.FailureThreshold, OpenDuration: b.opts.Cooldown}
+	if breakerOpts.FailureThreshold <= 0 {
+		breakerOpts.FailureThreshold = 3
+	}
+	if breakerOpts.OpenDuration <= 0 {
+		breakerOpts.OpenDuration = 30 * time.Second
+	}
+	b.statuses = b.opts.FailoverStatuses
+	if b.statuses == nil {
+		b.statuses = []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+	}
+	for _, o := range origins {
+		u, err := url.Parse(o.URL)
+		if err != nil {
+			return nil, fmt.Errorf("fetch: invalid origin %q: %w", o.URL, err)
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("fetch: origin %q must be an absolute URL", o.URL)
+		}
+		b.origins = append(b.origins, &balancerOrigin{
+			url:     u,
+			weight:  max(o.Weight, 1),
+			breaker: &breaker{opts: breakerOpts},
+		})
+	}
+	return b, nil
+}
+
+// Do sends the request to origins until one succeeds.
+//   - the response of the last attempt is returned even if its status is a failover status.
+func (b *Balancer) Do(req *http.Request) (*http.Response, error) {
+	attempts := len(b.origins)
+	if b.opts.MaxAttempts > 0 {
+		attempts = min(attempts, b.opts.MaxAttempts)
+	}
+	if !retryable(req) {
+		attempts = 1
+	}
+	order := b.order()
+	var res *http.Response
+	var err error
+	tried := 0
+	// try sends the request to the origin, and reports whether the attempts should stop.
+	try := func(o *balancerOrigin) bool {
+		if res != nil {
+			io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+			res.Body.Close()
+		}
+		res, err = b.send(req, o, tried)
+		tried++
+		failed := err != nil || slices.Contains(b.statuses, res.StatusCode)
+		o.breaker.record(!failed)
+		return !failed || tried >= attempts || req.Context().Err() != nil
+	}
+	for _, o := range order {
+		// allow is called just before sending, so half-open origins receive only one trial request.
+		if o.breaker.allow() && try(o) {
+			return res, err
+		}
+	}
+	if tried == 0 {
+		// all origins are unhealthy, so they are tried anyway.
+		for _, o := range order {
+			if try(o) {
+				break
+			}
+		}
+	}
+	return res, err
+}
+
+// order returns the origins in the order of priority, or in a random order by weights if Weighted is set.
+func (b *Balancer) order() []*balancerOrigin {
+	if !b.opts.Weighted {
+		return b.origins
+	}
+	remaining := slices.Clone(b.origins)
+	order := make([]*balancerOrigin, 0, len(remaining))
+	for len(remaining) > 0 {
+		total := 0
+		for _, o := range remaining {
+			total += o.weight
+		}
+		r := rand.Intn(total)
+		for i, o := range remaining {
+			r -= o.weight
+			if r < 0 {
+				order = append(order, o)
+				remaining = slices.Delete(remaining, i, i+1)
+				break
+			}
+		}
+	}
+	return order
+}
+
+// send sends a clone of the request to the origin.
+func (b *Balancer) send(req *http.Request, o *balancerOrigin, attempt int) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	if attempt > 0 && req.Body != nil && req.Body != http.NoBody {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		out.Body = body
+	}
+	out.URL.Scheme = o.url.Scheme
+	out.URL.Host = o.url.Host
+	if prefix := strings.TrimSuffix(o.url.Path, "/"); prefix != "" {
+		out.URL.Path = prefix + req.URL.Path
+		out.URL.RawPath = ""
+	}
+	out.Host = ""
+	return b.fetcher.Do(out)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (b *Balancer) RoundTrip(req *http.Request) (*http.Response, error) {
+	return b.Do(req)
+}
+
+// HTTPClient returns *http.Client using the Balancer as Transport.
+func (b *Balancer) HTTPClient() *http.Client {
+	return &http.Client{Transport: b}
+}
+
+// OriginStatus is the health of an origin of Balancer.
+type OriginStatus struct {
+	URL     string
+	Healthy bool
+	// Failures is the number of consecutive failures.
+	Failures int
+}
+
+// Status returns the health of the origins in this isolate, e.g. to be served by a debug endpoint.
+func (b *Balancer) Status() []*OriginStatus {
+	statuses := make([]*OriginStatus, len(b.origins))
+	for i, o := range b.origins {
+		o.breaker.mu.Lock()
+		statuses[i] = &OriginStatus{
+			URL:      o.url.String(),
+			Healthy:  o.breaker.failures < o.breaker.opts.FailureThreshold,
+			Failures: o.breaker.failures,
+		}
+		o.breaker.mu.Unlock()
+	}
+	return statuses
+}
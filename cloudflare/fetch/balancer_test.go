@@ -0,0 +1,165 @@
+package fetch_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/syumai/workers/cloudflare/fetch"
+	"github.com/syumai/workers/workerstest"
+)
+
+// fakeOrigins returns Fetcher responding with the status of each host, recording requested URLs.
+func fakeOrigins(statuses map[string]int) (*workerstest.Fetcher, func() []string) {
+	var mu sync.Mutex
+	var requested []string
+	f := &workerstest.Fetcher{DoFunc: func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		requested = append(requested, req.URL.String())
+		status := statuses[req.URL.Host]
+		mu.Unlock()
+		if status == 0 {
+			return nil, errors.New("connection refused")
+		}
+		return &http.Response{StatusCode: status, Body: http.NoBody}, nil
+	}}
+	return f, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		r := requested
+		requested = nil
+		return r
+	}
+}
+
+func TestBalancer_Failover(t *testing.T) {
+	tests := map[string]struct {
+		method     string
+		statuses   map[string]int
+		wantStatus int
+		wantErr    bool
+		wantURLs   []string
+	}{
+		"first origin": {
+			method:     http.MethodGet,
+			statuses:   map[string]int{"a.example.com": 200, "b.example.com": 200},
+			wantStatus: 200,
+			wantURLs:   []string{"https://a.example.com/api/items?q=1"},
+		},
+		"failover on status": {
+			method:     http.MethodGet,
+			statuses:   map[string]int{"a.example.com": 503, "b.example.com": 200},
+			wantStatus: 200,
+			wantURLs:   []string{"https://a.example.com/api/items?q=1", "http://b.example.com/items?q=1"},
+		},
+		"failover on network error": {
+			method:     http.MethodGet,
+			statuses:   map[string]int{"b.example.com": 404},
+			wantStatus: 404,
+			wantURLs:   []string{"https://a.example.com/api/items?q=1", "http://b.example.com/items?q=1"},
+		},
+		"all failed": {
+			method:   http.MethodGet,
+			statuses: map[string]int{"a.example.com": 502},
+			wantErr:  true,
+			wantURLs: []string{"https://a.example.com/api/items?q=1", "http://b.example.com/items?q=1"},
+		},
+		"post is not failed over": {
+			method:     http.MethodPost,
+			statuses:   map[string]int{"a.example.com": 503, "b.example.com": 200},
+			wantStatus: 503,
+			wantURLs:   []string{"https://a.example.com/api/items?q=1"},
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			f, requested := fakeOrigins(tc.statuses)
+			b, err := fetch.NewBalancer(f, []*fetch.Origin{
+				{URL: "https://a.example.com/api/"},
+				{URL: "http://b.example.com"},
+			}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, _ := http.NewRequest(tc.method, "https://worker.example.com/items?q=1", nil)
+			res, err := b.Do(req)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("want error %v, got %v", tc.wantErr, err)
+			}
+			if err == nil && res.StatusCode != tc.wantStatus {
+				t.Errorf("want status %d, got %d", tc.wantStatus, res.StatusCode)
+			}
+			if got := requested(); strings.Join(got, " ") != strings.Join(tc.wantURLs, " ") {
+				t.Errorf("want requests %v, got %v", tc.wantURLs, got)
+			}
+		})
+	}
+}
+
+func TestBalancer_Health(t *testing.T) {
+	statuses := map[string]int{"a.example.com": 503, "b.example.com": 200}
+	f, requested := fakeOrigins(statuses)
+	b, err := fetch.NewBalancer(f, []*fetch.Origin{
+		{URL: "https://a.example.com"},
+		{URL: "https://b.example.com"},
+	}, &fetch.BalancerOptions{FailureThreshold: 2, Cooldown: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	do := func() {
+		req, _ := http.NewRequest(http.MethodGet, "https://worker.example.com/", nil)
+		if _, err := b.Do(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	do()
+	do()
+	requested()
+	// the unhealthy origin is skipped.
+	do()
+	if got := requested(); len(got) != 1 || got[0] != "https://b.example.com/" {
+		t.Errorf("want the unhealthy origin to be skipped, got %v", got)
+	}
+	if s := b.Status(); s[0].Healthy || s[0].Failures != 2 || !s[1].Healthy {
+		t.Errorf("unexpected status: %+v %+v", s[0], s[1])
+	}
+	// after the cooldown, the trial request succeeds and the origin is healthy again.
+	time.Sleep(20 * time.Millisecond)
+	f.DoFunc = func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	}
+	do()
+	if s := b.Status(); !s[0].Healthy {
+		t.Errorf("want the origin to be healthy after the trial, got %+v", s[0])
+	}
+}
+
+func TestBalancer_Weighted(t *testing.T) {
+	f, requested := fakeOrigins(map[string]int{"a.example.com": 200, "b.example.com": 200})
+	b, err := fetch.NewBalancer(f, []*fetch.Origin{
+		{URL: "https://a.example.com", Weight: 3},
+		{URL: "https://b.example.com", Weight: 1},
+	}, &fetch.BalancerOptions{Weighted: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 400; i++ {
+		req, _ := http.NewRequest(http.MethodGet, "https://worker.example.com/", nil)
+		if _, err := b.Do(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	counts := map[string]int{}
+	for _, u := range requested() {
+		counts[u]++
+	}
+	if a := counts["https://a.example.com/"]; a < 240 || a > 360 {
+		t.Errorf("want about 300 requests to the heavier origin, got %v", counts)
+	}
+}
@@ -0,0 +1,72 @@
+package fetch
+
+import (
+	"net/http"
+
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jshttp"
+	"github.com/syumai/workers/internal/jsutil"
+	"github.com/syumai/workers/internal/runtimecontext"
+	"github.com/syumai/workers/trace"
+)
+
+// Fetcher is the interface implemented by Client.
+type Fetcher interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+var _ Fetcher = (*Client)(nil)
+
+// Client is an HTTP client sending requests by JavaScript side's fetch function.
+//   - https://developers.cloudflare.com/workers/runtime-apis/fetch/
+//   - Client implements http.RoundTripper, so it can be used as http.Client's Transport.
+type Client struct {
+	// namespace is an object which has fetch method (e.g. globalThis, service binding).
+	namespace js.Value
+}
+
+var _ http.RoundTripper = (*Client)(nil)
+
+// NewClient returns new Client using global fetch function.
+func NewClient() *Client {
+	return &Client{namespace: jsutil.Global}
+}
+
+// Do sends the HTTP request and returns the HTTP response.
+//   - The body of the response is streamed, so it must be closed after use.
+//   - if the context of the request holds trace.TraceContext, traceparent and tracestate headers are attached.
+//   - if the context of the request is canceled, the request is aborted and returns the error of the context.
+//   - if a network error happens, returns error.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	if _, ok := trace.FromContext(req.Context()); ok {
+		req = req.Clone(req.Context())
+		trace.Inject(req.Context(), req.Header)
+	}
+	jsReq, release := jshttp.ToJSRequest(req)
+	runtimecontext.SubrequestsFrom(req.Context()).Add()
+	promise := c.namespace.Call("fetch", jsReq)
+	jsRes, err := jsutil.AwaitPromise(promise)
+	if err != nil {
+		release()
+		if ctxErr := req.Context().Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, err
+	}
+	res, err := jshttp.ToFetchResponse(jsRes, release)
+	if err != nil {
+		return nil, err
+	}
+	res.Request = req
+	return res, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (c *Client) RoundTrip(req *http.Request) (*http.Response, error) {
+	return c.Do(req)
+}
+
+// HTTPClient returns *http.Client using the Client as Transport.
+func (c *Client) HTTPClient() *http.Client {
+	return &http.Client{Transport: c}
+}
@@ -0,0 +1,87 @@
+//go:build js && wasm
+
+package fetch
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jsutil"
+	"github.com/syumai/workers/trace"
+)
+
+// newFakeNamespace returns an object whose fetch records requests, and responds with 201 or rejects for paths ending with /fail.
+func newFakeNamespace() js.Value {
+	return jsutil.Global.Get("Function").New(`
+const ns = {requests: []};
+ns.fetch = (req) => {
+  ns.requests.push(req);
+  if (req.url.endsWith("/fail")) {
+    return Promise.reject(new TypeError("network error"));
+  }
+  return Promise.resolve(new Response("hello", {status: 201, headers: {"X-Test": "1"}}));
+};
+return ns;`).Invoke()
+}
+
+func TestClient_Do(t *testing.T) {
+	ns := newFakeNamespace()
+	c := &Client{namespace: ns}
+	tc := &trace.TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true}
+	req, err := http.NewRequestWithContext(trace.NewContext(context.Background(), tc), http.MethodGet, "https://example.com/a", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Request", "1")
+	res, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusCreated || res.Header.Get("X-Test") != "1" {
+		t.Errorf("want 201 with X-Test header, got %d, %v", res.StatusCode, res.Header)
+	}
+	if res.Request == nil || res.Request.URL.String() != "https://example.com/a" {
+		t.Error("want the sent request set to the response")
+	}
+	b, err := io.ReadAll(res.Body)
+	if err != nil || string(b) != "hello" {
+		t.Errorf("want body hello, got %q, %v", b, err)
+	}
+
+	sent := ns.Get("requests").Index(0)
+	if got := sent.Get("url").String(); got != "https://example.com/a" {
+		t.Errorf("want the URL sent, got %s", got)
+	}
+	headers := sent.Get("headers")
+	if got := headers.Call("get", "x-request"); got.IsNull() || got.String() != "1" {
+		t.Errorf("want the header sent, got %v", got)
+	}
+	if got := headers.Call("get", "traceparent"); got.IsNull() || got.String() != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Errorf("want traceparent injected, got %v", got)
+	}
+	if req.Header.Get("traceparent") != "" {
+		t.Error("want the header of the given request unmodified")
+	}
+}
+
+func TestClient_Do_Error(t *testing.T) {
+	c := &Client{namespace: newFakeNamespace()}
+	req, err := http.NewRequest(http.MethodGet, "https://example.com/fail", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Do(req); err == nil {
+		t.Error("want the network error, got nil")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.Do(req.WithContext(ctx)); !errors.Is(err, context.Canceled) {
+		t.Errorf("want the error of the context, got %v", err)
+	}
+}
@@ -0,0 +1,279 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/syumai/workers/internal/httputil"
+)
+
+// ErrCircuitOpen is returned when requests to the host are rejected by the circuit breaker.
+var ErrCircuitOpen = errors.New("fetch: circuit breaker is open")
+
+// RetryPolicy represents how failed requests are retried.
+//   - requests are retried on network errors and RetryStatuses.
+//   - only idempotent requests (GET, HEAD, OPTIONS, PUT, DELETE, or with Idempotency-Key header) are retried.
+//   - requests with bodies are retried only if http.Request.GetBody is set (e.g. bodies of bytes or strings).
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts including the first one. Defaults to 3.
+	MaxAttempts int
+	// InitialBackoff is the backoff before the first retry, which is doubled for each retry with jitter. Defaults to 100ms.
+	InitialBackoff time.Duration
+	// MaxBackoff is the maximum backoff, also limiting Retry-After header. Defaults to 2s.
+	MaxBackoff time.Duration
+	// RetryStatuses are status codes to retry. Defaults to 429, 502, 503 and 504.
+	RetryStatuses []int
+}
+
+// BreakerOptions represents options of the circuit breaker of each host.
+//   - the breaker opens after FailureThreshold consecutive failures (network errors or status 5xx),
+//     and rejects requests with ErrCircuitOpen for OpenDuration.
+//   - after OpenDuration, a single trial request is allowed. The breaker is closed if it succeeds, or opens again.
+//   - the state is kept in memory of the isolate, so the client should be shared by requests (e.g. a package level variable).
+type BreakerOptions struct {
+	// FailureThreshold is the number of consecutive failures opening the breaker. Defaults to 5.
+	FailureThreshold int
+	// OpenDuration is how long the breaker stays open. Defaults to 30s.
+	OpenDuration time.Duration
+}
+
+// PolicyOptions represents options of WithPolicy.
+type PolicyOptions struct {
+	// Timeout is the timeout of each attempt until the response header is received. Zero means no timeout.
+	Timeout time.Duration
+	// HostTimeouts overrides Timeout for hosts (e.g. "api.example.com").
+	HostTimeouts map[string]time.Duration
+	// Retry enables retries if set.
+	Retry *RetryPolicy
+	// Breaker enables circuit breakers for each host if set.
+	Breaker *BreakerOptions
+}
+
+// PolicyClient is Fetcher applying timeouts, retries and circuit breakers to requests sent by the underlying Fetcher.
+type PolicyClient struct {
+	fetcher Fetcher
+	opts    PolicyOptions
+	retry   RetryPolicy
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+var (
+	_ Fetcher           = (*PolicyClient)(nil)
+	_ http.RoundTripper = (*PolicyClient)(nil)
+)
+
+// WithPolicy returns PolicyClient sending requests by the fetcher. If fetcher is nil, NewClient() is used.
+func WithPolicy(fetcher Fetcher, opts *PolicyOptions) *PolicyClient {
+	if fetcher == nil {
+		fetcher = NewClient()
+	}
+	c := &PolicyClient{fetcher: fetcher, breakers: map[string]*breaker{}}
+	if opts != nil {
+		c.opts = *opts
+	}
+	if c.opts.Retry != nil {
+		c.retry = *c.opts.Retry
+	}
+	if c.retry.MaxAttempts <= 0 {
+		c.retry.MaxAttempts = 3
+	}
+	if c.retry.InitialBackoff <= 0 {
+		c.retry.InitialBackoff = 100 * time.Millisecond
+	}
+	if c.retry.MaxBackoff <= 0 {
+		c.retry.MaxBackoff = 2 * time.Second
+	}
+	if c.retry.RetryStatuses == nil {
+		c.retry.RetryStatuses = []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		}
+	}
+	if c.opts.Breaker != nil {
+		b := *c.opts.Breaker
+		if b.FailureThreshold <= 0 {
+			b.FailureThreshold = 5
+		}
+		if b.OpenDuration <= 0 {
+			b.OpenDuration = 30 * time.Second
+		}
+		c.opts.Breaker = &b
+	}
+	return c
+}
+
+// Do sends the request with the policy.
+//   - the response of the last attempt is returned even if its status is retryable.
+//   - if the context of the request is canceled during the backoff, returns the error of the context.
+func (c *PolicyClient) Do(req *http.Request) (*http.Response, error) {
+	var b *breaker
+	if c.opts.Breaker != nil {
+		b = c.breaker(req.URL.Host)
+	}
+	attempts := 1
+	if c.opts.Retry != nil && retryable(req) {
+		attempts = c.retry.MaxAttempts
+	}
+	for attempt := 1; ; attempt++ {
+		if b != nil && !b.allow() {
+			return nil, ErrCircuitOpen
+		}
+		res, err := c.do(req, attempt)
+		if b != nil {
+			b.record(err == nil && res.StatusCode < http.StatusInternalServerError)
+		}
+		if attempt >= attempts || !c.shouldRetry(res, err) || req.Context().Err() != nil {
+			return res, err
+		}
+		wait := c.backoff(attempt, res)
+		if res != nil {
+			io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+			res.Body.Close()
+		}
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (c *PolicyClient) RoundTrip(req *http.Request) (*http.Response, error) {
+	return c.Do(req)
+}
+
+// HTTPClient returns *http.Client using the PolicyClient as Transport.
+func (c *PolicyClient) HTTPClient() *http.Client {
+	return &http.Client{Transport: c}
+}
+
+// do sends a single attempt with the timeout.
+func (c *PolicyClient) do(req *http.Request, attempt int) (*http.Response, error) {
+	if attempt > 1 && req.Body != nil && req.Body != http.NoBody {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = body
+	}
+	timeout, ok := c.opts.HostTimeouts[req.URL.Host]
+	if !ok {
+		timeout = c.opts.Timeout
+	}
+	if timeout <= 0 {
+		return c.fetcher.Do(req)
+	}
+	return httputil.DoWithHeaderTimeout(req, timeout, c.fetcher.Do)
+}
+
+func (c *PolicyClient) shouldRetry(res *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, ErrCircuitOpen)
+	}
+	for _, s := range c.retry.RetryStatuses {
+		if res.StatusCode == s {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the backoff before the retry after the attempt.
+//   - Retry-After header in seconds is respected if present.
+func (c *PolicyClient) backoff(attempt int, res *http.Response) time.Duration {
+	if res != nil {
+		if sec, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && sec >= 0 {
+			return min(time.Duration(sec)*time.Second, c.retry.MaxBackoff)
+		}
+	}
+	d := c.retry.InitialBackoff << (attempt - 1)
+	if d <= 0 || d > c.retry.MaxBackoff {
+		d = c.retry.MaxBackoff
+	}
+	// equal jitter: [d/2, d)
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func (c *PolicyClient) breaker(host string) *breaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.breakers[host]
+	if !ok {
+		b = &breaker{opts: c.opts.Breaker}
+		c.breakers[host] = b
+	}
+	return b
+}
+
+// retryable reports whether the request is idempotent and its body can be sent again.
+func retryable(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// breaker is the circuit breaker of a host.
+type breaker struct {
+	opts *BreakerOptions
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	// trial reports whether a trial request of the half-open state is in flight.
+	trial bool
+}
+
+// allow reports whether a request can be sent.
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.opts.FailureThreshold {
+		return true
+	}
+	if b.trial || time.Since(b.openedAt) < b.opts.OpenDuration {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+// record records the result of a request.
+func (b *breaker) record(ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+	if ok {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.opts.FailureThreshold {
+		b.openedAt = time.Now()
+	}
+}
@@ -0,0 +1,171 @@
+package fetch_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/syumai/workers/cloudflare/fetch"
+	"github.com/syumai/workers/workerstest"
+)
+
+func TestPolicyClient_Retry(t *testing.T) {
+	tests := map[string]struct {
+		method       string
+		body         string
+		statuses     []int
+		wantStatus   int
+		wantAttempts int32
+	}{
+		"retried until success": {
+			method:       http.MethodGet,
+			statuses:     []int{503, 502, 200},
+			wantStatus:   200,
+			wantAttempts: 3,
+		},
+		"last response is returned": {
+			method:       http.MethodGet,
+			statuses:     []int{503, 503, 503, 200},
+			wantStatus:   503,
+			wantAttempts: 3,
+		},
+		"put with body is retried": {
+			method:       http.MethodPut,
+			body:         "data",
+			statuses:     []int{504, 200},
+			wantStatus:   200,
+			wantAttempts: 2,
+		},
+		"non-retryable status": {
+			method:       http.MethodGet,
+			statuses:     []int{500, 200},
+			wantStatus:   500,
+			wantAttempts: 1,
+		},
+		"post is not retried": {
+			method:       http.MethodPost,
+			body:         "data",
+			statuses:     []int{503, 200},
+			wantStatus:   503,
+			wantAttempts: 1,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var attempts atomic.Int32
+			f := &workerstest.Fetcher{Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				n := attempts.Add(1)
+				if b, _ := io.ReadAll(req.Body); string(b) != tc.body {
+					t.Errorf("want body %q, got %q", tc.body, b)
+				}
+				w.WriteHeader(tc.statuses[n-1])
+			})}
+			c := fetch.WithPolicy(f, &fetch.PolicyOptions{Retry: &fetch.RetryPolicy{InitialBackoff: time.Millisecond}})
+			req, _ := http.NewRequest(tc.method, "https://example.com/", strings.NewReader(tc.body))
+			res, err := c.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+			if res.StatusCode != tc.wantStatus {
+				t.Errorf("want status %d, got %d", tc.wantStatus, res.StatusCode)
+			}
+			if got := attempts.Load(); got != tc.wantAttempts {
+				t.Errorf("want %d attempts, got %d", tc.wantAttempts, got)
+			}
+		})
+	}
+}
+
+func TestPolicyClient_Timeout(t *testing.T) {
+	f := &workerstest.Fetcher{DoFunc: func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}}
+	c := fetch.WithPolicy(f, &fetch.PolicyOptions{
+		Timeout:      time.Hour,
+		HostTimeouts: map[string]time.Duration{"slow.example.com": time.Millisecond},
+	})
+	req, _ := http.NewRequest(http.MethodGet, "https://slow.example.com/", nil)
+	if _, err := c.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestPolicyClient_TimeoutStreamingBody(t *testing.T) {
+	f := &workerstest.Fetcher{DoFunc: func(req *http.Request) (*http.Response, error) {
+		pr, pw := io.Pipe()
+		go func() {
+			select {
+			case <-time.After(30 * time.Millisecond):
+				pw.Write([]byte("ok"))
+				pw.Close()
+			case <-req.Context().Done():
+				pw.CloseWithError(req.Context().Err())
+			}
+		}()
+		return &http.Response{StatusCode: http.StatusOK, Body: pr}, nil
+	}}
+	c := fetch.WithPolicy(f, &fetch.PolicyOptions{Timeout: 10 * time.Millisecond})
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+	res, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	// the timeout only covers the response header, so the body streamed longer is not aborted.
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("want the body to be read, got %v", err)
+	}
+	if string(b) != "ok" {
+		t.Errorf("want body %q, got %q", "ok", b)
+	}
+}
+
+func TestPolicyClient_Breaker(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	f := &workerstest.Fetcher{DoFunc: func(req *http.Request) (*http.Response, error) {
+		if failing.Load() {
+			return nil, errors.New("connection refused")
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	}}
+	c := fetch.WithPolicy(f, &fetch.PolicyOptions{
+		Breaker: &fetch.BreakerOptions{FailureThreshold: 2, OpenDuration: 10 * time.Millisecond},
+	})
+	do := func(host string) error {
+		req, _ := http.NewRequest(http.MethodGet, "https://"+host+"/", nil)
+		_, err := c.Do(req)
+		return err
+	}
+	for i := 0; i < 2; i++ {
+		if err := do("a.example.com"); err == nil || errors.Is(err, fetch.ErrCircuitOpen) {
+			t.Fatalf("want the request to fail, got %v", err)
+		}
+	}
+	if err := do("a.example.com"); !errors.Is(err, fetch.ErrCircuitOpen) {
+		t.Errorf("want ErrCircuitOpen, got %v", err)
+	}
+	// breakers are independent for each host.
+	if err := do("b.example.com"); errors.Is(err, fetch.ErrCircuitOpen) {
+		t.Errorf("want the breaker of other host to be closed")
+	}
+	time.Sleep(20 * time.Millisecond)
+	failing.Store(false)
+	if err := do("a.example.com"); err != nil {
+		t.Errorf("want the trial request to succeed, got %v", err)
+	}
+	if err := do("a.example.com"); err != nil {
+		t.Errorf("want the breaker to be closed, got %v", err)
+	}
+}
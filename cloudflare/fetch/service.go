@@ -0,0 +1,38 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/syumai/workers/cloudflare/internal/cfruntimecontext"
+)
+
+// NewServiceClient returns Client sending requests to the Worker bound by the service binding.
+//   - variable name must be defined in wrangler.toml as services' binding.
+//   - if the given variable name doesn't exist on runtime context, returns error.
+//   - This function panics when a runtime context is not found.
+func NewServiceClient(ctx context.Context, varName string) (*Client, error) {
+	inst := cfruntimecontext.GetRuntimeContextEnv(ctx).Get(varName)
+	if inst.IsUndefined() {
+		return nil, fmt.Errorf("%s is undefined", varName)
+	}
+	return &Client{namespace: inst}, nil
+}
+
+// Service returns Fetcher sending requests to the Worker bound by the service binding.
+// The binding is looked up from the context of each request, so the Fetcher can be created before requests arrive
+// (e.g. ProxyOptions.Client of workers.NewProxy).
+func Service(varName string) Fetcher {
+	return serviceFetcher(varName)
+}
+
+type serviceFetcher string
+
+func (name serviceFetcher) Do(req *http.Request) (*http.Response, error) {
+	c, err := NewServiceClient(req.Context(), string(name))
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
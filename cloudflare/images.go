@@ -0,0 +1,236 @@
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/syumai/workers/cloudflare/internal/cfruntimecontext"
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jshttp"
+	"github.com/syumai/workers/internal/jsutil"
+)
+
+// ImagesBinding is the interface implemented by Images.
+type ImagesBinding interface {
+	Info(image io.ReadCloser) (*ImageInfo, error)
+	Input(image io.ReadCloser) *ImageTransformer
+}
+
+var _ ImagesBinding = (*Images)(nil)
+
+// Images represents interface of Cloudflare Images binding.
+//   - https://developers.cloudflare.com/images/transform-images/bindings/
+type Images struct {
+	instance js.Value
+}
+
+// NewImages returns Images for given variable name.
+//   - variable name must be defined in wrangler.toml as images' binding.
+//   - if the given variable name doesn't exist on runtime context, returns error.
+//   - This function panics when a runtime context is not found.
+func NewImages(ctx context.Context, varName string) (*Images, error) {
+	inst := cfruntimecontext.GetRuntimeContextEnv(ctx).Get(varName)
+	if inst.IsUndefined() {
+		return nil, fmt.Errorf("%s is undefined", varName)
+	}
+	return &Images{instance: inst}, nil
+}
+
+// Output formats of images.
+const (
+	ImageFormatAVIF = "image/avif"
+	ImageFormatWebP = "image/webp"
+	ImageFormatJPEG = "image/jpeg"
+	ImageFormatPNG  = "image/png"
+	ImageFormatGIF  = "image/gif"
+)
+
+// ImageInfo represents information of an image.
+type ImageInfo struct {
+	// Format is the MIME type of the image (e.g. "image/png").
+	Format string
+	// FileSize, Width and Height are zero for vector images (image/svg+xml).
+	FileSize int
+	Width    int
+	Height   int
+}
+
+// Info returns information of the image.
+//   - the image is read until the header of the image is parsed.
+//   - if the image is not supported, returns error.
+func (i *Images) Info(image io.ReadCloser) (*ImageInfo, error) {
+	v, err := jsutil.AwaitPromise(i.instance.Call("info", jsutil.ConvertReaderToReadableStream(image)))
+	if err != nil {
+		return nil, err
+	}
+	return &ImageInfo{
+		Format:   v.Get("format").String(),
+		FileSize: jsutil.MaybeInt(v.Get("fileSize")),
+		Width:    jsutil.MaybeInt(v.Get("width")),
+		Height:   jsutil.MaybeInt(v.Get("height")),
+	}, nil
+}
+
+// Input starts a chain of transformations of the image.
+// Transformations are applied when Output is called.
+func (i *Images) Input(image io.ReadCloser) *ImageTransformer {
+	return &ImageTransformer{instance: i.instance.Call("input", jsutil.ConvertReaderToReadableStream(image))}
+}
+
+// ImageTransform represents a transformation of an image.
+// Zero values are not applied.
+//   - https://developers.cloudflare.com/images/transform-images/transform-via-workers/#fetch-options
+type ImageTransform struct {
+	Width  int
+	Height int
+	// Fit is one of "scale-down", "contain", "cover", "crop" and "pad".
+	Fit string
+	// Gravity is one of "auto", "left", "right", "top", "bottom" and "center".
+	Gravity string
+	// Rotate is one of 90, 180 and 270.
+	Rotate int
+	// Blur is the radius of the blur, between 1 and 250.
+	Blur       float64
+	Brightness float64
+	Contrast   float64
+	Gamma      float64
+	Saturation float64
+	// Sharpen is the strength of the sharpening, between 0 and 10.
+	Sharpen float64
+	// Background is the CSS color of the background of transparent images.
+	Background string
+}
+
+func (t *ImageTransform) toJS() js.Value {
+	obj := jsutil.NewObject()
+	if t == nil {
+		return obj
+	}
+	setIfNotZero(obj, "width", t.Width)
+	setIfNotZero(obj, "height", t.Height)
+	setIfNotZero(obj, "fit", t.Fit)
+	setIfNotZero(obj, "gravity", t.Gravity)
+	setIfNotZero(obj, "rotate", t.Rotate)
+	setIfNotZero(obj, "blur", t.Blur)
+	setIfNotZero(obj, "brightness", t.Brightness)
+	setIfNotZero(obj, "contrast", t.Contrast)
+	setIfNotZero(obj, "gamma", t.Gamma)
+	setIfNotZero(obj, "saturation", t.Saturation)
+	setIfNotZero(obj, "sharpen", t.Sharpen)
+	setIfNotZero(obj, "background", t.Background)
+	return obj
+}
+
+// ImageDrawOptions represents options of Draw.
+//   - https://developers.cloudflare.com/images/transform-images/draw-overlays/
+type ImageDrawOptions struct {
+	// Opacity is the opacity of the overlay, between 0 and 1. Zero means opaque.
+	Opacity float64
+	// Repeat is one of "true", "x" and "y". Empty means the overlay is not repeated.
+	Repeat string
+	// Position of the overlay in pixels. Nil values are not applied.
+	Top    *int
+	Left   *int
+	Bottom *int
+	Right  *int
+}
+
+func (opts *ImageDrawOptions) toJS() js.Value {
+	if opts == nil {
+		return js.Undefined()
+	}
+	obj := jsutil.NewObject()
+	setIfNotZero(obj, "opacity", opts.Opacity)
+	switch opts.Repeat {
+	case "":
+	case "true":
+		obj.Set("repeat", true)
+	default:
+		obj.Set("repeat", opts.Repeat)
+	}
+	for _, p := range []struct {
+		name string
+		v    *int
+	}{{"top", opts.Top}, {"left", opts.Left}, {"bottom", opts.Bottom}, {"right", opts.Right}} {
+		if p.v != nil {
+			obj.Set(p.name, *p.v)
+		}
+	}
+	return obj
+}
+
+// ImageOutputOptions represents options of Output.
+type ImageOutputOptions struct {
+	// Format is the format of the output (e.g. ImageFormatWebP). Required.
+	Format string
+	// Quality is the quality of the output, between 1 and 100. Zero means the default quality.
+	Quality int
+	// Background is the CSS color of the background for formats without transparency.
+	Background string
+}
+
+func (opts *ImageOutputOptions) toJS() js.Value {
+	obj := jsutil.NewObject()
+	obj.Set("format", opts.Format)
+	setIfNotZero(obj, "quality", opts.Quality)
+	setIfNotZero(obj, "background", opts.Background)
+	return obj
+}
+
+func setIfNotZero[T comparable](obj js.Value, name string, v T) {
+	var zero T
+	if v != zero {
+		obj.Set(name, v)
+	}
+}
+
+// ImageTransformer represents a chain of transformations of an image.
+type ImageTransformer struct {
+	instance js.Value
+}
+
+// Transform appends the transformation to the chain.
+func (t *ImageTransformer) Transform(transform *ImageTransform) *ImageTransformer {
+	return &ImageTransformer{instance: t.instance.Call("transform", transform.toJS())}
+}
+
+// Draw draws the overlay on the image. The overlay can be transformed before it is drawn.
+//   - e.g. images.Input(img).Draw(images.Input(logo).Transform(&ImageTransform{Width: 32}), nil)
+func (t *ImageTransformer) Draw(overlay *ImageTransformer, opts *ImageDrawOptions) *ImageTransformer {
+	return &ImageTransformer{instance: t.instance.Call("draw", overlay.instance, opts.toJS())}
+}
+
+// Output applies the transformations and encodes the image.
+//   - if the image is not supported or a transformation fails, returns error.
+func (t *ImageTransformer) Output(opts *ImageOutputOptions) (*ImageTransformationResult, error) {
+	if opts == nil || opts.Format == "" {
+		return nil, fmt.Errorf("output format must be specified")
+	}
+	v, err := jsutil.AwaitPromise(t.instance.Call("output", opts.toJS()))
+	if err != nil {
+		return nil, err
+	}
+	return &ImageTransformationResult{instance: v}, nil
+}
+
+// ImageTransformationResult represents a transformed image.
+type ImageTransformationResult struct {
+	instance js.Value
+}
+
+// ContentType returns the MIME type of the image.
+func (r *ImageTransformationResult) ContentType() string {
+	return r.instance.Call("contentType").String()
+}
+
+// Image returns the body of the image.
+func (r *ImageTransformationResult) Image() io.ReadCloser {
+	return jshttp.ToBody(r.instance.Call("image"))
+}
+
+// Response returns the image as a response with Content-Type header.
+func (r *ImageTransformationResult) Response() (*http.Response, error) {
+	return jshttp.ToResponse(r.instance.Call("response"))
+}
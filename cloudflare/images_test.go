@@ -0,0 +1,153 @@
+//go:build js && wasm
+
+package cloudflare_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/runtimecontext"
+)
+
+// newFakeImages returns a fake of the Images binding.
+//   - info supports images whose content is "png".
+//   - output returns an image whose content is the JSON of the input, the chain of transformations and the options.
+func newFakeImages() js.Value {
+	return js.Global().Get("Function").New(`
+const transformer = (input, chain) => ({
+  input,
+  chain,
+  transform(t) { return transformer(input, [...chain, {transform: t}]); },
+  draw(overlay, opts) { return transformer(input, [...chain, {draw: overlay.chain, opts}]); },
+  async output(opts) {
+    const text = await input;
+    if (text !== "png") {
+      throw new Error("unsupported image");
+    }
+    const body = JSON.stringify({chain, opts});
+    return {
+      contentType: () => opts.format,
+      image: () => new Response(body).body,
+      response: () => new Response(body, {headers: {"Content-Type": opts.format}}),
+    };
+  },
+});
+return {
+  async info(stream) {
+    if (await new Response(stream).text() !== "png") {
+      throw new Error("unsupported image");
+    }
+    return {format: "image/png", fileSize: 3, width: 2, height: 1};
+  },
+  input(stream) { return transformer(new Response(stream).text(), []); },
+};`).Invoke()
+}
+
+func newImages(t *testing.T) *cloudflare.Images {
+	t.Helper()
+	runtimeCtxObj := js.Global().Get("Object").New()
+	runtimeCtxObj.Set("env", js.ValueOf(map[string]any{"IMAGES": newFakeImages()}))
+	ctx := runtimecontext.New(context.Background(), js.Undefined(), runtimeCtxObj)
+	images, err := cloudflare.NewImages(ctx, "IMAGES")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return images
+}
+
+func image(content string) io.ReadCloser {
+	return io.NopCloser(strings.NewReader(content))
+}
+
+func TestImages_Info(t *testing.T) {
+	images := newImages(t)
+	info, err := images.Info(image("png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := cloudflare.ImageInfo{Format: "image/png", FileSize: 3, Width: 2, Height: 1}
+	if *info != want {
+		t.Errorf("want %+v, got %+v", want, *info)
+	}
+	if _, err := images.Info(image("txt")); err == nil {
+		t.Error("want error for an unsupported image, got nil")
+	}
+}
+
+func TestImages_Output(t *testing.T) {
+	top := 0
+	tests := map[string]struct {
+		transformer func(images *cloudflare.Images) *cloudflare.ImageTransformer
+		opts        *cloudflare.ImageOutputOptions
+		want        string
+	}{
+		"transform": {
+			transformer: func(images *cloudflare.Images) *cloudflare.ImageTransformer {
+				return images.Input(image("png")).
+					Transform(&cloudflare.ImageTransform{Width: 32, Fit: "cover"}).
+					Transform(&cloudflare.ImageTransform{Rotate: 90, Blur: 1.5})
+			},
+			opts: &cloudflare.ImageOutputOptions{Format: cloudflare.ImageFormatWebP, Quality: 80},
+			want: `{"chain":[{"transform":{"width":32,"fit":"cover"}},{"transform":{"rotate":90,"blur":1.5}}],"opts":{"format":"image/webp","quality":80}}`,
+		},
+		"draw": {
+			transformer: func(images *cloudflare.Images) *cloudflare.ImageTransformer {
+				overlay := images.Input(image("png")).Transform(&cloudflare.ImageTransform{Width: 8})
+				return images.Input(image("png")).
+					Draw(overlay, &cloudflare.ImageDrawOptions{Opacity: 0.5, Repeat: "true", Top: &top}).
+					Draw(images.Input(image("png")), nil)
+			},
+			opts: &cloudflare.ImageOutputOptions{Format: cloudflare.ImageFormatPNG},
+			want: `{"chain":[{"draw":[{"transform":{"width":8}}],"opts":{"opacity":0.5,"repeat":true,"top":0}},{"draw":[]}],"opts":{"format":"image/png"}}`,
+		},
+		"no transformations": {
+			transformer: func(images *cloudflare.Images) *cloudflare.ImageTransformer {
+				return images.Input(image("png")).Transform(nil)
+			},
+			opts: &cloudflare.ImageOutputOptions{Format: cloudflare.ImageFormatAVIF, Background: "#fff"},
+			want: `{"chain":[{"transform":{}}],"opts":{"format":"image/avif","background":"#fff"}}`,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			result, err := tc.transformer(newImages(t)).Output(tc.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := result.ContentType(); got != tc.opts.Format {
+				t.Errorf("want content type %s, got %s", tc.opts.Format, got)
+			}
+			b, err := io.ReadAll(result.Image())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tc.want {
+				t.Errorf("want %s, got %s", tc.want, b)
+			}
+			res, err := result.Response()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			if got := res.Header.Get("Content-Type"); got != tc.opts.Format {
+				t.Errorf("want Content-Type %s, got %s", tc.opts.Format, got)
+			}
+		})
+	}
+}
+
+func TestImages_Output_Error(t *testing.T) {
+	images := newImages(t)
+	if _, err := images.Input(image("png")).Output(nil); err == nil {
+		t.Error("want error for no output format, got nil")
+	}
+	if _, err := images.Input(image("txt")).Output(&cloudflare.ImageOutputOptions{Format: cloudflare.ImageFormatPNG}); err == nil {
+		t.Error("want error for an unsupported image, got nil")
+	}
+}
@@ -0,0 +1,96 @@
+package cloudflare
+
+import (
+	"context"
+	"errors"
+
+	"github.com/syumai/workers/cloudflare/internal/cfruntimecontext"
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jsutil"
+)
+
+// IncomingBotManagement represents bot management information of the incoming request.
+//   - This is available only when Bot Management is enabled on the zone.
+//   - https://developers.cloudflare.com/bots/reference/bot-management-variables/
+type IncomingBotManagement struct {
+	Score          int
+	VerifiedBot    bool
+	StaticResource bool
+	CorporateProxy bool
+	JA3Hash        string
+}
+
+// IncomingProperties represents the `cf` properties of the incoming request.
+//   - https://developers.cloudflare.com/workers/runtime-apis/request/#incomingrequestcfproperties
+type IncomingProperties struct {
+	Colo                 string
+	ASN                  int
+	AsOrganization       string
+	Country              string
+	IsEUCountry          bool
+	City                 string
+	Continent            string
+	Region               string
+	RegionCode           string
+	PostalCode           string
+	MetroCode            string
+	Timezone             string
+	Latitude             float64
+	Longitude            float64
+	HTTPProtocol         string
+	TLSVersion           string
+	TLSCipher            string
+	ClientTCPRTT         int
+	ClientAcceptEncoding string
+	BotManagement        IncomingBotManagement
+}
+
+// ErrIncomingPropertiesNotFound is returned when the incoming request doesn't have `cf` properties (e.g. in local development).
+var ErrIncomingPropertiesNotFound = errors.New("cloudflare: incoming properties not found")
+
+// NewIncomingProperties returns the `cf` properties of the incoming request.
+//   - if the incoming request doesn't have `cf` properties, returns ErrIncomingPropertiesNotFound.
+//   - This function panics when a runtime context is not found.
+func NewIncomingProperties(ctx context.Context) (*IncomingProperties, error) {
+	cf := cfruntimecontext.GetRequestCF(ctx)
+	if cf.IsUndefined() || cf.IsNull() {
+		return nil, ErrIncomingPropertiesNotFound
+	}
+	return toIncomingProperties(cf), nil
+}
+
+func toIncomingProperties(v js.Value) *IncomingProperties {
+	bm := v.Get("botManagement")
+	var botManagement IncomingBotManagement
+	if !bm.IsUndefined() && !bm.IsNull() {
+		botManagement = IncomingBotManagement{
+			Score:          jsutil.MaybeInt(bm.Get("score")),
+			VerifiedBot:    jsutil.MaybeBool(bm.Get("verifiedBot")),
+			StaticResource: jsutil.MaybeBool(bm.Get("staticResource")),
+			CorporateProxy: jsutil.MaybeBool(bm.Get("corporateProxy")),
+			JA3Hash:        jsutil.MaybeString(bm.Get("ja3Hash")),
+		}
+	}
+	return &IncomingProperties{
+		Colo:                 jsutil.MaybeString(v.Get("colo")),
+		ASN:                  jsutil.MaybeInt(v.Get("asn")),
+		AsOrganization:       jsutil.MaybeString(v.Get("asOrganization")),
+		Country:              jsutil.MaybeString(v.Get("country")),
+		IsEUCountry:          jsutil.MaybeString(v.Get("isEUCountry")) == "1",
+		City:                 jsutil.MaybeString(v.Get("city")),
+		Continent:            jsutil.MaybeString(v.Get("continent")),
+		Region:               jsutil.MaybeString(v.Get("region")),
+		RegionCode:           jsutil.MaybeString(v.Get("regionCode")),
+		PostalCode:           jsutil.MaybeString(v.Get("postalCode")),
+		MetroCode:            jsutil.MaybeString(v.Get("metroCode")),
+		Timezone:             jsutil.MaybeString(v.Get("timezone")),
+		Latitude:             jsutil.MaybeFloat(v.Get("latitude")),
+		Longitude:            jsutil.MaybeFloat(v.Get("longitude")),
+		HTTPProtocol:         jsutil.MaybeString(v.Get("httpProtocol")),
+		TLSVersion:           jsutil.MaybeString(v.Get("tlsVersion")),
+		TLSCipher:            jsutil.MaybeString(v.Get("tlsCipher")),
+		ClientTCPRTT:         jsutil.MaybeInt(v.Get("clientTcpRtt")),
+		ClientAcceptEncoding: jsutil.MaybeString(v.Get("clientAcceptEncoding")),
+		BotManagement:        botManagement,
+	}
+}
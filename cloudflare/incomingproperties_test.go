@@ -0,0 +1,73 @@
+package cloudflare_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/runtimecontext"
+)
+
+func TestNewIncomingProperties(t *testing.T) {
+	tests := map[string]struct {
+		reqObj  map[string]any
+		want    *cloudflare.IncomingProperties
+		wantErr error
+	}{
+		"properties": {
+			reqObj: map[string]any{"cf": map[string]any{
+				"colo":          "NRT",
+				"asn":           2516,
+				"country":       "JP",
+				"isEUCountry":   "0",
+				"city":          "Tokyo",
+				"latitude":      35.6,
+				"longitude":     139.7,
+				"httpProtocol":  "HTTP/2",
+				"clientTcpRtt":  12,
+				"botManagement": map[string]any{"score": 99, "verifiedBot": true, "ja3Hash": "abc"},
+			}},
+			want: &cloudflare.IncomingProperties{
+				Colo:         "NRT",
+				ASN:          2516,
+				Country:      "JP",
+				City:         "Tokyo",
+				Latitude:     35.6,
+				Longitude:    139.7,
+				HTTPProtocol: "HTTP/2",
+				ClientTCPRTT: 12,
+				BotManagement: cloudflare.IncomingBotManagement{
+					Score:       99,
+					VerifiedBot: true,
+					JA3Hash:     "abc",
+				},
+			},
+		},
+		"EU country": {
+			reqObj: map[string]any{"cf": map[string]any{"country": "DE", "isEUCountry": "1"}},
+			want:   &cloudflare.IncomingProperties{Country: "DE", IsEUCountry: true},
+		},
+		"local development": {
+			reqObj:  map[string]any{},
+			wantErr: cloudflare.ErrIncomingPropertiesNotFound,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			ctx := runtimecontext.New(context.Background(), js.ValueOf(tc.reqObj), js.ValueOf(map[string]any{}))
+			got, err := cloudflare.NewIncomingProperties(ctx)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("want error %v, got %v", tc.wantErr, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("want %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
@@ -2,8 +2,8 @@ package cfruntimecontext
 
 import (
 	"context"
-	"syscall/js"
 
+	"github.com/syumai/workers/internal/js"
 	"github.com/syumai/workers/internal/runtimecontext"
 )
 
@@ -26,6 +26,16 @@ func GetRuntimeContextEnv(ctx context.Context) js.Value {
 	return runtimeCtxValue.Get("env")
 }
 
+// LookupRuntimeContextEnv gets object which holds environment variables bound to Cloudflare worker.
+// If runtime context was not found, the returned bool is false.
+func LookupRuntimeContextEnv(ctx context.Context) (js.Value, bool) {
+	runtimeCtxValue, ok := runtimecontext.Extract(ctx)
+	if !ok {
+		return js.Value{}, false
+	}
+	return runtimeCtxValue.Get("env"), true
+}
+
 // GetExecutionContext gets ExecutionContext object from context.
 // - see: https://github.com/cloudflare/workers-types/blob/c8d9533caa4415c2156d2cf1daca75289d01ae70/index.d.ts#L567
 // - see also: https://github.com/cloudflare/workers-types/blob/c8d9533caa4415c2156d2cf1daca75289d01ae70/index.d.ts#L554
@@ -33,3 +43,16 @@ func GetExecutionContext(ctx context.Context) js.Value {
 	runtimeCtxValue := runtimecontext.MustExtract(ctx)
 	return runtimeCtxValue.Get("ctx")
 }
+
+// GetRequestCF gets the `cf` object of the incoming Request from context.
+// - see: https://developers.cloudflare.com/workers/runtime-apis/request/#incomingrequestcfproperties
+func GetRequestCF(ctx context.Context) js.Value {
+	reqObj := runtimecontext.MustExtractIncomingRequest(ctx)
+	return reqObj.Get("cf")
+}
+
+// GetIncomingRequest gets the incoming Request object from context.
+// It is undefined for events other than fetch.
+func GetIncomingRequest(ctx context.Context) js.Value {
+	return runtimecontext.MustExtractIncomingRequest(ctx)
+}
@@ -0,0 +1,104 @@
+//go:build go1.23
+
+package cloudflare
+
+import "iter"
+
+// ListKVKeys returns an iterator over keys of the KV namespace, fetching pages as needed.
+//   - Limit of opts is used as the size of pages, and Cursor of opts is used as the start of the iteration.
+//   - if listing a page fails, the error is yielded and the iteration stops.
+//   - use List directly to control cursors.
+func ListKVKeys(kv KVNamespaceBinding, opts *KVNamespaceListOptions) iter.Seq2[*KVNamespaceListKey, error] {
+	return func(yield func(*KVNamespaceListKey, error) bool) {
+		var o KVNamespaceListOptions
+		if opts != nil {
+			o = *opts
+		}
+		for {
+			result, err := kv.List(&o)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			for _, key := range result.Keys {
+				if !yield(key, nil) {
+					return
+				}
+			}
+			if result.ListComplete || result.Cursor == "" {
+				return
+			}
+			o.Cursor = result.Cursor
+		}
+	}
+}
+
+// ListR2Objects returns an iterator over objects of the bucket, fetching pages as needed.
+//   - Limit of opts is used as the size of pages, and Cursor of opts is used as the start of the iteration.
+//   - DelimitedPrefixes are not yielded. Use ListWithOptions directly to get them.
+//   - if listing a page fails, the error is yielded and the iteration stops.
+func ListR2Objects(bucket R2BucketBinding, opts *R2ListOptions) iter.Seq2[*R2Object, error] {
+	return func(yield func(*R2Object, error) bool) {
+		var o R2ListOptions
+		if opts != nil {
+			o = *opts
+		}
+		for {
+			result, err := bucket.ListWithOptions(&o)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			for _, obj := range result.Objects {
+				if !yield(obj, nil) {
+					return
+				}
+			}
+			if !result.Truncated || result.Cursor == "" {
+				return
+			}
+			o.Cursor = result.Cursor
+		}
+	}
+}
+
+// durableObjectStorageListPageSize is the size of pages of ListDurableObjectStorage if Limit of opts is not set.
+const durableObjectStorageListPageSize = 1000
+
+// ListDurableObjectStorage returns an iterator over entries of the storage in the order of keys, fetching pages as needed.
+//   - Limit of opts is used as the size of pages. Defaults to 1000.
+//   - pages are fetched after the last key of the previous page (or before it if Reverse is set),
+//     so keys written during the iteration are yielded if they are not passed yet.
+//   - if listing a page fails, the error is yielded and the iteration stops.
+func ListDurableObjectStorage(storage DurableObjectStorageBinding, opts *DurableObjectStorageListOptions) iter.Seq2[*DurableObjectStorageEntry, error] {
+	return func(yield func(*DurableObjectStorageEntry, error) bool) {
+		var o DurableObjectStorageListOptions
+		if opts != nil {
+			o = *opts
+		}
+		if o.Limit <= 0 {
+			o.Limit = durableObjectStorageListPageSize
+		}
+		for {
+			entries, err := storage.List(&o)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			for _, entry := range entries {
+				if !yield(entry, nil) {
+					return
+				}
+			}
+			if len(entries) < o.Limit {
+				return
+			}
+			last := entries[len(entries)-1].Key
+			if o.Reverse {
+				o.End = last
+			} else {
+				o.Start, o.StartAfter = "", last
+			}
+		}
+	}
+}
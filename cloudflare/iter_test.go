@@ -0,0 +1,130 @@
+//go:build go1.23
+
+package cloudflare_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/workerstest"
+)
+
+func TestListKVKeys(t *testing.T) {
+	kv := &workerstest.KVNamespace{}
+	for i := 0; i < 5; i++ {
+		if err := kv.PutString(fmt.Sprintf("key%d", i), "v", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := kv.PutString("other", "v", nil); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for key, err := range cloudflare.ListKVKeys(kv, &cloudflare.KVNamespaceListOptions{Prefix: "key", Limit: 2}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, key.Name)
+	}
+	if want := "key0,key1,key2,key3,key4"; strings.Join(got, ",") != want {
+		t.Errorf("want %s, got %v", want, got)
+	}
+
+	wantErr := errors.New("list failed")
+	kv.ListFunc = func(opts *cloudflare.KVNamespaceListOptions) (*cloudflare.KVNamespaceListResult, error) {
+		return nil, wantErr
+	}
+	for _, err := range cloudflare.ListKVKeys(kv, nil) {
+		if !errors.Is(err, wantErr) {
+			t.Errorf("want %v, got %v", wantErr, err)
+		}
+	}
+}
+
+func TestListR2Objects(t *testing.T) {
+	bucket := &workerstest.R2Bucket{}
+	for i := 0; i < 5; i++ {
+		if _, err := bucket.Put(fmt.Sprintf("key%d", i), io.NopCloser(strings.NewReader("v")), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got []string
+	for obj, err := range cloudflare.ListR2Objects(bucket, &cloudflare.R2ListOptions{Limit: 2}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, obj.Key)
+		if len(got) == 3 {
+			break
+		}
+	}
+	if want := "key0,key1,key2"; strings.Join(got, ",") != want {
+		t.Errorf("want %s, got %v", want, got)
+	}
+}
+
+// failingStorage fails to list entries.
+type failingStorage struct {
+	*workerstest.DurableObjectStorage
+	err error
+}
+
+func (s failingStorage) List(opts *cloudflare.DurableObjectStorageListOptions) ([]*cloudflare.DurableObjectStorageEntry, error) {
+	return nil, s.err
+}
+
+func TestListDurableObjectStorage(t *testing.T) {
+	storage := &workerstest.DurableObjectStorage{}
+	for i := 0; i < 5; i++ {
+		if err := storage.Put(fmt.Sprintf("key%d", i), i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := storage.Put("other", 0); err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]struct {
+		opts *cloudflare.DurableObjectStorageListOptions
+		want string
+	}{
+		"forward": {
+			opts: &cloudflare.DurableObjectStorageListOptions{Start: "key1", Prefix: "key", Limit: 2},
+			want: "key1,key2,key3,key4",
+		},
+		"reverse": {
+			opts: &cloudflare.DurableObjectStorageListOptions{Start: "key1", Prefix: "key", Reverse: true, Limit: 2},
+			want: "key4,key3,key2,key1",
+		},
+		"default page size": {
+			want: "key0,key1,key2,key3,key4,other",
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var got []string
+			for entry, err := range cloudflare.ListDurableObjectStorage(storage, tc.opts) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, entry.Key)
+			}
+			if strings.Join(got, ",") != tc.want {
+				t.Errorf("want %s, got %v", tc.want, got)
+			}
+		})
+	}
+
+	wantErr := errors.New("list failed")
+	for _, err := range cloudflare.ListDurableObjectStorage(failingStorage{storage, wantErr}, nil) {
+		if !errors.Is(err, wantErr) {
+			t.Errorf("want %v, got %v", wantErr, err)
+		}
+	}
+}
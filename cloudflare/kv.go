@@ -2,20 +2,41 @@ package cloudflare
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
-	"syscall/js"
+	"sync"
 
 	"github.com/syumai/workers/cloudflare/internal/cfruntimecontext"
-
+	"github.com/syumai/workers/internal/debuglog"
+	"github.com/syumai/workers/internal/js"
 	"github.com/syumai/workers/internal/jsutil"
+	"github.com/syumai/workers/internal/runtimecontext"
+	"github.com/syumai/workers/jsoncodec"
 )
 
+// KVNamespaceBinding is the interface implemented by KVNamespace.
+type KVNamespaceBinding interface {
+	GetString(key string, opts *KVNamespaceGetOptions) (string, error)
+	GetReader(key string, opts *KVNamespaceGetOptions) (io.Reader, error)
+	GetWithMetadata(key string, opts *KVNamespaceGetOptions) (*KVNamespaceValueWithMetadata, error)
+	List(opts *KVNamespaceListOptions) (*KVNamespaceListResult, error)
+	PutString(key string, value string, opts *KVNamespacePutOptions) error
+	PutReader(key string, value io.Reader, opts *KVNamespacePutOptions) error
+	Delete(key string) error
+	Update(key string, fn func(old []byte) ([]byte, error), opts *KVNamespaceUpdateOptions) error
+}
+
+var _ KVNamespaceBinding = (*KVNamespace)(nil)
+
 // KVNamespace represents interface of Cloudflare Worker's KV namespace instance.
 //   - https://developers.cloudflare.com/workers/runtime-apis/kv/
 //   - https://github.com/cloudflare/workers-types/blob/3012f263fb1239825e5f0061b267c8650d01b717/index.d.ts#L850
 type KVNamespace struct {
 	instance js.Value
+	retry    *RetryPolicy
+	// subrequests counts calls as subrequests of the event the binding was obtained in.
+	subrequests *runtimecontext.Subrequests
 }
 
 // NewKVNamespace returns KVNamespace for given variable name.
@@ -27,7 +48,13 @@ func NewKVNamespace(ctx context.Context, varName string) (*KVNamespace, error) {
 	if inst.IsUndefined() {
 		return nil, fmt.Errorf("%s is undefined", varName)
 	}
-	return &KVNamespace{instance: inst}, nil
+	return &KVNamespace{instance: inst, subrequests: runtimecontext.SubrequestsFrom(ctx)}, nil
+}
+
+// WithRetry returns a copy of the namespace which retries calls failed by transient errors with the policy.
+//   - Reading values returned by GetReader is not retried.
+func (kv *KVNamespace) WithRetry(policy *RetryPolicy) *KVNamespace {
+	return &KVNamespace{instance: kv.instance, retry: policy, subrequests: kv.subrequests}
 }
 
 // KVNamespaceGetOptions represents Cloudflare KV namespace get options.
@@ -37,13 +64,46 @@ type KVNamespaceGetOptions struct {
 }
 
 func (opts *KVNamespaceGetOptions) toJS(type_ string) js.Value {
-	obj := jsutil.NewObject()
-	obj.Set("type", type_)
-	if opts == nil {
+	key := kvGetOptionsKey{type_: type_}
+	if opts != nil {
+		key.cacheTTL = opts.CacheTTL
+	}
+	return kvGetOptions.get(key)
+}
+
+// kvGetOptionsKey is the key of options objects of get.
+type kvGetOptionsKey struct {
+	type_    string
+	cacheTTL int
+}
+
+// maxKVGetOptions is the maximum number of options objects cached by kvGetOptionsCache.
+const maxKVGetOptions = 64
+
+// kvGetOptionsCache caches options objects of get.
+//   - get is called on hot read paths on every request, so options objects are reused instead of being created per call.
+//   - options objects are never modified after creation, and the runtime doesn't modify them either.
+//   - the number of cached objects is limited, since CacheTTL can be any value.
+type kvGetOptionsCache struct {
+	mu   sync.Mutex
+	objs map[kvGetOptionsKey]js.Value
+}
+
+var kvGetOptions = &kvGetOptionsCache{objs: map[kvGetOptionsKey]js.Value{}}
+
+func (c *kvGetOptionsCache) get(key kvGetOptionsKey) js.Value {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if obj, ok := c.objs[key]; ok {
 		return obj
 	}
-	if opts.CacheTTL != 0 {
-		obj.Set("cacheTtl", opts.CacheTTL)
+	obj := jsutil.NewObject()
+	obj.Set("type", key.type_)
+	if key.cacheTTL != 0 {
+		obj.Set("cacheTtl", key.cacheTTL)
+	}
+	if len(c.objs) < maxKVGetOptions {
+		c.objs[key] = obj
 	}
 	return obj
 }
@@ -51,24 +111,71 @@ func (opts *KVNamespaceGetOptions) toJS(type_ string) js.Value {
 // GetString gets string value by the specified key.
 //   - if a network error happens, returns error.
 func (kv *KVNamespace) GetString(key string, opts *KVNamespaceGetOptions) (string, error) {
-	p := kv.instance.Call("get", key, opts.toJS("text"))
-	v, err := jsutil.AwaitPromise(p)
+	v, err := kv.retry.await(kv.subrequests, func() js.Value {
+		return kv.instance.Call("get", key, opts.toJS("text"))
+	})
 	if err != nil {
 		return "", err
 	}
+	if debuglog.Enabled() {
+		debuglog.Log("cloudflare: KVNamespace get", "key", key, "type", "text", "found", !v.IsNull())
+	}
 	return v.String(), nil
 }
 
 // GetReader gets stream value by the specified key.
 //   - if a network error happens, returns error.
 func (kv *KVNamespace) GetReader(key string, opts *KVNamespaceGetOptions) (io.Reader, error) {
-	p := kv.instance.Call("get", key, opts.toJS("stream"))
-	v, err := jsutil.AwaitPromise(p)
+	v, err := kv.retry.await(kv.subrequests, func() js.Value {
+		return kv.instance.Call("get", key, opts.toJS("stream"))
+	})
+	if err != nil {
+		return nil, err
+	}
+	if debuglog.Enabled() {
+		debuglog.Log("cloudflare: KVNamespace get", "key", key, "type", "stream", "found", !v.IsNull())
+	}
+	return jsutil.ConvertByteStreamToReader(v), nil
+}
+
+// KVNamespaceValueWithMetadata represents a value of KV namespace with its metadata.
+//   - https://developers.cloudflare.com/kv/api/read-key-value-pairs/#get-method
+type KVNamespaceValueWithMetadata struct {
+	Value []byte
+	// Metadata is the JSON-encoded metadata of the value. nil if the value has no metadata.
+	Metadata json.RawMessage
+}
+
+// GetWithMetadata gets the value and its metadata by the specified key.
+//   - if the key doesn't exist, returns nil.
+//   - if a network error happens, returns error.
+func (kv *KVNamespace) GetWithMetadata(key string, opts *KVNamespaceGetOptions) (*KVNamespaceValueWithMetadata, error) {
+	v, err := kv.retry.await(kv.subrequests, func() js.Value {
+		return kv.instance.Call("getWithMetadata", key, opts.toJS("arrayBuffer"))
+	})
 	if err != nil {
 		return nil, err
 	}
-	jsutil.Global.Get("console").Call("log", v)
-	return jsutil.ConvertStreamReaderToReader(v.Call("getReader")), nil
+	value := v.Get("value")
+	if debuglog.Enabled() {
+		debuglog.Log("cloudflare: KVNamespace getWithMetadata", "key", key, "found", !value.IsNull())
+	}
+	if value.IsNull() {
+		return nil, nil
+	}
+	return &KVNamespaceValueWithMetadata{
+		Value:    jsutil.BufferSourceToBytes(value),
+		Metadata: toKVMetadata(v.Get("metadata")),
+	}, nil
+}
+
+// toKVMetadata converts JavaScript side's metadata to JSON.
+//   - if the metadata is null or undefined, returns nil.
+func toKVMetadata(v js.Value) json.RawMessage {
+	if v.IsNull() || v.IsUndefined() {
+		return nil
+	}
+	return json.RawMessage(jsutil.Global.Get("JSON").Call("stringify", v).String())
 }
 
 // KVNamespaceListOptions represents Cloudflare KV namespace list options.
@@ -102,7 +209,8 @@ type KVNamespaceListKey struct {
 	Name string
 	// Expiration is an expiration of KV value cache. The value `0` means no expiration.
 	Expiration int
-	// Metadata   map[string]any // TODO: implement
+	// Metadata is the JSON-encoded metadata of the value. nil if the value has no metadata.
+	Metadata json.RawMessage
 }
 
 // toKVNamespaceListResult converts JavaScript side's KVNamespaceListKey to *KVNamespaceListKey.
@@ -116,7 +224,7 @@ func toKVNamespaceListKey(v js.Value) (*KVNamespaceListKey, error) {
 	return &KVNamespaceListKey{
 		Name:       v.Get("name").String(),
 		Expiration: exp,
-		// Metadata // TODO: implement. This may return an error, so this func signature has an error in return parameters.
+		Metadata:   toKVMetadata(v.Get("metadata")),
 	}, nil
 }
 
@@ -156,8 +264,9 @@ func toKVNamespaceListResult(v js.Value) (*KVNamespaceListResult, error) {
 
 // List lists keys stored into the KV namespace.
 func (kv *KVNamespace) List(opts *KVNamespaceListOptions) (*KVNamespaceListResult, error) {
-	p := kv.instance.Call("list", opts.toJS())
-	v, err := jsutil.AwaitPromise(p)
+	v, err := kv.retry.await(kv.subrequests, func() js.Value {
+		return kv.instance.Call("list", opts.toJS())
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -169,12 +278,14 @@ func (kv *KVNamespace) List(opts *KVNamespaceListOptions) (*KVNamespaceListResul
 type KVNamespacePutOptions struct {
 	Expiration    int
 	ExpirationTTL int
-	// Metadata // TODO: implement
+	// Metadata is stored with the value after it is encoded by the codec of the jsoncodec package.
+	//   - The encoded metadata must be up to 1024 bytes.
+	Metadata any
 }
 
-func (opts *KVNamespacePutOptions) toJS() js.Value {
+func (opts *KVNamespacePutOptions) toJS() (js.Value, error) {
 	if opts == nil {
-		return js.Undefined()
+		return js.Undefined(), nil
 	}
 	obj := jsutil.NewObject()
 	if opts.Expiration != 0 {
@@ -183,14 +294,26 @@ func (opts *KVNamespacePutOptions) toJS() js.Value {
 	if opts.ExpirationTTL != 0 {
 		obj.Set("expirationTtl", opts.ExpirationTTL)
 	}
-	return obj
+	if opts.Metadata != nil {
+		b, err := jsoncodec.Marshal(opts.Metadata)
+		if err != nil {
+			return js.Value{}, fmt.Errorf("error encoding metadata: %w", err)
+		}
+		obj.Set("metadata", jsutil.Global.Get("JSON").Call("parse", string(b)))
+	}
+	return obj, nil
 }
 
 // PutString puts string value into KV with key.
 //   - if a network error happens, returns error.
 func (kv *KVNamespace) PutString(key string, value string, opts *KVNamespacePutOptions) error {
-	p := kv.instance.Call("put", key, value, opts.toJS())
-	_, err := jsutil.AwaitPromise(p)
+	optsObj, err := opts.toJS()
+	if err != nil {
+		return err
+	}
+	_, err = kv.retry.await(kv.subrequests, func() js.Value {
+		return kv.instance.Call("put", key, value, optsObj)
+	})
 	if err != nil {
 		return err
 	}
@@ -201,6 +324,10 @@ func (kv *KVNamespace) PutString(key string, value string, opts *KVNamespacePutO
 //   - This method copies all bytes into memory for implementation restriction.
 //   - if a network error happens, returns error.
 func (kv *KVNamespace) PutReader(key string, value io.Reader, opts *KVNamespacePutOptions) error {
+	optsObj, err := opts.toJS()
+	if err != nil {
+		return err
+	}
 	// fetch body cannot be ReadableStream. see: https://github.com/whatwg/fetch/issues/1438
 	b, err := io.ReadAll(value)
 	if err != nil {
@@ -208,8 +335,9 @@ func (kv *KVNamespace) PutReader(key string, value io.Reader, opts *KVNamespaceP
 	}
 	ua := jsutil.NewUint8Array(len(b))
 	js.CopyBytesToJS(ua, b)
-	p := kv.instance.Call("put", key, ua.Get("buffer"), opts.toJS())
-	_, err = jsutil.AwaitPromise(p)
+	_, err = kv.retry.await(kv.subrequests, func() js.Value {
+		return kv.instance.Call("put", key, ua.Get("buffer"), optsObj)
+	})
 	if err != nil {
 		return err
 	}
@@ -219,8 +347,9 @@ func (kv *KVNamespace) PutReader(key string, value io.Reader, opts *KVNamespaceP
 // Delete deletes key-value pair specified by the key.
 //   - if a network error happens, returns error.
 func (kv *KVNamespace) Delete(key string) error {
-	p := kv.instance.Call("delete", key)
-	_, err := jsutil.AwaitPromise(p)
+	_, err := kv.retry.await(kv.subrequests, func() js.Value {
+		return kv.instance.Call("delete", key)
+	})
 	if err != nil {
 		return err
 	}
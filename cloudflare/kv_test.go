@@ -0,0 +1,71 @@
+//go:build js && wasm
+
+package cloudflare
+
+import (
+	"testing"
+
+	"github.com/syumai/workers/internal/js"
+)
+
+func TestKVNamespaceGetOptions_toJS(t *testing.T) {
+	tests := map[string]struct {
+		opts         *KVNamespaceGetOptions
+		type_        string
+		wantCacheTTL js.Value
+	}{
+		"nil": {
+			type_:        "text",
+			wantCacheTTL: js.Undefined(),
+		},
+		"zero": {
+			opts:         &KVNamespaceGetOptions{},
+			type_:        "stream",
+			wantCacheTTL: js.Undefined(),
+		},
+		"cache TTL": {
+			opts:         &KVNamespaceGetOptions{CacheTTL: 60},
+			type_:        "text",
+			wantCacheTTL: js.ValueOf(60),
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			obj := tc.opts.toJS(tc.type_)
+			if got := obj.Get("type").String(); got != tc.type_ {
+				t.Errorf("want type %s, got %s", tc.type_, got)
+			}
+			if got := obj.Get("cacheTtl"); !got.Equal(tc.wantCacheTTL) {
+				t.Errorf("want cacheTtl %v, got %v", tc.wantCacheTTL, got)
+			}
+			// options objects of the same options are reused.
+			var same *KVNamespaceGetOptions
+			if tc.opts != nil {
+				same = &KVNamespaceGetOptions{CacheTTL: tc.opts.CacheTTL}
+			}
+			if !same.toJS(tc.type_).Equal(obj) {
+				t.Error("want the options object reused")
+			}
+		})
+	}
+}
+
+func TestKVGetOptionsCache_Limit(t *testing.T) {
+	c := &kvGetOptionsCache{objs: map[kvGetOptionsKey]js.Value{}}
+	for ttl := 1; ttl <= maxKVGetOptions+10; ttl++ {
+		obj := c.get(kvGetOptionsKey{type_: "text", cacheTTL: ttl})
+		if got := obj.Get("cacheTtl").Int(); got != ttl {
+			t.Errorf("want cacheTtl %d, got %d", ttl, got)
+		}
+	}
+	if len(c.objs) != maxKVGetOptions {
+		t.Errorf("want %d options objects cached, got %d", maxKVGetOptions, len(c.objs))
+	}
+	// options objects not cached are created per call.
+	key := kvGetOptionsKey{type_: "text", cacheTTL: maxKVGetOptions + 1}
+	if c.get(key).Equal(c.get(key)) {
+		t.Error("want options objects over the limit not cached")
+	}
+}
@@ -0,0 +1,32 @@
+package cloudflare
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/syumai/workers/jsoncodec"
+)
+
+// GetJSON gets the value of the key, and decodes it into T by the codec of the jsoncodec package.
+//   - if the key doesn't exist, returns the zero value of T and false.
+//   - if the value can't be decoded, returns error.
+func GetJSON[T any](kv KVNamespaceBinding, key string, opts *KVNamespaceGetOptions) (T, bool, error) {
+	var v T
+	entry, err := kv.GetWithMetadata(key, opts)
+	if err != nil || entry == nil {
+		return v, false, err
+	}
+	if err := jsoncodec.Unmarshal(entry.Value, &v); err != nil {
+		return v, false, fmt.Errorf("error decoding value of %s: %w", key, err)
+	}
+	return v, true, nil
+}
+
+// PutJSON encodes the value by the codec of the jsoncodec package, and puts it to the key.
+func PutJSON[T any](kv KVNamespaceBinding, key string, v T, opts *KVNamespacePutOptions) error {
+	b, err := jsoncodec.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("error encoding value of %s: %w", key, err)
+	}
+	return kv.PutReader(key, bytes.NewReader(b), opts)
+}
@@ -0,0 +1,140 @@
+package cloudflare
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrKVUpdateConflict is returned by Update when the value kept being updated concurrently until the attempts ran out.
+var ErrKVUpdateConflict = errors.New("cloudflare: KV value was updated concurrently")
+
+// KVNamespaceUpdateOptions represents options of Update.
+type KVNamespaceUpdateOptions struct {
+	// MaxAttempts is the maximum number of attempts including the first one. Defaults to 5.
+	MaxAttempts int
+	// RetryInterval is the interval between attempts.
+	// Defaults to 1 second, since KV allows only one write per second to the same key.
+	RetryInterval time.Duration
+	// Expiration and ExpirationTTL are passed to put.
+	Expiration    int
+	ExpirationTTL int
+}
+
+// kvVersion is the metadata stored by Update.
+type kvVersion struct {
+	Version int64 `json:"version"`
+	// Token identifies the write of an attempt.
+	Token string `json:"token"`
+}
+
+const (
+	defaultKVUpdateMaxAttempts   = 5
+	defaultKVUpdateRetryInterval = time.Second
+)
+
+// Update updates the value of the key by fn, detecting concurrent updates by a version stored in the metadata.
+//   - fn receives the current value, or nil if the key doesn't exist, and returns the new value.
+//     If fn returns error, Update returns it without writing.
+//   - Before writing, the version is read again and compared with the version given to fn.
+//     If another write replaced the value in between, fn is called again with the latest value.
+//   - After writing, the value is read again. If another write replaced it, fn is called again with the latest value.
+//   - If conflicts continue until MaxAttempts, returns ErrKVUpdateConflict.
+//   - The metadata of the value is overwritten by the version. Values are written as bytes, so binary values are kept.
+//   - Caution: the update is best-effort. KV has no compare-and-swap, so a write landing between the comparison and
+//     the write of Update can still be lost, and KV is eventually consistent: writes from other locations may become
+//     visible up to 60 seconds later, so conflicts between them can't be detected and the last write wins.
+//     Use Durable Objects (e.g. cloudflare.DurableObjectStorage) if updates must not be lost.
+func (kv *KVNamespace) Update(key string, fn func(old []byte) ([]byte, error), opts *KVNamespaceUpdateOptions) error {
+	return UpdateKV(kv, key, fn, opts)
+}
+
+// UpdateKV implements Update for any KVNamespaceBinding, so test doubles can share the behavior.
+func UpdateKV(kv KVNamespaceBinding, key string, fn func(old []byte) ([]byte, error), opts *KVNamespaceUpdateOptions) error {
+	if opts == nil {
+		opts = &KVNamespaceUpdateOptions{}
+	}
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultKVUpdateMaxAttempts
+	}
+	interval := opts.RetryInterval
+	if interval == 0 {
+		interval = defaultKVUpdateRetryInterval
+	}
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(interval)
+		}
+		cur, err := kv.GetWithMetadata(key, nil)
+		if err != nil {
+			return err
+		}
+		var old []byte
+		var version kvVersion
+		if cur != nil {
+			old = cur.Value
+			// metadata not written by Update is treated as version 0.
+			_ = json.Unmarshal(cur.Metadata, &version)
+		}
+		value, err := fn(old)
+		if err != nil {
+			return err
+		}
+		latest, err := kv.GetWithMetadata(key, nil)
+		if err != nil {
+			return err
+		}
+		if !sameKVVersion(cur, latest) {
+			// the value was replaced while fn was running.
+			continue
+		}
+		next := kvVersion{Version: version.Version + 1, Token: newKVUpdateToken()}
+		err = kv.PutReader(key, bytes.NewReader(value), &KVNamespacePutOptions{
+			Expiration:    opts.Expiration,
+			ExpirationTTL: opts.ExpirationTTL,
+			Metadata:      next,
+		})
+		if err != nil {
+			return err
+		}
+		written, err := kv.GetWithMetadata(key, nil)
+		if err != nil {
+			return err
+		}
+		if written == nil {
+			// the key was deleted after the write.
+			continue
+		}
+		var got kvVersion
+		_ = json.Unmarshal(written.Metadata, &got)
+		if got.Token == next.Token {
+			return nil
+		}
+	}
+	return ErrKVUpdateConflict
+}
+
+// sameKVVersion reports whether the values have the same version written by Update.
+//   - values without the version written by Update are compared by their bytes.
+func sameKVVersion(a, b *KVNamespaceValueWithMetadata) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	var va, vb kvVersion
+	_ = json.Unmarshal(a.Metadata, &va)
+	_ = json.Unmarshal(b.Metadata, &vb)
+	if va.Token == "" && vb.Token == "" {
+		return bytes.Equal(a.Value, b.Value)
+	}
+	return va == vb
+}
+
+func newKVUpdateToken() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,141 @@
+package cloudflare_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/workerstest"
+)
+
+// racingKV writes the value of another writer once before the n-th call of GetWithMetadata.
+type racingKV struct {
+	*workerstest.KVNamespace
+	before int
+	calls  int
+	value  string
+	// puts counts writes of Update.
+	puts int
+}
+
+func (kv *racingKV) PutReader(key string, value io.Reader, opts *cloudflare.KVNamespacePutOptions) error {
+	kv.puts++
+	return kv.KVNamespace.PutReader(key, value, opts)
+}
+
+func (kv *racingKV) GetWithMetadata(key string, opts *cloudflare.KVNamespaceGetOptions) (*cloudflare.KVNamespaceValueWithMetadata, error) {
+	kv.calls++
+	if kv.calls == kv.before {
+		if err := kv.KVNamespace.PutString(key, kv.value, nil); err != nil {
+			return nil, err
+		}
+	}
+	return kv.KVNamespace.GetWithMetadata(key, opts)
+}
+
+func TestUpdateKV(t *testing.T) {
+	increment := func(old []byte) []byte {
+		n, _ := strconv.Atoi(string(old))
+		return []byte(strconv.Itoa(n + 1))
+	}
+	tests := map[string]struct {
+		// before is the call of GetWithMetadata before which another writer increments the original value. 0 means no writer.
+		before   int
+		want     string
+		wantPuts int
+	}{
+		"no conflict": {
+			want:     "2",
+			wantPuts: 1,
+		},
+		// the value is replaced while fn is running.
+		"replaced before write": {
+			before:   2,
+			want:     "3",
+			wantPuts: 1,
+		},
+		// the value is replaced between the write and the read for verification.
+		"replaced after write": {
+			before:   3,
+			want:     "3",
+			wantPuts: 2,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			fake := &workerstest.KVNamespace{}
+			if err := fake.PutString("counter", "1", nil); err != nil {
+				t.Fatal(err)
+			}
+			kv := &racingKV{KVNamespace: fake, before: tc.before, value: "2"}
+			err := cloudflare.UpdateKV(kv, "counter", func(old []byte) ([]byte, error) {
+				return increment(old), nil
+			}, &cloudflare.KVNamespaceUpdateOptions{RetryInterval: -1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := fake.GetString("counter", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("want %s, got %s", tc.want, got)
+			}
+			if kv.puts != tc.wantPuts {
+				t.Errorf("want %d writes, got %d", tc.wantPuts, kv.puts)
+			}
+		})
+	}
+}
+
+func TestUpdateKV_Binary(t *testing.T) {
+	kv := &workerstest.KVNamespace{}
+	want := []byte{0x00, 0xff, 0xfe, 0x80, 'a', 0xc3}
+	err := cloudflare.UpdateKV(kv, "bin", func(old []byte) ([]byte, error) {
+		return want, nil
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := kv.GetReader("bin", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(want, got) {
+		t.Errorf("want %x, got %x", want, got)
+	}
+}
+
+func TestUpdateKV_Conflict(t *testing.T) {
+	// another writer replaces the value before every read.
+	kv := &alwaysRacingKV{KVNamespace: &workerstest.KVNamespace{}}
+	err := cloudflare.UpdateKV(kv, "counter", func(old []byte) ([]byte, error) {
+		return []byte("mine"), nil
+	}, &cloudflare.KVNamespaceUpdateOptions{MaxAttempts: 3, RetryInterval: -1})
+	if !errors.Is(err, cloudflare.ErrKVUpdateConflict) {
+		t.Errorf("want ErrKVUpdateConflict, got %v", err)
+	}
+}
+
+type alwaysRacingKV struct {
+	*workerstest.KVNamespace
+	writes int
+}
+
+func (kv *alwaysRacingKV) GetWithMetadata(key string, opts *cloudflare.KVNamespaceGetOptions) (*cloudflare.KVNamespaceValueWithMetadata, error) {
+	kv.writes++
+	if err := kv.KVNamespace.PutString(key, "other"+strconv.Itoa(kv.writes), nil); err != nil {
+		return nil, err
+	}
+	return kv.KVNamespace.GetWithMetadata(key, opts)
+}
@@ -0,0 +1,141 @@
+package queues
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// MessageHandler processes a message of a batch.
+type MessageHandler func(ctx context.Context, m *Message) error
+
+// ProcessOptions represents options of Process and EachMessage.
+type ProcessOptions struct {
+	// Concurrency is the maximum number of messages processed in parallel. Defaults to 1, processing messages in order.
+	Concurrency int
+	// BaseDelaySeconds is the delay of the retry of messages failed at the first attempt. Defaults to 10.
+	//   - the delay is doubled for each attempt.
+	BaseDelaySeconds int
+	// MaxDelaySeconds is the upper limit of delays. Defaults to 43200 (12 hours), which is the limit of Queues.
+	MaxDelaySeconds int
+}
+
+const (
+	defaultBaseDelaySeconds = 10
+	maxDelaySeconds         = 12 * 60 * 60
+)
+
+// Result is the result of a message processed by Process.
+type Result struct {
+	Message *Message
+	// Err is the error returned by the handler. Messages are acked if it is nil, and retried otherwise.
+	Err error
+	// DelaySeconds is the delay of the retry of the failed message.
+	DelaySeconds int
+}
+
+// BatchResult holds results of messages of a batch in the order of the messages.
+type BatchResult struct {
+	Results []*Result
+}
+
+// Failed returns results of failed messages.
+func (r *BatchResult) Failed() []*Result {
+	var failed []*Result
+	for _, res := range r.Results {
+		if res.Err != nil {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// Err returns errors of failed messages joined by errors.Join, or nil if all messages succeeded.
+func (r *BatchResult) Err() error {
+	var errs []error
+	for _, res := range r.Failed() {
+		errs = append(errs, fmt.Errorf("message %s: %w", res.Message.ID, res.Err))
+	}
+	return errors.Join(errs...)
+}
+
+// Process calls the handler for each message of the batch with bounded concurrency.
+//   - messages are acked when the handler succeeds, and retried with the delay of
+//     min(MaxDelaySeconds, BaseDelaySeconds * 2^(attempts-1)) when it fails, so one failure doesn't redeliver the whole batch.
+//   - panics of the handler are recovered, and treated as failures.
+//   - after ctx is done, remaining messages are retried without calling the handler.
+func Process(ctx context.Context, batch *MessageBatch, handler MessageHandler, opts *ProcessOptions) *BatchResult {
+	if opts == nil {
+		opts = &ProcessOptions{}
+	}
+	concurrency := max(opts.Concurrency, 1)
+	result := &BatchResult{Results: make([]*Result, len(batch.Messages))}
+
+	// mu serializes calls of ack and retry to the runtime.
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for i, m := range batch.Messages {
+		i, m := i, m
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			res := &Result{Message: m, Err: ctx.Err()}
+			if res.Err == nil {
+				res.Err = handleMessage(ctx, handler, m)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if res.Err == nil {
+				m.Ack()
+			} else {
+				res.DelaySeconds = retryDelay(m.Attempts, opts)
+				m.Retry(&RetryOptions{DelaySeconds: res.DelaySeconds})
+			}
+			result.Results[i] = res
+		}()
+	}
+	wg.Wait()
+	return result
+}
+
+// EachMessage returns Consumer processing each message of batches by Process.
+//   - failures are handled by retrying the messages, so the consumer doesn't return error.
+func EachMessage(handler MessageHandler, opts *ProcessOptions) Consumer {
+	return func(ctx context.Context, batch *MessageBatch) error {
+		Process(ctx, batch, handler, opts)
+		return nil
+	}
+}
+
+// handleMessage calls the handler, converting the panic into error.
+func handleMessage(ctx context.Context, handler MessageHandler, m *Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return handler(ctx, m)
+}
+
+// retryDelay returns the delay of the retry of the message delivered attempts times.
+func retryDelay(attempts int, opts *ProcessOptions) int {
+	base := opts.BaseDelaySeconds
+	if base <= 0 {
+		base = defaultBaseDelaySeconds
+	}
+	limit := opts.MaxDelaySeconds
+	if limit <= 0 || limit > maxDelaySeconds {
+		limit = maxDelaySeconds
+	}
+	delay := base
+	for i := 1; i < attempts && delay < limit; i++ {
+		delay *= 2
+	}
+	return min(delay, limit)
+}
@@ -0,0 +1,100 @@
+package queues
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/syumai/workers/internal/js"
+)
+
+// fakeBatch returns the batch of messages with the attempts, recording acks and delays of retries by IDs.
+func fakeBatch(attempts []int) (*MessageBatch, map[string]int) {
+	var mu sync.Mutex
+	marks := map[string]int{}
+	msgs := make([]any, len(attempts))
+	for i, a := range attempts {
+		id := string(rune('a' + i))
+		msgs[i] = map[string]any{
+			"id":        id,
+			"body":      id,
+			"attempts":  a,
+			"timestamp": map[string]any{"getTime": js.FuncOf(func(js.Value, []js.Value) any { return 0 })},
+			"ack": js.FuncOf(func(js.Value, []js.Value) any {
+				mu.Lock()
+				defer mu.Unlock()
+				marks[id] = -1
+				return js.Undefined()
+			}),
+			"retry": js.FuncOf(func(_ js.Value, args []js.Value) any {
+				mu.Lock()
+				defer mu.Unlock()
+				marks[id] = args[0].Get("delaySeconds").Int()
+				return js.Undefined()
+			}),
+		}
+	}
+	return toMessageBatch(js.ValueOf(map[string]any{"queue": "q", "messages": msgs})), marks
+}
+
+func TestProcess(t *testing.T) {
+	tests := map[string]struct {
+		opts *ProcessOptions
+	}{
+		"sequential": {opts: nil},
+		"concurrent": {opts: &ProcessOptions{Concurrency: 3}},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			batch, marks := fakeBatch([]int{1, 1, 3, 20})
+			errFailed := errors.New("failed")
+			result := Process(context.Background(), batch, func(ctx context.Context, m *Message) error {
+				switch m.ID {
+				case "b":
+					panic("boom")
+				case "c", "d":
+					return errFailed
+				}
+				return nil
+			}, tc.opts)
+
+			want := map[string]int{"a": -1, "b": 10, "c": 40, "d": maxDelaySeconds}
+			for id, w := range want {
+				if marks[id] != w {
+					t.Errorf("message %s: want mark %d, got %d", id, w, marks[id])
+				}
+			}
+			if failed := result.Failed(); len(failed) != 3 || failed[0].Message.ID != "b" {
+				t.Errorf("want 3 failures from b, got %v", failed)
+			}
+			if err := result.Err(); !errors.Is(err, errFailed) {
+				t.Errorf("want joined error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestProcess_canceled(t *testing.T) {
+	t.Parallel()
+	batch, marks := fakeBatch([]int{1, 2})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	called := false
+	result := Process(ctx, batch, func(ctx context.Context, m *Message) error {
+		called = true
+		return nil
+	}, &ProcessOptions{BaseDelaySeconds: 1})
+	if called {
+		t.Error("want handler not to be called after ctx is done")
+	}
+	if marks["a"] != 1 || marks["b"] != 2 {
+		t.Errorf("want messages to be retried, got %v", marks)
+	}
+	if !errors.Is(result.Err(), context.Canceled) {
+		t.Errorf("want context.Canceled, got %v", result.Err())
+	}
+}
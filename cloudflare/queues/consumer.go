@@ -0,0 +1,149 @@
+package queues
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jsutil"
+	"github.com/syumai/workers/internal/runtimecontext"
+	"github.com/syumai/workers/jsoncodec"
+)
+
+// Message represents a message of the queue delivered to the consumer.
+//   - https://developers.cloudflare.com/queues/configuration/javascript-apis/#message
+type Message struct {
+	ID        string
+	Timestamp time.Time
+	// Attempts is the number of times the message has been delivered, starting from 1.
+	Attempts int
+
+	body     js.Value
+	instance js.Value
+}
+
+func toMessage(v js.Value) *Message {
+	attempts := 1
+	if a := v.Get("attempts"); !a.IsUndefined() {
+		attempts = a.Int()
+	}
+	return &Message{
+		ID:        v.Get("id").String(),
+		Timestamp: time.UnixMilli(int64(v.Get("timestamp").Call("getTime").Float())),
+		Attempts:  attempts,
+		body:      v.Get("body"),
+		instance:  v,
+	}
+}
+
+// Bytes returns the body of the message as bytes.
+//   - text messages are returned as UTF-8 bytes, and bytes messages are returned as they are.
+//   - other messages are returned as JSON.
+func (m *Message) Bytes() []byte {
+	switch {
+	case m.body.Type() == js.TypeString:
+		return []byte(m.body.String())
+	case m.body.InstanceOf(jsutil.Uint8ArrayClass) || m.body.InstanceOf(jsutil.Global.Get("ArrayBuffer")):
+		return jsutil.BufferSourceToBytes(m.body)
+	}
+	return []byte(jsutil.Global.Get("JSON").Call("stringify", m.body).String())
+}
+
+// DecodeJSON decodes the body of the message into T by the codec of the jsoncodec package.
+//   - json and v8 messages are decoded from their values, and text and bytes messages are decoded as JSON texts.
+func DecodeJSON[T any](m *Message) (T, error) {
+	var v T
+	err := jsoncodec.Unmarshal(m.Bytes(), &v)
+	if err != nil && m.body.Type() == js.TypeString {
+		// the body may be a string value of a json message rather than a JSON text.
+		if jsoncodec.Unmarshal([]byte(jsutil.Global.Get("JSON").Call("stringify", m.body).String()), &v) == nil {
+			return v, nil
+		}
+	}
+	if err != nil {
+		return v, fmt.Errorf("error decoding message %s: %w", m.ID, err)
+	}
+	return v, nil
+}
+
+// RetryOptions represents options of Retry.
+//   - https://developers.cloudflare.com/queues/configuration/javascript-apis/#queueretryoptions
+type RetryOptions struct {
+	// DelaySeconds delays the redelivery of the message.
+	DelaySeconds int
+}
+
+func (opts *RetryOptions) toJS() js.Value {
+	if opts == nil || opts.DelaySeconds == 0 {
+		return js.Undefined()
+	}
+	return js.ValueOf(map[string]any{"delaySeconds": opts.DelaySeconds})
+}
+
+// Ack marks the message as delivered successfully, regardless of the result of the consumer.
+func (m *Message) Ack() {
+	m.instance.Call("ack")
+}
+
+// Retry marks the message to be redelivered, regardless of the result of the consumer.
+func (m *Message) Retry(opts *RetryOptions) {
+	m.instance.Call("retry", opts.toJS())
+}
+
+// MessageBatch represents a batch of messages delivered to the consumer.
+//   - https://developers.cloudflare.com/queues/configuration/javascript-apis/#messagebatch
+type MessageBatch struct {
+	// Queue is the name of the queue.
+	Queue    string
+	Messages []*Message
+
+	instance js.Value
+}
+
+func toMessageBatch(v js.Value) *MessageBatch {
+	msgsVal := v.Get("messages")
+	msgs := make([]*Message, msgsVal.Length())
+	for i := range msgs {
+		msgs[i] = toMessage(msgsVal.Index(i))
+	}
+	return &MessageBatch{
+		Queue:    v.Get("queue").String(),
+		Messages: msgs,
+		instance: v,
+	}
+}
+
+// AckAll marks all messages of the batch as delivered successfully.
+func (b *MessageBatch) AckAll() {
+	b.instance.Call("ackAll")
+}
+
+// RetryAll marks all messages of the batch to be redelivered.
+func (b *MessageBatch) RetryAll(opts *RetryOptions) {
+	b.instance.Call("retryAll", opts.toJS())
+}
+
+// Consumer is a function consuming a batch of messages.
+//   - if the consumer returns error, messages which are neither acked nor retried explicitly are retried.
+//   - bindings can be obtained from ctx as well as handlers of requests (e.g. cloudflare.NewKVNamespace(ctx, ...)).
+type Consumer func(ctx context.Context, batch *MessageBatch) error
+
+// RegisterQueue registers the consumer by Consume, so it can be given to workers.Options as the Queue consumer.
+func (c Consumer) RegisterQueue() {
+	Consume(c)
+}
+
+// Consume registers the consumer of queues. It must be called before workers.Serve or workers.Start.
+// The JavaScript entry point must export the queue handler calling `handleQueue`:
+//
+//	async queue(batch, env, ctx) {
+//	  await load;
+//	  await readyPromise;
+//	  return handleQueue(batch, { env, ctx });
+//	}
+func Consume(consumer Consumer) {
+	runtimecontext.RegisterHandler("handleQueue", func(ctx context.Context, batchObj js.Value) error {
+		return consumer(ctx, toMessageBatch(batchObj))
+	})
+}
@@ -0,0 +1,180 @@
+// Package queues provides the producer and the consumer of Cloudflare Queues.
+//   - https://developers.cloudflare.com/queues/
+package queues
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/syumai/workers/cloudflare/internal/cfruntimecontext"
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jsutil"
+	"github.com/syumai/workers/jsoncodec"
+)
+
+// ContentType is the format of message bodies.
+//   - https://developers.cloudflare.com/queues/configuration/javascript-apis/#queuescontenttype
+type ContentType string
+
+const (
+	// ContentTypeText sends a string.
+	ContentTypeText ContentType = "text"
+	// ContentTypeBytes sends raw bytes.
+	ContentTypeBytes ContentType = "bytes"
+	// ContentTypeJSON sends a JSON-serializable value.
+	ContentTypeJSON ContentType = "json"
+	// ContentTypeV8 sends a value serialized by the structured clone algorithm of V8.
+	// Go values are converted through JSON, so it is the same as ContentTypeJSON for Go producers.
+	ContentTypeV8 ContentType = "v8"
+)
+
+// ProducerBinding is the interface implemented by Producer.
+type ProducerBinding interface {
+	Send(body any, opts *SendOptions) error
+	SendBatch(messages []*MessageSendRequest, opts *SendBatchOptions) error
+}
+
+var _ ProducerBinding = (*Producer)(nil)
+
+// Producer represents the producer binding of a queue.
+//   - https://developers.cloudflare.com/queues/configuration/javascript-apis/#producer
+type Producer struct {
+	instance js.Value
+}
+
+// NewProducer returns Producer for given variable name.
+//   - variable name must be defined in wrangler.toml as queues.producers' binding.
+//   - if the given variable name doesn't exist on runtime context, returns error.
+//   - This function panics when a runtime context is not found.
+func NewProducer(ctx context.Context, varName string) (*Producer, error) {
+	inst := cfruntimecontext.GetRuntimeContextEnv(ctx).Get(varName)
+	if inst.IsUndefined() {
+		return nil, fmt.Errorf("%s is undefined", varName)
+	}
+	return &Producer{instance: inst}, nil
+}
+
+// SendOptions represents options of Send.
+//   - https://developers.cloudflare.com/queues/configuration/javascript-apis/#queuesendoptions
+type SendOptions struct {
+	// ContentType is the format of the body. If empty, it is chosen by the type of the body:
+	//   - string: ContentTypeText
+	//   - []byte: ContentTypeBytes
+	//   - others: ContentTypeJSON
+	ContentType ContentType
+	// DelaySeconds delays the delivery of the message.
+	DelaySeconds int
+}
+
+// MessageSendRequest represents a message sent by SendBatch.
+//   - https://developers.cloudflare.com/queues/configuration/javascript-apis/#messagesendrequest
+type MessageSendRequest struct {
+	// Body is the body of the message. It is converted as well as the body of Send.
+	Body any
+	// ContentType is the format of the body. See SendOptions.ContentType.
+	ContentType ContentType
+	// DelaySeconds delays the delivery of the message.
+	DelaySeconds int
+}
+
+// SendBatchOptions represents options of SendBatch.
+//   - https://developers.cloudflare.com/queues/configuration/javascript-apis/#queuesendbatchoptions
+type SendBatchOptions struct {
+	// DelaySeconds delays the delivery of messages without their own DelaySeconds.
+	DelaySeconds int
+}
+
+// Send sends the message to the queue.
+//   - if the body can't be converted for the content type, or a network error happens, returns error.
+func (p *Producer) Send(body any, opts *SendOptions) error {
+	if opts == nil {
+		opts = &SendOptions{}
+	}
+	jsBody, contentType, err := toJSBody(body, opts.ContentType)
+	if err != nil {
+		return err
+	}
+	jsOpts := jsutil.NewObject()
+	jsOpts.Set("contentType", string(contentType))
+	if opts.DelaySeconds != 0 {
+		jsOpts.Set("delaySeconds", opts.DelaySeconds)
+	}
+	_, err = jsutil.AwaitPromise(p.instance.Call("send", jsBody, jsOpts))
+	return err
+}
+
+// SendBatch sends the messages to the queue at once.
+//   - if a body can't be converted for the content type, or a network error happens, returns error.
+func (p *Producer) SendBatch(messages []*MessageSendRequest, opts *SendBatchOptions) error {
+	jsMessages := make([]any, len(messages))
+	for i, m := range messages {
+		jsBody, contentType, err := toJSBody(m.Body, m.ContentType)
+		if err != nil {
+			return fmt.Errorf("error converting message %d: %w", i, err)
+		}
+		obj := jsutil.NewObject()
+		obj.Set("body", jsBody)
+		obj.Set("contentType", string(contentType))
+		if m.DelaySeconds != 0 {
+			obj.Set("delaySeconds", m.DelaySeconds)
+		}
+		jsMessages[i] = obj
+	}
+	jsOpts := js.Undefined()
+	if opts != nil && opts.DelaySeconds != 0 {
+		jsOpts = jsutil.NewObject()
+		jsOpts.Set("delaySeconds", opts.DelaySeconds)
+	}
+	_, err := jsutil.AwaitPromise(p.instance.Call("sendBatch", jsMessages, jsOpts))
+	return err
+}
+
+// SendJSON sends the value as a JSON message encoded by the codec of the jsoncodec package.
+// Consumers can decode the body into the same type by DecodeJSON.
+func SendJSON[T any](p ProducerBinding, v T, opts *SendOptions) error {
+	o := SendOptions{ContentType: ContentTypeJSON}
+	if opts != nil {
+		o.DelaySeconds = opts.DelaySeconds
+	}
+	return p.Send(v, &o)
+}
+
+// toJSBody converts the body into JavaScript value for the content type.
+//   - if the content type is empty, it is chosen by the type of the body.
+func toJSBody(body any, contentType ContentType) (js.Value, ContentType, error) {
+	if contentType == "" {
+		switch body.(type) {
+		case string:
+			contentType = ContentTypeText
+		case []byte:
+			contentType = ContentTypeBytes
+		default:
+			contentType = ContentTypeJSON
+		}
+	}
+	switch contentType {
+	case ContentTypeText:
+		switch b := body.(type) {
+		case string:
+			return js.ValueOf(b), contentType, nil
+		case []byte:
+			return js.ValueOf(string(b)), contentType, nil
+		}
+		return js.Value{}, "", fmt.Errorf("body of text message must be string or []byte, got %T", body)
+	case ContentTypeBytes:
+		switch b := body.(type) {
+		case []byte:
+			return jsutil.NewUint8ArrayFromBytes(b), contentType, nil
+		case string:
+			return jsutil.NewUint8ArrayFromBytes([]byte(b)), contentType, nil
+		}
+		return js.Value{}, "", fmt.Errorf("body of bytes message must be []byte or string, got %T", body)
+	case ContentTypeJSON, ContentTypeV8:
+		b, err := jsoncodec.Marshal(body)
+		if err != nil {
+			return js.Value{}, "", fmt.Errorf("error encoding body: %w", err)
+		}
+		return jsutil.Global.Get("JSON").Call("parse", string(b)), contentType, nil
+	}
+	return js.Value{}, "", fmt.Errorf("unknown content type: %s", contentType)
+}
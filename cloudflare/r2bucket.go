@@ -4,17 +4,36 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"syscall/js"
 
 	"github.com/syumai/workers/cloudflare/internal/cfruntimecontext"
+	"github.com/syumai/workers/internal/js"
 	"github.com/syumai/workers/internal/jsutil"
+	"github.com/syumai/workers/internal/runtimecontext"
 )
 
+// R2BucketBinding is the interface implemented by R2Bucket.
+type R2BucketBinding interface {
+	Head(key string) (*R2Object, error)
+	Get(key string) (*R2Object, error)
+	GetRange(key string, rng *R2Range) (*R2Object, error)
+	Put(key string, value io.ReadCloser, opts *R2PutOptions) (*R2Object, error)
+	Delete(key string) error
+	List() (*R2Objects, error)
+	ListWithOptions(opts *R2ListOptions) (*R2Objects, error)
+	CreateMultipartUpload(key string, opts *R2MultipartOptions) (R2MultipartUpload, error)
+	ResumeMultipartUpload(key string, uploadID string) R2MultipartUpload
+}
+
+var _ R2BucketBinding = (*R2Bucket)(nil)
+
 // R2Bucket represents interface of Cloudflare Worker's R2 Bucket instance.
 //   - https://developers.cloudflare.com/r2/runtime-apis/#bucket-method-definitions
 //   - https://github.com/cloudflare/workers-types/blob/3012f263fb1239825e5f0061b267c8650d01b717/index.d.ts#L1006
 type R2Bucket struct {
 	instance js.Value
+	retry    *RetryPolicy
+	// subrequests counts calls as subrequests of the event the binding was obtained in.
+	subrequests *runtimecontext.Subrequests
 }
 
 // NewR2Bucket returns R2Bucket for given variable name.
@@ -27,7 +46,14 @@ func NewR2Bucket(ctx context.Context, varName string) (*R2Bucket, error) {
 	if inst.IsUndefined() {
 		return nil, fmt.Errorf("%s is undefined", varName)
 	}
-	return &R2Bucket{instance: inst}, nil
+	return &R2Bucket{instance: inst, subrequests: runtimecontext.SubrequestsFrom(ctx)}, nil
+}
+
+// WithRetry returns a copy of the bucket which retries calls failed by transient errors with the policy.
+//   - Bodies of Put are sent again on retries, since they are copied into memory.
+//   - Reading bodies of returned objects is not retried.
+func (r *R2Bucket) WithRetry(policy *RetryPolicy) *R2Bucket {
+	return &R2Bucket{instance: r.instance, retry: policy, subrequests: r.subrequests}
 }
 
 // Head returns the result of `head` call to R2Bucket.
@@ -35,8 +61,9 @@ func NewR2Bucket(ctx context.Context, varName string) (*R2Bucket, error) {
 //   - if the object for given key doesn't exist, returns nil.
 //   - if a network error happens, returns error.
 func (r *R2Bucket) Head(key string) (*R2Object, error) {
-	p := r.instance.Call("head", key)
-	v, err := jsutil.AwaitPromise(p)
+	v, err := r.retry.await(r.subrequests, func() js.Value {
+		return r.instance.Call("head", key)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -50,8 +77,45 @@ func (r *R2Bucket) Head(key string) (*R2Object, error) {
 //   - if the object for given key doesn't exist, returns nil.
 //   - if a network error happens, returns error.
 func (r *R2Bucket) Get(key string) (*R2Object, error) {
-	p := r.instance.Call("get", key)
-	v, err := jsutil.AwaitPromise(p)
+	v, err := r.retry.await(r.subrequests, func() js.Value {
+		return r.instance.Call("get", key)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if v.IsNull() {
+		return nil, nil
+	}
+	return toR2Object(v)
+}
+
+// R2Range represents the range of R2 object to get.
+//   - https://developers.cloudflare.com/r2/api/workers/workers-api-reference/#ranged-reads
+type R2Range struct {
+	Offset int64
+	// Length is the number of bytes to get. The value `0` means the rest of the object.
+	Length int64
+}
+
+func (rng *R2Range) toJS() js.Value {
+	obj := jsutil.NewObject()
+	obj.Set("offset", rng.Offset)
+	if rng.Length != 0 {
+		obj.Set("length", rng.Length)
+	}
+	return obj
+}
+
+// GetRange returns the result of `get` call to R2Bucket with the range option.
+//   - Body field of *R2Object contains only the bytes of the range.
+//   - if the object for given key doesn't exist, returns nil.
+//   - if a network error happens, returns error.
+func (r *R2Bucket) GetRange(key string, rng *R2Range) (*R2Object, error) {
+	opts := jsutil.NewObject()
+	opts.Set("range", rng.toJS())
+	v, err := r.retry.await(r.subrequests, func() js.Value {
+		return r.instance.Call("get", key, opts)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -106,8 +170,9 @@ func (r *R2Bucket) Put(key string, value io.ReadCloser, opts *R2PutOptions) (*R2
 	defer value.Close()
 	ua := jsutil.NewUint8Array(len(b))
 	js.CopyBytesToJS(ua, b)
-	p := r.instance.Call("put", key, ua.Get("buffer"), opts.toJS())
-	v, err := jsutil.AwaitPromise(p)
+	v, err := r.retry.await(r.subrequests, func() js.Value {
+		return r.instance.Call("put", key, ua.Get("buffer"), opts.toJS())
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -117,8 +182,10 @@ func (r *R2Bucket) Put(key string, value io.ReadCloser, opts *R2PutOptions) (*R2
 // Delete returns the result of `delete` call to R2Bucket.
 //   - if a network error happens, returns error.
 func (r *R2Bucket) Delete(key string) error {
-	p := r.instance.Call("delete", key)
-	if _, err := jsutil.AwaitPromise(p); err != nil {
+	_, err := r.retry.await(r.subrequests, func() js.Value {
+		return r.instance.Call("delete", key)
+	})
+	if err != nil {
 		return err
 	}
 	return nil
@@ -127,8 +194,53 @@ func (r *R2Bucket) Delete(key string) error {
 // List returns the result of `list` call to R2Bucket.
 //   - if a network error happens, returns error.
 func (r *R2Bucket) List() (*R2Objects, error) {
-	p := r.instance.Call("list")
-	v, err := jsutil.AwaitPromise(p)
+	return r.ListWithOptions(nil)
+}
+
+// R2ListOptions represents Cloudflare R2 list options.
+//   - https://developers.cloudflare.com/r2/api/workers/workers-api-reference/#r2listoptions
+type R2ListOptions struct {
+	// Limit is the maximum number of objects returned. Defaults to 1000, which is the maximum.
+	Limit int
+	// Prefix limits objects to keys starting with the prefix.
+	Prefix string
+	// Cursor is the cursor of the next page returned as R2Objects.Cursor.
+	Cursor string
+	// Delimiter groups keys sharing the same prefix up to the delimiter into R2Objects.DelimitedPrefixes.
+	Delimiter string
+	// StartAfter lists objects after the key lexicographically.
+	StartAfter string
+}
+
+func (opts *R2ListOptions) toJS() js.Value {
+	if opts == nil {
+		return js.Undefined()
+	}
+	obj := jsutil.NewObject()
+	if opts.Limit != 0 {
+		obj.Set("limit", opts.Limit)
+	}
+	if opts.Prefix != "" {
+		obj.Set("prefix", opts.Prefix)
+	}
+	if opts.Cursor != "" {
+		obj.Set("cursor", opts.Cursor)
+	}
+	if opts.Delimiter != "" {
+		obj.Set("delimiter", opts.Delimiter)
+	}
+	if opts.StartAfter != "" {
+		obj.Set("startAfter", opts.StartAfter)
+	}
+	return obj
+}
+
+// ListWithOptions returns the result of `list` call to R2Bucket with the options.
+//   - if a network error happens, returns error.
+func (r *R2Bucket) ListWithOptions(opts *R2ListOptions) (*R2Objects, error) {
+	v, err := r.retry.await(r.subrequests, func() js.Value {
+		return r.instance.Call("list", opts.toJS())
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,189 @@
+package r2fs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"time"
+
+	"github.com/syumai/workers/cloudflare"
+)
+
+// fileInfo is fs.FileInfo of files and directories, which is encoded into JSON for the metadata cache.
+type fileInfo struct {
+	FileName string    `json:"name"`
+	FileSize int64     `json:"size,omitempty"`
+	Modified time.Time `json:"mtime,omitempty"`
+	Dir      bool      `json:"dir,omitempty"`
+	// Missing reports that the file doesn't exist, cached to avoid repeated lookups.
+	Missing bool `json:"missing,omitempty"`
+}
+
+var _ fs.FileInfo = (*fileInfo)(nil)
+
+// objectInfo returns fileInfo of the object. The time is normalized to UTC without the monotonic clock reading, so that cached and fresh infos are equal.
+func objectInfo(name string, obj *cloudflare.R2Object) *fileInfo {
+	return &fileInfo{FileName: name, FileSize: int64(obj.Size), Modified: obj.Uploaded.UTC().Round(0)}
+}
+
+func (i *fileInfo) Name() string       { return i.FileName }
+func (i *fileInfo) Size() int64        { return i.FileSize }
+func (i *fileInfo) ModTime() time.Time { return i.Modified }
+func (i *fileInfo) IsDir() bool        { return i.Dir }
+func (i *fileInfo) Sys() any           { return nil }
+
+func (i *fileInfo) Mode() fs.FileMode {
+	if i.Dir {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
+
+// file is a file of an object. The body is read by ranged gets from the offset.
+type file struct {
+	fs     *FS
+	key    string
+	info   *fileInfo
+	offset int64
+	body   io.Reader
+	closed bool
+}
+
+var (
+	_ io.ReadSeeker = (*file)(nil)
+	_ io.ReaderAt   = (*file)(nil)
+)
+
+func (f *file) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	if f.offset >= f.info.FileSize {
+		return 0, io.EOF
+	}
+	if f.body == nil {
+		obj, err := f.fs.bucket.GetRange(f.key, &cloudflare.R2Range{Offset: f.offset})
+		if err != nil {
+			return 0, err
+		}
+		if obj == nil {
+			return 0, fs.ErrNotExist
+		}
+		f.body = obj.Body
+	}
+	n, err := f.body.Read(p)
+	f.offset += int64(n)
+	if err == io.EOF && f.offset < f.info.FileSize {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.info.FileSize
+	}
+	if offset < 0 {
+		return 0, errors.New("r2fs: negative position")
+	}
+	if offset != f.offset {
+		// the body is fetched again from the new offset on the next read.
+		f.offset = offset
+		f.body = nil
+	}
+	return offset, nil
+}
+
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	if off >= f.info.FileSize {
+		return 0, io.EOF
+	}
+	length := min(int64(len(p)), f.info.FileSize-off)
+	obj, err := f.fs.bucket.GetRange(f.key, &cloudflare.R2Range{Offset: off, Length: length})
+	if err != nil {
+		return 0, err
+	}
+	if obj == nil {
+		return 0, fs.ErrNotExist
+	}
+	n, err := io.ReadFull(obj.Body, p[:length])
+	if err == nil && n < len(p) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (f *file) Close() error {
+	if f.closed {
+		return fs.ErrClosed
+	}
+	f.closed = true
+	return nil
+}
+
+// dir is a directory of a key prefix. Entries are listed on the first ReadDir.
+type dir struct {
+	fs      *FS
+	name    string
+	info    *fileInfo
+	entries []*fileInfo
+	listed  bool
+	closed  bool
+}
+
+var _ fs.ReadDirFile = (*dir)(nil)
+
+func (d *dir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *dir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if d.closed {
+		return nil, fs.ErrClosed
+	}
+	if !d.listed {
+		entries, err := d.fs.readDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries, d.listed = entries, true
+	}
+	count := len(d.entries)
+	if n > 0 && n < count {
+		count = n
+	}
+	if n > 0 && count == 0 {
+		return nil, io.EOF
+	}
+	result := make([]fs.DirEntry, count)
+	for i := range result {
+		result[i] = fs.FileInfoToDirEntry(d.entries[i])
+	}
+	d.entries = d.entries[count:]
+	return result, nil
+}
+
+func (d *dir) Close() error {
+	if d.closed {
+		return fs.ErrClosed
+	}
+	d.closed = true
+	return nil
+}
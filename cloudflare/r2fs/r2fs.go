@@ -0,0 +1,255 @@
+// Package r2fs implements io/fs.FS over an R2 bucket, so code accepting fs.FS (e.g. template.ParseFS, workers.ServeFS)
+// can read R2 transparently.
+//   - keys are paths separated by "/", and prefixes of keys are directories. Directories don't exist as objects.
+//   - files are read lazily by ranged gets, so they can be seeked (io.Seeker) and read at offsets (io.ReaderAt)
+//     without downloading whole objects.
+//   - metadata of files and directory listings can be cached in KV to reduce R2 operations.
+package r2fs
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/syumai/workers/cloudflare"
+)
+
+// minKVTTL is the minimum expiration TTL of KV.
+const minKVTTL = 60 * time.Second
+
+// Options represents options of New.
+type Options struct {
+	// Root is the key prefix of the root directory (e.g. "assets"). Defaults to the root of the bucket.
+	Root string
+	// MetadataCache caches metadata of files and directory listings if set.
+	// Changes to the bucket are not visible until cached entries expire.
+	MetadataCache cloudflare.KVNamespaceBinding
+	// MetadataCacheTTL is how long metadata is cached. Defaults to 5 minutes, and the minimum is 60 seconds.
+	MetadataCacheTTL time.Duration
+}
+
+// FS is fs.FS over an R2 bucket. It also implements fs.ReadDirFS, fs.StatFS and fs.ReadFileFS.
+type FS struct {
+	bucket cloudflare.R2BucketBinding
+	root   string
+	kv     cloudflare.KVNamespaceBinding
+	ttl    time.Duration
+}
+
+var (
+	_ fs.ReadDirFS  = (*FS)(nil)
+	_ fs.StatFS     = (*FS)(nil)
+	_ fs.ReadFileFS = (*FS)(nil)
+)
+
+// New returns FS over the bucket.
+func New(bucket cloudflare.R2BucketBinding, opts *Options) *FS {
+	f := &FS{bucket: bucket, ttl: 5 * time.Minute}
+	if opts != nil {
+		f.root = strings.Trim(opts.Root, "/")
+		f.kv = opts.MetadataCache
+		if opts.MetadataCacheTTL > 0 {
+			f.ttl = max(opts.MetadataCacheTTL, minKVTTL)
+		}
+	}
+	return f
+}
+
+// key returns the key of the object of the name.
+func (f *FS) key(name string) string {
+	if name == "." {
+		return f.root
+	}
+	if f.root == "" {
+		return name
+	}
+	return f.root + "/" + name
+}
+
+// dirPrefix returns the key prefix of entries of the directory of the name.
+func (f *FS) dirPrefix(name string) string {
+	if k := f.key(name); k != "" {
+		return k + "/"
+	}
+	return ""
+}
+
+// Open opens the file or the directory of the name.
+func (f *FS) Open(name string) (fs.File, error) {
+	info, err := f.stat("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &dir{fs: f, name: name, info: info}, nil
+	}
+	return &file{fs: f, key: f.key(name), info: info}, nil
+}
+
+// Stat returns fs.FileInfo of the file or the directory of the name.
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	return f.stat("stat", name)
+}
+
+func (f *FS) stat(op, name string) (*fileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &fileInfo{FileName: ".", Dir: true}, nil
+	}
+	var info fileInfo
+	if f.cached("stat:"+name, &info) {
+		if info.Missing {
+			return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+		return &info, nil
+	}
+	obj, err := f.bucket.Head(f.key(name))
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	switch {
+	case obj != nil:
+		info = *objectInfo(path.Base(name), obj)
+	default:
+		objs, err := f.bucket.ListWithOptions(&cloudflare.R2ListOptions{Prefix: f.dirPrefix(name), Limit: 1})
+		if err != nil {
+			return nil, &fs.PathError{Op: op, Path: name, Err: err}
+		}
+		if len(objs.Objects) == 0 && len(objs.DelimitedPrefixes) == 0 {
+			info = fileInfo{Missing: true}
+		} else {
+			info = fileInfo{FileName: path.Base(name), Dir: true}
+		}
+	}
+	f.cache("stat:"+name, &info)
+	if info.Missing {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return &info, nil
+}
+
+// ReadDir reads the directory of the name, and returns its entries sorted by names.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	infos, err := f.readDir(name)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]fs.DirEntry, len(infos))
+	for i, info := range infos {
+		entries[i] = fs.FileInfoToDirEntry(info)
+	}
+	return entries, nil
+}
+
+func (f *FS) readDir(name string) ([]*fileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	var infos []*fileInfo
+	if f.cached("dir:"+name, &infos) {
+		if infos == nil {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+		}
+		return infos, nil
+	}
+	prefix := f.dirPrefix(name)
+	opts := &cloudflare.R2ListOptions{Prefix: prefix, Delimiter: "/"}
+	infos = []*fileInfo{}
+	seen := map[string]bool{}
+	for {
+		objs, err := f.bucket.ListWithOptions(opts)
+		if err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+		}
+		for _, obj := range objs.Objects {
+			base := strings.TrimPrefix(obj.Key, prefix)
+			// keys ending with "/" or containing empty segments can't be represented as files.
+			if base == "" || !fs.ValidPath(base) {
+				continue
+			}
+			infos = append(infos, objectInfo(base, obj))
+		}
+		for _, p := range objs.DelimitedPrefixes {
+			base := strings.TrimSuffix(strings.TrimPrefix(p, prefix), "/")
+			if base == "" || !fs.ValidPath(base) || seen[base] {
+				continue
+			}
+			seen[base] = true
+			infos = append(infos, &fileInfo{FileName: base, Dir: true})
+		}
+		if !objs.Truncated || objs.Cursor == "" {
+			break
+		}
+		opts.Cursor = objs.Cursor
+	}
+	if len(infos) == 0 && name != "." {
+		// an empty listing means the directory doesn't exist, unless the name is a file.
+		if info, err := f.stat("readdir", name); err != nil || !info.IsDir() {
+			f.cache("dir:"+name, (*[]*fileInfo)(nil))
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].FileName < infos[j].FileName
+	})
+	f.cache("dir:"+name, &infos)
+	return infos, nil
+}
+
+// ReadFile reads the whole file of the name.
+func (f *FS) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: errors.New("is a directory")}
+	}
+	obj, err := f.bucket.Get(f.key(name))
+	if err != nil {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: err}
+	}
+	if obj == nil {
+		if info, err := f.stat("readfile", name); err == nil && info.IsDir() {
+			return nil, &fs.PathError{Op: "readfile", Path: name, Err: errors.New("is a directory")}
+		}
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrNotExist}
+	}
+	return io.ReadAll(obj.Body)
+}
+
+// cached decodes the cached value of the key into v. It returns false if the metadata cache is disabled or missed.
+func (f *FS) cached(key string, v any) bool {
+	if f.kv == nil {
+		return false
+	}
+	entry, err := f.kv.GetWithMetadata(f.cacheKey(key), nil)
+	if err != nil || entry == nil {
+		return false
+	}
+	return json.Unmarshal(entry.Value, v) == nil
+}
+
+// cache stores v as the value of the key. Failures are ignored, since the cache is optional.
+func (f *FS) cache(key string, v any) {
+	if f.kv == nil {
+		return
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	_ = f.kv.PutString(f.cacheKey(key), string(b), &cloudflare.KVNamespacePutOptions{
+		ExpirationTTL: int(f.ttl / time.Second),
+	})
+}
+
+func (f *FS) cacheKey(key string) string {
+	return "r2fs:" + f.root + ":" + key
+}
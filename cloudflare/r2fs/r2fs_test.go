@@ -0,0 +1,88 @@
+package r2fs_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/cloudflare/r2fs"
+	"github.com/syumai/workers/workerstest"
+)
+
+func newBucket(t *testing.T, files map[string]string) *workerstest.R2Bucket {
+	t.Helper()
+	bucket := &workerstest.R2Bucket{}
+	for key, data := range files {
+		if _, err := bucket.Put(key, io.NopCloser(strings.NewReader(data)), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return bucket
+}
+
+func TestFS(t *testing.T) {
+	bucket := newBucket(t, map[string]string{
+		"site/index.html":           "<h1>index</h1>",
+		"site/css/style.css":        "body {}",
+		"site/js/app/main.js":       "main()",
+		"site/js/app/vendor/lib.js": "lib()",
+		"other/secret.txt":          "secret",
+	})
+	tests := map[string]*r2fs.Options{
+		"without cache": {Root: "site"},
+		"with cache":    {Root: "site", MetadataCache: &workerstest.KVNamespace{}},
+	}
+	for name, opts := range tests {
+		name := name
+		opts := opts
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			fsys := r2fs.New(bucket, opts)
+			if err := fstest.TestFS(fsys, "index.html", "css/style.css", "js/app/main.js", "js/app/vendor/lib.js"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := fs.Stat(fsys, "secret.txt"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("want ErrNotExist outside the root, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFS_Seek(t *testing.T) {
+	bucket := newBucket(t, map[string]string{"data.txt": "0123456789"})
+	var gets int
+	bucket.GetRangeFunc = func(key string, rng *cloudflare.R2Range) (*cloudflare.R2Object, error) {
+		gets++
+		obj, err := bucket.Get(key)
+		if err != nil || obj == nil {
+			return obj, err
+		}
+		b, _ := io.ReadAll(obj.Body)
+		end := int64(len(b))
+		if rng.Length != 0 {
+			end = rng.Offset + rng.Length
+		}
+		obj.Body = strings.NewReader(string(b[rng.Offset:end]))
+		return obj, nil
+	}
+	f, err := r2fs.New(bucket, nil).Open("data.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rs := f.(io.ReadSeeker)
+	if _, err := rs.Seek(6, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(rs)
+	if err != nil || string(b) != "6789" {
+		t.Errorf("want 6789, got %q, %v", b, err)
+	}
+	if gets != 1 {
+		t.Errorf("want 1 ranged get, got %d", gets)
+	}
+}
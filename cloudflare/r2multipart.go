@@ -0,0 +1,119 @@
+package cloudflare
+
+import (
+	"io"
+
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jsutil"
+)
+
+// R2MultipartOptions represents options of CreateMultipartUpload.
+//   - https://developers.cloudflare.com/r2/api/workers/workers-api-reference/#r2multipartoptions
+type R2MultipartOptions struct {
+	HTTPMetadata   R2HTTPMetadata
+	CustomMetadata map[string]string
+}
+
+func (opts *R2MultipartOptions) toJS() js.Value {
+	if opts == nil {
+		return js.Undefined()
+	}
+	return (&R2PutOptions{
+		HTTPMetadata:   opts.HTTPMetadata,
+		CustomMetadata: opts.CustomMetadata,
+	}).toJS()
+}
+
+// R2UploadedPart represents a part uploaded by R2MultipartUpload.
+//   - https://developers.cloudflare.com/r2/api/workers/workers-api-reference/#r2uploadedpart-definition
+type R2UploadedPart struct {
+	PartNumber int
+	ETag       string
+}
+
+// R2MultipartUpload represents an in-progress multipart upload of R2.
+//   - https://developers.cloudflare.com/r2/api/workers/workers-api-reference/#r2multipartupload-definition
+//   - all parts except the last one must have the same size, and be at least 5 MiB.
+type R2MultipartUpload interface {
+	// Key returns the key of the object.
+	Key() string
+	// UploadID returns the ID of the upload, which can be used to resume the upload.
+	UploadID() string
+	// UploadPart uploads the part. partNumber starts from 1.
+	UploadPart(partNumber int, value io.Reader) (*R2UploadedPart, error)
+	// Abort aborts the upload.
+	Abort() error
+	// Complete completes the upload by the parts in the order of the slice.
+	// Body field of *R2Object is always nil.
+	Complete(parts []*R2UploadedPart) (*R2Object, error)
+}
+
+type r2MultipartUpload struct {
+	instance js.Value
+}
+
+var _ R2MultipartUpload = (*r2MultipartUpload)(nil)
+
+// CreateMultipartUpload starts a multipart upload of the object.
+//   - if a network error happens, returns error.
+func (r *R2Bucket) CreateMultipartUpload(key string, opts *R2MultipartOptions) (R2MultipartUpload, error) {
+	p := r.instance.Call("createMultipartUpload", key, opts.toJS())
+	v, err := jsutil.AwaitPromise(p)
+	if err != nil {
+		return nil, err
+	}
+	return &r2MultipartUpload{instance: v}, nil
+}
+
+// ResumeMultipartUpload returns the multipart upload for the key and the upload ID.
+//   - The upload is not validated until UploadPart, Abort or Complete is called.
+func (r *R2Bucket) ResumeMultipartUpload(key string, uploadID string) R2MultipartUpload {
+	v := r.instance.Call("resumeMultipartUpload", key, uploadID)
+	return &r2MultipartUpload{instance: v}
+}
+
+func (u *r2MultipartUpload) Key() string {
+	return u.instance.Get("key").String()
+}
+
+func (u *r2MultipartUpload) UploadID() string {
+	return u.instance.Get("uploadId").String()
+}
+
+// UploadPart uploads the part.
+//   - This method copies all bytes of the part into memory for implementation restriction.
+func (u *r2MultipartUpload) UploadPart(partNumber int, value io.Reader) (*R2UploadedPart, error) {
+	b, err := io.ReadAll(value)
+	if err != nil {
+		return nil, err
+	}
+	p := u.instance.Call("uploadPart", partNumber, jsutil.NewUint8ArrayFromBytes(b))
+	v, err := jsutil.AwaitPromise(p)
+	if err != nil {
+		return nil, err
+	}
+	return &R2UploadedPart{
+		PartNumber: v.Get("partNumber").Int(),
+		ETag:       v.Get("etag").String(),
+	}, nil
+}
+
+func (u *r2MultipartUpload) Abort() error {
+	_, err := jsutil.AwaitPromise(u.instance.Call("abort"))
+	return err
+}
+
+func (u *r2MultipartUpload) Complete(parts []*R2UploadedPart) (*R2Object, error) {
+	jsParts := make([]any, len(parts))
+	for i, part := range parts {
+		jsParts[i] = map[string]any{
+			"partNumber": part.PartNumber,
+			"etag":       part.ETag,
+		}
+	}
+	v, err := jsutil.AwaitPromise(u.instance.Call("complete", jsParts))
+	if err != nil {
+		return nil, err
+	}
+	return toR2Object(v)
+}
@@ -4,9 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"syscall/js"
 	"time"
 
+	"github.com/syumai/workers/internal/js"
 	"github.com/syumai/workers/internal/jsutil"
 )
 
@@ -33,6 +33,9 @@ type R2Object struct {
 // }
 
 func (o *R2Object) BodyUsed() (bool, error) {
+	if o.instance.IsUndefined() {
+		return false, errors.New("bodyUsed doesn't exist for this R2Object")
+	}
 	v := o.instance.Get("bodyUsed")
 	if v.IsUndefined() {
 		return false, errors.New("bodyUsed doesn't exist for this R2Object")
@@ -54,7 +57,7 @@ func toR2Object(v js.Value) (*R2Object, error) {
 	bodyVal := v.Get("body")
 	var body io.Reader
 	if !bodyVal.IsUndefined() {
-		body = jsutil.ConvertStreamReaderToReader(v.Get("body").Call("getReader"))
+		body = jsutil.ConvertByteStreamToReader(bodyVal)
 	}
 	return &R2Object{
 		instance:       v,
@@ -2,8 +2,8 @@ package cloudflare
 
 import (
 	"fmt"
-	"syscall/js"
 
+	"github.com/syumai/workers/internal/js"
 	"github.com/syumai/workers/internal/jsutil"
 )
 
@@ -0,0 +1,207 @@
+// Package r2presign generates presigned URLs of R2 objects for the S3-compatible API.
+//   - https://developers.cloudflare.com/r2/api/s3/presigned-urls/
+//   - URLs are signed by AWS Signature Version 4, so clients can download / upload objects directly
+//     without proxying bytes through the worker.
+package r2presign
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/internal/awssig"
+)
+
+const (
+	// MaxExpires is the maximum lifetime of presigned URLs.
+	MaxExpires = 7 * 24 * time.Hour
+
+	// Default names of environment variables read by NewSigner.
+	EnvAccountID       = "R2_ACCOUNT_ID"
+	EnvAccessKeyID     = "R2_ACCESS_KEY_ID"
+	EnvSecretAccessKey = "R2_SECRET_ACCESS_KEY"
+
+	region  = "auto"
+	service = "s3"
+)
+
+// Signer generates presigned URLs of objects in the bucket.
+type Signer struct {
+	AccountID       string
+	AccessKeyID     string
+	SecretAccessKey string
+	// Bucket is the name of the bucket. Note that it is not the name of the binding.
+	Bucket string
+	// Jurisdiction is the jurisdiction of the bucket (e.g. "eu"). Empty means the default jurisdiction.
+	Jurisdiction string
+	// Now returns the signing time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// NewSigner returns Signer for the bucket, reading credentials from environment variables.
+//   - R2_ACCOUNT_ID, R2_ACCESS_KEY_ID and R2_SECRET_ACCESS_KEY must be defined as variables or secrets.
+//   - if one of them is not defined, returns error.
+//   - This function panics when a runtime context is not found.
+func NewSigner(ctx context.Context, bucket string) (*Signer, error) {
+	s := &Signer{Bucket: bucket}
+	for _, v := range []struct {
+		name string
+		dst  *string
+	}{
+		{EnvAccountID, &s.AccountID},
+		{EnvAccessKeyID, &s.AccessKeyID},
+		{EnvSecretAccessKey, &s.SecretAccessKey},
+	} {
+		val, ok := cloudflare.LookupEnv(ctx, v.name)
+		if !ok || val == "" {
+			return nil, fmt.Errorf("%s is undefined", v.name)
+		}
+		*v.dst = val
+	}
+	return s, nil
+}
+
+// Options represents options of Presign.
+type Options struct {
+	// Expires is the lifetime of the URL. Defaults to 15 minutes, and must not exceed MaxExpires.
+	Expires time.Duration
+	// ContentType is signed as the Content-Type header, so clients must upload with the same header.
+	ContentType string
+	// Query is added to the URL and signed (e.g. "response-content-disposition").
+	Query url.Values
+}
+
+const defaultExpires = 15 * time.Minute
+
+// PresignGet returns a presigned URL to download the object.
+func (s *Signer) PresignGet(key string, opts *Options) (string, error) {
+	return s.Presign("GET", key, opts)
+}
+
+// PresignPut returns a presigned URL to upload the object.
+func (s *Signer) PresignPut(key string, opts *Options) (string, error) {
+	return s.Presign("PUT", key, opts)
+}
+
+// Presign returns a presigned URL of the object for the method.
+//   - if credentials are empty or the expiration is out of range, returns error.
+func (s *Signer) Presign(method, key string, opts *Options) (string, error) {
+	if s.AccountID == "" || s.AccessKeyID == "" || s.SecretAccessKey == "" {
+		return "", errors.New("r2presign: credentials are empty")
+	}
+	if s.Bucket == "" || key == "" {
+		return "", errors.New("r2presign: bucket and key must not be empty")
+	}
+	if opts == nil {
+		opts = &Options{}
+	}
+	expires := opts.Expires
+	if expires == 0 {
+		expires = defaultExpires
+	}
+	if expires < time.Second || expires > MaxExpires {
+		return "", fmt.Errorf("r2presign: expires must be between 1s and %s, got %s", MaxExpires, expires)
+	}
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	host := s.AccountID + ".r2.cloudflarestorage.com"
+	if s.Jurisdiction != "" {
+		host = s.AccountID + "." + s.Jurisdiction + ".r2.cloudflarestorage.com"
+	}
+	headers := map[string]string{"host": host}
+	if opts.ContentType != "" {
+		headers["content-type"] = opts.ContentType
+	}
+	p := &presignParams{
+		method:    method,
+		host:      host,
+		path:      "/" + s.Bucket + "/" + key,
+		query:     opts.Query,
+		headers:   headers,
+		accessKey: s.AccessKeyID,
+		secretKey: s.SecretAccessKey,
+		region:    region,
+		time:      now().UTC(),
+		expires:   expires,
+	}
+	return p.presign(), nil
+}
+
+// presignParams holds parameters of a request to be presigned.
+type presignParams struct {
+	method    string
+	host      string
+	path      string
+	query     url.Values
+	headers   map[string]string
+	accessKey string
+	secretKey string
+	region    string
+	time      time.Time
+	expires   time.Duration
+}
+
+// presign signs the request by query parameters.
+//   - https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-query-string-auth.html
+func (p *presignParams) presign() string {
+	date := p.time.Format(awssig.DateFormat)
+	scope := awssig.Scope(date, p.region, service)
+
+	canonicalHeaders, signedHeaders := awssig.CanonicalHeaders(p.headers)
+
+	query := url.Values{}
+	for k, vs := range p.query {
+		query[k] = append([]string(nil), vs...)
+	}
+	query.Set("X-Amz-Algorithm", awssig.Algorithm)
+	query.Set("X-Amz-Credential", p.accessKey+"/"+scope)
+	query.Set("X-Amz-Date", p.time.Format(awssig.TimeFormat))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(p.expires/time.Second)))
+	query.Set("X-Amz-SignedHeaders", signedHeaders)
+	canonicalQuery := awssig.CanonicalQueryString(query)
+
+	canonicalRequest := strings.Join([]string{
+		p.method,
+		awssig.URIEncode(p.path, false),
+		canonicalQuery,
+		canonicalHeaders,
+		signedHeaders,
+		// the payload is not signed, since the body is sent by the client later.
+		awssig.UnsignedPayload,
+	}, "\n")
+	stringToSign := strings.Join([]string{
+		awssig.Algorithm,
+		p.time.Format(awssig.TimeFormat),
+		scope,
+		hexSHA256(canonicalRequest),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+p.secretKey), date)
+	key = hmacSHA256(key, p.region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	return "https://" + p.host + awssig.URIEncode(p.path, false) + "?" + canonicalQuery + "&X-Amz-Signature=" + signature
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func hexSHA256(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,255 @@
+// Package r2upload streams files of multipart/form-data requests into R2.
+//   - Files are read part by part, and large files are uploaded by R2 multipart uploads,
+//     so at most one part of a file is held in memory regardless of the size of the file.
+package r2upload
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/syumai/workers/cloudflare"
+)
+
+const (
+	// DefaultPartSize is the default size of parts of multipart uploads.
+	DefaultPartSize = 10 << 20
+	// MinPartSize is the minimum size of parts of multipart uploads required by R2.
+	MinPartSize = 5 << 20
+	// defaultMaxValueSize is the default maximum size of a non-file field.
+	defaultMaxValueSize = 1 << 20
+)
+
+// ErrValueTooLarge is returned when a non-file field exceeds Options.MaxValueSize.
+var ErrValueTooLarge = errors.New("r2upload: form value is too large")
+
+// Options represents options of Upload.
+type Options struct {
+	// Key returns the key of the object for the file. Defaults to RandomKey.
+	// If it returns an empty string, the file is skipped.
+	//   - FileNameKey names objects by file names given by clients. It must be used only if uploaders are allowed
+	//     to overwrite any object of the bucket.
+	Key func(fieldName, fileName string) (string, error)
+	// PartSize is the size of parts of R2 multipart uploads. Defaults to DefaultPartSize.
+	// Files smaller than PartSize are uploaded by a single put.
+	// R2 requires parts except the last one to be at least 5 MiB, so Upload returns error if PartSize is less than MinPartSize.
+	PartSize int
+	// MaxValueSize is the maximum size of a non-file field. Defaults to 1 MiB.
+	MaxValueSize int64
+	// Progress is called after each part of a file is uploaded.
+	Progress func(p *Progress)
+}
+
+// Progress represents the progress of a file being uploaded.
+type Progress struct {
+	FieldName string
+	FileName  string
+	Key       string
+	// Part is the number of parts uploaded so far.
+	Part int
+	// BytesUploaded is the number of bytes of the file uploaded so far.
+	BytesUploaded int64
+	// Done reports whether the file is uploaded completely.
+	Done bool
+}
+
+// File represents a file uploaded to R2.
+type File struct {
+	FieldName string
+	FileName  string
+	Key       string
+	Size      int64
+	// Object is the uploaded object. Its Body is always nil.
+	Object *cloudflare.R2Object
+}
+
+// Result represents the result of Upload.
+type Result struct {
+	Files []*File
+	// Values holds non-file fields of the form.
+	Values url.Values
+}
+
+// RandomKey returns a random key of 32 hex characters, followed by the extension of the file name if it is alphanumeric.
+// It is the default of Options.Key.
+func RandomKey(fieldName, fileName string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b) + fileExt(fileName), nil
+}
+
+// fileExt returns the extension of the file name, or an empty string if it is not short and alphanumeric.
+func fileExt(fileName string) string {
+	ext := path.Ext(path.Base(fileName))
+	if len(ext) < 2 || len(ext) > 16 {
+		return ""
+	}
+	for _, c := range ext[1:] {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9') {
+			return ""
+		}
+	}
+	return ext
+}
+
+// FileNameKey returns the base name of the file name given by the client.
+// Uploading files with the same name overwrites the object, so it must be opted in only for trusted uploaders.
+func FileNameKey(fieldName, fileName string) (string, error) {
+	return path.Base(fileName), nil
+}
+
+// Upload parses the multipart/form-data request, and streams files into the bucket.
+//   - non-file fields are returned as Result.Values.
+//   - if an upload fails, the in-progress multipart upload is aborted, and files uploaded before remain in the bucket.
+//   - returns error before reading the request if opts.PartSize is less than MinPartSize.
+func Upload(req *http.Request, bucket cloudflare.R2BucketBinding, opts *Options) (*Result, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if opts.PartSize > 0 && opts.PartSize < MinPartSize {
+		return nil, fmt.Errorf("r2upload: PartSize must be at least %d bytes, got %d", MinPartSize, opts.PartSize)
+	}
+	mediaType, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return nil, errors.New("r2upload: request is not multipart/form-data")
+	}
+	u := &uploader{
+		bucket: bucket,
+		opts:   opts,
+	}
+	partSize := opts.PartSize
+	if partSize <= 0 {
+		partSize = DefaultPartSize
+	}
+	u.buf = make([]byte, partSize)
+
+	result := &Result{Values: url.Values{}}
+	mr := multipart.NewReader(req.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FileName() == "" {
+			value, err := readValue(part, opts.MaxValueSize)
+			if err != nil {
+				return nil, err
+			}
+			result.Values.Add(part.FormName(), value)
+			continue
+		}
+		f, err := u.upload(part)
+		if err != nil {
+			return nil, err
+		}
+		if f != nil {
+			result.Files = append(result.Files, f)
+		}
+	}
+}
+
+// readValue reads the value of a non-file field up to maxSize bytes.
+func readValue(part *multipart.Part, maxSize int64) (string, error) {
+	if maxSize <= 0 {
+		maxSize = defaultMaxValueSize
+	}
+	b, err := io.ReadAll(io.LimitReader(part, maxSize+1))
+	if err != nil {
+		return "", err
+	}
+	if int64(len(b)) > maxSize {
+		return "", fmt.Errorf("%w: %s", ErrValueTooLarge, part.FormName())
+	}
+	return string(b), nil
+}
+
+type uploader struct {
+	bucket cloudflare.R2BucketBinding
+	opts   *Options
+	// buf is reused to hold a part of files.
+	buf []byte
+}
+
+func (u *uploader) key(fieldName, fileName string) (string, error) {
+	if u.opts.Key != nil {
+		return u.opts.Key(fieldName, fileName)
+	}
+	return RandomKey(fieldName, fileName)
+}
+
+func (u *uploader) progress(p *Progress) {
+	if u.opts.Progress != nil {
+		u.opts.Progress(p)
+	}
+}
+
+// upload uploads the file part. If the key is empty, the part is skipped and returns nil.
+func (u *uploader) upload(part *multipart.Part) (*File, error) {
+	f := &File{FieldName: part.FormName(), FileName: part.FileName()}
+	key, err := u.key(f.FieldName, f.FileName)
+	if err != nil {
+		return nil, err
+	}
+	if key == "" {
+		return nil, nil
+	}
+	f.Key = key
+	md := cloudflare.R2HTTPMetadata{ContentType: part.Header.Get("Content-Type")}
+
+	n, err := io.ReadFull(part, u.buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		// the file fits in a part, so it is uploaded by a single put.
+		obj, err := u.bucket.Put(key, io.NopCloser(bytes.NewReader(u.buf[:n])), &cloudflare.R2PutOptions{HTTPMetadata: md})
+		if err != nil {
+			return nil, fmt.Errorf("r2upload: failed to put %s: %w", key, err)
+		}
+		f.Size, f.Object = int64(n), obj
+		u.progress(&Progress{FieldName: f.FieldName, FileName: f.FileName, Key: key, Part: 1, BytesUploaded: f.Size, Done: true})
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	mu, err := u.bucket.CreateMultipartUpload(key, &cloudflare.R2MultipartOptions{HTTPMetadata: md})
+	if err != nil {
+		return nil, fmt.Errorf("r2upload: failed to create multipart upload of %s: %w", key, err)
+	}
+	var parts []*cloudflare.R2UploadedPart
+	for n > 0 {
+		uploaded, err := mu.UploadPart(len(parts)+1, bytes.NewReader(u.buf[:n]))
+		if err != nil {
+			_ = mu.Abort()
+			return nil, fmt.Errorf("r2upload: failed to upload part %d of %s: %w", len(parts)+1, key, err)
+		}
+		parts = append(parts, uploaded)
+		f.Size += int64(n)
+		u.progress(&Progress{FieldName: f.FieldName, FileName: f.FileName, Key: key, Part: len(parts), BytesUploaded: f.Size})
+		n, err = io.ReadFull(part, u.buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			_ = mu.Abort()
+			return nil, err
+		}
+	}
+	obj, err := mu.Complete(parts)
+	if err != nil {
+		_ = mu.Abort()
+		return nil, fmt.Errorf("r2upload: failed to complete multipart upload of %s: %w", key, err)
+	}
+	f.Object = obj
+	u.progress(&Progress{FieldName: f.FieldName, FileName: f.FileName, Key: key, Part: len(parts), BytesUploaded: f.Size, Done: true})
+	return f, nil
+}
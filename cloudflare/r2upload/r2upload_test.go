@@ -0,0 +1,164 @@
+package r2upload_test
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/syumai/workers/cloudflare/r2upload"
+	"github.com/syumai/workers/workerstest"
+)
+
+func newUploadRequest(t *testing.T, files map[string]string, values map[string]string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for name, v := range values {
+		if err := w.WriteField(name, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, content := range files {
+		fw, err := w.CreateFormFile("file", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(fw, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+// content returns a content of size bytes, whose parts differ from each other.
+func content(size int) string {
+	b := make([]byte, size)
+	for i := range b {
+		b[i] = byte('a' + i%251%26)
+	}
+	return string(b)
+}
+
+func TestUpload(t *testing.T) {
+	tests := map[string]struct {
+		content   string
+		wantParts int
+	}{
+		"single put": {
+			content:   "abc",
+			wantParts: 1,
+		},
+		"multipart upload": {
+			content:   content(2*r2upload.MinPartSize + 2),
+			wantParts: 3,
+		},
+		"multipart upload of exact parts": {
+			content:   content(2 * r2upload.MinPartSize),
+			wantParts: 2,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			bucket := &workerstest.R2Bucket{}
+			req := newUploadRequest(t, map[string]string{"dir/a.txt": tc.content}, map[string]string{"title": "hello"})
+			var progress []r2upload.Progress
+			result, err := r2upload.Upload(req, bucket, &r2upload.Options{
+				Key:      r2upload.FileNameKey,
+				PartSize: r2upload.MinPartSize,
+				Progress: func(p *r2upload.Progress) {
+					progress = append(progress, *p)
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := result.Values.Get("title"); got != "hello" {
+				t.Errorf("want value hello, got %q", got)
+			}
+			if len(result.Files) != 1 {
+				t.Fatalf("want 1 file, got %d", len(result.Files))
+			}
+			f := result.Files[0]
+			if f.Key != "a.txt" || f.Size != int64(len(tc.content)) {
+				t.Errorf("want a.txt of %d bytes, got %s of %d bytes", len(tc.content), f.Key, f.Size)
+			}
+			obj, err := bucket.Get("a.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, _ := io.ReadAll(obj.Body)
+			if string(b) != tc.content {
+				t.Errorf("want the content of %d bytes, got %d bytes", len(tc.content), len(b))
+			}
+			last := progress[len(progress)-1]
+			if !last.Done || last.Part != tc.wantParts || last.BytesUploaded != int64(len(tc.content)) {
+				t.Errorf("want done progress of %d parts, got %+v", tc.wantParts, last)
+			}
+		})
+	}
+}
+
+func TestUpload_ValueTooLarge(t *testing.T) {
+	t.Parallel()
+	req := newUploadRequest(t, nil, map[string]string{"title": "hello"})
+	_, err := r2upload.Upload(req, &workerstest.R2Bucket{}, &r2upload.Options{MaxValueSize: 4})
+	if err == nil {
+		t.Error("want error, got nil")
+	}
+}
+
+func TestUpload_RandomKey(t *testing.T) {
+	t.Parallel()
+	bucket := &workerstest.R2Bucket{}
+	pattern := regexp.MustCompile(`^[0-9a-f]{32}\.txt$`)
+	var keys []string
+	for i := 0; i < 2; i++ {
+		req := newUploadRequest(t, map[string]string{"../a.txt": "abc"}, nil)
+		result, err := r2upload.Upload(req, bucket, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key := result.Files[0].Key
+		if !pattern.MatchString(key) {
+			t.Errorf("want a random key with the extension, got %q", key)
+		}
+		keys = append(keys, key)
+	}
+	if keys[0] == keys[1] {
+		t.Errorf("want files of the same name uploaded to different keys, got %q", keys[0])
+	}
+
+	for name, want := range map[string]string{"a": "", "a.tar.gz": ".gz", "a.t xt": "", "a.": ""} {
+		key, err := r2upload.RandomKey("file", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := key[32:]; got != want {
+			t.Errorf("%s: want extension %q, got %q", name, want, got)
+		}
+	}
+}
+
+func TestUpload_PartSizeTooSmall(t *testing.T) {
+	t.Parallel()
+	bucket := &workerstest.R2Bucket{}
+	req := newUploadRequest(t, map[string]string{"a.txt": "abc"}, nil)
+	if _, err := r2upload.Upload(req, bucket, &r2upload.Options{PartSize: r2upload.MinPartSize - 1}); err == nil {
+		t.Error("want error for a part size less than MinPartSize, got nil")
+	}
+	if obj, err := bucket.Head("a.txt"); err != nil || obj != nil {
+		t.Errorf("want nothing uploaded, got %v, %v", obj, err)
+	}
+}
@@ -0,0 +1,48 @@
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/syumai/workers/cloudflare/internal/cfruntimecontext"
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jsutil"
+)
+
+// RateLimiterBinding is the interface implemented by RateLimiter.
+type RateLimiterBinding interface {
+	Limit(key string) (bool, error)
+}
+
+var _ RateLimiterBinding = (*RateLimiter)(nil)
+
+// RateLimiter represents interface of Cloudflare Worker's rate limiting binding.
+//   - https://developers.cloudflare.com/workers/runtime-apis/bindings/rate-limit/
+type RateLimiter struct {
+	instance js.Value
+}
+
+// NewRateLimiter returns RateLimiter for given variable name.
+//   - variable name must be defined in wrangler.toml as unsafe binding of type "ratelimit".
+//   - if the given variable name doesn't exist on runtime context, returns error.
+//   - This function panics when a runtime context is not found.
+func NewRateLimiter(ctx context.Context, varName string) (*RateLimiter, error) {
+	inst := cfruntimecontext.GetRuntimeContextEnv(ctx).Get(varName)
+	if inst.IsUndefined() {
+		return nil, fmt.Errorf("%s is undefined", varName)
+	}
+	return &RateLimiter{instance: inst}, nil
+}
+
+// Limit reports whether a request identified by the key is within the limit.
+//   - if a network error happens, returns error.
+func (r *RateLimiter) Limit(key string) (bool, error) {
+	opts := jsutil.NewObject()
+	opts.Set("key", key)
+	p := r.instance.Call("limit", opts)
+	v, err := jsutil.AwaitPromise(p)
+	if err != nil {
+		return false, err
+	}
+	return v.Get("success").Bool(), nil
+}
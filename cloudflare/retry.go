@@ -0,0 +1,98 @@
+package cloudflare
+
+import (
+	"math/rand"
+	"regexp"
+	"time"
+
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jsutil"
+	"github.com/syumai/workers/internal/runtimecontext"
+)
+
+// RetryPolicy represents a policy to retry binding calls failed by transient errors.
+//   - Retries are opt-in. Bindings retry calls only if a policy is given by WithRetry.
+//   - Delays between attempts are chosen randomly from 0 to min(MaxDelay, BaseDelay * 2^retries) (full jitter).
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts including the first one. Defaults to 3.
+	MaxAttempts int
+	// BaseDelay is the base of the exponential backoff. Defaults to 100 milliseconds.
+	BaseDelay time.Duration
+	// MaxDelay is the upper limit of delays. Defaults to 2 seconds.
+	MaxDelay time.Duration
+	// Retryable reports whether the call should be retried for the error. Defaults to IsTransientError.
+	Retryable func(err error) bool
+}
+
+const (
+	defaultRetryMaxAttempts = 3
+	defaultRetryBaseDelay   = 100 * time.Millisecond
+	defaultRetryMaxDelay    = 2 * time.Second
+)
+
+// transientErrorRe matches messages of JavaScript errors thrown for rate limits and server errors.
+//   - e.g. "KV PUT failed: 429 Too Many Requests", "get: We encountered an internal error. Please try again. (10001)"
+var transientErrorRe = regexp.MustCompile(`(?i)\b(429|5\d\d)\b|too many requests|internal error|service unavailable|network connection lost|please try again`)
+
+// IsTransientError reports whether the error of a binding call is transient, such as rate limits (429) and server errors (5xx).
+func IsTransientError(err error) bool {
+	return err != nil && transientErrorRe.MatchString(err.Error())
+}
+
+// do calls fn until it succeeds, the error is not retryable or the attempts run out.
+//   - if the policy is nil, fn is called only once.
+func (p *RetryPolicy) do(fn func() error) error {
+	if p == nil {
+		return fn()
+	}
+	maxAttempts := p.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultRetryMaxAttempts
+	}
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsTransientError
+	}
+	var err error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(p.delay(attempt))
+		}
+		err = fn()
+		if err == nil || !retryable(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// delay returns the jittered delay before the retry.
+func (p *RetryPolicy) delay(retry int) time.Duration {
+	base := p.BaseDelay
+	if base <= 0 {
+		base = defaultRetryBaseDelay
+	}
+	maxDelay := p.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = defaultRetryMaxDelay
+	}
+	d := maxDelay
+	// compares without shifting base not to overflow.
+	if shift := retry - 1; shift < 63 && base <= maxDelay>>shift {
+		d = base << shift
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// await calls the method of the binding, and waits for the returned promise with retries.
+// Each attempt is counted as a subrequest of the event.
+func (p *RetryPolicy) await(subrequests *runtimecontext.Subrequests, call func() js.Value) (js.Value, error) {
+	var v js.Value
+	err := p.do(func() error {
+		var err error
+		subrequests.Add()
+		v, err = jsutil.AwaitPromise(call())
+		return err
+	})
+	return v, err
+}
@@ -0,0 +1,109 @@
+package cloudflare
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIsTransientError(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want bool
+	}{
+		"rate limited": {
+			err:  errors.New("failed on promise: Error: KV PUT failed: 429 Too Many Requests"),
+			want: true,
+		},
+		"server error": {
+			err:  errors.New("failed on promise: Error: KV GET failed: 503 Service Unavailable"),
+			want: true,
+		},
+		"internal error of R2": {
+			err:  errors.New("failed on promise: Error: put: We encountered an internal error. Please try again. (10001)"),
+			want: true,
+		},
+		"client error": {
+			err:  errors.New("failed on promise: TypeError: KV GET failed: 400 Invalid key"),
+			want: false,
+		},
+		"nil": {
+			err:  nil,
+			want: false,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if got := IsTransientError(tc.err); got != tc.want {
+				t.Errorf("want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_do(t *testing.T) {
+	errTransient := errors.New("429 Too Many Requests")
+	errPermanent := errors.New("400 Bad Request")
+	tests := map[string]struct {
+		policy       *RetryPolicy
+		errs         []error
+		wantAttempts int
+		wantErr      error
+	}{
+		"nil policy doesn't retry": {
+			policy:       nil,
+			errs:         []error{errTransient, nil},
+			wantAttempts: 1,
+			wantErr:      errTransient,
+		},
+		"succeeds after retries": {
+			policy:       &RetryPolicy{BaseDelay: time.Nanosecond},
+			errs:         []error{errTransient, errTransient, nil},
+			wantAttempts: 3,
+			wantErr:      nil,
+		},
+		"attempts run out": {
+			policy:       &RetryPolicy{MaxAttempts: 2, BaseDelay: time.Nanosecond},
+			errs:         []error{errTransient, errTransient, nil},
+			wantAttempts: 2,
+			wantErr:      errTransient,
+		},
+		"permanent error is not retried": {
+			policy:       &RetryPolicy{BaseDelay: time.Nanosecond},
+			errs:         []error{errPermanent, nil},
+			wantAttempts: 1,
+			wantErr:      errPermanent,
+		},
+		"custom retryable": {
+			policy: &RetryPolicy{
+				BaseDelay: time.Nanosecond,
+				Retryable: func(err error) bool { return err == errPermanent },
+			},
+			errs:         []error{errPermanent, nil},
+			wantAttempts: 2,
+			wantErr:      nil,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var attempts int
+			err := tc.policy.do(func() error {
+				err := tc.errs[attempts]
+				attempts++
+				return err
+			})
+			if err != tc.wantErr {
+				t.Errorf("want error %v, got %v", tc.wantErr, err)
+			}
+			if attempts != tc.wantAttempts {
+				t.Errorf("want %d attempts, got %d", tc.wantAttempts, attempts)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package cloudflare
+
+import (
+	"context"
+
+	"github.com/syumai/workers/internal/runtimecontext"
+)
+
+// Limits of subrequests per event of the platform.
+//   - https://developers.cloudflare.com/workers/platform/limits/#subrequests
+const (
+	FreeSubrequestLimit = 50
+	PaidSubrequestLimit = 1000
+)
+
+// Subrequests returns the number of subrequests sent in the event so far.
+//   - fetch (including service bindings and Durable Objects), KV, R2, D1 and Cache API calls are counted.
+//     Each retry of bindings is counted too.
+//   - D1 queries are counted only if they are run with the context of the event (e.g. db.QueryContext(req.Context(), ...)).
+//   - returns 0 if ctx is not a context of an event.
+func Subrequests(ctx context.Context) int {
+	return runtimecontext.SubrequestsFrom(ctx).Count()
+}
+
+// OnSubrequestLimit sets the hook called once per event when the number of subrequests reaches the soft limit,
+// e.g. to log or report handlers approaching the limits of the platform before they start failing.
+//   - the hook is called in the goroutine sending the subrequest, with the context of the event.
+//   - the limit is not enforced. Subrequests continue to be sent after the hook is called.
+//   - calling this function again replaces the hook. A nil hook disables it.
+func OnSubrequestLimit(limit int, hook func(ctx context.Context, count int)) {
+	runtimecontext.SetSubrequestSoftLimit(limit, hook)
+}
@@ -0,0 +1,249 @@
+// Package pipeline filters, transforms and batches trace items of Tail Workers, and forwards them to a sink such as a log service.
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/cloudflare/fetch"
+	"github.com/syumai/workers/cloudflare/tail"
+)
+
+// Filter reports whether the trace item is forwarded by Pipeline.
+type Filter func(item *tail.TraceItem) bool
+
+// Outcomes returns Filter passing trace items of the outcomes (e.g. "exception", "exceededCpu").
+func Outcomes(outcomes ...string) Filter {
+	set := toSet(outcomes)
+	return func(item *tail.TraceItem) bool {
+		return set[item.Outcome]
+	}
+}
+
+// Scripts returns Filter passing trace items of the producer Workers.
+func Scripts(names ...string) Filter {
+	set := toSet(names)
+	return func(item *tail.TraceItem) bool {
+		return set[item.ScriptName]
+	}
+}
+
+// MinLogLevel returns Filter passing trace items which have a log of the level or above, or an exception.
+//   - levels are ordered as "debug" < "log" = "info" < "warn" < "error".
+func MinLogLevel(level string) Filter {
+	minLevel := logLevels[level]
+	return func(item *tail.TraceItem) bool {
+		if len(item.Exceptions) > 0 {
+			return true
+		}
+		for _, l := range item.Logs {
+			if logLevels[l.Level] >= minLevel {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+var logLevels = map[string]int{
+	"debug": 0,
+	"log":   1,
+	"info":  1,
+	"warn":  2,
+	"error": 3,
+}
+
+// Not returns Filter negating the filter.
+func Not(f Filter) Filter {
+	return func(item *tail.TraceItem) bool {
+		return !f(item)
+	}
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+// Transform converts the trace item into a record sent to the sink. If it returns nil, the item is dropped.
+type Transform func(item *tail.TraceItem) any
+
+// Sink receives batches of records.
+type Sink interface {
+	Send(ctx context.Context, records []any) error
+}
+
+// SinkFunc is a function implementing Sink.
+type SinkFunc func(ctx context.Context, records []any) error
+
+func (f SinkFunc) Send(ctx context.Context, records []any) error {
+	return f(ctx, records)
+}
+
+// HTTPSink returns Sink posting batches to the URL as NDJSON, one record per line.
+//   - header is added to each request (e.g. Authorization of the log service).
+//   - if the response status is not 2xx, returns error.
+//   - if client is nil, fetch.NewClient() is used.
+func HTTPSink(client fetch.Fetcher, url string, header http.Header) Sink {
+	if client == nil {
+		client = fetch.NewClient()
+	}
+	return SinkFunc(func(ctx context.Context, records []any) error {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		for _, r := range records {
+			if err := enc.Encode(r); err != nil {
+				return fmt.Errorf("pipeline: error encoding record: %w", err)
+			}
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &buf)
+		if err != nil {
+			return err
+		}
+		for k, vs := range header {
+			req.Header[k] = append([]string(nil), vs...)
+		}
+		req.Header.Set("Content-Type", "application/x-ndjson")
+		res, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("pipeline: error sending records: %w", err)
+		}
+		defer res.Body.Close()
+		_, _ = io.Copy(io.Discard, res.Body)
+		if res.StatusCode < 200 || res.StatusCode >= 300 {
+			return fmt.Errorf("pipeline: sink responded with %s", res.Status)
+		}
+		return nil
+	})
+}
+
+// Pipeline filters, transforms and batches trace items, and forwards them to the sink.
+//
+// Example:
+//
+//	p := &pipeline.Pipeline{
+//	  Filters: []pipeline.Filter{pipeline.Not(pipeline.Outcomes("ok")), pipeline.Scripts("api")},
+//	  Sink:    pipeline.HTTPSink(nil, "https://logs.example.com/ingest", header),
+//	}
+//	tail.Handle(p.Handler())
+type Pipeline struct {
+	// Filters are applied in order. Trace items passing all filters are forwarded.
+	Filters []Filter
+	// Transform converts trace items into records. Defaults to the trace item itself.
+	Transform Transform
+	// Sink receives batches of records.
+	Sink Sink
+	// BatchSize is the maximum number of records in a batch. Defaults to 100.
+	BatchSize int
+	// MaxInFlight is the maximum number of batches being sent in the background in the isolate. Defaults to 4.
+	//   - when it is reached, the handler waits for a batch to be sent before returning (backpressure).
+	MaxInFlight int
+	// OnError is called with errors of the sink in the background. Defaults to logging them by slog.Default().
+	OnError func(ctx context.Context, err error)
+
+	// sem holds slots of in-flight batches.
+	sem chan struct{}
+}
+
+const (
+	defaultBatchSize   = 100
+	defaultMaxInFlight = 4
+)
+
+// Batches returns batches of records of the trace items passing the filters.
+func (p *Pipeline) Batches(items []*tail.TraceItem) [][]any {
+	size := p.BatchSize
+	if size <= 0 {
+		size = defaultBatchSize
+	}
+	var batches [][]any
+	var batch []any
+	for _, item := range items {
+		if !p.pass(item) {
+			continue
+		}
+		var record any = item
+		if p.Transform != nil {
+			record = p.Transform(item)
+		}
+		if record == nil {
+			continue
+		}
+		batch = append(batch, record)
+		if len(batch) == size {
+			batches = append(batches, batch)
+			batch = nil
+		}
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+func (p *Pipeline) pass(item *tail.TraceItem) bool {
+	for _, f := range p.Filters {
+		if !f(item) {
+			return false
+		}
+	}
+	return true
+}
+
+// Process sends batches of the trace items to the sink in order, and waits for them.
+//   - it stops at the first error of the sink.
+func (p *Pipeline) Process(ctx context.Context, items []*tail.TraceItem) error {
+	for _, batch := range p.Batches(items) {
+		if err := p.Sink.Send(ctx, batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler returns tail.Handler sending batches to the sink in the background by waitUntil, so the tail event finishes quickly.
+//   - at most MaxInFlight batches are sent at once. When all slots are in use, the handler waits for a free slot.
+//   - errors of the sink are given to OnError.
+//   - the Pipeline must not be modified after Handler is called.
+func (p *Pipeline) Handler() tail.Handler {
+	maxInFlight := p.MaxInFlight
+	if maxInFlight <= 0 {
+		maxInFlight = defaultMaxInFlight
+	}
+	p.sem = make(chan struct{}, maxInFlight)
+	return func(ctx context.Context, items []*tail.TraceItem) error {
+		for _, batch := range p.Batches(items) {
+			batch := batch
+			select {
+			case p.sem <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			// the context of the event is canceled after the handler returns, but sending continues by the context of the task.
+			cloudflare.WaitUntil(ctx, func(taskCtx context.Context) {
+				defer func() { <-p.sem }()
+				if err := p.Sink.Send(taskCtx, batch); err != nil {
+					p.reportError(taskCtx, err)
+				}
+			})
+		}
+		return nil
+	}
+}
+
+func (p *Pipeline) reportError(ctx context.Context, err error) {
+	if p.OnError != nil {
+		p.OnError(ctx, err)
+		return
+	}
+	slog.Default().ErrorContext(ctx, "pipeline: error sending records", "error", err)
+}
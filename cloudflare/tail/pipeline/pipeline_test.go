@@ -0,0 +1,118 @@
+package pipeline
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/syumai/workers/cloudflare/tail"
+)
+
+type fetcherFunc func(req *http.Request) (*http.Response, error)
+
+func (f fetcherFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func testItems() []*tail.TraceItem {
+	return []*tail.TraceItem{
+		{ScriptName: "api", Outcome: "ok", Logs: []*tail.Log{{Level: "debug"}}},
+		{ScriptName: "api", Outcome: "ok", Logs: []*tail.Log{{Level: "warn"}}},
+		{ScriptName: "api", Outcome: "exception", Exceptions: []*tail.Exception{{Name: "Error"}}},
+		{ScriptName: "web", Outcome: "exceededCpu"},
+		{ScriptName: "api", Outcome: "canceled", Logs: []*tail.Log{{Level: "error"}}},
+	}
+}
+
+func TestPipeline_Batches(t *testing.T) {
+	tests := map[string]struct {
+		pipeline *Pipeline
+		want     [][]string
+	}{
+		"no filters": {
+			pipeline: &Pipeline{BatchSize: 2},
+			want:     [][]string{{"ok", "ok"}, {"exception", "exceededCpu"}, {"canceled"}},
+		},
+		"outcomes and scripts": {
+			pipeline: &Pipeline{Filters: []Filter{Not(Outcomes("ok")), Scripts("api")}},
+			want:     [][]string{{"exception", "canceled"}},
+		},
+		"log level": {
+			pipeline: &Pipeline{Filters: []Filter{MinLogLevel("warn")}},
+			want:     [][]string{{"ok", "exception", "canceled"}},
+		},
+		"transform dropping items": {
+			pipeline: &Pipeline{Transform: func(item *tail.TraceItem) any {
+				if item.ScriptName != "web" {
+					return nil
+				}
+				return item
+			}},
+			want: [][]string{{"exceededCpu"}},
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var got [][]string
+			for _, batch := range tc.pipeline.Batches(testItems()) {
+				var outcomes []string
+				for _, r := range batch {
+					outcomes = append(outcomes, r.(*tail.TraceItem).Outcome)
+				}
+				got = append(got, outcomes)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("want %v, got %v", tc.want, got)
+			}
+			for i := range got {
+				if strings.Join(got[i], ",") != strings.Join(tc.want[i], ",") {
+					t.Errorf("want %v, got %v", tc.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestPipeline_Process(t *testing.T) {
+	var lines []map[string]any
+	client := fetcherFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("Authorization") != "Bearer token" || req.Header.Get("Content-Type") != "application/x-ndjson" {
+			t.Errorf("unexpected header: %v", req.Header)
+		}
+		sc := bufio.NewScanner(req.Body)
+		for sc.Scan() {
+			var line map[string]any
+			if err := json.Unmarshal(sc.Bytes(), &line); err != nil {
+				t.Fatal(err)
+			}
+			lines = append(lines, line)
+		}
+		status := http.StatusAccepted
+		if len(lines) > 2 {
+			status = http.StatusTooManyRequests
+		}
+		return &http.Response{StatusCode: status, Status: http.StatusText(status), Body: io.NopCloser(strings.NewReader(""))}, nil
+	})
+	p := &Pipeline{
+		Filters: []Filter{Outcomes("exception", "canceled", "exceededCpu")},
+		Transform: func(item *tail.TraceItem) any {
+			return map[string]string{"script": item.ScriptName, "outcome": item.Outcome}
+		},
+		Sink:      HTTPSink(client, "https://logs.example.com/", http.Header{"Authorization": {"Bearer token"}}),
+		BatchSize: 2,
+	}
+	err := p.Process(context.Background(), testItems())
+	if err == nil || !strings.Contains(err.Error(), "Too Many Requests") {
+		t.Errorf("want error of the sink, got %v", err)
+	}
+	if len(lines) != 3 || lines[0]["outcome"] != "exception" || lines[1]["script"] != "web" {
+		t.Errorf("unexpected records: %v", lines)
+	}
+}
@@ -0,0 +1,136 @@
+// Package tail handles events of Tail Workers.
+//   - https://developers.cloudflare.com/workers/observability/logs/tail-workers/
+package tail
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jsutil"
+	"github.com/syumai/workers/internal/runtimecontext"
+)
+
+// TraceItem represents an invocation of the producer Worker.
+//   - https://developers.cloudflare.com/workers/runtime-apis/handlers/tail/
+type TraceItem struct {
+	// ScriptName is the name of the producer Worker.
+	ScriptName string `json:"scriptName"`
+	// Outcome is the result of the invocation (e.g. "ok", "exception", "exceededCpu", "canceled").
+	Outcome        string    `json:"outcome"`
+	EventTimestamp time.Time `json:"eventTimestamp"`
+	// Event holds the JSON of the event of the invocation, e.g. the request and the response of fetch events.
+	Event      json.RawMessage `json:"event,omitempty"`
+	Logs       []*Log          `json:"logs,omitempty"`
+	Exceptions []*Exception    `json:"exceptions,omitempty"`
+	ScriptTags []string        `json:"scriptTags,omitempty"`
+	Entrypoint string          `json:"entrypoint,omitempty"`
+	// CPUTime is the CPU time of the invocation reported by the runtime. It is zero if not reported.
+	// It is encoded into JSON in nanoseconds.
+	CPUTime time.Duration `json:"cpuTimeNs,omitempty"`
+	// WallTime is the wall time of the invocation reported by the runtime. It is zero if not reported.
+	// It is encoded into JSON in nanoseconds.
+	WallTime time.Duration `json:"wallTimeNs,omitempty"`
+}
+
+// Log represents a console.log call of the producer Worker.
+type Log struct {
+	Timestamp time.Time `json:"timestamp"`
+	// Level is the level of the log (e.g. "log", "debug", "info", "warn", "error").
+	Level string `json:"level"`
+	// Message holds JSON of the arguments of the call.
+	Message []json.RawMessage `json:"message"`
+}
+
+// Exception represents an uncaught exception of the producer Worker.
+type Exception struct {
+	Timestamp time.Time `json:"timestamp"`
+	Name      string    `json:"name"`
+	Message   string    `json:"message"`
+}
+
+type traceItemJSON struct {
+	ScriptName     string          `json:"scriptName"`
+	Outcome        string          `json:"outcome"`
+	EventTimestamp int64           `json:"eventTimestamp"`
+	Event          json.RawMessage `json:"event"`
+	Logs           []struct {
+		Timestamp int64             `json:"timestamp"`
+		Level     string            `json:"level"`
+		Message   []json.RawMessage `json:"message"`
+	} `json:"logs"`
+	Exceptions []struct {
+		Timestamp int64  `json:"timestamp"`
+		Name      string `json:"name"`
+		Message   string `json:"message"`
+	} `json:"exceptions"`
+	ScriptTags []string `json:"scriptTags"`
+	Entrypoint string   `json:"entrypoint"`
+	CPUTime    float64  `json:"cpuTime"`
+	WallTime   float64  `json:"wallTime"`
+}
+
+// decodeTraceItems decodes JSON of the array of trace items.
+func decodeTraceItems(b []byte) ([]*TraceItem, error) {
+	var items []traceItemJSON
+	if err := json.Unmarshal(b, &items); err != nil {
+		return nil, err
+	}
+	result := make([]*TraceItem, len(items))
+	for i, item := range items {
+		t := &TraceItem{
+			ScriptName:     item.ScriptName,
+			Outcome:        item.Outcome,
+			EventTimestamp: time.UnixMilli(item.EventTimestamp).UTC(),
+			Event:          item.Event,
+			ScriptTags:     item.ScriptTags,
+			Entrypoint:     item.Entrypoint,
+			CPUTime:        time.Duration(item.CPUTime * float64(time.Millisecond)),
+			WallTime:       time.Duration(item.WallTime * float64(time.Millisecond)),
+		}
+		for _, l := range item.Logs {
+			t.Logs = append(t.Logs, &Log{
+				Timestamp: time.UnixMilli(l.Timestamp).UTC(),
+				Level:     l.Level,
+				Message:   l.Message,
+			})
+		}
+		for _, e := range item.Exceptions {
+			t.Exceptions = append(t.Exceptions, &Exception{
+				Timestamp: time.UnixMilli(e.Timestamp).UTC(),
+				Name:      e.Name,
+				Message:   e.Message,
+			})
+		}
+		result[i] = t
+	}
+	return result, nil
+}
+
+// Handler handles trace items of producer Workers.
+//   - bindings can be obtained from ctx as well as handlers of requests (e.g. cloudflare.NewKVNamespace(ctx, ...)).
+type Handler func(ctx context.Context, items []*TraceItem) error
+
+// RegisterTail registers the handler by Handle, so it can be given to workers.Options as the Tail handler.
+func (h Handler) RegisterTail() {
+	Handle(h)
+}
+
+// Handle registers the handler of tail events. It must be called before workers.Serve or workers.Start.
+// The JavaScript entry point must export the tail handler calling `handleTail`:
+//
+//	async tail(events, env, ctx) {
+//	  await load;
+//	  await readyPromise;
+//	  return handleTail(events, { env, ctx });
+//	}
+func Handle(h Handler) {
+	runtimecontext.RegisterHandler("handleTail", func(ctx context.Context, eventsObj js.Value) error {
+		items, err := decodeTraceItems([]byte(jsutil.Global.Get("JSON").Call("stringify", eventsObj).String()))
+		if err != nil {
+			return err
+		}
+		return h(ctx, items)
+	})
+}
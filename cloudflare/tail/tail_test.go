@@ -0,0 +1,39 @@
+package tail
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecodeTraceItems(t *testing.T) {
+	b := []byte(`[{
+		"scriptName": "producer",
+		"outcome": "exception",
+		"eventTimestamp": 1700000000000,
+		"cpuTime": 3,
+		"wallTime": 120,
+		"event": {"request": {"url": "https://example.com/", "method": "GET"}},
+		"logs": [{"timestamp": 1700000000001, "level": "warn", "message": ["slow", 120]}],
+		"exceptions": [{"timestamp": 1700000000002, "name": "Error", "message": "failed"}]
+	}]`)
+	items, err := decodeTraceItems(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("want 1 item, got %d", len(items))
+	}
+	item := items[0]
+	if item.ScriptName != "producer" || item.Outcome != "exception" || item.CPUTime != 3*time.Millisecond || item.WallTime != 120*time.Millisecond {
+		t.Errorf("unexpected item: %+v", item)
+	}
+	if want := time.UnixMilli(1700000000000).UTC(); !item.EventTimestamp.Equal(want) {
+		t.Errorf("want event timestamp %v, got %v", want, item.EventTimestamp)
+	}
+	if len(item.Logs) != 1 || item.Logs[0].Level != "warn" || string(item.Logs[0].Message[1]) != "120" {
+		t.Errorf("unexpected logs: %+v", item.Logs)
+	}
+	if len(item.Exceptions) != 1 || item.Exceptions[0].Message != "failed" {
+		t.Errorf("unexpected exceptions: %+v", item.Exceptions)
+	}
+}
@@ -0,0 +1,181 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/syumai/workers/cloudflare/internal/cfruntimecontext"
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jsutil"
+)
+
+// VectorizeIndexBinding is the interface implemented by VectorizeIndex.
+type VectorizeIndexBinding interface {
+	Insert(vectors []*VectorizeVector) (string, error)
+	Upsert(vectors []*VectorizeVector) (string, error)
+	Query(vector []float32, opts *VectorizeQueryOptions) (*VectorizeMatches, error)
+	GetByIDs(ids []string) ([]*VectorizeVector, error)
+	DeleteByIDs(ids []string) (string, error)
+}
+
+var _ VectorizeIndexBinding = (*VectorizeIndex)(nil)
+
+// VectorizeIndex represents interface of Cloudflare Vectorize index binding.
+//   - https://developers.cloudflare.com/vectorize/reference/client-api/
+type VectorizeIndex struct {
+	instance js.Value
+}
+
+// NewVectorizeIndex returns VectorizeIndex for given variable name.
+//   - variable name must be defined in wrangler.toml as vectorize binding.
+//   - if the given variable name doesn't exist on runtime context, returns error.
+//   - This function panics when a runtime context is not found.
+func NewVectorizeIndex(ctx context.Context, varName string) (*VectorizeIndex, error) {
+	inst := cfruntimecontext.GetRuntimeContextEnv(ctx).Get(varName)
+	if inst.IsUndefined() {
+		return nil, fmt.Errorf("%s is undefined", varName)
+	}
+	return &VectorizeIndex{instance: inst}, nil
+}
+
+// VectorizeVector represents a vector of Vectorize.
+type VectorizeVector struct {
+	ID     string    `json:"id"`
+	Values []float32 `json:"values,omitempty"`
+	// Namespace partitions vectors of the index. Queries can be limited to a namespace.
+	Namespace string `json:"namespace,omitempty"`
+	// Metadata values must be strings, numbers, booleans or arrays of strings.
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// Values of VectorizeQueryOptions.ReturnMetadata.
+const (
+	VectorizeMetadataNone    = "none"
+	VectorizeMetadataIndexed = "indexed"
+	VectorizeMetadataAll     = "all"
+)
+
+// VectorizeQueryOptions represents options of VectorizeIndex.Query.
+type VectorizeQueryOptions struct {
+	// TopK is the number of matches returned. Defaults to 5.
+	TopK int `json:"topK,omitempty"`
+	// Namespace limits matches to vectors of the namespace.
+	Namespace    string `json:"namespace,omitempty"`
+	ReturnValues bool   `json:"returnValues,omitempty"`
+	// ReturnMetadata is one of VectorizeMetadataNone (default), VectorizeMetadataIndexed and VectorizeMetadataAll.
+	ReturnMetadata string `json:"returnMetadata,omitempty"`
+	// Filter limits matches by metadata indexes.
+	//   - values are compared for equality (e.g. {"genre": "docs"}), or by operators (e.g. {"year": {"$gte": 2020}}).
+	//   - operators are $eq, $ne, $in, $nin, $lt, $lte, $gt and $gte.
+	//   - https://developers.cloudflare.com/vectorize/reference/metadata-filtering/
+	Filter map[string]any `json:"filter,omitempty"`
+}
+
+// VectorizeMatch represents a match of VectorizeIndex.Query.
+type VectorizeMatch struct {
+	ID string `json:"id"`
+	// Score is the similarity to the queried vector by the metric of the index.
+	Score float64 `json:"score"`
+	// Values and Metadata are set if they are requested by VectorizeQueryOptions.
+	Values    []float32      `json:"values,omitempty"`
+	Namespace string         `json:"namespace,omitempty"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
+}
+
+// VectorizeMatches represents the result of VectorizeIndex.Query.
+type VectorizeMatches struct {
+	Count   int               `json:"count"`
+	Matches []*VectorizeMatch `json:"matches"`
+}
+
+// toJSValue converts v into a JavaScript value through JSON.
+func toJSValue(v any) (js.Value, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return js.Value{}, err
+	}
+	return js.Global().Get("JSON").Call("parse", string(b)), nil
+}
+
+// fromJSValue converts the JavaScript value into v through JSON.
+func fromJSValue(value js.Value, v any) error {
+	s := js.Global().Get("JSON").Call("stringify", value).String()
+	return json.Unmarshal([]byte(s), v)
+}
+
+// call calls the method of the index with the argument converted through JSON, and decodes the result into out.
+func (idx *VectorizeIndex) call(method string, arg, out any) error {
+	jsArg, err := toJSValue(arg)
+	if err != nil {
+		return fmt.Errorf("error encoding argument of %s: %w", method, err)
+	}
+	v, err := jsutil.AwaitPromise(idx.instance.Call(method, jsArg))
+	if err != nil {
+		return err
+	}
+	return fromJSValue(v, out)
+}
+
+// mutate calls the mutation method, and returns the ID of the mutation.
+func (idx *VectorizeIndex) mutate(method string, arg any) (string, error) {
+	var result struct {
+		MutationID string `json:"mutationId"`
+	}
+	if err := idx.call(method, arg, &result); err != nil {
+		return "", err
+	}
+	return result.MutationID, nil
+}
+
+// Insert inserts the vectors, and returns the ID of the mutation.
+//   - vectors whose IDs already exist are not updated. Use Upsert to overwrite them.
+//   - mutations are applied asynchronously, so inserted vectors may not be queried immediately.
+func (idx *VectorizeIndex) Insert(vectors []*VectorizeVector) (string, error) {
+	return idx.mutate("insert", vectors)
+}
+
+// Upsert inserts the vectors or overwrites existing ones, and returns the ID of the mutation.
+//   - mutations are applied asynchronously, so upserted vectors may not be queried immediately.
+func (idx *VectorizeIndex) Upsert(vectors []*VectorizeVector) (string, error) {
+	return idx.mutate("upsert", vectors)
+}
+
+// Query returns vectors nearest to the vector.
+//   - if opts is nil, the defaults of Vectorize are used.
+func (idx *VectorizeIndex) Query(vector []float32, opts *VectorizeQueryOptions) (*VectorizeMatches, error) {
+	jsVector, err := toJSValue(vector)
+	if err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = &VectorizeQueryOptions{}
+	}
+	jsOpts, err := toJSValue(opts)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding query options: %w", err)
+	}
+	v, err := jsutil.AwaitPromise(idx.instance.Call("query", jsVector, jsOpts))
+	if err != nil {
+		return nil, err
+	}
+	var matches VectorizeMatches
+	if err := fromJSValue(v, &matches); err != nil {
+		return nil, err
+	}
+	return &matches, nil
+}
+
+// GetByIDs returns vectors of the IDs. Vectors not found are omitted.
+func (idx *VectorizeIndex) GetByIDs(ids []string) ([]*VectorizeVector, error) {
+	var vectors []*VectorizeVector
+	if err := idx.call("getByIds", ids, &vectors); err != nil {
+		return nil, err
+	}
+	return vectors, nil
+}
+
+// DeleteByIDs deletes vectors of the IDs, and returns the ID of the mutation.
+func (idx *VectorizeIndex) DeleteByIDs(ids []string) (string, error) {
+	return idx.mutate("deleteByIds", ids)
+}
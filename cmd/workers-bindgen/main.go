@@ -0,0 +1,144 @@
+//go:build !tinygo
+
+// Command workers-bindgen generates and verifies bindings of wrangler.toml from bindings declared by Go code,
+// preventing drift between the code of a worker and its deployment configuration.
+//
+// Usage:
+//
+//	workers-bindgen [flags] [directories]
+//
+// Directories default to ".". A directory followed by "/..." includes its subdirectories.
+// Bindings are declared by Go code as below. Only string literals are recognized.
+//   - calls of constructors of bindings (e.g. cloudflare.NewKVNamespace(ctx, "MY_KV"), d1.OpenConnector(ctx, "DB")).
+//   - specs given to cloudflare.MustBindings and cloudflare.ValidateBindings (e.g. "MY_KV:kv_namespace").
+//   - fields of structs tagged by `binding:"NAME"` or `binding:"NAME,type"`. If the type is omitted,
+//     it is inferred from the type of the field (e.g. cloudflare.KVNamespaceBinding).
+//
+// By default, sections of bindings missing in wrangler.toml are printed, so they can be pasted into it.
+//   - -w appends the sections to wrangler.toml. Placeholders (e.g. "<namespace id>") must be filled in.
+//   - -check exits with status 1 if bindings are missing in wrangler.toml or configured as different types (e.g. in CI).
+//
+// Only top level bindings are handled; bindings of environments ([env.<name>]) are not checked.
+// Vars are not generated since they may be secrets.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// errDrift is returned by -check when the configuration drifts from the code.
+var errDrift = errors.New("bindings of wrangler.toml drift from the code")
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		if !errors.Is(err, errDrift) {
+			fmt.Fprintln(os.Stderr, "workers-bindgen:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("workers-bindgen", flag.ContinueOnError)
+	configPath := flags.String("config", "wrangler.toml", "path of the configuration of wrangler")
+	write := flags.Bool("w", false, "append sections of missing bindings to the configuration")
+	checkOnly := flags.Bool("check", false, "report drifts of bindings, and exit with status 1 if any")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: workers-bindgen [flags] [directories]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *write && *checkOnly {
+		return errors.New("-w and -check can't be used together")
+	}
+	patterns := flags.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+	decls, err := scan(patterns)
+	if err != nil {
+		return err
+	}
+	decls, err = merge(decls)
+	if err != nil {
+		return err
+	}
+
+	src, err := os.ReadFile(*configPath)
+	if err != nil && !(errors.Is(err, fs.ErrNotExist) && !*checkOnly) {
+		return err
+	}
+	cfg, err := parseConfig(bytes.NewReader(src))
+	if err != nil {
+		return fmt.Errorf("%s: %w", *configPath, err)
+	}
+	problems, notes := check(decls, cfg)
+
+	if *checkOnly {
+		for _, p := range problems {
+			fmt.Fprintln(stdout, p)
+		}
+		for _, n := range notes {
+			fmt.Fprintln(stdout, n)
+		}
+		if len(problems) > 0 {
+			return errDrift
+		}
+		return nil
+	}
+
+	// notes are written as comments, so the output can be pasted as it is.
+	for _, n := range notes {
+		fmt.Fprintln(stdout, "# "+n)
+	}
+	var missing []*declaration
+	for _, p := range problems {
+		if !p.missing {
+			return fmt.Errorf("%s. Fix it in %s", p, *configPath)
+		}
+		if s := sections[p.decl.Type]; s != nil && !s.array {
+			if _, ok := cfg.tables[s.table]; ok {
+				return fmt.Errorf("%s: %s can't be added, since [%s] of another binding exists in %s", p.decl.Pos, p.decl.Name, s.table, *configPath)
+			}
+		}
+		missing = append(missing, p.decl)
+	}
+	var out bytes.Buffer
+	if err := render(&out, missing); err != nil {
+		return err
+	}
+	if !*write {
+		_, err := stdout.Write(out.Bytes())
+		return err
+	}
+	if out.Len() == 0 {
+		return nil
+	}
+	f, err := os.OpenFile(*configPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	prefix := "\n"
+	if len(src) > 0 && !bytes.HasSuffix(src, []byte("\n")) {
+		prefix = "\n\n"
+	} else if len(src) == 0 {
+		prefix = ""
+	}
+	if _, err := io.WriteString(f, prefix); err != nil {
+		return err
+	}
+	if _, err := f.Write(out.Bytes()); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Added %d bindings to %s. Fill in placeholders of them.\n", len(missing), *configPath)
+	return f.Close()
+}
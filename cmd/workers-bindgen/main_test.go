@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSource = `package main
+
+import (
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/cloudflare/d1"
+)
+
+type Env struct {
+	Sessions cloudflare.KVNamespaceBinding ` + "`binding:\"SESSIONS\"`" + `
+	Index    any                           ` + "`binding:\"INDEX,vectorize\"`" + `
+}
+
+func main() {
+	cloudflare.MustBindings("MY_BUCKET:r2_bucket", "API_TOKEN")
+	kv, _ := cloudflare.NewKVNamespace(nil, "MY_KV")
+	_ = kv
+	d1.OpenConnector(nil, "DB")
+}
+`
+
+func TestRun(t *testing.T) {
+	tests := map[string]struct {
+		config     string
+		args       []string
+		wantOut    []string
+		notWantOut []string
+		wantConfig []string
+		wantErr    error
+	}{
+		"print missing sections": {
+			config: "[[kv_namespaces]]\nbinding = \"MY_KV\"\nid = \"1\"\n",
+			wantOut: []string{
+				"[[d1_databases]]\nbinding = \"DB\"\n",
+				"[[kv_namespaces]]\nbinding = \"SESSIONS\"\nid = \"<namespace id>\"\n",
+				"[[r2_buckets]]\nbinding = \"MY_BUCKET\"\n",
+				"[[vectorize]]\nbinding = \"INDEX\"\n",
+				"# ", "API_TOKEN is not configured",
+			},
+			notWantOut: []string{`binding = "MY_KV"`},
+		},
+		"write": {
+			config: "name = \"app\"\n\n[vars]\nAPI_TOKEN = \"x\" # comment\n",
+			args:   []string{"-w"},
+			wantConfig: []string{
+				"[vars]\nAPI_TOKEN = \"x\" # comment\n\n[[d1_databases]]\nbinding = \"DB\"\n",
+				"[[kv_namespaces]]\nbinding = \"MY_KV\"\n",
+				"[[vectorize]]\nbinding = \"INDEX\"\n",
+			},
+		},
+		"check passes": {
+			config: strings.Join([]string{
+				"[[kv_namespaces]]\nbinding = \"MY_KV\"\nid = \"1\"",
+				"[[kv_namespaces]]\nbinding = 'SESSIONS'\nid = \"2\"",
+				"[[r2_buckets]]\nbinding = \"MY_BUCKET\"\nbucket_name = \"b\"",
+				"[[d1_databases]]\nbinding = \"DB\"",
+				"[[vectorize]]\nbinding = \"INDEX\"",
+			}, "\n"),
+			args:    []string{"-check"},
+			wantOut: []string{"API_TOKEN is not configured"},
+		},
+		"check fails": {
+			config: "[[r2_buckets]]\nbinding = \"DB\"\n",
+			args:   []string{"-check"},
+			wantOut: []string{
+				"MY_KV (kv_namespace) is not configured",
+				"DB is configured as r2_bucket, but declared as d1_database",
+			},
+			wantErr: errDrift,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(testSource), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			configPath := filepath.Join(dir, "wrangler.toml")
+			if err := os.WriteFile(configPath, []byte(tc.config), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			err := run(append(tc.args, "-config", configPath, dir), &out)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("want error %v, got %v", tc.wantErr, err)
+			}
+			for _, want := range tc.wantOut {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("want output to contain %q, got:\n%s", want, out.String())
+				}
+			}
+			for _, notWant := range tc.notWantOut {
+				if strings.Contains(out.String(), notWant) {
+					t.Errorf("want output not to contain %q, got:\n%s", notWant, out.String())
+				}
+			}
+			b, err := os.ReadFile(configPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tc.wantConfig {
+				if !strings.Contains(string(b), want) {
+					t.Errorf("want wrangler.toml to contain %q, got:\n%s", want, b)
+				}
+			}
+		})
+	}
+}
+
+func TestRun_ConflictingDeclarations(t *testing.T) {
+	dir := t.TempDir()
+	src := "package main\n\nimport \"github.com/syumai/workers/cloudflare\"\n\nfunc main() {\n\tcloudflare.MustBindings(\"X:kv_namespace\", \"X:r2_bucket\")\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err := run([]string{"-config", filepath.Join(dir, "wrangler.toml"), dir}, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "X is declared as r2_bucket") {
+		t.Errorf("want error of conflicting types, got %v", err)
+	}
+}
@@ -0,0 +1,257 @@
+//go:build !tinygo
+
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// declaration is a binding declared by Go code.
+type declaration struct {
+	Name string
+	// Type is the type of the binding (e.g. "kv_namespace"), or empty if it is not known.
+	Type string
+	Pos  token.Position
+}
+
+const modulePath = "github.com/syumai/workers"
+
+// constructors maps package paths and names of functions taking binding names to types of the bindings.
+//   - argIndex is the index of the argument of the binding name.
+var constructors = map[string]map[string]struct {
+	typ      string
+	argIndex int
+}{
+	modulePath + "/cloudflare": {
+		"NewKVNamespace":            {"kv_namespace", 1},
+		"NewR2Bucket":               {"r2_bucket", 1},
+		"NewDurableObjectNamespace": {"durable_object_namespace", 1},
+		"NewContainerNamespace":     {"durable_object_namespace", 1},
+		"NewAnalyticsEngineDataset": {"analytics_engine", 1},
+		"NewRateLimiter":            {"ratelimit", 1},
+		"NewImages":                 {"images", 1},
+		"NewVectorizeIndex":         {"vectorize", 1},
+	},
+	modulePath + "/cloudflare/d1":     {"OpenConnector": {"d1_database", 1}},
+	modulePath + "/cloudflare/queues": {"NewProducer": {"queue", 1}},
+	modulePath + "/cloudflare/fetch":  {"NewServiceClient": {"service", 1}},
+	modulePath + "/cloudflare/ai":     {"NewAI": {"ai", 1}},
+}
+
+// specFuncs maps package paths and names of functions taking specs of bindings ("NAME:type") to the index of the first spec.
+var specFuncs = map[string]map[string]int{
+	modulePath + "/cloudflare": {"MustBindings": 0, "ValidateBindings": 1},
+}
+
+// fieldTypes maps names of types of fields of Env structs to types of bindings.
+var fieldTypes = map[string]string{
+	"KVNamespace":                   "kv_namespace",
+	"KVNamespaceBinding":            "kv_namespace",
+	"R2Bucket":                      "r2_bucket",
+	"R2BucketBinding":               "r2_bucket",
+	"DurableObjectNamespace":        "durable_object_namespace",
+	"AnalyticsEngineDataset":        "analytics_engine",
+	"AnalyticsEngineDatasetBinding": "analytics_engine",
+	"RateLimiter":                   "ratelimit",
+	"RateLimiterBinding":            "ratelimit",
+	"Images":                        "images",
+	"ImagesBinding":                 "images",
+	"VectorizeIndex":                "vectorize",
+	"VectorizeIndexBinding":         "vectorize",
+	"Producer":                      "queue",
+	"ProducerBinding":               "queue",
+	"AI":                            "ai",
+	"DB":                            "d1_database",
+	"string":                        "var",
+}
+
+// scan returns bindings declared by Go files in the directories of the patterns.
+//   - a pattern is a directory, or a directory followed by "/..." to include its subdirectories.
+//   - test files, and directories of testdata, vendor and hidden ones are skipped.
+func scan(patterns []string) ([]*declaration, error) {
+	var decls []*declaration
+	fset := token.NewFileSet()
+	for _, pattern := range patterns {
+		dir, recursive := strings.CutSuffix(pattern, "/...")
+		if dir == "" {
+			dir = "."
+		}
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path == dir {
+					return nil
+				}
+				name := d.Name()
+				if !recursive || name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+				return nil
+			}
+			src, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			f, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+			if err != nil {
+				return err
+			}
+			decls = append(decls, scanFile(fset, f)...)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return decls, nil
+}
+
+// scanFile returns bindings declared in the file by calls of the functions, and fields tagged by `binding`.
+func scanFile(fset *token.FileSet, f *ast.File) []*declaration {
+	imports := make(map[string]string, len(f.Imports))
+	for _, imp := range f.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		name := path[strings.LastIndex(path, "/")+1:]
+		if path == modulePath {
+			name = "workers"
+		}
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		imports[name] = path
+	}
+	var decls []*declaration
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			decls = append(decls, scanCall(fset, imports, n)...)
+		case *ast.Field:
+			if d := scanField(fset, n); d != nil {
+				decls = append(decls, d)
+			}
+		}
+		return true
+	})
+	return decls
+}
+
+func scanCall(fset *token.FileSet, imports map[string]string, call *ast.CallExpr) []*declaration {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return nil
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	path := imports[pkg.Name]
+	if c, ok := constructors[path][sel.Sel.Name]; ok {
+		if c.argIndex >= len(call.Args) {
+			return nil
+		}
+		if name, ok := stringLit(call.Args[c.argIndex]); ok {
+			return []*declaration{{Name: name, Type: c.typ, Pos: fset.Position(call.Pos())}}
+		}
+		return nil
+	}
+	start, ok := specFuncs[path][sel.Sel.Name]
+	if !ok {
+		return nil
+	}
+	var decls []*declaration
+	for i := start; i < len(call.Args); i++ {
+		spec, ok := stringLit(call.Args[i])
+		if !ok {
+			continue
+		}
+		name, typ, _ := strings.Cut(spec, ":")
+		decls = append(decls, &declaration{Name: name, Type: typ, Pos: fset.Position(call.Args[i].Pos())})
+	}
+	return decls
+}
+
+// scanField returns the binding declared by the tag of the field: `binding:"NAME"` or `binding:"NAME,type"`.
+//   - if the type is omitted, it is inferred from the type of the field.
+func scanField(fset *token.FileSet, field *ast.Field) *declaration {
+	if field.Tag == nil {
+		return nil
+	}
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return nil
+	}
+	value, ok := reflect.StructTag(tag).Lookup("binding")
+	if !ok || value == "" || value == "-" {
+		return nil
+	}
+	name, typ, _ := strings.Cut(value, ",")
+	if typ == "" {
+		typ = fieldTypes[typeName(field.Type)]
+	}
+	return &declaration{Name: name, Type: typ, Pos: fset.Position(field.Pos())}
+}
+
+// typeName returns the name of the type expression without the package and pointers.
+func typeName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return typeName(e.X)
+	case *ast.SelectorExpr:
+		return e.Sel.Name
+	case *ast.Ident:
+		return e.Name
+	}
+	return ""
+}
+
+func stringLit(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+// merge merges declarations of the same names.
+//   - declarations without types are merged into typed ones.
+//   - if a binding is declared with different types, or of an unknown type, returns error.
+func merge(decls []*declaration) ([]*declaration, error) {
+	byName := make(map[string]*declaration, len(decls))
+	var merged []*declaration
+	for _, d := range decls {
+		if d.Type != "" && d.Type != "var" && sections[d.Type] == nil {
+			return nil, fmt.Errorf("%s: unknown type %s of %s", d.Pos, d.Type, d.Name)
+		}
+		existing, ok := byName[d.Name]
+		if !ok {
+			c := *d
+			byName[d.Name] = &c
+			merged = append(merged, &c)
+			continue
+		}
+		switch {
+		case d.Type == "" || d.Type == existing.Type:
+		case existing.Type == "":
+			existing.Type = d.Type
+			existing.Pos = d.Pos
+		default:
+			return nil, fmt.Errorf("%s: %s is declared as %s, but it is declared as %s at %s", d.Pos, d.Name, d.Type, existing.Type, existing.Pos)
+		}
+	}
+	return merged, nil
+}
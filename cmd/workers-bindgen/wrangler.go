@@ -0,0 +1,247 @@
+//go:build !tinygo
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// section describes how bindings of a type are configured in wrangler.toml.
+type section struct {
+	// table is the name of the table, or of the array of tables if array is true.
+	table string
+	array bool
+	// nameKey is the key of the binding name.
+	nameKey string
+	// fields are other keys and values of generated sections. Values are written as they are.
+	fields [][2]string
+	// match reports whether the entry of the table is of the type. If nil, all entries are.
+	match func(entry map[string]string) bool
+}
+
+// sections maps types of bindings to their sections.
+//   - vars are declared in the [vars] table, and are never generated since they may be secrets.
+var sections = map[string]*section{
+	"kv_namespace": {table: "kv_namespaces", array: true, nameKey: "binding", fields: [][2]string{
+		{"id", `"<namespace id>"`},
+	}},
+	"r2_bucket": {table: "r2_buckets", array: true, nameKey: "binding", fields: [][2]string{
+		{"bucket_name", `"<bucket name>"`},
+	}},
+	"d1_database": {table: "d1_databases", array: true, nameKey: "binding", fields: [][2]string{
+		{"database_name", `"<database name>"`},
+		{"database_id", `"<database id>"`},
+	}},
+	"durable_object_namespace": {table: "durable_objects.bindings", array: true, nameKey: "name", fields: [][2]string{
+		{"class_name", `"<class name>"`},
+	}},
+	"queue": {table: "queues.producers", array: true, nameKey: "binding", fields: [][2]string{
+		{"queue", `"<queue name>"`},
+	}},
+	"service": {table: "services", array: true, nameKey: "binding", fields: [][2]string{
+		{"service", `"<worker name>"`},
+	}},
+	"ai": {table: "ai", nameKey: "binding"},
+	"vectorize": {table: "vectorize", array: true, nameKey: "binding", fields: [][2]string{
+		{"index_name", `"<index name>"`},
+	}},
+	"analytics_engine": {table: "analytics_engine_datasets", array: true, nameKey: "binding", fields: [][2]string{
+		{"dataset", `"<dataset name>"`},
+	}},
+	"ratelimit": {table: "unsafe.bindings", array: true, nameKey: "name", fields: [][2]string{
+		{"type", `"ratelimit"`},
+		{"namespace_id", `"<namespace id>"`},
+		{"simple", `{ limit = 100, period = 60 }`},
+	}, match: func(entry map[string]string) bool {
+		return entry["type"] == "ratelimit"
+	}},
+	"images": {table: "images", nameKey: "binding"},
+	"hyperdrive": {table: "hyperdrive", array: true, nameKey: "binding", fields: [][2]string{
+		{"id", `"<hyperdrive id>"`},
+	}},
+}
+
+// config holds top level tables and arrays of tables of wrangler.toml.
+type config struct {
+	tables map[string]map[string]string
+	arrays map[string][]map[string]string
+}
+
+// parseConfig parses tables and keys of strings of wrangler.toml.
+//   - it is not a complete TOML parser. Values other than single line strings are kept as raw text,
+//     and lines of multi-line values are ignored.
+func parseConfig(r io.Reader) (*config, error) {
+	cfg := &config{
+		tables: map[string]map[string]string{},
+		arrays: map[string][]map[string]string{},
+	}
+	current := map[string]string{}
+	cfg.tables[""] = current
+	sc := bufio.NewScanner(r)
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "[["):
+			end := strings.Index(line, "]]")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: invalid array of tables", lineNo)
+			}
+			name := strings.TrimSpace(line[2:end])
+			current = map[string]string{}
+			cfg.arrays[name] = append(cfg.arrays[name], current)
+		case strings.HasPrefix(line, "["):
+			end := strings.Index(line, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: invalid table", lineNo)
+			}
+			name := strings.TrimSpace(line[1:end])
+			current = cfg.tables[name]
+			if current == nil {
+				current = map[string]string{}
+				cfg.tables[name] = current
+			}
+		default:
+			key, value, ok := strings.Cut(line, "=")
+			if !ok {
+				// a line of a multi-line value.
+				continue
+			}
+			key = strings.TrimSpace(key)
+			if k, err := strconv.Unquote(key); err == nil {
+				key = k
+			}
+			current[key] = parseValue(strings.TrimSpace(value))
+		}
+	}
+	return cfg, sc.Err()
+}
+
+// parseValue returns the string of a quoted value, or the raw value without a trailing comment.
+func parseValue(v string) string {
+	if strings.HasPrefix(v, `'`) {
+		if end := strings.Index(v[1:], `'`); end >= 0 {
+			return v[1 : end+1]
+		}
+	}
+	if strings.HasPrefix(v, `"`) {
+		for i := 1; i < len(v); i++ {
+			if v[i] == '\\' {
+				i++
+				continue
+			}
+			if v[i] == '"' {
+				if s, err := strconv.Unquote(v[:i+1]); err == nil {
+					return s
+				}
+				return v[1:i]
+			}
+		}
+	}
+	if i := strings.Index(v, "#"); i >= 0 {
+		v = v[:i]
+	}
+	return strings.TrimSpace(v)
+}
+
+// bindings returns types of bindings configured by the config by their names.
+func (cfg *config) bindings() map[string]string {
+	bindings := map[string]string{}
+	for name := range cfg.tables["vars"] {
+		bindings[name] = "var"
+	}
+	for typ, s := range sections {
+		var entries []map[string]string
+		if s.array {
+			entries = cfg.arrays[s.table]
+		} else if t, ok := cfg.tables[s.table]; ok {
+			entries = []map[string]string{t}
+		}
+		for _, entry := range entries {
+			name := entry[s.nameKey]
+			if name == "" || (s.match != nil && !s.match(entry)) {
+				continue
+			}
+			bindings[name] = typ
+		}
+	}
+	return bindings
+}
+
+// problem is a drift between declarations and the config.
+type problem struct {
+	decl *declaration
+	// missing is true if the binding is not configured.
+	missing bool
+	// configured is the type of the configured binding.
+	configured string
+}
+
+func (p *problem) String() string {
+	d := p.decl
+	switch {
+	case !p.missing:
+		return fmt.Sprintf("%s: %s is configured as %s, but declared as %s", d.Pos, d.Name, p.configured, d.Type)
+	case d.Type == "":
+		return fmt.Sprintf("%s: %s is not configured", d.Pos, d.Name)
+	}
+	return fmt.Sprintf("%s: %s (%s) is not configured", d.Pos, d.Name, d.Type)
+}
+
+// check compares the declarations with the config.
+//   - problems are drifts which should be fixed. notes are bindings which may be configured outside wrangler.toml:
+//     vars and bindings of unknown types may be secrets.
+func check(decls []*declaration, cfg *config) (problems []*problem, notes []string) {
+	configured := cfg.bindings()
+	for _, d := range decls {
+		typ, ok := configured[d.Name]
+		switch {
+		case !ok && (d.Type == "" || d.Type == "var"):
+			notes = append(notes, fmt.Sprintf("%s: %s is not configured. Add it to [vars], or set it as a secret by `wrangler secret put %s`", d.Pos, d.Name, d.Name))
+		case !ok:
+			problems = append(problems, &problem{decl: d, missing: true})
+		case d.Type != "" && d.Type != typ:
+			problems = append(problems, &problem{decl: d, configured: typ})
+		}
+	}
+	return problems, notes
+}
+
+// render writes sections of the declarations in the order of types and names.
+//   - declarations of types without sections (e.g. vars) are skipped.
+func render(w io.Writer, decls []*declaration) error {
+	sorted := make([]*declaration, 0, len(decls))
+	for _, d := range decls {
+		if sections[d.Type] != nil {
+			sorted = append(sorted, d)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Type != sorted[j].Type {
+			return sorted[i].Type < sorted[j].Type
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	bw := bufio.NewWriter(w)
+	for i, d := range sorted {
+		s := sections[d.Type]
+		if i > 0 {
+			bw.WriteString("\n")
+		}
+		if s.array {
+			fmt.Fprintf(bw, "[[%s]]\n", s.table)
+		} else {
+			fmt.Fprintf(bw, "[%s]\n", s.table)
+		}
+		fmt.Fprintf(bw, "%s = %s\n", s.nameKey, strconv.Quote(d.Name))
+		for _, f := range s.fields {
+			fmt.Fprintf(bw, "%s = %s\n", f[0], f[1])
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,144 @@
+//go:build !tinygo
+
+// Command workers-init generates a ready-to-run project of a worker using github.com/syumai/workers.
+//
+// Usage:
+//
+//	workers-init [flags] <directory>
+//
+// The generated project contains:
+//   - main.go: the entry point starting a router.
+//   - worker.mjs: the JavaScript shim loading the Wasm binary and dispatching events to Go.
+//   - wrangler.toml: the configuration of the worker with example bindings.
+//   - Makefile: targets to build, run locally and deploy the worker.
+//   - go.mod and .gitignore.
+package main
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+//go:embed templates/*.tmpl
+var templates embed.FS
+
+// files maps generated file names to template names.
+var files = map[string]string{
+	"main.go":       "main.go.tmpl",
+	"worker.mjs":    "worker.mjs.tmpl",
+	"wrangler.toml": "wrangler.toml.tmpl",
+	"Makefile":      "Makefile.tmpl",
+	"go.mod":        "go.mod.tmpl",
+	".gitignore":    "gitignore.tmpl",
+}
+
+// config represents values given to templates.
+type config struct {
+	// Name is the name of the worker.
+	Name string
+	// Module is the module path of the project.
+	Module string
+	// TinyGo builds the worker by TinyGo instead of Go.
+	TinyGo bool
+	// CompatibilityDate is the compatibility date of the worker.
+	CompatibilityDate string
+}
+
+var workerNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "workers-init:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("workers-init", flag.ContinueOnError)
+	name := flags.String("name", "", "name of the worker (default: base name of the directory)")
+	module := flags.String("module", "", "module path of the project (default: name of the worker)")
+	tinygo := flags.Bool("tinygo", true, "build the worker by TinyGo. Go binaries require a paid plan due to their size")
+	force := flags.Bool("force", false, "overwrite existing files")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: workers-init [flags] <directory>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("directory must be given")
+	}
+	dir := flags.Arg(0)
+	cfg := &config{
+		Name:              *name,
+		Module:            *module,
+		TinyGo:            *tinygo,
+		CompatibilityDate: time.Now().UTC().Format("2006-01-02"),
+	}
+	if cfg.Name == "" {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		cfg.Name = strings.ToLower(filepath.Base(abs))
+	}
+	if !workerNameRe.MatchString(cfg.Name) {
+		return fmt.Errorf("invalid worker name %q: only lowercase letters, digits and dashes are allowed", cfg.Name)
+	}
+	if cfg.Module == "" {
+		cfg.Module = cfg.Name
+	}
+	if err := generate(dir, cfg, *force); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Created %s in %s. Next steps:\n", cfg.Name, dir)
+	fmt.Fprintf(stdout, "  cd %s\n", dir)
+	fmt.Fprintln(stdout, "  go mod tidy")
+	fmt.Fprintln(stdout, "  make dev")
+	return nil
+}
+
+// generate writes project files into dir.
+//   - if any of the files exists and force is false, returns error without writing files.
+func generate(dir string, cfg *config, force bool) error {
+	tmpl, err := template.ParseFS(templates, "templates/*.tmpl")
+	if err != nil {
+		return err
+	}
+	rendered := make(map[string][]byte, len(files))
+	for name, tmplName := range files {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil && !force {
+			return fmt.Errorf("%s already exists. use -force to overwrite", path)
+		} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&buf, tmplName, cfg); err != nil {
+			return err
+		}
+		rendered[path] = buf.Bytes()
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for path, b := range rendered {
+		if err := os.WriteFile(path, b, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	tests := map[string]struct {
+		args      []string
+		dir       string
+		wantFiles map[string][]string
+		wantErr   bool
+	}{
+		"default": {
+			dir: "my-worker",
+			wantFiles: map[string][]string{
+				"main.go":       {"workers.Start(workers.Options{Fetch: r})", "from my-worker"},
+				"wrangler.toml": {`name = "my-worker"`, `main = "./worker.mjs"`},
+				"Makefile":      {"tinygo build"},
+				"go.mod":        {"module my-worker"},
+				"worker.mjs":    {`dispatch("handleRequest", req, env, ctx)`, `dispatch("handleQueue", batch, env, ctx)`, "globalThis.teardown()"},
+				".gitignore":    {"build"},
+			},
+		},
+		"go and module": {
+			args: []string{"-tinygo=false", "-module", "example.com/app", "-name", "app"},
+			dir:  "src",
+			wantFiles: map[string][]string{
+				"Makefile":      {"GOOS=js GOARCH=wasm go build"},
+				"go.mod":        {"module example.com/app"},
+				"wrangler.toml": {`name = "app"`},
+			},
+		},
+		"invalid name": {
+			args:    []string{"-name", "My_Worker"},
+			dir:     "src",
+			wantErr: true,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			dir := filepath.Join(t.TempDir(), tc.dir)
+			err := run(append(tc.args, dir), io.Discard)
+			if tc.wantErr {
+				if err == nil {
+					t.Error("want error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for file, wants := range tc.wantFiles {
+				b, err := os.ReadFile(filepath.Join(dir, file))
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, want := range wants {
+					if !strings.Contains(string(b), want) {
+						t.Errorf("want %s to contain %q, got:\n%s", file, want, b)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRun_ExistingFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := run([]string{"-name", "app", dir}, io.Discard); err == nil {
+		t.Error("want error for existing files, got nil")
+	}
+	if err := run([]string{"-name", "app", "-force", dir}, io.Discard); err != nil {
+		t.Errorf("want no error with -force, got %v", err)
+	}
+}
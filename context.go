@@ -0,0 +1,15 @@
+package workers
+
+import "github.com/syumai/workers/internal/runtimecontext"
+
+// Contexts given to handlers of requests, queues and scheduled events are canceled when the events settle.
+// The cause of the cancellation can be obtained by context.Cause.
+//   - bindings, environment variables and properties of the event can be obtained from the context
+//     (e.g. cloudflare.Getenv, cloudflare.NewIncomingProperties, cloudflare.RayID).
+//   - use Go or cloudflare.WaitUntil to run tasks after the event settles.
+var (
+	// ErrEventSettled is the cause of the cancellation after the response body is written, or the handler returns.
+	ErrEventSettled = runtimecontext.ErrEventSettled
+	// ErrRequestAborted is the cause of the cancellation when the client aborted the request.
+	ErrRequestAborted = runtimecontext.ErrRequestAborted
+)
@@ -0,0 +1,14 @@
+package workers
+
+import (
+	"github.com/syumai/workers/internal/debuglog"
+)
+
+// SetDebug enables internal diagnostic logs of workers packages (e.g. calls of bindings) by the given function.
+//   - Diagnostic logs are disabled by default. If nil is given, they are disabled again.
+//   - The function is called with a message and alternating keys and values, as Debug of *slog.Logger.
+//     e.g. workers.SetDebug(slog.New(logging.NewHandler(&logging.HandlerOptions{Level: slog.LevelDebug})).Debug)
+//   - log/slog is not linked into workers unless it is used, so this takes a function instead of *slog.Logger.
+func SetDebug(logFn func(msg string, args ...any)) {
+	debuglog.SetLogFunc(logFn)
+}
@@ -0,0 +1,32 @@
+package workers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/syumai/workers/internal/runtimecontext"
+)
+
+// PanicError represents a panic recovered while handling a request or an event.
+//   - Value is the value passed to panic, and Stack is the stack trace of the goroutine which panicked.
+type PanicError = runtimecontext.PanicError
+
+// ErrorHook is a function called with errors occurred while handling the request.
+//   - err is *PanicError for recovered panics, which holds the stack trace.
+//   - req is the request being handled. Its URL and Cf-Ray header can be used to identify the request.
+//     It is nil for errors of other events (e.g. scheduled events and queues) and of cloudflare.WaitUntil tasks.
+//   - Hooks are called synchronously, so sending reports over the network should be done with cloudflare.WaitUntil.
+type ErrorHook = runtimecontext.ErrorHook
+
+// OnError registers the hook called for handler errors and recovered panics.
+//   - errors returned by handlers of other events (e.g. cron.Handler) and panics in them are reported as well.
+func OnError(hook ErrorHook) {
+	runtimecontext.OnError(hook)
+}
+
+// ReportError calls hooks registered by OnError.
+// Middlewares and handlers can call this to report errors that don't reach the Serve layer.
+//   - panics in hooks are ignored to avoid failing the request while reporting.
+func ReportError(ctx context.Context, err error, req *http.Request) {
+	runtimecontext.ReportError(ctx, err, req)
+}
@@ -0,0 +1,95 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// reportedErrors holds errors reported to OnError by requests.
+var reportedErrors sync.Map
+
+func init() {
+	OnError(func(ctx context.Context, err error, req *http.Request) {
+		if req != nil {
+			reportedErrors.Store(req, err)
+		}
+	})
+}
+
+func TestOnError(t *testing.T) {
+	errFailed := errors.New("failed")
+	tests := map[string]struct {
+		handler    http.Handler
+		wantStatus int
+		wantErr    func(err error) bool
+	}{
+		"internal error": {
+			handler: HandlerFunc(func(w http.ResponseWriter, req *http.Request) error {
+				return errFailed
+			}),
+			wantStatus: http.StatusInternalServerError,
+			wantErr: func(err error) bool {
+				return errors.Is(err, errFailed)
+			},
+		},
+		"client error": {
+			handler: HandlerFunc(func(w http.ResponseWriter, req *http.Request) error {
+				return NewHTTPError(http.StatusNotFound, "not found", errFailed)
+			}),
+			wantStatus: http.StatusNotFound,
+		},
+		"panic": {
+			handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				panic("boom")
+			}),
+			wantStatus: http.StatusInternalServerError,
+			wantErr: func(err error) bool {
+				var panicErr *PanicError
+				return errors.As(err, &panicErr) && panicErr.Value == "boom" && len(panicErr.Stack) > 0
+			},
+		},
+		"aborted": {
+			handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				panic(http.ErrAbortHandler)
+			}),
+			wantStatus: http.StatusOK,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			// dispatchRequest serves the handler registered globally, so the cases are not parallel.
+			httpHandler = tc.handler
+			t.Cleanup(func() { httpHandler = nil })
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			var reported error
+			// the request is replaced by dispatchRequest, so the reported request is found by the URL.
+			rec := httptest.NewRecorder()
+			dispatchRequest(context.Background(), rec, req)
+			reportedErrors.Range(func(k, v any) bool {
+				if k.(*http.Request).URL == req.URL {
+					reported = v.(error)
+					reportedErrors.Delete(k)
+				}
+				return true
+			})
+			if rec.Code != tc.wantStatus {
+				t.Errorf("want status %d, got %d", tc.wantStatus, rec.Code)
+			}
+			if tc.wantErr == nil {
+				if reported != nil {
+					t.Errorf("want no errors reported, got %v", reported)
+				}
+				return
+			}
+			if reported == nil || !tc.wantErr(reported) {
+				t.Errorf("want the error reported, got %v", reported)
+			}
+		})
+	}
+}
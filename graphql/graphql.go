@@ -0,0 +1,226 @@
+// Package graphql serves GraphQL over HTTP by an executor of a schema (e.g. graphql-go or gqlgen).
+//   - GET requests and POST requests of "application/json" and "application/graphql" are parsed into Params.
+//   - Automatic Persisted Queries are supported. Queries are cached by their SHA-256 hashes in the Cache API by default,
+//     so clients can send hashes instead of whole queries, and GET requests of persisted queries can be cached by CDNs.
+//   - https://graphql.org/learn/serving-over-http/
+//   - https://www.apollographql.com/docs/apollo-server/performance/apq
+//
+// Example of graphql-go:
+//
+//	h := graphql.NewHandler(graphql.ExecutorFunc(func(ctx context.Context, p *graphql.Params) *graphql.Response {
+//		r := gql.Do(gql.Params{Schema: schema, RequestString: p.Query, OperationName: p.OperationName, VariableValues: p.Variables, Context: ctx})
+//		return graphql.NewResponse(r.Data, r.Errors)
+//	}), nil)
+package graphql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Params is the parameters of a GraphQL request.
+type Params struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+	Extensions    map[string]any `json:"extensions,omitempty"`
+	// Method is the HTTP method of the request. Executors should reject mutations requested by GET.
+	Method string `json:"-"`
+}
+
+// Error is an error of a GraphQL response.
+type Error struct {
+	Message    string         `json:"message"`
+	Path       []any          `json:"path,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+// Response is the result of a GraphQL request.
+type Response struct {
+	Data       any            `json:"data,omitempty"`
+	Errors     []*Error       `json:"errors,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+// NewResponse returns Response of the data and errors of an executor.
+// Errors are converted by their Error method, so errors of any libraries can be used.
+func NewResponse[E error](data any, errs []E) *Response {
+	res := &Response{Data: data}
+	for _, err := range errs {
+		res.Errors = append(res.Errors, &Error{Message: err.Error()})
+	}
+	return res
+}
+
+// Executor executes GraphQL requests.
+type Executor interface {
+	Execute(ctx context.Context, params *Params) *Response
+}
+
+// ExecutorFunc is a function implementing Executor.
+type ExecutorFunc func(ctx context.Context, params *Params) *Response
+
+func (f ExecutorFunc) Execute(ctx context.Context, params *Params) *Response {
+	return f(ctx, params)
+}
+
+const defaultMaxBodySize = 1 << 20
+
+// Options represents options of NewHandler.
+type Options struct {
+	// PersistedQueries stores queries of Automatic Persisted Queries. Defaults to NewCacheQueryStore(nil).
+	PersistedQueries QueryStore
+	// DisablePersistedQueries disables Automatic Persisted Queries.
+	DisablePersistedQueries bool
+	// MaxBodySize is the maximum size of request bodies. Defaults to 1 MiB.
+	MaxBodySize int64
+}
+
+type handler struct {
+	exec        Executor
+	queries     QueryStore
+	maxBodySize int64
+}
+
+// NewHandler returns a handler executing GraphQL requests by the executor.
+//   - responses are "application/json" with status 200, even if they have errors.
+//     Requests which can't be parsed respond with status 400, and unsupported methods respond with status 405.
+func NewHandler(exec Executor, opts *Options) http.Handler {
+	h := &handler{exec: exec, maxBodySize: defaultMaxBodySize}
+	if opts == nil {
+		opts = &Options{}
+	}
+	if opts.MaxBodySize > 0 {
+		h.maxBodySize = opts.MaxBodySize
+	}
+	if !opts.DisablePersistedQueries {
+		h.queries = opts.PersistedQueries
+		if h.queries == nil {
+			h.queries = NewCacheQueryStore(nil)
+		}
+	}
+	return h
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var params *Params
+	var err error
+	switch req.Method {
+	case http.MethodGet:
+		params, err = parseQuery(req)
+	case http.MethodPost:
+		params, err = h.parseBody(req)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, errorResponse(err.Error(), ""))
+		return
+	}
+	params.Method = req.Method
+	if h.queries != nil {
+		if res := h.resolvePersistedQuery(req.Context(), params); res != nil {
+			writeResponse(w, http.StatusOK, res)
+			return
+		}
+	}
+	if params.Query == "" {
+		writeResponse(w, http.StatusBadRequest, errorResponse("query is required", ""))
+		return
+	}
+	writeResponse(w, http.StatusOK, h.exec.Execute(req.Context(), params))
+}
+
+func parseQuery(req *http.Request) (*Params, error) {
+	q := req.URL.Query()
+	params := &Params{Query: q.Get("query"), OperationName: q.Get("operationName")}
+	if v := q.Get("variables"); v != "" {
+		if err := json.Unmarshal([]byte(v), &params.Variables); err != nil {
+			return nil, errors.New("variables must be a JSON object")
+		}
+	}
+	if v := q.Get("extensions"); v != "" {
+		if err := json.Unmarshal([]byte(v), &params.Extensions); err != nil {
+			return nil, errors.New("extensions must be a JSON object")
+		}
+	}
+	return params, nil
+}
+
+func (h *handler) parseBody(req *http.Request) (*Params, error) {
+	body, err := io.ReadAll(io.LimitReader(req.Body, h.maxBodySize+1))
+	if err != nil {
+		return nil, errors.New("failed to read the request")
+	}
+	if int64(len(body)) > h.maxBodySize {
+		return nil, errors.New("the request is too large")
+	}
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/graphql":
+		return &Params{Query: string(body)}, nil
+	case "application/json", "":
+		var params Params
+		if err := json.Unmarshal(body, &params); err != nil {
+			return nil, errors.New("the request must be a JSON object")
+		}
+		return &params, nil
+	}
+	return nil, errors.New("unsupported Content-Type: " + mediaType)
+}
+
+// resolvePersistedQuery resolves the query of Automatic Persisted Queries.
+// It returns the response of an error if the query can't be resolved, or nil.
+func (h *handler) resolvePersistedQuery(ctx context.Context, params *Params) *Response {
+	pq, ok := params.Extensions["persistedQuery"].(map[string]any)
+	if !ok {
+		return nil
+	}
+	hash, _ := pq["sha256Hash"].(string)
+	if version, _ := pq["version"].(float64); version != 1 || hash == "" {
+		return errorResponse("unsupported persisted query version", "PERSISTED_QUERY_NOT_SUPPORTED")
+	}
+	hash = strings.ToLower(hash)
+	if params.Query == "" {
+		query, found, err := h.queries.Get(ctx, hash)
+		if err != nil || !found {
+			return errorResponse("PersistedQueryNotFound", "PERSISTED_QUERY_NOT_FOUND")
+		}
+		params.Query = query
+		return nil
+	}
+	sum := sha256.Sum256([]byte(params.Query))
+	if hex.EncodeToString(sum[:]) != hash {
+		return errorResponse("provided sha does not match query", "")
+	}
+	// storing failure is not fatal, since the client sends the query again.
+	_ = h.queries.Put(ctx, hash, params.Query)
+	return nil
+}
+
+func errorResponse(message, code string) *Response {
+	e := &Error{Message: message}
+	if code != "" {
+		e.Extensions = map[string]any{"code": code}
+	}
+	return &Response{Errors: []*Error{e}}
+}
+
+func writeResponse(w http.ResponseWriter, status int, res *Response) {
+	b, err := json.Marshal(res)
+	if err != nil {
+		b, _ = json.Marshal(errorResponse("failed to encode the response", ""))
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(b)
+}
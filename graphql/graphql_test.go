@@ -0,0 +1,112 @@
+package graphql_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/syumai/workers/graphql"
+	"github.com/syumai/workers/workerstest"
+)
+
+func TestHandler(t *testing.T) {
+	exec := graphql.ExecutorFunc(func(ctx context.Context, p *graphql.Params) *graphql.Response {
+		return &graphql.Response{Data: map[string]any{"query": p.Query, "method": p.Method, "name": p.Variables["name"]}}
+	})
+	h := graphql.NewHandler(exec, &graphql.Options{
+		PersistedQueries: graphql.NewCacheQueryStore(&graphql.CacheQueryStoreOptions{Cache: &workerstest.Cache{}}),
+	})
+	query := "{ hello }"
+	sum := sha256.Sum256([]byte(query))
+	apq := `{"persistedQuery":{"version":1,"sha256Hash":"` + hex.EncodeToString(sum[:]) + `"}}`
+
+	// the steps share the persisted query store, so they run in order.
+	steps := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        string
+		wantStatus  int
+		wantBody    string
+	}{
+		{
+			name:        "json",
+			method:      http.MethodPost,
+			target:      "/graphql",
+			contentType: "application/json",
+			body:        `{"query":"query($name: String) { hello(name: $name) }","variables":{"name":"gopher"}}`,
+			wantStatus:  http.StatusOK,
+			wantBody:    `{"data":{"method":"POST","name":"gopher","query":"query($name: String) { hello(name: $name) }"}}`,
+		},
+		{
+			name:        "graphql",
+			method:      http.MethodPost,
+			target:      "/graphql",
+			contentType: "application/graphql",
+			body:        query,
+			wantStatus:  http.StatusOK,
+			wantBody:    `{"data":{"method":"POST","name":null,"query":"{ hello }"}}`,
+		},
+		{
+			name:       "persisted query not found",
+			method:     http.MethodGet,
+			target:     "/graphql?extensions=" + url.QueryEscape(apq),
+			wantStatus: http.StatusOK,
+			wantBody:   `{"errors":[{"message":"PersistedQueryNotFound","extensions":{"code":"PERSISTED_QUERY_NOT_FOUND"}}]}`,
+		},
+		{
+			name:        "persisted query registered",
+			method:      http.MethodPost,
+			target:      "/graphql",
+			contentType: "application/json",
+			body:        `{"query":"{ hello }","extensions":` + apq + `}`,
+			wantStatus:  http.StatusOK,
+			wantBody:    `{"data":{"method":"POST","name":null,"query":"{ hello }"}}`,
+		},
+		{
+			name:       "persisted query found",
+			method:     http.MethodGet,
+			target:     "/graphql?extensions=" + url.QueryEscape(apq),
+			wantStatus: http.StatusOK,
+			wantBody:   `{"data":{"method":"GET","name":null,"query":"{ hello }"}}`,
+		},
+		{
+			name:        "hash mismatch",
+			method:      http.MethodPost,
+			target:      "/graphql",
+			contentType: "application/json",
+			body:        `{"query":"{ other }","extensions":` + apq + `}`,
+			wantStatus:  http.StatusOK,
+			wantBody:    `{"errors":[{"message":"provided sha does not match query"}]}`,
+		},
+		{
+			name:        "invalid body",
+			method:      http.MethodPost,
+			target:      "/graphql",
+			contentType: "application/json",
+			body:        `[`,
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    `{"errors":[{"message":"the request must be a JSON object"}]}`,
+		},
+	}
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.target, strings.NewReader(step.body))
+		if step.contentType != "" {
+			req.Header.Set("Content-Type", step.contentType)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != step.wantStatus {
+			t.Errorf("%s: want status %d, got %d", step.name, step.wantStatus, rec.Code)
+		}
+		if got := rec.Body.String(); got != step.wantBody {
+			t.Errorf("%s: want body %s, got %s", step.name, step.wantBody, got)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/syumai/workers/cloudflare/cache"
+)
+
+// QueryStore stores queries of Automatic Persisted Queries by their SHA-256 hashes in hex.
+type QueryStore interface {
+	Get(ctx context.Context, hash string) (query string, found bool, err error)
+	Put(ctx context.Context, hash, query string) error
+}
+
+// persistedQueryURL is the base of URLs of cache keys of queries. It is never fetched.
+const persistedQueryURL = "https://graphql-persisted-queries.invalid/"
+
+// CacheQueryStore is QueryStore backed by the Cache API.
+//   - the Cache API is local to each data center, so clients may need to send queries again in other locations.
+type CacheQueryStore struct {
+	cache cache.Store
+	ttl   time.Duration
+}
+
+var _ QueryStore = (*CacheQueryStore)(nil)
+
+// CacheQueryStoreOptions represents options of NewCacheQueryStore.
+type CacheQueryStoreOptions struct {
+	// Cache is the cache to store queries. Defaults to the default cache (cache.New()).
+	Cache cache.Store
+	// TTL is how long queries are cached. Defaults to 24 hours.
+	TTL time.Duration
+}
+
+// NewCacheQueryStore returns CacheQueryStore.
+func NewCacheQueryStore(opts *CacheQueryStoreOptions) *CacheQueryStore {
+	s := &CacheQueryStore{ttl: 24 * time.Hour}
+	if opts != nil {
+		s.cache = opts.Cache
+		if opts.TTL > 0 {
+			s.ttl = opts.TTL
+		}
+	}
+	return s
+}
+
+func (s *CacheQueryStore) store() cache.Store {
+	if s.cache != nil {
+		return s.cache
+	}
+	// the default cache is obtained lazily, since it is available only in the runtime.
+	return cache.New()
+}
+
+func (s *CacheQueryStore) request(ctx context.Context, hash string) (*http.Request, error) {
+	return http.NewRequestWithContext(ctx, http.MethodGet, persistedQueryURL+hash, nil)
+}
+
+func (s *CacheQueryStore) Get(ctx context.Context, hash string) (string, bool, error) {
+	req, err := s.request(ctx, hash)
+	if err != nil {
+		return "", false, err
+	}
+	res, err := s.store().Match(req, nil)
+	if errors.Is(err, cache.ErrNotFound) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", false, err
+	}
+	return string(b), true, nil
+}
+
+func (s *CacheQueryStore) Put(ctx context.Context, hash, query string) error {
+	req, err := s.request(ctx, hash)
+	if err != nil {
+		return err
+	}
+	res := &http.Response{
+		StatusCode: http.StatusOK,
+		Header: http.Header{
+			"Content-Type":  {"application/graphql"},
+			"Cache-Control": {"public, max-age=" + strconv.Itoa(int(s.ttl/time.Second))},
+		},
+		Body:          io.NopCloser(strings.NewReader(query)),
+		ContentLength: int64(len(query)),
+	}
+	return s.store().Put(req, res)
+}
@@ -3,13 +3,13 @@ package workers
 import (
 	"context"
 	"fmt"
-	"io"
 	"net/http"
-	"syscall/js"
 
+	"github.com/syumai/workers/internal/js"
 	"github.com/syumai/workers/internal/jshttp"
 	"github.com/syumai/workers/internal/jsutil"
 	"github.com/syumai/workers/internal/runtimecontext"
+	"github.com/syumai/workers/trace"
 )
 
 var httpHandler http.Handler
@@ -29,10 +29,12 @@ func init() {
 		cb = js.FuncOf(func(_ js.Value, pArgs []js.Value) any {
 			defer cb.Release()
 			resolve := pArgs[0]
+			reject := pArgs[1]
 			go func() {
 				res, err := handleRequest(reqObj, runtimeCtxObj)
 				if err != nil {
-					panic(err)
+					reject.Invoke(jsutil.ErrorClass.New(err.Error()))
+					return
 				}
 				resolve.Invoke(res)
 			}()
@@ -41,42 +43,45 @@ func init() {
 		return jsutil.NewPromise(cb)
 	})
 	jsutil.Global.Set("handleRequest", handleRequestCallback)
+	runtimecontext.RegisterHTTPHandler(dispatchRequest)
+}
+
+// dispatchRequest serves the request dispatched without the JavaScript runtime (e.g. by the replay package).
+func dispatchRequest(ctx context.Context, w http.ResponseWriter, req *http.Request) {
+	if httpHandler == nil {
+		http.Error(w, "Register, or Start with Fetch handler must be called before dispatching requests.", http.StatusInternalServerError)
+		return
+	}
+	ctx = trace.NewContext(ctx, trace.Extract(req))
+	req = req.WithContext(withBackgroundTasks(ctx, req))
+	defer func() {
+		recovered := recover()
+		if recovered == nil || recovered == http.ErrAbortHandler {
+			return
+		}
+		ReportError(req.Context(), runtimecontext.NewPanicError(recovered), req)
+		w.WriteHeader(http.StatusInternalServerError)
+	}()
+	httpHandler.ServeHTTP(w, req)
 }
 
 // handleRequest accepts a Request object and returns Response object.
 func handleRequest(reqObj js.Value, runtimeCtxObj js.Value) (js.Value, error) {
 	if httpHandler == nil {
-		return js.Value{}, fmt.Errorf("Serve must be called before handleRequest.")
-	}
-	req, err := jshttp.ToRequest(reqObj)
-	if err != nil {
-		panic(err)
-	}
-	ctx := runtimecontext.New(context.Background(), runtimeCtxObj)
-	req = req.WithContext(ctx)
-	reader, writer := io.Pipe()
-	w := &jshttp.ResponseWriterBuffer{
-		HeaderValue: http.Header{},
-		StatusCode:  http.StatusOK,
-		Reader:      reader,
-		Writer:      writer,
-		ReadyCh:     make(chan struct{}),
+		return js.Value{}, fmt.Errorf("Serve, or Start with Fetch handler must be called before handleRequest.")
 	}
-	go func() {
-		defer w.Ready()
-		defer writer.Close()
-		httpHandler.ServeHTTP(w, req)
-	}()
-	return jshttp.ToJSResponse(w)
+	return jshttp.Serve(httpHandler, reqObj, runtimeCtxObj, func(ctx context.Context, req *http.Request) context.Context {
+		ctx = trace.NewContext(ctx, trace.Extract(req))
+		return withBackgroundTasks(ctx, req)
+	})
 }
 
 // Server serves http.Handler on Cloudflare Workers.
 // if the given handler is nil, http.DefaultServeMux will be used.
+// Middlewares registered by Use are applied to the handler.
 func Serve(handler http.Handler) {
 	if handler == nil {
 		handler = http.DefaultServeMux
 	}
-	httpHandler = handler
-	jsutil.Global.Call("ready")
-	select {}
+	Start(Options{Fetch: handler})
 }
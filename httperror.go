@@ -0,0 +1,111 @@
+package workers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/syumai/workers/internal/debuglog"
+)
+
+// HTTPError is an error with the status and the message of the response.
+//   - Message is public and written to the response. Err is internal, and is only logged and reported.
+type HTTPError struct {
+	// Status is the status code of the response.
+	Status int
+	// Message is written to the response. Defaults to the status text.
+	Message string
+	// Err is the internal error causing this error.
+	Err error
+}
+
+// NewHTTPError returns a new HTTPError.
+func NewHTTPError(status int, message string, err error) *HTTPError {
+	return &HTTPError{Status: status, Message: message, Err: err}
+}
+
+func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%d %s: %v", e.Status, e.message(), e.Err)
+	}
+	return fmt.Sprintf("%d %s", e.Status, e.message())
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+func (e *HTTPError) message() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return http.StatusText(e.Status)
+}
+
+// HandlerFunc is a handler returning error. The error is written to the response by WriteError.
+//   - the handler must not write the response before returning error.
+type HandlerFunc func(w http.ResponseWriter, req *http.Request) error
+
+func (f HandlerFunc) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if err := f(w, req); err != nil {
+		WriteError(w, req, err)
+	}
+}
+
+// problemDetails represents problem details of RFC 9457.
+//   - https://www.rfc-editor.org/rfc/rfc9457
+type problemDetails struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// WriteError writes the response for the error.
+//   - *HTTPError in the chain of err responds with its status and message. Other errors respond with status 500.
+//   - if the request accepts "application/problem+json" or "application/json", the response is problem details (RFC 9457).
+//     Otherwise, the response is plain text.
+//   - errors of status 500 and above are reported to hooks registered by OnError, and logged as diagnostic logs enabled by SetDebug.
+//     Internal errors are never written to the response.
+func WriteError(w http.ResponseWriter, req *http.Request, err error) {
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		httpErr = &HTTPError{Status: http.StatusInternalServerError, Err: err}
+	}
+	if httpErr.Status >= http.StatusInternalServerError {
+		debuglog.Log("workers: error response", "method", req.Method, "path", req.URL.Path, "status", httpErr.Status, "error", err)
+		ReportError(req.Context(), err, req)
+	}
+	if !acceptsJSON(req.Header.Get("Accept")) {
+		http.Error(w, httpErr.message(), httpErr.Status)
+		return
+	}
+	p := &problemDetails{
+		Type:   "about:blank",
+		Title:  http.StatusText(httpErr.Status),
+		Status: httpErr.Status,
+	}
+	if msg := httpErr.message(); msg != p.Title {
+		p.Detail = msg
+	}
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", "application/problem+json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(httpErr.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// acceptsJSON reports whether the Accept header contains JSON media types.
+func acceptsJSON(accept string) bool {
+	for _, t := range strings.Split(accept, ",") {
+		t, _, _ = strings.Cut(t, ";")
+		switch strings.TrimSpace(t) {
+		case "application/problem+json", "application/json":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package workers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandlerFunc(t *testing.T) {
+	tests := map[string]struct {
+		err             error
+		accept          string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		"no error": {
+			wantStatus: http.StatusOK,
+			wantBody:   "ok",
+		},
+		"http error": {
+			err:             NewHTTPError(http.StatusNotFound, "user not found", errors.New("no rows")),
+			wantStatus:      http.StatusNotFound,
+			wantContentType: "text/plain; charset=utf-8",
+			wantBody:        "user not found\n",
+		},
+		"wrapped http error as problem details": {
+			err:             fmt.Errorf("loading user: %w", NewHTTPError(http.StatusNotFound, "user not found", nil)),
+			accept:          "application/json",
+			wantStatus:      http.StatusNotFound,
+			wantContentType: "application/problem+json",
+			wantBody:        `{"type":"about:blank","title":"Not Found","status":404,"detail":"user not found"}` + "\n",
+		},
+		"internal error is not leaked": {
+			err:             errors.New("connection refused"),
+			accept:          "text/html, application/problem+json;q=0.9",
+			wantStatus:      http.StatusInternalServerError,
+			wantContentType: "application/problem+json",
+			wantBody:        `{"type":"about:blank","title":"Internal Server Error","status":500}` + "\n",
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			h := HandlerFunc(func(w http.ResponseWriter, req *http.Request) error {
+				if tc.err != nil {
+					return tc.err
+				}
+				_, err := w.Write([]byte("ok"))
+				return err
+			})
+			req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tc.wantStatus {
+				t.Errorf("want status %d, got %d", tc.wantStatus, rec.Code)
+			}
+			if got := rec.Header().Get("Content-Type"); tc.wantContentType != "" && got != tc.wantContentType {
+				t.Errorf("want Content-Type %q, got %q", tc.wantContentType, got)
+			}
+			if got := rec.Body.String(); got != tc.wantBody {
+				t.Errorf("want body %q, got %q", tc.wantBody, got)
+			}
+		})
+	}
+}
+
+func TestWriteError_DebugLog(t *testing.T) {
+	var logs []string
+	SetDebug(func(msg string, args ...any) {
+		logs = append(logs, fmt.Sprint(append([]any{msg}, args...)...))
+	})
+	defer SetDebug(nil)
+
+	WriteError(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/a", nil), &HTTPError{Status: http.StatusNotFound})
+	WriteError(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/b", nil), errors.New("database is down"))
+	if len(logs) != 1 {
+		t.Fatalf("want only the internal error logged, got %q", logs)
+	}
+	for _, want := range []string{"/b", "500", "database is down"} {
+		if !strings.Contains(logs[0], want) {
+			t.Errorf("want %s logged, got %q", want, logs[0])
+		}
+	}
+}
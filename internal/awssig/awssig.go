@@ -0,0 +1,86 @@
+// Package awssig provides canonicalization shared by signers of AWS Signature Version 4.
+//   - https://docs.aws.amazon.com/IAM/latest/UserGuide/create-signed-request.html
+package awssig
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const (
+	// Algorithm is the signing algorithm of SigV4.
+	Algorithm = "AWS4-HMAC-SHA256"
+	// TimeFormat is the format of X-Amz-Date.
+	TimeFormat = "20060102T150405Z"
+	// DateFormat is the format of the date in the credential scope.
+	DateFormat = "20060102"
+	// UnsignedPayload is used as the payload hash when the payload is not signed.
+	UnsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// Scope returns the credential scope of the date, region and service.
+func Scope(date, region, service string) string {
+	return strings.Join([]string{date, region, service, "aws4_request"}, "/")
+}
+
+// CanonicalHeaders returns the canonical headers and the signed headers of the headers keyed by lowercase names.
+func CanonicalHeaders(headers map[string]string) (canonical, signed string) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name + ":" + strings.Join(strings.Fields(headers[name]), " ") + "\n")
+	}
+	return b.String(), strings.Join(names, ";")
+}
+
+// CanonicalQueryString encodes the query sorted by encoded keys, and then by encoded values.
+//   - pairs are sorted before they are joined, since "=" sorts after characters of keys such as "-".
+func CanonicalQueryString(query url.Values) string {
+	pairs := make([][2]string, 0, len(query))
+	for k, vs := range query {
+		ek := URIEncode(k, true)
+		for _, v := range vs {
+			pairs = append(pairs, [2]string{ek, URIEncode(v, true)})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	var b strings.Builder
+	for i, p := range pairs {
+		if i > 0 {
+			b.WriteByte('&')
+		}
+		b.WriteString(p[0] + "=" + p[1])
+	}
+	return b.String()
+}
+
+// URIEncode encodes s as required by SigV4. Slashes are encoded only if encodeSlash is true.
+func URIEncode(s string, encodeSlash bool) string {
+	const hexChars = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hexChars[c>>4])
+			b.WriteByte(hexChars[c&15])
+		}
+	}
+	return b.String()
+}
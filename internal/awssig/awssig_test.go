@@ -0,0 +1,44 @@
+package awssig
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestCanonicalQueryString(t *testing.T) {
+	tests := map[string]struct {
+		query string
+		want  string
+	}{
+		"sorted by keys": {
+			query: "b=1&a=2",
+			want:  "a=2&b=1",
+		},
+		"key prefixed by another key": {
+			query: "a-b=1&a=2",
+			want:  "a=2&a-b=1",
+		},
+		"same keys sorted by values": {
+			query: "a=2&a=1",
+			want:  "a=1&a=2",
+		},
+		"encoded": {
+			query: "key=a%2Fb%20c",
+			want:  "key=a%2Fb%20c",
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			query, err := url.ParseQuery(tc.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := CanonicalQueryString(query); got != tc.want {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+// Package debuglog holds the function of internal diagnostic logs enabled by workers.SetDebug.
+//   - Diagnostic logs are disabled by default, so packages of workers don't write to the console unless enabled.
+//   - The function is a plain func instead of *slog.Logger, so log/slog is not linked into workers which don't use it.
+package debuglog
+
+import "sync"
+
+var (
+	mu    sync.RWMutex
+	logFn func(msg string, args ...any)
+)
+
+// SetLogFunc sets the function of diagnostic logs. If nil is given, diagnostic logs are disabled.
+func SetLogFunc(fn func(msg string, args ...any)) {
+	mu.Lock()
+	defer mu.Unlock()
+	logFn = fn
+}
+
+// Enabled reports whether diagnostic logs are enabled.
+//   - Callers must check this before building arguments, so disabled logs don't allocate.
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return logFn != nil
+}
+
+// Log logs the message with alternating keys and values if diagnostic logs are enabled.
+func Log(msg string, args ...any) {
+	mu.RLock()
+	fn := logFn
+	mu.RUnlock()
+	if fn == nil {
+		return
+	}
+	fn(msg, args...)
+}
@@ -0,0 +1,29 @@
+package debuglog
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLog(t *testing.T) {
+	var buf bytes.Buffer
+	Log("disabled")
+	if Enabled() {
+		t.Fatal("diagnostic logs must be disabled by default")
+	}
+
+	SetLogFunc(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})).Debug)
+	Log("enabled", "key", "value")
+	SetLogFunc(nil)
+	Log("disabled again")
+
+	got := buf.String()
+	if !strings.Contains(got, "level=DEBUG msg=enabled key=value") {
+		t.Fatalf("want enabled log, got: %q", got)
+	}
+	if strings.Contains(got, "disabled") {
+		t.Fatalf("want no logs while disabled, got: %q", got)
+	}
+}
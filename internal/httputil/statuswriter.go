@@ -0,0 +1,52 @@
+// Package httputil provides helpers of net/http shared by packages of this module.
+package httputil
+
+import "net/http"
+
+// StatusWriter is a http.ResponseWriter recording the status and the body size of the response.
+type StatusWriter struct {
+	http.ResponseWriter
+	// Status is the status of the response. This is http.StatusOK until WriteHeader is called.
+	Status int
+	// Size is the number of bytes of the body written.
+	Size int64
+	// WroteHeader reports whether the header has been written.
+	WroteHeader bool
+}
+
+// NewStatusWriter returns a StatusWriter wrapping w.
+func NewStatusWriter(w http.ResponseWriter) *StatusWriter {
+	return &StatusWriter{ResponseWriter: w, Status: http.StatusOK}
+}
+
+func (w *StatusWriter) WriteHeader(status int) {
+	if !w.WroteHeader {
+		w.Status = status
+		w.WroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *StatusWriter) Write(b []byte) (int, error) {
+	if !w.WroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.Size += int64(n)
+	return n, err
+}
+
+// Flush writes the header if it is not written yet, and flushes the underlying ResponseWriter if it implements http.Flusher.
+func (w *StatusWriter) Flush() {
+	if !w.WroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (w *StatusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
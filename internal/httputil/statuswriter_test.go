@@ -0,0 +1,25 @@
+package httputil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStatusWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := NewStatusWriter(rec)
+	if err := http.NewResponseController(w).Flush(); err != nil {
+		t.Fatalf("want flush through ResponseController, got %v", err)
+	}
+	if !rec.Flushed || !w.WroteHeader || w.Status != http.StatusOK {
+		t.Errorf("want flushed response of status 200, got flushed: %v, status: %d", rec.Flushed, w.Status)
+	}
+	w.WriteHeader(http.StatusInternalServerError)
+	if _, err := w.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if w.Status != http.StatusOK || w.Size != 5 {
+		t.Errorf("want status 200 and size 5, got %d and %d", w.Status, w.Size)
+	}
+}
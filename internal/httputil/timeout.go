@@ -0,0 +1,46 @@
+package httputil
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// DoWithHeaderTimeout sends the request by do, and aborts it if the headers of the response don't arrive within the timeout.
+//   - unlike context.WithTimeout, the timeout doesn't abort the body of the response being read.
+//     The context of the request is canceled when the body is closed.
+//   - if the timeout exceeds, returns context.DeadlineExceeded.
+func DoWithHeaderTimeout(req *http.Request, timeout time.Duration, do func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	timer := time.AfterFunc(timeout, cancel)
+	res, err := do(req.WithContext(ctx))
+	if !timer.Stop() {
+		// the timer has fired, so the request is aborted.
+		if err == nil {
+			res.Body.Close()
+		}
+		cancel()
+		if parentErr := req.Context().Err(); parentErr != nil {
+			return nil, parentErr
+		}
+		return nil, context.DeadlineExceeded
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	res.Body = &cancelOnClose{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+// cancelOnClose cancels the context when the body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
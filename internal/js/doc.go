@@ -0,0 +1,8 @@
+// Package js provides the subset of syscall/js used by the packages of this module.
+//   - On js/wasm, the declarations are aliases of syscall/js.
+//   - On other platforms, the declarations are backed by an in-memory mock runtime,
+//     so packages importing workers can be compiled, vetted and unit-tested without GOOS=js.
+//     The mock runtime supports plain objects, arrays and functions, and its global object has
+//     console, performance.now, JSON.parse / JSON.stringify and Object.keys. Other JavaScript APIs are undefined,
+//     and calling them panics.
+package js
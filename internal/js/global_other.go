@@ -0,0 +1,119 @@
+//go:build !(js && wasm)
+
+package js
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// global is the global object of the mock runtime.
+var global = newGlobal()
+
+func newGlobal() Value {
+	g := newObject()
+
+	console := newObject()
+	for _, m := range []string{"log", "info", "debug", "warn", "error"} {
+		m := m
+		console.Set(m, FuncOf(func(_ Value, args []Value) any {
+			consoleWrite(m, args)
+			return Undefined()
+		}))
+	}
+	g.Set("console", console)
+
+	start := time.Now()
+	performance := newObject()
+	performance.Set("now", FuncOf(func(Value, []Value) any {
+		return float64(time.Since(start).Nanoseconds()) / 1e6
+	}))
+	g.Set("performance", performance)
+
+	jsonObj := newObject()
+	jsonObj.Set("parse", FuncOf(func(_ Value, args []Value) any {
+		var v any
+		if err := json.Unmarshal([]byte(args[0].String()), &v); err != nil {
+			panic(err)
+		}
+		return ValueOf(v)
+	}))
+	jsonObj.Set("stringify", FuncOf(func(_ Value, args []Value) any {
+		b, err := json.Marshal(toGo(args[0]))
+		if err != nil {
+			panic(err)
+		}
+		return string(b)
+	}))
+	g.Set("JSON", jsonObj)
+
+	objectObj := newObject()
+	objectObj.Set("keys", FuncOf(func(_ Value, args []Value) any {
+		// properties are not ordered in the mock runtime, so keys are sorted.
+		var keys []Value
+		if o := args[0]; o.typ.isObject() && !o.obj.isArray {
+			names := make([]string, 0, len(o.obj.props))
+			for k := range o.obj.props {
+				names = append(names, k)
+			}
+			sort.Strings(names)
+			for _, k := range names {
+				keys = append(keys, Value{typ: TypeString, s: k})
+			}
+		}
+		return newArray(keys)
+	}))
+	g.Set("Object", objectObj)
+	return g
+}
+
+// consoleWrite writes the arguments of console methods to stderr.
+func consoleWrite(method string, args []Value) {
+	s := make([]string, len(args))
+	for i, a := range args {
+		if a.typ == TypeString {
+			s[i] = a.s
+			continue
+		}
+		b, err := json.Marshal(toGo(a))
+		if err != nil {
+			s[i] = a.String()
+			continue
+		}
+		s[i] = string(b)
+	}
+	fmt.Fprintf(os.Stderr, "console.%s: %s\n", method, strings.Join(s, " "))
+}
+
+// toGo converts the value into a JSON-compatible Go value.
+func toGo(v Value) any {
+	switch v.typ {
+	case TypeBoolean:
+		return v.b
+	case TypeNumber:
+		return v.n
+	case TypeString:
+		return v.s
+	case TypeObject:
+		if v.obj.isArray {
+			a := make([]any, len(v.obj.elems))
+			for i, e := range v.obj.elems {
+				a[i] = toGo(e)
+			}
+			return a
+		}
+		m := make(map[string]any, len(v.obj.props))
+		for k, p := range v.obj.props {
+			if p.typ != TypeUndefined && p.typ != TypeFunction {
+				m[k] = toGo(p)
+			}
+		}
+		return m
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,370 @@
+//go:build !(js && wasm)
+
+package js
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Type represents the JavaScript type of a Value.
+type Type int
+
+const (
+	TypeUndefined Type = iota
+	TypeNull
+	TypeBoolean
+	TypeNumber
+	TypeString
+	TypeSymbol
+	TypeObject
+	TypeFunction
+)
+
+func (t Type) String() string {
+	switch t {
+	case TypeUndefined:
+		return "undefined"
+	case TypeNull:
+		return "null"
+	case TypeBoolean:
+		return "boolean"
+	case TypeNumber:
+		return "number"
+	case TypeString:
+		return "string"
+	case TypeSymbol:
+		return "symbol"
+	case TypeObject:
+		return "object"
+	case TypeFunction:
+		return "function"
+	default:
+		panic("bad type")
+	}
+}
+
+func (t Type) isObject() bool {
+	return t == TypeObject || t == TypeFunction
+}
+
+// Value represents a value of the mock runtime.
+type Value struct {
+	typ Type
+	b   bool
+	n   float64
+	s   string
+	obj *object
+}
+
+// object holds properties of objects, elements of arrays, and bodies of functions.
+type object struct {
+	props   map[string]Value
+	isArray bool
+	elems   []Value
+	fn      func(this Value, args []Value) any
+}
+
+func newObject() Value {
+	return Value{typ: TypeObject, obj: &object{props: map[string]Value{}}}
+}
+
+func newArray(elems []Value) Value {
+	return Value{typ: TypeObject, obj: &object{props: map[string]Value{}, isArray: true, elems: elems}}
+}
+
+// Error wraps a JavaScript error.
+type Error struct {
+	Value
+}
+
+func (e Error) Error() string {
+	return "JavaScript error: " + e.Get("message").String()
+}
+
+// ValueError is the panic value of Value methods called on a value of the wrong type.
+type ValueError struct {
+	Method string
+	Type   Type
+}
+
+func (e *ValueError) Error() string {
+	return "syscall/js: call of " + e.Method + " on " + e.Type.String()
+}
+
+// Func is a function of the mock runtime wrapping a Go function.
+type Func struct {
+	Value
+}
+
+// FuncOf returns a function calling fn.
+func FuncOf(fn func(this Value, args []Value) any) Func {
+	v := newObject()
+	v.typ = TypeFunction
+	v.obj.fn = fn
+	return Func{Value: v}
+}
+
+// Release does nothing on the mock runtime.
+func (f Func) Release() {}
+
+// Undefined returns the JavaScript value undefined.
+func Undefined() Value {
+	return Value{typ: TypeUndefined}
+}
+
+// Null returns the JavaScript value null.
+func Null() Value {
+	return Value{typ: TypeNull}
+}
+
+// Global returns the global object of the mock runtime.
+func Global() Value {
+	return global
+}
+
+// ValueOf returns x as a Value.
+//   - []any and map[string]any are converted into new arrays and objects recursively.
+func ValueOf(x any) Value {
+	switch x := x.(type) {
+	case Value:
+		return x
+	case Func:
+		return x.Value
+	case nil:
+		return Null()
+	case bool:
+		return Value{typ: TypeBoolean, b: x}
+	case int:
+		return number(float64(x))
+	case int8:
+		return number(float64(x))
+	case int16:
+		return number(float64(x))
+	case int32:
+		return number(float64(x))
+	case int64:
+		return number(float64(x))
+	case uint:
+		return number(float64(x))
+	case uint8:
+		return number(float64(x))
+	case uint16:
+		return number(float64(x))
+	case uint32:
+		return number(float64(x))
+	case uint64:
+		return number(float64(x))
+	case uintptr:
+		return number(float64(x))
+	case float32:
+		return number(float64(x))
+	case float64:
+		return number(x)
+	case string:
+		return Value{typ: TypeString, s: x}
+	case []any:
+		elems := make([]Value, len(x))
+		for i, e := range x {
+			elems[i] = ValueOf(e)
+		}
+		return newArray(elems)
+	case map[string]any:
+		v := newObject()
+		for k, e := range x {
+			v.obj.props[k] = ValueOf(e)
+		}
+		return v
+	default:
+		panic("ValueOf: invalid value")
+	}
+}
+
+func number(f float64) Value {
+	return Value{typ: TypeNumber, n: f}
+}
+
+// CopyBytesToGo is not supported by the mock runtime since it has no Uint8Array.
+func CopyBytesToGo(dst []byte, src Value) int {
+	panic(notAvailable("Uint8Array"))
+}
+
+// CopyBytesToJS is not supported by the mock runtime since it has no Uint8Array.
+func CopyBytesToJS(dst Value, src []byte) int {
+	panic(notAvailable("Uint8Array"))
+}
+
+func notAvailable(name string) error {
+	return fmt.Errorf("syscall/js: %s is not available since the JavaScript runtime is mocked on this platform", name)
+}
+
+func (v Value) Type() Type {
+	return v.typ
+}
+
+func (v Value) IsUndefined() bool {
+	return v.typ == TypeUndefined
+}
+
+func (v Value) IsNull() bool {
+	return v.typ == TypeNull
+}
+
+func (v Value) IsNaN() bool {
+	return v.typ == TypeNumber && math.IsNaN(v.n)
+}
+
+func (v Value) Equal(w Value) bool {
+	if v.typ.isObject() {
+		return v.obj == w.obj
+	}
+	return v.typ == w.typ && v.b == w.b && v.n == w.n && v.s == w.s
+}
+
+// Get returns the property of the object.
+//   - unlike JavaScript, getting a property of a primitive value returns undefined instead of panicking,
+//     so lookups of runtime APIs (e.g. globalThis.crypto.subtle) don't panic until they are called.
+func (v Value) Get(p string) Value {
+	if !v.typ.isObject() {
+		return Undefined()
+	}
+	if v.obj.isArray && p == "length" {
+		return number(float64(len(v.obj.elems)))
+	}
+	if p, ok := v.obj.props[p]; ok {
+		return p
+	}
+	return Undefined()
+}
+
+func (v Value) Set(p string, x any) {
+	if !v.typ.isObject() {
+		panic(&ValueError{Method: "Value.Set", Type: v.typ})
+	}
+	v.obj.props[p] = ValueOf(x)
+}
+
+func (v Value) Delete(p string) {
+	if !v.typ.isObject() {
+		panic(&ValueError{Method: "Value.Delete", Type: v.typ})
+	}
+	delete(v.obj.props, p)
+}
+
+func (v Value) Index(i int) Value {
+	if !v.typ.isObject() {
+		panic(&ValueError{Method: "Value.Index", Type: v.typ})
+	}
+	if !v.obj.isArray || i < 0 || i >= len(v.obj.elems) {
+		return Undefined()
+	}
+	return v.obj.elems[i]
+}
+
+func (v Value) SetIndex(i int, x any) {
+	if !v.typ.isObject() || !v.obj.isArray {
+		panic(&ValueError{Method: "Value.SetIndex", Type: v.typ})
+	}
+	for len(v.obj.elems) <= i {
+		v.obj.elems = append(v.obj.elems, Undefined())
+	}
+	v.obj.elems[i] = ValueOf(x)
+}
+
+func (v Value) Length() int {
+	if !v.typ.isObject() {
+		panic(&ValueError{Method: "Value.Length", Type: v.typ})
+	}
+	return v.Get("length").Int()
+}
+
+// Call calls the method of the object.
+//   - if the method is not a function, Call panics as well as JavaScript's TypeError.
+func (v Value) Call(m string, args ...any) Value {
+	f := v.Get(m)
+	if f.typ != TypeFunction {
+		panic(notAvailable(m))
+	}
+	return f.call(v, args)
+}
+
+func (v Value) Invoke(args ...any) Value {
+	if v.typ != TypeFunction {
+		panic(&ValueError{Method: "Value.Invoke", Type: v.typ})
+	}
+	return v.call(Undefined(), args)
+}
+
+// New is not supported by the mock runtime since it has no classes.
+func (v Value) New(args ...any) Value {
+	panic(notAvailable("new"))
+}
+
+// InstanceOf always returns false since the mock runtime has no classes.
+func (v Value) InstanceOf(t Value) bool {
+	return false
+}
+
+func (v Value) call(this Value, args []any) Value {
+	jsArgs := make([]Value, len(args))
+	for i, a := range args {
+		jsArgs[i] = ValueOf(a)
+	}
+	return ValueOf(v.obj.fn(this, jsArgs))
+}
+
+func (v Value) Bool() bool {
+	if v.typ != TypeBoolean {
+		panic(&ValueError{Method: "Value.Bool", Type: v.typ})
+	}
+	return v.b
+}
+
+func (v Value) Float() float64 {
+	if v.typ != TypeNumber {
+		panic(&ValueError{Method: "Value.Float", Type: v.typ})
+	}
+	return v.n
+}
+
+func (v Value) Int() int {
+	return int(v.Float())
+}
+
+func (v Value) Truthy() bool {
+	switch v.typ {
+	case TypeUndefined, TypeNull:
+		return false
+	case TypeBoolean:
+		return v.b
+	case TypeNumber:
+		return v.n != 0 && !math.IsNaN(v.n)
+	case TypeString:
+		return v.s != ""
+	default:
+		return true
+	}
+}
+
+// String returns the value as a string, formatted as well as syscall/js for non-string values.
+func (v Value) String() string {
+	switch v.typ {
+	case TypeString:
+		return v.s
+	case TypeUndefined:
+		return "<undefined>"
+	case TypeNull:
+		return "<null>"
+	case TypeBoolean:
+		return "<boolean: " + strconv.FormatBool(v.b) + ">"
+	case TypeNumber:
+		return "<number: " + strconv.FormatFloat(v.n, 'g', -1, 64) + ">"
+	case TypeSymbol:
+		return "<symbol>"
+	case TypeObject:
+		return "<object>"
+	default:
+		return "<function>"
+	}
+}
@@ -0,0 +1,88 @@
+//go:build !(js && wasm)
+
+package js
+
+import "testing"
+
+func TestValueOf(t *testing.T) {
+	tests := map[string]struct {
+		value    any
+		wantType Type
+		want     string
+	}{
+		"nil": {
+			value:    nil,
+			wantType: TypeNull,
+			want:     "<null>",
+		},
+		"bool": {
+			value:    true,
+			wantType: TypeBoolean,
+			want:     "<boolean: true>",
+		},
+		"int": {
+			value:    42,
+			wantType: TypeNumber,
+			want:     "<number: 42>",
+		},
+		"string": {
+			value:    "abc",
+			wantType: TypeString,
+			want:     "abc",
+		},
+		"map": {
+			value:    map[string]any{"a": 1},
+			wantType: TypeObject,
+			want:     "<object>",
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			v := ValueOf(tc.value)
+			if v.Type() != tc.wantType {
+				t.Errorf("want type %v, got %v", tc.wantType, v.Type())
+			}
+			if v.String() != tc.want {
+				t.Errorf("want %q, got %q", tc.want, v.String())
+			}
+		})
+	}
+}
+
+func TestGlobal(t *testing.T) {
+	obj := Global().Get("JSON").Call("parse", `{"a":[1,"b"],"c":{"d":true}}`)
+	if got := obj.Get("a").Length(); got != 2 {
+		t.Errorf("want length 2, got %d", got)
+	}
+	if got := obj.Get("a").Index(1).String(); got != "b" {
+		t.Errorf("want b, got %s", got)
+	}
+	if !obj.Get("c").Get("d").Bool() {
+		t.Error("want c.d to be true")
+	}
+	if got := Global().Get("JSON").Call("stringify", obj.Get("c")).String(); got != `{"d":true}` {
+		t.Errorf("want {\"d\":true}, got %s", got)
+	}
+	if !Global().Get("crypto").Get("subtle").IsUndefined() {
+		t.Error("want crypto.subtle to be undefined")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("want calling undefined function to panic")
+		}
+	}()
+	Global().Call("fetch")
+}
+
+func TestFuncOf(t *testing.T) {
+	obj := ValueOf(map[string]any{})
+	obj.Set("add", FuncOf(func(this Value, args []Value) any {
+		return args[0].Int() + args[1].Int()
+	}))
+	if got := obj.Call("add", 1, 2).Int(); got != 3 {
+		t.Errorf("want 3, got %d", got)
+	}
+}
@@ -0,0 +1,31 @@
+//go:build js && wasm
+
+package js
+
+import "syscall/js"
+
+type (
+	Value = js.Value
+	Func  = js.Func
+	Type  = js.Type
+	Error = js.Error
+)
+
+const (
+	TypeUndefined = js.TypeUndefined
+	TypeNull      = js.TypeNull
+	TypeBoolean   = js.TypeBoolean
+	TypeNumber    = js.TypeNumber
+	TypeString    = js.TypeString
+	TypeSymbol    = js.TypeSymbol
+	TypeObject    = js.TypeObject
+	TypeFunction  = js.TypeFunction
+)
+
+func Global() Value                                     { return js.Global() }
+func Null() Value                                       { return js.Null() }
+func Undefined() Value                                  { return js.Undefined() }
+func ValueOf(x any) Value                               { return js.ValueOf(x) }
+func FuncOf(fn func(this Value, args []Value) any) Func { return js.FuncOf(fn) }
+func CopyBytesToGo(dst []byte, src Value) int           { return js.CopyBytesToGo(dst, src) }
+func CopyBytesToJS(dst Value, src []byte) int           { return js.CopyBytesToJS(dst, src) }
@@ -0,0 +1,97 @@
+package jshttp
+
+import (
+	"io"
+	"sync"
+
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jsutil"
+)
+
+// streamBody is io.ReadCloser sourced from ReadableStream.
+//   - the reader of the stream is acquired on the first Read, so the stream can be teed or passed to JavaScript
+//     as it is until then.
+//   - Close cancels the stream once it has been read, so pending reads return and the rest of the stream is discarded.
+//     Streams which have not been read are left as they are, since they may have been passed to JavaScript.
+type streamBody struct {
+	stream js.Value
+	// byteStream reports whether the stream is a byte stream, which is read by ReadableStreamBYOBReader.
+	byteStream bool
+	reader     io.Reader
+	// onClose is called once when the body is closed.
+	onClose func()
+}
+
+func (b *streamBody) Read(p []byte) (int, error) {
+	if b.reader == nil {
+		if b.byteStream {
+			b.reader = jsutil.ConvertByteStreamToReader(b.stream)
+		} else {
+			b.reader = jsutil.ConvertReadableStreamToReader(b.stream)
+		}
+	}
+	return b.reader.Read(p)
+}
+
+func (b *streamBody) Close() error {
+	if c, ok := b.reader.(io.Closer); ok {
+		c.Close()
+	}
+	if b.onClose != nil {
+		b.onClose()
+		b.onClose = nil
+	}
+	return nil
+}
+
+// unread returns the stream of the body if it has not been read.
+func (b *streamBody) unread() (js.Value, bool) {
+	if b.reader != nil {
+		return js.Value{}, false
+	}
+	return b.stream, true
+}
+
+// TeeStreamBody splits the body converted from ReadableStream into two bodies by ReadableStream.tee().
+//   - if the body is not converted from ReadableStream, or it has already been read, ok is false.
+//   - the body must not be used after it is teed.
+func TeeStreamBody(body io.ReadCloser) (a, b io.ReadCloser, ok bool) {
+	sb, isStreamBody := body.(*streamBody)
+	if !isStreamBody {
+		return nil, nil, false
+	}
+	stream, ok := sb.unread()
+	if !ok {
+		return nil, nil, false
+	}
+	branches := stream.Call("tee")
+	var onClose func()
+	if sb.onClose != nil {
+		// onClose of the body is called after both branches are closed.
+		var mu sync.Mutex
+		remaining := 2
+		onClose = func() {
+			mu.Lock()
+			remaining--
+			last := remaining == 0
+			mu.Unlock()
+			if last {
+				sb.onClose()
+			}
+		}
+	}
+	// branches of a byte stream are byte streams.
+	return &streamBody{stream: branches.Index(0), byteStream: sb.byteStream, onClose: onClose},
+		&streamBody{stream: branches.Index(1), byteStream: sb.byteStream, onClose: onClose}, true
+}
+
+// toJSBody converts the body to ReadableStream.
+//   - if the body is converted from ReadableStream and has not been read, the stream is returned as it is.
+func toJSBody(body io.ReadCloser) js.Value {
+	if sb, ok := body.(*streamBody); ok {
+		if stream, ok := sb.unread(); ok {
+			return stream
+		}
+	}
+	return jsutil.ConvertReaderToReadableStream(body)
+}
@@ -3,8 +3,8 @@ package jshttp
 import (
 	"net/http"
 	"strings"
-	"syscall/js"
 
+	"github.com/syumai/workers/internal/js"
 	"github.com/syumai/workers/internal/jsutil"
 )
 
@@ -1,13 +1,14 @@
 package jshttp
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
-	"syscall/js"
 
+	"github.com/syumai/workers/internal/js"
 	"github.com/syumai/workers/internal/jsutil"
 )
 
@@ -17,8 +18,7 @@ func ToBody(streamOrNull js.Value) io.ReadCloser {
 	if streamOrNull.IsNull() {
 		return nil
 	}
-	sr := streamOrNull.Call("getReader")
-	return io.NopCloser(jsutil.ConvertStreamReaderToReader(sr))
+	return &streamBody{stream: streamOrNull}
 }
 
 // ToRequest converts JavaScript sides Request to *http.Request.
@@ -45,15 +45,25 @@ func ToRequest(req js.Value) (*http.Request, error) {
 
 // ToJSRequest converts *http.Request to JavaScript sides Request.
 //   - Request: https://developer.mozilla.org/docs/Web/API/Request
-func ToJSRequest(req *http.Request) js.Value {
+//   - if the context of the request can be canceled, the request is aborted when the context is canceled until release is called.
+//     release must be called when the response, including its body, is no longer read.
+func ToJSRequest(req *http.Request) (jsReq js.Value, release func()) {
 	jsReqOptions := jsutil.NewObject()
 	jsReqOptions.Set("method", req.Method)
 	jsReqOptions.Set("headers", ToJSHeader(req.Header))
 	jsReqBody := js.Undefined()
-	if req.Body != nil {
-		jsReqBody = jsutil.ConvertReaderToReadableStream(req.Body)
+	if req.Body != nil && req.Body != http.NoBody {
+		jsReqBody = toJSBody(req.Body)
 	}
 	jsReqOptions.Set("body", jsReqBody)
-	jsReq := jsutil.RequestClass.New(req.URL.String(), jsReqOptions)
-	return jsReq
+	release = func() {}
+	if ctx := req.Context(); ctx.Done() != nil {
+		controller := jsutil.Global.Get("AbortController").New()
+		jsReqOptions.Set("signal", controller.Get("signal"))
+		stop := context.AfterFunc(ctx, func() {
+			controller.Call("abort", jsutil.ErrorClass.New(context.Cause(ctx).Error()))
+		})
+		release = func() { stop() }
+	}
+	return jsutil.RequestClass.New(req.URL.String(), jsReqOptions), release
 }
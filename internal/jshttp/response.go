@@ -4,45 +4,82 @@ import (
 	"io"
 	"net/http"
 	"strconv"
-	"strings"
-	"syscall/js"
 
+	"github.com/syumai/workers/internal/js"
 	"github.com/syumai/workers/internal/jsutil"
 )
 
 // ToResponse converts JavaScript sides Response to *http.Response.
 //   - Response: https://developer.mozilla.org/docs/Web/API/Response
 func ToResponse(res js.Value) (*http.Response, error) {
+	return toResponse(res, ToBody(res.Get("body")))
+}
+
+// ToFetchResponse converts Response returned by fetch of the runtime to *http.Response.
+//   - bodies of responses of fetch are byte streams, so they are read by ReadableStreamBYOBReader.
+//   - release returned by ToJSRequest is called when the body is closed, or immediately if the response has no body.
+func ToFetchResponse(res js.Value, release func()) (*http.Response, error) {
+	stream := res.Get("body")
+	if stream.IsNull() {
+		release()
+		return toResponse(res, nil)
+	}
+	return toResponse(res, &streamBody{stream: stream, byteStream: true, onClose: release})
+}
+
+func toResponse(res js.Value, body io.ReadCloser) (*http.Response, error) {
 	status := res.Get("status").Int()
-	promise := res.Call("text")
-	body, err := jsutil.AwaitPromise(promise)
+	header := ToHeader(res.Get("headers"))
+	contentLength, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
 	if err != nil {
-		return nil, err
+		contentLength = -1
+	}
+	if body == nil {
+		body = http.NoBody
+		contentLength = 0
 	}
-	header := ToHeader(res.Get("headers"))
-	contentLength, _ := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
 
 	return &http.Response{
 		Status:        strconv.Itoa(status) + " " + res.Get("statusText").String(),
 		StatusCode:    status,
 		Header:        header,
-		Body:          io.NopCloser(strings.NewReader(body.String())),
+		Body:          body,
 		ContentLength: contentLength,
 	}, nil
 }
 
 // ToJSResponse converts *http.Response to JavaScript sides Response.
 //   - Response: https://developer.mozilla.org/docs/Web/API/Response
-func ToJSResponse(w *ResponseWriterBuffer) (js.Value, error) {
-	<-w.ReadyCh // wait until ready
-	status := w.StatusCode
+func ToJSResponse(res *http.Response) js.Value {
+	status := res.StatusCode
 	if status == 0 {
 		status = http.StatusOK
 	}
 	respInit := jsutil.NewObject()
 	respInit.Set("status", status)
 	respInit.Set("statusText", http.StatusText(status))
-	respInit.Set("headers", ToJSHeader(w.Header()))
-	readableStream := jsutil.ConvertReaderToReadableStream(w.Reader)
-	return jsutil.ResponseClass.New(readableStream, respInit), nil
+	respInit.Set("headers", ToJSHeader(res.Header))
+	body := js.Null()
+	if res.Body != nil && res.Body != http.NoBody {
+		if isNullBodyStatus(status) {
+			// Response with null body status cannot have body, so it is discarded.
+			go func() {
+				_, _ = io.Copy(io.Discard, res.Body)
+				res.Body.Close()
+			}()
+		} else {
+			body = toJSBody(res.Body)
+		}
+	}
+	return jsutil.ResponseClass.New(body, respInit)
+}
+
+// isNullBodyStatus reports whether the status is a null body status.
+//   - https://fetch.spec.whatwg.org/#null-body-status
+func isNullBodyStatus(status int) bool {
+	switch status {
+	case http.StatusSwitchingProtocols, http.StatusNoContent, http.StatusResetContent, http.StatusNotModified:
+		return true
+	}
+	return false
 }
@@ -0,0 +1,158 @@
+//go:build js && wasm
+
+package jshttp
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jsutil"
+)
+
+// newJS evaluates the body of a JavaScript function, and calls it with args.
+func newJS(body string, args ...any) js.Value {
+	return jsutil.Global.Get("Function").New(body).Invoke(args...)
+}
+
+func TestToResponse(t *testing.T) {
+	tests := map[string]struct {
+		res               js.Value
+		wantStatus        int
+		wantBody          string
+		wantContentLength int64
+	}{
+		"body": {
+			res:               newJS(`return new Response("hello", {status: 201, headers: {"Content-Length": "5", "X-Test": "1"}});`),
+			wantStatus:        http.StatusCreated,
+			wantBody:          "hello",
+			wantContentLength: 5,
+		},
+		"unknown length": {
+			res:               newJS(`return new Response("hello");`),
+			wantStatus:        http.StatusOK,
+			wantBody:          "hello",
+			wantContentLength: -1,
+		},
+		"null body": {
+			res:        newJS(`return new Response(null, {status: 204});`),
+			wantStatus: http.StatusNoContent,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			res, err := ToResponse(tc.res)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			if res.StatusCode != tc.wantStatus {
+				t.Errorf("want status %d, got %d", tc.wantStatus, res.StatusCode)
+			}
+			if res.ContentLength != tc.wantContentLength {
+				t.Errorf("want Content-Length %d, got %d", tc.wantContentLength, res.ContentLength)
+			}
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tc.wantBody {
+				t.Errorf("want body %q, got %q", tc.wantBody, b)
+			}
+		})
+	}
+}
+
+func TestToResponse_Streaming(t *testing.T) {
+	// the stream is closed only after the first chunk is read by Go.
+	stream := newJS(`
+const encoder = new TextEncoder();
+let controller;
+const body = new ReadableStream({start(c) { controller = c; c.enqueue(encoder.encode("first")); }});
+return {
+  response: new Response(body),
+  finish() { controller.enqueue(encoder.encode(",last")); controller.close(); },
+};`)
+	res, err := ToResponse(stream.Get("response"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	first := make(chan string)
+	go func() {
+		buf := make([]byte, 16)
+		n, _ := res.Body.Read(buf)
+		first <- string(buf[:n])
+	}()
+	select {
+	case got := <-first:
+		if got != "first" {
+			t.Errorf("want the first chunk, got %q", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("want the first chunk read before the stream is closed")
+	}
+	stream.Call("finish")
+	rest, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rest) != ",last" {
+		t.Errorf("want the rest of the stream, got %q", rest)
+	}
+}
+
+func TestToJSRequest_NoBody(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "https://example.com/", http.NoBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsReq, release := ToJSRequest(req)
+	defer release()
+	if body := jsReq.Get("body"); !body.IsNull() {
+		t.Errorf("want no body for http.NoBody, got %v", body)
+	}
+}
+
+func TestToResponse_CloseCancelsStream(t *testing.T) {
+	// the stream never closes, and records the cancellation.
+	stream := newJS(`
+const state = {canceled: false};
+const body = new ReadableStream({
+  start(c) { c.enqueue(new TextEncoder().encode("first")); },
+  cancel() { state.canceled = true; },
+});
+state.response = new Response(body);
+return state;`)
+	res, err := ToResponse(stream.Get("response"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 16)
+	if _, err := res.Body.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+	pending := make(chan error)
+	go func() {
+		_, err := res.Body.Read(buf)
+		pending <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	res.Body.Close()
+	select {
+	case err := <-pending:
+		if err != io.EOF {
+			t.Errorf("want io.EOF for the pending read, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("want the pending read finished by Close")
+	}
+	if !stream.Get("canceled").Bool() {
+		t.Error("want the stream canceled")
+	}
+}
@@ -1,9 +1,13 @@
 package jshttp
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"sync"
+
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jsutil"
 )
 
 type ResponseWriterBuffer struct {
@@ -13,9 +17,30 @@ type ResponseWriterBuffer struct {
 	Writer      *io.PipeWriter
 	ReadyCh     chan struct{}
 	Once        sync.Once
+	// OnBodyClosed is called when the body of the response is closed by the runtime, after it is read or canceled.
+	OnBodyClosed func()
+	// WebSocket is the client side WebSocket of the WebSocketPair returned with the response of status 101.
+	WebSocket js.Value
+}
+
+type responseWriterKey struct{}
+
+// WithResponseWriter returns a context holding the ResponseWriterBuffer of the request,
+// so packages can reach it under middlewares wrapping http.ResponseWriter (e.g. to upgrade WebSockets).
+func WithResponseWriter(ctx context.Context, w *ResponseWriterBuffer) context.Context {
+	return context.WithValue(ctx, responseWriterKey{}, w)
 }
 
-var _ http.ResponseWriter = &ResponseWriterBuffer{}
+// ResponseWriterFromContext returns the ResponseWriterBuffer held by the context.
+func ResponseWriterFromContext(ctx context.Context) (*ResponseWriterBuffer, bool) {
+	w, ok := ctx.Value(responseWriterKey{}).(*ResponseWriterBuffer)
+	return w, ok
+}
+
+var (
+	_ http.ResponseWriter = &ResponseWriterBuffer{}
+	_ http.Flusher        = &ResponseWriterBuffer{}
+)
 
 // Ready indicates that ResponseWriterBuffer is ready to be converted to Response.
 func (w *ResponseWriterBuffer) Ready() {
@@ -24,11 +49,34 @@ func (w *ResponseWriterBuffer) Ready() {
 	})
 }
 
+// IsReady reports whether Ready has been called.
+func (w *ResponseWriterBuffer) IsReady() bool {
+	select {
+	case <-w.ReadyCh:
+		return true
+	default:
+		return false
+	}
+}
+
 func (w *ResponseWriterBuffer) Write(data []byte) (n int, err error) {
 	w.Ready()
 	return w.Writer.Write(data)
 }
 
+// Flush sends the status and headers of the response even if the body is not written yet.
+// Written bytes are always sent to the client without buffering.
+func (w *ResponseWriterBuffer) Flush() {
+	w.Ready()
+}
+
+// UpgradeWebSocket responds with status 101 and the client side WebSocket immediately.
+func (w *ResponseWriterBuffer) UpgradeWebSocket(client js.Value) {
+	w.WebSocket = client
+	w.StatusCode = http.StatusSwitchingProtocols
+	w.Ready()
+}
+
 func (w *ResponseWriterBuffer) Header() http.Header {
 	return w.HeaderValue
 }
@@ -36,3 +84,44 @@ func (w *ResponseWriterBuffer) Header() http.Header {
 func (w *ResponseWriterBuffer) WriteHeader(statusCode int) {
 	w.StatusCode = statusCode
 }
+
+// ToJSResponse converts buffered response to JavaScript sides Response.
+//   - Response: https://developer.mozilla.org/docs/Web/API/Response
+func (w *ResponseWriterBuffer) ToJSResponse() (js.Value, error) {
+	<-w.ReadyCh // wait until ready
+	if w.WebSocket.Truthy() {
+		respInit := jsutil.NewObject()
+		respInit.Set("status", w.StatusCode)
+		respInit.Set("headers", ToJSHeader(w.Header()))
+		respInit.Set("webSocket", w.WebSocket)
+		// the body is not used, so the writes of the handler are discarded.
+		go func() {
+			_, _ = io.Copy(io.Discard, w.Reader)
+			_ = w.Reader.Close()
+			if w.OnBodyClosed != nil {
+				w.OnBodyClosed()
+			}
+		}()
+		return jsutil.ResponseClass.New(js.Null(), respInit), nil
+	}
+	var body io.ReadCloser = w.Reader
+	if w.OnBodyClosed != nil {
+		body = &hookedBody{ReadCloser: w.Reader, onClose: sync.OnceFunc(w.OnBodyClosed)}
+	}
+	return ToJSResponse(&http.Response{
+		StatusCode: w.StatusCode,
+		Header:     w.Header(),
+		Body:       body,
+	}), nil
+}
+
+// hookedBody calls onClose when it is closed.
+type hookedBody struct {
+	io.ReadCloser
+	onClose func()
+}
+
+func (b *hookedBody) Close() error {
+	defer b.onClose()
+	return b.ReadCloser.Close()
+}
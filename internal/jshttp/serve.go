@@ -0,0 +1,55 @@
+package jshttp
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/runtimecontext"
+)
+
+// Serve serves the Request object by the handler in a new goroutine, and returns the Response object.
+//   - the context of the request is created by runtimecontext.NewEvent, and is canceled after the response body is written.
+//   - if withContext is not nil, it derives the context of the request given to the handler (e.g. to add values).
+//   - panics of the handler are recovered and reported to hooks registered by runtimecontext.OnError,
+//     and 500 is responded if the response is not sent yet.
+func Serve(h http.Handler, reqObj, runtimeCtxObj js.Value, withContext func(ctx context.Context, req *http.Request) context.Context) (js.Value, error) {
+	req, err := ToRequest(reqObj)
+	if err != nil {
+		return js.Value{}, err
+	}
+	ctx, settle := runtimecontext.NewEvent(reqObj, runtimeCtxObj)
+	if withContext != nil {
+		ctx = withContext(ctx, req)
+	}
+	reader, writer := io.Pipe()
+	w := &ResponseWriterBuffer{
+		HeaderValue: http.Header{},
+		StatusCode:  http.StatusOK,
+		Reader:      reader,
+		Writer:      writer,
+		ReadyCh:     make(chan struct{}),
+		// the instance must not be torn down until the runtime reads the response body.
+		OnBodyClosed: runtimecontext.Hold(),
+	}
+	req = req.WithContext(WithResponseWriter(ctx, w))
+	go func() {
+		// the context of the request is canceled after the response body is written.
+		defer settle()
+		defer w.Ready()
+		defer writer.Close()
+		defer func() {
+			recovered := recover()
+			if recovered == nil || recovered == http.ErrAbortHandler {
+				return
+			}
+			runtimecontext.ReportError(req.Context(), runtimecontext.NewPanicError(recovered), req)
+			if !w.IsReady() {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}()
+		h.ServeHTTP(w, req)
+	}()
+	return w.ToJSResponse()
+}
@@ -0,0 +1,77 @@
+//go:build js && wasm
+
+package jshttp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/runtimecontext"
+)
+
+func TestServe_Panic(t *testing.T) {
+	var reported error
+	runtimecontext.OnError(func(ctx context.Context, err error, req *http.Request) {
+		if req != nil && req.URL.Path == "/panic" {
+			reported = err
+		}
+	})
+	tests := map[string]struct {
+		handler    http.HandlerFunc
+		wantStatus int
+	}{
+		"before the header is written": {
+			handler: func(w http.ResponseWriter, req *http.Request) {
+				panic("boom")
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+		"header not sent yet": {
+			handler: func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+				panic("boom")
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+		"after the response is sent": {
+			handler: func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+				w.Write([]byte("partial"))
+				panic("boom")
+			},
+			wantStatus: http.StatusAccepted,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			reported = nil
+			reqObj := newJS(`return new Request("https://example.com/panic");`)
+			res, err := Serve(tc.handler, reqObj, js.Global().Get("Object").New(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := res.Get("status").Int(); got != tc.wantStatus {
+				t.Errorf("want status %d, got %d", tc.wantStatus, got)
+			}
+			// the panic is reported before the body is finished.
+			r, err := ToResponse(res)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := io.ReadAll(r.Body); err != nil {
+				t.Fatal(err)
+			}
+			r.Body.Close()
+			var panicErr *runtimecontext.PanicError
+			if !errors.As(reported, &panicErr) || panicErr.Value != "boom" {
+				t.Errorf("want the panic reported, got %v", reported)
+			}
+		})
+	}
+}
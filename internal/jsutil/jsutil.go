@@ -1,23 +1,27 @@
 package jsutil
 
 import (
+	"errors"
 	"fmt"
-	"syscall/js"
+	"strconv"
 	"time"
+
+	"github.com/syumai/workers/internal/js"
 )
 
 var (
-	Global              = js.Global()
-	ObjectClass         = Global.Get("Object")
-	PromiseClass        = Global.Get("Promise")
-	RequestClass        = Global.Get("Request")
-	ResponseClass       = Global.Get("Response")
-	HeadersClass        = Global.Get("Headers")
-	ArrayClass          = Global.Get("Array")
-	Uint8ArrayClass     = Global.Get("Uint8Array")
-	ErrorClass          = Global.Get("Error")
-	ReadableStreamClass = Global.Get("ReadableStream")
-	DateClass           = Global.Get("Date")
+	Global                 = js.Global()
+	ObjectClass            = Global.Get("Object")
+	PromiseClass           = Global.Get("Promise")
+	RequestClass           = Global.Get("Request")
+	ResponseClass          = Global.Get("Response")
+	HeadersClass           = Global.Get("Headers")
+	ArrayClass             = Global.Get("Array")
+	Uint8ArrayClass        = Global.Get("Uint8Array")
+	ErrorClass             = Global.Get("Error")
+	ReadableStreamClass    = Global.Get("ReadableStream")
+	DateClass              = Global.Get("Date")
+	CompressionStreamClass = Global.Get("CompressionStream")
 )
 
 func NewObject() js.Value {
@@ -28,6 +32,23 @@ func NewUint8Array(size int) js.Value {
 	return Uint8ArrayClass.New(size)
 }
 
+// NewUint8ArrayFromBytes creates Uint8Array and copies given bytes into it.
+func NewUint8ArrayFromBytes(b []byte) js.Value {
+	ua := NewUint8Array(len(b))
+	js.CopyBytesToJS(ua, b)
+	return ua
+}
+
+// BufferSourceToBytes copies bytes of JavaScript side's ArrayBuffer or Uint8Array into []byte.
+func BufferSourceToBytes(v js.Value) []byte {
+	if !v.InstanceOf(Uint8ArrayClass) {
+		v = Uint8ArrayClass.New(v)
+	}
+	b := make([]byte, v.Get("byteLength").Int())
+	js.CopyBytesToGo(b, v)
+	return b
+}
+
 func NewPromise(fn js.Func) js.Value {
 	return PromiseClass.New(fn)
 }
@@ -37,29 +58,90 @@ func ArrayFrom(v js.Value) js.Value {
 	return ArrayClass.Call("from", v)
 }
 
-func AwaitPromise(promiseVal js.Value) (js.Value, error) {
-	resultCh := make(chan js.Value)
-	errCh := make(chan error)
+// PromiseResult represents the result of a settled promise.
+type PromiseResult struct {
+	Value js.Value
+	Err   error
+}
+
+// AwaitPromiseChan returns a channel which receives the result of the promise when it settles.
+//   - the channel is buffered, so it is fine not to receive the result.
+func AwaitPromiseChan(promiseVal js.Value) <-chan PromiseResult {
+	// the channel is buffered so callbacks never block, since TinyGo doesn't allow blocking in callbacks.
+	ch := make(chan PromiseResult, 1)
 	var then, catch js.Func
 	then = js.FuncOf(func(_ js.Value, args []js.Value) any {
 		defer then.Release()
-		result := args[0]
-		resultCh <- result
+		defer catch.Release()
+		ch <- PromiseResult{Value: args[0]}
 		return js.Undefined()
 	})
 	catch = js.FuncOf(func(_ js.Value, args []js.Value) any {
+		defer then.Release()
 		defer catch.Release()
-		result := args[0]
-		errCh <- fmt.Errorf("failed on promise: %s", result.Call("toString").String())
+		ch <- PromiseResult{Err: fmt.Errorf("failed on promise: %s", args[0].Call("toString").String())}
 		return js.Undefined()
 	})
-	promiseVal.Call("then", then).Call("catch", catch)
-	select {
-	case result := <-resultCh:
-		return result, nil
-	case err := <-errCh:
-		return js.Value{}, err
+	promiseVal.Call("then", then, catch)
+	return ch
+}
+
+// AwaitPromise waits for the promise to settle, and returns its result.
+//   - This must be called from a goroutine, not from a callback of JavaScript.
+func AwaitPromise(promiseVal js.Value) (js.Value, error) {
+	r := <-AwaitPromiseChan(promiseVal)
+	return r.Value, r.Err
+}
+
+// AwaitAll waits for all promises to be fulfilled, and returns their values in the same order.
+//   - if one of promises is rejected, returns its error without waiting for the rest.
+//   - This must be called from a goroutine, not from a callback of JavaScript.
+func AwaitAll(promises []js.Value) ([]js.Value, error) {
+	type indexed struct {
+		i int
+		PromiseResult
+	}
+	ch := make(chan indexed, len(promises))
+	for i, p := range promises {
+		i, resultCh := i, AwaitPromiseChan(p)
+		go func() {
+			ch <- indexed{i, <-resultCh}
+		}()
+	}
+	values := make([]js.Value, len(promises))
+	for range promises {
+		r := <-ch
+		if r.Err != nil {
+			return nil, r.Err
+		}
+		values[r.i] = r.Value
 	}
+	return values, nil
+}
+
+// ErrNoPromises is returned by AwaitRace when no promises are given.
+var ErrNoPromises = errors.New("jsutil: no promises to race")
+
+// AwaitRace waits for the first promise to settle, and returns its index and result.
+//   - if promises are empty, returns ErrNoPromises instead of blocking forever as Promise.race does.
+//   - This must be called from a goroutine, not from a callback of JavaScript.
+func AwaitRace(promises []js.Value) (int, js.Value, error) {
+	if len(promises) == 0 {
+		return -1, js.Undefined(), ErrNoPromises
+	}
+	type indexed struct {
+		i int
+		PromiseResult
+	}
+	ch := make(chan indexed, len(promises))
+	for i, p := range promises {
+		i, resultCh := i, AwaitPromiseChan(p)
+		go func() {
+			ch <- indexed{i, <-resultCh}
+		}()
+	}
+	r := <-ch
+	return r.i, r.Value, r.Err
 }
 
 // StrRecordToMap converts JavaScript side's Record<string, string> into map[string]string.
@@ -84,6 +166,35 @@ func MaybeString(v js.Value) string {
 	return v.String()
 }
 
+// MaybeInt returns int value of given JavaScript value or returns 0 if the value is undefined or null.
+func MaybeInt(v js.Value) int {
+	if v.IsUndefined() || v.IsNull() {
+		return 0
+	}
+	return v.Int()
+}
+
+// MaybeFloat returns float64 value of given JavaScript value or returns 0 if the value is undefined or null.
+// String values such as latitude of IncomingRequestCfProperties are parsed as float64.
+func MaybeFloat(v js.Value) float64 {
+	switch v.Type() {
+	case js.TypeNumber:
+		return v.Float()
+	case js.TypeString:
+		f, _ := strconv.ParseFloat(v.String(), 64)
+		return f
+	}
+	return 0
+}
+
+// MaybeBool returns bool value of given JavaScript value or returns false if the value is undefined or null.
+func MaybeBool(v js.Value) bool {
+	if v.IsUndefined() || v.IsNull() {
+		return false
+	}
+	return v.Truthy()
+}
+
 // MaybeDate returns time.Time value of given JavaScript Date value or returns nil if the value is undefined.
 func MaybeDate(v js.Value) (time.Time, error) {
 	if v.IsUndefined() {
@@ -102,3 +213,10 @@ func DateToTime(v js.Value) (time.Time, error) {
 func TimeToDate(t time.Time) js.Value {
 	return DateClass.New(t.UnixMilli())
 }
+
+// PerformanceNow returns the value of performance.now() in milliseconds.
+// Date is frozen while the request is processed on Cloudflare Workers, so this is used to measure durations.
+//   - https://developers.cloudflare.com/workers/runtime-apis/performance/
+func PerformanceNow() float64 {
+	return Global.Get("performance").Call("now").Float()
+}
@@ -0,0 +1,87 @@
+//go:build js && wasm
+
+package jsutil
+
+import (
+	"testing"
+
+	"github.com/syumai/workers/internal/js"
+)
+
+// delayed returns a promise settled with v after ms milliseconds. If reject is true, the promise is rejected.
+func delayed(v any, ms int, reject bool) js.Value {
+	newPromise := Global.Get("Function").New("v", "ms", "reject", `
+return new Promise((resolve, rej) => setTimeout(() => reject ? rej(new Error(v)) : resolve(v), ms));
+`)
+	return newPromise.Invoke(v, ms, reject)
+}
+
+func TestAwaitAll(t *testing.T) {
+	tests := map[string]struct {
+		// promises is a function, since rejected promises must be awaited before they settle.
+		promises func() []js.Value
+		want     []int
+		wantErr  bool
+	}{
+		"fulfilled in reverse order": {
+			promises: func() []js.Value {
+				return []js.Value{delayed(1, 30, false), delayed(2, 20, false), delayed(3, 10, false)}
+			},
+			want: []int{1, 2, 3},
+		},
+		"empty": {
+			promises: func() []js.Value { return nil },
+			want:     []int{},
+		},
+		"rejected": {
+			promises: func() []js.Value {
+				return []js.Value{delayed(1, 1000, false), delayed("failed", 10, true)}
+			},
+			wantErr: true,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			got, err := AwaitAll(tc.promises())
+			if tc.wantErr {
+				if err == nil {
+					t.Error("want error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("want %d values, got %d", len(tc.want), len(got))
+			}
+			for i, v := range got {
+				if v.Int() != tc.want[i] {
+					t.Errorf("want %d at %d, got %d", tc.want[i], i, v.Int())
+				}
+			}
+		})
+	}
+}
+
+func TestAwaitRace(t *testing.T) {
+	i, v, err := AwaitRace([]js.Value{delayed(1, 100, false), delayed(2, 10, false)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i != 1 || v.Int() != 2 {
+		t.Errorf("want 2 at 1, got %d at %d", v.Int(), i)
+	}
+	if _, _, err := AwaitRace(nil); err != ErrNoPromises {
+		t.Errorf("want ErrNoPromises for no promises, got %v", err)
+	}
+}
+
+func TestAwaitPromiseChan(t *testing.T) {
+	r := <-AwaitPromiseChan(delayed("failed", 10, true))
+	if r.Err == nil {
+		t.Error("want error, got nil")
+	}
+}
@@ -1,59 +1,191 @@
 package jsutil
 
 import (
-	"bytes"
 	"fmt"
 	"io"
-	"syscall/js"
+
+	"github.com/syumai/workers/internal/js"
 )
 
 // streamReaderToReader implements io.Reader sourced from ReadableStreamDefaultReader.
 //   - ReadableStreamDefaultReader: https://developer.mozilla.org/en-US/docs/Web/API/ReadableStreamDefaultReader
 //   - This implementation is based on: https://deno.land/std@0.139.0/streams/conversion.ts#L76
+//   - Chunks of the stream are copied into buf in fixed-size pieces, so reading a large body doesn't allocate per read.
+//   - If p can hold the rest of the chunk, bytes are copied into p directly without buf.
 type streamReaderToReader struct {
-	buf          bytes.Buffer
 	streamReader js.Value
+	// chunk is the Uint8Array read from the stream last, and bytes from chunkOff to chunkLen are not copied yet.
+	chunk    js.Value
+	chunkOff int
+	chunkLen int
+	// buf holds bytes copied from chunk, and bytes from r to w are not read yet.
+	buf  []byte
+	r, w int
 }
 
 // Read reads bytes from ReadableStreamDefaultReader.
 func (sr *streamReaderToReader) Read(p []byte) (n int, err error) {
-	if sr.buf.Len() == 0 {
-		promise := sr.streamReader.Call("read")
-		resultCh := make(chan js.Value)
-		errCh := make(chan error)
-		var then, catch js.Func
-		then = js.FuncOf(func(_ js.Value, args []js.Value) any {
-			defer then.Release()
-			result := args[0]
-			if result.Get("done").Bool() {
-				errCh <- io.EOF
-				return js.Undefined()
-			}
-			resultCh <- result.Get("value")
-			return js.Undefined()
-		})
-		catch = js.FuncOf(func(_ js.Value, args []js.Value) any {
-			defer catch.Release()
-			result := args[0]
-			errCh <- fmt.Errorf("JavaScript error on read: %s", result.Call("toString").String())
-			return js.Undefined()
-		})
-		promise.Call("then", then).Call("catch", catch)
-		select {
-		case result := <-resultCh:
-			chunk := make([]byte, result.Get("byteLength").Int())
-			_ = js.CopyBytesToGo(chunk, result)
-			// The length written is always the same as the length of chunk, so it can be discarded.
-			//   - https://pkg.go.dev/bytes#Buffer.Write
-			_, err := sr.buf.Write(chunk)
-			if err != nil {
-				return 0, err
-			}
-		case err := <-errCh:
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if sr.r < sr.w {
+		n = copy(p, sr.buf[sr.r:sr.w])
+		sr.r += n
+		return n, nil
+	}
+	for sr.chunkOff >= sr.chunkLen {
+		if err := sr.readChunk(); err != nil {
 			return 0, err
 		}
 	}
-	return sr.buf.Read(p)
+	remaining := sr.chunkLen - sr.chunkOff
+	if len(p) >= remaining {
+		n = js.CopyBytesToGo(p[:remaining], sr.chunkRange(remaining))
+		sr.chunkOff += n
+		return n, nil
+	}
+	if sr.buf == nil {
+		sr.buf = make([]byte, defaultChunkSize)
+	}
+	size := remaining
+	if size > len(sr.buf) {
+		size = len(sr.buf)
+	}
+	sr.w = js.CopyBytesToGo(sr.buf[:size], sr.chunkRange(size))
+	sr.r = 0
+	sr.chunkOff += sr.w
+	n = copy(p, sr.buf[:sr.w])
+	sr.r += n
+	return n, nil
+}
+
+// chunkRange returns size bytes of the chunk from chunkOff.
+//   - subarray doesn't copy bytes of the chunk.
+func (sr *streamReaderToReader) chunkRange(size int) js.Value {
+	if sr.chunkOff == 0 && size == sr.chunkLen {
+		return sr.chunk
+	}
+	return sr.chunk.Call("subarray", sr.chunkOff, sr.chunkOff+size)
+}
+
+// Close cancels the stream, and a pending Read returns io.EOF.
+func (sr *streamReaderToReader) Close() error {
+	cancelStreamReader(sr.streamReader)
+	return nil
+}
+
+// readChunk reads next chunk from ReadableStreamDefaultReader.
+func (sr *streamReaderToReader) readChunk() error {
+	chunk, err := readStreamReader(sr.streamReader)
+	if err != nil {
+		sr.chunk = js.Undefined()
+		sr.chunkOff, sr.chunkLen = 0, 0
+		return err
+	}
+	sr.chunk = chunk
+	sr.chunkOff = 0
+	sr.chunkLen = chunk.Get("byteLength").Int()
+	return nil
+}
+
+// readStreamReader calls read method of ReadableStreamDefaultReader or ReadableStreamBYOBReader, and waits for the result.
+//   - returns the value of the result, or io.EOF if the stream is done.
+func readStreamReader(streamReader js.Value, args ...any) (js.Value, error) {
+	promise := streamReader.Call("read", args...)
+	// channels are buffered so callbacks never block, since TinyGo doesn't allow blocking in callbacks.
+	resultCh := make(chan js.Value, 1)
+	errCh := make(chan error, 1)
+	var then, catch js.Func
+	then = js.FuncOf(func(_ js.Value, args []js.Value) any {
+		defer then.Release()
+		result := args[0]
+		if result.Get("done").Bool() {
+			errCh <- io.EOF
+			return js.Undefined()
+		}
+		resultCh <- result.Get("value")
+		return js.Undefined()
+	})
+	catch = js.FuncOf(func(_ js.Value, args []js.Value) any {
+		defer catch.Release()
+		result := args[0]
+		errCh <- fmt.Errorf("JavaScript error on read: %s", result.Call("toString").String())
+		return js.Undefined()
+	})
+	promise.Call("then", then).Call("catch", catch)
+	select {
+	case value := <-resultCh:
+		return value, nil
+	case err := <-errCh:
+		return js.Undefined(), err
+	}
+}
+
+// ignoreRejection is a callback of catch to ignore rejected promises.
+var ignoreRejection = js.FuncOf(func(js.Value, []js.Value) any {
+	return js.Undefined()
+})
+
+// cancelStreamReader cancels the stream of ReadableStreamDefaultReader or ReadableStreamBYOBReader.
+//   - the promise of cancel is rejected if the stream has errored, and the rejection is ignored.
+func cancelStreamReader(streamReader js.Value) {
+	streamReader.Call("cancel").Call("catch", ignoreRejection)
+}
+
+// byobReaderToReader implements io.Reader sourced from ReadableStreamBYOBReader.
+//   - ReadableStreamBYOBReader: https://developer.mozilla.org/en-US/docs/Web/API/ReadableStreamBYOBReader
+//   - Each Read reads at most len(p) bytes into a view of the caller's size, so reads map 1:1 to reads of the stream.
+//   - The ArrayBuffer transferred back by read is reused for the next read.
+type byobReaderToReader struct {
+	streamReader js.Value
+	// buffer is the ArrayBuffer returned by the last read. It is undefined before the first read.
+	buffer js.Value
+}
+
+// Read reads bytes from ReadableStreamBYOBReader.
+func (br *byobReaderToReader) Read(p []byte) (n int, err error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	var view js.Value
+	if !br.buffer.IsUndefined() && br.buffer.Get("byteLength").Int() >= len(p) {
+		view = Uint8ArrayClass.New(br.buffer, 0, len(p))
+	} else {
+		view = NewUint8Array(len(p))
+	}
+	result, err := readStreamReader(br.streamReader, view)
+	if err != nil {
+		return 0, err
+	}
+	br.buffer = result.Get("buffer")
+	return js.CopyBytesToGo(p, result), nil
+}
+
+// Close cancels the stream, and a pending Read returns io.EOF.
+func (br *byobReaderToReader) Close() error {
+	cancelStreamReader(br.streamReader)
+	return nil
+}
+
+// byobReaderOptions is the options of getReader to get ReadableStreamBYOBReader.
+var byobReaderOptions = map[string]any{"mode": "byob"}
+
+// ConvertReadableStreamToReader converts ReadableStream to io.Reader by ReadableStreamDefaultReader.
+//   - the reader implements io.Closer, which cancels the stream.
+func ConvertReadableStreamToReader(stream js.Value) io.Reader {
+	return ConvertStreamReaderToReader(stream.Call("getReader"))
+}
+
+// ConvertByteStreamToReader converts ReadableStream of bytes to io.Reader by ReadableStreamBYOBReader,
+// reading bytes into caller-sized buffers.
+//   - the stream must be a byte stream (e.g. bodies of fetch, R2 objects and KV values), otherwise getReader throws TypeError.
+//     Streams which may not be byte streams must be converted by ConvertReadableStreamToReader.
+//   - the reader implements io.Closer, which cancels the stream.
+func ConvertByteStreamToReader(stream js.Value) io.Reader {
+	return &byobReaderToReader{
+		streamReader: stream.Call("getReader", byobReaderOptions),
+		buffer:       js.Undefined(),
+	}
 }
 
 // ConvertStreamReaderToReader converts ReadableStreamDefaultReader to io.Reader.
@@ -66,6 +198,8 @@ func ConvertStreamReaderToReader(sr js.Value) io.Reader {
 // readerToReadableStream implements ReadableStream sourced from io.ReadCloser.
 //   - ReadableStream: https://developer.mozilla.org/docs/Web/API/ReadableStream
 //   - This implementation is based on: https://deno.land/std@0.139.0/streams/conversion.ts#L230
+//   - The stream is a byte stream whose highWaterMark is 0, so the reader is read only when the consumer requests bytes.
+//     Bytes are copied into the view of the BYOB request directly, and no chunk is buffered in the stream.
 type readerToReadableStream struct {
 	reader   io.ReadCloser
 	chunkBuf []byte
@@ -73,13 +207,43 @@ type readerToReadableStream struct {
 
 // Pull implements ReadableStream's pull method.
 //   - https://developer.mozilla.org/en-US/docs/Web/API/ReadableStream/ReadableStream#pull
+//   - https://developer.mozilla.org/en-US/docs/Web/API/ReadableByteStreamController/byobRequest
 func (rs *readerToReadableStream) Pull(controller js.Value) error {
-	n, err := rs.reader.Read(rs.chunkBuf)
+	byobRequest := controller.Get("byobRequest")
+	hasView := !byobRequest.IsNull() && !byobRequest.IsUndefined()
+	buf := rs.chunkBuf
+	var view js.Value
+	if hasView {
+		view = byobRequest.Get("view")
+		if size := view.Get("byteLength").Int(); size < len(buf) {
+			buf = buf[:size]
+		}
+	}
+	var n int
+	var err error
+	// a byte stream doesn't accept empty chunks, so reads until some bytes or an error are returned.
+	for n == 0 && err == nil {
+		n, err = rs.reader.Read(buf)
+	}
+	if n > 0 {
+		if hasView {
+			_ = js.CopyBytesToJS(view, buf[:n])
+			byobRequest.Call("respond", n)
+		} else {
+			ua := NewUint8Array(n)
+			_ = js.CopyBytesToJS(ua, buf[:n])
+			controller.Call("enqueue", ua)
+		}
+	}
 	if err == io.EOF {
 		if err := rs.reader.Close(); err != nil {
 			return err
 		}
 		controller.Call("close")
+		if hasView && n == 0 {
+			// the pending BYOB request must be responded with 0 bytes after the stream is closed.
+			byobRequest.Call("respond", 0)
+		}
 		return nil
 	}
 	if err != nil {
@@ -90,9 +254,6 @@ func (rs *readerToReadableStream) Pull(controller js.Value) error {
 		}
 		return err
 	}
-	ua := NewUint8Array(n)
-	_ = js.CopyBytesToJS(ua, rs.chunkBuf[:n])
-	controller.Call("enqueue", ua)
 	return nil
 }
 
@@ -112,6 +273,9 @@ func ConvertReaderToReadableStream(reader io.ReadCloser) js.Value {
 		chunkBuf: make([]byte, defaultChunkSize),
 	}
 	rsInit := NewObject()
+	rsInit.Set("type", "bytes")
+	// chunks are allocated by the stream for default readers, so byobRequest is always available in pull.
+	rsInit.Set("autoAllocateChunkSize", defaultChunkSize)
 	rsInit.Set("pull", js.FuncOf(func(_ js.Value, args []js.Value) any {
 		var cb js.Func
 		cb = js.FuncOf(func(this js.Value, pArgs []js.Value) any {
@@ -119,12 +283,16 @@ func ConvertReaderToReadableStream(reader io.ReadCloser) js.Value {
 			resolve := pArgs[0]
 			reject := pArgs[1]
 			controller := args[0]
-			err := stream.Pull(controller)
-			if err != nil {
-				reject.Invoke(ErrorClass.New(err.Error()))
-				return js.Undefined()
-			}
-			resolve.Invoke()
+			// Pull blocks until the reader returns bytes, so it must run in a new goroutine
+			// not to block the event loop (TinyGo panics on blocking in callbacks).
+			go func() {
+				err := stream.Pull(controller)
+				if err != nil {
+					reject.Invoke(ErrorClass.New(err.Error()))
+					return
+				}
+				resolve.Invoke()
+			}()
 			return js.Undefined()
 		})
 		return NewPromise(cb)
@@ -136,5 +304,7 @@ func ConvertReaderToReadableStream(reader io.ReadCloser) js.Value {
 		}
 		return js.Undefined()
 	}))
-	return ReadableStreamClass.New(rsInit)
+	strategy := NewObject()
+	strategy.Set("highWaterMark", 0)
+	return ReadableStreamClass.New(rsInit, strategy)
 }
@@ -0,0 +1,242 @@
+//go:build js && wasm
+
+package jsutil
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/syumai/workers/internal/js"
+)
+
+// newChunkedStream returns ReadableStream which emits Uint8Array src in chunks of chunkSize.
+//   - if byteStream is true, the stream is a byte stream which supports ReadableStreamBYOBReader.
+func newChunkedStream(src js.Value, chunkSize int, byteStream bool) js.Value {
+	newStream := Global.Get("Function").New("src", "chunkSize", "byteStream", `
+let sent = 0;
+return new ReadableStream({
+  type: byteStream ? "bytes" : undefined,
+  pull(controller) {
+    if (sent >= src.byteLength) {
+      controller.close();
+      controller.byobRequest?.respond(0);
+      return;
+    }
+    const n = Math.min(chunkSize, src.byteLength - sent);
+    // chunks enqueued to a byte stream are transferred, so they are copied.
+    controller.enqueue(byteStream ? src.slice(sent, sent + n) : src.subarray(sent, sent + n));
+    sent += n;
+  },
+});
+`)
+	return newStream.Invoke(src, chunkSize, byteStream)
+}
+
+// testBytes returns n bytes whose byte i is i % 251.
+func testBytes(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i % 251)
+	}
+	return b
+}
+
+func TestConvertStreamReaderToReader(t *testing.T) {
+	tests := map[string]struct {
+		total     int
+		chunkSize int
+		readSize  int
+	}{
+		"read size equals to chunk size": {
+			total:     100_000,
+			chunkSize: 1000,
+			readSize:  1000,
+		},
+		"read size is smaller than chunk size": {
+			total:     100_000,
+			chunkSize: 65536,
+			readSize:  3000,
+		},
+		"read size is larger than chunk size": {
+			total:     100_000,
+			chunkSize: 1000,
+			readSize:  4096,
+		},
+		"chunk size is larger than buffer": {
+			total:     100_000,
+			chunkSize: defaultChunkSize*2 + 1,
+			readSize:  defaultChunkSize + 7,
+		},
+		"empty stream": {
+			total:     0,
+			chunkSize: 1000,
+			readSize:  1000,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			want := testBytes(tc.total)
+			r := ConvertStreamReaderToReader(newChunkedStream(NewUint8ArrayFromBytes(want), tc.chunkSize, false).Call("getReader"))
+			assertReadAll(t, r, tc.readSize, want)
+		})
+	}
+}
+
+func TestConvertReadableStreamToReader(t *testing.T) {
+	tests := map[string]struct {
+		byteStream bool
+		readSize   int
+	}{
+		"byte stream read by smaller buffer than chunks": {
+			byteStream: true,
+			readSize:   3000,
+		},
+		"byte stream read by larger buffer than chunks": {
+			byteStream: true,
+			readSize:   5000,
+		},
+		"default stream": {
+			byteStream: false,
+			readSize:   3000,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			want := testBytes(100_000)
+			stream := newChunkedStream(NewUint8ArrayFromBytes(want), 4096, tc.byteStream)
+			var r io.Reader
+			if tc.byteStream {
+				r = ConvertByteStreamToReader(stream)
+			} else {
+				r = ConvertReadableStreamToReader(stream)
+			}
+			assertReadAll(t, r, tc.readSize, want)
+		})
+	}
+}
+
+// countingReader counts calls of Read, and returns the rest of bytes with io.EOF at the end.
+type countingReader struct {
+	b     []byte
+	reads int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	r.reads++
+	n := copy(p, r.b)
+	r.b = r.b[n:]
+	if len(r.b) == 0 {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func TestConvertReaderToReadableStream(t *testing.T) {
+	tests := map[string]struct {
+		size     int
+		readSize int
+		byob     bool
+	}{
+		"read by default reader": {
+			size:     100_000,
+			readSize: 3000,
+			byob:     false,
+		},
+		"read by BYOB reader": {
+			size:     100_000,
+			readSize: 3000,
+			byob:     true,
+		},
+		"empty body": {
+			size:     0,
+			readSize: 3000,
+			byob:     true,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			want := testBytes(tc.size)
+			src := &countingReader{b: want}
+			stream := ConvertReaderToReadableStream(io.NopCloser(src))
+			// the reader must not be read until the consumer reads the stream.
+			time.Sleep(10 * time.Millisecond)
+			if src.reads != 0 {
+				t.Fatalf("reader was read %d times before the stream was read", src.reads)
+			}
+			var r io.Reader
+			if tc.byob {
+				r = ConvertByteStreamToReader(stream)
+			} else {
+				r = ConvertStreamReaderToReader(stream.Call("getReader"))
+			}
+			assertReadAll(t, r, tc.readSize, want)
+		})
+	}
+}
+
+// assertReadAll reads r by readSize until EOF, and compares the bytes with want.
+func assertReadAll(t *testing.T, r io.Reader, readSize int, want []byte) {
+	t.Helper()
+	var got bytes.Buffer
+	p := make([]byte, readSize)
+	for {
+		n, err := r.Read(p)
+		got.Write(p[:n])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if !bytes.Equal(want, got.Bytes()) {
+		t.Fatalf("read %d bytes which don't match the %d bytes of the stream", got.Len(), len(want))
+	}
+}
+
+func BenchmarkConvertStreamReaderToReader(b *testing.B) {
+	const total = 50 << 20
+	const chunkSize = 64 << 10
+	src := NewUint8ArrayFromBytes(testBytes(total))
+	b.SetBytes(total)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r := ConvertStreamReaderToReader(newChunkedStream(src, chunkSize, false).Call("getReader"))
+		// io.Discard reads by 8 KiB, which is smaller than chunks.
+		if _, err := io.Copy(io.Discard, r); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkConvertByteStreamToReader(b *testing.B) {
+	const total = 50 << 20
+	const chunkSize = 64 << 10
+	src := NewUint8ArrayFromBytes(testBytes(total))
+	p := make([]byte, chunkSize)
+	b.SetBytes(total)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r := ConvertByteStreamToReader(newChunkedStream(src, chunkSize, true))
+		// reads are the same size as chunks, so each Read maps to a read of the stream.
+		for {
+			_, err := r.Read(p)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -3,23 +3,53 @@ package runtimecontext
 import (
 	"context"
 	"errors"
-	"syscall/js"
+
+	"github.com/syumai/workers/internal/js"
 )
 
 type runtimeCtxKey struct{}
 
-func New(ctx context.Context, runtimeCtxObj js.Value) context.Context {
-	return context.WithValue(ctx, runtimeCtxKey{}, runtimeCtxObj)
+type runtimeCtxValue struct {
+	reqObj        js.Value
+	runtimeCtxObj js.Value
+}
+
+// New returns a context holding the incoming Request object and the runtime context object.
+func New(ctx context.Context, reqObj, runtimeCtxObj js.Value) context.Context {
+	return context.WithValue(ctx, runtimeCtxKey{}, &runtimeCtxValue{
+		reqObj:        reqObj,
+		runtimeCtxObj: runtimeCtxObj,
+	})
 }
 
 var ErrRuntimeContextNotFound = errors.New("runtime context was not found")
 
-// MustExtract extracts runtime context object from context.
-// This function panics when runtime context object was not found.
-func MustExtract(ctx context.Context) js.Value {
-	v, ok := ctx.Value(runtimeCtxKey{}).(js.Value)
+func mustExtractValue(ctx context.Context) *runtimeCtxValue {
+	v, ok := ctx.Value(runtimeCtxKey{}).(*runtimeCtxValue)
 	if !ok {
 		panic(ErrRuntimeContextNotFound)
 	}
 	return v
 }
+
+// Extract extracts runtime context object from context.
+// If runtime context object was not found, the returned bool is false.
+func Extract(ctx context.Context) (js.Value, bool) {
+	v, ok := ctx.Value(runtimeCtxKey{}).(*runtimeCtxValue)
+	if !ok {
+		return js.Value{}, false
+	}
+	return v.runtimeCtxObj, true
+}
+
+// MustExtract extracts runtime context object from context.
+// This function panics when runtime context object was not found.
+func MustExtract(ctx context.Context) js.Value {
+	return mustExtractValue(ctx).runtimeCtxObj
+}
+
+// MustExtractIncomingRequest extracts the incoming Request object from context.
+// This function panics when runtime context object was not found.
+func MustExtractIncomingRequest(ctx context.Context) js.Value {
+	return mustExtractValue(ctx).reqObj
+}
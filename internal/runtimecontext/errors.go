@@ -0,0 +1,63 @@
+package runtimecontext
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+)
+
+// PanicError represents a panic recovered while handling an event.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+	// Stack is the stack trace of the goroutine which panicked.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the value passed to panic if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// NewPanicError returns PanicError of the recovered value with the stack trace of the current goroutine.
+func NewPanicError(recovered any) *PanicError {
+	return &PanicError{Value: recovered, Stack: debug.Stack()}
+}
+
+// ErrorHook is a function called with errors occurred while handling events.
+//   - req is nil for events other than requests.
+type ErrorHook func(ctx context.Context, err error, req *http.Request)
+
+var (
+	errorHooksMu sync.RWMutex
+	errorHooks   []ErrorHook
+)
+
+// OnError registers the hook called for errors of handlers and recovered panics.
+//   - packages can report errors without importing the workers package (e.g. handlers of events and cloudflare.WaitUntil).
+func OnError(hook ErrorHook) {
+	errorHooksMu.Lock()
+	defer errorHooksMu.Unlock()
+	errorHooks = append(errorHooks, hook)
+}
+
+// ReportError calls hooks registered by OnError.
+//   - panics in hooks are ignored to avoid failing the event while reporting.
+func ReportError(ctx context.Context, err error, req *http.Request) {
+	errorHooksMu.RLock()
+	hooks := errorHooks
+	errorHooksMu.RUnlock()
+	for _, hook := range hooks {
+		func() {
+			defer func() { _ = recover() }()
+			hook(ctx, err, req)
+		}()
+	}
+}
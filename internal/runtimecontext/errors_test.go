@@ -0,0 +1,70 @@
+package runtimecontext
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReportError(t *testing.T) {
+	defer func() { errorHooks = nil }()
+	var calls []string
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	errFailed := errors.New("failed")
+	OnError(func(ctx context.Context, err error, r *http.Request) {
+		calls = append(calls, "first")
+		if err != errFailed || r != req {
+			t.Errorf("want the error and the request, got %v, %v", err, r)
+		}
+	})
+	OnError(func(ctx context.Context, err error, r *http.Request) {
+		calls = append(calls, "panicking")
+		panic("hook failed")
+	})
+	OnError(func(ctx context.Context, err error, r *http.Request) {
+		calls = append(calls, "last")
+	})
+	ReportError(context.Background(), errFailed, req)
+	if want := "first panicking last"; strings.Join(calls, " ") != want {
+		t.Errorf("want hooks called in order of registration, got %v", calls)
+	}
+}
+
+func TestNewPanicError(t *testing.T) {
+	errFailed := errors.New("failed")
+	tests := map[string]struct {
+		recovered any
+		wantMsg   string
+		wantErr   error
+	}{
+		"error": {
+			recovered: errFailed,
+			wantMsg:   "panic: failed",
+			wantErr:   errFailed,
+		},
+		"value": {
+			recovered: "boom",
+			wantMsg:   "panic: boom",
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			err := NewPanicError(tc.recovered)
+			if err.Value != tc.recovered || len(err.Stack) == 0 {
+				t.Errorf("want the value and the stack, got %v, %d bytes", err.Value, len(err.Stack))
+			}
+			if got := err.Error(); got != tc.wantMsg {
+				t.Errorf("want message %q, got %q", tc.wantMsg, got)
+			}
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Errorf("want the recovered error unwrapped, got %v", err)
+			}
+		})
+	}
+}
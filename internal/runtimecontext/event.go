@@ -0,0 +1,62 @@
+package runtimecontext
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/syumai/workers/internal/js"
+)
+
+var (
+	// ErrEventSettled is the cause of cancellation of contexts of events which settled.
+	ErrEventSettled = errors.New("the event has settled")
+	// ErrRequestAborted is the cause of cancellation of contexts of requests aborted by clients.
+	ErrRequestAborted = errors.New("the request was aborted by the client")
+)
+
+// events counts events which have not settled yet.
+var events sync.WaitGroup
+
+// Wait blocks until all events settle.
+func Wait() {
+	events.Wait()
+}
+
+// Hold delays Wait until the returned function is called, e.g. while the runtime reads the body of a response.
+func Hold() (release func()) {
+	events.Add(1)
+	return sync.OnceFunc(events.Done)
+}
+
+// NewEvent returns a context for the event, holding the incoming Request object and the runtime context object.
+//   - the context is canceled with ErrEventSettled by the returned function, which must be called when the event settles.
+//     Wait blocks until it is called.
+//   - the context counts subrequests, which can be obtained by SubrequestsFrom.
+//   - if the Request object has an AbortSignal, the context is canceled with ErrRequestAborted when the signal is aborted.
+func NewEvent(reqObj, runtimeCtxObj js.Value) (context.Context, func()) {
+	events.Add(1)
+	ctx, cancel := context.WithCancelCause(context.Background())
+	ctx = withSubrequests(New(ctx, reqObj, runtimeCtxObj))
+	settle := sync.OnceFunc(func() {
+		cancel(ErrEventSettled)
+		events.Done()
+	})
+	if reqObj.Type() != js.TypeObject {
+		return ctx, settle
+	}
+	signal := reqObj.Get("signal")
+	if signal.Type() != js.TypeObject {
+		return ctx, settle
+	}
+	onAbort := js.FuncOf(func(js.Value, []js.Value) any {
+		cancel(ErrRequestAborted)
+		return js.Undefined()
+	})
+	signal.Call("addEventListener", "abort", onAbort)
+	return ctx, func() {
+		settle()
+		signal.Call("removeEventListener", "abort", onAbort)
+		onAbort.Release()
+	}
+}
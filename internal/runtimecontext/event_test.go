@@ -0,0 +1,47 @@
+//go:build js && wasm
+
+package runtimecontext
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/syumai/workers/internal/js"
+)
+
+func TestNewEvent(t *testing.T) {
+	tests := map[string]struct {
+		abort     bool
+		wantCause error
+	}{
+		"settled": {
+			wantCause: ErrEventSettled,
+		},
+		"aborted": {
+			abort:     true,
+			wantCause: ErrRequestAborted,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			controller := js.Global().Get("AbortController").New()
+			reqObj := js.Global().Get("Object").New()
+			reqObj.Set("signal", controller.Get("signal"))
+			ctx, settle := NewEvent(reqObj, js.Null())
+			if ctx.Err() != nil {
+				t.Fatalf("want active context, got %v", ctx.Err())
+			}
+			if tc.abort {
+				controller.Call("abort")
+			}
+			settle()
+			<-ctx.Done()
+			if cause := context.Cause(ctx); !errors.Is(cause, tc.wantCause) {
+				t.Errorf("want %v, got %v", tc.wantCause, cause)
+			}
+		})
+	}
+}
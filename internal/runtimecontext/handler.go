@@ -0,0 +1,108 @@
+package runtimecontext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jsutil"
+)
+
+// RegisterHandler sets the global function of the name, called by the JavaScript entry point as `name(event, { env, ctx })`.
+//   - the function returns a Promise which is resolved when fn returns nil, or rejected with the error.
+//   - fn is called in a new goroutine with the context of the event, which is canceled after fn returns.
+//   - if fn panics, the panic is recovered as *PanicError, so it doesn't abort the instance.
+//   - errors of fn, including recovered panics, are reported to hooks registered by OnError.
+func RegisterHandler(name string, fn func(ctx context.Context, eventObj js.Value) error) {
+	handlersMu.Lock()
+	handlers[name] = fn
+	handlersMu.Unlock()
+	handlerCallback := js.FuncOf(func(_ js.Value, args []js.Value) any {
+		eventObj := args[0]
+		runtimeCtxObj := js.Null()
+		if len(args) > 1 {
+			runtimeCtxObj = args[1]
+		}
+		var cb js.Func
+		cb = js.FuncOf(func(_ js.Value, pArgs []js.Value) any {
+			defer cb.Release()
+			resolve := pArgs[0]
+			reject := pArgs[1]
+			go func() {
+				ctx, settle := NewEvent(js.Undefined(), runtimeCtxObj)
+				defer settle()
+				if err := callHandler(ctx, fn, eventObj); err != nil {
+					reject.Invoke(jsutil.ErrorClass.New(err.Error()))
+					return
+				}
+				resolve.Invoke(js.Undefined())
+			}()
+			return js.Undefined()
+		})
+		return jsutil.NewPromise(cb)
+	})
+	jsutil.Global.Set(name, handlerCallback)
+}
+
+// callHandler calls fn, and reports its error to hooks registered by OnError.
+//   - if fn panics, returns *PanicError instead.
+func callHandler(ctx context.Context, fn func(ctx context.Context, eventObj js.Value) error, eventObj js.Value) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = NewPanicError(recovered)
+		}
+		if err != nil {
+			ReportError(ctx, err, nil)
+		}
+	}()
+	return fn(ctx, eventObj)
+}
+
+var (
+	handlersMu sync.RWMutex
+	// handlers holds functions registered by RegisterHandler, so Dispatch can call them without the JavaScript runtime.
+	handlers = map[string]func(ctx context.Context, eventObj js.Value) error{}
+	// httpHandler is registered by RegisterHTTPHandler.
+	httpHandler func(ctx context.Context, w http.ResponseWriter, req *http.Request)
+)
+
+// RegisterHTTPHandler registers the function serving requests dispatched by DispatchRequest.
+func RegisterHTTPHandler(fn func(ctx context.Context, w http.ResponseWriter, req *http.Request)) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+	httpHandler = fn
+}
+
+// Dispatch calls the function registered by RegisterHandler with the event, and waits for it.
+//   - unlike the global function, it doesn't need Promise of the JavaScript runtime, so events can be dispatched under the mock runtime.
+//   - as well as the global function, panics are recovered and errors are reported to hooks registered by OnError.
+//   - if no function is registered for the name, returns error.
+func Dispatch(name string, eventObj, runtimeCtxObj js.Value) error {
+	handlersMu.RLock()
+	fn, ok := handlers[name]
+	handlersMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s is not registered", name)
+	}
+	ctx, settle := NewEvent(js.Undefined(), runtimeCtxObj)
+	defer settle()
+	return callHandler(ctx, fn, eventObj)
+}
+
+// DispatchRequest serves the request by the function registered by RegisterHTTPHandler, and waits for it.
+//   - if no function is registered, returns error.
+func DispatchRequest(w http.ResponseWriter, req *http.Request, runtimeCtxObj js.Value) error {
+	handlersMu.RLock()
+	fn := httpHandler
+	handlersMu.RUnlock()
+	if fn == nil {
+		return errors.New("HTTP handler is not registered")
+	}
+	ctx, settle := NewEvent(js.Undefined(), runtimeCtxObj)
+	defer settle()
+	fn(ctx, w, req.WithContext(ctx))
+	return nil
+}
@@ -0,0 +1,55 @@
+package runtimecontext
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/syumai/workers/internal/js"
+)
+
+func TestDispatch(t *testing.T) {
+	defer func() { errorHooks = nil }()
+	var reported []error
+	OnError(func(ctx context.Context, err error, req *http.Request) {
+		reported = append(reported, err)
+	})
+	errFailed := errors.New("failed")
+	tests := map[string]struct {
+		fn        func(ctx context.Context, eventObj js.Value) error
+		wantErr   error
+		wantPanic bool
+	}{
+		"success": {
+			fn: func(ctx context.Context, eventObj js.Value) error { return nil },
+		},
+		"error": {
+			fn:      func(ctx context.Context, eventObj js.Value) error { return errFailed },
+			wantErr: errFailed,
+		},
+		"panic": {
+			fn:        func(ctx context.Context, eventObj js.Value) error { panic(errFailed) },
+			wantErr:   errFailed,
+			wantPanic: true,
+		},
+	}
+	for name, tc := range tests {
+		reported = nil
+		RegisterHandler("handleTest", tc.fn)
+		err := Dispatch("handleTest", js.Undefined(), js.Null())
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("%s: want %v, got %v", name, tc.wantErr, err)
+		}
+		var panicErr *PanicError
+		if got := errors.As(err, &panicErr); got != tc.wantPanic {
+			t.Errorf("%s: want PanicError %v, got %v", name, tc.wantPanic, err)
+		}
+		if tc.wantErr == nil && len(reported) != 0 {
+			t.Errorf("%s: want no errors reported, got %v", name, reported)
+		}
+		if tc.wantErr != nil && (len(reported) != 1 || reported[0] != err) {
+			t.Errorf("%s: want the error reported to OnError, got %v", name, reported)
+		}
+	}
+}
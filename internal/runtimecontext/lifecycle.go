@@ -0,0 +1,51 @@
+package runtimecontext
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	lifecycleMu   sync.Mutex
+	readyHooks    []func() error
+	teardownHooks []func()
+)
+
+// OnReady registers the hook called before the instance is signaled ready.
+//   - packages can register hooks without importing the workers package (e.g. cloudflare.MustBindings).
+func OnReady(fn func() error) {
+	lifecycleMu.Lock()
+	defer lifecycleMu.Unlock()
+	readyHooks = append(readyHooks, fn)
+}
+
+// OnTeardown registers the hook called when the instance is torn down.
+func OnTeardown(fn func()) {
+	lifecycleMu.Lock()
+	defer lifecycleMu.Unlock()
+	teardownHooks = append(teardownHooks, fn)
+}
+
+// RunReadyHooks calls hooks registered by OnReady in the order of registration, and joins their errors.
+func RunReadyHooks() error {
+	lifecycleMu.Lock()
+	hooks := readyHooks
+	lifecycleMu.Unlock()
+	var errs []error
+	for _, fn := range hooks {
+		if err := fn(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RunTeardownHooks calls hooks registered by OnTeardown in the reverse order of registration.
+func RunTeardownHooks() {
+	lifecycleMu.Lock()
+	hooks := teardownHooks
+	lifecycleMu.Unlock()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+}
@@ -0,0 +1,35 @@
+package runtimecontext
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestLifecycleHooks(t *testing.T) {
+	defer func() {
+		readyHooks = nil
+		teardownHooks = nil
+	}()
+	var calls []string
+	errFailed := errors.New("failed")
+	OnReady(func() error {
+		calls = append(calls, "ready1")
+		return errFailed
+	})
+	OnReady(func() error {
+		calls = append(calls, "ready2")
+		return nil
+	})
+	OnTeardown(func() { calls = append(calls, "teardown1") })
+	OnTeardown(func() { calls = append(calls, "teardown2") })
+
+	if err := RunReadyHooks(); !errors.Is(err, errFailed) {
+		t.Errorf("want %v, got %v", errFailed, err)
+	}
+	RunTeardownHooks()
+	want := []string{"ready1", "ready2", "teardown2", "teardown1"}
+	if !reflect.DeepEqual(want, calls) {
+		t.Errorf("want %v, got %v", want, calls)
+	}
+}
@@ -0,0 +1,67 @@
+package runtimecontext
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Subrequests counts subrequests sent in an event.
+type Subrequests struct {
+	// ctx is the context of the event passed to the soft limit hook.
+	ctx      context.Context
+	count    atomic.Int64
+	notified atomic.Bool
+}
+
+type subrequestsKey struct{}
+
+var softLimit struct {
+	mu    sync.RWMutex
+	limit int64
+	hook  func(ctx context.Context, count int)
+}
+
+// SetSubrequestSoftLimit sets the hook called once per event when the count of subrequests reaches the limit.
+func SetSubrequestSoftLimit(limit int, hook func(ctx context.Context, count int)) {
+	softLimit.mu.Lock()
+	defer softLimit.mu.Unlock()
+	softLimit.limit = int64(limit)
+	softLimit.hook = hook
+}
+
+// withSubrequests returns a context holding a new counter of subrequests.
+func withSubrequests(ctx context.Context) context.Context {
+	s := &Subrequests{}
+	ctx = context.WithValue(ctx, subrequestsKey{}, s)
+	s.ctx = ctx
+	return ctx
+}
+
+// SubrequestsFrom returns the counter of subrequests of the event. It returns nil if ctx is not a context of an event.
+func SubrequestsFrom(ctx context.Context) *Subrequests {
+	s, _ := ctx.Value(subrequestsKey{}).(*Subrequests)
+	return s
+}
+
+// Add counts a subrequest. It does nothing if s is nil.
+func (s *Subrequests) Add() {
+	if s == nil {
+		return
+	}
+	n := s.count.Add(1)
+	softLimit.mu.RLock()
+	limit, hook := softLimit.limit, softLimit.hook
+	softLimit.mu.RUnlock()
+	if hook != nil && limit > 0 && n >= limit && s.notified.CompareAndSwap(false, true) {
+		hook(s.ctx, int(n))
+	}
+}
+
+// Count returns the count of subrequests. It returns 0 if s is nil.
+func (s *Subrequests) Count() int {
+	if s == nil {
+		return 0
+	}
+	return int(s.count.Load())
+}
@@ -0,0 +1,39 @@
+package runtimecontext
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSubrequests(t *testing.T) {
+	var calls []int
+	SetSubrequestSoftLimit(2, func(ctx context.Context, count int) {
+		if SubrequestsFrom(ctx) == nil {
+			t.Error("want the context of the event")
+		}
+		calls = append(calls, count)
+	})
+	defer SetSubrequestSoftLimit(0, nil)
+
+	ctx := withSubrequests(context.Background())
+	s := SubrequestsFrom(ctx)
+	for i := 0; i < 3; i++ {
+		s.Add()
+	}
+	if got := s.Count(); got != 3 {
+		t.Errorf("want 3, got %d", got)
+	}
+	if len(calls) != 1 || calls[0] != 2 {
+		t.Errorf("want the hook to be called once at 2, got %v", calls)
+	}
+
+	// counters are not shared by events, and nil counters are ignored.
+	if got := SubrequestsFrom(withSubrequests(context.Background())).Count(); got != 0 {
+		t.Errorf("want 0 for a new event, got %d", got)
+	}
+	var none *Subrequests
+	none.Add()
+	if SubrequestsFrom(context.Background()) != nil || none.Count() != 0 {
+		t.Error("want no counter outside events")
+	}
+}
@@ -0,0 +1,59 @@
+package webcrypto
+
+import (
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jsutil"
+)
+
+// subtle is JavaScript side's SubtleCrypto.
+//   - https://developer.mozilla.org/docs/Web/API/SubtleCrypto
+//   - https://developers.cloudflare.com/workers/runtime-apis/web-crypto/
+var subtle = jsutil.Global.Get("crypto").Get("subtle")
+
+// Digest calculates digest of data by given algorithm (e.g. "SHA-256").
+func Digest(algorithm string, data []byte) ([]byte, error) {
+	p := subtle.Call("digest", algorithm, jsutil.NewUint8ArrayFromBytes(data))
+	v, err := jsutil.AwaitPromise(p)
+	if err != nil {
+		return nil, err
+	}
+	return jsutil.BufferSourceToBytes(v), nil
+}
+
+// ImportKey imports the key and returns CryptoKey object.
+//   - https://developer.mozilla.org/docs/Web/API/SubtleCrypto/importKey
+func ImportKey(format string, keyData any, algorithm any, usages ...string) (js.Value, error) {
+	jsUsages := make([]any, len(usages))
+	for i, u := range usages {
+		jsUsages[i] = u
+	}
+	p := subtle.Call("importKey", format, keyData, algorithm, false, jsUsages)
+	return jsutil.AwaitPromise(p)
+}
+
+// ImportRawKey imports raw bytes key and returns CryptoKey object.
+func ImportRawKey(key []byte, algorithm any, usages ...string) (js.Value, error) {
+	return ImportKey("raw", jsutil.NewUint8ArrayFromBytes(key), algorithm, usages...)
+}
+
+// Sign signs data with the key.
+//   - https://developer.mozilla.org/docs/Web/API/SubtleCrypto/sign
+func Sign(algorithm any, key js.Value, data []byte) ([]byte, error) {
+	p := subtle.Call("sign", algorithm, key, jsutil.NewUint8ArrayFromBytes(data))
+	v, err := jsutil.AwaitPromise(p)
+	if err != nil {
+		return nil, err
+	}
+	return jsutil.BufferSourceToBytes(v), nil
+}
+
+// Verify verifies the signature of data with the key.
+//   - https://developer.mozilla.org/docs/Web/API/SubtleCrypto/verify
+func Verify(algorithm any, key js.Value, signature, data []byte) (bool, error) {
+	p := subtle.Call("verify", algorithm, key, jsutil.NewUint8ArrayFromBytes(signature), jsutil.NewUint8ArrayFromBytes(data))
+	v, err := jsutil.AwaitPromise(p)
+	if err != nil {
+		return false, err
+	}
+	return v.Bool(), nil
+}
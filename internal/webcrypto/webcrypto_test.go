@@ -0,0 +1,100 @@
+//go:build js && wasm
+
+package webcrypto
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestDigest(t *testing.T) {
+	tests := map[string]struct {
+		algorithm string
+		data      string
+		want      string
+	}{
+		"SHA-256": {
+			algorithm: "SHA-256",
+			data:      "abc",
+			want:      "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		},
+		"SHA-256 of empty data": {
+			algorithm: "SHA-256",
+			want:      "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		},
+		"SHA-1": {
+			algorithm: "SHA-1",
+			data:      "abc",
+			want:      "a9993e364706816aba3e25717850c26c9cd0d89d",
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			got, err := Digest(tc.algorithm, []byte(tc.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hex.EncodeToString(got) != tc.want {
+				t.Errorf("want %s, got %x", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	// https://datatracker.ietf.org/doc/html/rfc4231#section-4.3
+	alg := map[string]any{"name": "HMAC", "hash": "SHA-256"}
+	key, err := ImportRawKey([]byte("Jefe"), alg, "sign", "verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("what do ya want for nothing?")
+	sig, err := Sign("HMAC", key, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"; hex.EncodeToString(sig) != want {
+		t.Errorf("want signature %s, got %x", want, sig)
+	}
+
+	tests := map[string]struct {
+		sig  []byte
+		data []byte
+		want bool
+	}{
+		"valid": {
+			sig:  sig,
+			data: data,
+			want: true,
+		},
+		"tampered data": {
+			sig:  sig,
+			data: []byte("what do ya want for something?"),
+		},
+		"tampered signature": {
+			sig:  append([]byte{sig[0] ^ 1}, sig[1:]...),
+			data: data,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			ok, err := Verify("HMAC", key, tc.sig, tc.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tc.want {
+				t.Errorf("want %v, got %v", tc.want, ok)
+			}
+		})
+	}
+}
+
+func TestImportKey_Error(t *testing.T) {
+	if _, err := ImportRawKey(nil, map[string]any{"name": "HMAC", "hash": "SHA-256"}, "sign"); err == nil {
+		t.Error("want error for an empty HMAC key, got nil")
+	}
+}
@@ -0,0 +1,65 @@
+// Package jsoncodec holds the JSON codec used by typed helpers of bindings.
+//   - KV (cloudflare.GetJSON, cloudflare.PutJSON and metadata), D1 (d1.JSON), Queues (queues.SendJSON, queues.DecodeJSON and json messages)
+//     and WebSocket attachments (websocket.SerializeAttachment) encode and decode values by the codec.
+//   - the default codec is encoding/json. Reflection of encoding/json is costly on WebAssembly,
+//     so a codec using generated code (e.g. easyjson) or custom formats can be set by Set at startup.
+package jsoncodec
+
+import (
+	"encoding/json"
+	"sync/atomic"
+)
+
+// Codec encodes and decodes values as JSON.
+//   - Marshal must return valid JSON, since values may be parsed by JSON.parse of the runtime.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// Std is Codec of encoding/json.
+var Std Codec = stdCodec{}
+
+type stdCodec struct{}
+
+func (stdCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (stdCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// holder holds Codec, since atomic.Value requires values of the same concrete type.
+type holder struct {
+	codec Codec
+}
+
+var current atomic.Value
+
+// Set sets the codec used by typed helpers. If c is nil, Std is used.
+//   - it should be called before handling events, e.g. in main or init.
+func Set(c Codec) {
+	if c == nil {
+		c = Std
+	}
+	current.Store(holder{codec: c})
+}
+
+// Get returns the codec set by Set, or Std.
+func Get() Codec {
+	if h, ok := current.Load().(holder); ok {
+		return h.codec
+	}
+	return Std
+}
+
+// Marshal encodes v by the current codec.
+func Marshal(v any) ([]byte, error) {
+	return Get().Marshal(v)
+}
+
+// Unmarshal decodes data into v by the current codec.
+func Unmarshal(data []byte, v any) error {
+	return Get().Unmarshal(data, v)
+}
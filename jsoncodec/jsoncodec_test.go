@@ -0,0 +1,64 @@
+package jsoncodec_test
+
+import (
+	"testing"
+
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/cloudflare/d1"
+	"github.com/syumai/workers/jsoncodec"
+	"github.com/syumai/workers/workerstest"
+)
+
+// countingCodec counts calls of the standard codec.
+type countingCodec struct {
+	marshals, unmarshals int
+}
+
+func (c *countingCodec) Marshal(v any) ([]byte, error) {
+	c.marshals++
+	return jsoncodec.Std.Marshal(v)
+}
+
+func (c *countingCodec) Unmarshal(data []byte, v any) error {
+	c.unmarshals++
+	return jsoncodec.Std.Unmarshal(data, v)
+}
+
+type user struct {
+	Name string `json:"name"`
+}
+
+func TestSet(t *testing.T) {
+	codec := &countingCodec{}
+	jsoncodec.Set(codec)
+	defer jsoncodec.Set(nil)
+
+	kv := &workerstest.KVNamespace{}
+	if err := cloudflare.PutJSON(kv, "user", user{Name: "gopher"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	got, ok, err := cloudflare.GetJSON[user](kv, "user", nil)
+	if err != nil || !ok || got.Name != "gopher" {
+		t.Errorf("want gopher, got %v, %v, %v", got, ok, err)
+	}
+	if _, ok, err := cloudflare.GetJSON[user](kv, "missing", nil); ok || err != nil {
+		t.Errorf("want missing key not to be found, got %v, %v", ok, err)
+	}
+
+	v, err := d1.JSON[user]{V: user{Name: "gopher"}}.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scanned d1.JSON[user]
+	if err := scanned.Scan(v); err != nil || scanned.V.Name != "gopher" {
+		t.Errorf("want gopher, got %v, %v", scanned.V, err)
+	}
+
+	if codec.marshals != 2 || codec.unmarshals != 2 {
+		t.Errorf("want 2 marshals and 2 unmarshals by the codec, got %d and %d", codec.marshals, codec.unmarshals)
+	}
+	jsoncodec.Set(nil)
+	if jsoncodec.Get() != jsoncodec.Std {
+		t.Error("want Set(nil) to reset the codec")
+	}
+}
@@ -0,0 +1,191 @@
+// Package jwt signs and verifies JSON Web Tokens by native SubtleCrypto.
+//   - HS256, RS256 and ES256 are supported.
+//   - https://datatracker.ietf.org/doc/html/rfc7519
+package jwt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/syumai/workers/internal/webcrypto"
+)
+
+var (
+	// ErrMalformed is returned when the token can't be decoded.
+	ErrMalformed = errors.New("jwt: malformed token")
+	// ErrUnsupportedAlgorithm is returned when the algorithm is not supported, or doesn't match the key.
+	ErrUnsupportedAlgorithm = errors.New("jwt: unsupported algorithm")
+	// ErrInvalidSignature is returned when the signature doesn't match.
+	ErrInvalidSignature = errors.New("jwt: invalid signature")
+	// ErrExpired is returned when the token is expired ("exp" claim).
+	ErrExpired = errors.New("jwt: token is expired")
+	// ErrNotValidYet is returned when the token is not valid yet ("nbf" claim).
+	ErrNotValidYet = errors.New("jwt: token is not valid yet")
+	// ErrInvalidIssuer is returned when "iss" claim doesn't match.
+	ErrInvalidIssuer = errors.New("jwt: invalid issuer")
+	// ErrInvalidAudience is returned when "aud" claim doesn't contain the audience.
+	ErrInvalidAudience = errors.New("jwt: invalid audience")
+)
+
+var encoding = base64.RawURLEncoding
+
+// Header represents the JOSE header of a token.
+type Header struct {
+	Algorithm Algorithm `json:"alg"`
+	Type      string    `json:"typ,omitempty"`
+	KeyID     string    `json:"kid,omitempty"`
+}
+
+// Token represents a decoded token.
+type Token struct {
+	Header Header
+	// Claims holds all claims of the token.
+	Claims map[string]any
+	// rawClaims is the JSON of claims, used by DecodeClaims.
+	rawClaims []byte
+}
+
+// DecodeClaims decodes claims of the token into v.
+func (t *Token) DecodeClaims(v any) error {
+	return json.Unmarshal(t.rawClaims, v)
+}
+
+// Sign signs the claims with the key, and returns the token.
+//   - claims must be encodable to a JSON object (e.g. a struct or map[string]any).
+func Sign(claims any, key *Key) (string, error) {
+	if !key.canSign {
+		return "", errors.New("jwt: the key can't sign tokens")
+	}
+	header, err := json.Marshal(&Header{Algorithm: key.Algorithm, Type: "JWT", KeyID: key.ID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("jwt: error encoding claims: %w", err)
+	}
+	signingInput := encoding.EncodeToString(header) + "." + encoding.EncodeToString(payload)
+	sig, err := webcrypto.Sign(key.Algorithm.signParams(), key.key, []byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + encoding.EncodeToString(sig), nil
+}
+
+// Parse decodes the token without verifying it.
+// It can be used to choose the key by Header.KeyID before Verify.
+func Parse(token string) (*Token, error) {
+	t, _, _, err := parse(token)
+	return t, err
+}
+
+func parse(token string) (t *Token, signingInput string, sig []byte, err error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, "", nil, ErrMalformed
+	}
+	t = &Token{}
+	headerJSON, err := encoding.DecodeString(parts[0])
+	if err != nil || json.Unmarshal(headerJSON, &t.Header) != nil {
+		return nil, "", nil, fmt.Errorf("%w: invalid header", ErrMalformed)
+	}
+	t.rawClaims, err = encoding.DecodeString(parts[1])
+	if err != nil || json.Unmarshal(t.rawClaims, &t.Claims) != nil {
+		return nil, "", nil, fmt.Errorf("%w: invalid claims", ErrMalformed)
+	}
+	sig, err = encoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("%w: invalid signature", ErrMalformed)
+	}
+	return t, parts[0] + "." + parts[1], sig, nil
+}
+
+// VerifyOptions represents options of Verify.
+type VerifyOptions struct {
+	// Issuer is required to match "iss" claim if set.
+	Issuer string
+	// Audience is required to be contained in "aud" claim if set.
+	Audience string
+	// RequireExpiration rejects tokens without "exp" claim.
+	RequireExpiration bool
+	// Leeway is the allowed clock skew for "exp" and "nbf" claims.
+	Leeway time.Duration
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Verify verifies the signature and the claims of the token with the key.
+//   - "exp" and "nbf" claims are validated if present.
+//   - the algorithm of the token must match the algorithm of the key.
+func Verify(token string, key *Key, opts *VerifyOptions) (*Token, error) {
+	if opts == nil {
+		opts = &VerifyOptions{}
+	}
+	t, signingInput, sig, err := parse(token)
+	if err != nil {
+		return nil, err
+	}
+	if t.Header.Algorithm != key.Algorithm {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, t.Header.Algorithm)
+	}
+	if !key.canVerify {
+		return nil, errors.New("jwt: the key can't verify tokens")
+	}
+	ok, err := webcrypto.Verify(key.Algorithm.signParams(), key.key, sig, []byte(signingInput))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidSignature
+	}
+	if err := validateClaims(t.Claims, opts); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func validateClaims(claims map[string]any, opts *VerifyOptions) error {
+	now := time.Now
+	if opts.Now != nil {
+		now = opts.Now
+	}
+	t := now()
+	exp, ok := claims["exp"].(float64)
+	if !ok && opts.RequireExpiration {
+		return ErrExpired
+	}
+	if ok && !t.Before(time.Unix(int64(exp), 0).Add(opts.Leeway)) {
+		return ErrExpired
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && t.Before(time.Unix(int64(nbf), 0).Add(-opts.Leeway)) {
+		return ErrNotValidYet
+	}
+	if opts.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != opts.Issuer {
+			return ErrInvalidIssuer
+		}
+	}
+	if opts.Audience != "" && !containsAudience(claims["aud"], opts.Audience) {
+		return ErrInvalidAudience
+	}
+	return nil
+}
+
+// containsAudience reports whether "aud" claim, a string or an array of strings, contains the audience.
+func containsAudience(aud any, audience string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == audience
+	case []any:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+//go:build js && wasm
+
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"testing"
+	"time"
+)
+
+// keyPair returns keys to sign and verify tokens of the algorithm.
+func keyPair(t *testing.T, alg Algorithm) (signKey, verifyKey *Key) {
+	t.Helper()
+	if alg == HS256 {
+		key, err := NewHS256Key([]byte("secret"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key, key
+	}
+	var priv, pub any
+	switch alg {
+	case RS256:
+		k, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		priv, pub = k, &k.PublicKey
+	case ES256:
+		k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		priv, pub = k, &k.PublicKey
+	}
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signKey, err = ImportPKCS8(privDER, alg); err != nil {
+		t.Fatal(err)
+	}
+	if verifyKey, err = ImportSPKI(pubDER, alg); err != nil {
+		t.Fatal(err)
+	}
+	return signKey, verifyKey
+}
+
+func TestSignAndVerify(t *testing.T) {
+	for _, alg := range []Algorithm{HS256, RS256, ES256} {
+		alg := alg
+		t.Run(string(alg), func(t *testing.T) {
+			signKey, verifyKey := keyPair(t, alg)
+			signKey.ID = "key-1"
+			token, err := Sign(map[string]any{"sub": "user", "exp": time.Now().Add(time.Hour).Unix()}, signKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Verify(token, verifyKey, &VerifyOptions{RequireExpiration: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var claims struct {
+				Sub string `json:"sub"`
+			}
+			if err := got.DecodeClaims(&claims); err != nil {
+				t.Fatal(err)
+			}
+			if claims.Sub != "user" || got.Header.KeyID != "key-1" {
+				t.Errorf("unexpected token: %+v", got)
+			}
+			tampered := token[:len(token)-4] + "AAAA"
+			if _, err := Verify(tampered, verifyKey, nil); !errors.Is(err, ErrInvalidSignature) && !errors.Is(err, ErrMalformed) {
+				t.Errorf("want ErrInvalidSignature, got %v", err)
+			}
+		})
+	}
+}
+
+func TestVerify_Claims(t *testing.T) {
+	key, _ := keyPair(t, HS256)
+	now := time.Unix(1700000000, 0)
+	tests := map[string]struct {
+		claims  map[string]any
+		opts    *VerifyOptions
+		wantErr error
+	}{
+		"valid": {
+			claims: map[string]any{"exp": now.Unix() + 60, "nbf": now.Unix() - 60, "iss": "me", "aud": []string{"a", "b"}},
+			opts:   &VerifyOptions{Issuer: "me", Audience: "b"},
+		},
+		"expired": {
+			claims:  map[string]any{"exp": now.Unix()},
+			wantErr: ErrExpired,
+		},
+		"expired within leeway": {
+			claims: map[string]any{"exp": now.Unix() - 10},
+			opts:   &VerifyOptions{Leeway: time.Minute},
+		},
+		"missing exp": {
+			claims:  map[string]any{},
+			opts:    &VerifyOptions{RequireExpiration: true},
+			wantErr: ErrExpired,
+		},
+		"not valid yet": {
+			claims:  map[string]any{"nbf": now.Unix() + 60},
+			wantErr: ErrNotValidYet,
+		},
+		"invalid issuer": {
+			claims:  map[string]any{"iss": "other"},
+			opts:    &VerifyOptions{Issuer: "me"},
+			wantErr: ErrInvalidIssuer,
+		},
+		"invalid audience": {
+			claims:  map[string]any{"aud": "other"},
+			opts:    &VerifyOptions{Audience: "me"},
+			wantErr: ErrInvalidAudience,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			token, err := Sign(tc.claims, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := tc.opts
+			if opts == nil {
+				opts = &VerifyOptions{}
+			}
+			opts.Now = func() time.Time { return now }
+			_, err = Verify(token, key, opts)
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("want %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package jwt
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jsutil"
+	"github.com/syumai/workers/internal/webcrypto"
+)
+
+// Algorithm is the algorithm of signatures ("alg" header).
+type Algorithm string
+
+const (
+	// HS256 is HMAC using SHA-256.
+	HS256 Algorithm = "HS256"
+	// RS256 is RSASSA-PKCS1-v1_5 using SHA-256.
+	RS256 Algorithm = "RS256"
+	// ES256 is ECDSA using P-256 and SHA-256.
+	ES256 Algorithm = "ES256"
+)
+
+// importParams returns the algorithm parameters of importKey.
+func (a Algorithm) importParams() (map[string]any, error) {
+	switch a {
+	case HS256:
+		return map[string]any{"name": "HMAC", "hash": "SHA-256"}, nil
+	case RS256:
+		return map[string]any{"name": "RSASSA-PKCS1-v1_5", "hash": "SHA-256"}, nil
+	case ES256:
+		return map[string]any{"name": "ECDSA", "namedCurve": "P-256"}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, a)
+}
+
+// signParams returns the algorithm parameters of sign and verify.
+func (a Algorithm) signParams() any {
+	switch a {
+	case HS256:
+		return "HMAC"
+	case RS256:
+		return "RSASSA-PKCS1-v1_5"
+	}
+	return map[string]any{"name": "ECDSA", "hash": "SHA-256"}
+}
+
+// Key is a key to sign or verify tokens, imported into native SubtleCrypto.
+type Key struct {
+	// ID is set to "kid" header of signed tokens.
+	ID        string
+	Algorithm Algorithm
+	key       js.Value
+	canSign   bool
+	canVerify bool
+}
+
+func importKey(format string, data any, alg Algorithm, usages ...string) (*Key, error) {
+	params, err := alg.importParams()
+	if err != nil {
+		return nil, err
+	}
+	key, err := webcrypto.ImportKey(format, data, params, usages...)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: error importing key: %w", err)
+	}
+	k := &Key{Algorithm: alg, key: key}
+	for _, u := range usages {
+		switch u {
+		case "sign":
+			k.canSign = true
+		case "verify":
+			k.canVerify = true
+		}
+	}
+	return k, nil
+}
+
+// NewHS256Key returns the key of HS256 to sign and verify tokens.
+func NewHS256Key(secret []byte) (*Key, error) {
+	return importKey("raw", jsutil.NewUint8ArrayFromBytes(secret), HS256, "sign", "verify")
+}
+
+// ImportPKCS8 imports the private key in PKCS #8 DER to sign tokens by RS256 or ES256.
+// PEM can be decoded by encoding/pem.
+func ImportPKCS8(der []byte, alg Algorithm) (*Key, error) {
+	return importKey("pkcs8", jsutil.NewUint8ArrayFromBytes(der), alg, "sign")
+}
+
+// ImportSPKI imports the public key in SubjectPublicKeyInfo DER to verify tokens by RS256 or ES256.
+func ImportSPKI(der []byte, alg Algorithm) (*Key, error) {
+	return importKey("spki", jsutil.NewUint8ArrayFromBytes(der), alg, "verify")
+}
+
+// ImportJWK imports the JSON Web Key of RSA or EC P-256.
+//   - the algorithm is chosen by "kty", and ID is set from "kid".
+//   - private keys (with "d") can sign tokens, and public keys can verify tokens.
+func ImportJWK(jwk []byte) (*Key, error) {
+	var m map[string]any
+	if err := json.Unmarshal(jwk, &m); err != nil {
+		return nil, fmt.Errorf("jwt: error decoding JWK: %w", err)
+	}
+	var alg Algorithm
+	switch m["kty"] {
+	case "RSA":
+		alg = RS256
+	case "EC":
+		if m["crv"] != "P-256" {
+			return nil, fmt.Errorf("%w: EC curve %v", ErrUnsupportedAlgorithm, m["crv"])
+		}
+		alg = ES256
+	default:
+		return nil, fmt.Errorf("%w: key type %v", ErrUnsupportedAlgorithm, m["kty"])
+	}
+	usage := "verify"
+	if _, ok := m["d"]; ok {
+		usage = "sign"
+	}
+	// "alg", "use" and "key_ops" may conflict with the usage, so they are removed.
+	delete(m, "alg")
+	delete(m, "use")
+	delete(m, "key_ops")
+	key, err := importKey("jwk", m, alg, usage)
+	if err != nil {
+		return nil, err
+	}
+	key.ID, _ = m["kid"].(string)
+	return key, nil
+}
@@ -0,0 +1,52 @@
+package workers
+
+import (
+	"sync"
+
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jsutil"
+	"github.com/syumai/workers/internal/runtimecontext"
+)
+
+// OnReady registers the hook called by Start before the instance is signaled ready.
+//   - hooks are called in the order of registration.
+//   - if a hook returns error, events dispatched to the instance fail with the error, and the entry point discards the instance.
+//     It is suitable for validating configurations at startup (e.g. cloudflare.MustBindings).
+func OnReady(fn func() error) {
+	runtimecontext.OnReady(fn)
+}
+
+// OnTeardown registers the hook called when the entry point tears down the instance, after all in-flight events settle.
+//   - hooks are called in the reverse order of registration.
+func OnTeardown(fn func()) {
+	runtimecontext.OnTeardown(fn)
+}
+
+// signalReady runs OnReady hooks and signals the entry point, then blocks until the instance is torn down.
+//
+// The JavaScript entry point and the Go program hand shake as below.
+//   - the entry point defines `globalThis.ready(err)`, and awaits it before dispatching events to the instance.
+//   - the entry point sets the env of the first event to `globalThis.env` before starting the instance,
+//     so OnReady hooks can inspect bindings (e.g. cloudflare.MustBindings).
+//   - Start runs OnReady hooks after handlers are registered, and calls `ready()`, or `ready(err)` if a hook failed.
+//     If the program exits before calling `ready`, the entry point fails the events awaiting it.
+//   - the entry point may call `globalThis.teardown()` to discard the idle instance, e.g. when its memory has grown.
+//     Start waits for in-flight events, runs OnTeardown hooks, and returns, so the program exits.
+func signalReady() {
+	if err := runtimecontext.RunReadyHooks(); err != nil {
+		jsutil.Global.Call("ready", jsutil.ErrorClass.New(err.Error()))
+		return
+	}
+	teardown := make(chan struct{})
+	closeTeardown := sync.OnceFunc(func() { close(teardown) })
+	teardownCallback := js.FuncOf(func(js.Value, []js.Value) any {
+		closeTeardown()
+		return js.Undefined()
+	})
+	defer teardownCallback.Release()
+	jsutil.Global.Set("teardown", teardownCallback)
+	jsutil.Global.Call("ready")
+	<-teardown
+	runtimecontext.Wait()
+	runtimecontext.RunTeardownHooks()
+}
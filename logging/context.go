@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/syumai/workers"
+	"github.com/syumai/workers/middleware"
+	"github.com/syumai/workers/trace"
+)
+
+type loggerKey struct{}
+
+// NewContext returns a context holding the logger.
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger held by the context.
+//   - if the context doesn't hold a logger, returns slog.Default().
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// Middleware returns a middleware injecting a request-scoped logger into the request context.
+// The logger has method, path, ray_id and trace_id attributes of the request.
+//   - if base is nil, slog.Default() is used.
+func Middleware(base *slog.Logger) workers.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			logger := base
+			if logger == nil {
+				logger = slog.Default()
+			}
+			var traceID string
+			if tc, ok := trace.FromContext(req.Context()); ok {
+				traceID = tc.TraceID
+			}
+			logger = logger.With(
+				slog.String("method", req.Method),
+				slog.String("path", req.URL.Path),
+				slog.String("ray_id", req.Header.Get("Cf-Ray")),
+				slog.String("trace_id", traceID),
+			)
+			next.ServeHTTP(w, req.WithContext(NewContext(req.Context(), logger)))
+		})
+	}
+}
+
+// RequestLogSink returns a sink of the middleware.Logger writing entries to the logger.
+//   - if logger is nil, the logger held by the request context is used.
+func RequestLogSink(logger *slog.Logger) func(req *http.Request, entry *middleware.LogEntry) {
+	return func(req *http.Request, entry *middleware.LogEntry) {
+		l := logger
+		if l == nil {
+			l = FromContext(req.Context())
+		}
+		l.LogAttrs(req.Context(), slog.LevelInfo, "request",
+			slog.String("method", entry.Method),
+			slog.String("path", entry.Path),
+			slog.Int("status", entry.Status),
+			slog.Int64("size", entry.Size),
+			slog.Float64("duration_ms", entry.Duration.Seconds()*1000),
+			slog.String("ray_id", entry.RayID),
+			slog.String("colo", entry.Colo),
+		)
+	}
+}
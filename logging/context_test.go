@@ -0,0 +1,79 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/syumai/workers/middleware"
+	"github.com/syumai/workers/trace"
+)
+
+func TestFromContext(t *testing.T) {
+	if got := FromContext(context.Background()); got != slog.Default() {
+		t.Errorf("want slog.Default() for a context without a logger, got %v", got)
+	}
+	logger := slog.New(NewHandler(nil))
+	if got := FromContext(NewContext(context.Background(), logger)); got != logger {
+		t.Errorf("want the logger held by the context, got %v", got)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	calls := captureConsole(t)
+	h := Middleware(slog.New(NewHandler(nil)))(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		FromContext(req.Context()).Info("handled", "user", "u")
+	}))
+	tc := &trace.TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"}
+	req := httptest.NewRequest(http.MethodGet, "/a?q=1", nil)
+	req = req.WithContext(trace.NewContext(req.Context(), tc))
+	req.Header.Set("Cf-Ray", "8a1b2c3d4e5f6a7b-NRT")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	want := []consoleCall{{method: "log", entry: map[string]any{
+		"level":    "INFO",
+		"msg":      "handled",
+		"method":   "GET",
+		"path":     "/a",
+		"ray_id":   "8a1b2c3d4e5f6a7b-NRT",
+		"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736",
+		"user":     "u",
+	}}}
+	if got := calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
+
+func TestRequestLogSink(t *testing.T) {
+	calls := captureConsole(t)
+	logger := slog.New(NewHandler(nil)).With("scope", "request")
+	req := httptest.NewRequest(http.MethodGet, "/a", nil)
+	req = req.WithContext(NewContext(req.Context(), logger))
+	RequestLogSink(nil)(req, &middleware.LogEntry{
+		Method:   http.MethodGet,
+		Path:     "/a",
+		Status:   http.StatusOK,
+		Size:     5,
+		Duration: 1500 * time.Microsecond,
+		RayID:    "r",
+		Colo:     "NRT",
+	})
+	want := []consoleCall{{method: "log", entry: map[string]any{
+		"level":       "INFO",
+		"msg":         "request",
+		"scope":       "request",
+		"method":      "GET",
+		"path":        "/a",
+		"status":      200.0,
+		"size":        5.0,
+		"duration_ms": 1.5,
+		"ray_id":      "r",
+		"colo":        "NRT",
+	}}}
+	if got := calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
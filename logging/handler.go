@@ -0,0 +1,177 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/syumai/workers/internal/jsutil"
+	"github.com/syumai/workers/secret"
+)
+
+// HandlerOptions represents options of Handler.
+type HandlerOptions struct {
+	// Level is the minimum level of records to be logged. Defaults to slog.LevelInfo.
+	Level slog.Leveler
+	// RedactKeys are keys of attributes whose values are replaced by "[REDACTED]" (e.g. secret.DefaultRedactKeys).
+	//   - keys are matched case-insensitively in any group.
+	RedactKeys []string
+}
+
+// Handler is a slog.Handler writing records to the console as structured objects,
+// so Workers Logs and Logpush can index their attributes.
+//   - The console method is chosen by the level: console.debug, console.log, console.warn and console.error.
+//   - https://developers.cloudflare.com/workers/observability/logs/workers-logs/
+type Handler struct {
+	level slog.Leveler
+	// attrs holds attributes added by WithAttrs. Attributes added under a group are nested maps.
+	attrs map[string]any
+	// groups is the current group path opened by WithGroup.
+	groups []string
+	// redact holds lowercase keys of attributes to be redacted.
+	redact map[string]bool
+}
+
+var _ slog.Handler = (*Handler)(nil)
+
+// NewHandler returns a new Handler.
+func NewHandler(opts *HandlerOptions) *Handler {
+	var level slog.Leveler = slog.LevelInfo
+	if opts != nil && opts.Level != nil {
+		level = opts.Level
+	}
+	h := &Handler{level: level, attrs: map[string]any{}}
+	if opts != nil && len(opts.RedactKeys) > 0 {
+		h.redact = make(map[string]bool, len(opts.RedactKeys))
+		for _, k := range opts.RedactKeys {
+			h.redact[strings.ToLower(k)] = true
+		}
+	}
+	return h
+}
+
+// Enabled implements slog.Handler.
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle implements slog.Handler.
+func (h *Handler) Handle(_ context.Context, r slog.Record) error {
+	entry := cloneMap(h.attrs)
+	target := groupMap(entry, h.groups)
+	r.Attrs(func(a slog.Attr) bool {
+		h.addAttr(target, a)
+		return true
+	})
+	entry[slog.LevelKey] = r.Level.String()
+	entry[slog.MessageKey] = r.Message
+	if !r.Time.IsZero() {
+		entry[slog.TimeKey] = r.Time.UTC().Format(time.RFC3339Nano)
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	obj := jsutil.Global.Get("JSON").Call("parse", string(b))
+	jsutil.Global.Get("console").Call(consoleMethod(r.Level), obj)
+	return nil
+}
+
+// consoleMethod returns the console method name for the level.
+func consoleMethod(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return "error"
+	case level >= slog.LevelWarn:
+		return "warn"
+	case level >= slog.LevelInfo:
+		return "log"
+	default:
+		return "debug"
+	}
+}
+
+// WithAttrs implements slog.Handler.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := &Handler{level: h.level, attrs: cloneMap(h.attrs), groups: h.groups, redact: h.redact}
+	target := groupMap(h2.attrs, h2.groups)
+	for _, a := range attrs {
+		h2.addAttr(target, a)
+	}
+	return h2
+}
+
+// WithGroup implements slog.Handler.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	groups := append(append([]string(nil), h.groups...), name)
+	return &Handler{level: h.level, attrs: cloneMap(h.attrs), groups: groups, redact: h.redact}
+}
+
+// groupMap returns the nested map for the group path, creating it if needed.
+func groupMap(m map[string]any, groups []string) map[string]any {
+	for _, g := range groups {
+		child, ok := m[g].(map[string]any)
+		if !ok {
+			child = map[string]any{}
+			m[g] = child
+		}
+		m = child
+	}
+	return m
+}
+
+// cloneMap deeply copies nested maps of attributes.
+func cloneMap(m map[string]any) map[string]any {
+	c := make(map[string]any, len(m))
+	for k, v := range m {
+		if child, ok := v.(map[string]any); ok {
+			v = cloneMap(child)
+		}
+		c[k] = v
+	}
+	return c
+}
+
+// addAttr adds the attribute to the map converting its value into JSON-compatible value.
+func (h *Handler) addAttr(m map[string]any, a slog.Attr) {
+	v := a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if h.redact[strings.ToLower(a.Key)] {
+		m[a.Key] = secret.Redacted
+		return
+	}
+	switch v.Kind() {
+	case slog.KindGroup:
+		attrs := v.Group()
+		if len(attrs) == 0 {
+			return
+		}
+		// attributes of a group with empty key are inlined.
+		target := m
+		if a.Key != "" {
+			target = groupMap(m, []string{a.Key})
+		}
+		for _, ga := range attrs {
+			h.addAttr(target, ga)
+		}
+	case slog.KindTime:
+		m[a.Key] = v.Time().UTC().Format(time.RFC3339Nano)
+	case slog.KindDuration:
+		m[a.Key] = v.Duration().String()
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			m[a.Key] = err.Error()
+			return
+		}
+		m[a.Key] = v.Any()
+	default:
+		m[a.Key] = v.Any()
+	}
+}
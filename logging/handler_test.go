@@ -0,0 +1,115 @@
+package logging
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/syumai/workers/internal/js"
+)
+
+type consoleCall struct {
+	method string
+	entry  map[string]any
+}
+
+// captureConsole replaces console of the runtime with a fake recording the objects logged.
+// Tests using it must not be parallel, since console is global.
+func captureConsole(t *testing.T) func() []consoleCall {
+	t.Helper()
+	console := js.Global().Get("console")
+	t.Cleanup(func() { js.Global().Set("console", console) })
+	var calls []consoleCall
+	fake := map[string]any{}
+	for _, method := range []string{"debug", "log", "warn", "error"} {
+		method := method
+		fake[method] = js.FuncOf(func(_ js.Value, args []js.Value) any {
+			var entry map[string]any
+			if err := json.Unmarshal([]byte(js.Global().Get("JSON").Call("stringify", args[0]).String()), &entry); err != nil {
+				t.Error(err)
+			}
+			// the time is not compared.
+			delete(entry, slog.TimeKey)
+			calls = append(calls, consoleCall{method: method, entry: entry})
+			return js.Undefined()
+		})
+	}
+	js.Global().Set("console", js.ValueOf(fake))
+	return func() []consoleCall {
+		return calls
+	}
+}
+
+func TestHandler(t *testing.T) {
+	tests := map[string]struct {
+		opts *HandlerOptions
+		log  func(logger *slog.Logger)
+		want []consoleCall
+	}{
+		"levels": {
+			opts: &HandlerOptions{Level: slog.LevelDebug},
+			log: func(logger *slog.Logger) {
+				logger.Debug("d")
+				logger.Info("i")
+				logger.Warn("w")
+				logger.Error("e")
+			},
+			want: []consoleCall{
+				{method: "debug", entry: map[string]any{"level": "DEBUG", "msg": "d"}},
+				{method: "log", entry: map[string]any{"level": "INFO", "msg": "i"}},
+				{method: "warn", entry: map[string]any{"level": "WARN", "msg": "w"}},
+				{method: "error", entry: map[string]any{"level": "ERROR", "msg": "e"}},
+			},
+		},
+		"default level": {
+			log: func(logger *slog.Logger) {
+				logger.Debug("d")
+				logger.Info("i")
+			},
+			want: []consoleCall{
+				{method: "log", entry: map[string]any{"level": "INFO", "msg": "i"}},
+			},
+		},
+		"attributes": {
+			log: func(logger *slog.Logger) {
+				logger.Info("m", "n", 1, "ok", true, "err", errors.New("failed"), slog.Group("g", "a", "b"), slog.Group("empty"))
+			},
+			want: []consoleCall{
+				{method: "log", entry: map[string]any{"level": "INFO", "msg": "m", "n": 1.0, "ok": true, "err": "failed", "g": map[string]any{"a": "b"}}},
+			},
+		},
+		"groups": {
+			log: func(logger *slog.Logger) {
+				logger = logger.With("a", 1).WithGroup("req").With("b", 2)
+				logger.Info("m", "c", 3)
+				logger.Info("n", "d", 4)
+			},
+			want: []consoleCall{
+				{method: "log", entry: map[string]any{"level": "INFO", "msg": "m", "a": 1.0, "req": map[string]any{"b": 2.0, "c": 3.0}}},
+				{method: "log", entry: map[string]any{"level": "INFO", "msg": "n", "a": 1.0, "req": map[string]any{"b": 2.0, "d": 4.0}}},
+			},
+		},
+		"redacted": {
+			opts: &HandlerOptions{RedactKeys: []string{"Password"}},
+			log: func(logger *slog.Logger) {
+				logger.With("password", "p").Info("m", slog.Group("user", "PASSWORD", "q", "name", "n"))
+			},
+			want: []consoleCall{
+				{method: "log", entry: map[string]any{"level": "INFO", "msg": "m", "password": "[REDACTED]", "user": map[string]any{"PASSWORD": "[REDACTED]", "name": "n"}}},
+			},
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			calls := captureConsole(t)
+			tc.log(slog.New(NewHandler(tc.opts)))
+			if got := calls(); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("want %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
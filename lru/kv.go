@@ -0,0 +1,44 @@
+package lru
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/jsoncodec"
+)
+
+// GetKV returns the value of the key in KV through the cache. If the key doesn't exist in KV, returns nil.
+//   - missing keys are cached as nil values as well, so lookups of missing keys don't hit KV either.
+//   - opts is used on cache misses (e.g. CacheTTL of KV).
+func GetKV(ctx context.Context, c *Cache[string, []byte], kv cloudflare.KVNamespaceBinding, key string, opts *cloudflare.KVNamespaceGetOptions) ([]byte, error) {
+	return c.GetOrLoad(ctx, key, func(context.Context) ([]byte, error) {
+		entry, err := kv.GetWithMetadata(key, opts)
+		if err != nil || entry == nil {
+			return nil, err
+		}
+		if entry.Value == nil {
+			// distinguishes an empty value from a missing key.
+			return []byte{}, nil
+		}
+		return entry.Value, nil
+	})
+}
+
+// GetKVJSON returns the value of the key in KV decoded by the codec of the jsoncodec package through the cache.
+// If the key doesn't exist in KV, returns nil.
+//   - missing keys are cached as nil values as well, so lookups of missing keys don't hit KV either.
+//   - values are shared by callers, so they must not be modified.
+func GetKVJSON[T any](ctx context.Context, c *Cache[string, *T], kv cloudflare.KVNamespaceBinding, key string, opts *cloudflare.KVNamespaceGetOptions) (*T, error) {
+	return c.GetOrLoad(ctx, key, func(context.Context) (*T, error) {
+		entry, err := kv.GetWithMetadata(key, opts)
+		if err != nil || entry == nil {
+			return nil, err
+		}
+		v := new(T)
+		if err := jsoncodec.Unmarshal(entry.Value, v); err != nil {
+			return nil, fmt.Errorf("lru: error decoding value of %s: %w", key, err)
+		}
+		return v, nil
+	})
+}
@@ -0,0 +1,274 @@
+// Package lru provides an in-memory LRU cache of the isolate, bounded by the number of entries, their size and TTL.
+//   - the cache lives in the memory of the Go program, so hits don't cost subrequests nor reads of KV or D1.
+//   - each isolate has its own cache. Isolates are evicted at any time, and other isolates (in the same or other
+//     data centers) don't share it, so the cache is a best effort layer. Values may be stale for up to the TTL after
+//     they are updated in the store.
+//   - the memory of Wasm never shrinks. Keep MaxBytes well below the memory limit of the isolate (128 MB),
+//     and the MAX_MEMORY_BYTES of the entry point generated by workers-init, over which the instance is torn down.
+//
+// Example of config lookups hitting KV at most once a minute per isolate:
+//
+//	var configs = lru.New(&lru.Options[string, *Config]{MaxEntries: 100, TTL: time.Minute})
+//
+//	cfg, err := lru.GetKVJSON(ctx, configs, kv, "config:"+tenant, nil)
+//
+// Example of reads of D1:
+//
+//	var users = lru.New(&lru.Options[int64, *User]{MaxEntries: 10000, TTL: 10 * time.Second})
+//
+//	user, err := users.GetOrLoad(ctx, id, func(ctx context.Context) (*User, error) {
+//	  var u User
+//	  err := db.QueryRowContext(ctx, "SELECT name FROM users WHERE id = ?", id).Scan(&u.Name)
+//	  return &u, err
+//	})
+package lru
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Options represents options of New.
+type Options[K comparable, V any] struct {
+	// MaxEntries is the maximum number of entries. Zero means no limit.
+	// If both MaxEntries and MaxBytes are zero, MaxEntries defaults to 1000.
+	MaxEntries int
+	// MaxBytes is the budget of the total size of entries measured by SizeOf. Zero means no limit.
+	MaxBytes int
+	// SizeOf returns the size of the entry in bytes.
+	// Defaults to the lengths of strings and byte slices of the key and the value. Other types are counted as zero bytes,
+	// so SizeOf must be set to use MaxBytes with them.
+	SizeOf func(key K, value V) int
+	// TTL is the time to live of entries set by Set and loaded by GetOrLoad. Zero means entries don't expire.
+	TTL time.Duration
+	// Now returns the current time used for expirations. Defaults to time.Now.
+	Now func() time.Time
+}
+
+const defaultMaxEntries = 1000
+
+// Stats represents statistics of Cache.
+type Stats struct {
+	Hits      int
+	Misses    int
+	Evictions int
+	// Entries and Bytes are the current number and total size of entries.
+	Entries int
+	Bytes   int
+}
+
+// Cache is an LRU cache of the isolate. It is safe for concurrent use.
+//   - the least recently used entries are evicted when MaxEntries or MaxBytes is exceeded.
+//   - expired entries are removed when they are accessed, or evicted as well as other entries.
+type Cache[K comparable, V any] struct {
+	opts Options[K, V]
+
+	mu      sync.Mutex
+	ll      *list.List
+	entries map[K]*list.Element
+	bytes   int
+	stats   Stats
+	loads   map[K]*loadCall[V]
+}
+
+type entry[K comparable, V any] struct {
+	key     K
+	value   V
+	size    int
+	expires time.Time
+}
+
+type loadCall[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// New returns Cache of the options. If opts is nil, the cache holds up to 1000 entries without TTL.
+func New[K comparable, V any](opts *Options[K, V]) *Cache[K, V] {
+	c := &Cache[K, V]{
+		ll:      list.New(),
+		entries: make(map[K]*list.Element),
+		loads:   make(map[K]*loadCall[V]),
+	}
+	if opts != nil {
+		c.opts = *opts
+	}
+	if c.opts.MaxEntries <= 0 && c.opts.MaxBytes <= 0 {
+		c.opts.MaxEntries = defaultMaxEntries
+	}
+	if c.opts.SizeOf == nil {
+		c.opts.SizeOf = defaultSizeOf[K, V]
+	}
+	if c.opts.Now == nil {
+		c.opts.Now = time.Now
+	}
+	return c
+}
+
+func defaultSizeOf[K comparable, V any](key K, value V) int {
+	return sizeOf(key) + sizeOf(value)
+}
+
+func sizeOf(v any) int {
+	switch v := v.(type) {
+	case string:
+		return len(v)
+	case []byte:
+		return len(v)
+	}
+	return 0
+}
+
+// Get returns the value of the key, and marks it as recently used.
+//   - if the key is not found or expired, returns the zero value and false.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.lookup(key); ok {
+		c.stats.Hits++
+		return e.value, true
+	}
+	c.stats.Misses++
+	var zero V
+	return zero, false
+}
+
+// lookup returns the live entry of the key, and marks it as recently used. Expired entries are removed.
+func (c *Cache[K, V]) lookup(key K) (*entry[K, V], bool) {
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := elem.Value.(*entry[K, V])
+	if !e.expires.IsZero() && !c.opts.Now().Before(e.expires) {
+		c.remove(elem)
+		return nil, false
+	}
+	c.ll.MoveToFront(elem)
+	return e, true
+}
+
+// Set sets the value of the key with TTL of the options.
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.opts.TTL)
+}
+
+// SetWithTTL sets the value of the key, which expires after ttl. Zero ttl means the entry doesn't expire.
+//   - an entry larger than MaxBytes is not stored, and the existing entry of the key is removed.
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	e := &entry[K, V]{key: key, value: value, size: c.opts.SizeOf(key, value)}
+	if ttl > 0 {
+		e.expires = c.opts.Now().Add(ttl)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+	if c.opts.MaxBytes > 0 && e.size > c.opts.MaxBytes {
+		return
+	}
+	c.entries[key] = c.ll.PushFront(e)
+	c.bytes += e.size
+	for c.overflow() {
+		c.remove(c.ll.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *Cache[K, V]) overflow() bool {
+	return (c.opts.MaxEntries > 0 && c.ll.Len() > c.opts.MaxEntries) ||
+		(c.opts.MaxBytes > 0 && c.bytes > c.opts.MaxBytes)
+}
+
+func (c *Cache[K, V]) remove(elem *list.Element) {
+	e := c.ll.Remove(elem).(*entry[K, V])
+	delete(c.entries, e.key)
+	c.bytes -= e.size
+}
+
+// Delete removes the entry of the key.
+func (c *Cache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+}
+
+// Purge removes all entries.
+func (c *Cache[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ll.Init()
+	c.entries = make(map[K]*list.Element)
+	c.bytes = 0
+}
+
+// Stats returns statistics of the cache.
+func (c *Cache[K, V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.stats
+	s.Entries = c.ll.Len()
+	s.Bytes = c.bytes
+	return s
+}
+
+// GetOrLoad returns the cached value of the key, or calls load and caches its value with TTL of the options.
+//   - concurrent loads of the same key are coalesced into a single call of load with ctx of the first caller.
+//     Other callers wait for the result.
+//   - if load returns error, the error is returned to all waiting callers and nothing is cached.
+//   - if load panics, waiting callers get an error, and the next call loads the key again.
+func (c *Cache[K, V]) GetOrLoad(ctx context.Context, key K, load func(ctx context.Context) (V, error)) (V, error) {
+	c.mu.Lock()
+	if e, ok := c.lookup(key); ok {
+		c.stats.Hits++
+		c.mu.Unlock()
+		return e.value, nil
+	}
+	c.stats.Misses++
+	call, ok := c.loads[key]
+	if !ok {
+		call = &loadCall[V]{done: make(chan struct{})}
+		c.loads[key] = call
+		c.mu.Unlock()
+		c.load(ctx, key, call, load)
+		return call.value, call.err
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// load calls load for the call, and releases the waiting callers even if load panics.
+//   - the panic is returned to the waiting callers as an error, and propagated to the caller of load.
+func (c *Cache[K, V]) load(ctx context.Context, key K, call *loadCall[V], load func(ctx context.Context) (V, error)) {
+	defer func() {
+		r := recover()
+		if r != nil {
+			call.err = fmt.Errorf("lru: load of the key panicked: %v", r)
+		}
+		c.mu.Lock()
+		delete(c.loads, key)
+		close(call.done)
+		c.mu.Unlock()
+		if r != nil {
+			panic(r)
+		}
+	}()
+	call.value, call.err = load(ctx)
+	if call.err == nil {
+		c.Set(key, call.value)
+	}
+}
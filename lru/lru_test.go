@@ -0,0 +1,193 @@
+package lru_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/lru"
+	"github.com/syumai/workers/workerstest"
+)
+
+func TestCache(t *testing.T) {
+	now := time.Unix(0, 0)
+	tests := map[string]struct {
+		opts     *lru.Options[string, string]
+		run      func(c *lru.Cache[string, string])
+		wantKeys []string
+		missKeys []string
+	}{
+		"max entries evicts least recently used": {
+			opts: &lru.Options[string, string]{MaxEntries: 2},
+			run: func(c *lru.Cache[string, string]) {
+				c.Set("a", "1")
+				c.Set("b", "2")
+				c.Get("a")
+				c.Set("c", "3")
+			},
+			wantKeys: []string{"a", "c"},
+			missKeys: []string{"b"},
+		},
+		"max bytes": {
+			opts: &lru.Options[string, string]{MaxBytes: 10},
+			run: func(c *lru.Cache[string, string]) {
+				c.Set("a", "1234")
+				c.Set("b", "1234")
+				c.Set("c", "1234")
+			},
+			wantKeys: []string{"b", "c"},
+			missKeys: []string{"a"},
+		},
+		"entry larger than max bytes is not stored": {
+			opts: &lru.Options[string, string]{MaxBytes: 4},
+			run: func(c *lru.Cache[string, string]) {
+				c.Set("a", "1")
+				c.Set("a", "123456")
+			},
+			missKeys: []string{"a"},
+		},
+		"ttl": {
+			opts: &lru.Options[string, string]{TTL: time.Minute, Now: func() time.Time { return now }},
+			run: func(c *lru.Cache[string, string]) {
+				c.Set("a", "1")
+				c.SetWithTTL("b", "2", 2*time.Minute)
+				c.SetWithTTL("c", "3", 0)
+				now = now.Add(time.Minute)
+			},
+			wantKeys: []string{"b", "c"},
+			missKeys: []string{"a"},
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			c := lru.New(tc.opts)
+			tc.run(c)
+			for _, key := range tc.wantKeys {
+				if _, ok := c.Get(key); !ok {
+					t.Errorf("want %s to be cached", key)
+				}
+			}
+			for _, key := range tc.missKeys {
+				if v, ok := c.Get(key); ok {
+					t.Errorf("want %s to be missing, got %q", key, v)
+				}
+			}
+		})
+	}
+}
+
+func TestCache_GetOrLoad(t *testing.T) {
+	c := lru.New[string, int](nil)
+	var calls atomic.Int32
+	release := make(chan struct{})
+	load := func(ctx context.Context) (int, error) {
+		calls.Add(1)
+		<-release
+		return 42, nil
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := c.GetOrLoad(context.Background(), "k", load)
+			if err != nil || v != 42 {
+				t.Errorf("want 42, got %d, %v", v, err)
+			}
+		}()
+	}
+	// waits for all callers to miss, so their loads are coalesced.
+	for c.Stats().Misses < 5 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	if got := calls.Load(); got != 1 {
+		t.Errorf("want 1 load, got %d", got)
+	}
+	if v, err := c.GetOrLoad(context.Background(), "k", load); err != nil || v != 42 {
+		t.Errorf("want cached 42, got %d, %v", v, err)
+	}
+	if s := c.Stats(); s.Hits != 1 || s.Entries != 1 {
+		t.Errorf("want 1 hit and 1 entry, got %+v", s)
+	}
+}
+
+func TestCache_GetOrLoadPanic(t *testing.T) {
+	c := lru.New[string, int](nil)
+	release := make(chan struct{})
+	panicked := make(chan any, 1)
+	go func() {
+		defer func() { panicked <- recover() }()
+		c.GetOrLoad(context.Background(), "k", func(ctx context.Context) (int, error) {
+			<-release
+			panic("boom")
+		})
+	}()
+	for c.Stats().Misses < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	waiter := make(chan error, 1)
+	go func() {
+		_, err := c.GetOrLoad(context.Background(), "k", func(ctx context.Context) (int, error) {
+			return 0, errors.New("want the load to be coalesced")
+		})
+		waiter <- err
+	}()
+	for c.Stats().Misses < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	if r := <-panicked; r != "boom" {
+		t.Errorf("want the panic to be propagated to the loading caller, got %v", r)
+	}
+	if err := <-waiter; err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("want the panic as an error of the waiting caller, got %v", err)
+	}
+	// the key is not stuck by the panicked load.
+	if v, err := c.GetOrLoad(context.Background(), "k", func(ctx context.Context) (int, error) { return 42, nil }); err != nil || v != 42 {
+		t.Errorf("want 42, got %d, %v", v, err)
+	}
+}
+
+type config struct {
+	Theme string `json:"theme"`
+}
+
+func TestGetKVJSON(t *testing.T) {
+	var reads atomic.Int32
+	kv := &workerstest.KVNamespace{}
+	if err := kv.PutString("config:a", `{"theme":"dark"}`, nil); err != nil {
+		t.Fatal(err)
+	}
+	counted := &workerstest.KVNamespace{
+		GetWithMetadataFunc: func(key string, opts *cloudflare.KVNamespaceGetOptions) (*cloudflare.KVNamespaceValueWithMetadata, error) {
+			reads.Add(1)
+			return kv.GetWithMetadata(key, opts)
+		},
+	}
+	c := lru.New(&lru.Options[string, *config]{TTL: time.Minute})
+	for i := 0; i < 3; i++ {
+		cfg, err := lru.GetKVJSON(context.Background(), c, counted, "config:a", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg == nil || cfg.Theme != "dark" {
+			t.Fatalf("want dark theme, got %+v", cfg)
+		}
+		missing, err := lru.GetKVJSON(context.Background(), c, counted, "config:b", nil)
+		if err != nil || missing != nil {
+			t.Fatalf("want nil for missing key, got %+v, %v", missing, err)
+		}
+	}
+	if got := reads.Load(); got != 2 {
+		t.Errorf("want 2 reads of KV, got %d", got)
+	}
+}
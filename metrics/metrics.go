@@ -0,0 +1,201 @@
+package metrics
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/syumai/workers/cloudflare"
+)
+
+// Labels is a set of label names and values of a metric.
+// Label values are written as blobs ordered by label names, so the same label names should be used for a metric.
+type Labels map[string]string
+
+// Kind is a kind of metric.
+type Kind string
+
+const (
+	KindCounter   Kind = "counter"
+	KindGauge     Kind = "gauge"
+	KindHistogram Kind = "histogram"
+)
+
+// Analytics Engine limits.
+//   - https://developers.cloudflare.com/analytics/analytics-engine/limits/
+const (
+	maxBlobs          = 20
+	maxDataPointsSent = 250
+)
+
+// Registry collects metrics of a request and writes them to Analytics Engine on Flush.
+// Registry is safe for concurrent use.
+type Registry struct {
+	mu      sync.Mutex
+	metrics map[string]*metric
+	order   []string
+}
+
+// NewRegistry returns a new Registry.
+func NewRegistry() *Registry {
+	return &Registry{metrics: map[string]*metric{}}
+}
+
+type metric struct {
+	name   string
+	kind   Kind
+	labels Labels
+	// value is the sum for counters and the last value for gauges.
+	value float64
+	// count, sum, min and max are statistics of histogram observations.
+	count, sum, min, max float64
+}
+
+// metricKey returns the key identifying the metric and its labels.
+func metricKey(name string, kind Kind, labels Labels) string {
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteByte(0)
+	b.WriteString(string(kind))
+	for _, k := range sortedKeys(labels) {
+		b.WriteByte(0)
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(labels[k])
+	}
+	return b.String()
+}
+
+func sortedKeys(labels Labels) []string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (r *Registry) get(name string, kind Kind, labels Labels) *metric {
+	key := metricKey(name, kind, labels)
+	m, ok := r.metrics[key]
+	if !ok {
+		m = &metric{name: name, kind: kind, labels: labels, min: math.Inf(1), max: math.Inf(-1)}
+		r.metrics[key] = m
+		r.order = append(r.order, key)
+	}
+	return m
+}
+
+// Add adds delta to the counter.
+func (r *Registry) Add(name string, labels Labels, delta float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.get(name, KindCounter, labels).value += delta
+}
+
+// Set sets the value of the gauge.
+func (r *Registry) Set(name string, labels Labels, value float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.get(name, KindGauge, labels).value = value
+}
+
+// Observe records the value to the histogram.
+func (r *Registry) Observe(name string, labels Labels, value float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m := r.get(name, KindHistogram, labels)
+	m.count++
+	m.sum += value
+	m.min = math.Min(m.min, value)
+	m.max = math.Max(m.max, value)
+}
+
+// DataPoints converts collected metrics to Analytics Engine data points, and resets the Registry.
+//   - index1 is the metric name.
+//   - blob1 is the metric name, blob2 is the kind, and blob3 and later are label values ordered by label names.
+//   - counters and gauges have the value in double1.
+//   - histograms have count, sum, min and max in double1 to double4.
+func (r *Registry) DataPoints() []*cloudflare.AnalyticsEngineDataPoint {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	points := make([]*cloudflare.AnalyticsEngineDataPoint, 0, len(r.order))
+	for _, key := range r.order {
+		m := r.metrics[key]
+		blobs := []string{m.name, string(m.kind)}
+		for _, k := range sortedKeys(m.labels) {
+			if len(blobs) == maxBlobs {
+				break
+			}
+			blobs = append(blobs, m.labels[k])
+		}
+		var doubles []float64
+		if m.kind == KindHistogram {
+			doubles = []float64{m.count, m.sum, m.min, m.max}
+		} else {
+			doubles = []float64{m.value}
+		}
+		points = append(points, &cloudflare.AnalyticsEngineDataPoint{
+			Indexes: []string{m.name},
+			Blobs:   blobs,
+			Doubles: doubles,
+		})
+	}
+	r.metrics = map[string]*metric{}
+	r.order = nil
+	return points
+}
+
+// Flush writes collected metrics to the dataset, and resets the Registry.
+//   - Data points exceeding the limit per invocation are dropped.
+func (r *Registry) Flush(dataset cloudflare.AnalyticsEngineDatasetBinding) {
+	points := r.DataPoints()
+	if len(points) > maxDataPointsSent {
+		points = points[:maxDataPointsSent]
+	}
+	for _, p := range points {
+		dataset.WriteDataPoint(p)
+	}
+}
+
+type registryKey struct{}
+
+// NewContext returns a context holding the Registry.
+func NewContext(ctx context.Context, r *Registry) context.Context {
+	return context.WithValue(ctx, registryKey{}, r)
+}
+
+// FromContext returns the Registry held by the context.
+//   - if the context doesn't hold a Registry, returns nil. Package level functions such as IncCounter do nothing in that case.
+func FromContext(ctx context.Context) *Registry {
+	r, _ := ctx.Value(registryKey{}).(*Registry)
+	return r
+}
+
+// IncCounter adds 1 to the counter of the Registry held by the context.
+func IncCounter(ctx context.Context, name string, labels Labels) {
+	AddCounter(ctx, name, labels, 1)
+}
+
+// AddCounter adds delta to the counter of the Registry held by the context.
+func AddCounter(ctx context.Context, name string, labels Labels, delta float64) {
+	if r := FromContext(ctx); r != nil {
+		r.Add(name, labels, delta)
+	}
+}
+
+// SetGauge sets the value of the gauge of the Registry held by the context.
+func SetGauge(ctx context.Context, name string, labels Labels, value float64) {
+	if r := FromContext(ctx); r != nil {
+		r.Set(name, labels, value)
+	}
+}
+
+// Observe records the value to the histogram of the Registry held by the context.
+func Observe(ctx context.Context, name string, labels Labels, value float64) {
+	if r := FromContext(ctx); r != nil {
+		r.Observe(name, labels, value)
+	}
+}
@@ -0,0 +1,130 @@
+package metrics_test
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/metrics"
+	"github.com/syumai/workers/workerstest"
+)
+
+func TestRegistry_DataPoints(t *testing.T) {
+	tests := map[string]struct {
+		record func(r *metrics.Registry)
+		want   []*cloudflare.AnalyticsEngineDataPoint
+	}{
+		"counter": {
+			record: func(r *metrics.Registry) {
+				r.Add("hits", metrics.Labels{"route": "/a", "method": "GET"}, 1)
+				r.Add("hits", metrics.Labels{"method": "GET", "route": "/a"}, 2)
+				r.Add("hits", metrics.Labels{"route": "/b", "method": "GET"}, 1)
+			},
+			want: []*cloudflare.AnalyticsEngineDataPoint{
+				{Indexes: []string{"hits"}, Blobs: []string{"hits", "counter", "GET", "/a"}, Doubles: []float64{3}},
+				{Indexes: []string{"hits"}, Blobs: []string{"hits", "counter", "GET", "/b"}, Doubles: []float64{1}},
+			},
+		},
+		"gauge": {
+			record: func(r *metrics.Registry) {
+				r.Set("queue", nil, 5)
+				r.Set("queue", nil, 3)
+			},
+			want: []*cloudflare.AnalyticsEngineDataPoint{
+				{Indexes: []string{"queue"}, Blobs: []string{"queue", "gauge"}, Doubles: []float64{3}},
+			},
+		},
+		"histogram": {
+			record: func(r *metrics.Registry) {
+				r.Observe("latency", nil, 20)
+				r.Observe("latency", nil, 5)
+				r.Observe("latency", nil, 11)
+			},
+			want: []*cloudflare.AnalyticsEngineDataPoint{
+				{Indexes: []string{"latency"}, Blobs: []string{"latency", "histogram"}, Doubles: []float64{3, 36, 5, 20}},
+			},
+		},
+		"same name of different kinds": {
+			record: func(r *metrics.Registry) {
+				r.Add("m", nil, 1)
+				r.Set("m", nil, 2)
+			},
+			want: []*cloudflare.AnalyticsEngineDataPoint{
+				{Indexes: []string{"m"}, Blobs: []string{"m", "counter"}, Doubles: []float64{1}},
+				{Indexes: []string{"m"}, Blobs: []string{"m", "gauge"}, Doubles: []float64{2}},
+			},
+		},
+		"too many labels": {
+			record: func(r *metrics.Registry) {
+				labels := metrics.Labels{}
+				for c := 'a'; c <= 'z'; c++ {
+					labels[string(c)] = string(c)
+				}
+				r.Add("m", labels, 1)
+			},
+			want: []*cloudflare.AnalyticsEngineDataPoint{
+				{
+					Indexes: []string{"m"},
+					Blobs:   []string{"m", "counter", "a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p", "q", "r"},
+					Doubles: []float64{1},
+				},
+			},
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			r := metrics.NewRegistry()
+			tc.record(r)
+			if got := r.DataPoints(); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("want %+v, got %+v", tc.want, got)
+			}
+			if got := r.DataPoints(); len(got) != 0 {
+				t.Errorf("want the Registry reset, got %+v", got)
+			}
+		})
+	}
+}
+
+func TestRegistry_Flush(t *testing.T) {
+	t.Parallel()
+	r := metrics.NewRegistry()
+	for i := 0; i < 300; i++ {
+		r.Set("m", metrics.Labels{"i": strconv.Itoa(i)}, float64(i))
+	}
+	dataset := &workerstest.AnalyticsEngineDataset{}
+	r.Flush(dataset)
+	if got := len(dataset.DataPoints()); got != 250 {
+		t.Errorf("want data points limited to 250, got %d", got)
+	}
+}
+
+func TestContext(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	// package level functions do nothing without a Registry.
+	metrics.IncCounter(ctx, "m", nil)
+	if metrics.FromContext(ctx) != nil {
+		t.Error("want no Registry")
+	}
+
+	r := metrics.NewRegistry()
+	ctx = metrics.NewContext(ctx, r)
+	metrics.IncCounter(ctx, "c", nil)
+	metrics.AddCounter(ctx, "c", nil, 2)
+	metrics.SetGauge(ctx, "g", nil, 4)
+	metrics.Observe(ctx, "h", nil, math.Pi)
+	want := []*cloudflare.AnalyticsEngineDataPoint{
+		{Indexes: []string{"c"}, Blobs: []string{"c", "counter"}, Doubles: []float64{3}},
+		{Indexes: []string{"g"}, Blobs: []string{"g", "gauge"}, Doubles: []float64{4}},
+		{Indexes: []string{"h"}, Blobs: []string{"h", "histogram"}, Doubles: []float64{1, math.Pi, math.Pi, math.Pi}},
+	}
+	if got := r.DataPoints(); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
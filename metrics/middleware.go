@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/syumai/workers"
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/internal/httputil"
+	"github.com/syumai/workers/timing"
+)
+
+// MiddlewareOptions represents options of Middleware.
+type MiddlewareOptions struct {
+	// Dataset is a name of the Analytics Engine dataset binding.
+	Dataset string
+	// DurationMetric is a name of the histogram of request durations in milliseconds.
+	// Defaults to "http_request_duration_ms".
+	DurationMetric string
+	// CPUTimeMetric is a name of the histogram of CPU time of requests in milliseconds, measured by timing.Stopwatch.
+	// It is recorded only if the runtime reports CPU time. Defaults to "http_request_cpu_ms".
+	CPUTimeMetric string
+}
+
+// Middleware returns a middleware injecting a Registry into the request context,
+// recording request durations and CPU time per route, and flushing metrics to Analytics Engine after the response by waitUntil.
+//   - The duration histogram has method, route and status labels. The route label is workers.RoutePattern, or "unmatched" if no route is matched.
+//   - Register this middleware by Router.Use to label the durations by route patterns.
+//   - This function panics when opts.Dataset is empty.
+func Middleware(opts *MiddlewareOptions) workers.Middleware {
+	if opts == nil || opts.Dataset == "" {
+		panic("metrics: MiddlewareOptions.Dataset must be set")
+	}
+	durationMetric := opts.DurationMetric
+	if durationMetric == "" {
+		durationMetric = "http_request_duration_ms"
+	}
+	cpuTimeMetric := opts.CPUTimeMetric
+	if cpuTimeMetric == "" {
+		cpuTimeMetric = "http_request_cpu_ms"
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			r := NewRegistry()
+			req = req.WithContext(NewContext(req.Context(), r))
+			sw := httputil.NewStatusWriter(w)
+			watch := timing.Start()
+			next.ServeHTTP(sw, req)
+			route := workers.RoutePattern(req)
+			if route == "" {
+				// paths are not used as labels, so that requests of arbitrary paths don't grow the cardinality.
+				route = "unmatched"
+			}
+			labels := Labels{
+				"method": req.Method,
+				"route":  route,
+				"status": strconv.Itoa(sw.Status),
+			}
+			r.Observe(durationMetric, labels, millis(watch.Wall()))
+			if cpu, ok := watch.CPU(); ok {
+				r.Observe(cpuTimeMetric, labels, millis(cpu))
+			}
+			cloudflare.WaitUntil(req.Context(), func(ctx context.Context) {
+				dataset, err := cloudflare.NewAnalyticsEngineDataset(ctx, opts.Dataset)
+				if err != nil {
+					return
+				}
+				r.Flush(dataset)
+			})
+		})
+	}
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
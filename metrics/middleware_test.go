@@ -0,0 +1,68 @@
+//go:build js && wasm
+
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/syumai/workers"
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/runtimecontext"
+	"github.com/syumai/workers/metrics"
+)
+
+func TestMiddleware(t *testing.T) {
+	points := js.Global().Get("Array").New()
+	dataset := js.Global().Get("Object").New()
+	dataset.Set("writeDataPoint", js.FuncOf(func(_ js.Value, args []js.Value) any {
+		points.Call("push", js.Global().Get("JSON").Call("stringify", args[0].Get("blobs")))
+		return js.Undefined()
+	}))
+	exCtx := js.Global().Get("Object").New()
+	exCtx.Set("waitUntil", js.FuncOf(func(js.Value, []js.Value) any { return js.Undefined() }))
+	runtimeCtxObj := js.Global().Get("Object").New()
+	runtimeCtxObj.Set("ctx", exCtx)
+	runtimeCtxObj.Set("env", js.ValueOf(map[string]any{"METRICS": dataset}))
+
+	r := workers.NewRouter()
+	r.Use(metrics.Middleware(&metrics.MiddlewareOptions{Dataset: "METRICS"}))
+	r.GET("/users/:id", func(w http.ResponseWriter, req *http.Request) {
+		metrics.IncCounter(req.Context(), "user_views", metrics.Labels{"id": workers.PathParam(req, "id")})
+		w.WriteHeader(http.StatusAccepted)
+	})
+	for _, path := range []string{"/users/1", "/unknown"} {
+		ctx, settle := runtimecontext.NewEvent(js.Undefined(), runtimeCtxObj)
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil).WithContext(ctx))
+		settle()
+	}
+	runtimecontext.Wait()
+
+	var got []string
+	for i := 0; i < points.Length(); i++ {
+		// CPU time is recorded only if the runtime reports it.
+		if p := points.Index(i).String(); !strings.HasPrefix(p, `["http_request_cpu_ms"`) {
+			got = append(got, p)
+		}
+	}
+	want := []string{
+		`["user_views","counter","1"]`,
+		`["http_request_duration_ms","histogram","GET","/users/:id","202"]`,
+		`["http_request_duration_ms","histogram","GET","unmatched","404"]`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestMiddleware_NoDataset(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("want panic for no dataset")
+		}
+	}()
+	metrics.Middleware(&metrics.MiddlewareOptions{})
+}
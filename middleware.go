@@ -0,0 +1,25 @@
+package workers
+
+import "net/http"
+
+// Middleware wraps http.Handler to add cross-cutting behavior such as authentication, logging, or caching.
+type Middleware func(http.Handler) http.Handler
+
+// Chain composes the given middlewares into a single Middleware.
+//   - The first middleware becomes the outermost one, so it handles the request first.
+func Chain(mws ...Middleware) Middleware {
+	return func(h http.Handler) http.Handler {
+		for i := len(mws) - 1; i >= 0; i-- {
+			h = mws[i](h)
+		}
+		return h
+	}
+}
+
+var globalMiddlewares []Middleware
+
+// Use registers middlewares applied to the handler given to Serve.
+//   - This function must be called before Serve.
+func Use(mws ...Middleware) {
+	globalMiddlewares = append(globalMiddlewares, mws...)
+}
@@ -0,0 +1,289 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/syumai/workers"
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/cloudflare/fetch"
+	"github.com/syumai/workers/jwt"
+)
+
+// AccessOptions represents options of the CloudflareAccess middleware.
+//   - https://developers.cloudflare.com/cloudflare-one/identity/authorization-cookie/validating-json/
+type AccessOptions struct {
+	// TeamDomain is a domain of the Cloudflare Access team (e.g. "myteam.cloudflareaccess.com").
+	TeamDomain string
+	// Audience is an Application Audience (AUD) tag of the Access application.
+	Audience string
+	// CertsKVBinding is a name of KV namespace binding used to cache the signing keys across isolates.
+	// If empty, the keys are cached only in memory of the isolate.
+	CertsKVBinding string
+	// CertsCacheTTL is seconds of how long the signing keys are cached. Defaults to 3600.
+	CertsCacheTTL int
+}
+
+// AccessIdentity represents the identity verified by the CloudflareAccess middleware.
+type AccessIdentity struct {
+	Email   string
+	Subject string
+	Country string
+	// Claims holds all claims of the verified JWT.
+	Claims map[string]any
+}
+
+type accessIdentityKey struct{}
+
+// AccessIdentityFromContext returns the identity verified by the CloudflareAccess middleware.
+func AccessIdentityFromContext(ctx context.Context) (*AccessIdentity, bool) {
+	id, ok := ctx.Value(accessIdentityKey{}).(*AccessIdentity)
+	return id, ok
+}
+
+// CloudflareAccess returns a middleware validating the JWT issued by Cloudflare Access.
+//   - The JWT is read from Cf-Access-Jwt-Assertion header, or CF_Authorization cookie as a fallback.
+//   - The verified identity can be retrieved by AccessIdentityFromContext.
+//   - if the JWT is missing or invalid, responds with status 403.
+//   - This function panics when TeamDomain or Audience is empty.
+func CloudflareAccess(opts *AccessOptions) workers.Middleware {
+	if opts == nil || opts.TeamDomain == "" || opts.Audience == "" {
+		panic("middleware: AccessOptions.TeamDomain and AccessOptions.Audience must be set")
+	}
+	ttl := opts.CertsCacheTTL
+	if ttl <= 0 {
+		ttl = 3600
+	}
+	teamDomain := strings.TrimSuffix(strings.TrimPrefix(opts.TeamDomain, "https://"), "/")
+	v := &accessVerifier{
+		issuer:    "https://" + teamDomain,
+		audience:  opts.Audience,
+		certsURL:  "https://" + teamDomain + "/cdn-cgi/access/certs",
+		kvBinding: opts.CertsKVBinding,
+		ttl:       time.Duration(ttl) * time.Second,
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			token := req.Header.Get("Cf-Access-Jwt-Assertion")
+			if token == "" {
+				if c, err := req.Cookie("CF_Authorization"); err == nil {
+					token = c.Value
+				}
+			}
+			if token == "" {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			id, err := v.verify(req.Context(), token)
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), accessIdentityKey{}, id)))
+		})
+	}
+}
+
+type accessVerifier struct {
+	issuer    string
+	audience  string
+	certsURL  string
+	kvBinding string
+	ttl       time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]*jwt.Key
+	expiresAt time.Time
+	// loadedAt is the time of the last load of the keys, limiting reloads for unknown kids.
+	loadedAt time.Time
+	// load is the load of the keys in flight, shared by concurrent requests.
+	load *keysLoad
+}
+
+// minKeysReloadInterval is the minimum interval of reloading the keys for an unknown kid,
+// so that tokens with arbitrary kids can't cause subrequests and KV operations on every request.
+const minKeysReloadInterval = time.Minute
+
+type keysLoad struct {
+	done chan struct{}
+	err  error
+}
+
+func (v *accessVerifier) verify(ctx context.Context, token string) (*AccessIdentity, error) {
+	t, err := jwt.Parse(token)
+	if err != nil {
+		return nil, err
+	}
+	if t.Header.Algorithm != jwt.RS256 {
+		return nil, fmt.Errorf("unsupported JWT algorithm: %s", t.Header.Algorithm)
+	}
+	key, err := v.key(ctx, t.Header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	t, err = jwt.Verify(token, key, &jwt.VerifyOptions{
+		Issuer:            v.issuer,
+		Audience:          v.audience,
+		RequireExpiration: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	claims := t.Claims
+	id := &AccessIdentity{Claims: claims}
+	id.Email, _ = claims["email"].(string)
+	id.Subject, _ = claims["sub"].(string)
+	id.Country, _ = claims["country"].(string)
+	return id, nil
+}
+
+// key returns the signing key for kid. Keys are reloaded when kid is unknown or the cache is expired.
+//   - reloads for unknown kids are limited to once per minKeysReloadInterval.
+//   - concurrent reloads are coalesced into one, and run without holding the lock.
+func (v *accessVerifier) key(ctx context.Context, kid string) (*jwt.Key, error) {
+	now := time.Now()
+	v.mu.Lock()
+	key, ok := v.keys[kid]
+	switch {
+	case ok && now.Before(v.expiresAt):
+		v.mu.Unlock()
+		return key, nil
+	case !ok && now.Before(v.expiresAt) && now.Sub(v.loadedAt) < minKeysReloadInterval:
+		v.mu.Unlock()
+		return nil, fmt.Errorf("signing key not found: %s", kid)
+	}
+	load := v.load
+	if load == nil {
+		load = &keysLoad{done: make(chan struct{})}
+		v.load = load
+		v.loadedAt = now
+		v.mu.Unlock()
+		v.reload(ctx, kid, load)
+	} else {
+		v.mu.Unlock()
+	}
+
+	select {
+	case <-load.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if load.err != nil {
+		return nil, load.err
+	}
+	v.mu.Lock()
+	key, ok = v.keys[kid]
+	v.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("signing key not found: %s", kid)
+	}
+	return key, nil
+}
+
+func (v *accessVerifier) reload(ctx context.Context, kid string, load *keysLoad) {
+	defer func() {
+		v.mu.Lock()
+		v.load = nil
+		v.mu.Unlock()
+		close(load.done)
+	}()
+	keys, err := v.loadKeys(ctx, kid)
+	if err != nil {
+		load.err = err
+		return
+	}
+	v.mu.Lock()
+	v.keys = keys
+	v.expiresAt = time.Now().Add(v.ttl)
+	v.mu.Unlock()
+}
+
+type jwks struct {
+	Keys []map[string]any `json:"keys"`
+}
+
+func (v *accessVerifier) loadKeys(ctx context.Context, kid string) (map[string]*jwt.Key, error) {
+	var kv *cloudflare.KVNamespace
+	var cached string
+	if v.kvBinding != "" {
+		var err error
+		kv, err = cloudflare.NewKVNamespace(ctx, v.kvBinding)
+		if err != nil {
+			return nil, err
+		}
+		if cached, err = kv.GetString(v.certsURL, nil); err == nil {
+			if keys, err := importJWKS([]byte(cached)); err == nil {
+				if _, ok := keys[kid]; ok {
+					return keys, nil
+				}
+			}
+		}
+	}
+	body, err := v.fetchCerts()
+	if err != nil {
+		return nil, err
+	}
+	keys, err := importJWKS(body)
+	if err != nil {
+		return nil, err
+	}
+	if kv != nil && string(body) != cached {
+		// caching failure is not fatal.
+		_ = kv.PutString(v.certsURL, string(body), &cloudflare.KVNamespacePutOptions{
+			ExpirationTTL: int(v.ttl / time.Second),
+		})
+	}
+	return keys, nil
+}
+
+func (v *accessVerifier) fetchCerts() ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, v.certsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := fetch.NewClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch Access certs: %s", res.Status)
+	}
+	return io.ReadAll(res.Body)
+}
+
+// importJWKS imports RSA keys of JSON Web Key Set.
+func importJWKS(b []byte) (map[string]*jwt.Key, error) {
+	var set jwks
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]*jwt.Key, len(set.Keys))
+	for _, jwk := range set.Keys {
+		kid, _ := jwk["kid"].(string)
+		if kty, _ := jwk["kty"].(string); kty != "RSA" || kid == "" {
+			continue
+		}
+		jwkJSON, err := json.Marshal(map[string]any{
+			"kty": "RSA",
+			"kid": kid,
+			"n":   jwk["n"],
+			"e":   jwk["e"],
+		})
+		if err != nil {
+			return nil, err
+		}
+		key, err := jwt.ImportJWK(jwkJSON)
+		if err != nil {
+			return nil, fmt.Errorf("error importing key %s: %w", kid, err)
+		}
+		keys[kid] = key
+	}
+	return keys, nil
+}
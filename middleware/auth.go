@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/syumai/workers"
+	"github.com/syumai/workers/secret"
+)
+
+// BasicAuthOptions represents options of the BasicAuth middleware.
+type BasicAuthOptions struct {
+	// Realm is a realm sent in WWW-Authenticate header. Defaults to "Restricted".
+	Realm string
+	// Credentials maps user names to passwords.
+	Credentials map[string]string
+	// UserEnv and PasswordEnv are names of the environment variables (secrets) holding the credentials.
+	//   - These are read on every request, so secrets can be rotated without rebuilding the worker.
+	//   - https://developers.cloudflare.com/workers/platform/environment-variables/#adding-secrets-via-wrangler
+	UserEnv     string
+	PasswordEnv string
+}
+
+// BasicAuth returns a middleware which requires HTTP Basic authentication.
+//   - credentials are compared in constant time.
+//   - if the credentials are missing or invalid, responds with status 401.
+//   - This middleware panics when a runtime context is not found and env variables are used.
+func BasicAuth(opts *BasicAuthOptions) workers.Middleware {
+	if opts == nil {
+		opts = &BasicAuthOptions{}
+	}
+	realm := opts.Realm
+	if realm == "" {
+		realm = "Restricted"
+	}
+	challenge := fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"", realm)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			user, pass, ok := req.BasicAuth()
+			if !ok || !opts.validate(req, user, pass) {
+				w.Header().Set("WWW-Authenticate", challenge)
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+func (opts *BasicAuthOptions) validate(req *http.Request, user, pass string) bool {
+	valid := false
+	for u, p := range opts.Credentials {
+		// every credential is compared, and the password is compared even if the user doesn't match,
+		// to avoid leaking which user exists by timing.
+		userMatch := secret.EqualString(u, user)
+		passMatch := secret.EqualString(p, pass)
+		if userMatch && passMatch {
+			valid = true
+		}
+	}
+	if opts.UserEnv != "" && opts.PasswordEnv != "" {
+		envUser, userOK := secret.Lookup(req.Context(), opts.UserEnv)
+		envPass, passOK := secret.Lookup(req.Context(), opts.PasswordEnv)
+		if userOK && passOK {
+			userMatch := envUser.Equal(user)
+			passMatch := envPass.Equal(pass)
+			if userMatch && passMatch {
+				valid = true
+			}
+		}
+	}
+	return valid
+}
+
+var (
+	// ErrInvalidToken indicates that the bearer token is invalid. The BearerAuth middleware responds with status 401.
+	ErrInvalidToken = errors.New("middleware: invalid token")
+	// ErrInsufficientScope indicates that the bearer token doesn't have the required permission. The BearerAuth middleware responds with status 403.
+	ErrInsufficientScope = errors.New("middleware: insufficient scope")
+)
+
+// BearerAuthOptions represents options of the BearerAuth middleware.
+//   - https://datatracker.ietf.org/doc/html/rfc6750
+type BearerAuthOptions struct {
+	// Realm is a realm sent in WWW-Authenticate header. Defaults to "Restricted".
+	Realm string
+	// Validate validates the token given in Authorization header.
+	// The returned request is passed to the next handler, so identity can be injected into its context.
+	//   - returning ErrInsufficientScope responds with status 403.
+	//   - returning other errors responds with status 401.
+	Validate func(req *http.Request, token string) (*http.Request, error)
+}
+
+// BearerAuth returns a middleware which requires Bearer token authentication.
+//   - This function panics when opts.Validate is nil.
+func BearerAuth(opts *BearerAuthOptions) workers.Middleware {
+	if opts == nil || opts.Validate == nil {
+		panic("middleware: BearerAuthOptions.Validate must be set")
+	}
+	realm := opts.Realm
+	if realm == "" {
+		realm = "Restricted"
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			token, ok := bearerToken(req)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q", realm))
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			authReq, err := opts.Validate(req, token)
+			if errors.Is(err, ErrInsufficientScope) {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q, error=\"insufficient_scope\"", realm))
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q, error=\"invalid_token\"", realm))
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			if authReq == nil {
+				authReq = req
+			}
+			next.ServeHTTP(w, authReq)
+		})
+	}
+}
+
+// bearerToken extracts the token from Authorization header.
+func bearerToken(req *http.Request) (string, bool) {
+	scheme, token, found := strings.Cut(req.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// StaticBearerTokenValidator returns a validator for BearerAuthOptions which accepts
+// the token stored in the environment variable (secret) named envName.
+//   - tokens are compared in constant time.
+func StaticBearerTokenValidator(envName string) func(req *http.Request, token string) (*http.Request, error) {
+	return func(req *http.Request, token string) (*http.Request, error) {
+		want, ok := secret.Lookup(req.Context(), envName)
+		if !ok || !want.Equal(token) {
+			return nil, ErrInvalidToken
+		}
+		return req, nil
+	}
+}
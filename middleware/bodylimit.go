@@ -0,0 +1,219 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/syumai/workers"
+)
+
+// BodyLimitOptions represents options of the BodyLimit middleware.
+type BodyLimitOptions struct {
+	// MaxBytes is the maximum size of request bodies in bytes. Defaults to 10 MiB.
+	MaxBytes int64
+	// ReadTimeout is the time limit to read the whole body since the request is received. Zero disables it.
+	ReadTimeout time.Duration
+	// IdleTimeout is the time limit of each read of the body, detecting stalled uploads. Zero disables it.
+	IdleTimeout time.Duration
+}
+
+const defaultMaxBodyBytes = 10 << 20
+
+// ErrBodyReadTimeout is returned by reads of request bodies exceeding ReadTimeout or IdleTimeout of BodyLimit.
+var ErrBodyReadTimeout = errors.New("middleware: request body read timed out")
+
+// errResponseAborted is returned by writes of responses aborted by BodyLimit.
+var errResponseAborted = errors.New("middleware: response is aborted")
+
+// BodyLimit returns a middleware limiting the size of request bodies, and the time to read them.
+//   - requests whose Content-Length exceeds MaxBytes are rejected with status 413 without calling the next handler.
+//   - bodies are counted while they are streamed. Once MaxBytes is exceeded, reads return *http.MaxBytesError,
+//     and the response is aborted with status 413.
+//   - once a timeout is exceeded, the body is closed, reads return ErrBodyReadTimeout, and the response is aborted with status 408.
+//     Closing a request body of the runtime cancels its stream, so the pending read is finished and no data is buffered any more.
+//   - aborted responses can't be overwritten by the next handler. If the header has already been written, it is kept as it is.
+func BodyLimit(opts *BodyLimitOptions) workers.Middleware {
+	if opts == nil {
+		opts = &BodyLimitOptions{}
+	}
+	maxBytes := opts.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxBodyBytes
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.ContentLength > maxBytes {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+			if req.Body == nil || req.Body == http.NoBody {
+				next.ServeHTTP(w, req)
+				return
+			}
+			aw := &abortWriter{ResponseWriter: w}
+			body := &limitedBody{
+				body:      req.Body,
+				remaining: maxBytes,
+				maxBytes:  maxBytes,
+				idle:      opts.IdleTimeout,
+				abort:     aw.abort,
+			}
+			if opts.ReadTimeout > 0 {
+				body.deadline = time.Now().Add(opts.ReadTimeout)
+			}
+			r := req.Clone(req.Context())
+			r.Body = body
+			next.ServeHTTP(aw, r)
+		})
+	}
+}
+
+// limitedBody counts bytes of the body, and limits the time to read it.
+type limitedBody struct {
+	body      io.ReadCloser
+	remaining int64
+	maxBytes  int64
+	idle      time.Duration
+	deadline  time.Time
+	// abort aborts the response with the status.
+	abort func(status int)
+	err   error
+}
+
+type readResult struct {
+	b   []byte
+	err error
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	// reads one more byte than remaining to detect bodies exceeding the limit.
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.timedRead(p)
+	if int64(n) > l.remaining {
+		n = int(l.remaining)
+		l.remaining = 0
+		l.err = &http.MaxBytesError{Limit: l.maxBytes}
+		l.abort(http.StatusRequestEntityTooLarge)
+		return n, l.err
+	}
+	l.remaining -= int64(n)
+	if errors.Is(err, ErrBodyReadTimeout) {
+		l.err = err
+		l.body.Close()
+		l.abort(http.StatusRequestTimeout)
+	}
+	return n, err
+}
+
+// timedRead reads the body within the timeouts.
+//   - a read of the body can't be interrupted, so it is done in a goroutine reading into its own buffer.
+//     The goroutine returns when the body is closed on timeout, and its result is discarded.
+func (l *limitedBody) timedRead(p []byte) (int, error) {
+	timeout := l.idle
+	if !l.deadline.IsZero() {
+		untilDeadline := time.Until(l.deadline)
+		if timeout <= 0 || untilDeadline < timeout {
+			timeout = untilDeadline
+		}
+		if timeout <= 0 {
+			return 0, ErrBodyReadTimeout
+		}
+	}
+	if timeout <= 0 {
+		return l.body.Read(p)
+	}
+	ch := make(chan readResult, 1)
+	go func(size int) {
+		b := make([]byte, size)
+		n, err := l.body.Read(b)
+		ch <- readResult{b: b[:n], err: err}
+	}(len(p))
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-ch:
+		return copy(p, r.b), r.err
+	case <-timer.C:
+		return 0, ErrBodyReadTimeout
+	}
+}
+
+func (l *limitedBody) Close() error {
+	return l.body.Close()
+}
+
+// abortWriter is http.ResponseWriter whose response can be aborted with an error status.
+type abortWriter struct {
+	http.ResponseWriter
+
+	mu          sync.Mutex
+	wroteHeader bool
+	aborted     bool
+}
+
+// abort writes the error response of the status unless the header has already been written,
+// and discards later writes of the handler.
+func (w *abortWriter) abort(status int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.aborted {
+		return
+	}
+	w.aborted = true
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	http.Error(w.ResponseWriter, http.StatusText(status), status)
+}
+
+func (w *abortWriter) WriteHeader(status int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *abortWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	if w.aborted {
+		w.mu.Unlock()
+		return 0, errResponseAborted
+	}
+	w.wroteHeader = true
+	w.mu.Unlock()
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush flushes the underlying ResponseWriter unless the response is aborted.
+func (w *abortWriter) Flush() {
+	w.mu.Lock()
+	if w.aborted {
+		w.mu.Unlock()
+		return
+	}
+	w.wroteHeader = true
+	w.mu.Unlock()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (w *abortWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stalledBody returns the data, and then blocks until it is closed.
+type stalledBody struct {
+	data   io.Reader
+	closed chan struct{}
+}
+
+func (b *stalledBody) Read(p []byte) (int, error) {
+	if n, _ := b.data.Read(p); n > 0 {
+		return n, nil
+	}
+	<-b.closed
+	return 0, io.ErrUnexpectedEOF
+}
+
+func (b *stalledBody) Close() error {
+	select {
+	case <-b.closed:
+	default:
+		close(b.closed)
+	}
+	return nil
+}
+
+func TestBodyLimit(t *testing.T) {
+	tests := map[string]struct {
+		opts          *BodyLimitOptions
+		body          io.Reader
+		contentLength int64
+		wantStatus    int
+		wantErr       error
+		wantRead      int
+	}{
+		"within limit": {
+			opts:          &BodyLimitOptions{MaxBytes: 5},
+			body:          strings.NewReader("hello"),
+			contentLength: -1,
+			wantStatus:    http.StatusOK,
+			wantRead:      5,
+		},
+		"content length exceeds limit": {
+			opts:          &BodyLimitOptions{MaxBytes: 4},
+			body:          strings.NewReader("hello"),
+			contentLength: 5,
+			wantStatus:    http.StatusRequestEntityTooLarge,
+		},
+		"streamed body exceeds limit": {
+			opts:          &BodyLimitOptions{MaxBytes: 4, IdleTimeout: time.Second},
+			body:          strings.NewReader("hello"),
+			contentLength: -1,
+			wantStatus:    http.StatusRequestEntityTooLarge,
+			wantErr:       &http.MaxBytesError{},
+			wantRead:      4,
+		},
+		"stalled body": {
+			opts:          &BodyLimitOptions{IdleTimeout: 100 * time.Millisecond},
+			body:          &stalledBody{data: strings.NewReader("he"), closed: make(chan struct{})},
+			contentLength: -1,
+			wantStatus:    http.StatusRequestTimeout,
+			wantErr:       ErrBodyReadTimeout,
+			wantRead:      2,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var read int
+			var readErr error
+			h := BodyLimit(tc.opts)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				b, err := io.ReadAll(req.Body)
+				read, readErr = len(b), err
+				// the handler's response is discarded if the response is aborted.
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte("ok"))
+			}))
+			req := httptest.NewRequest(http.MethodPost, "/", tc.body)
+			req.ContentLength = tc.contentLength
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tc.wantStatus {
+				t.Errorf("want status %d, got %d", tc.wantStatus, rec.Code)
+			}
+			if read != tc.wantRead {
+				t.Errorf("want %d bytes read, got %d", tc.wantRead, read)
+			}
+			switch want := tc.wantErr.(type) {
+			case nil:
+				if readErr != nil {
+					t.Errorf("want no error, got %v", readErr)
+				}
+			case *http.MaxBytesError:
+				if !errors.As(readErr, &want) {
+					t.Errorf("want *http.MaxBytesError, got %v", readErr)
+				}
+			default:
+				if !errors.Is(readErr, want) {
+					t.Errorf("want %v, got %v", want, readErr)
+				}
+			}
+		})
+	}
+}
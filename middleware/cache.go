@@ -0,0 +1,258 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/syumai/workers"
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/cloudflare/cache"
+)
+
+// CacheOptions represents options of the Cache middleware.
+type CacheOptions struct {
+	// TTL is seconds of how long responses are cached. Defaults to 60.
+	TTL int
+	// Vary is a list of request headers whose values are included in the cache key.
+	// The Cache API doesn't respect Vary header of responses, so the headers must be listed here.
+	Vary []string
+	// KeyFunc returns the URL used as the cache key. Defaults to the request URL.
+	KeyFunc func(req *http.Request) string
+	// Cache is the cache used to store responses. Defaults to `caches.default`.
+	Cache cache.Store
+	// StaleWhileRevalidate is seconds of how long responses are served after TTL expires.
+	// Stale responses are served immediately, and refreshed in the background by waitUntil. Zero disables it.
+	StaleWhileRevalidate int
+	// Now returns the current time used to calculate ages of cached responses. Defaults to time.Now.
+	Now func() time.Time
+	// MaxBodySize is the maximum size of response bodies buffered to be stored. Larger responses are streamed
+	// to the client without being cached. Defaults to 1 MiB.
+	MaxBodySize int
+}
+
+// defaultCacheMaxBodySize is the default of CacheOptions.MaxBodySize.
+const defaultCacheMaxBodySize = 1 << 20
+
+// cachedAtHeader holds the time responses were cached at in Unix seconds. It is removed from served responses.
+const cachedAtHeader = "X-Workers-Cached-At"
+
+// revalidating holds cache keys of stale responses being revalidated in this isolate.
+var revalidating sync.Map
+
+// Cache returns a middleware caching responses of GET requests by the Cache API.
+//   - cached responses are served without calling the next handler.
+//   - responses are stored only if the status is 200, and they don't have Set-Cookie header
+//     or Cache-Control header with private, no-store or no-cache directive.
+//   - requests with Authorization header are never cached. Requests with Cookie header are neither cached,
+//     unless Cookie is listed in Vary, since responses may be personalized by cookies without Set-Cookie header.
+//   - responses larger than MaxBodySize are not cached.
+//   - responses are stored after they are returned to the client by using waitUntil.
+//   - if StaleWhileRevalidate is set, responses older than TTL are served with Age header, and the next handler is called
+//     in the background to refresh them. Revalidations of the same key are not duplicated in the isolate.
+//   - https://developers.cloudflare.com/workers/runtime-apis/cache/
+func Cache(opts *CacheOptions) workers.Middleware {
+	if opts == nil {
+		opts = &CacheOptions{}
+	}
+	ttl := opts.TTL
+	if ttl <= 0 {
+		ttl = 60
+	}
+	swr := max(opts.StaleWhileRevalidate, 0)
+	now := time.Now
+	if opts.Now != nil {
+		now = opts.Now
+	}
+	maxBodySize := opts.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = defaultCacheMaxBodySize
+	}
+	varyCookie := false
+	for _, h := range opts.Vary {
+		varyCookie = varyCookie || strings.EqualFold(h, "Cookie")
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Method != http.MethodGet || req.Header.Get("Authorization") != "" || (req.Header.Get("Cookie") != "" && !varyCookie) {
+				next.ServeHTTP(w, req)
+				return
+			}
+			c := opts.Cache
+			if c == nil {
+				c = cache.New()
+			}
+			keyReq, err := http.NewRequest(http.MethodGet, cacheKey(req, opts), nil)
+			if err != nil {
+				next.ServeHTTP(w, req)
+				return
+			}
+			if res, err := c.Match(keyReq, nil); err == nil {
+				age, stale := cacheAge(res.Header, now(), ttl)
+				if age <= ttl+swr {
+					writeResponse(w, res)
+					if stale && swr > 0 {
+						revalidate(req, keyReq, c, next, ttl+swr, maxBodySize, now)
+					}
+					return
+				}
+				// the response is older than the window, e.g. cached with a longer TTL before.
+				res.Body.Close()
+			}
+			rec := &cacheRecorder{ResponseWriter: w, status: http.StatusOK, maxBodySize: maxBodySize}
+			next.ServeHTTP(rec, req)
+			if rec.overflowed || !cacheable(rec.status, w.Header()) {
+				return
+			}
+			header := w.Header().Clone()
+			body := rec.buf.Bytes()
+			cloudflare.WaitUntil(req.Context(), func(context.Context) {
+				storeResponse(c, keyReq, rec.status, header, body, ttl+swr, now())
+			})
+		})
+	}
+}
+
+// cacheAge returns the age of the cached response in seconds, and reports whether it is older than ttl.
+// The internal header is replaced by Age header.
+func cacheAge(h http.Header, now time.Time, ttl int) (int, bool) {
+	cachedAt, err := strconv.ParseInt(h.Get(cachedAtHeader), 10, 64)
+	h.Del(cachedAtHeader)
+	if err != nil {
+		// responses cached without the time are fresh until the Cache API expires them.
+		return 0, false
+	}
+	age := max(int(now.Unix()-cachedAt), 0)
+	h.Set("Age", strconv.Itoa(age))
+	return age, age > ttl
+}
+
+// revalidate calls the next handler in the background by waitUntil, and stores its response.
+func revalidate(req, keyReq *http.Request, c cache.Store, next http.Handler, maxAge, maxBodySize int, now func() time.Time) {
+	key := keyReq.URL.String()
+	if _, loaded := revalidating.LoadOrStore(key, struct{}{}); loaded {
+		return
+	}
+	cloudflare.WaitUntil(req.Context(), func(ctx context.Context) {
+		defer revalidating.Delete(key)
+		rec := &responseBuffer{header: http.Header{}, status: http.StatusOK}
+		// the context of the request is canceled when the response is returned, but the revalidation continues.
+		next.ServeHTTP(rec, req.Clone(ctx))
+		if rec.buf.Len() <= maxBodySize && cacheable(rec.status, rec.header) {
+			storeResponse(c, keyReq, rec.status, rec.header, rec.buf.Bytes(), maxAge, now())
+		}
+	})
+}
+
+// storeResponse stores the response for maxAge seconds, recording the time it is cached at.
+func storeResponse(c cache.Store, keyReq *http.Request, status int, header http.Header, body []byte, maxAge int, now time.Time) {
+	header.Set("Cache-Control", "public, max-age="+strconv.Itoa(maxAge))
+	header.Set(cachedAtHeader, strconv.FormatInt(now.Unix(), 10))
+	// caching failure is not fatal.
+	_ = c.Put(keyReq, &http.Response{
+		StatusCode:    status,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+	})
+}
+
+// cacheKey returns the URL used as the cache key for the request.
+func cacheKey(req *http.Request, opts *CacheOptions) string {
+	key := req.URL.String()
+	if opts.KeyFunc != nil {
+		key = opts.KeyFunc(req)
+	}
+	if len(opts.Vary) == 0 {
+		return key
+	}
+	u, err := url.Parse(key)
+	if err != nil {
+		return key
+	}
+	q := u.Query()
+	for _, h := range opts.Vary {
+		q.Set("__vary_"+strings.ToLower(h), req.Header.Get(h))
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// writeResponse writes the response to the ResponseWriter.
+func writeResponse(w http.ResponseWriter, res *http.Response) {
+	defer res.Body.Close()
+	for k, v := range res.Header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(res.StatusCode)
+	_, _ = io.Copy(w, res.Body)
+}
+
+// cacheRecorder writes the response to the underlying ResponseWriter and records it.
+// Once the body exceeds maxBodySize, it stops recording and the buffer is dropped.
+type cacheRecorder struct {
+	http.ResponseWriter
+	status      int
+	buf         bytes.Buffer
+	maxBodySize int
+	overflowed  bool
+}
+
+func (r *cacheRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *cacheRecorder) Write(b []byte) (int, error) {
+	if !r.overflowed {
+		if r.buf.Len()+len(b) > r.maxBodySize {
+			r.overflowed = true
+			r.buf = bytes.Buffer{}
+		} else {
+			r.buf.Write(b)
+		}
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// responseBuffer is http.ResponseWriter recording the response without writing it to the client.
+type responseBuffer struct {
+	header http.Header
+	status int
+	buf    bytes.Buffer
+}
+
+func (r *responseBuffer) Header() http.Header {
+	return r.header
+}
+
+func (r *responseBuffer) WriteHeader(status int) {
+	r.status = status
+}
+
+func (r *responseBuffer) Write(b []byte) (int, error) {
+	return r.buf.Write(b)
+}
+
+// cacheable reports whether the response can be stored.
+func cacheable(status int, h http.Header) bool {
+	if status != http.StatusOK {
+		return false
+	}
+	if h.Get("Set-Cookie") != "" {
+		return false
+	}
+	for _, directive := range parseHeaderList(h.Values("Cache-Control")) {
+		switch strings.ToLower(directive) {
+		case "private", "no-store", "no-cache":
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,286 @@
+//go:build js && wasm
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/runtimecontext"
+	"github.com/syumai/workers/workerstest"
+)
+
+// serveEvent serves the request as an event, and waits for tasks of waitUntil to finish.
+func serveEvent(h http.Handler, header http.Header) *httptest.ResponseRecorder {
+	rec := startEvent(h, header)
+	runtimecontext.Wait()
+	return rec
+}
+
+// startEvent serves the request as an event without waiting for tasks of waitUntil.
+func startEvent(h http.Handler, header http.Header) *httptest.ResponseRecorder {
+	exCtx := js.Global().Get("Object").New()
+	exCtx.Set("waitUntil", js.FuncOf(func(js.Value, []js.Value) any { return js.Undefined() }))
+	runtimeCtxObj := js.Global().Get("Object").New()
+	runtimeCtxObj.Set("ctx", exCtx)
+	ctx, settle := runtimecontext.NewEvent(js.Undefined(), runtimeCtxObj)
+
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/a", nil).WithContext(ctx)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	settle()
+	return rec
+}
+
+// countingHandler responds with the body and the header, and counts calls.
+//   - if block is set, calls are blocked until it is closed.
+type countingHandler struct {
+	mu     sync.Mutex
+	calls  int
+	body   string
+	header http.Header
+	block  chan struct{}
+}
+
+func (h *countingHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	h.mu.Lock()
+	h.calls++
+	body := h.body
+	header := h.header
+	block := h.block
+	h.mu.Unlock()
+	if block != nil {
+		<-block
+	}
+	for k, v := range header {
+		w.Header()[k] = v
+	}
+	w.Write([]byte(body))
+}
+
+// update changes the response of later calls.
+func (h *countingHandler) update(body string, header http.Header, block chan struct{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.body = body
+	h.header = header
+	h.block = block
+}
+
+func (h *countingHandler) callCount() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.calls
+}
+
+func TestCache(t *testing.T) {
+	tests := map[string]struct {
+		opts       *CacheOptions
+		header     http.Header
+		body       string
+		respHeader http.Header
+		wantCached bool
+	}{
+		"cached": {
+			body:       "hello",
+			wantCached: true,
+		},
+		"authorization": {
+			header: http.Header{"Authorization": {"Bearer token"}},
+			body:   "hello",
+		},
+		"cookie": {
+			header: http.Header{"Cookie": {"session=a"}},
+			body:   "hello",
+		},
+		"cookie in vary": {
+			opts:       &CacheOptions{Vary: []string{"cookie"}},
+			header:     http.Header{"Cookie": {"session=a"}},
+			body:       "hello",
+			wantCached: true,
+		},
+		"set-cookie": {
+			body:       "hello",
+			respHeader: http.Header{"Set-Cookie": {"session=a"}},
+		},
+		"private": {
+			body:       "hello",
+			respHeader: http.Header{"Cache-Control": {"private"}},
+		},
+		"too large": {
+			opts: &CacheOptions{MaxBodySize: 4},
+			body: "hello",
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			opts := &CacheOptions{}
+			if tc.opts != nil {
+				opts = tc.opts
+			}
+			opts.Cache = &workerstest.Cache{}
+			next := &countingHandler{body: tc.body, header: tc.respHeader}
+			h := Cache(opts)(next)
+			for i := 0; i < 2; i++ {
+				if got := serveEvent(h, tc.header).Body.String(); got != tc.body {
+					t.Errorf("request %d: want body %q, got %q", i, tc.body, got)
+				}
+			}
+			wantCalls := 2
+			if tc.wantCached {
+				wantCalls = 1
+			}
+			if got := next.callCount(); got != wantCalls {
+				t.Errorf("want the handler called %d times, got %d", wantCalls, got)
+			}
+		})
+	}
+}
+
+func TestCache_StaleWhileRevalidate(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	var mu sync.Mutex
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	advance := func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(d)
+	}
+	next := &countingHandler{body: "v1"}
+	h := Cache(&CacheOptions{
+		TTL:                  10,
+		StaleWhileRevalidate: 30,
+		Cache:                &workerstest.Cache{Now: clock},
+		Now:                  clock,
+	})(next)
+
+	serveEvent(h, nil)
+	next.update("v2", nil, nil)
+
+	advance(5 * time.Second)
+	if rec := serveEvent(h, nil); rec.Body.String() != "v1" || rec.Header().Get("Age") != "5" {
+		t.Errorf("want the fresh response with Age 5, got %q, Age %q", rec.Body.String(), rec.Header().Get("Age"))
+	}
+	if got := next.callCount(); got != 1 {
+		t.Errorf("want no revalidation of the fresh response, got %d calls", got)
+	}
+
+	advance(15 * time.Second)
+	if rec := serveEvent(h, nil); rec.Body.String() != "v1" || rec.Header().Get("Age") != "20" {
+		t.Errorf("want the stale response with Age 20, got %q, Age %q", rec.Body.String(), rec.Header().Get("Age"))
+	}
+	if got := next.callCount(); got != 2 {
+		t.Errorf("want the stale response revalidated in the background, got %d calls", got)
+	}
+	if rec := serveEvent(h, nil); rec.Body.String() != "v2" {
+		t.Errorf("want the revalidated response, got %q", rec.Body.String())
+	}
+}
+
+func TestCache_StaleWhileRevalidate_Window(t *testing.T) {
+	tests := map[string]struct {
+		age        time.Duration
+		respHeader http.Header
+		want       []string
+		wantCalls  int
+	}{
+		"stale": {
+			age:       20 * time.Second,
+			want:      []string{"v1", "v2"},
+			wantCalls: 2,
+		},
+		"expired": {
+			age:       50 * time.Second,
+			want:      []string{"v2", "v2"},
+			wantCalls: 2,
+		},
+		"uncacheable revalidation": {
+			age:        20 * time.Second,
+			respHeader: http.Header{"Cache-Control": {"private"}},
+			want:       []string{"v1", "v1"},
+			wantCalls:  3,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			now := time.Unix(1700000000, 0)
+			var mu sync.Mutex
+			clock := func() time.Time {
+				mu.Lock()
+				defer mu.Unlock()
+				return now
+			}
+			next := &countingHandler{body: "v1"}
+			h := Cache(&CacheOptions{
+				TTL:                  10,
+				StaleWhileRevalidate: 30,
+				Cache:                &workerstest.Cache{Now: clock},
+				Now:                  clock,
+			})(next)
+			serveEvent(h, nil)
+			next.update("v2", tc.respHeader, nil)
+			mu.Lock()
+			now = now.Add(tc.age)
+			mu.Unlock()
+
+			var got []string
+			for i := 0; i < 2; i++ {
+				got = append(got, serveEvent(h, nil).Body.String())
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("want bodies %q, got %q", tc.want, got)
+			}
+			if calls := next.callCount(); calls != tc.wantCalls {
+				t.Errorf("want the handler called %d times, got %d", tc.wantCalls, calls)
+			}
+		})
+	}
+}
+
+func TestCache_StaleWhileRevalidate_Dedup(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	clock := func() time.Time { return now }
+	next := &countingHandler{body: "v1"}
+	h := Cache(&CacheOptions{
+		TTL:                  10,
+		StaleWhileRevalidate: 30,
+		Cache:                &workerstest.Cache{Now: clock},
+		Now:                  clock,
+	})(next)
+	serveEvent(h, nil)
+	now = now.Add(20 * time.Second)
+
+	// stale responses are served while the first revalidation is blocked.
+	block := make(chan struct{})
+	next.update("v2", nil, block)
+	for i := 0; i < 3; i++ {
+		if got := startEvent(h, nil).Body.String(); got != "v1" {
+			t.Errorf("request %d: want the stale response, got %q", i, got)
+		}
+	}
+	close(block)
+	runtimecontext.Wait()
+	if got := next.callCount(); got != 2 {
+		t.Errorf("want one revalidation for concurrent stale requests, got %d calls", got)
+	}
+	if got := serveEvent(h, nil).Body.String(); got != "v2" {
+		t.Errorf("want the revalidated response, got %q", got)
+	}
+}
@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/syumai/workers"
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jsutil"
+)
+
+// CompressOptions represents options of the Compress middleware.
+type CompressOptions struct {
+	// MinLength is the minimum Content-Length of responses to be compressed.
+	// Responses without Content-Length header are always compressed.
+	MinLength int64
+	// SkipContentTypes is a list of media types which are not compressed in addition to the defaults.
+	// A media type ending with "/" (e.g. "image/") matches all subtypes.
+	SkipContentTypes []string
+}
+
+// defaultSkipContentTypes is a list of media types which are already compressed or must be streamed as is.
+var defaultSkipContentTypes = []string{
+	"image/",
+	"audio/",
+	"video/",
+	"font/woff",
+	"font/woff2",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/pdf",
+	"application/octet-stream",
+	"text/event-stream",
+	"application/x-ndjson",
+}
+
+// supportedEncodings is a list of encodings supported by CompressionStream in preference order.
+var supportedEncodings = []string{"gzip", "deflate"}
+
+// Compress returns a middleware compressing response bodies by native CompressionStream.
+//   - The encoding is negotiated by Accept-Encoding header.
+//   - Responses already having Content-Encoding header are not compressed.
+//   - https://developers.cloudflare.com/workers/runtime-apis/streams/compressionstream/
+func Compress(opts *CompressOptions) workers.Middleware {
+	if opts == nil {
+		opts = &CompressOptions{}
+	}
+	skipTypes := append(append([]string(nil), defaultSkipContentTypes...), opts.SkipContentTypes...)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			encoding := negotiateEncoding(req.Header.Values("Accept-Encoding"))
+			if encoding == "" || req.Method == http.MethodHead {
+				next.ServeHTTP(w, req)
+				return
+			}
+			cw := &compressWriter{
+				ResponseWriter: w,
+				encoding:       encoding,
+				minLength:      opts.MinLength,
+				skipTypes:      skipTypes,
+			}
+			defer cw.close()
+			next.ServeHTTP(cw, req)
+		})
+	}
+}
+
+// negotiateEncoding returns the most preferred encoding supported by CompressionStream.
+//   - if no encoding is acceptable, returns empty string.
+func negotiateEncoding(acceptEncoding []string) string {
+	var (
+		best  string
+		bestQ float64
+	)
+	for _, v := range parseHeaderList(acceptEncoding) {
+		coding, params, _ := strings.Cut(v, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		q := 1.0
+		if name, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(name) == "q" {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 || q <= bestQ {
+			continue
+		}
+		for _, e := range supportedEncodings {
+			if coding == e || coding == "*" {
+				best, bestQ = e, q
+				break
+			}
+		}
+	}
+	return best
+}
+
+// compressWriter pipes written bytes through CompressionStream into the underlying ResponseWriter.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	minLength   int64
+	skipTypes   []string
+	wroteHeader bool
+	compressing bool
+	// jsWriter is a writer of CompressionStream's writable side.
+	jsWriter js.Value
+	// done is closed when all compressed bytes are copied to the ResponseWriter.
+	done chan struct{}
+}
+
+func (w *compressWriter) shouldCompress(status int) bool {
+	h := w.Header()
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		return false
+	}
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+	if cl, err := strconv.ParseInt(h.Get("Content-Length"), 10, 64); err == nil && cl < w.minLength {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(h.Get("Content-Type"))
+	for _, t := range w.skipTypes {
+		if mediaType == t || (strings.HasSuffix(t, "/") && strings.HasPrefix(mediaType, t)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if !w.shouldCompress(status) {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	h := w.Header()
+	h.Set("Content-Encoding", w.encoding)
+	h.Del("Content-Length")
+	h.Del("Accept-Ranges")
+	if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		// compressed representation is not byte-identical, so the ETag is weakened.
+		h.Set("ETag", "W/"+etag)
+	}
+	w.ResponseWriter.WriteHeader(status)
+
+	cs := jsutil.CompressionStreamClass.New(w.encoding)
+	w.jsWriter = cs.Get("writable").Call("getWriter")
+	reader := jsutil.ConvertStreamReaderToReader(cs.Get("readable").Call("getReader"))
+	w.compressing = true
+	w.done = make(chan struct{})
+	go func() {
+		defer close(w.done)
+		_, _ = io.Copy(w.ResponseWriter, reader)
+	}()
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.compressing {
+		return w.ResponseWriter.Write(b)
+	}
+	if _, err := jsutil.AwaitPromise(w.jsWriter.Call("write", jsutil.NewUint8ArrayFromBytes(b))); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Flush writes the header if it is not written yet, and flushes the underlying ResponseWriter.
+//   - CompressionStream can't be flushed, so bytes being compressed are sent when CompressionStream emits them.
+//     Streamed responses should be skipped by their content types.
+func (w *compressWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// close flushes the CompressionStream and waits until all compressed bytes are written.
+func (w *compressWriter) close() {
+	if !w.compressing {
+		return
+	}
+	_, _ = jsutil.AwaitPromise(w.jsWriter.Call("close"))
+	<-w.done
+}
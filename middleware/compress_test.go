@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := map[string]struct {
+		acceptEncoding []string
+		want           string
+	}{
+		"no header": {},
+		"gzip": {
+			acceptEncoding: []string{"gzip, deflate, br"},
+			want:           "gzip",
+		},
+		"preferred by q": {
+			acceptEncoding: []string{"gzip;q=0.5", "deflate;q=0.8"},
+			want:           "deflate",
+		},
+		"case insensitive": {
+			acceptEncoding: []string{"GZIP"},
+			want:           "gzip",
+		},
+		"wildcard": {
+			acceptEncoding: []string{"*"},
+			want:           "gzip",
+		},
+		"unsupported": {
+			acceptEncoding: []string{"br, zstd"},
+		},
+		"refused": {
+			acceptEncoding: []string{"gzip;q=0, br"},
+		},
+		"invalid q": {
+			acceptEncoding: []string{"gzip;q=x"},
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if got := negotiateEncoding(tc.acceptEncoding); got != tc.want {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCompress_Skip(t *testing.T) {
+	tests := map[string]struct {
+		method         string
+		acceptEncoding string
+		opts           *CompressOptions
+		status         int
+		header         http.Header
+	}{
+		"not accepted": {
+			status: http.StatusOK,
+			header: http.Header{"Content-Type": {"text/plain"}},
+		},
+		"HEAD": {
+			method:         http.MethodHead,
+			acceptEncoding: "gzip",
+			status:         http.StatusOK,
+			header:         http.Header{"Content-Type": {"text/plain"}},
+		},
+		"compressed content type": {
+			acceptEncoding: "gzip",
+			status:         http.StatusOK,
+			header:         http.Header{"Content-Type": {"image/png"}},
+		},
+		"skipped content type": {
+			acceptEncoding: "gzip",
+			opts:           &CompressOptions{SkipContentTypes: []string{"application/wasm"}},
+			status:         http.StatusOK,
+			header:         http.Header{"Content-Type": {"application/wasm"}},
+		},
+		"shorter than MinLength": {
+			acceptEncoding: "gzip",
+			opts:           &CompressOptions{MinLength: 1024},
+			status:         http.StatusOK,
+			header:         http.Header{"Content-Type": {"text/plain"}, "Content-Length": {"5"}},
+		},
+		"already encoded": {
+			acceptEncoding: "gzip",
+			status:         http.StatusOK,
+			header:         http.Header{"Content-Type": {"text/plain"}, "Content-Encoding": {"br"}},
+		},
+		"partial content": {
+			acceptEncoding: "gzip",
+			status:         http.StatusPartialContent,
+			header:         http.Header{"Content-Type": {"text/plain"}, "Content-Range": {"bytes 0-4/10"}},
+		},
+		"no content": {
+			acceptEncoding: "gzip",
+			status:         http.StatusNoContent,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			h := Compress(tc.opts)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				for k, v := range tc.header {
+					w.Header()[k] = v
+				}
+				w.WriteHeader(tc.status)
+				if tc.status != http.StatusNoContent {
+					w.Write([]byte("hello"))
+				}
+			}))
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, "/", nil)
+			if tc.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Errorf("want status %d, got %d", tc.status, rec.Code)
+			}
+			if got, want := rec.Header().Get("Content-Encoding"), tc.header.Get("Content-Encoding"); got != want {
+				t.Errorf("want Content-Encoding %q, got %q", want, got)
+			}
+			if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("want Vary: Accept-Encoding, got %q", got)
+			}
+			if tc.status != http.StatusNoContent && rec.Body.String() != "hello" {
+				t.Errorf("want the body as it is, got %q", rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+//go:build js && wasm
+
+package middleware
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompress(t *testing.T) {
+	body := strings.Repeat("hello, world. ", 100)
+	tests := map[string]struct {
+		acceptEncoding string
+		decode         func(r io.Reader) (io.Reader, error)
+	}{
+		"gzip": {
+			acceptEncoding: "gzip",
+			decode: func(r io.Reader) (io.Reader, error) {
+				return gzip.NewReader(r)
+			},
+		},
+		"deflate": {
+			acceptEncoding: "deflate",
+			decode: func(r io.Reader) (io.Reader, error) {
+				// CompressionStream of deflate emits the zlib format.
+				return zlib.NewReader(r)
+			},
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			h := Compress(nil)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Content-Length", "1400")
+				w.Header().Set("ETag", `"a"`)
+				// written in pieces to check they are compressed as a stream.
+				for i := 0; i < 100; i++ {
+					io.WriteString(w, "hello, world. ")
+				}
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			res := rec.Result()
+			if got := res.Header.Get("Content-Encoding"); got != tc.acceptEncoding {
+				t.Errorf("want Content-Encoding %s, got %q", tc.acceptEncoding, got)
+			}
+			if got := res.Header.Get("Content-Length"); got != "" {
+				t.Errorf("want Content-Length removed, got %q", got)
+			}
+			if got := res.Header.Get("ETag"); got != `W/"a"` {
+				t.Errorf("want the weakened ETag, got %q", got)
+			}
+			if got := res.Header.Get("Content-Type"); !strings.HasPrefix(got, "text/plain") {
+				t.Errorf("want the detected Content-Type, got %q", got)
+			}
+			if rec.Body.Len() >= len(body) {
+				t.Errorf("want the body compressed, got %d bytes", rec.Body.Len())
+			}
+			r, err := tc.decode(rec.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != body {
+				t.Errorf("want the body restored, got %q", b)
+			}
+		})
+	}
+}
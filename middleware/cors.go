@@ -0,0 +1,205 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/syumai/workers"
+)
+
+// CORSOptions represents options of the CORS middleware.
+//   - https://developer.mozilla.org/docs/Web/HTTP/CORS
+type CORSOptions struct {
+	// AllowedOrigins is a list of origins allowed to make cross-origin requests.
+	//   - "*" allows all origins.
+	//   - an origin can contain one wildcard, e.g. "https://*.example.com".
+	//   - if both AllowedOrigins and AllowOriginFunc are empty, all origins are allowed.
+	AllowedOrigins []string
+	// AllowOriginFunc is a custom function to validate the origin.
+	// If set, AllowedOrigins is ignored.
+	AllowOriginFunc func(origin string) bool
+	// AllowedMethods is a list of methods allowed for cross-origin requests.
+	// Defaults to GET, HEAD and POST.
+	AllowedMethods []string
+	// AllowedHeaders is a list of request headers allowed for cross-origin requests.
+	//   - "*" allows all headers.
+	//   - if empty, the headers requested in preflight request are allowed.
+	AllowedHeaders []string
+	// ExposedHeaders is a list of response headers exposed to the client.
+	ExposedHeaders []string
+	// AllowCredentials indicates whether the request can include user credentials.
+	// It can't be used with all origins allowed, since any site could make authenticated requests on behalf of users.
+	AllowCredentials bool
+	// MaxAge is seconds of how long the result of a preflight request can be cached.
+	// The value `0` means that Access-Control-Max-Age header is not sent.
+	MaxAge int
+}
+
+var defaultCORSMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+
+// CORS returns a middleware handling Cross-Origin Resource Sharing.
+//   - preflight requests are responded with status 204 and never reach the next handler.
+//   - if opts is nil, all origins are allowed with default methods.
+//   - This function panics when AllowCredentials is set with all origins allowed.
+//     Origins must be listed in AllowedOrigins or validated by AllowOriginFunc instead.
+func CORS(opts *CORSOptions) workers.Middleware {
+	if opts == nil {
+		opts = &CORSOptions{}
+	}
+	c := &cors{opts: opts}
+	methods := opts.AllowedMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	for _, m := range methods {
+		c.allowedMethods = append(c.allowedMethods, strings.ToUpper(m))
+	}
+	c.allowAllOrigins = opts.AllowOriginFunc == nil && (len(opts.AllowedOrigins) == 0 || contains(opts.AllowedOrigins, "*"))
+	if c.allowAllOrigins && opts.AllowCredentials {
+		panic("middleware: CORSOptions.AllowCredentials can't be used with all origins allowed")
+	}
+	for _, h := range opts.AllowedHeaders {
+		if h == "*" {
+			c.allowAllHeaders = true
+			continue
+		}
+		c.allowedHeaders = append(c.allowedHeaders, http.CanonicalHeaderKey(h))
+	}
+	c.reflectHeaders = len(opts.AllowedHeaders) == 0
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Method == http.MethodOptions && req.Header.Get("Access-Control-Request-Method") != "" {
+				c.handlePreflight(w, req)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			c.handleActual(w, req)
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+type cors struct {
+	opts            *CORSOptions
+	allowedMethods  []string
+	allowedHeaders  []string
+	allowAllOrigins bool
+	allowAllHeaders bool
+	reflectHeaders  bool
+}
+
+func (c *cors) isOriginAllowed(origin string) bool {
+	if c.opts.AllowOriginFunc != nil {
+		return c.opts.AllowOriginFunc(origin)
+	}
+	if c.allowAllOrigins {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	for _, o := range c.opts.AllowedOrigins {
+		if matchOrigin(strings.ToLower(o), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchOrigin matches origin with pattern which can contain one wildcard.
+func matchOrigin(pattern, origin string) bool {
+	prefix, suffix, found := strings.Cut(pattern, "*")
+	if !found {
+		return pattern == origin
+	}
+	return len(origin) >= len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix)
+}
+
+// setAllowOrigin sets Access-Control-Allow-Origin and related headers.
+func (c *cors) setAllowOrigin(h http.Header, origin string) {
+	if c.allowAllOrigins {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+	if c.opts.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (c *cors) handlePreflight(w http.ResponseWriter, req *http.Request) {
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+	origin := req.Header.Get("Origin")
+	if origin == "" || !c.isOriginAllowed(origin) {
+		return
+	}
+	method := strings.ToUpper(req.Header.Get("Access-Control-Request-Method"))
+	if !contains(c.allowedMethods, method) {
+		return
+	}
+	reqHeaders := parseHeaderList(req.Header.Values("Access-Control-Request-Headers"))
+	if !c.areHeadersAllowed(reqHeaders) {
+		return
+	}
+	c.setAllowOrigin(h, origin)
+	h.Set("Access-Control-Allow-Methods", strings.Join(c.allowedMethods, ", "))
+	if len(reqHeaders) > 0 {
+		h.Set("Access-Control-Allow-Headers", strings.Join(reqHeaders, ", "))
+	}
+	if c.opts.MaxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(c.opts.MaxAge))
+	}
+}
+
+func (c *cors) handleActual(w http.ResponseWriter, req *http.Request) {
+	h := w.Header()
+	if !c.allowAllOrigins {
+		h.Add("Vary", "Origin")
+	}
+	origin := req.Header.Get("Origin")
+	if origin == "" || !c.isOriginAllowed(origin) {
+		return
+	}
+	c.setAllowOrigin(h, origin)
+	if len(c.opts.ExposedHeaders) > 0 {
+		h.Set("Access-Control-Expose-Headers", strings.Join(c.opts.ExposedHeaders, ", "))
+	}
+}
+
+func (c *cors) areHeadersAllowed(headers []string) bool {
+	if c.allowAllHeaders || c.reflectHeaders {
+		return true
+	}
+	for _, header := range headers {
+		if !contains(c.allowedHeaders, http.CanonicalHeaderKey(header)) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseHeaderList parses comma separated header values.
+// The values converted from JavaScript side's Headers are already split by comma, so they must be trimmed.
+func parseHeaderList(values []string) []string {
+	var result []string
+	for _, value := range values {
+		for _, v := range strings.Split(value, ",") {
+			v = strings.TrimSpace(v)
+			if v != "" {
+				result = append(result, v)
+			}
+		}
+	}
+	return result
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
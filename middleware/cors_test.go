@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORS(t *testing.T) {
+	tests := map[string]struct {
+		opts       *CORSOptions
+		method     string
+		header     http.Header
+		wantStatus int
+		want       http.Header
+	}{
+		"allow all origins": {
+			opts:       nil,
+			method:     http.MethodGet,
+			header:     http.Header{"Origin": {"https://example.com"}},
+			wantStatus: http.StatusOK,
+			want: http.Header{
+				"Access-Control-Allow-Origin": {"*"},
+			},
+		},
+		"no origin": {
+			opts:       &CORSOptions{AllowedOrigins: []string{"https://example.com"}},
+			method:     http.MethodGet,
+			wantStatus: http.StatusOK,
+			want: http.Header{
+				"Vary": {"Origin"},
+			},
+		},
+		"allowed origin with wildcard": {
+			opts: &CORSOptions{
+				AllowedOrigins: []string{"https://*.example.com"},
+				ExposedHeaders: []string{"X-Total"},
+			},
+			method:     http.MethodGet,
+			header:     http.Header{"Origin": {"https://api.example.com"}},
+			wantStatus: http.StatusOK,
+			want: http.Header{
+				"Vary":                          {"Origin"},
+				"Access-Control-Allow-Origin":   {"https://api.example.com"},
+				"Access-Control-Expose-Headers": {"X-Total"},
+			},
+		},
+		"disallowed origin": {
+			opts:       &CORSOptions{AllowedOrigins: []string{"https://example.com"}},
+			method:     http.MethodGet,
+			header:     http.Header{"Origin": {"https://evil.com"}},
+			wantStatus: http.StatusOK,
+			want: http.Header{
+				"Vary": {"Origin"},
+			},
+		},
+		"credentials": {
+			opts:       &CORSOptions{AllowedOrigins: []string{"https://example.com"}, AllowCredentials: true},
+			method:     http.MethodGet,
+			header:     http.Header{"Origin": {"https://example.com"}},
+			wantStatus: http.StatusOK,
+			want: http.Header{
+				"Vary":                             {"Origin"},
+				"Access-Control-Allow-Origin":      {"https://example.com"},
+				"Access-Control-Allow-Credentials": {"true"},
+			},
+		},
+		"preflight": {
+			opts: &CORSOptions{
+				AllowedOrigins: []string{"https://example.com"},
+				AllowedMethods: []string{"get", "put"},
+				AllowedHeaders: []string{"Content-Type", "X-Custom"},
+				MaxAge:         600,
+			},
+			method: http.MethodOptions,
+			header: http.Header{
+				"Origin":                         {"https://example.com"},
+				"Access-Control-Request-Method":  {"PUT"},
+				"Access-Control-Request-Headers": {"content-type", " x-custom"},
+			},
+			wantStatus: http.StatusNoContent,
+			want: http.Header{
+				"Vary":                         {"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+				"Access-Control-Allow-Origin":  {"https://example.com"},
+				"Access-Control-Allow-Methods": {"GET, PUT"},
+				"Access-Control-Allow-Headers": {"content-type, x-custom"},
+				"Access-Control-Max-Age":       {"600"},
+			},
+		},
+		"preflight with disallowed header": {
+			opts: &CORSOptions{
+				AllowedHeaders: []string{"Content-Type"},
+			},
+			method: http.MethodOptions,
+			header: http.Header{
+				"Origin":                         {"https://example.com"},
+				"Access-Control-Request-Method":  {"GET"},
+				"Access-Control-Request-Headers": {"X-Custom"},
+			},
+			wantStatus: http.StatusNoContent,
+			want: http.Header{
+				"Vary": {"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+			},
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			h := CORS(tc.opts)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+			req := httptest.NewRequest(tc.method, "/", nil)
+			for k, v := range tc.header {
+				req.Header[k] = v
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tc.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tc.wantStatus)
+			}
+			got := rec.Header()
+			if len(got) != len(tc.want) {
+				t.Fatalf("header = %v, want %v", got, tc.want)
+			}
+			for k, want := range tc.want {
+				if g := got.Values(k); len(g) != len(want) || g == nil {
+					t.Fatalf("header %s = %v, want %v", k, g, want)
+				} else {
+					for i := range want {
+						if g[i] != want[i] {
+							t.Fatalf("header %s = %v, want %v", k, g, want)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCORS_CredentialsWithAllOrigins(t *testing.T) {
+	tests := map[string]struct {
+		opts      *CORSOptions
+		wantPanic bool
+	}{
+		"no origins": {
+			opts:      &CORSOptions{AllowCredentials: true},
+			wantPanic: true,
+		},
+		"wildcard": {
+			opts:      &CORSOptions{AllowedOrigins: []string{"https://example.com", "*"}, AllowCredentials: true},
+			wantPanic: true,
+		},
+		"listed origins": {
+			opts: &CORSOptions{AllowedOrigins: []string{"https://*.example.com"}, AllowCredentials: true},
+		},
+		"origin func": {
+			opts: &CORSOptions{AllowOriginFunc: func(string) bool { return true }, AllowCredentials: true},
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			defer func() {
+				if panicked := recover() != nil; panicked != tc.wantPanic {
+					t.Errorf("panicked = %v, want %v", panicked, tc.wantPanic)
+				}
+			}()
+			CORS(tc.opts)
+		})
+	}
+}
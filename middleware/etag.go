@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/syumai/workers"
+	"github.com/syumai/workers/internal/webcrypto"
+)
+
+// ComputeETag computes ETag of the body by SHA-256 digest of native crypto.
+//   - if weak is true, returns weak ETag (e.g. `W/"..."`).
+//   - https://developer.mozilla.org/docs/Web/HTTP/Headers/ETag
+func ComputeETag(body []byte, weak bool) (string, error) {
+	digest, err := webcrypto.Digest("SHA-256", body)
+	if err != nil {
+		return "", err
+	}
+	etag := `"` + base64.RawURLEncoding.EncodeToString(digest) + `"`
+	if weak {
+		etag = "W/" + etag
+	}
+	return etag, nil
+}
+
+// IsNotModified reports whether the conditional request matches the given ETag or last modified time,
+// so the response can be replaced by 304 Not Modified.
+//   - If-None-Match takes precedence over If-Modified-Since.
+//   - empty etag and zero lastModified are ignored.
+//   - https://www.rfc-editor.org/rfc/rfc9110#section-13.2.2
+func IsNotModified(req *http.Request, etag string, lastModified time.Time) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	if inm := req.Header.Values("If-None-Match"); len(inm) > 0 {
+		if etag == "" {
+			return false
+		}
+		for _, tag := range parseHeaderList(inm) {
+			if tag == "*" || weakETagEqual(tag, etag) {
+				return true
+			}
+		}
+		return false
+	}
+	ims := req.Header.Get("If-Modified-Since")
+	if ims == "" || lastModified.IsZero() {
+		return false
+	}
+	t, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	// HTTP dates have second precision.
+	return !lastModified.Truncate(time.Second).After(t)
+}
+
+// weakETagEqual compares ETags by weak comparison.
+func weakETagEqual(a, b string) bool {
+	return strings.TrimPrefix(a, "W/") == strings.TrimPrefix(b, "W/")
+}
+
+// WriteNotModified writes 304 Not Modified response if the conditional request matches the given ETag or last modified time.
+// The ETag and Last-Modified headers are set regardless of the result.
+//   - returns true if 304 response was written, so the caller must not write the body.
+func WriteNotModified(w http.ResponseWriter, req *http.Request, etag string, lastModified time.Time) bool {
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	if !IsNotModified(req, etag, lastModified) {
+		return false
+	}
+	h := w.Header()
+	for _, k := range []string{"Content-Type", "Content-Length", "Content-Encoding"} {
+		h.Del(k)
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// ETagOptions represents options of the ETag middleware.
+type ETagOptions struct {
+	// Weak generates weak ETags.
+	Weak bool
+}
+
+// ETag returns a middleware adding ETag header to successful responses of GET and HEAD requests,
+// and responding with 304 Not Modified when If-None-Match header matches.
+//   - The response body is buffered to compute the digest.
+//   - ETag header set by the next handler is respected.
+func ETag(opts *ETagOptions) workers.Middleware {
+	if opts == nil {
+		opts = &ETagOptions{}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Method != http.MethodGet && req.Method != http.MethodHead {
+				next.ServeHTTP(w, req)
+				return
+			}
+			bw := &bufferedWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(bw, req)
+			if bw.status != http.StatusOK {
+				bw.flush()
+				return
+			}
+			etag := w.Header().Get("ETag")
+			if etag == "" {
+				var err error
+				etag, err = ComputeETag(bw.buf.Bytes(), opts.Weak)
+				if err != nil {
+					bw.flush()
+					return
+				}
+			}
+			var lastModified time.Time
+			if lm := w.Header().Get("Last-Modified"); lm != "" {
+				lastModified, _ = http.ParseTime(lm)
+			}
+			if WriteNotModified(w, req, etag, lastModified) {
+				return
+			}
+			bw.flush()
+		})
+	}
+}
+
+// bufferedWriter buffers the status and the body of the response until flush is called.
+type bufferedWriter struct {
+	http.ResponseWriter
+	status int
+	buf    bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	return w.buf.Write(b)
+}
+
+// flush writes buffered response to the underlying ResponseWriter.
+func (w *bufferedWriter) flush() {
+	w.ResponseWriter.WriteHeader(w.status)
+	_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+}
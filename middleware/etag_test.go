@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIsNotModified(t *testing.T) {
+	lastModified := time.Date(2022, 5, 13, 10, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		method       string
+		header       http.Header
+		etag         string
+		lastModified time.Time
+		want         bool
+	}{
+		"matched ETag": {
+			method: http.MethodGet,
+			header: http.Header{"If-None-Match": {`"a"`, ` "b"`}},
+			etag:   `"b"`,
+			want:   true,
+		},
+		"matched weak ETag": {
+			method: http.MethodGet,
+			header: http.Header{"If-None-Match": {`W/"a"`}},
+			etag:   `"a"`,
+			want:   true,
+		},
+		"wildcard": {
+			method: http.MethodHead,
+			header: http.Header{"If-None-Match": {"*"}},
+			etag:   `"a"`,
+			want:   true,
+		},
+		"unmatched ETag": {
+			method: http.MethodGet,
+			header: http.Header{"If-None-Match": {`"a"`}},
+			etag:   `"b"`,
+			want:   false,
+		},
+		"If-None-Match takes precedence over If-Modified-Since": {
+			method: http.MethodGet,
+			header: http.Header{
+				"If-None-Match":     {`"a"`},
+				"If-Modified-Since": {lastModified.Format(http.TimeFormat)},
+			},
+			etag:         `"b"`,
+			lastModified: lastModified,
+			want:         false,
+		},
+		"not modified since": {
+			method:       http.MethodGet,
+			header:       http.Header{"If-Modified-Since": {lastModified.Format(http.TimeFormat)}},
+			lastModified: lastModified.Add(500 * time.Millisecond),
+			want:         true,
+		},
+		"modified since": {
+			method:       http.MethodGet,
+			header:       http.Header{"If-Modified-Since": {lastModified.Format(http.TimeFormat)}},
+			lastModified: lastModified.Add(time.Second),
+			want:         false,
+		},
+		"unsafe method": {
+			method: http.MethodPost,
+			header: http.Header{"If-None-Match": {`"a"`}},
+			etag:   `"a"`,
+			want:   false,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequest(tc.method, "/", nil)
+			req.Header = tc.header
+			if got := IsNotModified(req, tc.etag, tc.lastModified); got != tc.want {
+				t.Errorf("IsNotModified() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
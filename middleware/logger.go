@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/syumai/workers"
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/internal/debuglog"
+	"github.com/syumai/workers/internal/httputil"
+	"github.com/syumai/workers/internal/jsutil"
+	"github.com/syumai/workers/timing"
+)
+
+// LogEntry represents a log entry of a request handled by the Logger middleware.
+type LogEntry struct {
+	Method   string
+	Path     string
+	Status   int
+	Size     int64
+	Duration time.Duration
+	// CPUTime is the CPU time of the isolate consumed while handling the request, measured by timing.Stopwatch.
+	// It is zero if the runtime doesn't report CPU time.
+	CPUTime time.Duration
+	// RayID is the value of Cf-Ray header. This is empty in local development.
+	RayID string
+	// Colo is the IATA code of the data center which handled the request. This is empty in local development.
+	Colo string
+}
+
+// LoggerOptions represents options of the Logger middleware.
+type LoggerOptions struct {
+	// Sink receives log entries. Defaults to DebugLogSink, so requests are logged only if diagnostic logs are enabled.
+	// Use ConsoleLogSink or logging.RequestLogSink to always log requests.
+	Sink func(req *http.Request, entry *LogEntry)
+}
+
+// DebugLogSink logs the entry as diagnostic logs enabled by workers.SetDebug. It does nothing if they are disabled.
+func DebugLogSink(_ *http.Request, entry *LogEntry) {
+	if !debuglog.Enabled() {
+		return
+	}
+	args := []any{
+		"method", entry.Method,
+		"path", entry.Path,
+		"status", entry.Status,
+		"size", entry.Size,
+		"duration", entry.Duration,
+		"ray_id", entry.RayID,
+		"colo", entry.Colo,
+	}
+	if entry.CPUTime > 0 {
+		args = append(args, "cpu_time", entry.CPUTime)
+	}
+	debuglog.Log("middleware: request", args...)
+}
+
+// ConsoleLogSink writes the entry to console.log as a JSON object, so Workers Logs can index its fields.
+func ConsoleLogSink(_ *http.Request, entry *LogEntry) {
+	fields := map[string]any{
+		"method":      entry.Method,
+		"path":        entry.Path,
+		"status":      entry.Status,
+		"size":        entry.Size,
+		"duration_ms": float64(entry.Duration) / float64(time.Millisecond),
+		"ray_id":      entry.RayID,
+		"colo":        entry.Colo,
+	}
+	if entry.CPUTime > 0 {
+		fields["cpu_ms"] = float64(entry.CPUTime) / float64(time.Millisecond)
+	}
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return
+	}
+	jsutil.Global.Get("console").Call("log", string(b))
+}
+
+// Logger returns a middleware logging method, path, status, size, duration and CPU time of requests.
+//   - The duration is measured by performance.now, since Date is frozen while the request is processed.
+//   - The CPU time is logged only if the runtime reports it. See the timing package.
+//   - The duration doesn't include the time to stream the body after the handler returns.
+func Logger(opts *LoggerOptions) workers.Middleware {
+	sink := DebugLogSink
+	if opts != nil && opts.Sink != nil {
+		sink = opts.Sink
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			watch := timing.Start()
+			sw := httputil.NewStatusWriter(w)
+			defer func() {
+				entry := &LogEntry{
+					Method:   req.Method,
+					Path:     req.URL.Path,
+					Status:   sw.Status,
+					Size:     sw.Size,
+					Duration: watch.Wall(),
+					RayID:    req.Header.Get("Cf-Ray"),
+				}
+				entry.CPUTime, _ = watch.CPU()
+				if props, err := cloudflare.NewIncomingProperties(req.Context()); err == nil {
+					entry.Colo = props.Colo
+				}
+				sink(req, entry)
+			}()
+			next.ServeHTTP(sw, req)
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/syumai/workers/internal/debuglog"
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/runtimecontext"
+)
+
+// captureDebugLog enables diagnostic logs recorded as lines, and disables them when the test finishes.
+// Tests using it must not be parallel, since the function of diagnostic logs is global.
+func captureDebugLog(t *testing.T) func() []string {
+	t.Helper()
+	var mu sync.Mutex
+	var lines []string
+	debuglog.SetLogFunc(func(msg string, args ...any) {
+		mu.Lock()
+		defer mu.Unlock()
+		lines = append(lines, fmt.Sprint(append([]any{msg}, args...)...))
+	})
+	t.Cleanup(func() { debuglog.SetLogFunc(nil) })
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), lines...)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	h := Logger(nil)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	newRequest := func(path string) *http.Request {
+		reqObj := js.ValueOf(map[string]any{"cf": map[string]any{"colo": "NRT"}})
+		ctx := runtimecontext.New(context.Background(), reqObj, js.ValueOf(map[string]any{}))
+		return httptest.NewRequest(http.MethodGet, path, nil).WithContext(ctx)
+	}
+	// requests are not logged while diagnostic logs are disabled.
+	h.ServeHTTP(httptest.NewRecorder(), newRequest("/a"))
+
+	lines := captureDebugLog(t)
+	h.ServeHTTP(httptest.NewRecorder(), newRequest("/b"))
+	got := lines()
+	if len(got) != 1 {
+		t.Fatalf("want 1 log, got %q", got)
+	}
+	for _, want := range []string{"/b", "418", "NRT"} {
+		if !strings.Contains(got[0], want) {
+			t.Errorf("want %s logged, got %q", want, got[0])
+		}
+	}
+}
+
+func TestRecover(t *testing.T) {
+	lines := captureDebugLog(t)
+	var onPanic any
+	h := Recover(&RecoverOptions{
+		OnPanic: func(req *http.Request, recovered any, stack []byte) {
+			onPanic = recovered
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		panic("boom")
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("want status 500, got %d", rec.Code)
+	}
+	if onPanic != "boom" {
+		t.Errorf("want OnPanic called with the recovered value, got %v", onPanic)
+	}
+	got := lines()
+	if len(got) != 1 || !strings.Contains(got[0], "boom") || !strings.Contains(got[0], "goroutine") {
+		t.Errorf("want the panic and the stack logged, got %q", got)
+	}
+
+	written := Recover(nil)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("after header")
+	}))
+	rec = httptest.NewRecorder()
+	written.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("want the written status kept, got %d", rec.Code)
+	}
+}
+
+func TestLogger_Sink(t *testing.T) {
+	tests := map[string]struct {
+		cf      map[string]any
+		handler http.HandlerFunc
+		want    LogEntry
+	}{
+		"written": {
+			cf: map[string]any{"colo": "NRT"},
+			handler: func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte("hello"))
+			},
+			want: LogEntry{Method: http.MethodPost, Path: "/a", Status: http.StatusCreated, Size: 5, RayID: "8a1b2c3d4e5f6a7b-NRT", Colo: "NRT"},
+		},
+		"implicit status": {
+			cf: map[string]any{"colo": "NRT"},
+			handler: func(w http.ResponseWriter, req *http.Request) {
+				w.Write([]byte("hi"))
+			},
+			want: LogEntry{Method: http.MethodPost, Path: "/a", Status: http.StatusOK, Size: 2, RayID: "8a1b2c3d4e5f6a7b-NRT", Colo: "NRT"},
+		},
+		"local development": {
+			handler: func(w http.ResponseWriter, req *http.Request) {},
+			want:    LogEntry{Method: http.MethodPost, Path: "/a", Status: http.StatusOK, RayID: "8a1b2c3d4e5f6a7b-NRT"},
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var got *LogEntry
+			h := Logger(&LoggerOptions{
+				Sink: func(req *http.Request, entry *LogEntry) {
+					got = entry
+				},
+			})(tc.handler)
+			reqObj := map[string]any{}
+			if tc.cf != nil {
+				reqObj["cf"] = tc.cf
+			}
+			ctx := runtimecontext.New(context.Background(), js.ValueOf(reqObj), js.ValueOf(map[string]any{}))
+			req := httptest.NewRequest(http.MethodPost, "/a?q=1", nil).WithContext(ctx)
+			req.Header.Set("Cf-Ray", "8a1b2c3d4e5f6a7b-NRT")
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if got == nil {
+				t.Fatal("want the entry passed to the sink")
+			}
+			if got.Duration < 0 {
+				t.Errorf("want a non-negative duration, got %v", got.Duration)
+			}
+			got.Duration, got.CPUTime = 0, 0
+			if *got != tc.want {
+				t.Errorf("want %+v, got %+v", tc.want, *got)
+			}
+		})
+	}
+}
+
+func TestConsoleLogSink(t *testing.T) {
+	console := js.Global().Get("console")
+	t.Cleanup(func() { js.Global().Set("console", console) })
+	var logged []string
+	js.Global().Set("console", js.ValueOf(map[string]any{
+		"log": js.FuncOf(func(_ js.Value, args []js.Value) any {
+			logged = append(logged, args[0].String())
+			return js.Undefined()
+		}),
+	}))
+
+	ConsoleLogSink(nil, &LogEntry{Method: http.MethodGet, Path: "/a", Status: http.StatusOK, Size: 5, Duration: 1500 * time.Microsecond, RayID: "r", Colo: "NRT"})
+	if len(logged) != 1 {
+		t.Fatalf("want 1 log, got %q", logged)
+	}
+	var got map[string]any
+	if err := json.Unmarshal([]byte(logged[0]), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"method": "GET", "path": "/a", "status": 200.0, "size": 5.0, "duration_ms": 1.5, "ray_id": "r", "colo": "NRT"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/syumai/workers"
+	"github.com/syumai/workers/cloudflare"
+)
+
+// PolicyAction is the action taken for requests matching a PolicyRule.
+type PolicyAction int
+
+const (
+	// PolicyAllow passes the request to the next handler.
+	PolicyAllow PolicyAction = iota
+	// PolicyDeny responds with status 403.
+	PolicyDeny
+	// PolicyChallenge serves the challenge, e.g. a page with Turnstile widget.
+	PolicyChallenge
+)
+
+// PolicyRule is a rule of RequestPolicy. Conditions which are set must all match.
+type PolicyRule struct {
+	// Countries matches ISO 3166-1 alpha-2 country codes (e.g. "JP").
+	Countries []string
+	// Continents matches continent codes (e.g. "EU").
+	Continents []string
+	// ASNs matches autonomous system numbers.
+	ASNs []int
+	// MaxBotScore matches requests whose bot score is from 1 to MaxBotScore.
+	// Requests without bot scores (Bot Management is disabled) never match.
+	MaxBotScore int
+	// VerifiedBot matches requests from verified bots (e.g. search engine crawlers).
+	VerifiedBot bool
+	// Match is a custom condition.
+	Match func(props *cloudflare.IncomingProperties, req *http.Request) bool
+	// Action is the action taken for matching requests.
+	Action PolicyAction
+}
+
+func (r *PolicyRule) matches(props *cloudflare.IncomingProperties, req *http.Request) bool {
+	if len(r.Countries) > 0 && !slices.ContainsFunc(r.Countries, func(c string) bool { return strings.EqualFold(c, props.Country) }) {
+		return false
+	}
+	if len(r.Continents) > 0 && !slices.ContainsFunc(r.Continents, func(c string) bool { return strings.EqualFold(c, props.Continent) }) {
+		return false
+	}
+	if len(r.ASNs) > 0 && !slices.Contains(r.ASNs, props.ASN) {
+		return false
+	}
+	if r.MaxBotScore > 0 {
+		score := props.BotManagement.Score
+		if score < 1 || score > r.MaxBotScore {
+			return false
+		}
+	}
+	if r.VerifiedBot && !props.BotManagement.VerifiedBot {
+		return false
+	}
+	if r.Match != nil && !r.Match(props, req) {
+		return false
+	}
+	return true
+}
+
+// RequestPolicy decides actions for requests by their `cf` properties.
+type RequestPolicy struct {
+	// Rules are evaluated in order, and the first matching rule decides the action.
+	Rules []*PolicyRule
+	// Default is the action for requests matching no rule.
+	Default PolicyAction
+	// MissingProperties is the action for requests without `cf` properties (e.g. in local development).
+	MissingProperties PolicyAction
+	// DenyHandler serves denied requests. Defaults to responding with status 403.
+	DenyHandler http.Handler
+	// ChallengeHandler serves challenged requests. Defaults to responding with status 403.
+	// Workers can't issue challenges of Cloudflare, so serve a page verifying the client by Turnstile here.
+	ChallengeHandler http.Handler
+}
+
+// Evaluate returns the action for the request with the properties.
+// props can be nil if the request doesn't have `cf` properties.
+func (p *RequestPolicy) Evaluate(props *cloudflare.IncomingProperties, req *http.Request) PolicyAction {
+	if props == nil {
+		return p.MissingProperties
+	}
+	for _, r := range p.Rules {
+		if r.matches(props, req) {
+			return r.Action
+		}
+	}
+	return p.Default
+}
+
+// Policy returns a middleware allowing, denying or challenging requests by the policy.
+//   - the `cf` properties of the request are obtained by cloudflare.NewIncomingProperties.
+//
+// Example allowing verified bots, challenging likely bots, and denying a country:
+//
+//	middleware.Policy(&middleware.RequestPolicy{
+//	  Rules: []*middleware.PolicyRule{
+//	    {VerifiedBot: true, Action: middleware.PolicyAllow},
+//	    {MaxBotScore: 29, Action: middleware.PolicyChallenge},
+//	    {Countries: []string{"KP"}, Action: middleware.PolicyDeny},
+//	  },
+//	})
+func Policy(p *RequestPolicy) workers.Middleware {
+	forbidden := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	})
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			props, err := cloudflare.NewIncomingProperties(req.Context())
+			if err != nil && !errors.Is(err, cloudflare.ErrIncomingPropertiesNotFound) {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			switch p.Evaluate(props, req) {
+			case PolicyDeny:
+				h := p.DenyHandler
+				if h == nil {
+					h = forbidden
+				}
+				h.ServeHTTP(w, req)
+			case PolicyChallenge:
+				h := p.ChallengeHandler
+				if h == nil {
+					h = forbidden
+				}
+				h.ServeHTTP(w, req)
+			default:
+				next.ServeHTTP(w, req)
+			}
+		})
+	}
+}
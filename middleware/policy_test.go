@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/syumai/workers/cloudflare"
+)
+
+func TestRequestPolicy_Evaluate(t *testing.T) {
+	p := &RequestPolicy{
+		Rules: []*PolicyRule{
+			{VerifiedBot: true, Action: PolicyAllow},
+			{MaxBotScore: 29, Action: PolicyChallenge},
+			{Countries: []string{"kp"}, Action: PolicyDeny},
+			{Continents: []string{"EU"}, ASNs: []int{64496}, Action: PolicyDeny},
+		},
+		Default:           PolicyAllow,
+		MissingProperties: PolicyDeny,
+	}
+	tests := map[string]struct {
+		props *cloudflare.IncomingProperties
+		want  PolicyAction
+	}{
+		"human": {
+			props: &cloudflare.IncomingProperties{Country: "JP", BotManagement: cloudflare.IncomingBotManagement{Score: 90}},
+			want:  PolicyAllow,
+		},
+		"verified bot": {
+			props: &cloudflare.IncomingProperties{Country: "KP", BotManagement: cloudflare.IncomingBotManagement{Score: 1, VerifiedBot: true}},
+			want:  PolicyAllow,
+		},
+		"likely bot": {
+			props: &cloudflare.IncomingProperties{Country: "JP", BotManagement: cloudflare.IncomingBotManagement{Score: 2}},
+			want:  PolicyChallenge,
+		},
+		"no bot score": {
+			props: &cloudflare.IncomingProperties{Country: "JP"},
+			want:  PolicyAllow,
+		},
+		"denied country": {
+			props: &cloudflare.IncomingProperties{Country: "KP"},
+			want:  PolicyDeny,
+		},
+		"all conditions must match": {
+			props: &cloudflare.IncomingProperties{Continent: "EU", ASN: 64497},
+			want:  PolicyAllow,
+		},
+		"missing properties": {
+			want: PolicyDeny,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if got := p.Evaluate(tc.props, httptest.NewRequest("GET", "/", nil)); got != tc.want {
+				t.Errorf("want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
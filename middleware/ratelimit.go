@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/syumai/workers"
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/cloudflare/coordination"
+)
+
+// RateLimiter is a storage strategy of the RateLimit middleware.
+type RateLimiter interface {
+	// Allow reports whether a request identified by the key is allowed.
+	// If not allowed, retryAfter is how long the client should wait before retrying.
+	Allow(ctx context.Context, key string) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// RateLimitOptions represents options of the RateLimit middleware.
+type RateLimitOptions struct {
+	// Limiter decides whether requests are allowed.
+	Limiter RateLimiter
+	// KeyFunc returns the key identifying the client. Defaults to KeyByIP.
+	// If it returns empty string, the request is not limited.
+	KeyFunc func(req *http.Request) string
+	// OnError decides how requests are handled when Limiter returns an error. It must be set to FailOpen or FailClosed.
+	OnError RateLimitFailureMode
+}
+
+// RateLimitFailureMode decides how requests are handled when the RateLimiter fails.
+type RateLimitFailureMode int
+
+const (
+	// FailOpen allows requests when the limiter fails, so an outage of its storage doesn't take the site down.
+	FailOpen RateLimitFailureMode = iota + 1
+	// FailClosed rejects requests with status 503 when the limiter fails, so the limit is never bypassed.
+	FailClosed
+)
+
+// KeyByIP returns the IP address of the client given in CF-Connecting-IP header.
+func KeyByIP(req *http.Request) string {
+	return req.Header.Get("CF-Connecting-IP")
+}
+
+// KeyByHeader returns KeyFunc using the value of the header (e.g. API key).
+func KeyByHeader(name string) func(req *http.Request) string {
+	return func(req *http.Request) string {
+		return req.Header.Get(name)
+	}
+}
+
+// RateLimit returns a middleware limiting request rates.
+//   - if the limit is exceeded, responds with status 429 and Retry-After header.
+//   - errors of the limiter are reported to hooks registered by workers.OnError, and handled by opts.OnError.
+//   - This function panics when opts.Limiter is nil, or opts.OnError is neither FailOpen nor FailClosed.
+func RateLimit(opts *RateLimitOptions) workers.Middleware {
+	if opts == nil || opts.Limiter == nil {
+		panic("middleware: RateLimitOptions.Limiter must be set")
+	}
+	if opts.OnError != FailOpen && opts.OnError != FailClosed {
+		panic("middleware: RateLimitOptions.OnError must be FailOpen or FailClosed")
+	}
+	keyFunc := opts.KeyFunc
+	if keyFunc == nil {
+		keyFunc = KeyByIP
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			key := keyFunc(req)
+			if key == "" {
+				next.ServeHTTP(w, req)
+				return
+			}
+			allowed, retryAfter, err := opts.Limiter.Allow(req.Context(), key)
+			if err != nil {
+				workers.ReportError(req.Context(), err, req)
+				if opts.OnError == FailClosed {
+					http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+					return
+				}
+				allowed = true
+			}
+			if !allowed {
+				if retryAfter > 0 {
+					w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				}
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// BindingRateLimiter is a RateLimiter backed by the native rate limiting binding.
+//   - https://developers.cloudflare.com/workers/runtime-apis/bindings/rate-limit/
+//   - the binding counts requests per Cloudflare location, and the counts are eventually consistent.
+//     Use DurableObjectRateLimiter if the limit must be exact.
+type BindingRateLimiter struct {
+	// Binding is a name of the rate limiting binding.
+	Binding string
+	// Limiter is used instead of Binding if it is set (e.g. workerstest.RateLimiter).
+	Limiter cloudflare.RateLimiterBinding
+	// Period is the period configured for the binding. This is used as Retry-After.
+	Period time.Duration
+}
+
+var _ RateLimiter = (*BindingRateLimiter)(nil)
+
+// Allow implements RateLimiter.
+func (l *BindingRateLimiter) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	rl := l.Limiter
+	if rl == nil {
+		binding, err := cloudflare.NewRateLimiter(ctx, l.Binding)
+		if err != nil {
+			return false, 0, err
+		}
+		rl = binding
+	}
+	ok, err := rl.Limit(key)
+	if err != nil {
+		return false, 0, err
+	}
+	return ok, l.Period, nil
+}
+
+// DurableObjectRateLimiter is a RateLimiter implementing fixed window counters by coordination.RateLimiter.
+//   - each key is counted by its own Durable Object of the Coordinator class, so the limit is exact across all locations
+//     and concurrent requests, in exchange for a round trip to the object per request.
+//   - Allow returns error if Limit or Period is not positive.
+type DurableObjectRateLimiter struct {
+	// Binding is a name of the Durable Object namespace binding of the Coordinator class (see coordination.Register).
+	Binding string
+	// Namespace is used instead of Binding if it is set (e.g. workerstest.DurableObjectNamespace).
+	Namespace cloudflare.DurableObjectNamespaceBinding
+	// Limit is the number of requests allowed within Period.
+	Limit int
+	// Period is the length of the window.
+	Period time.Duration
+}
+
+var _ RateLimiter = (*DurableObjectRateLimiter)(nil)
+
+// Allow implements RateLimiter.
+func (l *DurableObjectRateLimiter) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	if l.Limit <= 0 || l.Period <= 0 {
+		return false, 0, errors.New("middleware: DurableObjectRateLimiter.Limit and DurableObjectRateLimiter.Period must be positive")
+	}
+	ns := l.Namespace
+	if ns == nil {
+		binding, err := cloudflare.NewDurableObjectNamespace(ctx, l.Binding)
+		if err != nil {
+			return false, 0, err
+		}
+		ns = binding
+	}
+	res, err := coordination.NewRateLimiter(ns, key, l.Limit, l.Period).Allow()
+	if err != nil {
+		return false, 0, err
+	}
+	if res.Allowed {
+		return true, 0, nil
+	}
+	return false, max(time.Until(res.ResetAt), 0), nil
+}
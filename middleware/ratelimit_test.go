@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/syumai/workers/workerstest"
+)
+
+// stubLimiter is a RateLimiter returning the fixed result.
+type stubLimiter struct {
+	allowed    bool
+	retryAfter time.Duration
+	err        error
+}
+
+func (l *stubLimiter) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	return l.allowed, l.retryAfter, l.err
+}
+
+func TestRateLimit(t *testing.T) {
+	tests := map[string]struct {
+		limiter        RateLimiter
+		onError        RateLimitFailureMode
+		ip             string
+		wantStatus     int
+		wantRetryAfter string
+	}{
+		"allowed": {
+			limiter:    &stubLimiter{allowed: true},
+			onError:    FailOpen,
+			ip:         "192.0.2.1",
+			wantStatus: http.StatusOK,
+		},
+		"limited": {
+			limiter:        &stubLimiter{retryAfter: 1500 * time.Millisecond},
+			onError:        FailOpen,
+			ip:             "192.0.2.1",
+			wantStatus:     http.StatusTooManyRequests,
+			wantRetryAfter: "2",
+		},
+		"no key": {
+			limiter:    &stubLimiter{},
+			onError:    FailClosed,
+			wantStatus: http.StatusOK,
+		},
+		"fail open": {
+			limiter:    &stubLimiter{err: errors.New("unavailable")},
+			onError:    FailOpen,
+			ip:         "192.0.2.1",
+			wantStatus: http.StatusOK,
+		},
+		"fail closed": {
+			limiter:    &stubLimiter{err: errors.New("unavailable")},
+			onError:    FailClosed,
+			ip:         "192.0.2.1",
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			h := RateLimit(&RateLimitOptions{Limiter: tc.limiter, OnError: tc.onError})(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.ip != "" {
+				req.Header.Set("CF-Connecting-IP", tc.ip)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tc.wantStatus {
+				t.Errorf("want status %d, got %d", tc.wantStatus, rec.Code)
+			}
+			if got := rec.Header().Get("Retry-After"); got != tc.wantRetryAfter {
+				t.Errorf("want Retry-After %q, got %q", tc.wantRetryAfter, got)
+			}
+		})
+	}
+}
+
+func TestRateLimit_FailureModeRequired(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("want panic without OnError")
+		}
+	}()
+	RateLimit(&RateLimitOptions{Limiter: &stubLimiter{}})
+}
+
+func TestDurableObjectRateLimiter(t *testing.T) {
+	l := &DurableObjectRateLimiter{
+		Namespace: &workerstest.DurableObjectNamespace{NewObject: workerstest.NewCoordinatorObject},
+		Limit:     2,
+		Period:    time.Minute,
+	}
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if allowed, _, err := l.Allow(ctx, "a"); err != nil || !allowed {
+			t.Fatalf("request %d: want allowed, got %v, %v", i, allowed, err)
+		}
+	}
+	allowed, retryAfter, err := l.Allow(ctx, "a")
+	if err != nil || allowed {
+		t.Fatalf("want limited, got %v, %v", allowed, err)
+	}
+	if retryAfter <= 0 || retryAfter > time.Minute {
+		t.Errorf("want Retry-After within the period, got %v", retryAfter)
+	}
+	if allowed, _, err := l.Allow(ctx, "b"); err != nil || !allowed {
+		t.Errorf("want other keys counted separately, got %v, %v", allowed, err)
+	}
+	if _, _, err := (&DurableObjectRateLimiter{Namespace: l.Namespace}).Allow(ctx, "a"); err == nil {
+		t.Error("want error without Limit and Period")
+	}
+}
+
+func TestBindingRateLimiter(t *testing.T) {
+	l := &BindingRateLimiter{
+		Limiter: &workerstest.RateLimiter{LimitFunc: func(key string) (bool, error) {
+			return key == "allowed", nil
+		}},
+		Period: 10 * time.Second,
+	}
+	ctx := context.Background()
+	if allowed, _, err := l.Allow(ctx, "allowed"); err != nil || !allowed {
+		t.Errorf("want allowed, got %v, %v", allowed, err)
+	}
+	allowed, retryAfter, err := l.Allow(ctx, "limited")
+	if err != nil || allowed || retryAfter != 10*time.Second {
+		t.Errorf("want limited with the period as Retry-After, got %v, %v, %v", allowed, retryAfter, err)
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/syumai/workers"
+	"github.com/syumai/workers/internal/debuglog"
+	"github.com/syumai/workers/internal/httputil"
+)
+
+// RecoverOptions represents options of the Recover middleware.
+type RecoverOptions struct {
+	// ErrorHandler writes the response for the recovered panic.
+	// It is not called if the response header has already been written.
+	// Defaults to responding with status 500.
+	ErrorHandler func(w http.ResponseWriter, req *http.Request, recovered any)
+	// OnPanic is called with the recovered value and the stack trace, e.g. to report the panic to an external service.
+	OnPanic func(req *http.Request, recovered any, stack []byte)
+}
+
+// Recover returns a middleware recovering panics in the next handler.
+//   - The recovered value and the stack trace are logged as diagnostic logs enabled by workers.SetDebug.
+//   - The panic is reported to hooks registered by workers.OnError as *workers.PanicError.
+//   - http.ErrAbortHandler is recovered silently.
+func Recover(opts *RecoverOptions) workers.Middleware {
+	if opts == nil {
+		opts = &RecoverOptions{}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			rw := httputil.NewStatusWriter(w)
+			defer func() {
+				recovered := recover()
+				if recovered == nil || recovered == http.ErrAbortHandler {
+					return
+				}
+				stack := debug.Stack()
+				if debuglog.Enabled() {
+					debuglog.Log("middleware: recovered panic", "panic", fmt.Sprint(recovered), "stack", string(stack))
+				}
+				workers.ReportError(req.Context(), &workers.PanicError{Value: recovered, Stack: stack}, req)
+				if opts.OnPanic != nil {
+					opts.OnPanic(req, recovered, stack)
+				}
+				if rw.WroteHeader {
+					return
+				}
+				if opts.ErrorHandler != nil {
+					opts.ErrorHandler(w, req, recovered)
+					return
+				}
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}()
+			next.ServeHTTP(rw, req)
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/syumai/workers"
+)
+
+// reportedErrors holds errors reported to workers.OnError by requests.
+var reportedErrors sync.Map
+
+func init() {
+	workers.OnError(func(ctx context.Context, err error, req *http.Request) {
+		if req != nil {
+			reportedErrors.Store(req, err)
+		}
+	})
+}
+
+func TestRecover_Response(t *testing.T) {
+	tests := map[string]struct {
+		opts       *RecoverOptions
+		handler    http.HandlerFunc
+		wantStatus int
+		wantBody   string
+		wantReport bool
+	}{
+		"default": {
+			handler: func(w http.ResponseWriter, req *http.Request) {
+				panic("boom")
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   "Internal Server Error\n",
+			wantReport: true,
+		},
+		"ErrorHandler": {
+			opts: &RecoverOptions{
+				ErrorHandler: func(w http.ResponseWriter, req *http.Request, recovered any) {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					w.Write([]byte(recovered.(string)))
+				},
+			},
+			handler: func(w http.ResponseWriter, req *http.Request) {
+				panic("boom")
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody:   "boom",
+			wantReport: true,
+		},
+		"header written": {
+			opts: &RecoverOptions{
+				ErrorHandler: func(w http.ResponseWriter, req *http.Request, recovered any) {
+					t.Error("want ErrorHandler not called after the header is written")
+				},
+			},
+			handler: func(w http.ResponseWriter, req *http.Request) {
+				w.Write([]byte("partial"))
+				panic("boom")
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   "partial",
+			wantReport: true,
+		},
+		"ErrAbortHandler": {
+			handler: func(w http.ResponseWriter, req *http.Request) {
+				panic(http.ErrAbortHandler)
+			},
+			wantStatus: http.StatusOK,
+		},
+		"no panic": {
+			handler: func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+			wantStatus: http.StatusNoContent,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			Recover(tc.opts)(tc.handler).ServeHTTP(rec, req)
+			if rec.Code != tc.wantStatus {
+				t.Errorf("want status %d, got %d", tc.wantStatus, rec.Code)
+			}
+			if rec.Body.String() != tc.wantBody {
+				t.Errorf("want body %q, got %q", tc.wantBody, rec.Body.String())
+			}
+			reported, ok := reportedErrors.Load(req)
+			if ok != tc.wantReport {
+				t.Fatalf("want reported %v, got %v", tc.wantReport, reported)
+			}
+			if !ok {
+				return
+			}
+			var panicErr *workers.PanicError
+			if !errors.As(reported.(error), &panicErr) || panicErr.Value != "boom" || len(panicErr.Stack) == 0 {
+				t.Errorf("want PanicError with the stack reported, got %v", reported)
+			}
+		})
+	}
+}
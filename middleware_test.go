@@ -0,0 +1,74 @@
+package workers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func appendHeaderMiddleware(value string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Add("X-Trace", value)
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	r := NewRouter()
+	r.Use(appendHeaderMiddleware("router"))
+	ok := func(w http.ResponseWriter, req *http.Request) {}
+	r.GET("/", ok)
+	g := r.Group("/api")
+	g.GET("/before", ok)
+	g.Use(appendHeaderMiddleware("group"))
+	g.GET("/after", ok)
+	nested := g.Group("/nested")
+	nested.Use(appendHeaderMiddleware("nested"))
+	nested.GET("/", ok)
+
+	tests := map[string]struct {
+		path string
+		want []string
+	}{
+		"router middleware": {
+			path: "/",
+			want: []string{"router"},
+		},
+		"group middleware is not applied to routes registered before Use": {
+			path: "/api/before",
+			want: []string{"router"},
+		},
+		"group middleware": {
+			path: "/api/after",
+			want: []string{"router", "group"},
+		},
+		"nested group middleware": {
+			path: "/api/nested",
+			want: []string{"router", "group", "nested"},
+		},
+		"router middleware wraps not found": {
+			path: "/unknown",
+			want: []string{"router"},
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			got := rec.Header().Values("X-Trace")
+			if len(got) != len(tc.want) {
+				t.Fatalf("X-Trace = %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Fatalf("X-Trace = %v, want %v", got, tc.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+// Package ndjson streams newline delimited JSON (https://github.com/ndjson/ndjson-spec).
+//   - values are written into streaming response bodies as they arrive, and flushed one by one.
+//   - upstream bodies are decoded into typed channels without buffering the whole body.
+package ndjson
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// ContentType is the media type of NDJSON.
+const ContentType = "application/x-ndjson"
+
+// Write writes values received from ch as NDJSON into w until ch is closed or ctx is done.
+//   - if w implements http.Flusher (e.g. http.ResponseWriter), it is flushed after each value.
+//   - returns ctx.Err() if ctx is done before ch is closed.
+func Write[T any](ctx context.Context, w io.Writer, ch <-chan T) error {
+	enc := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	for {
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			// Encode writes the value followed by a newline.
+			if err := enc.Encode(v); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Serve sets Content-Type header of NDJSON, and writes values received from ch into the response.
+// It is Write with the context of req.
+func Serve[T any](w http.ResponseWriter, req *http.Request, ch <-chan T) error {
+	w.Header().Set("Content-Type", ContentType)
+	return Write(req.Context(), w, ch)
+}
+
+// Read decodes NDJSON in r into values sent to the returned channel.
+//   - the channel of values is closed at the end of r, or on an error.
+//   - the channel of errors receives exactly one value after the channel of values is closed: nil, or the error.
+//   - if ctx is done, reading stops and ctx.Err() is sent. Callers should cancel ctx when they stop receiving values.
+func Read[T any](ctx context.Context, r io.Reader) (<-chan T, <-chan error) {
+	ch := make(chan T)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(errc)
+		errc <- read(ctx, r, ch)
+	}()
+	return ch, errc
+}
+
+func read[T any](ctx context.Context, r io.Reader, ch chan<- T) error {
+	defer close(ch)
+	dec := json.NewDecoder(r)
+	for {
+		var v T
+		if err := dec.Decode(&v); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		select {
+		case ch <- v:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package ndjson
+
+import (
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type entry struct {
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+func TestServe(t *testing.T) {
+	ch := make(chan entry, 2)
+	ch <- entry{Level: "info", Message: "a"}
+	ch <- entry{Level: "error", Message: "b"}
+	close(ch)
+	rec := httptest.NewRecorder()
+	if err := Serve(rec, httptest.NewRequest("GET", "/", nil), ch); err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.Header().Get("Content-Type"); got != ContentType {
+		t.Errorf("want Content-Type %q, got %q", ContentType, got)
+	}
+	want := "{\"level\":\"info\",\"message\":\"a\"}\n{\"level\":\"error\",\"message\":\"b\"}\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("want body %q, got %q", want, got)
+	}
+	if !rec.Flushed {
+		t.Error("want response to be flushed")
+	}
+}
+
+func TestRead(t *testing.T) {
+	tests := map[string]struct {
+		body    string
+		want    []entry
+		wantErr bool
+	}{
+		"entries": {
+			body: "{\"level\":\"info\",\"message\":\"a\"}\n\n{\"level\":\"error\",\"message\":\"b\"}\n",
+			want: []entry{{Level: "info", Message: "a"}, {Level: "error", Message: "b"}},
+		},
+		"empty": {
+			body: "",
+		},
+		"malformed": {
+			body:    "{\"level\":\"info\",\"message\":\"a\"}\n{\"level\":",
+			want:    []entry{{Level: "info", Message: "a"}},
+			wantErr: true,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			ch, errc := Read[entry](context.Background(), strings.NewReader(tc.body))
+			var got []entry
+			for v := range ch {
+				got = append(got, v)
+			}
+			if err := <-errc; (err != nil) != tc.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
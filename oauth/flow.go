@@ -0,0 +1,162 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/syumai/workers"
+	"github.com/syumai/workers/secret"
+	"github.com/syumai/workers/session"
+)
+
+// pendingTTL is how long the pending authorization is valid.
+const pendingTTL = 10 * time.Minute
+
+// Pending is the authorization pending until the callback, stored in the session of Flow.
+type Pending struct {
+	State    string `json:"state"`
+	Verifier string `json:"verifier"`
+	Nonce    string `json:"nonce,omitempty"`
+	ReturnTo string `json:"return_to,omitempty"`
+}
+
+// Result is the result of the authorization passed to FlowOptions.OnLogin.
+type Result struct {
+	Token *Token
+	// IDToken is the verified ID token. It is nil if Config.Issuer is not set.
+	IDToken *IDToken
+	// ReturnTo is the "return_to" query parameter of the login request, which is a local path.
+	ReturnTo string
+}
+
+// FlowOptions represents options of NewFlow.
+type FlowOptions struct {
+	// OnLogin is called after the authorization succeeds.
+	// It typically saves the user into the session of the application by session.Manager.Renew, and redirects to Result.ReturnTo.
+	OnLogin func(w http.ResponseWriter, req *http.Request, result *Result) error
+	// CookieName is the name of the cookie of pending authorizations. Defaults to "oauth_pending".
+	CookieName string
+	// AuthParams returns extra parameters of the authorization request if set (e.g. "prompt").
+	AuthParams func(req *http.Request) url.Values
+}
+
+// Flow handles the authorization code flow. Login and Callback are handlers.
+type Flow struct {
+	config   *Config
+	pending  *session.Manager[Pending]
+	onLogin  func(w http.ResponseWriter, req *http.Request, result *Result) error
+	authArgs func(req *http.Request) url.Values
+}
+
+// NewFlow returns Flow storing pending authorizations to the store.
+//   - pending authorizations are stored in sessions of their own cookie which expire in 10 minutes, and can't be reused.
+//   - This function panics when OnLogin is nil.
+func NewFlow(config *Config, store session.StoreFunc, opts *FlowOptions) *Flow {
+	if opts == nil || opts.OnLogin == nil {
+		panic("oauth: FlowOptions.OnLogin must be set")
+	}
+	cookieName := opts.CookieName
+	if cookieName == "" {
+		cookieName = "oauth_pending"
+	}
+	return &Flow{
+		config: config,
+		pending: session.NewManager[Pending](store, &session.Options{
+			CookieName: cookieName,
+			TTL:        pendingTTL,
+		}),
+		onLogin:  opts.OnLogin,
+		authArgs: opts.AuthParams,
+	}
+}
+
+// Login redirects to the provider.
+//   - the "return_to" query parameter is passed to Result.ReturnTo if it is a local path.
+func (f *Flow) Login(w http.ResponseWriter, req *http.Request) {
+	workers.HandlerFunc(f.login).ServeHTTP(w, req)
+}
+
+func (f *Flow) login(w http.ResponseWriter, req *http.Request) error {
+	p, err := f.pending.Load(req)
+	if err != nil {
+		return err
+	}
+	p.Data = Pending{State: RandomString(), Verifier: RandomString(), ReturnTo: localPath(req.URL.Query().Get("return_to"))}
+	if f.config.Issuer != "" {
+		p.Data.Nonce = RandomString()
+	}
+	if err := f.pending.Renew(w, req, p); err != nil {
+		return err
+	}
+	var extra url.Values
+	if f.authArgs != nil {
+		extra = f.authArgs(req)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, req, f.config.AuthCodeURL(p.Data.State, p.Data.Verifier, p.Data.Nonce, extra), http.StatusFound)
+	return nil
+}
+
+// Callback handles the redirect from the provider, and calls FlowOptions.OnLogin.
+//   - if the state doesn't match or the provider returns an error, responds with status 400.
+//   - if the token exchange or the ID token verification fails, responds with status 502.
+func (f *Flow) Callback(w http.ResponseWriter, req *http.Request) {
+	workers.HandlerFunc(f.callback).ServeHTTP(w, req)
+}
+
+func (f *Flow) callback(w http.ResponseWriter, req *http.Request) error {
+	p, err := f.pending.Load(req)
+	if err != nil {
+		return err
+	}
+	// the pending authorization is used only once.
+	if err := f.pending.Destroy(w, req, p); err != nil {
+		return err
+	}
+	q := req.URL.Query()
+	if p.IsNew() || p.Data.State == "" ||
+		!secret.EqualString(q.Get("state"), p.Data.State) {
+		return workers.NewHTTPError(http.StatusBadRequest, "invalid state", nil)
+	}
+	if code := q.Get("error"); code != "" {
+		return workers.NewHTTPError(http.StatusBadRequest, "authorization failed", &Error{Code: code, Description: q.Get("error_description")})
+	}
+	result, err := f.exchange(req.Context(), q.Get("code"), &p.Data)
+	if err != nil {
+		return workers.NewHTTPError(http.StatusBadGateway, "", err)
+	}
+	return f.onLogin(w, req, result)
+}
+
+func (f *Flow) exchange(ctx context.Context, code string, p *Pending) (*Result, error) {
+	if code == "" {
+		return nil, errors.New("oauth: missing code")
+	}
+	tok, err := f.config.Exchange(ctx, code, p.Verifier)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{Token: tok, ReturnTo: p.ReturnTo}
+	if f.config.Issuer != "" {
+		if tok.IDToken == "" {
+			return nil, errors.New("oauth: token response has no id_token")
+		}
+		result.IDToken, err = f.config.VerifyIDToken(ctx, tok.IDToken, p.Nonce)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// localPath returns the path if it is local to the site, to prevent open redirects. Otherwise returns "/".
+func localPath(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return "/"
+	}
+	return path
+}
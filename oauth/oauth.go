@@ -0,0 +1,240 @@
+// Package oauth implements the authorization code flow of OAuth 2.0 and OpenID Connect for "login with" providers.
+//   - state and PKCE (S256) are always used, and the nonce is used for OpenID Connect.
+//   - tokens are exchanged by the fetch client, and ID tokens are verified by the jwt package.
+//   - Flow stores the pending authorization in the session package, and handles the login and the callback.
+//   - https://datatracker.ietf.org/doc/html/rfc6749
+//   - https://openid.net/specs/openid-connect-core-1_0.html
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/syumai/workers/cloudflare/fetch"
+)
+
+// Endpoint is the endpoints of the provider.
+type Endpoint struct {
+	AuthURL  string
+	TokenURL string
+}
+
+var (
+	// GoogleEndpoint is the endpoint of Google.
+	GoogleEndpoint = Endpoint{
+		AuthURL:  "https://accounts.google.com/o/oauth2/v2/auth",
+		TokenURL: "https://oauth2.googleapis.com/token",
+	}
+	// GitHubEndpoint is the endpoint of GitHub. GitHub doesn't support OpenID Connect for users.
+	GitHubEndpoint = Endpoint{
+		AuthURL:  "https://github.com/login/oauth/authorize",
+		TokenURL: "https://github.com/login/oauth/access_token",
+	}
+)
+
+// Config is the configuration of the client of the provider.
+type Config struct {
+	ClientID     string
+	ClientSecret string
+	Endpoint     Endpoint
+	// RedirectURL is the URL of the callback.
+	RedirectURL string
+	Scopes      []string
+	// Issuer is the issuer of ID tokens. If set, ID tokens are required and verified by keys of JWKSURL.
+	Issuer string
+	// JWKSURL is the URL of the JSON Web Key Set of the issuer.
+	JWKSURL string
+	// Client sends requests to the provider. Defaults to fetch.NewClient().
+	Client fetch.Fetcher
+
+	keys keySet
+}
+
+// Google returns Config of Google with OpenID Connect, requesting "openid", "email" and "profile" scopes.
+func Google(clientID, clientSecret, redirectURL string) *Config {
+	return &Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Endpoint:     GoogleEndpoint,
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"openid", "email", "profile"},
+		Issuer:       "https://accounts.google.com",
+		JWKSURL:      "https://www.googleapis.com/oauth2/v3/certs",
+	}
+}
+
+// GitHub returns Config of GitHub, requesting "read:user" and "user:email" scopes.
+func GitHub(clientID, clientSecret, redirectURL string) *Config {
+	return &Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Endpoint:     GitHubEndpoint,
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"read:user", "user:email"},
+	}
+}
+
+func (c *Config) client() fetch.Fetcher {
+	if c.Client != nil {
+		return c.Client
+	}
+	return fetch.NewClient()
+}
+
+// RandomString returns a random URL safe string of 256 bits, used for states, nonces and PKCE verifiers.
+func RandomString() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// S256Challenge returns the PKCE code challenge of the verifier by S256 method.
+//   - https://datatracker.ietf.org/doc/html/rfc7636#section-4.2
+func S256Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the URL of the authorization request to redirect users to.
+//   - nonce is added only if it is not empty.
+//   - extra parameters (e.g. "prompt", "login_hint") are added if not nil.
+func (c *Config) AuthCodeURL(state, verifier, nonce string, extra url.Values) string {
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.ClientID},
+		"state":                 {state},
+		"code_challenge":        {S256Challenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	if c.RedirectURL != "" {
+		q.Set("redirect_uri", c.RedirectURL)
+	}
+	if len(c.Scopes) > 0 {
+		q.Set("scope", strings.Join(c.Scopes, " "))
+	}
+	if nonce != "" {
+		q.Set("nonce", nonce)
+	}
+	for k, v := range extra {
+		q[k] = v
+	}
+	sep := "?"
+	if strings.Contains(c.Endpoint.AuthURL, "?") {
+		sep = "&"
+	}
+	return c.Endpoint.AuthURL + sep + q.Encode()
+}
+
+// Token is the token response.
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	// IDToken is the raw ID token of OpenID Connect.
+	IDToken string `json:"id_token,omitempty"`
+	// Expiry is the time the access token expires. It is zero if unknown.
+	Expiry time.Time `json:"expiry"`
+}
+
+// Error is the error response of the token endpoint.
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e *Error) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("oauth: %s: %s", e.Code, e.Description)
+	}
+	return "oauth: " + e.Code
+}
+
+// Exchange exchanges the authorization code and the PKCE verifier for the token.
+func (c *Config) Exchange(ctx context.Context, code, verifier string) (*Token, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"code_verifier": {verifier},
+	}
+	if c.RedirectURL != "" {
+		form.Set("redirect_uri", c.RedirectURL)
+	}
+	return c.token(ctx, form)
+}
+
+// Refresh obtains a new token by the refresh token.
+func (c *Config) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	return c.token(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	})
+}
+
+// token sends the request to the token endpoint.
+func (c *Config) token(ctx context.Context, form url.Values) (*Token, error) {
+	form.Set("client_id", c.ClientID)
+	if c.ClientSecret != "" {
+		form.Set("client_secret", c.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Endpoint.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// GitHub responds with form encoding without Accept header.
+	req.Header.Set("Accept", "application/json")
+	res, err := c.client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oauth: error requesting token: %w", err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("oauth: error reading token response: %w", err)
+	}
+	var raw struct {
+		Token
+		Error
+		ExpiresIn json.Number `json:"expires_in"`
+	}
+	if mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type")); mediaType == "application/x-www-form-urlencoded" || mediaType == "text/plain" {
+		q, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, fmt.Errorf("oauth: error decoding token response: %w", err)
+		}
+		raw.AccessToken, raw.TokenType, raw.RefreshToken = q.Get("access_token"), q.Get("token_type"), q.Get("refresh_token")
+		raw.Scope, raw.IDToken, raw.ExpiresIn = q.Get("scope"), q.Get("id_token"), json.Number(q.Get("expires_in"))
+		raw.Code, raw.Description = q.Get("error"), q.Get("error_description")
+	} else if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("oauth: error decoding token response (%s): %w", res.Status, err)
+	}
+	if raw.Code != "" {
+		e := raw.Error
+		return nil, &e
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oauth: token endpoint responded with %s", res.Status)
+	}
+	if raw.AccessToken == "" {
+		return nil, errors.New("oauth: token response has no access_token")
+	}
+	tok := raw.Token
+	if sec, err := raw.ExpiresIn.Int64(); err == nil && sec > 0 {
+		tok.Expiry = time.Now().Add(time.Duration(sec) * time.Second)
+	}
+	return &tok, nil
+}
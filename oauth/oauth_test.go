@@ -0,0 +1,92 @@
+package oauth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/syumai/workers/oauth"
+	"github.com/syumai/workers/session"
+	"github.com/syumai/workers/workerstest"
+)
+
+func TestS256Challenge(t *testing.T) {
+	// https://datatracker.ietf.org/doc/html/rfc7636#appendix-B
+	got := oauth.S256Challenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	if want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"; got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+}
+
+func TestFlow(t *testing.T) {
+	tokenEndpoint := &workerstest.Fetcher{Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.PostFormValue("code") != "code1" || req.PostFormValue("code_verifier") == "" {
+			w.Header().Set("Content-Type", "application/x-www-form-urlencoded")
+			w.Write([]byte("error=bad_verification_code"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-www-form-urlencoded")
+		w.Write([]byte("access_token=token1&token_type=bearer&scope=read%3Auser"))
+	})}
+	config := oauth.GitHub("client", "secret", "https://example.com/callback")
+	config.Client = tokenEndpoint
+	kv := &workerstest.KVNamespace{}
+	var result *oauth.Result
+	flow := oauth.NewFlow(config, func(context.Context) (session.Store, error) {
+		return session.NewKVStore(kv, "oauth:"), nil
+	}, &oauth.FlowOptions{
+		OnLogin: func(w http.ResponseWriter, req *http.Request, r *oauth.Result) error {
+			result = r
+			http.Redirect(w, req, r.ReturnTo, http.StatusFound)
+			return nil
+		},
+	})
+
+	login := func(returnTo string) (*http.Cookie, url.Values) {
+		rec := httptest.NewRecorder()
+		flow.Login(rec, httptest.NewRequest(http.MethodGet, "/login?return_to="+url.QueryEscape(returnTo), nil))
+		if rec.Code != http.StatusFound {
+			t.Fatalf("want 302, got %d", rec.Code)
+		}
+		loc, err := url.Parse(rec.Header().Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rec.Result().Cookies()[0], loc.Query()
+	}
+	callback := func(cookie *http.Cookie, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/callback?"+query, nil)
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		flow.Callback(rec, req)
+		return rec
+	}
+
+	cookie, q := login("/dashboard")
+	if q.Get("client_id") != "client" || q.Get("code_challenge_method") != "S256" || q.Get("scope") != "read:user user:email" {
+		t.Errorf("unexpected authorization request: %v", q)
+	}
+	if rec := callback(cookie, "state=wrong&code=code1"); rec.Code != http.StatusBadRequest {
+		t.Errorf("want 400 for a wrong state, got %d", rec.Code)
+	}
+
+	cookie, q = login("https://evil.example.com/")
+	rec := callback(cookie, "state="+q.Get("state")+"&code=code1")
+	if rec.Code != http.StatusFound || result == nil || result.Token.AccessToken != "token1" {
+		t.Fatalf("want the login to succeed, got %d, %v", rec.Code, result)
+	}
+	if got := rec.Header().Get("Location"); got != "/" {
+		t.Errorf("want redirect to /, got %s", got)
+	}
+	// the pending authorization can't be reused.
+	if rec := callback(cookie, "state="+q.Get("state")+"&code=code1"); rec.Code != http.StatusBadRequest {
+		t.Errorf("want 400 for a reused state, got %d", rec.Code)
+	}
+
+	cookie, q = login("/")
+	if rec := callback(cookie, "state="+q.Get("state")+"&code=wrong"); rec.Code != http.StatusBadGateway {
+		t.Errorf("want 502 for a failed exchange, got %d", rec.Code)
+	}
+}
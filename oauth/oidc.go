@@ -0,0 +1,182 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/syumai/workers/jwt"
+)
+
+// ErrInvalidNonce is returned when "nonce" claim of the ID token doesn't match.
+var ErrInvalidNonce = errors.New("oauth: invalid nonce")
+
+// jwksTTL is how long keys of JWKSURL are cached in memory of the isolate.
+const jwksTTL = time.Hour
+
+// IDToken represents the verified ID token of OpenID Connect.
+type IDToken struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
+	// Claims holds all claims of the ID token.
+	Claims map[string]any
+}
+
+// VerifyIDToken verifies the ID token issued for the client.
+//   - the signature is verified by the key of JWKSURL chosen by "kid" header. RS256 and ES256 are supported.
+//   - "iss", "aud" and "exp" claims are required and validated.
+//   - if nonce is not empty, "nonce" claim must match it.
+func (c *Config) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*IDToken, error) {
+	if c.Issuer == "" || c.JWKSURL == "" {
+		return nil, errors.New("oauth: Issuer and JWKSURL must be set to verify ID tokens")
+	}
+	t, err := jwt.Parse(rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+	key, err := c.keys.key(ctx, c, t.Header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	t, err = jwt.Verify(rawIDToken, key, &jwt.VerifyOptions{
+		Issuer:            c.Issuer,
+		Audience:          c.ClientID,
+		RequireExpiration: true,
+		Leeway:            time.Minute,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if nonce != "" {
+		if got, _ := t.Claims["nonce"].(string); got != nonce {
+			return nil, ErrInvalidNonce
+		}
+	}
+	id := &IDToken{Claims: t.Claims}
+	id.Subject, _ = t.Claims["sub"].(string)
+	id.Email, _ = t.Claims["email"].(string)
+	id.EmailVerified, _ = t.Claims["email_verified"].(bool)
+	id.Name, _ = t.Claims["name"].(string)
+	id.Picture, _ = t.Claims["picture"].(string)
+	return id, nil
+}
+
+// keySet caches keys of JWKSURL.
+type keySet struct {
+	mu        sync.Mutex
+	keys      map[string]*jwt.Key
+	expiresAt time.Time
+	// loadedAt is the time of the last load of the keys, limiting reloads for unknown kids.
+	loadedAt time.Time
+	// load is the load of the keys in flight, shared by concurrent requests.
+	load *keySetLoad
+}
+
+// minJWKSReloadInterval is the minimum interval of reloading the keys for an unknown kid,
+// so that tokens with arbitrary kids can't cause subrequests on every request.
+const minJWKSReloadInterval = time.Minute
+
+type keySetLoad struct {
+	done chan struct{}
+	err  error
+}
+
+// key returns the key of kid. Keys are reloaded when kid is unknown or the cache is expired.
+//   - reloads for unknown kids are limited to once per minJWKSReloadInterval.
+//   - concurrent reloads are coalesced into one, and JWKSURL is fetched without holding the lock.
+func (s *keySet) key(ctx context.Context, c *Config, kid string) (*jwt.Key, error) {
+	now := time.Now()
+	s.mu.Lock()
+	key, ok := s.keys[kid]
+	switch {
+	case ok && now.Before(s.expiresAt):
+		s.mu.Unlock()
+		return key, nil
+	case !ok && now.Before(s.expiresAt) && now.Sub(s.loadedAt) < minJWKSReloadInterval:
+		s.mu.Unlock()
+		return nil, fmt.Errorf("oauth: signing key not found: %s", kid)
+	}
+	load := s.load
+	if load == nil {
+		load = &keySetLoad{done: make(chan struct{})}
+		s.load = load
+		s.loadedAt = now
+		s.mu.Unlock()
+		s.reload(ctx, c, load)
+	} else {
+		s.mu.Unlock()
+	}
+
+	select {
+	case <-load.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if load.err != nil {
+		return nil, load.err
+	}
+	s.mu.Lock()
+	key, ok = s.keys[kid]
+	s.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("oauth: signing key not found: %s", kid)
+	}
+	return key, nil
+}
+
+func (s *keySet) reload(ctx context.Context, c *Config, load *keySetLoad) {
+	defer func() {
+		s.mu.Lock()
+		s.load = nil
+		s.mu.Unlock()
+		close(load.done)
+	}()
+	keys, err := fetchJWKS(ctx, c)
+	if err != nil {
+		load.err = err
+		return
+	}
+	s.mu.Lock()
+	s.keys = keys
+	s.expiresAt = time.Now().Add(jwksTTL)
+	s.mu.Unlock()
+}
+
+func fetchJWKS(ctx context.Context, c *Config) (map[string]*jwt.Key, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.JWKSURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oauth: error fetching JWKS: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oauth: failed to fetch JWKS: %s", res.Status)
+	}
+	var set struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&set); err != nil {
+		return nil, fmt.Errorf("oauth: error decoding JWKS: %w", err)
+	}
+	keys := make(map[string]*jwt.Key, len(set.Keys))
+	for _, jwk := range set.Keys {
+		// keys of unsupported types are skipped.
+		key, err := jwt.ImportJWK(jwk)
+		if err != nil || key.ID == "" {
+			continue
+		}
+		keys[key.ID] = key
+	}
+	return keys, nil
+}
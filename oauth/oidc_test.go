@@ -0,0 +1,74 @@
+package oauth
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/syumai/workers/jwt"
+	"github.com/syumai/workers/workerstest"
+)
+
+func TestKeySet_Reload(t *testing.T) {
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	c := &Config{
+		JWKSURL: "https://example.com/jwks",
+		Client: &workerstest.Fetcher{Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			fetches.Add(1)
+			<-release
+			w.Write([]byte(`{"keys":[]}`))
+		})},
+	}
+	known := &jwt.Key{ID: "a"}
+	s := &keySet{keys: map[string]*jwt.Key{"a": known}, expiresAt: time.Now().Add(time.Hour)}
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = s.key(ctx, c, "unknown")
+		}()
+	}
+	for fetches.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	found := make(chan *jwt.Key)
+	go func() {
+		key, _ := s.key(ctx, c, "a")
+		found <- key
+	}()
+	select {
+	case key := <-found:
+		if key != known {
+			t.Errorf("want the cached key, got %v", key)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("want the cached key returned without waiting for the reload")
+	}
+
+	close(release)
+	wg.Wait()
+	for _, err := range errs {
+		if err == nil {
+			t.Error("want error for the unknown kid, got nil")
+		}
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("want concurrent reloads coalesced into 1 fetch, got %d", got)
+	}
+	if _, err := s.key(ctx, c, "other"); err == nil {
+		t.Error("want error for the unknown kid, got nil")
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("want reloads for unknown kids limited, got %d fetches", got)
+	}
+}
@@ -0,0 +1,45 @@
+// Package promise awaits JavaScript promises from Go, so subrequests made by JavaScript APIs (e.g. parallel KV gets
+// and fetches by syscall/js) can be fanned out and joined idiomatically.
+//   - on js/wasm, Value is syscall/js.Value, so promises of syscall/js can be given as is.
+//   - functions of this package must be called from a goroutine, not from a callback of JavaScript,
+//     since they block until promises settle.
+package promise
+
+import (
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jsutil"
+)
+
+// Value is a JavaScript value. It is syscall/js.Value on js/wasm.
+type Value = js.Value
+
+// Result represents the result of a settled promise.
+//   - Err is set if the promise is rejected.
+type Result = jsutil.PromiseResult
+
+// ErrNoPromises is returned by AwaitRace when no promises are given.
+var ErrNoPromises = jsutil.ErrNoPromises
+
+// Await waits for the promise to settle, and returns its value.
+//   - if the promise is rejected, returns error describing the reason.
+func Await(p Value) (Value, error) {
+	return jsutil.AwaitPromise(p)
+}
+
+// AwaitChan returns a channel which receives the result of the promise when it settles.
+//   - the channel is buffered, so it is fine not to receive the result (e.g. in select with timeouts).
+func AwaitChan(p Value) <-chan Result {
+	return jsutil.AwaitPromiseChan(p)
+}
+
+// AwaitAll waits for all promises to be fulfilled, and returns their values in the same order.
+//   - if one of promises is rejected, returns its error without waiting for the rest.
+func AwaitAll(promises []Value) ([]Value, error) {
+	return jsutil.AwaitAll(promises)
+}
+
+// AwaitRace waits for the first promise to settle, and returns its index and result.
+//   - if promises are empty, returns ErrNoPromises.
+func AwaitRace(promises []Value) (int, Value, error) {
+	return jsutil.AwaitRace(promises)
+}
@@ -0,0 +1,64 @@
+//go:build !(js && wasm)
+
+package promise_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/promise"
+)
+
+// settled returns a thenable which is settled with v synchronously. If reject is true, it is rejected.
+func settled(v any, reject bool) js.Value {
+	return js.ValueOf(map[string]any{
+		"then": js.FuncOf(func(_ js.Value, args []js.Value) any {
+			if reject {
+				args[1].Invoke(map[string]any{
+					"toString": js.FuncOf(func(js.Value, []js.Value) any { return v }),
+				})
+				return js.Undefined()
+			}
+			args[0].Invoke(v)
+			return js.Undefined()
+		}),
+	})
+}
+
+func TestAwait(t *testing.T) {
+	if v, err := promise.Await(settled("ok", false)); err != nil || v.String() != "ok" {
+		t.Errorf("want ok, got %v, %v", v, err)
+	}
+	if _, err := promise.Await(settled("failed", true)); err == nil {
+		t.Error("want error of the rejected promise, got nil")
+	}
+	if r := <-promise.AwaitChan(settled(1, false)); r.Err != nil || r.Value.Int() != 1 {
+		t.Errorf("want 1, got %v, %v", r.Value, r.Err)
+	}
+}
+
+func TestAwaitAll(t *testing.T) {
+	values, err := promise.AwaitAll([]promise.Value{settled(1, false), settled(2, false), settled(3, false)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range values {
+		if v.Int() != i+1 {
+			t.Errorf("want %d at %d, got %d", i+1, i, v.Int())
+		}
+	}
+	if _, err := promise.AwaitAll([]promise.Value{settled(1, false), settled("failed", true)}); err == nil {
+		t.Error("want error of the rejected promise, got nil")
+	}
+}
+
+func TestAwaitRace(t *testing.T) {
+	i, v, err := promise.AwaitRace([]promise.Value{settled("only", false)})
+	if err != nil || i != 0 || v.String() != "only" {
+		t.Errorf("want only at 0, got %v at %d, %v", v, i, err)
+	}
+	if _, _, err := promise.AwaitRace(nil); !errors.Is(err, promise.ErrNoPromises) {
+		t.Errorf("want ErrNoPromises, got %v", err)
+	}
+}
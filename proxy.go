@@ -0,0 +1,160 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/syumai/workers/cloudflare/fetch"
+	"github.com/syumai/workers/internal/httputil"
+)
+
+// hopHeaders are hop-by-hop headers, which are not forwarded by proxies.
+//   - https://www.rfc-editor.org/rfc/rfc9110#section-7.6.1
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// ProxyOptions represents options of Proxy.
+type ProxyOptions struct {
+	// Client sends requests to the upstream. Defaults to fetch.NewClient().
+	Client fetch.Fetcher
+	// Host is set to Host header of the upstream request. Defaults to the host of the upstream URL.
+	// Cloudflare honors it only for origins in the same zone.
+	Host string
+	// Timeout is the timeout until the upstream responds headers. It doesn't abort the body being streamed.
+	// Zero means no timeout.
+	Timeout time.Duration
+	// RewriteRequest modifies the upstream request before it is sent, e.g. to add credentials.
+	RewriteRequest func(out *http.Request)
+	// RewriteResponse modifies the upstream response before it is returned.
+	// If it returns error, Proxy returns the error.
+	RewriteResponse func(res *http.Response) error
+}
+
+// Proxy forwards the request to the upstream URL, and returns the response of the upstream.
+//   - the path of the request is appended to the path of the upstream URL, and queries are merged.
+//   - the method, headers and body are forwarded except hop-by-hop headers.
+//     X-Forwarded-For, X-Forwarded-Host and X-Forwarded-Proto headers are set.
+//   - the body of the response is streamed, so it must be closed after use.
+//   - if the timeout exceeds, returns context.DeadlineExceeded.
+func Proxy(req *http.Request, upstream string, opts *ProxyOptions) (*http.Response, error) {
+	if opts == nil {
+		opts = &ProxyOptions{}
+	}
+	target, err := url.Parse(upstream)
+	if err != nil {
+		return nil, err
+	}
+	out := req.Clone(req.Context())
+	out.RequestURI = ""
+	out.URL = proxyURL(target, req.URL)
+	out.Host = ""
+	for _, h := range hopHeaders {
+		out.Header.Del(h)
+	}
+	out.Header.Del("Host")
+	if opts.Host != "" {
+		out.Header.Set("Host", opts.Host)
+	}
+	setForwardedHeaders(out.Header, req)
+	if opts.RewriteRequest != nil {
+		opts.RewriteRequest(out)
+	}
+
+	client := opts.Client
+	if client == nil {
+		client = fetch.NewClient()
+	}
+	var res *http.Response
+	if opts.Timeout > 0 {
+		res, err = httputil.DoWithHeaderTimeout(out, opts.Timeout, client.Do)
+	} else {
+		res, err = client.Do(out)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range hopHeaders {
+		res.Header.Del(h)
+	}
+	if opts.RewriteResponse != nil {
+		if err := opts.RewriteResponse(res); err != nil {
+			res.Body.Close()
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// NewProxy returns a handler forwarding requests to the upstream URL by Proxy.
+//   - responds with status 504 on timeout, and status 502 on other errors.
+func NewProxy(upstream string, opts *ProxyOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		res, err := Proxy(req, upstream, opts)
+		if errors.Is(err, context.DeadlineExceeded) {
+			WriteError(w, req, NewHTTPError(http.StatusGatewayTimeout, "", err))
+			return
+		}
+		if err != nil {
+			WriteError(w, req, NewHTTPError(http.StatusBadGateway, "", err))
+			return
+		}
+		defer res.Body.Close()
+		header := w.Header()
+		for k, v := range res.Header {
+			header[k] = v
+		}
+		w.WriteHeader(res.StatusCode)
+		_, _ = io.Copy(w, res.Body)
+	})
+}
+
+// proxyURL joins the path of the request to the path of the target, and merges queries.
+func proxyURL(target, reqURL *url.URL) *url.URL {
+	u := *target
+	switch {
+	case strings.HasSuffix(target.Path, "/") && strings.HasPrefix(reqURL.Path, "/"):
+		u.Path = target.Path + reqURL.Path[1:]
+	case target.Path == "" || strings.HasPrefix(reqURL.Path, "/"):
+		u.Path = target.Path + reqURL.Path
+	default:
+		u.Path = target.Path + "/" + reqURL.Path
+	}
+	u.RawPath = ""
+	switch {
+	case target.RawQuery == "":
+		u.RawQuery = reqURL.RawQuery
+	case reqURL.RawQuery != "":
+		u.RawQuery = target.RawQuery + "&" + reqURL.RawQuery
+	}
+	return &u
+}
+
+// setForwardedHeaders sets X-Forwarded-* headers from the incoming request.
+func setForwardedHeaders(h http.Header, req *http.Request) {
+	if ip := req.Header.Get("CF-Connecting-IP"); ip != "" {
+		if prior := req.Header.Get("X-Forwarded-For"); prior != "" {
+			ip = prior + ", " + ip
+		}
+		h.Set("X-Forwarded-For", ip)
+	}
+	h.Set("X-Forwarded-Host", req.Host)
+	proto := "https"
+	if req.URL.Scheme != "" {
+		proto = req.URL.Scheme
+	}
+	h.Set("X-Forwarded-Proto", proto)
+}
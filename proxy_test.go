@@ -0,0 +1,135 @@
+package workers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fetcherFunc func(req *http.Request) (*http.Response, error)
+
+func (f fetcherFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestNewProxy(t *testing.T) {
+	tests := map[string]struct {
+		upstream   string
+		path       string
+		opts       *ProxyOptions
+		upstreamFn func(out *http.Request) (*http.Response, error)
+		wantURL    string
+		wantStatus int
+		wantBody   string
+	}{
+		"forwarded": {
+			upstream: "https://api.example.com/v1/?key=a",
+			path:     "/users/1?page=2",
+			opts: &ProxyOptions{
+				RewriteRequest: func(out *http.Request) { out.Header.Set("Authorization", "Bearer token") },
+				RewriteResponse: func(res *http.Response) error {
+					res.Header.Set("X-Proxy", "1")
+					return nil
+				},
+			},
+			wantURL:    "https://api.example.com/v1/users/1?key=a&page=2",
+			wantStatus: http.StatusOK,
+			wantBody:   "ok",
+		},
+		"timeout": {
+			upstream: "https://api.example.com",
+			path:     "/slow",
+			opts:     &ProxyOptions{Timeout: time.Millisecond},
+			upstreamFn: func(out *http.Request) (*http.Response, error) {
+				<-out.Context().Done()
+				return nil, out.Context().Err()
+			},
+			wantURL:    "https://api.example.com/slow",
+			wantStatus: http.StatusGatewayTimeout,
+			wantBody:   "Gateway Timeout\n",
+		},
+		"body streamed longer than timeout": {
+			upstream: "https://api.example.com",
+			path:     "/stream",
+			opts:     &ProxyOptions{Timeout: 10 * time.Millisecond},
+			upstreamFn: func(out *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(&slowReader{ctx: out.Context(), delay: 30 * time.Millisecond, b: []byte("ok")}),
+				}, nil
+			},
+			wantURL:    "https://api.example.com/stream",
+			wantStatus: http.StatusOK,
+			wantBody:   "ok",
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var out *http.Request
+			opts := tc.opts
+			opts.Client = fetcherFunc(func(req *http.Request) (*http.Response, error) {
+				out = req
+				if tc.upstreamFn != nil {
+					return tc.upstreamFn(req)
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Connection": {"close"}},
+					Body:       io.NopCloser(strings.NewReader("ok")),
+				}, nil
+			})
+			req := httptest.NewRequest(http.MethodGet, "https://example.com"+tc.path, nil)
+			req.Header.Set("CF-Connecting-IP", "192.0.2.1")
+			req.Header.Set("Connection", "keep-alive")
+			rec := httptest.NewRecorder()
+			NewProxy(tc.upstream, opts).ServeHTTP(rec, req)
+
+			if got := out.URL.String(); got != tc.wantURL {
+				t.Errorf("want upstream URL %q, got %q", tc.wantURL, got)
+			}
+			if got := out.Header.Get("X-Forwarded-For"); got != "192.0.2.1" {
+				t.Errorf("want X-Forwarded-For, got %q", got)
+			}
+			if got := out.Header.Get("Connection"); got != "" {
+				t.Errorf("want hop-by-hop headers to be removed, got %q", got)
+			}
+			if rec.Code != tc.wantStatus {
+				t.Errorf("want status %d, got %d", tc.wantStatus, rec.Code)
+			}
+			if got := rec.Body.String(); got != tc.wantBody {
+				t.Errorf("want body %q, got %q", tc.wantBody, got)
+			}
+			if tc.opts.RewriteResponse != nil && (rec.Header().Get("X-Proxy") != "1" || rec.Header().Get("Connection") != "") {
+				t.Errorf("unexpected response headers: %v", rec.Header())
+			}
+		})
+	}
+}
+
+// slowReader returns b after the delay, or the error of ctx if it is canceled.
+type slowReader struct {
+	ctx   context.Context
+	delay time.Duration
+	b     []byte
+}
+
+func (r *slowReader) Read(p []byte) (int, error) {
+	if len(r.b) == 0 {
+		return 0, io.EOF
+	}
+	select {
+	case <-time.After(r.delay):
+	case <-r.ctx.Done():
+		return 0, r.ctx.Err()
+	}
+	n := copy(p, r.b)
+	r.b = r.b[n:]
+	return n, nil
+}
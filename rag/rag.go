@@ -0,0 +1,206 @@
+// Package rag stores and retrieves documents for retrieval augmented generation,
+// by embedding texts with Workers AI and indexing them in Vectorize.
+//   - metadata of documents is typed. It is encoded into metadata of vectors by the codec of the jsoncodec package,
+//     so it must be encoded as a JSON object of strings, numbers, booleans or arrays of strings.
+//   - texts of documents are stored in metadata of vectors by default, so matches can be used as context of prompts
+//     without another lookup. Metadata of a vector is limited to 10 KiB, so long texts should be split into chunks.
+//
+// Example:
+//
+//	type Meta struct {
+//	  Source string `json:"source"`
+//	}
+//	index := rag.New[Meta](model, vectors, nil)
+//	err := index.Upsert(&rag.Document[Meta]{ID: "faq-1", Text: "...", Metadata: Meta{Source: "faq"}})
+//	matches, err := index.Query(question, &rag.QueryOptions{TopK: 3, Filter: map[string]any{"source": "faq"}})
+package rag
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/cloudflare/ai"
+	"github.com/syumai/workers/jsoncodec"
+)
+
+// DefaultTextKey is the metadata key storing texts of documents by default.
+const DefaultTextKey = "text"
+
+// maxUpsertBatch is the maximum number of vectors upserted at once.
+const maxUpsertBatch = 1000
+
+// Options represents options of New.
+type Options struct {
+	// Model is the text embedding model. Its dimensions must match the index. Defaults to ai.DefaultEmbeddingModel.
+	Model string
+	// Namespace is the namespace of vectors of documents. Defaults to no namespace.
+	Namespace string
+	// TextKey is the metadata key storing texts of documents. Defaults to DefaultTextKey.
+	//   - if it is "-", texts are not stored, and Text of matches is empty.
+	TextKey string
+}
+
+// Document is a text stored in the index with its metadata.
+type Document[M any] struct {
+	ID       string
+	Text     string
+	Metadata M
+}
+
+// Match is a document matched by Query.
+type Match[M any] struct {
+	ID string
+	// Score is the similarity to the query by the metric of the index.
+	Score    float64
+	Text     string
+	Metadata M
+}
+
+// QueryOptions represents options of Index.Query.
+type QueryOptions struct {
+	// TopK is the number of matches returned. Defaults to 5.
+	TopK int
+	// Filter limits matches by metadata indexes. See cloudflare.VectorizeQueryOptions.
+	Filter map[string]any
+	// MinScore drops matches whose score is lower than it.
+	MinScore float64
+}
+
+// Index stores documents of metadata type M.
+type Index[M any] struct {
+	ai    ai.Binding
+	index cloudflare.VectorizeIndexBinding
+	opts  Options
+}
+
+// New returns Index storing documents into the Vectorize index, embedding texts by the AI binding.
+func New[M any](model ai.Binding, index cloudflare.VectorizeIndexBinding, opts *Options) *Index[M] {
+	idx := &Index[M]{ai: model, index: index}
+	if opts != nil {
+		idx.opts = *opts
+	}
+	if idx.opts.TextKey == "" {
+		idx.opts.TextKey = DefaultTextKey
+	}
+	return idx
+}
+
+// Embed returns embeddings of the texts by the model of the index.
+func (idx *Index[M]) Embed(texts ...string) ([][]float32, error) {
+	return ai.Embed(idx.ai, idx.opts.Model, texts)
+}
+
+// Upsert embeds texts of the documents, and inserts them into the index or overwrites existing ones of the same IDs.
+//   - mutations of Vectorize are applied asynchronously, so upserted documents may not be queried immediately.
+func (idx *Index[M]) Upsert(docs ...*Document[M]) error {
+	texts := make([]string, len(docs))
+	for i, doc := range docs {
+		texts[i] = doc.Text
+	}
+	embeddings, err := idx.Embed(texts...)
+	if err != nil {
+		return err
+	}
+	vectors := make([]*cloudflare.VectorizeVector, len(docs))
+	for i, doc := range docs {
+		metadata, err := idx.encodeMetadata(doc)
+		if err != nil {
+			return err
+		}
+		vectors[i] = &cloudflare.VectorizeVector{
+			ID:        doc.ID,
+			Values:    embeddings[i],
+			Namespace: idx.opts.Namespace,
+			Metadata:  metadata,
+		}
+	}
+	for start := 0; start < len(vectors); start += maxUpsertBatch {
+		if _, err := idx.index.Upsert(vectors[start:min(start+maxUpsertBatch, len(vectors))]); err != nil {
+			return fmt.Errorf("rag: error upserting vectors: %w", err)
+		}
+	}
+	return nil
+}
+
+// Query embeds the text, and returns documents nearest to it in the order of scores.
+func (idx *Index[M]) Query(text string, opts *QueryOptions) ([]*Match[M], error) {
+	if opts == nil {
+		opts = &QueryOptions{}
+	}
+	embeddings, err := idx.Embed(text)
+	if err != nil {
+		return nil, err
+	}
+	result, err := idx.index.Query(embeddings[0], &cloudflare.VectorizeQueryOptions{
+		TopK:           opts.TopK,
+		Namespace:      idx.opts.Namespace,
+		ReturnMetadata: cloudflare.VectorizeMetadataAll,
+		Filter:         opts.Filter,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("rag: error querying vectors: %w", err)
+	}
+	matches := make([]*Match[M], 0, len(result.Matches))
+	for _, m := range result.Matches {
+		if m.Score < opts.MinScore {
+			continue
+		}
+		match := &Match[M]{ID: m.ID, Score: m.Score}
+		if err := idx.decodeMetadata(m.Metadata, match); err != nil {
+			return nil, err
+		}
+		matches = append(matches, match)
+	}
+	return matches, nil
+}
+
+// Delete deletes documents of the IDs.
+func (idx *Index[M]) Delete(ids ...string) error {
+	if _, err := idx.index.DeleteByIDs(ids); err != nil {
+		return fmt.Errorf("rag: error deleting vectors: %w", err)
+	}
+	return nil
+}
+
+// encodeMetadata returns metadata of the vector of the document, holding its metadata and text.
+func (idx *Index[M]) encodeMetadata(doc *Document[M]) (map[string]any, error) {
+	b, err := jsoncodec.Marshal(doc.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("rag: error encoding metadata of %s: %w", doc.ID, err)
+	}
+	var metadata map[string]any
+	if err := json.Unmarshal(b, &metadata); err != nil {
+		return nil, fmt.Errorf("rag: metadata of %s must be a JSON object: %w", doc.ID, err)
+	}
+	if idx.opts.TextKey != "-" {
+		if metadata == nil {
+			metadata = make(map[string]any, 1)
+		}
+		metadata[idx.opts.TextKey] = doc.Text
+	}
+	return metadata, nil
+}
+
+// decodeMetadata sets the text and the metadata of the match from metadata of the vector.
+func (idx *Index[M]) decodeMetadata(metadata map[string]any, match *Match[M]) error {
+	if idx.opts.TextKey != "-" {
+		match.Text, _ = metadata[idx.opts.TextKey].(string)
+		// the text is removed from a copy, so metadata returned by the binding is not modified.
+		rest := make(map[string]any, len(metadata))
+		for k, v := range metadata {
+			if k != idx.opts.TextKey {
+				rest[k] = v
+			}
+		}
+		metadata = rest
+	}
+	b, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	if err := jsoncodec.Unmarshal(b, &match.Metadata); err != nil {
+		return fmt.Errorf("rag: error decoding metadata of %s: %w", match.ID, err)
+	}
+	return nil
+}
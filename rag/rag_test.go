@@ -0,0 +1,130 @@
+package rag_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/syumai/workers/rag"
+	"github.com/syumai/workers/workerstest"
+)
+
+type meta struct {
+	Source string `json:"source"`
+	Year   int    `json:"year"`
+}
+
+// newFakeAI returns a fake embedding texts by counts of the words.
+func newFakeAI(words ...string) *workerstest.AI {
+	return &workerstest.AI{
+		RunFunc: func(model string, input any) ([]byte, error) {
+			b, err := json.Marshal(input)
+			if err != nil {
+				return nil, err
+			}
+			var in struct {
+				Text []string `json:"text"`
+			}
+			if err := json.Unmarshal(b, &in); err != nil {
+				return nil, err
+			}
+			data := make([][]float32, len(in.Text))
+			for i, text := range in.Text {
+				data[i] = make([]float32, len(words))
+				for j, w := range words {
+					data[i][j] = float32(strings.Count(text, w))
+				}
+			}
+			return json.Marshal(map[string]any{"shape": []int{len(data), len(words)}, "data": data})
+		},
+	}
+}
+
+// newIndex returns Index holding documents about cats, dogs and fish.
+func newIndex(t *testing.T) *rag.Index[meta] {
+	t.Helper()
+	index := rag.New[meta](newFakeAI("cat", "dog", "fish"), &workerstest.VectorizeIndex{}, nil)
+	err := index.Upsert(
+		&rag.Document[meta]{ID: "cats", Text: "cat cat", Metadata: meta{Source: "pets", Year: 2020}},
+		&rag.Document[meta]{ID: "dogs", Text: "dog", Metadata: meta{Source: "pets", Year: 2024}},
+		&rag.Document[meta]{ID: "fish", Text: "fish and a cat", Metadata: meta{Source: "sea", Year: 2024}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return index
+}
+
+func TestIndex_Query(t *testing.T) {
+	index := newIndex(t)
+	tests := map[string]struct {
+		query   string
+		opts    *rag.QueryOptions
+		wantIDs []string
+	}{
+		"nearest first": {
+			query:   "cat",
+			wantIDs: []string{"cats", "fish", "dogs"},
+		},
+		"top k": {
+			query:   "cat",
+			opts:    &rag.QueryOptions{TopK: 1},
+			wantIDs: []string{"cats"},
+		},
+		"filter": {
+			query:   "cat",
+			opts:    &rag.QueryOptions{Filter: map[string]any{"year": map[string]any{"$gte": 2024}}},
+			wantIDs: []string{"fish", "dogs"},
+		},
+		"min score": {
+			query:   "dog",
+			opts:    &rag.QueryOptions{MinScore: 0.5},
+			wantIDs: []string{"dogs"},
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			matches, err := index.Query(tc.query, tc.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, m := range matches {
+				ids = append(ids, m.ID)
+			}
+			if !reflect.DeepEqual(tc.wantIDs, ids) {
+				t.Errorf("want %v, got %v", tc.wantIDs, ids)
+			}
+		})
+	}
+}
+
+func TestIndex_Match(t *testing.T) {
+	index := newIndex(t)
+	matches, err := index.Query("fish", &rag.QueryOptions{TopK: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &rag.Match[meta]{ID: "fish", Score: matches[0].Score, Text: "fish and a cat", Metadata: meta{Source: "sea", Year: 2024}}
+	if !reflect.DeepEqual(want, matches[0]) {
+		t.Errorf("want %+v, got %+v", want, matches[0])
+	}
+}
+
+func TestIndex_Delete(t *testing.T) {
+	index := newIndex(t)
+	if err := index.Delete("cats"); err != nil {
+		t.Fatal(err)
+	}
+	matches, err := index.Query("cat", &rag.QueryOptions{TopK: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0].ID != "fish" {
+		t.Errorf("want fish after deleting cats, got %+v", matches)
+	}
+}
@@ -0,0 +1,173 @@
+package workers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ByteRange represents a range of bytes requested by Range header.
+type ByteRange struct {
+	Start  int64
+	Length int64
+}
+
+// ContentRange returns the value of Content-Range header for the range.
+func (r *ByteRange) ContentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.Start, r.Start+r.Length-1, size)
+}
+
+var (
+	// ErrUnsatisfiableRange is returned by ParseRange when no range overlaps the content.
+	ErrUnsatisfiableRange = errors.New("workers: unsatisfiable range")
+	// ErrInvalidRange is returned by ParseRange when the Range header is malformed.
+	ErrInvalidRange = errors.New("workers: invalid range")
+)
+
+// ParseRange parses the value of Range header for the content of the given size.
+//   - Only a single byte range is supported. If multiple ranges are requested, returns nil so the whole content is served.
+//   - if the header is empty, returns nil.
+//   - https://www.rfc-editor.org/rfc/rfc9110#section-14.2
+func ParseRange(header string, size int64) (*ByteRange, error) {
+	if header == "" {
+		return nil, nil
+	}
+	const prefix = "bytes="
+	if !strings.HasPrefix(header, prefix) {
+		return nil, ErrInvalidRange
+	}
+	spec := strings.TrimSpace(header[len(prefix):])
+	if strings.Contains(spec, ",") {
+		return nil, nil
+	}
+	startStr, endStr, found := strings.Cut(spec, "-")
+	if !found {
+		return nil, ErrInvalidRange
+	}
+	startStr, endStr = strings.TrimSpace(startStr), strings.TrimSpace(endStr)
+	if startStr == "" {
+		// suffix range: the last N bytes.
+		n, err := strconv.ParseInt(endStr, 10, 64)
+		if err != nil || n < 0 {
+			return nil, ErrInvalidRange
+		}
+		if n == 0 || size == 0 {
+			return nil, ErrUnsatisfiableRange
+		}
+		if n > size {
+			n = size
+		}
+		return &ByteRange{Start: size - n, Length: n}, nil
+	}
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil || start < 0 {
+		return nil, ErrInvalidRange
+	}
+	if start >= size {
+		return nil, ErrUnsatisfiableRange
+	}
+	end := size - 1
+	if endStr != "" {
+		end, err = strconv.ParseInt(endStr, 10, 64)
+		if err != nil || end < start {
+			return nil, ErrInvalidRange
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	return &ByteRange{Start: start, Length: end - start + 1}, nil
+}
+
+// ServeRange serves the content of the given size supporting Range requests.
+//   - if the content implements io.Seeker, it is used to skip bytes. Otherwise, skipped bytes are read and discarded.
+//   - see ServeRangeFunc for details.
+func ServeRange(w http.ResponseWriter, req *http.Request, content io.Reader, size int64) {
+	ServeRangeFunc(w, req, size, func(r *ByteRange) (io.Reader, error) {
+		if r == nil {
+			return content, nil
+		}
+		if s, ok := content.(io.Seeker); ok {
+			if _, err := s.Seek(r.Start, io.SeekStart); err != nil {
+				return nil, err
+			}
+		} else if _, err := io.CopyN(io.Discard, content, r.Start); err != nil {
+			return nil, err
+		}
+		return io.LimitReader(content, r.Length), nil
+	})
+}
+
+// ServeRangeFunc serves the content of the given size supporting Range requests.
+// open is called with the requested range, or nil for the whole content, and must return the reader of the range.
+// This is useful for sources supporting ranged reads natively, e.g. R2Bucket.GetRange.
+//   - responds with status 206 and Content-Range header for a satisfiable range.
+//   - responds with status 416 for an unsatisfiable range.
+//   - If-Range header is compared with ETag or Last-Modified header set on w.
+//   - Content-Type header should be set on w before calling this function.
+func ServeRangeFunc(w http.ResponseWriter, req *http.Request, size int64, open func(r *ByteRange) (io.Reader, error)) {
+	h := w.Header()
+	h.Set("Accept-Ranges", "bytes")
+	var rng *ByteRange
+	if (req.Method == http.MethodGet || req.Method == http.MethodHead) && checkIfRange(req, h) {
+		var err error
+		rng, err = ParseRange(req.Header.Get("Range"), size)
+		if errors.Is(err, ErrUnsatisfiableRange) {
+			h.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+			http.Error(w, http.StatusText(http.StatusRequestedRangeNotSatisfiable), http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		if err != nil {
+			// invalid Range header is ignored.
+			rng = nil
+		}
+	}
+	status := http.StatusOK
+	length := size
+	if rng != nil {
+		status = http.StatusPartialContent
+		length = rng.Length
+		h.Set("Content-Range", rng.ContentRange(size))
+	}
+	h.Set("Content-Length", strconv.FormatInt(length, 10))
+	if req.Method == http.MethodHead {
+		w.WriteHeader(status)
+		return
+	}
+	content, err := open(rng)
+	if err != nil {
+		h.Del("Content-Range")
+		h.Del("Content-Length")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(status)
+	_, _ = io.CopyN(w, content, length)
+}
+
+// checkIfRange reports whether the Range header should be respected according to If-Range header.
+//   - https://www.rfc-editor.org/rfc/rfc9110#section-13.1.5
+func checkIfRange(req *http.Request, h http.Header) bool {
+	ifRange := req.Header.Get("If-Range")
+	if ifRange == "" {
+		return true
+	}
+	if strings.HasPrefix(ifRange, `"`) {
+		// strong comparison is required.
+		etag := h.Get("ETag")
+		return etag != "" && etag == ifRange
+	}
+	t, err := http.ParseTime(ifRange)
+	if err != nil {
+		return false
+	}
+	lastModified, err := http.ParseTime(h.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+	return lastModified.Truncate(time.Second).Equal(t)
+}
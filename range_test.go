@@ -0,0 +1,80 @@
+package workers
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := map[string]struct {
+		header  string
+		size    int64
+		want    *ByteRange
+		wantErr error
+	}{
+		"empty": {
+			header: "",
+			size:   100,
+			want:   nil,
+		},
+		"start and end": {
+			header: "bytes=0-9",
+			size:   100,
+			want:   &ByteRange{Start: 0, Length: 10},
+		},
+		"start only": {
+			header: "bytes=90-",
+			size:   100,
+			want:   &ByteRange{Start: 90, Length: 10},
+		},
+		"end exceeds size": {
+			header: "bytes=90-200",
+			size:   100,
+			want:   &ByteRange{Start: 90, Length: 10},
+		},
+		"suffix": {
+			header: "bytes=-20",
+			size:   100,
+			want:   &ByteRange{Start: 80, Length: 20},
+		},
+		"suffix exceeds size": {
+			header: "bytes=-200",
+			size:   100,
+			want:   &ByteRange{Start: 0, Length: 100},
+		},
+		"multiple ranges are ignored": {
+			header: "bytes=0-9,20-29",
+			size:   100,
+			want:   nil,
+		},
+		"unsatisfiable": {
+			header:  "bytes=100-",
+			size:    100,
+			wantErr: ErrUnsatisfiableRange,
+		},
+		"invalid unit": {
+			header:  "items=0-1",
+			size:    100,
+			wantErr: ErrInvalidRange,
+		},
+		"end before start": {
+			header:  "bytes=10-5",
+			size:    100,
+			wantErr: ErrInvalidRange,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := ParseRange(tc.header, tc.size)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("ParseRange() error = %v, want %v", err, tc.wantErr)
+			}
+			if (got == nil) != (tc.want == nil) || (got != nil && *got != *tc.want) {
+				t.Errorf("ParseRange() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
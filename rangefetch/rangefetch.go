@@ -0,0 +1,286 @@
+// Package rangefetch reads large objects by parallel ranged subrequests, and assembles them into a single ordered stream.
+//   - parts are read ahead in parallel while the current part is streamed, improving throughput of large downloads
+//     without delaying the first byte.
+//   - sources of R2 buckets and HTTP origins are provided by R2 and HTTP.
+//   - Workers can open up to 6 connections simultaneously, so Concurrency should not exceed it.
+//     https://developers.cloudflare.com/workers/platform/limits/#simultaneous-open-connections
+package rangefetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/syumai/workers"
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/cloudflare/fetch"
+)
+
+// Source reads ranges of an object.
+type Source interface {
+	// ReadRange returns the reader of length bytes of the object from offset.
+	ReadRange(ctx context.Context, offset, length int64) (io.ReadCloser, error)
+}
+
+// SourceFunc is a function implementing Source.
+type SourceFunc func(ctx context.Context, offset, length int64) (io.ReadCloser, error)
+
+func (f SourceFunc) ReadRange(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+	return f(ctx, offset, length)
+}
+
+// ErrNotFound is returned by sources when the object doesn't exist.
+var ErrNotFound = errors.New("rangefetch: object not found")
+
+// R2 returns Source reading ranges of the object of the bucket by GetRange.
+func R2(bucket cloudflare.R2BucketBinding, key string) Source {
+	return SourceFunc(func(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+		obj, err := bucket.GetRange(key, &cloudflare.R2Range{Offset: offset, Length: length})
+		if err != nil {
+			return nil, err
+		}
+		if obj == nil {
+			return nil, ErrNotFound
+		}
+		return io.NopCloser(obj.Body), nil
+	})
+}
+
+// HTTP returns Source reading ranges of the URL by GET requests with Range header.
+//   - header is added to each request. Setting If-Match header avoids mixing parts of different versions of the object.
+//   - if the origin doesn't respond with status 206 for the requested range, returns error.
+//   - if client is nil, fetch.NewClient() is used.
+func HTTP(client fetch.Fetcher, url string, header http.Header) Source {
+	if client == nil {
+		client = fetch.NewClient()
+	}
+	return SourceFunc(func(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		for k, vs := range header {
+			req.Header[k] = append([]string(nil), vs...)
+		}
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+		res, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if res.StatusCode == http.StatusPartialContent && strings.HasPrefix(res.Header.Get("Content-Range"), "bytes "+strconv.FormatInt(offset, 10)+"-") {
+			return res.Body, nil
+		}
+		res.Body.Close()
+		if res.StatusCode == http.StatusNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("rangefetch: unexpected response of range %d-%d: %s", offset, offset+length-1, res.Status)
+	})
+}
+
+// Options represents options of NewReader and Serve.
+type Options struct {
+	// PartSize is the size of each ranged read. Defaults to 8 MiB.
+	PartSize int64
+	// Concurrency is the maximum number of parts read in parallel, including the part being streamed. Defaults to 4.
+	Concurrency int
+}
+
+const (
+	defaultPartSize    = 8 << 20
+	defaultConcurrency = 4
+	// chunkSize is the size of reads from parts.
+	chunkSize = 32 << 10
+)
+
+// reader reads parts in order while reading ahead the following parts.
+type reader struct {
+	ctx         context.Context
+	cancel      context.CancelFunc
+	src         Source
+	end         int64
+	partSize    int64
+	concurrency int
+
+	// parts are started parts which are not fully read yet, in order.
+	parts []*part
+	// next is the offset of the next part to be started.
+	next int64
+}
+
+// NewReader returns the reader of length bytes of the object from offset, read by parallel ranged reads of the source.
+//   - at most Concurrency parts are buffered in memory, so the memory usage is up to PartSize * Concurrency.
+//   - reading parts is canceled when ctx is canceled or the reader is closed.
+//   - if a ranged read fails or returns fewer bytes than requested, Read returns the error.
+func NewReader(ctx context.Context, src Source, offset, length int64, opts *Options) io.ReadCloser {
+	r := &reader{
+		src:         src,
+		end:         offset + length,
+		partSize:    defaultPartSize,
+		concurrency: defaultConcurrency,
+		next:        offset,
+	}
+	if opts != nil {
+		if opts.PartSize > 0 {
+			r.partSize = opts.PartSize
+		}
+		if opts.Concurrency > 0 {
+			r.concurrency = opts.Concurrency
+		}
+	}
+	r.ctx, r.cancel = context.WithCancel(ctx)
+	r.fill()
+	return r
+}
+
+// fill starts parts until Concurrency parts are in flight.
+func (r *reader) fill() {
+	for len(r.parts) < r.concurrency && r.next < r.end {
+		length := min(r.partSize, r.end-r.next)
+		p := newPart()
+		go p.run(r.ctx, r.src, r.next, length)
+		r.parts = append(r.parts, p)
+		r.next += length
+	}
+}
+
+func (r *reader) Read(b []byte) (int, error) {
+	for len(r.parts) > 0 {
+		n, err := r.parts[0].read(r.ctx, b)
+		if err == io.EOF {
+			r.parts = r.parts[1:]
+			r.fill()
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+	return 0, io.EOF
+}
+
+func (r *reader) Close() error {
+	r.cancel()
+	return nil
+}
+
+// part is a buffer of a ranged read, which can be read while it is written.
+type part struct {
+	mu  sync.Mutex
+	buf []byte
+	// err is io.EOF after all bytes are written, or the error of the ranged read.
+	err error
+	// wake is signaled when buf or err is updated.
+	wake chan struct{}
+}
+
+func newPart() *part {
+	return &part{wake: make(chan struct{}, 1)}
+}
+
+// run reads the range from the source into the part.
+func (p *part) run(ctx context.Context, src Source, offset, length int64) {
+	rc, err := src.ReadRange(ctx, offset, length)
+	if err != nil {
+		p.finish(err)
+		return
+	}
+	defer rc.Close()
+	var read int64
+	chunk := make([]byte, chunkSize)
+	for read < length {
+		if ctx.Err() != nil {
+			p.finish(ctx.Err())
+			return
+		}
+		n, err := rc.Read(chunk[:min(int64(len(chunk)), length-read)])
+		if n > 0 {
+			p.write(chunk[:n])
+			read += int64(n)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			p.finish(err)
+			return
+		}
+	}
+	if read < length {
+		p.finish(fmt.Errorf("rangefetch: range %d-%d: %w", offset, offset+length-1, io.ErrUnexpectedEOF))
+		return
+	}
+	p.finish(io.EOF)
+}
+
+func (p *part) write(b []byte) {
+	p.mu.Lock()
+	p.buf = append(p.buf, b...)
+	p.mu.Unlock()
+	p.notify()
+}
+
+func (p *part) finish(err error) {
+	p.mu.Lock()
+	p.err = err
+	p.mu.Unlock()
+	p.notify()
+}
+
+func (p *part) notify() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// read reads buffered bytes, waiting for them to be written.
+func (p *part) read(ctx context.Context, b []byte) (int, error) {
+	for {
+		p.mu.Lock()
+		if len(p.buf) > 0 {
+			n := copy(b, p.buf)
+			p.buf = p.buf[n:]
+			if len(p.buf) == 0 {
+				// release the buffer, since it may be large.
+				p.buf = nil
+			}
+			p.mu.Unlock()
+			return n, nil
+		}
+		err := p.err
+		p.mu.Unlock()
+		if err != nil {
+			return 0, err
+		}
+		select {
+		case <-p.wake:
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+}
+
+// Serve serves the object of the size supporting Range requests, reading the requested range by NewReader.
+//   - see workers.ServeRangeFunc for Range requests. Headers such as Content-Type and ETag should be set on w before calling this.
+func Serve(w http.ResponseWriter, req *http.Request, src Source, size int64, opts *Options) {
+	var rc io.ReadCloser
+	defer func() {
+		if rc != nil {
+			rc.Close()
+		}
+	}()
+	workers.ServeRangeFunc(w, req, size, func(r *workers.ByteRange) (io.Reader, error) {
+		if r == nil {
+			r = &workers.ByteRange{Start: 0, Length: size}
+		}
+		rc = NewReader(req.Context(), src, r.Start, r.Length, opts)
+		return rc, nil
+	})
+}
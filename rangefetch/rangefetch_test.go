@@ -0,0 +1,149 @@
+package rangefetch_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/syumai/workers/rangefetch"
+	"github.com/syumai/workers/workerstest"
+)
+
+type fetcherFunc func(req *http.Request) (*http.Response, error)
+
+func (f fetcherFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func newContent(size int) []byte {
+	b := make([]byte, size)
+	for i := range b {
+		b[i] = byte(i % 251)
+	}
+	return b
+}
+
+func TestNewReader(t *testing.T) {
+	content := newContent(1000)
+	tests := map[string]struct {
+		offset, length int64
+		opts           *rangefetch.Options
+		short          bool
+		wantErr        bool
+	}{
+		"whole content": {offset: 0, length: 1000, opts: &rangefetch.Options{PartSize: 64, Concurrency: 3}},
+		"range":         {offset: 100, length: 333, opts: &rangefetch.Options{PartSize: 50, Concurrency: 2}},
+		"single part":   {offset: 10, length: 20, opts: nil},
+		"short read":    {offset: 0, length: 1000, opts: &rangefetch.Options{PartSize: 100}, short: true, wantErr: true},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var mu sync.Mutex
+			var inFlight, maxInFlight int
+			src := rangefetch.SourceFunc(func(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+				mu.Lock()
+				inFlight++
+				maxInFlight = max(maxInFlight, inFlight)
+				mu.Unlock()
+				if tc.short && offset > 0 {
+					length--
+				}
+				return &closer{
+					Reader: bytes.NewReader(content[offset : offset+length]),
+					close: func() {
+						mu.Lock()
+						inFlight--
+						mu.Unlock()
+					},
+				}, nil
+			})
+			r := rangefetch.NewReader(context.Background(), src, tc.offset, tc.length, tc.opts)
+			defer r.Close()
+			got, err := io.ReadAll(r)
+			if tc.wantErr {
+				if !errors.Is(err, io.ErrUnexpectedEOF) {
+					t.Errorf("want io.ErrUnexpectedEOF, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, content[tc.offset:tc.offset+tc.length]) {
+				t.Errorf("unexpected content of %d bytes", len(got))
+			}
+			if tc.opts != nil && maxInFlight > tc.opts.Concurrency {
+				t.Errorf("want at most %d parts in flight, got %d", tc.opts.Concurrency, maxInFlight)
+			}
+		})
+	}
+}
+
+type closer struct {
+	io.Reader
+	close func()
+}
+
+func (c *closer) Close() error {
+	c.close()
+	return nil
+}
+
+func TestServe_R2(t *testing.T) {
+	content := newContent(500)
+	bucket := &workerstest.R2Bucket{}
+	if _, err := bucket.Put("video.mp4", io.NopCloser(bytes.NewReader(content)), nil); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/video.mp4", nil)
+	req.Header.Set("Range", "bytes=100-399")
+	rec := httptest.NewRecorder()
+	rangefetch.Serve(rec, req, rangefetch.R2(bucket, "video.mp4"), int64(len(content)), &rangefetch.Options{PartSize: 64})
+
+	if rec.Code != http.StatusPartialContent {
+		t.Fatalf("want status 206, got %d", rec.Code)
+	}
+	if !bytes.Equal(rec.Body.Bytes(), content[100:400]) {
+		t.Errorf("unexpected body of %d bytes", rec.Body.Len())
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	content := newContent(300)
+	client := fetcherFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("If-Match") != `"v1"` {
+			return &http.Response{StatusCode: http.StatusPreconditionFailed, Status: "412 Precondition Failed", Body: http.NoBody}, nil
+		}
+		var start, end int
+		if _, err := fmt.Sscanf(req.Header.Get("Range"), "bytes=%d-%d", &start, &end); err != nil {
+			return nil, err
+		}
+		return &http.Response{
+			StatusCode: http.StatusPartialContent,
+			Header:     http.Header{"Content-Range": {fmt.Sprintf("bytes %d-%d/%d", start, end, len(content))}},
+			Body:       io.NopCloser(bytes.NewReader(content[start : end+1])),
+		}, nil
+	})
+	src := rangefetch.HTTP(client, "https://origin.example.com/file", http.Header{"If-Match": {`"v1"`}})
+	r := rangefetch.NewReader(context.Background(), src, 0, 300, &rangefetch.Options{PartSize: 70})
+	got, err := io.ReadAll(r)
+	if err != nil || !bytes.Equal(got, content) {
+		t.Errorf("unexpected content of %d bytes, %v", len(got), err)
+	}
+
+	src = rangefetch.HTTP(client, "https://origin.example.com/file", nil)
+	_, err = io.ReadAll(rangefetch.NewReader(context.Background(), src, 0, 300, nil))
+	if err == nil || !strings.Contains(err.Error(), "412") {
+		t.Errorf("want error of the unexpected status, got %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+// Package render executes templates directly into streaming responses.
+//   - templates are executed into a buffer flushed periodically, so the page starts painting before
+//     slow data used by the template (e.g. functions fetching data) is resolved.
+//   - both *html/template.Template and *text/template.Template can be used. This package doesn't import them,
+//     so it doesn't increase the binary size for workers not using templates.
+package render
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Template is a template executed into the response. It is implemented by *html/template.Template and *text/template.Template.
+type Template interface {
+	ExecuteTemplate(w io.Writer, name string, data any) error
+}
+
+const defaultBufferSize = 4096
+
+// Options represents options of Stream.
+type Options struct {
+	// Head is the name of the template executed and flushed before the template, e.g. `<head>` of the page,
+	// so browsers can start loading stylesheets and scripts early.
+	Head string
+	// Status is the status code of the response. Defaults to 200.
+	Status int
+	// ContentType is Content-Type header of the response. Defaults to "text/html; charset=utf-8".
+	ContentType string
+	// BufferSize is the size of the buffer. Output is flushed when the buffer is full. Defaults to 4096.
+	BufferSize int
+	// FlushInterval is the interval of flushes of buffered output. Zero means output is flushed only when the buffer is full.
+	FlushInterval time.Duration
+}
+
+// Stream executes the template of the name into the response.
+//   - if the template fails before anything is flushed, responds with status 500.
+//     Otherwise, the response is truncated, since the status has already been sent.
+//   - returns the error of the template.
+func Stream(w http.ResponseWriter, tmpl Template, name string, data any, opts *Options) error {
+	if opts == nil {
+		opts = &Options{}
+	}
+	status := opts.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	contentType := opts.ContentType
+	if contentType == "" {
+		contentType = "text/html; charset=utf-8"
+	}
+	size := opts.BufferSize
+	if size <= 0 {
+		size = defaultBufferSize
+	}
+	fw := &flushWriter{w: w, status: status, contentType: contentType}
+	fw.buf = bufio.NewWriterSize(writerFunc(fw.write), size)
+	if opts.FlushInterval > 0 {
+		stop := fw.flushEvery(opts.FlushInterval)
+		defer stop()
+	}
+
+	if opts.Head != "" {
+		if err := tmpl.ExecuteTemplate(fw, opts.Head, data); err != nil {
+			return fw.fail(err)
+		}
+		fw.Flush()
+	}
+	if err := tmpl.ExecuteTemplate(fw, name, data); err != nil {
+		return fw.fail(err)
+	}
+	fw.Flush()
+	return nil
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+// flushWriter buffers output of templates, and writes the header on the first flush.
+type flushWriter struct {
+	w           http.ResponseWriter
+	status      int
+	contentType string
+
+	mu  sync.Mutex
+	buf *bufio.Writer
+	// started reports whether the header has been written.
+	started bool
+}
+
+func (fw *flushWriter) Write(p []byte) (int, error) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	return fw.buf.Write(p)
+}
+
+// write writes buffered output into the response. fw.mu must be held.
+func (fw *flushWriter) write(p []byte) (int, error) {
+	fw.start()
+	return fw.w.Write(p)
+}
+
+// start writes the header of the response once. fw.mu must be held.
+func (fw *flushWriter) start() {
+	if fw.started {
+		return
+	}
+	fw.started = true
+	fw.w.Header().Set("Content-Type", fw.contentType)
+	fw.w.WriteHeader(fw.status)
+}
+
+// Flush writes buffered output, and flushes the response.
+func (fw *flushWriter) Flush() {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	fw.start()
+	_ = fw.buf.Flush()
+	if f, ok := fw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// flushEvery flushes buffered output at the interval until the returned function is called.
+func (fw *flushWriter) flushEvery(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				fw.mu.Lock()
+				buffered := fw.buf.Buffered() > 0
+				fw.mu.Unlock()
+				if buffered {
+					fw.Flush()
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
+
+// fail responds with status 500 if nothing has been written, and returns err.
+func (fw *flushWriter) fail(err error) error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if !fw.started {
+		fw.started = true
+		fw.buf.Reset(io.Discard)
+		http.Error(fw.w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return err
+	}
+	_ = fw.buf.Flush()
+	return err
+}
@@ -0,0 +1,85 @@
+package render
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	tests := map[string]struct {
+		tmpl       string
+		opts       *Options
+		wantStatus int
+		wantBody   string
+		wantErr    bool
+		// wantFlushedBeforeBody is the body already flushed when the template calls fetch.
+		wantFlushedBeforeBody string
+	}{
+		"no options": {
+			tmpl:       `{{define "page"}}<p>{{fetch}}</p>{{end}}`,
+			wantStatus: http.StatusOK,
+			wantBody:   "<p>data</p>",
+		},
+		"head is flushed early": {
+			tmpl:                  `{{define "head"}}<head>{{.}}</head>{{end}}{{define "page"}}<p>{{fetch}}</p>{{end}}`,
+			opts:                  &Options{Head: "head", Status: http.StatusCreated},
+			wantStatus:            http.StatusCreated,
+			wantBody:              "<head>title</head><p>data</p>",
+			wantFlushedBeforeBody: "<head>title</head>",
+		},
+		"small buffer is flushed when full": {
+			tmpl:                  `{{define "page"}}<p>0123456789</p>{{fetch}}{{end}}`,
+			opts:                  &Options{BufferSize: 16},
+			wantStatus:            http.StatusOK,
+			wantBody:              "<p>0123456789</p>data",
+			wantFlushedBeforeBody: "<p>0123456789</p>",
+		},
+		"error before flush": {
+			tmpl:       `{{define "page"}}<p>{{fail}}</p>{{end}}`,
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   "Internal Server Error\n",
+			wantErr:    true,
+		},
+		"error after flush": {
+			tmpl:       `{{define "head"}}<head>{{.}}</head>{{end}}{{define "page"}}<p>{{fail}}</p>{{end}}`,
+			opts:       &Options{Head: "head"},
+			wantStatus: http.StatusOK,
+			wantBody:   "<head>title</head><p>",
+			wantErr:    true,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			rec := httptest.NewRecorder()
+			var flushed string
+			tmpl := template.Must(template.New("").Funcs(template.FuncMap{
+				"fetch": func() string {
+					flushed = rec.Body.String()
+					return "data"
+				},
+				"fail": func() (string, error) {
+					return "", errors.New("failed")
+				},
+			}).Parse(tc.tmpl))
+			err := Stream(rec, tmpl, "page", "title", tc.opts)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rec.Code != tc.wantStatus {
+				t.Errorf("want status %d, got %d", tc.wantStatus, rec.Code)
+			}
+			if got := rec.Body.String(); got != tc.wantBody {
+				t.Errorf("want body %q, got %q", tc.wantBody, got)
+			}
+			if flushed != tc.wantFlushedBeforeBody {
+				t.Errorf("want flushed %q before body, got %q", tc.wantFlushedBeforeBody, flushed)
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+// Package replay dispatches events synthesized from JSON fixtures to handlers of workers for development.
+//   - events are dispatched under the mock runtime on the host, so handler logic can be exercised and debugged by `go run`.
+//   - fetch, scheduled and queue events are supported.
+//   - bindings are not available, and environment variables are given by fixtures.
+//     APIs awaiting Promise of the runtime (e.g. workers.Go) panic.
+//
+// Example:
+//
+//	func main() {
+//	  opts := workers.Options{Fetch: router, Scheduled: cron.DefaultMux}
+//	  if len(os.Args) > 1 {
+//	    replay.Main(opts) // go run . fixtures/*.json
+//	    return
+//	  }
+//	  workers.Start(opts)
+//	}
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/syumai/workers"
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/runtimecontext"
+)
+
+// Types of events.
+const (
+	TypeFetch     = "fetch"
+	TypeScheduled = "scheduled"
+	TypeQueue     = "queue"
+)
+
+// Event is a fixture of an event.
+type Event struct {
+	// Type is the type of the event: "fetch", "scheduled" or "queue".
+	Type string `json:"type"`
+	// Env holds environment variables of the event.
+	Env map[string]string `json:"env,omitempty"`
+
+	// Request is the request of fetch events.
+	Request *Request `json:"request,omitempty"`
+
+	// Cron and ScheduledTime are of scheduled events. ScheduledTime defaults to the current time.
+	Cron          string    `json:"cron,omitempty"`
+	ScheduledTime time.Time `json:"scheduledTime,omitempty"`
+
+	// Queue and Messages are of queue events.
+	Queue    string     `json:"queue,omitempty"`
+	Messages []*Message `json:"messages,omitempty"`
+}
+
+// Request is a fixture of a request.
+type Request struct {
+	// Method defaults to GET.
+	Method string `json:"method,omitempty"`
+	// URL is the absolute URL, or the path on http://localhost.
+	URL    string            `json:"url"`
+	Header map[string]string `json:"headers,omitempty"`
+	Body   string            `json:"body,omitempty"`
+}
+
+// Message is a fixture of a message of queues.
+type Message struct {
+	// ID defaults to the index of the message.
+	ID string `json:"id,omitempty"`
+	// Body is the body of the message. JSON strings are delivered as text messages.
+	Body json.RawMessage `json:"body"`
+	// Attempts defaults to 1.
+	Attempts int `json:"attempts,omitempty"`
+	// Timestamp defaults to the current time.
+	Timestamp time.Time `json:"timestamp,omitempty"`
+}
+
+// Result is the result of the dispatched event.
+type Result struct {
+	Event *Event
+	// Err is the error returned by the handler.
+	Err error
+	// Response is the response of fetch events.
+	Response *http.Response
+	// Acked and Retried are IDs of messages of queue events.
+	//   - as well as the runtime, messages which are neither acked nor retried explicitly
+	//     are acked if the consumer succeeds, and retried otherwise.
+	Acked   []string
+	Retried []string
+}
+
+// Load reads fixtures from r. The fixture is an event object, or an array of them.
+func Load(r io.Reader) ([]*Event, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '[' {
+		var events []*Event
+		if err := json.Unmarshal(b, &events); err != nil {
+			return nil, fmt.Errorf("error decoding fixtures: %w", err)
+		}
+		return events, nil
+	}
+	var event Event
+	if err := json.Unmarshal(b, &event); err != nil {
+		return nil, fmt.Errorf("error decoding fixture: %w", err)
+	}
+	return []*Event{&event}, nil
+}
+
+// LoadFile reads fixtures from the file. See Load.
+func LoadFile(name string) ([]*Event, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	events, err := Load(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return events, nil
+}
+
+// Run registers the handler of requests by workers.Register, and dispatches the events in order.
+//   - handlers of other events must be registered by their packages before (e.g. cron.Schedule).
+func Run(opts workers.Options, events ...*Event) []*Result {
+	workers.Register(opts)
+	results := make([]*Result, len(events))
+	for i, e := range events {
+		results[i] = Dispatch(e)
+	}
+	return results
+}
+
+// Dispatch dispatches the event to the handler registered by workers.Register (or workers.Start), and waits for it.
+func Dispatch(e *Event) *Result {
+	r := &Result{Event: e}
+	runtimeCtxObj := newRuntimeContext(e.Env)
+	switch e.Type {
+	case TypeFetch:
+		r.Response, r.Err = dispatchFetch(e.Request, runtimeCtxObj)
+	case TypeScheduled:
+		scheduledTime := e.ScheduledTime
+		if scheduledTime.IsZero() {
+			scheduledTime = time.Now()
+		}
+		eventObj := js.ValueOf(map[string]any{
+			"cron":          e.Cron,
+			"scheduledTime": float64(scheduledTime.UnixMilli()),
+		})
+		r.Err = runtimecontext.Dispatch("handleScheduled", eventObj, runtimeCtxObj)
+	case TypeQueue:
+		r.Acked, r.Retried, r.Err = dispatchQueue(e, runtimeCtxObj)
+	default:
+		r.Err = fmt.Errorf("replay: unsupported event type %q", e.Type)
+	}
+	return r
+}
+
+// newRuntimeContext returns the runtime context object holding the environment variables.
+func newRuntimeContext(env map[string]string) js.Value {
+	envObj := make(map[string]any, len(env))
+	for k, v := range env {
+		envObj[k] = v
+	}
+	return js.ValueOf(map[string]any{
+		"env": envObj,
+		"ctx": map[string]any{},
+	})
+}
+
+func dispatchFetch(fixture *Request, runtimeCtxObj js.Value) (*http.Response, error) {
+	if fixture == nil {
+		return nil, errors.New("replay: request of the fetch event is missing")
+	}
+	method := fixture.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	u := fixture.URL
+	if strings.HasPrefix(u, "/") {
+		u = "http://localhost" + u
+	}
+	req, err := http.NewRequest(method, u, strings.NewReader(fixture.Body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range fixture.Header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	if err := runtimecontext.DispatchRequest(rec, req, runtimeCtxObj); err != nil {
+		return nil, err
+	}
+	res := rec.Result()
+	res.Request = req
+	return res, nil
+}
+
+// message state of queue events.
+const (
+	pending = iota
+	acked
+	retried
+)
+
+func dispatchQueue(e *Event, runtimeCtxObj js.Value) (ackedIDs, retriedIDs []string, err error) {
+	states := make([]int, len(e.Messages))
+	ids := make([]string, len(e.Messages))
+	setAll := func(state int) any {
+		for i := range states {
+			states[i] = state
+		}
+		return js.Undefined()
+	}
+	msgs := make([]any, len(e.Messages))
+	for i, m := range e.Messages {
+		i := i
+		ids[i] = m.ID
+		if ids[i] == "" {
+			ids[i] = fmt.Sprint(i)
+		}
+		var body any
+		if len(m.Body) > 0 {
+			if err := json.Unmarshal(m.Body, &body); err != nil {
+				return nil, nil, fmt.Errorf("replay: error decoding body of message %s: %w", ids[i], err)
+			}
+		}
+		attempts := m.Attempts
+		if attempts == 0 {
+			attempts = 1
+		}
+		timestamp := m.Timestamp
+		if timestamp.IsZero() {
+			timestamp = time.Now()
+		}
+		msgs[i] = map[string]any{
+			"id":       ids[i],
+			"body":     body,
+			"attempts": attempts,
+			"timestamp": map[string]any{
+				"getTime": js.FuncOf(func(js.Value, []js.Value) any { return float64(timestamp.UnixMilli()) }),
+			},
+			"ack":   js.FuncOf(func(js.Value, []js.Value) any { states[i] = acked; return js.Undefined() }),
+			"retry": js.FuncOf(func(js.Value, []js.Value) any { states[i] = retried; return js.Undefined() }),
+		}
+	}
+	batchObj := js.ValueOf(map[string]any{
+		"queue":    e.Queue,
+		"messages": msgs,
+		"ackAll":   js.FuncOf(func(js.Value, []js.Value) any { return setAll(acked) }),
+		"retryAll": js.FuncOf(func(js.Value, []js.Value) any { return setAll(retried) }),
+	})
+	err = runtimecontext.Dispatch("handleQueue", batchObj, runtimeCtxObj)
+	for i, state := range states {
+		if state == retried || (state == pending && err != nil) {
+			retriedIDs = append(retriedIDs, ids[i])
+			continue
+		}
+		ackedIDs = append(ackedIDs, ids[i])
+	}
+	return ackedIDs, retriedIDs, err
+}
+
+// Main dispatches events of fixture files given as command line arguments, and writes their results to stdout.
+//   - if an event fails, Main exits with status 1 after all events are dispatched.
+func Main(opts workers.Options) {
+	workers.Register(opts)
+	failed := false
+	for _, name := range os.Args[1:] {
+		events, err := LoadFile(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+			continue
+		}
+		for _, e := range events {
+			r := Dispatch(e)
+			r.Write(os.Stdout)
+			if r.Err != nil || (r.Response != nil && r.Response.StatusCode >= http.StatusInternalServerError) {
+				failed = true
+			}
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// Write writes the summary of the result to w.
+func (r *Result) Write(w io.Writer) {
+	switch r.Event.Type {
+	case TypeFetch:
+		if r.Response != nil {
+			fmt.Fprintf(w, "fetch %s %s: %s\n", r.Response.Request.Method, r.Response.Request.URL, r.Response.Status)
+			r.Response.Header.Write(w)
+			fmt.Fprintln(w)
+			io.Copy(w, r.Response.Body)
+			fmt.Fprintln(w)
+		}
+	case TypeScheduled:
+		fmt.Fprintf(w, "scheduled %q\n", r.Event.Cron)
+	case TypeQueue:
+		fmt.Fprintf(w, "queue %q: acked %v, retried %v\n", r.Event.Queue, r.Acked, r.Retried)
+	}
+	if r.Err != nil {
+		fmt.Fprintf(w, "%s: error: %v\n", r.Event.Type, r.Err)
+	}
+}
@@ -0,0 +1,82 @@
+package replay_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/syumai/workers"
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/cloudflare/cron"
+	"github.com/syumai/workers/cloudflare/queues"
+	"github.com/syumai/workers/replay"
+)
+
+const fixtures = `[
+  {"type": "fetch", "env": {"GREETING": "hello"}, "request": {"url": "/greet?name=gopher", "headers": {"X-Test": "1"}}},
+  {"type": "scheduled", "cron": "0 3 * * *"},
+  {"type": "queue", "queue": "jobs", "messages": [
+    {"id": "a", "body": {"n": 1}},
+    {"id": "b", "body": {"n": 2}},
+    {"id": "c", "body": "retry", "attempts": 2}
+  ]}
+]`
+
+func TestRun(t *testing.T) {
+	events, err := replay.Load(strings.NewReader(fixtures))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scheduled string
+	consume := queues.Consumer(func(ctx context.Context, batch *queues.MessageBatch) error {
+		for _, m := range batch.Messages {
+			if string(m.Bytes()) == "retry" {
+				m.Retry(nil)
+				continue
+			}
+			if _, err := queues.DecodeJSON[struct{ N int }](m); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	results := replay.Run(workers.Options{
+		Fetch: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			io.WriteString(w, cloudflare.Getenv(req.Context(), "GREETING")+", "+req.URL.Query().Get("name"))
+		}),
+		Scheduled: cron.Handler(func(ctx context.Context, event *cron.Event) error {
+			scheduled = event.Cron
+			return nil
+		}),
+		Queue: consume,
+	}, events...)
+
+	if len(results) != 3 {
+		t.Fatalf("want 3 results, got %d", len(results))
+	}
+	for _, r := range results {
+		if r.Err != nil {
+			t.Errorf("%s: unexpected error: %v", r.Event.Type, r.Err)
+		}
+	}
+	b, _ := io.ReadAll(results[0].Response.Body)
+	if got := string(b); got != "hello, gopher" {
+		t.Errorf("want response %q, got %q", "hello, gopher", got)
+	}
+	if scheduled != "0 3 * * *" {
+		t.Errorf("want the scheduled handler to be called, got %q", scheduled)
+	}
+	if got := strings.Join(results[2].Acked, ","); got != "a,b" {
+		t.Errorf("want acked a,b, got %s", got)
+	}
+	if got := strings.Join(results[2].Retried, ","); got != "c" {
+		t.Errorf("want retried c, got %s", got)
+	}
+
+	failed := replay.Dispatch(&replay.Event{Type: "email"})
+	if failed.Err == nil {
+		t.Error("want error for unsupported events")
+	}
+}
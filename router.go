@@ -0,0 +1,371 @@
+package workers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Router is a lightweight HTTP request router implementing http.Handler.
+//   - Patterns are matched segment by segment.
+//   - `:name` segment matches a single path segment and captures it as a path parameter.
+//   - `*name` segment matches the rest of the path and must be the last segment of a pattern.
+//   - Static segments take precedence over parameter segments, and parameter segments take precedence over wildcard segments.
+//   - HEAD requests are handled by GET routes when no HEAD route is registered.
+type Router struct {
+	routes      []*route
+	middlewares []Middleware
+	// NotFound is called when no route matches the request path.
+	// If nil, http.NotFound is used.
+	NotFound http.Handler
+	// MethodNotAllowed is called when a route matches the request path but not the method.
+	// The Allow header is set before calling this handler.
+	// If nil, responds with status 405.
+	MethodNotAllowed http.Handler
+}
+
+var _ http.Handler = (*Router)(nil)
+
+// NewRouter returns a new Router.
+func NewRouter() *Router {
+	return &Router{}
+}
+
+type segmentKind int
+
+const (
+	segmentStatic segmentKind = iota
+	segmentParam
+	segmentWildcard
+)
+
+type segment struct {
+	kind  segmentKind
+	value string
+}
+
+type route struct {
+	method   string
+	pattern  string
+	segments []segment
+	handler  http.Handler
+}
+
+// splitPath splits the path into segments. Leading and trailing slashes are ignored.
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+func parsePattern(pattern string) []segment {
+	parts := splitPath(pattern)
+	segments := make([]segment, len(parts))
+	for i, part := range parts {
+		switch {
+		case strings.HasPrefix(part, ":"):
+			segments[i] = segment{kind: segmentParam, value: part[1:]}
+		case strings.HasPrefix(part, "*"):
+			if i != len(parts)-1 {
+				panic("workers: wildcard segment must be the last segment of pattern: " + pattern)
+			}
+			segments[i] = segment{kind: segmentWildcard, value: part[1:]}
+		default:
+			segments[i] = segment{kind: segmentStatic, value: part}
+		}
+	}
+	return segments
+}
+
+// match reports whether the route matches given path segments, and returns captured path parameters.
+func (rt *route) match(parts []string) (map[string]string, bool) {
+	var params map[string]string
+	setParam := func(name, value string) {
+		if params == nil {
+			params = make(map[string]string)
+		}
+		params[name] = value
+	}
+	for i, seg := range rt.segments {
+		if seg.kind == segmentWildcard {
+			setParam(seg.value, strings.Join(parts[i:], "/"))
+			return params, true
+		}
+		if i >= len(parts) {
+			return nil, false
+		}
+		switch seg.kind {
+		case segmentStatic:
+			if seg.value != parts[i] {
+				return nil, false
+			}
+		case segmentParam:
+			setParam(seg.value, parts[i])
+		}
+	}
+	if len(rt.segments) != len(parts) {
+		return nil, false
+	}
+	return params, true
+}
+
+// lessSpecific reports whether route a is less specific than route b.
+func lessSpecific(a, b *route) bool {
+	for i := 0; i < len(a.segments) && i < len(b.segments); i++ {
+		if a.segments[i].kind != b.segments[i].kind {
+			return a.segments[i].kind > b.segments[i].kind
+		}
+	}
+	// a wildcard segment can match empty path, so it is less specific than the end of pattern.
+	if n := len(b.segments); len(a.segments) > n {
+		return a.segments[n].kind == segmentWildcard
+	}
+	if n := len(a.segments); len(b.segments) > n {
+		return b.segments[n].kind != segmentWildcard
+	}
+	return false
+}
+
+// Handle registers the handler for the given method and pattern.
+//   - This method panics when the pattern is invalid.
+func (r *Router) Handle(method, pattern string, handler http.Handler) {
+	r.routes = append(r.routes, &route{
+		method:   method,
+		pattern:  pattern,
+		segments: parsePattern(pattern),
+		handler:  handler,
+	})
+	// keep the most specific routes first.
+	sort.SliceStable(r.routes, func(i, j int) bool {
+		return lessSpecific(r.routes[j], r.routes[i])
+	})
+}
+
+// HandleFunc registers the handler function for the given method and pattern.
+func (r *Router) HandleFunc(method, pattern string, handler http.HandlerFunc) {
+	r.Handle(method, pattern, handler)
+}
+
+// GET registers the handler function for GET requests.
+func (r *Router) GET(pattern string, handler http.HandlerFunc) {
+	r.Handle(http.MethodGet, pattern, handler)
+}
+
+// HEAD registers the handler function for HEAD requests.
+func (r *Router) HEAD(pattern string, handler http.HandlerFunc) {
+	r.Handle(http.MethodHead, pattern, handler)
+}
+
+// POST registers the handler function for POST requests.
+func (r *Router) POST(pattern string, handler http.HandlerFunc) {
+	r.Handle(http.MethodPost, pattern, handler)
+}
+
+// PUT registers the handler function for PUT requests.
+func (r *Router) PUT(pattern string, handler http.HandlerFunc) {
+	r.Handle(http.MethodPut, pattern, handler)
+}
+
+// PATCH registers the handler function for PATCH requests.
+func (r *Router) PATCH(pattern string, handler http.HandlerFunc) {
+	r.Handle(http.MethodPatch, pattern, handler)
+}
+
+// DELETE registers the handler function for DELETE requests.
+func (r *Router) DELETE(pattern string, handler http.HandlerFunc) {
+	r.Handle(http.MethodDelete, pattern, handler)
+}
+
+// OPTIONS registers the handler function for OPTIONS requests.
+func (r *Router) OPTIONS(pattern string, handler http.HandlerFunc) {
+	r.Handle(http.MethodOptions, pattern, handler)
+}
+
+// Use registers middlewares applied to every request handled by the Router.
+//   - Middlewares also wrap NotFound and MethodNotAllowed handlers.
+func (r *Router) Use(mws ...Middleware) {
+	r.middlewares = append(r.middlewares, mws...)
+}
+
+// Group returns a RouteGroup which registers routes prefixed with the given prefix.
+func (r *Router) Group(prefix string) *RouteGroup {
+	return &RouteGroup{router: r, prefix: strings.Trim(prefix, "/")}
+}
+
+// ServeHTTP dispatches the request to the handler whose pattern matches the request.
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	// routeMatch is filled by dispatch, so middlewares can read it after calling next handler.
+	req = req.WithContext(context.WithValue(req.Context(), routeMatchKey{}, &routeMatch{}))
+	if len(r.middlewares) > 0 {
+		Chain(r.middlewares...)(http.HandlerFunc(r.dispatch)).ServeHTTP(w, req)
+		return
+	}
+	r.dispatch(w, req)
+}
+
+func (r *Router) dispatch(w http.ResponseWriter, req *http.Request) {
+	parts := splitPath(req.URL.Path)
+	var (
+		allowed []string
+		headRt  *route
+		headPs  map[string]string
+	)
+	for _, rt := range r.routes {
+		params, ok := rt.match(parts)
+		if !ok {
+			continue
+		}
+		if rt.method == req.Method {
+			r.serveRoute(w, req, rt, params)
+			return
+		}
+		if req.Method == http.MethodHead && rt.method == http.MethodGet && headRt == nil {
+			headRt, headPs = rt, params
+		}
+		allowed = appendMethod(allowed, rt.method)
+	}
+	if headRt != nil {
+		r.serveRoute(w, req, headRt, headPs)
+		return
+	}
+	if len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		if r.MethodNotAllowed != nil {
+			r.MethodNotAllowed.ServeHTTP(w, req)
+			return
+		}
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if r.NotFound != nil {
+		r.NotFound.ServeHTTP(w, req)
+		return
+	}
+	http.NotFound(w, req)
+}
+
+func (r *Router) serveRoute(w http.ResponseWriter, req *http.Request, rt *route, params map[string]string) {
+	m, ok := req.Context().Value(routeMatchKey{}).(*routeMatch)
+	if !ok {
+		// a middleware replaced the context of the request, so the match is held by a new context.
+		m = &routeMatch{}
+		req = req.WithContext(context.WithValue(req.Context(), routeMatchKey{}, m))
+	}
+	m.pattern = rt.pattern
+	m.params = params
+	rt.handler.ServeHTTP(w, req)
+}
+
+func appendMethod(methods []string, method string) []string {
+	for _, m := range methods {
+		if m == method {
+			return methods
+		}
+	}
+	return append(methods, method)
+}
+
+// RouteGroup registers routes sharing a common path prefix on a Router.
+type RouteGroup struct {
+	router      *Router
+	prefix      string
+	middlewares []Middleware
+}
+
+// Use registers middlewares applied to the routes registered on the group after this call.
+//   - Nested groups inherit middlewares of the parent group.
+func (g *RouteGroup) Use(mws ...Middleware) {
+	g.middlewares = append(g.middlewares, mws...)
+}
+
+// Handle registers the handler for the given method and the pattern prefixed with the group's prefix.
+func (g *RouteGroup) Handle(method, pattern string, handler http.Handler) {
+	g.router.Handle(method, joinPath(g.prefix, pattern), Chain(g.middlewares...)(handler))
+}
+
+// HandleFunc registers the handler function for the given method and the pattern prefixed with the group's prefix.
+func (g *RouteGroup) HandleFunc(method, pattern string, handler http.HandlerFunc) {
+	g.Handle(method, pattern, handler)
+}
+
+// GET registers the handler function for GET requests.
+func (g *RouteGroup) GET(pattern string, handler http.HandlerFunc) {
+	g.Handle(http.MethodGet, pattern, handler)
+}
+
+// HEAD registers the handler function for HEAD requests.
+func (g *RouteGroup) HEAD(pattern string, handler http.HandlerFunc) {
+	g.Handle(http.MethodHead, pattern, handler)
+}
+
+// POST registers the handler function for POST requests.
+func (g *RouteGroup) POST(pattern string, handler http.HandlerFunc) {
+	g.Handle(http.MethodPost, pattern, handler)
+}
+
+// PUT registers the handler function for PUT requests.
+func (g *RouteGroup) PUT(pattern string, handler http.HandlerFunc) {
+	g.Handle(http.MethodPut, pattern, handler)
+}
+
+// PATCH registers the handler function for PATCH requests.
+func (g *RouteGroup) PATCH(pattern string, handler http.HandlerFunc) {
+	g.Handle(http.MethodPatch, pattern, handler)
+}
+
+// DELETE registers the handler function for DELETE requests.
+func (g *RouteGroup) DELETE(pattern string, handler http.HandlerFunc) {
+	g.Handle(http.MethodDelete, pattern, handler)
+}
+
+// OPTIONS registers the handler function for OPTIONS requests.
+func (g *RouteGroup) OPTIONS(pattern string, handler http.HandlerFunc) {
+	g.Handle(http.MethodOptions, pattern, handler)
+}
+
+// Group returns a nested RouteGroup.
+func (g *RouteGroup) Group(prefix string) *RouteGroup {
+	return &RouteGroup{
+		router:      g.router,
+		prefix:      strings.Trim(joinPath(g.prefix, prefix), "/"),
+		middlewares: append([]Middleware(nil), g.middlewares...),
+	}
+}
+
+// joinPath joins path prefix and pattern with a single slash.
+func joinPath(prefix, pattern string) string {
+	return strings.TrimRight(prefix, "/") + "/" + strings.TrimLeft(pattern, "/")
+}
+
+type routeMatchKey struct{}
+
+// routeMatch holds the route matched by the Router.
+type routeMatch struct {
+	pattern string
+	params  map[string]string
+}
+
+// PathParam returns the value of the path parameter matched by the Router.
+//   - if the parameter doesn't exist, returns empty string.
+func PathParam(req *http.Request, name string) string {
+	m, ok := req.Context().Value(routeMatchKey{}).(*routeMatch)
+	if !ok {
+		return ""
+	}
+	return m.params[name]
+}
+
+// RoutePattern returns the pattern of the route matched by the Router (e.g. "/users/:id").
+// This is useful to label metrics and logs without high cardinality of paths.
+//   - Middlewares registered by Router.Use can read the pattern after calling the next handler.
+//   - if no route is matched, returns empty string.
+func RoutePattern(req *http.Request) string {
+	m, ok := req.Context().Value(routeMatchKey{}).(*routeMatch)
+	if !ok {
+		return ""
+	}
+	return m.pattern
+}
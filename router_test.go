@@ -0,0 +1,180 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestRouter() *Router {
+	r := NewRouter()
+	handler := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			fmt.Fprintf(w, "%s id=%s path=%s", name, PathParam(req, "id"), PathParam(req, "path"))
+		}
+	}
+	r.GET("/", handler("index"))
+	r.GET("/users/:id", handler("getUser"))
+	r.GET("/users/me", handler("getMe"))
+	r.DELETE("/users/:id", handler("deleteUser"))
+	r.GET("/files/*path", handler("getFile"))
+	r.GET("/files", handler("listFiles"))
+	api := r.Group("/api")
+	api.POST("/items", handler("postItem"))
+	api.Group("v1").GET("/items/:id", handler("getItemV1"))
+	return r
+}
+
+func TestRouter(t *testing.T) {
+	tests := map[string]struct {
+		method     string
+		path       string
+		wantStatus int
+		wantBody   string
+		wantAllow  string
+	}{
+		"index": {
+			method:     http.MethodGet,
+			path:       "/",
+			wantStatus: http.StatusOK,
+			wantBody:   "index id= path=",
+		},
+		"path param": {
+			method:     http.MethodGet,
+			path:       "/users/123",
+			wantStatus: http.StatusOK,
+			wantBody:   "getUser id=123 path=",
+		},
+		"static segment takes precedence over param": {
+			method:     http.MethodGet,
+			path:       "/users/me",
+			wantStatus: http.StatusOK,
+			wantBody:   "getMe id= path=",
+		},
+		"method matching": {
+			method:     http.MethodDelete,
+			path:       "/users/me",
+			wantStatus: http.StatusOK,
+			wantBody:   "deleteUser id=me path=",
+		},
+		"HEAD falls back to GET": {
+			method:     http.MethodHead,
+			path:       "/users/123",
+			wantStatus: http.StatusOK,
+			wantBody:   "getUser id=123 path=",
+		},
+		"wildcard": {
+			method:     http.MethodGet,
+			path:       "/files/a/b.txt",
+			wantStatus: http.StatusOK,
+			wantBody:   "getFile id= path=a/b.txt",
+		},
+		"end of pattern takes precedence over wildcard": {
+			method:     http.MethodGet,
+			path:       "/files",
+			wantStatus: http.StatusOK,
+			wantBody:   "listFiles id= path=",
+		},
+		"group": {
+			method:     http.MethodPost,
+			path:       "/api/items",
+			wantStatus: http.StatusOK,
+			wantBody:   "postItem id= path=",
+		},
+		"nested group": {
+			method:     http.MethodGet,
+			path:       "/api/v1/items/1",
+			wantStatus: http.StatusOK,
+			wantBody:   "getItemV1 id=1 path=",
+		},
+		"not found": {
+			method:     http.MethodGet,
+			path:       "/unknown",
+			wantStatus: http.StatusNotFound,
+			wantBody:   "404 page not found\n",
+		},
+		"method not allowed": {
+			method:     http.MethodPut,
+			path:       "/users/1",
+			wantStatus: http.StatusMethodNotAllowed,
+			wantBody:   "Method Not Allowed\n",
+			wantAllow:  "GET, DELETE",
+		},
+	}
+	r := newTestRouter()
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+			if rec.Code != tc.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tc.wantStatus)
+			}
+			if got := rec.Body.String(); got != tc.wantBody {
+				t.Errorf("body = %q, want %q", got, tc.wantBody)
+			}
+			if got := rec.Header().Get("Allow"); got != tc.wantAllow {
+				t.Errorf("Allow = %q, want %q", got, tc.wantAllow)
+			}
+		})
+	}
+}
+
+func TestRoutePattern(t *testing.T) {
+	tests := map[string]struct {
+		middleware Middleware
+		path       string
+		want       string
+	}{
+		"matched": {
+			path: "/users/1",
+			want: "/users/:id",
+		},
+		"not found": {
+			path: "/unknown",
+		},
+		"context replaced by a middleware": {
+			middleware: func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					next.ServeHTTP(w, req.WithContext(context.Background()))
+				})
+			},
+			path: "/users/1",
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			r := NewRouter()
+			var got string
+			r.Use(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					next.ServeHTTP(w, req)
+					got = RoutePattern(req)
+				})
+			})
+			if tc.middleware != nil {
+				r.Use(tc.middleware)
+			}
+			r.GET("/users/:id", func(w http.ResponseWriter, req *http.Request) {
+				fmt.Fprintf(w, "%s id=%s", RoutePattern(req), PathParam(req, "id"))
+			})
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			if got != tc.want {
+				t.Errorf("RoutePattern = %q, want %q", got, tc.want)
+			}
+			if rec.Code == http.StatusOK {
+				if body, want := rec.Body.String(), "/users/:id id=1"; body != want {
+					t.Errorf("body = %q, want %q", body, want)
+				}
+			}
+		})
+	}
+}
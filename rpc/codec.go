@@ -0,0 +1,29 @@
+package rpc
+
+import "encoding/json"
+
+// Codec encodes and decodes messages.
+//   - a Protocol Buffers codec can be implemented by proto.Marshal and proto.Unmarshal, with the name "proto".
+type Codec interface {
+	// Name is the name of the codec in content types (e.g. "json" of "application/json" and "application/grpc-web+json").
+	Name() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSON is Codec of encoding/json.
+var JSON Codec = jsonCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
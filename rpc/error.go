@@ -0,0 +1,118 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Code is the status code of RPCs, shared by the Connect protocol and gRPC.
+//   - https://connectrpc.com/docs/protocol#error-codes
+type Code int
+
+const (
+	CodeCanceled           Code = 1
+	CodeUnknown            Code = 2
+	CodeInvalidArgument    Code = 3
+	CodeDeadlineExceeded   Code = 4
+	CodeNotFound           Code = 5
+	CodeAlreadyExists      Code = 6
+	CodePermissionDenied   Code = 7
+	CodeResourceExhausted  Code = 8
+	CodeFailedPrecondition Code = 9
+	CodeAborted            Code = 10
+	CodeOutOfRange         Code = 11
+	CodeUnimplemented      Code = 12
+	CodeInternal           Code = 13
+	CodeUnavailable        Code = 14
+	CodeDataLoss           Code = 15
+	CodeUnauthenticated    Code = 16
+)
+
+var codeNames = map[Code]string{
+	CodeCanceled:           "canceled",
+	CodeUnknown:            "unknown",
+	CodeInvalidArgument:    "invalid_argument",
+	CodeDeadlineExceeded:   "deadline_exceeded",
+	CodeNotFound:           "not_found",
+	CodeAlreadyExists:      "already_exists",
+	CodePermissionDenied:   "permission_denied",
+	CodeResourceExhausted:  "resource_exhausted",
+	CodeFailedPrecondition: "failed_precondition",
+	CodeAborted:            "aborted",
+	CodeOutOfRange:         "out_of_range",
+	CodeUnimplemented:      "unimplemented",
+	CodeInternal:           "internal",
+	CodeUnavailable:        "unavailable",
+	CodeDataLoss:           "data_loss",
+	CodeUnauthenticated:    "unauthenticated",
+}
+
+// String returns the name of the code in the Connect protocol (e.g. "not_found").
+func (c Code) String() string {
+	if name, ok := codeNames[c]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// HTTPStatus returns the HTTP status of the code in the Connect protocol.
+func (c Code) HTTPStatus() int {
+	switch c {
+	case CodeCanceled:
+		// 499 Client Closed Request
+		return 499
+	case CodeInvalidArgument, CodeFailedPrecondition, CodeOutOfRange:
+		return http.StatusBadRequest
+	case CodeDeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case CodeNotFound:
+		return http.StatusNotFound
+	case CodeAlreadyExists, CodeAborted:
+		return http.StatusConflict
+	case CodePermissionDenied:
+		return http.StatusForbidden
+	case CodeResourceExhausted:
+		return http.StatusTooManyRequests
+	case CodeUnimplemented:
+		return http.StatusNotImplemented
+	case CodeUnavailable:
+		return http.StatusServiceUnavailable
+	case CodeUnauthenticated:
+		return http.StatusUnauthorized
+	}
+	return http.StatusInternalServerError
+}
+
+// Error is an error of RPCs with the code.
+//   - errors other than *Error returned by handlers are CodeUnknown, and their messages are not sent to clients.
+type Error struct {
+	Code    Code
+	Message string
+}
+
+// NewError returns a new Error.
+func NewError(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return "rpc: " + e.Code.String()
+	}
+	return "rpc: " + e.Code.String() + ": " + e.Message
+}
+
+// toError converts the error returned by handlers to *Error.
+func toError(err error) *Error {
+	var e *Error
+	switch {
+	case errors.As(err, &e):
+		return e
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Error{Code: CodeDeadlineExceeded}
+	case errors.Is(err, context.Canceled):
+		return &Error{Code: CodeCanceled}
+	}
+	return &Error{Code: CodeUnknown}
+}
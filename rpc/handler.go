@@ -0,0 +1,314 @@
+// Package rpc serves unary RPCs by the Connect protocol and gRPC-Web, so typed RPC APIs can be hosted on Workers.
+//   - gRPC over HTTP/2 is not supported, since Workers can't send HTTP trailers.
+//     gRPC-Web encodes trailers in the body, and the Connect protocol doesn't use trailers for unary RPCs.
+//   - messages are encoded by Codec. JSON is built in, and Protocol Buffers can be used by implementing Codec.
+//   - handlers of connect-go are http.Handler, so they can also be served by workers.Serve with the Connect protocol and gRPC-Web.
+//   - https://connectrpc.com/docs/protocol
+//   - https://github.com/grpc/grpc/blob/master/doc/PROTOCOL-WEB.md
+package rpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultMaxBodySize = 4 << 20
+
+// HandlerOptions represents options of NewUnaryHandler.
+type HandlerOptions struct {
+	// Codecs are codecs accepted by the handler. Defaults to JSON.
+	Codecs []Codec
+	// MaxBodySize is the maximum size of request bodies. Defaults to 4 MiB.
+	MaxBodySize int64
+}
+
+// UnaryFunc is the implementation of a unary RPC.
+//   - returning *Error responds with its code and message. Other errors respond with CodeUnknown.
+type UnaryFunc[Req, Res any] func(ctx context.Context, req *Req) (*Res, error)
+
+type callInfo struct {
+	requestHeader  http.Header
+	responseHeader http.Header
+}
+
+type callInfoKey struct{}
+
+// RequestHeader returns the header of the request of the RPC in ctx.
+func RequestHeader(ctx context.Context) http.Header {
+	if info, ok := ctx.Value(callInfoKey{}).(*callInfo); ok {
+		return info.requestHeader
+	}
+	return http.Header{}
+}
+
+// ResponseHeader returns the header of the response of the RPC in ctx, which can be modified by UnaryFunc.
+func ResponseHeader(ctx context.Context) http.Header {
+	if info, ok := ctx.Value(callInfoKey{}).(*callInfo); ok {
+		return info.responseHeader
+	}
+	return http.Header{}
+}
+
+// NewUnaryHandler returns the path of the procedure and the handler serving it, which can be registered by Router.Handle.
+//   - procedure is the path of the RPC (e.g. "/greet.v1.GreetService/Greet").
+//   - the handler accepts POST requests of the Connect protocol ("application/json"), and gRPC-Web
+//     ("application/grpc-web+json" and "application/grpc-web-text+json"), for each codec.
+//   - timeouts are read from Connect-Timeout-Ms or grpc-timeout header.
+//   - gzip compressed requests are accepted. Responses are not compressed.
+func NewUnaryHandler[Req, Res any](procedure string, fn UnaryFunc[Req, Res], opts *HandlerOptions) (string, http.Handler) {
+	h := &unaryHandler[Req, Res]{fn: fn, maxBodySize: defaultMaxBodySize, codecs: map[string]Codec{}}
+	codecs := []Codec{JSON}
+	if opts != nil {
+		if len(opts.Codecs) > 0 {
+			codecs = opts.Codecs
+		}
+		if opts.MaxBodySize > 0 {
+			h.maxBodySize = opts.MaxBodySize
+		}
+	}
+	var accept []string
+	for _, c := range codecs {
+		h.codecs[c.Name()] = c
+		accept = append(accept, "application/"+c.Name(), "application/grpc-web+"+c.Name())
+	}
+	h.acceptPost = strings.Join(accept, ", ")
+	return procedure, h
+}
+
+type unaryHandler[Req, Res any] struct {
+	fn          UnaryFunc[Req, Res]
+	codecs      map[string]Codec
+	acceptPost  string
+	maxBodySize int64
+}
+
+// protocol is the protocol of a request.
+type protocol struct {
+	codec Codec
+	// grpcWeb reports whether the request is gRPC-Web, and text reports whether it is base64 encoded.
+	grpcWeb, text bool
+	contentType   string
+}
+
+// negotiate determines the protocol and the codec by Content-Type header.
+func (h *unaryHandler[Req, Res]) negotiate(contentType string) (*protocol, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	p := &protocol{contentType: mediaType}
+	name, ok := strings.CutPrefix(mediaType, "application/")
+	if !ok {
+		return nil, false
+	}
+	switch {
+	case name == "grpc-web" || name == "grpc-web-text":
+		// the default codec of gRPC-Web is Protocol Buffers.
+		p.grpcWeb, p.text, name = true, name == "grpc-web-text", "proto"
+	case strings.HasPrefix(name, "grpc-web+"), strings.HasPrefix(name, "grpc-web-text+"):
+		prefix, codec, _ := strings.Cut(name, "+")
+		p.grpcWeb, p.text, name = true, prefix == "grpc-web-text", codec
+	}
+	p.codec, ok = h.codecs[name]
+	return p, ok
+}
+
+func (h *unaryHandler[Req, Res]) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	p, ok := h.negotiate(req.Header.Get("Content-Type"))
+	if !ok {
+		w.Header().Set("Accept-Post", h.acceptPost)
+		http.Error(w, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+		return
+	}
+	info := &callInfo{requestHeader: req.Header, responseHeader: http.Header{}}
+	ctx := context.WithValue(req.Context(), callInfoKey{}, info)
+	if timeout, ok := parseTimeout(req.Header, p.grpcWeb); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	res, err := h.call(ctx, req, p)
+	for k, v := range info.responseHeader {
+		w.Header()[k] = v
+	}
+	if p.grpcWeb {
+		writeGRPCWeb(w, p, res, err)
+		return
+	}
+	writeConnect(w, p, res, err)
+}
+
+// call decodes the request, and calls the function.
+func (h *unaryHandler[Req, Res]) call(ctx context.Context, req *http.Request, p *protocol) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(req.Body, h.maxBodySize+1))
+	if err != nil {
+		return nil, NewError(CodeInvalidArgument, "failed to read the request")
+	}
+	if int64(len(body)) > h.maxBodySize {
+		return nil, NewError(CodeResourceExhausted, "the request is too large")
+	}
+	var compressed bool
+	if p.grpcWeb {
+		if body, compressed, err = decodeFrame(body, p.text); err != nil {
+			return nil, NewError(CodeInvalidArgument, err.Error())
+		}
+		compressed = compressed && req.Header.Get("Grpc-Encoding") == "gzip"
+	} else {
+		compressed = req.Header.Get("Content-Encoding") == "gzip"
+	}
+	if compressed {
+		if body, err = gunzip(body, h.maxBodySize); err != nil {
+			return nil, NewError(CodeInvalidArgument, "failed to decompress the request")
+		}
+	}
+	in := new(Req)
+	if err := p.codec.Unmarshal(body, in); err != nil {
+		return nil, NewError(CodeInvalidArgument, "failed to decode the request: "+err.Error())
+	}
+	out, err := h.fn(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	if out == nil {
+		out = new(Res)
+	}
+	b, err := p.codec.Marshal(out)
+	if err != nil {
+		return nil, fmt.Errorf("rpc: error encoding the response: %w", err)
+	}
+	return b, nil
+}
+
+// parseTimeout parses Connect-Timeout-Ms header, or grpc-timeout header of gRPC-Web (e.g. "100m", "5S").
+func parseTimeout(h http.Header, grpcWeb bool) (time.Duration, bool) {
+	if !grpcWeb {
+		ms, err := strconv.ParseInt(h.Get("Connect-Timeout-Ms"), 10, 64)
+		return time.Duration(ms) * time.Millisecond, err == nil && ms > 0
+	}
+	v := h.Get("Grpc-Timeout")
+	if len(v) < 2 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(v[:len(v)-1], 10, 64)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	units := map[byte]time.Duration{'H': time.Hour, 'M': time.Minute, 'S': time.Second, 'm': time.Millisecond, 'u': time.Microsecond, 'n': time.Nanosecond}
+	unit, ok := units[v[len(v)-1]]
+	return time.Duration(n) * unit, ok
+}
+
+func gunzip(b []byte, limit int64) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	out, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(out)) > limit {
+		return nil, errors.New("too large")
+	}
+	return out, nil
+}
+
+// writeConnect writes the response of the Connect protocol.
+func writeConnect(w http.ResponseWriter, p *protocol, res []byte, err error) {
+	if err != nil {
+		e := toError(err)
+		b, _ := json.Marshal(map[string]string{"code": e.Code.String(), "message": e.Message})
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(e.Code.HTTPStatus())
+		w.Write(b)
+		return
+	}
+	w.Header().Set("Content-Type", p.contentType)
+	w.Write(res)
+}
+
+const (
+	frameCompressed = 0x01
+	frameTrailer    = 0x80
+)
+
+// decodeFrame decodes the message frame of gRPC-Web.
+func decodeFrame(body []byte, text bool) (msg []byte, compressed bool, err error) {
+	if text {
+		if body, err = base64.StdEncoding.DecodeString(string(body)); err != nil {
+			return nil, false, errors.New("invalid base64")
+		}
+	}
+	if len(body) < 5 {
+		return nil, false, errors.New("invalid frame")
+	}
+	size := binary.BigEndian.Uint32(body[1:5])
+	if body[0]&frameTrailer != 0 || uint64(len(body)-5) < uint64(size) {
+		return nil, false, errors.New("invalid frame")
+	}
+	return body[5 : 5+size], body[0]&frameCompressed != 0, nil
+}
+
+func appendFrame(b []byte, flags byte, msg []byte) []byte {
+	b = append(b, flags)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(msg)))
+	return append(b, msg...)
+}
+
+// writeGRPCWeb writes the response of gRPC-Web. The status is always 200, and the result is in the trailer frame.
+func writeGRPCWeb(w http.ResponseWriter, p *protocol, res []byte, err error) {
+	var body []byte
+	code, message := 0, ""
+	if err != nil {
+		e := toError(err)
+		code, message = int(e.Code), e.Message
+	} else {
+		body = appendFrame(body, 0, res)
+	}
+	trailer := "grpc-status: " + strconv.Itoa(code) + "\r\n"
+	if message != "" {
+		trailer += "grpc-message: " + percentEncode(message) + "\r\n"
+	}
+	body = appendFrame(body, frameTrailer, []byte(trailer))
+	contentType := "application/grpc-web"
+	if p.text {
+		contentType = "application/grpc-web-text"
+		body = []byte(base64.StdEncoding.EncodeToString(body))
+	}
+	w.Header().Set("Content-Type", contentType+"+"+p.codec.Name())
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// percentEncode encodes grpc-message by percent-encoding.
+//   - https://github.com/grpc/grpc/blob/master/doc/PROTOCOL-HTTP2.md#responses
+func percentEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c > 0x7e || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,116 @@
+package rpc_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/syumai/workers/rpc"
+)
+
+type greetRequest struct {
+	Name string `json:"name"`
+}
+
+type greetResponse struct {
+	Greeting string `json:"greeting"`
+}
+
+func greet(ctx context.Context, req *greetRequest) (*greetResponse, error) {
+	if req.Name == "" {
+		return nil, rpc.NewError(rpc.CodeInvalidArgument, "name is required")
+	}
+	rpc.ResponseHeader(ctx).Set("X-Greeter", rpc.RequestHeader(ctx).Get("X-Client"))
+	return &greetResponse{Greeting: "Hello, " + req.Name}, nil
+}
+
+func frame(flags byte, msg string) string {
+	b := []byte{flags}
+	b = binary.BigEndian.AppendUint32(b, uint32(len(msg)))
+	return string(append(b, msg...))
+}
+
+func TestUnaryHandler(t *testing.T) {
+	path, h := rpc.NewUnaryHandler("/greet.v1.GreetService/Greet", greet, nil)
+	if path != "/greet.v1.GreetService/Greet" {
+		t.Fatalf("unexpected path: %s", path)
+	}
+	tests := map[string]struct {
+		contentType     string
+		body            string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		"connect": {
+			contentType:     "application/json",
+			body:            `{"name":"gopher"}`,
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			wantBody:        `{"greeting":"Hello, gopher"}`,
+		},
+		"connect error": {
+			contentType:     "application/json",
+			body:            `{}`,
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: "application/json",
+			wantBody:        `{"code":"invalid_argument","message":"name is required"}`,
+		},
+		"grpc-web": {
+			contentType:     "application/grpc-web+json",
+			body:            frame(0, `{"name":"gopher"}`),
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/grpc-web+json",
+			wantBody:        frame(0, `{"greeting":"Hello, gopher"}`) + frame(0x80, "grpc-status: 0\r\n"),
+		},
+		"grpc-web error": {
+			contentType:     "application/grpc-web+json",
+			body:            frame(0, `{}`),
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/grpc-web+json",
+			wantBody:        frame(0x80, "grpc-status: 3\r\ngrpc-message: name is required\r\n"),
+		},
+		"grpc-web-text": {
+			contentType:     "application/grpc-web-text+json",
+			body:            base64.StdEncoding.EncodeToString([]byte(frame(0, `{"name":"gopher"}`))),
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/grpc-web-text+json",
+			wantBody:        base64.StdEncoding.EncodeToString([]byte(frame(0, `{"greeting":"Hello, gopher"}`) + frame(0x80, "grpc-status: 0\r\n"))),
+		},
+		"unsupported codec": {
+			contentType: "application/proto",
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+			req.Header.Set("X-Client", "test")
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("want status %d, got %d: %s", tc.wantStatus, rec.Code, rec.Body)
+			}
+			if tc.wantContentType == "" {
+				return
+			}
+			if got := rec.Header().Get("Content-Type"); got != tc.wantContentType {
+				t.Errorf("want Content-Type %s, got %s", tc.wantContentType, got)
+			}
+			if got := rec.Body.String(); got != tc.wantBody {
+				t.Errorf("want body %q, got %q", tc.wantBody, got)
+			}
+			if rec.Code == http.StatusOK && !strings.Contains(name, "error") && rec.Header().Get("X-Greeter") != "test" {
+				t.Errorf("want the response header set by the handler")
+			}
+		})
+	}
+}
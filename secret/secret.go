@@ -0,0 +1,112 @@
+// Package secret provides utilities to handle secrets safely.
+//   - Equal compares values in constant time by crypto.subtle.timingSafeEqual of the runtime.
+//   - String holds a secret which is redacted in formatting, JSON and logs.
+//   - DefaultRedactKeys are keys of log attributes which usually hold secrets.
+package secret
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/internal/jsutil"
+)
+
+// Redacted replaces secrets in formatting, JSON and logs.
+const Redacted = "[REDACTED]"
+
+// DefaultRedactKeys are keys of log attributes holding secrets in common.
+//   - it can be given to logging.HandlerOptions.RedactKeys.
+var DefaultRedactKeys = []string{
+	"authorization",
+	"cookie",
+	"set-cookie",
+	"password",
+	"secret",
+	"token",
+	"access_token",
+	"refresh_token",
+	"api_key",
+	"client_secret",
+}
+
+// Equal reports whether a and b are equal in constant time.
+//   - both values are hashed by SHA-256 before comparison, so their lengths are not leaked.
+//   - the hashes are compared by crypto.subtle.timingSafeEqual if the runtime provides it,
+//     otherwise by crypto/subtle.
+func Equal(a, b []byte) bool {
+	ha := sha256.Sum256(a)
+	hb := sha256.Sum256(b)
+	subtleCrypto := jsutil.Global.Get("crypto").Get("subtle")
+	if subtleCrypto.Get("timingSafeEqual").IsUndefined() {
+		return subtle.ConstantTimeCompare(ha[:], hb[:]) == 1
+	}
+	return subtleCrypto.Call("timingSafeEqual",
+		jsutil.NewUint8ArrayFromBytes(ha[:]),
+		jsutil.NewUint8ArrayFromBytes(hb[:]),
+	).Bool()
+}
+
+// EqualString reports whether a and b are equal in constant time. See Equal.
+func EqualString(a, b string) bool {
+	return Equal([]byte(a), []byte(b))
+}
+
+// String is a secret string which doesn't appear in formatting (e.g. %v, %s and %#v), JSON and logs.
+//   - use Reveal to get the value.
+type String string
+
+var (
+	_ fmt.Formatter  = String("")
+	_ slog.LogValuer = String("")
+)
+
+// Lookup returns the environment variable (secret) named name as String.
+//   - This function panics when a runtime context is not found.
+func Lookup(ctx context.Context, name string) (String, bool) {
+	v, ok := cloudflare.LookupEnv(ctx, name)
+	return String(v), ok
+}
+
+// Reveal returns the value of the secret.
+func (s String) Reveal() string {
+	return string(s)
+}
+
+// Equal reports whether the secret equals v in constant time.
+func (s String) Equal(v string) bool {
+	return EqualString(string(s), v)
+}
+
+// String returns Redacted.
+func (s String) String() string {
+	return Redacted
+}
+
+// GoString returns Redacted.
+func (s String) GoString() string {
+	return Redacted
+}
+
+// Format writes Redacted for all verbs.
+func (s String) Format(f fmt.State, verb rune) {
+	fmt.Fprint(f, Redacted)
+}
+
+// MarshalJSON encodes the secret as "[REDACTED]".
+func (s String) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + Redacted + `"`), nil
+}
+
+// MarshalText encodes the secret as [REDACTED].
+func (s String) MarshalText() ([]byte, error) {
+	return []byte(Redacted), nil
+}
+
+// LogValue returns Redacted.
+func (s String) LogValue() slog.Value {
+	return slog.StringValue(Redacted)
+}
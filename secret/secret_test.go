@@ -0,0 +1,59 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	tests := map[string]struct {
+		a, b string
+		want bool
+	}{
+		"equal":           {a: "token", b: "token", want: true},
+		"different":       {a: "token", b: "tokem", want: false},
+		"different sizes": {a: "token", b: "token2", want: false},
+		"empty":           {a: "", b: "", want: true},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if got := EqualString(tc.a, tc.b); got != tc.want {
+				t.Errorf("want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	s := String("hunter2")
+	v := struct {
+		Password String `json:"password"`
+	}{Password: s}
+	var out []string
+	for _, format := range []string{"%v", "%s", "%q", "%#v", "%+v", "%x"} {
+		out = append(out, fmt.Sprintf(format, v), fmt.Sprintf(format, s))
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out = append(out, string(b))
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("login", "password", s)
+	out = append(out, buf.String())
+	for _, o := range out {
+		if strings.Contains(o, "hunter2") {
+			t.Errorf("the secret is leaked: %s", o)
+		}
+	}
+	if s.Reveal() != "hunter2" || !s.Equal("hunter2") {
+		t.Error("want the secret to be revealed")
+	}
+}
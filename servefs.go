@@ -0,0 +1,186 @@
+package workers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+)
+
+const (
+	// immutableCacheControl is Cache-Control header of files with hashed names.
+	immutableCacheControl = "public, max-age=31536000, immutable"
+	// defaultFSCacheControl is Cache-Control header of other files. They are revalidated by ETag.
+	defaultFSCacheControl = "public, max-age=0, must-revalidate"
+)
+
+// ServeFSOptions represents options of ServeFS.
+type ServeFSOptions struct {
+	// Root is the directory in the file system to serve (e.g. "dist").
+	Root string
+	// Fallback is the file served for paths without extensions which are not found (e.g. "index.html" for SPAs).
+	// If empty, such paths respond with status 404.
+	Fallback string
+	// CacheControl is Cache-Control header of files whose names are not hashed.
+	// Defaults to "public, max-age=0, must-revalidate".
+	CacheControl string
+	// Immutable reports whether the file never changes, e.g. its name contains the hash of its content.
+	// Immutable files are served with "public, max-age=31536000, immutable", so clients never revalidate them for a year.
+	// If nil, no files are immutable. Set IsHashedName only if all files matching it are named by bundlers.
+	Immutable func(name string) bool
+}
+
+// ServeFS returns a handler serving files in the file system, typically embed.FS bundled in the binary.
+//   - Content-Type header is detected by the extension of the file name, or the content.
+//   - ETag header is the SHA-256 of the content, and If-None-Match header is respected.
+//   - requests for directories serve index.html in them.
+//   - Range requests are supported by ServeRange.
+//   - only GET and HEAD methods are allowed.
+func ServeFS(fsys fs.FS, opts *ServeFSOptions) http.Handler {
+	if opts == nil {
+		opts = &ServeFSOptions{}
+	}
+	if opts.Root != "" {
+		sub, err := fs.Sub(fsys, opts.Root)
+		if err != nil {
+			panic(err)
+		}
+		fsys = sub
+	}
+	h := &fsHandler{
+		fsys:         fsys,
+		fallback:     opts.Fallback,
+		cacheControl: opts.CacheControl,
+		immutable:    opts.Immutable,
+	}
+	if h.cacheControl == "" {
+		h.cacheControl = defaultFSCacheControl
+	}
+	return h
+}
+
+// IsHashedName reports whether the file name contains a hash generated by bundlers,
+// e.g. "index-BsD3kx9a.js" or "main.8f4e1c2a.css".
+//   - the hash is a segment of 8 or more alphanumeric characters including a digit, preceded by "-" or ".".
+//   - it is a heuristic, and names given by users may match it (e.g. "report-2024final.pdf"),
+//     so it must not be used for files which may change under the same name.
+func IsHashedName(name string) bool {
+	base := path.Base(name)
+	ext := path.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	i := strings.LastIndexAny(stem, "-.")
+	if i < 0 {
+		return false
+	}
+	hash := stem[i+1:]
+	if len(hash) < 8 {
+		return false
+	}
+	hasDigit := false
+	for _, c := range hash {
+		switch {
+		case '0' <= c && c <= '9':
+			hasDigit = true
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', c == '_':
+		default:
+			return false
+		}
+	}
+	return hasDigit
+}
+
+type fsHandler struct {
+	fsys         fs.FS
+	fallback     string
+	cacheControl string
+	immutable    func(name string) bool
+	// etags caches ETags of files by names, since files of embed.FS never change.
+	etags sync.Map
+}
+
+func (h *fsHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.TrimPrefix(path.Clean("/"+req.URL.Path), "/")
+	content, name, err := h.open(name)
+	if errors.Is(err, fs.ErrNotExist) && h.fallback != "" && path.Ext(name) == "" {
+		content, name, err = h.open(h.fallback)
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		http.NotFound(w, req)
+		return
+	}
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	header := w.Header()
+	etag := h.etag(name, content)
+	header.Set("ETag", etag)
+	if h.immutable != nil && h.immutable(name) {
+		header.Set("Cache-Control", immutableCacheControl)
+	} else {
+		header.Set("Cache-Control", h.cacheControl)
+	}
+	if matchETag(req.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = http.DetectContentType(content)
+	}
+	header.Set("Content-Type", contentType)
+	ServeRange(w, req, bytes.NewReader(content), int64(len(content)))
+}
+
+// open reads the file of the name, or index.html in the directory of the name.
+// It returns the name of the file actually read.
+func (h *fsHandler) open(name string) ([]byte, string, error) {
+	if name == "" {
+		name = "."
+	}
+	info, err := fs.Stat(h.fsys, name)
+	if err != nil {
+		return nil, name, err
+	}
+	if info.IsDir() {
+		name = path.Join(name, "index.html")
+	}
+	b, err := fs.ReadFile(h.fsys, name)
+	return b, name, err
+}
+
+func (h *fsHandler) etag(name string, content []byte) string {
+	if v, ok := h.etags.Load(name); ok {
+		return v.(string)
+	}
+	sum := sha256.Sum256(content)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	h.etags.Store(name, etag)
+	return etag
+}
+
+// matchETag reports whether If-None-Match header matches the ETag by weak comparison.
+func matchETag(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, t := range strings.Split(ifNoneMatch, ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == "*" || t == etag {
+			return true
+		}
+	}
+	return false
+}
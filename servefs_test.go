@@ -0,0 +1,137 @@
+package workers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestServeFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"dist/index.html":               {Data: []byte("<html>index</html>")},
+		"dist/assets/index-B1x9kLmQ.js": {Data: []byte("console.log(1)")},
+		"dist/docs/index.html":          {Data: []byte("<html>docs</html>")},
+	}
+	h := ServeFS(fsys, &ServeFSOptions{Root: "dist", Fallback: "index.html"})
+	immutable := ServeFS(fsys, &ServeFSOptions{Root: "dist", Immutable: IsHashedName})
+	tests := map[string]struct {
+		handler          http.Handler
+		method           string
+		path             string
+		wantStatus       int
+		wantBody         string
+		wantContentType  string
+		wantCacheControl string
+	}{
+		"root": {
+			path:             "/",
+			wantStatus:       http.StatusOK,
+			wantBody:         "<html>index</html>",
+			wantContentType:  "text/html; charset=utf-8",
+			wantCacheControl: defaultFSCacheControl,
+		},
+		"hashed asset": {
+			path:             "/assets/index-B1x9kLmQ.js",
+			wantStatus:       http.StatusOK,
+			wantBody:         "console.log(1)",
+			wantContentType:  "text/javascript; charset=utf-8",
+			wantCacheControl: defaultFSCacheControl,
+		},
+		"immutable hashed asset": {
+			handler:          immutable,
+			path:             "/assets/index-B1x9kLmQ.js",
+			wantStatus:       http.StatusOK,
+			wantBody:         "console.log(1)",
+			wantCacheControl: immutableCacheControl,
+		},
+		"immutable index": {
+			handler:          immutable,
+			path:             "/",
+			wantStatus:       http.StatusOK,
+			wantCacheControl: defaultFSCacheControl,
+		},
+		"directory": {
+			path:       "/docs",
+			wantStatus: http.StatusOK,
+			wantBody:   "<html>docs</html>",
+		},
+		"fallback": {
+			path:       "/users/1",
+			wantStatus: http.StatusOK,
+			wantBody:   "<html>index</html>",
+		},
+		"missing asset": {
+			path:       "/assets/missing.js",
+			wantStatus: http.StatusNotFound,
+		},
+		"post": {
+			method:     http.MethodPost,
+			path:       "/",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			handler := tc.handler
+			if handler == nil {
+				handler = h
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(method, tc.path, nil))
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("want status %d, got %d", tc.wantStatus, rec.Code)
+			}
+			if tc.wantBody != "" && rec.Body.String() != tc.wantBody {
+				t.Errorf("want body %q, got %q", tc.wantBody, rec.Body.String())
+			}
+			if tc.wantContentType != "" && rec.Header().Get("Content-Type") != tc.wantContentType {
+				t.Errorf("want Content-Type %q, got %q", tc.wantContentType, rec.Header().Get("Content-Type"))
+			}
+			if tc.wantCacheControl != "" && rec.Header().Get("Cache-Control") != tc.wantCacheControl {
+				t.Errorf("want Cache-Control %q, got %q", tc.wantCacheControl, rec.Header().Get("Cache-Control"))
+			}
+		})
+	}
+
+	t.Run("not modified", func(t *testing.T) {
+		t.Parallel()
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusNotModified {
+			t.Errorf("want status 304, got %d", rec.Code)
+		}
+	})
+}
+
+func TestIsHashedName(t *testing.T) {
+	tests := map[string]bool{
+		"assets/index-B1x9kLmQ.js": true,
+		"main.8f4e1c2a.css":        true,
+		"index.html":               false,
+		"logo-dark.svg":            false,
+		"app-abcdefgh.js":          false,
+		"index-B1x9.js":            false,
+	}
+	for name, want := range tests {
+		name := name
+		want := want
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if got := IsHashedName(name); got != want {
+				t.Errorf("want %v, got %v", want, got)
+			}
+		})
+	}
+}
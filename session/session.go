@@ -0,0 +1,285 @@
+// Package session manages sessions identified by cookies.
+//   - session data is typed by the type parameter, encoded by encoding/json, and saved to a Store (KV or Durable Objects).
+//   - the expiration is extended when a session is used after half of its TTL (sliding expiration).
+//   - each session has a CSRF token, which can be verified by RequireCSRF.
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/syumai/workers"
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/secret"
+)
+
+// CSRFHeader is the header holding CSRF tokens, which is verified by RequireCSRF.
+const CSRFHeader = "X-CSRF-Token"
+
+// CSRFFormField is the form field holding CSRF tokens, which is verified by RequireCSRF.
+const CSRFFormField = "csrf_token"
+
+// Session is a session of the client.
+type Session[T any] struct {
+	// ID identifies the session. It is stored in the cookie.
+	ID string
+	// Data is the data of the session, which is saved by Manager.Save.
+	Data T
+	// CSRFToken is the token which must be sent with unsafe requests.
+	CSRFToken string
+	// ExpiresAt is the time the session expires. It is zero for new sessions.
+	ExpiresAt time.Time
+}
+
+// IsNew reports whether the session has never been saved.
+func (s *Session[T]) IsNew() bool {
+	return s.ExpiresAt.IsZero()
+}
+
+// VerifyCSRF reports whether the request has the CSRF token of the session in CSRFHeader header or CSRFFormField field.
+func (s *Session[T]) VerifyCSRF(req *http.Request) bool {
+	token := req.Header.Get(CSRFHeader)
+	if token == "" {
+		token = req.PostFormValue(CSRFFormField)
+	}
+	return token != "" && secret.EqualString(token, s.CSRFToken)
+}
+
+// record is the encoded session saved to the Store.
+type record[T any] struct {
+	Data      T      `json:"data"`
+	CSRFToken string `json:"csrf"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// Options represents options of NewManager.
+type Options struct {
+	// CookieName is the name of the cookie. Defaults to "session".
+	CookieName string
+	// TTL is the duration until sessions expire since they are saved. Defaults to 24 hours.
+	TTL time.Duration
+	// Path is Path attribute of the cookie. Defaults to "/".
+	Path string
+	// Domain is Domain attribute of the cookie.
+	Domain string
+	// Insecure omits Secure attribute of the cookie, e.g. for local development over HTTP.
+	Insecure bool
+	// SameSite is SameSite attribute of the cookie. Defaults to http.SameSiteLaxMode.
+	SameSite http.SameSite
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// StoreFunc returns the Store for the context of the request, since bindings are obtained from contexts.
+type StoreFunc func(ctx context.Context) (Store, error)
+
+// KV returns StoreFunc of KVStore of the KV namespace binding.
+func KV(varName, prefix string) StoreFunc {
+	return func(ctx context.Context) (Store, error) {
+		kv, err := cloudflare.NewKVNamespace(ctx, varName)
+		if err != nil {
+			return nil, err
+		}
+		return NewKVStore(kv, prefix), nil
+	}
+}
+
+// DurableObject returns StoreFunc of DurableObjectStore of the Durable Object namespace binding of the Coordinator class.
+func DurableObject(varName string) StoreFunc {
+	return func(ctx context.Context) (Store, error) {
+		ns, err := cloudflare.NewDurableObjectNamespace(ctx, varName)
+		if err != nil {
+			return nil, err
+		}
+		return NewDurableObjectStore(ns), nil
+	}
+}
+
+// Manager loads and saves sessions whose data is T.
+type Manager[T any] struct {
+	store StoreFunc
+	opts  Options
+}
+
+// NewManager returns a Manager saving sessions to the store.
+func NewManager[T any](store StoreFunc, opts *Options) *Manager[T] {
+	m := &Manager[T]{store: store}
+	if opts != nil {
+		m.opts = *opts
+	}
+	if m.opts.CookieName == "" {
+		m.opts.CookieName = "session"
+	}
+	if m.opts.TTL <= 0 {
+		m.opts.TTL = 24 * time.Hour
+	}
+	if m.opts.Path == "" {
+		m.opts.Path = "/"
+	}
+	if m.opts.SameSite == 0 {
+		m.opts.SameSite = http.SameSiteLaxMode
+	}
+	if m.opts.Now == nil {
+		m.opts.Now = time.Now
+	}
+	return m
+}
+
+// randomToken returns a random URL safe token of 256 bits.
+func randomToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func (m *Manager[T]) newSession() *Session[T] {
+	return &Session[T]{ID: randomToken(), CSRFToken: randomToken()}
+}
+
+// Load returns the session of the request.
+//   - if the request has no valid session, returns a new session, which is not saved until Save is called.
+func (m *Manager[T]) Load(req *http.Request) (*Session[T], error) {
+	c, err := req.Cookie(m.opts.CookieName)
+	if err != nil || c.Value == "" {
+		return m.newSession(), nil
+	}
+	store, err := m.store(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	data, found, err := store.Load(req.Context(), c.Value)
+	if err != nil {
+		return nil, fmt.Errorf("session: error loading session: %w", err)
+	}
+	var r record[T]
+	if !found || json.Unmarshal(data, &r) != nil {
+		return m.newSession(), nil
+	}
+	expiresAt := time.UnixMilli(r.ExpiresAt)
+	if !m.opts.Now().Before(expiresAt) {
+		return m.newSession(), nil
+	}
+	return &Session[T]{ID: c.Value, Data: r.Data, CSRFToken: r.CSRFToken, ExpiresAt: expiresAt}, nil
+}
+
+// Save saves the session and sets the cookie. The expiration is extended by TTL.
+// It must be called before the response header is written.
+func (m *Manager[T]) Save(w http.ResponseWriter, req *http.Request, s *Session[T]) error {
+	expiresAt := m.opts.Now().Add(m.opts.TTL)
+	data, err := json.Marshal(&record[T]{Data: s.Data, CSRFToken: s.CSRFToken, ExpiresAt: expiresAt.UnixMilli()})
+	if err != nil {
+		return fmt.Errorf("session: error encoding session: %w", err)
+	}
+	store, err := m.store(req.Context())
+	if err != nil {
+		return err
+	}
+	if err := store.Save(req.Context(), s.ID, data, m.opts.TTL); err != nil {
+		return fmt.Errorf("session: error saving session: %w", err)
+	}
+	s.ExpiresAt = expiresAt
+	m.setCookie(w, s.ID, int(m.opts.TTL/time.Second))
+	return nil
+}
+
+// Renew replaces the ID and the CSRF token of the session, and saves it.
+// Call it when the privilege of the session changes (e.g. login) to prevent session fixation.
+func (m *Manager[T]) Renew(w http.ResponseWriter, req *http.Request, s *Session[T]) error {
+	if !s.IsNew() {
+		store, err := m.store(req.Context())
+		if err != nil {
+			return err
+		}
+		if err := store.Delete(req.Context(), s.ID); err != nil {
+			return fmt.Errorf("session: error deleting session: %w", err)
+		}
+	}
+	s.ID, s.CSRFToken = randomToken(), randomToken()
+	return m.Save(w, req, s)
+}
+
+// Destroy deletes the session, and expires the cookie.
+func (m *Manager[T]) Destroy(w http.ResponseWriter, req *http.Request, s *Session[T]) error {
+	if !s.IsNew() {
+		store, err := m.store(req.Context())
+		if err != nil {
+			return err
+		}
+		if err := store.Delete(req.Context(), s.ID); err != nil {
+			return fmt.Errorf("session: error deleting session: %w", err)
+		}
+	}
+	m.setCookie(w, "", -1)
+	return nil
+}
+
+func (m *Manager[T]) setCookie(w http.ResponseWriter, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     m.opts.CookieName,
+		Value:    value,
+		Path:     m.opts.Path,
+		Domain:   m.opts.Domain,
+		MaxAge:   maxAge,
+		Secure:   !m.opts.Insecure,
+		HttpOnly: true,
+		SameSite: m.opts.SameSite,
+	})
+}
+
+type sessionKey struct{}
+
+// FromContext returns the session loaded by Manager.Middleware.
+func FromContext[T any](ctx context.Context) (*Session[T], bool) {
+	s, ok := ctx.Value(sessionKey{}).(*Session[T])
+	return s, ok
+}
+
+// Middleware returns a middleware loading the session of the request into the context, which can be obtained by FromContext.
+//   - if less than half of TTL remains, the session is saved to extend the expiration.
+//   - if the session can't be loaded, responds with status 500.
+func (m *Manager[T]) Middleware() workers.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			s, err := m.Load(req)
+			if err != nil {
+				workers.WriteError(w, req, err)
+				return
+			}
+			if !s.IsNew() && s.ExpiresAt.Sub(m.opts.Now()) < m.opts.TTL/2 {
+				if err := m.Save(w, req, s); err != nil {
+					workers.WriteError(w, req, err)
+					return
+				}
+			}
+			next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), sessionKey{}, s)))
+		})
+	}
+}
+
+// RequireCSRF returns a middleware verifying CSRF tokens of requests of unsafe methods (e.g. POST) by Session.VerifyCSRF.
+//   - it must be used after Middleware.
+//   - if the token doesn't match, responds with status 403.
+func (m *Manager[T]) RequireCSRF() workers.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			switch req.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				next.ServeHTTP(w, req)
+				return
+			}
+			s, ok := FromContext[T](req.Context())
+			if !ok || s.IsNew() || !s.VerifyCSRF(req) {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
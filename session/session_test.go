@@ -0,0 +1,113 @@
+package session_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syumai/workers/session"
+	"github.com/syumai/workers/workerstest"
+)
+
+type user struct {
+	Name string `json:"name"`
+}
+
+func TestManager(t *testing.T) {
+	kv := &workerstest.KVNamespace{}
+	now := time.Unix(1700000000, 0)
+	m := session.NewManager[user](func(context.Context) (session.Store, error) {
+		return session.NewKVStore(kv, "session:"), nil
+	}, &session.Options{TTL: time.Hour, Now: func() time.Time { return now }})
+
+	var csrf string
+	h := m.Middleware()(m.RequireCSRF()(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		s, _ := session.FromContext[user](req.Context())
+		switch req.URL.Path {
+		case "/login":
+			s.Data.Name = "gopher"
+			if err := m.Renew(w, req, s); err != nil {
+				t.Fatal(err)
+			}
+			csrf = s.CSRFToken
+		case "/logout":
+			if err := m.Destroy(w, req, s); err != nil {
+				t.Fatal(err)
+			}
+		}
+		w.Write([]byte(s.Data.Name))
+	})))
+	do := func(method, path string, cookie *http.Cookie, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		if token != "" {
+			req.Header.Set(session.CSRFHeader, token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodGet, "/login", nil, "")
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "session" || !cookies[0].HttpOnly || !cookies[0].Secure {
+		t.Fatalf("unexpected cookies: %v", cookies)
+	}
+	cookie := cookies[0]
+
+	if got := do(http.MethodGet, "/", cookie, "").Body.String(); got != "gopher" {
+		t.Errorf("want the session to be loaded, got %q", got)
+	}
+	if code := do(http.MethodPost, "/", cookie, "").Code; code != http.StatusForbidden {
+		t.Errorf("want 403 without the CSRF token, got %d", code)
+	}
+	if code := do(http.MethodPost, "/", cookie, "wrong").Code; code != http.StatusForbidden {
+		t.Errorf("want 403 with a wrong CSRF token, got %d", code)
+	}
+	if code := do(http.MethodPost, "/", cookie, csrf).Code; code != http.StatusOK {
+		t.Errorf("want 200 with the CSRF token, got %d", code)
+	}
+
+	// the session is refreshed after half of TTL.
+	now = now.Add(40 * time.Minute)
+	rec = do(http.MethodGet, "/", cookie, "")
+	if len(rec.Result().Cookies()) != 1 {
+		t.Errorf("want the session to be refreshed")
+	}
+	now = now.Add(40 * time.Minute)
+	if got := do(http.MethodGet, "/", cookie, "").Body.String(); got != "gopher" {
+		t.Errorf("want the refreshed session to be loaded, got %q", got)
+	}
+
+	rec = do(http.MethodGet, "/logout", cookie, "")
+	if c := rec.Result().Cookies(); len(c) != 1 || c[0].MaxAge >= 0 {
+		t.Errorf("want the cookie to be expired, got %v", c)
+	}
+	if got := do(http.MethodGet, "/", cookie, "").Body.String(); got != "" {
+		t.Errorf("want a new session after logout, got %q", got)
+	}
+}
+
+func TestDurableObjectStore(t *testing.T) {
+	ns := &workerstest.DurableObjectNamespace{NewObject: workerstest.NewCoordinatorObject}
+	store := session.NewDurableObjectStore(ns)
+	ctx := context.Background()
+	if err := store.Save(ctx, "id", []byte(`{"data":1}`), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	data, found, err := store.Load(ctx, "id")
+	if err != nil || !found || !strings.Contains(string(data), `"data":1`) {
+		t.Fatalf("want the session, got %q, %v, %v", data, found, err)
+	}
+	if err := store.Delete(ctx, "id"); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, _ := store.Load(ctx, "id"); found {
+		t.Errorf("want the session to be deleted")
+	}
+}
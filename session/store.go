@@ -0,0 +1,84 @@
+package session
+
+import (
+	"context"
+	"time"
+
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/cloudflare/coordination"
+)
+
+// Store persists encoded sessions by their IDs.
+type Store interface {
+	// Load returns the session of the ID. found is false if it doesn't exist or is expired.
+	Load(ctx context.Context, id string) (data []byte, found bool, err error)
+	// Save saves the session, which expires after ttl.
+	Save(ctx context.Context, id string, data []byte, ttl time.Duration) error
+	// Delete deletes the session.
+	Delete(ctx context.Context, id string) error
+}
+
+// minKVTTL is the minimum expiration TTL of KV.
+const minKVTTL = 60 * time.Second
+
+// KVStore is Store backed by KV.
+//   - KV is eventually consistent, so updates may not be visible from other locations for up to 60 seconds.
+//     Use DurableObjectStore if it matters.
+type KVStore struct {
+	kv     cloudflare.KVNamespaceBinding
+	prefix string
+}
+
+var _ Store = (*KVStore)(nil)
+
+// NewKVStore returns KVStore saving sessions with the key prefix (e.g. "session:").
+func NewKVStore(kv cloudflare.KVNamespaceBinding, prefix string) *KVStore {
+	return &KVStore{kv: kv, prefix: prefix}
+}
+
+func (s *KVStore) Load(_ context.Context, id string) ([]byte, bool, error) {
+	v, err := s.kv.GetWithMetadata(s.prefix+id, nil)
+	if err != nil || v == nil {
+		return nil, false, err
+	}
+	return v.Value, true, nil
+}
+
+func (s *KVStore) Save(_ context.Context, id string, data []byte, ttl time.Duration) error {
+	return s.kv.PutString(s.prefix+id, string(data), &cloudflare.KVNamespacePutOptions{
+		ExpirationTTL: int(max(ttl, minKVTTL) / time.Second),
+	})
+}
+
+func (s *KVStore) Delete(_ context.Context, id string) error {
+	return s.kv.Delete(s.prefix + id)
+}
+
+// DurableObjectStore is Store backed by Durable Objects of the Coordinator class of the coordination package.
+// Sessions are strongly consistent, and each session is stored in its own object.
+type DurableObjectStore struct {
+	ns cloudflare.DurableObjectNamespaceBinding
+}
+
+var _ Store = (*DurableObjectStore)(nil)
+
+// NewDurableObjectStore returns DurableObjectStore of the namespace bound to the Coordinator class.
+func NewDurableObjectStore(ns cloudflare.DurableObjectNamespaceBinding) *DurableObjectStore {
+	return &DurableObjectStore{ns: ns}
+}
+
+func (s *DurableObjectStore) value(id string) *coordination.Value {
+	return coordination.NewValue(s.ns, "session:"+id)
+}
+
+func (s *DurableObjectStore) Load(_ context.Context, id string) ([]byte, bool, error) {
+	return s.value(id).Get()
+}
+
+func (s *DurableObjectStore) Save(_ context.Context, id string, data []byte, ttl time.Duration) error {
+	return s.value(id).Set(data, ttl)
+}
+
+func (s *DurableObjectStore) Delete(_ context.Context, id string) error {
+	return s.value(id).Delete()
+}
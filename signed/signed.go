@@ -0,0 +1,278 @@
+// Package signed issues and verifies HMAC-signed, expiring tokens for cookies and URLs.
+//   - tokens are signed by HMAC-SHA256 of WebCrypto (SubtleCrypto) of the runtime, and verified in constant time.
+//   - keys can be rotated: the first key signs tokens, and all keys verify them.
+//     Add a new secret in front of the old one, and remove the old one after tokens signed by it expire.
+//   - signed values are not encrypted, so they must not hold secrets.
+//
+// Example of a temporary link to an R2 object:
+//
+//	s, err := signed.NewSignerFromEnv(ctx, "LINK_KEY", "LINK_KEY_PREVIOUS")
+//	link, err := s.SignURL("https://example.com/files/report.pdf", time.Hour)
+//	// in the handler of /files/
+//	if err := s.VerifyURL(req.URL); err != nil { ... }
+package signed
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/syumai/workers"
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/webcrypto"
+)
+
+// Query parameters of signed URLs.
+const (
+	ExpiresParam   = "expires"
+	SignatureParam = "signature"
+)
+
+// MinSecretSize is the minimum size of secrets in bytes.
+const MinSecretSize = 32
+
+var (
+	// ErrInvalid is returned when the token is malformed, or its signature doesn't match any key.
+	ErrInvalid = errors.New("signed: invalid signature")
+	// ErrExpired is returned when the token is signed correctly, but it has expired.
+	ErrExpired = errors.New("signed: expired")
+)
+
+var hmacAlgorithm = map[string]any{"name": "HMAC", "hash": "SHA-256"}
+
+// Signer signs and verifies tokens by HMAC-SHA256.
+type Signer struct {
+	// Now returns the current time used for expirations. Defaults to time.Now.
+	Now func() time.Time
+
+	// keys are CryptoKeys of secrets. The first one signs tokens.
+	keys []js.Value
+}
+
+// NewSigner returns Signer of the secrets. The first secret signs tokens, and all secrets verify them.
+//   - if no secret is given, or a secret is shorter than MinSecretSize, returns error.
+func NewSigner(secrets ...[]byte) (*Signer, error) {
+	if len(secrets) == 0 {
+		return nil, errors.New("signed: no secret is given")
+	}
+	s := &Signer{keys: make([]js.Value, len(secrets))}
+	for i, secret := range secrets {
+		if len(secret) < MinSecretSize {
+			return nil, fmt.Errorf("signed: secret must be at least %d bytes", MinSecretSize)
+		}
+		key, err := webcrypto.ImportRawKey(secret, hmacAlgorithm, "sign", "verify")
+		if err != nil {
+			return nil, fmt.Errorf("signed: error importing key: %w", err)
+		}
+		s.keys[i] = key
+	}
+	return s, nil
+}
+
+// NewSignerFromEnv returns Signer of the secrets in environment variables of the names.
+//   - the first variable is the current secret, and must be defined.
+//   - following variables hold previous secrets for rotation, and are skipped if they are not defined.
+//   - This function panics when a runtime context is not found.
+func NewSignerFromEnv(ctx context.Context, names ...string) (*Signer, error) {
+	if len(names) == 0 {
+		return nil, errors.New("signed: no variable name is given")
+	}
+	var secrets [][]byte
+	for i, name := range names {
+		v, ok := cloudflare.LookupEnv(ctx, name)
+		if !ok || v == "" {
+			if i == 0 {
+				return nil, fmt.Errorf("%s is undefined", name)
+			}
+			continue
+		}
+		secrets = append(secrets, []byte(v))
+	}
+	return NewSigner(secrets...)
+}
+
+func (s *Signer) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+// sign returns the signature of the data of the purpose by the first key.
+func (s *Signer) sign(purpose, data string) (string, error) {
+	sig, err := webcrypto.Sign("HMAC", s.keys[0], []byte(purpose+"\x00"+data))
+	if err != nil {
+		return "", fmt.Errorf("signed: error signing: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// verify verifies the signature of the data of the purpose by all keys, and checks the expiration.
+func (s *Signer) verify(purpose, data, signature string, expires int64) error {
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return ErrInvalid
+	}
+	for _, key := range s.keys {
+		ok, err := webcrypto.Verify("HMAC", key, sig, []byte(purpose+"\x00"+data))
+		if err != nil {
+			return fmt.Errorf("signed: error verifying: %w", err)
+		}
+		if !ok {
+			continue
+		}
+		if s.now().Unix() >= expires {
+			return ErrExpired
+		}
+		return nil
+	}
+	return ErrInvalid
+}
+
+// expiration returns the expiration time in Unix seconds after ttl.
+func (s *Signer) expiration(ttl time.Duration) (int64, error) {
+	if ttl < time.Second {
+		return 0, fmt.Errorf("signed: ttl must be at least 1s, got %s", ttl)
+	}
+	return s.now().Add(ttl).Unix(), nil
+}
+
+// Sign returns the token of the value, which expires after ttl.
+//   - the token is URL safe, and can be used as a cookie value or a query parameter.
+//   - purpose separates tokens of different uses (e.g. "email-verification"), so a token can't be reused for another purpose.
+func (s *Signer) Sign(purpose, value string, ttl time.Duration) (string, error) {
+	exp, err := s.expiration(ttl)
+	if err != nil {
+		return "", err
+	}
+	data := base64.RawURLEncoding.EncodeToString([]byte(value)) + "." + strconv.FormatInt(exp, 10)
+	sig, err := s.sign("token:"+purpose, data)
+	if err != nil {
+		return "", err
+	}
+	return data + "." + sig, nil
+}
+
+// Verify returns the value of the token signed by Sign for the purpose.
+//   - returns ErrInvalid if the token is not signed by the keys, and ErrExpired if it has expired.
+func (s *Signer) Verify(purpose, token string) (string, error) {
+	data, sig, ok := cutLast(token, ".")
+	if !ok {
+		return "", ErrInvalid
+	}
+	encoded, expStr, ok := cutLast(data, ".")
+	if !ok {
+		return "", ErrInvalid
+	}
+	exp, err := strconv.ParseInt(expStr, 10, 64)
+	if err != nil {
+		return "", ErrInvalid
+	}
+	if err := s.verify("token:"+purpose, data, sig, exp); err != nil {
+		return "", err
+	}
+	value, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrInvalid
+	}
+	return string(value), nil
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+len(sep):], true
+}
+
+// SetCookie sets the cookie whose value is signed for the name of the cookie.
+//   - the token expires after ttl. If MaxAge and Expires of the cookie are not set, they are set to ttl.
+func (s *Signer) SetCookie(w http.ResponseWriter, cookie *http.Cookie, ttl time.Duration) error {
+	token, err := s.Sign("cookie:"+cookie.Name, cookie.Value, ttl)
+	if err != nil {
+		return err
+	}
+	c := *cookie
+	c.Value = token
+	if c.MaxAge == 0 && c.Expires.IsZero() {
+		c.MaxAge = int(ttl / time.Second)
+	}
+	http.SetCookie(w, &c)
+	return nil
+}
+
+// Cookie returns the value of the cookie set by SetCookie.
+//   - returns http.ErrNoCookie if the cookie is not found, and ErrInvalid or ErrExpired if it is not valid.
+func (s *Signer) Cookie(req *http.Request, name string) (string, error) {
+	c, err := req.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return s.Verify("cookie:"+name, c.Value)
+}
+
+// SignURL returns the URL with ExpiresParam and SignatureParam query parameters, which expires after ttl.
+//   - the path and the query are signed, but the scheme and the host are not, so the URL works on any hosts of the worker.
+func (s *Signer) SignURL(rawURL string, ttl time.Duration) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	exp, err := s.expiration(ttl)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Del(SignatureParam)
+	q.Set(ExpiresParam, strconv.FormatInt(exp, 10))
+	sig, err := s.sign("url", canonicalURL(u.EscapedPath(), q))
+	if err != nil {
+		return "", err
+	}
+	q.Set(SignatureParam, sig)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// VerifyURL verifies the URL signed by SignURL.
+//   - returns ErrInvalid if the URL is not signed by the keys, or the path or query is modified, and ErrExpired if it has expired.
+func (s *Signer) VerifyURL(u *url.URL) error {
+	q := u.Query()
+	sig := q.Get(SignatureParam)
+	exp, err := strconv.ParseInt(q.Get(ExpiresParam), 10, 64)
+	if sig == "" || err != nil {
+		return ErrInvalid
+	}
+	q.Del(SignatureParam)
+	return s.verify("url", canonicalURL(u.EscapedPath(), q), sig, exp)
+}
+
+// canonicalURL returns the signed form of the URL. Query parameters are sorted by url.Values.Encode.
+func canonicalURL(path string, q url.Values) string {
+	if path == "" {
+		path = "/"
+	}
+	return path + "?" + q.Encode()
+}
+
+// RequireURL returns a middleware verifying URLs of requests by VerifyURL.
+//   - if the URL is not valid, responds with status 403.
+func (s *Signer) RequireURL() workers.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if err := s.VerifyURL(req.URL); err != nil {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
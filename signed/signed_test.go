@@ -0,0 +1,164 @@
+//go:build js && wasm
+
+package signed_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/syumai/workers/signed"
+)
+
+var (
+	currentSecret  = bytes.Repeat([]byte("c"), signed.MinSecretSize)
+	previousSecret = bytes.Repeat([]byte("p"), signed.MinSecretSize)
+	otherSecret    = bytes.Repeat([]byte("o"), signed.MinSecretSize)
+)
+
+func newSigner(t *testing.T, now time.Time, secrets ...[]byte) *signed.Signer {
+	t.Helper()
+	s, err := signed.NewSigner(secrets...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Now = func() time.Time { return now }
+	return s
+}
+
+func TestSigner_Verify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	issued, err := newSigner(t, now, previousSecret).Sign("stamp", "user:42", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]struct {
+		verifier *signed.Signer
+		purpose  string
+		token    string
+		want     string
+		wantErr  error
+	}{
+		"rotated key": {
+			verifier: newSigner(t, now.Add(time.Minute), currentSecret, previousSecret),
+			purpose:  "stamp",
+			token:    issued,
+			want:     "user:42",
+		},
+		"expired": {
+			verifier: newSigner(t, now.Add(time.Hour), currentSecret, previousSecret),
+			purpose:  "stamp",
+			token:    issued,
+			wantErr:  signed.ErrExpired,
+		},
+		"removed key": {
+			verifier: newSigner(t, now, currentSecret, otherSecret),
+			purpose:  "stamp",
+			token:    issued,
+			wantErr:  signed.ErrInvalid,
+		},
+		"other purpose": {
+			verifier: newSigner(t, now, previousSecret),
+			purpose:  "reset",
+			token:    issued,
+			wantErr:  signed.ErrInvalid,
+		},
+		"tampered": {
+			verifier: newSigner(t, now, previousSecret),
+			purpose:  "stamp",
+			token:    "dXNlcjo0Mw" + issued[len("dXNlcjo0Mg"):],
+			wantErr:  signed.ErrInvalid,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			got, err := tc.verifier.Verify(tc.purpose, tc.token)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("want error %v, got %v", tc.wantErr, err)
+			}
+			if got != tc.want {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSigner_Cookie(t *testing.T) {
+	s := newSigner(t, time.Now(), currentSecret)
+	rec := httptest.NewRecorder()
+	if err := s.SetCookie(rec, &http.Cookie{Name: "uid", Value: "42; admin", Path: "/"}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	cookie := rec.Result().Cookies()[0]
+	if cookie.MaxAge != 60 {
+		t.Errorf("want MaxAge 60, got %d", cookie.MaxAge)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+	if got, err := s.Cookie(req, "uid"); err != nil || got != "42; admin" {
+		t.Errorf("want value, got %q, %v", got, err)
+	}
+
+	renamed := httptest.NewRequest(http.MethodGet, "/", nil)
+	renamed.AddCookie(&http.Cookie{Name: "sid", Value: cookie.Value})
+	if _, err := s.Cookie(renamed, "sid"); !errors.Is(err, signed.ErrInvalid) {
+		t.Errorf("want ErrInvalid for a token of another cookie, got %v", err)
+	}
+}
+
+func TestSigner_URL(t *testing.T) {
+	s := newSigner(t, time.Now(), currentSecret)
+	link, err := s.SignURL("https://example.com/files/a%20b.pdf?download=1", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(link)
+	if err := s.VerifyURL(u); err != nil {
+		t.Fatalf("want valid URL, got %v", err)
+	}
+
+	handler := s.RequireURL()(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	tests := map[string]struct {
+		modify     func(u url.URL) string
+		wantStatus int
+	}{
+		"valid on another host": {
+			modify:     func(u url.URL) string { u.Host = "worker.example.dev"; return u.String() },
+			wantStatus: http.StatusOK,
+		},
+		"modified path": {
+			modify:     func(u url.URL) string { u.Path = "/files/c.pdf"; u.RawPath = ""; return u.String() },
+			wantStatus: http.StatusForbidden,
+		},
+		"modified query": {
+			modify: func(u url.URL) string {
+				q := u.Query()
+				q.Set("download", "0")
+				u.RawQuery = q.Encode()
+				return u.String()
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		"unsigned": {
+			modify:     func(u url.URL) string { u.RawQuery = ""; return u.String() },
+			wantStatus: http.StatusForbidden,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.modify(*u), nil))
+			if rec.Code != tc.wantStatus {
+				t.Errorf("want status %d, got %d", tc.wantStatus, rec.Code)
+			}
+		})
+	}
+}
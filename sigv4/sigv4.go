@@ -0,0 +1,253 @@
+// Package sigv4 signs outbound requests by AWS Signature Version 4.
+//   - https://docs.aws.amazon.com/IAM/latest/UserGuide/create-signed-request.html
+//   - requests can be sent to S3-compatible APIs (including R2's S3 endpoint) and AWS services.
+//   - HMAC and SHA-256 are calculated by WebCrypto (SubtleCrypto) of the runtime.
+package sigv4
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/syumai/workers/cloudflare/fetch"
+	"github.com/syumai/workers/internal/awssig"
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/webcrypto"
+)
+
+// Credentials are AWS credentials used for signing.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is the token of temporary credentials. It is sent as X-Amz-Security-Token header.
+	SessionToken string
+}
+
+// Signer signs requests by AWS Signature Version 4.
+type Signer struct {
+	Credentials Credentials
+	// Region is the region of the service (e.g. "us-east-1"). Use "auto" for R2.
+	Region string
+	// Service is the name of the service (e.g. "s3", "sqs").
+	Service string
+	// UnsignedPayload skips hashing the body, and signs "UNSIGNED-PAYLOAD" instead.
+	//   - it is supported only by S3-compatible APIs. The body is streamed without buffering.
+	UnsignedPayload bool
+	// Now returns the signing time. Defaults to time.Now.
+	Now func() time.Time
+
+	mu sync.Mutex
+	// keyScope is the credential scope of key.
+	keyScope string
+	// key is the signing key (CryptoKey) derived for keyScope.
+	key js.Value
+}
+
+var hmacAlgorithm = map[string]any{"name": "HMAC", "hash": "SHA-256"}
+
+// unsignedHeaders are headers excluded from signing, since they may be changed in transit.
+var unsignedHeaders = map[string]bool{
+	"authorization":   true,
+	"user-agent":      true,
+	"connection":      true,
+	"expect":          true,
+	"content-length":  true,
+	"x-amzn-trace-id": true,
+	"traceparent":     true,
+	"tracestate":      true,
+}
+
+// Sign signs the request in place by setting X-Amz-Date and Authorization headers.
+//   - the body is read to calculate its hash, and replaced by a buffer of the body unless UnsignedPayload is set.
+//   - for S3, X-Amz-Content-Sha256 header is also set.
+//   - if credentials are empty or WebCrypto fails, returns error.
+func (s *Signer) Sign(req *http.Request) error {
+	if s.Credentials.AccessKeyID == "" || s.Credentials.SecretAccessKey == "" {
+		return errors.New("sigv4: credentials are empty")
+	}
+	if s.Region == "" || s.Service == "" {
+		return errors.New("sigv4: region and service must not be empty")
+	}
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	t := now().UTC()
+	date := t.Format(awssig.DateFormat)
+	scope := awssig.Scope(date, s.Region, s.Service)
+
+	payloadHash, err := s.payloadHash(req)
+	if err != nil {
+		return err
+	}
+	req.Header.Del("Authorization")
+	req.Header.Set("X-Amz-Date", t.Format(awssig.TimeFormat))
+	if s.Credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.Credentials.SessionToken)
+	}
+	if s.Service == "s3" || s.UnsignedPayload {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	// the path is encoded by SigV4 rules, and sent as it is signed.
+	path := req.URL.Path
+	if path == "" {
+		path = "/"
+	}
+	canonicalURI := awssig.URIEncode(path, false)
+	req.URL.RawPath = canonicalURI
+	if s.Service != "s3" {
+		// services other than S3 encode the path twice.
+		canonicalURI = awssig.URIEncode(canonicalURI, false)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if unsignedHeaders[name] {
+			continue
+		}
+		headers[name] = strings.Join(values, ",")
+	}
+	canonicalHeaders, signedHeaders := awssig.CanonicalHeaders(headers)
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI,
+		awssig.CanonicalQueryString(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	canonicalHash, err := hexSHA256([]byte(canonicalRequest))
+	if err != nil {
+		return err
+	}
+	stringToSign := strings.Join([]string{
+		awssig.Algorithm,
+		t.Format(awssig.TimeFormat),
+		scope,
+		canonicalHash,
+	}, "\n")
+
+	key, err := s.signingKey(date, scope)
+	if err != nil {
+		return err
+	}
+	signature, err := webcrypto.Sign("HMAC", key, []byte(stringToSign))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", awssig.Algorithm+
+		" Credential="+s.Credentials.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+
+		", Signature="+hex.EncodeToString(signature))
+	return nil
+}
+
+// payloadHash returns the hex-encoded SHA-256 hash of the body.
+func (s *Signer) payloadHash(req *http.Request) (string, error) {
+	if s.UnsignedPayload {
+		return awssig.UnsignedPayload, nil
+	}
+	if req.Body == nil || req.Body == http.NoBody {
+		return hexSHA256(nil)
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return "", err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.ContentLength = int64(len(body))
+	return hexSHA256(body)
+}
+
+// signingKey returns the signing key of the scope. The key is cached until the date changes.
+func (s *Signer) signingKey(date, scope string) (js.Value, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.keyScope == scope {
+		return s.key, nil
+	}
+	key := []byte("AWS4" + s.Credentials.SecretAccessKey)
+	for _, data := range []string{date, s.Region, s.Service, "aws4_request"} {
+		var err error
+		key, err = hmacSHA256(key, data)
+		if err != nil {
+			return js.Value{}, err
+		}
+	}
+	k, err := webcrypto.ImportRawKey(key, hmacAlgorithm, "sign")
+	if err != nil {
+		return js.Value{}, err
+	}
+	s.keyScope, s.key = scope, k
+	return k, nil
+}
+
+func hmacSHA256(key []byte, data string) ([]byte, error) {
+	k, err := webcrypto.ImportRawKey(key, hmacAlgorithm, "sign")
+	if err != nil {
+		return nil, err
+	}
+	return webcrypto.Sign("HMAC", k, []byte(data))
+}
+
+func hexSHA256(data []byte) (string, error) {
+	sum, err := webcrypto.Digest("SHA-256", data)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sum), nil
+}
+
+// Client is Fetcher signing requests before they are sent by the underlying Fetcher.
+type Client struct {
+	fetcher fetch.Fetcher
+	signer  *Signer
+}
+
+var (
+	_ fetch.Fetcher     = (*Client)(nil)
+	_ http.RoundTripper = (*Client)(nil)
+)
+
+// NewClient returns Client sending requests signed by the signer. If fetcher is nil, fetch.NewClient() is used.
+func NewClient(fetcher fetch.Fetcher, signer *Signer) *Client {
+	if fetcher == nil {
+		fetcher = fetch.NewClient()
+	}
+	return &Client{fetcher: fetcher, signer: signer}
+}
+
+// Do signs a clone of the request, and sends it.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	if err := c.signer.Sign(out); err != nil {
+		return nil, err
+	}
+	return c.fetcher.Do(out)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (c *Client) RoundTrip(req *http.Request) (*http.Response, error) {
+	return c.Do(req)
+}
+
+// HTTPClient returns *http.Client using the Client as Transport.
+func (c *Client) HTTPClient() *http.Client {
+	return &http.Client{Transport: c}
+}
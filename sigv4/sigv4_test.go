@@ -0,0 +1,114 @@
+//go:build js && wasm
+
+package sigv4_test
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syumai/workers/sigv4"
+)
+
+type fetcherFunc func(req *http.Request) (*http.Response, error)
+
+func (f fetcherFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// newSigner returns Signer of the AWS SigV4 test suite.
+//   - https://docs.aws.amazon.com/general/latest/gr/signature-v4-test-suite.html
+func newSigner() *sigv4.Signer {
+	return &sigv4.Signer{
+		Credentials: sigv4.Credentials{
+			AccessKeyID:     "AKIDEXAMPLE",
+			SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		},
+		Region:  "us-east-1",
+		Service: "service",
+		Now:     func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) },
+	}
+}
+
+func TestSigner_Sign(t *testing.T) {
+	const credential = "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "
+	tests := map[string]struct {
+		method  string
+		url     string
+		body    string
+		headers map[string]string
+		want    string
+	}{
+		"get-vanilla": {
+			method: http.MethodGet,
+			url:    "https://example.amazonaws.com/",
+			want:   credential + "SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		"get-vanilla-query-order-key-case": {
+			method: http.MethodGet,
+			url:    "https://example.amazonaws.com/?Param2=value2&Param1=value1",
+			want:   credential + "SignedHeaders=host;x-amz-date, Signature=b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+		},
+		"post-x-www-form-urlencoded": {
+			method:  http.MethodPost,
+			url:     "https://example.amazonaws.com/",
+			body:    "Param1=value1",
+			headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded", "User-Agent": "test"},
+			want:    credential + "SignedHeaders=content-type;host;x-amz-date, Signature=ff11897932ad3f4e8b18135d722051e5ac45fc38421b1da7b9d196a0fe09473a",
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, tc.url, strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			if err := newSigner().Sign(req); err != nil {
+				t.Fatal(err)
+			}
+			if got := req.Header.Get("Authorization"); got != tc.want {
+				t.Errorf("want Authorization %q, got %q", tc.want, got)
+			}
+			if b, _ := io.ReadAll(req.Body); string(b) != tc.body {
+				t.Errorf("want the body to be preserved, got %q", b)
+			}
+		})
+	}
+}
+
+func TestClient(t *testing.T) {
+	signer := newSigner()
+	signer.Service = "s3"
+	signer.UnsignedPayload = true
+	var out *http.Request
+	client := sigv4.NewClient(fetcherFunc(func(req *http.Request) (*http.Response, error) {
+		out = req
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	}), signer)
+	req, err := http.NewRequest(http.MethodPut, "https://account.r2.cloudflarestorage.com/bucket/a b.txt", strings.NewReader("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Header.Get("Authorization") != "" {
+		t.Error("want the original request not to be modified")
+	}
+	if got := out.Header.Get("X-Amz-Content-Sha256"); got != "UNSIGNED-PAYLOAD" {
+		t.Errorf("want UNSIGNED-PAYLOAD, got %q", got)
+	}
+	if got := out.URL.EscapedPath(); got != "/bucket/a%20b.txt" {
+		t.Errorf("want the path to be encoded as signed, got %q", got)
+	}
+	if !strings.Contains(out.Header.Get("Authorization"), "SignedHeaders=host;x-amz-content-sha256;x-amz-date,") {
+		t.Errorf("unexpected Authorization: %q", out.Header.Get("Authorization"))
+	}
+}
@@ -0,0 +1,140 @@
+// Package split splits traffic into weighted variants for A/B tests and canary deployments.
+//   - a client is assigned to a variant by the hash of a stable key (e.g. IP address), and the assignment is kept by a cookie.
+//   - variants are typically proxies to different origins or service bindings.
+//
+// Example:
+//
+//	s := split.New([]*split.Variant{
+//	  {Name: "stable", Weight: 95, Handler: workers.NewProxy("https://origin.example.com", nil)},
+//	  {Name: "canary", Weight: 5, Handler: workers.NewProxy("https://canary", &workers.ProxyOptions{Client: fetch.Service("CANARY")})},
+//	}, &split.Options{Salt: "release-42"})
+package split
+
+import (
+	"context"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// Variant is a destination of the traffic.
+type Variant struct {
+	// Name identifies the variant in the cookie. It must be a valid cookie value.
+	Name string
+	// Weight is the relative weight of the traffic. Variants of weight 0 receive no new clients,
+	// and clients assigned to them are assigned again, so a canary can be rolled back by setting its weight to 0.
+	Weight int
+	// Handler serves requests assigned to the variant.
+	Handler http.Handler
+}
+
+// Options represents options of New.
+type Options struct {
+	// CookieName is the name of the cookie keeping the assignment. Defaults to "variant".
+	CookieName string
+	// DisableCookie disables sticky assignment by cookies. Clients are assigned only by keys.
+	DisableCookie bool
+	// CookieMaxAge is the max age of the cookie. Defaults to 30 days.
+	CookieMaxAge time.Duration
+	// Key returns the stable key of the client. Defaults to CF-Connecting-IP header.
+	// If the key is empty, the client is assigned randomly.
+	Key func(req *http.Request) string
+	// Salt is hashed with keys, so different experiments assign clients independently.
+	Salt string
+}
+
+// Splitter is http.Handler dispatching requests to variants.
+type Splitter struct {
+	variants []*Variant
+	total    int
+	opts     Options
+}
+
+// New returns a Splitter of the variants.
+// It panics if no variant has positive weight.
+func New(variants []*Variant, opts *Options) *Splitter {
+	s := &Splitter{variants: variants}
+	if opts != nil {
+		s.opts = *opts
+	}
+	if s.opts.CookieName == "" {
+		s.opts.CookieName = "variant"
+	}
+	if s.opts.CookieMaxAge == 0 {
+		s.opts.CookieMaxAge = 30 * 24 * time.Hour
+	}
+	if s.opts.Key == nil {
+		s.opts.Key = func(req *http.Request) string {
+			return req.Header.Get("CF-Connecting-IP")
+		}
+	}
+	for _, v := range variants {
+		s.total += max(v.Weight, 0)
+	}
+	if s.total == 0 {
+		panic("split: no variant has positive weight")
+	}
+	return s
+}
+
+// Assign returns the variant of the request.
+//   - the variant in the cookie is respected if its weight is positive.
+//   - fromCookie reports whether the variant is given by the cookie.
+func (s *Splitter) Assign(req *http.Request) (v *Variant, fromCookie bool) {
+	if !s.opts.DisableCookie {
+		if c, err := req.Cookie(s.opts.CookieName); err == nil {
+			for _, v := range s.variants {
+				if v.Name == c.Value && v.Weight > 0 {
+					return v, true
+				}
+			}
+		}
+	}
+	var n int
+	if key := s.opts.Key(req); key != "" {
+		h := fnv.New32a()
+		h.Write([]byte(s.opts.Salt))
+		h.Write([]byte{0})
+		h.Write([]byte(key))
+		n = int(h.Sum32() % uint32(s.total))
+	} else {
+		n = rand.Intn(s.total)
+	}
+	for _, v := range s.variants {
+		if v.Weight <= 0 {
+			continue
+		}
+		if n < v.Weight {
+			return v, false
+		}
+		n -= v.Weight
+	}
+	// unreachable since n < s.total.
+	return s.variants[len(s.variants)-1], false
+}
+
+type variantKey struct{}
+
+// VariantName returns the name of the variant assigned to the request handled by Splitter.
+func VariantName(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(variantKey{}).(string)
+	return name, ok
+}
+
+// ServeHTTP dispatches the request to the assigned variant, and sets the cookie if the assignment is new.
+func (s *Splitter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	v, fromCookie := s.Assign(req)
+	if !fromCookie && !s.opts.DisableCookie {
+		http.SetCookie(w, &http.Cookie{
+			Name:     s.opts.CookieName,
+			Value:    v.Name,
+			Path:     "/",
+			MaxAge:   int(s.opts.CookieMaxAge / time.Second),
+			HttpOnly: true,
+			Secure:   true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+	v.Handler.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), variantKey{}, v.Name)))
+}
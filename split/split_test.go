@@ -0,0 +1,93 @@
+package split
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newVariants(weights ...int) []*Variant {
+	variants := make([]*Variant, len(weights))
+	for i, w := range weights {
+		name := fmt.Sprintf("v%d", i)
+		variants[i] = &Variant{
+			Name:   name,
+			Weight: w,
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				got, _ := VariantName(req.Context())
+				fmt.Fprint(w, got)
+			}),
+		}
+	}
+	return variants
+}
+
+func TestSplitter(t *testing.T) {
+	tests := map[string]struct {
+		weights    []int
+		cookie     string
+		ip         string
+		want       string
+		wantCookie bool
+	}{
+		"sticky cookie": {
+			weights: []int{50, 50},
+			cookie:  "v1",
+			ip:      "192.0.2.1",
+			want:    "v1",
+		},
+		"cookie of drained variant is reassigned": {
+			weights:    []int{100, 0},
+			cookie:     "v1",
+			ip:         "192.0.2.1",
+			want:       "v0",
+			wantCookie: true,
+		},
+		"assigned by key": {
+			weights:    []int{0, 100},
+			ip:         "192.0.2.1",
+			want:       "v1",
+			wantCookie: true,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			s := New(newVariants(tc.weights...), nil)
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("CF-Connecting-IP", tc.ip)
+			if tc.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "variant", Value: tc.cookie})
+			}
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+			if got := rec.Body.String(); got != tc.want {
+				t.Errorf("want variant %q, got %q", tc.want, got)
+			}
+			if got := rec.Header().Get("Set-Cookie") != ""; got != tc.wantCookie {
+				t.Errorf("want cookie %v, got %q", tc.wantCookie, rec.Header().Get("Set-Cookie"))
+			}
+		})
+	}
+}
+
+func TestSplitter_Assign_Weights(t *testing.T) {
+	s := New(newVariants(90, 10), &Options{Salt: "exp"})
+	counts := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("CF-Connecting-IP", fmt.Sprintf("10.0.%d.%d", i/256, i%256))
+		v, _ := s.Assign(req)
+		counts[v.Name]++
+		// the assignment of the same key is stable.
+		if again, _ := s.Assign(req); again != v {
+			t.Fatalf("want stable assignment for %s", req.Header.Get("CF-Connecting-IP"))
+		}
+	}
+	if counts["v1"] < 800 || counts["v1"] > 1200 {
+		t.Errorf("want about 10%% of clients assigned to v1, got %v", counts)
+	}
+}
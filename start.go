@@ -0,0 +1,82 @@
+package workers
+
+import (
+	"net/http"
+)
+
+// Options represents handlers of events registered by Start.
+// Handlers which are nil are not registered, and the JavaScript entry point must not dispatch their events.
+//   - handlers of events other than requests are interfaces implemented by handler types of the event packages
+//     (e.g. cron.Handler, queues.Consumer, email.Handler and tail.Handler), so workers link only the packages of the events they handle.
+//   - handlers can also be registered by their packages before Start (e.g. cron.Schedule and queues.Consume).
+type Options struct {
+	// Fetch handles requests. Middlewares registered by Use are applied to it.
+	Fetch http.Handler
+	// Scheduled handles scheduled events. Set cron.DefaultMux to dispatch events to handlers registered by cron.OnCron.
+	Scheduled ScheduledHandler
+	// Queue consumes batches of messages of queues (e.g. queues.Consumer(fn) or queues.EachMessage(fn, nil)).
+	Queue QueueConsumer
+	// Email handles incoming emails (e.g. email.Handler(fn)).
+	Email EmailHandler
+	// Tail handles trace items of producer Workers (e.g. tail.Handler(fn)).
+	Tail TailHandler
+}
+
+// ScheduledHandler is implemented by cron.Handler and *cron.Mux.
+type ScheduledHandler interface {
+	// RegisterScheduled registers the handler of scheduled events.
+	RegisterScheduled()
+}
+
+// QueueConsumer is implemented by queues.Consumer.
+type QueueConsumer interface {
+	// RegisterQueue registers the consumer of queues.
+	RegisterQueue()
+}
+
+// EmailHandler is implemented by email.Handler.
+type EmailHandler interface {
+	// RegisterEmail registers the handler of incoming emails.
+	RegisterEmail()
+}
+
+// TailHandler is implemented by tail.Handler.
+type TailHandler interface {
+	// RegisterTail registers the handler of tail events.
+	RegisterTail()
+}
+
+// Start registers the handlers of events, signals the JavaScript entry point that the Go program is ready,
+// and blocks until the entry point tears down the instance. See OnReady and OnTeardown.
+// It is called once at the end of main, instead of Serve.
+//
+//	workers.Start(workers.Options{
+//	  Fetch:     router,
+//	  Scheduled: cron.DefaultMux,
+//	  Queue:     queues.Consumer(consumeLogs),
+//	})
+func Start(opts Options) {
+	Register(opts)
+	signalReady()
+}
+
+// Register registers the handlers of events without signaling the JavaScript entry point.
+// Start calls it, so it is not needed to be called by workers in general.
+// It is used to dispatch events without the JavaScript runtime (e.g. by the replay package).
+func Register(opts Options) {
+	if opts.Fetch != nil {
+		httpHandler = Chain(globalMiddlewares...)(opts.Fetch)
+	}
+	if opts.Scheduled != nil {
+		opts.Scheduled.RegisterScheduled()
+	}
+	if opts.Queue != nil {
+		opts.Queue.RegisterQueue()
+	}
+	if opts.Email != nil {
+		opts.Email.RegisterEmail()
+	}
+	if opts.Tail != nil {
+		opts.Tail.RegisterTail()
+	}
+}
@@ -0,0 +1,72 @@
+// Package timing measures wall time and CPU time of handlers, to find handlers at risk of hitting CPU limits.
+//   - wall time is measured by performance.now. On Cloudflare Workers, it advances only while waiting for I/O,
+//     so it doesn't include time spent executing code.
+//   - CPU time is measured by process.cpuUsage if the runtime provides it (e.g. with nodejs_compat flag).
+//     It is the CPU time of the isolate, so it includes other requests handled concurrently.
+//   - the CPU time of each invocation is also reported to Tail Workers (tail.TraceItem.CPUTime).
+//   - https://developers.cloudflare.com/workers/platform/limits/#cpu-time
+package timing
+
+import (
+	"time"
+
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jsutil"
+)
+
+// WallTime returns the value of performance.now as a duration since the start of the event.
+func WallTime() time.Duration {
+	return millis(jsutil.PerformanceNow())
+}
+
+// CPUTime returns the cumulative CPU time (user and system) of the isolate.
+//   - ok is false if the runtime doesn't provide process.cpuUsage.
+func CPUTime() (d time.Duration, ok bool) {
+	process := jsutil.Global.Get("process")
+	if process.Type() != js.TypeObject {
+		return 0, false
+	}
+	if process.Get("cpuUsage").Type() != js.TypeFunction {
+		return 0, false
+	}
+	usage := process.Call("cpuUsage")
+	// cpuUsage reports microseconds.
+	micros := usage.Get("user").Float() + usage.Get("system").Float()
+	return time.Duration(micros * float64(time.Microsecond)), true
+}
+
+// Stopwatch measures wall time and CPU time since it is started.
+type Stopwatch struct {
+	wallStart time.Duration
+	cpuStart  time.Duration
+	cpuOK     bool
+}
+
+// Start returns a started Stopwatch.
+func Start() *Stopwatch {
+	s := &Stopwatch{wallStart: WallTime()}
+	s.cpuStart, s.cpuOK = CPUTime()
+	return s
+}
+
+// Wall returns the wall time elapsed since the Stopwatch started.
+func (s *Stopwatch) Wall() time.Duration {
+	return WallTime() - s.wallStart
+}
+
+// CPU returns the CPU time consumed since the Stopwatch started.
+//   - ok is false if the runtime doesn't report CPU time.
+func (s *Stopwatch) CPU() (d time.Duration, ok bool) {
+	if !s.cpuOK {
+		return 0, false
+	}
+	now, ok := CPUTime()
+	if !ok {
+		return 0, false
+	}
+	return now - s.cpuStart, true
+}
+
+func millis(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
@@ -0,0 +1,35 @@
+package timing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jsutil"
+)
+
+func TestStopwatch(t *testing.T) {
+	var micros float64
+	cpuUsage := js.FuncOf(func(js.Value, []js.Value) any {
+		return js.ValueOf(map[string]any{"user": micros, "system": 500})
+	})
+	defer cpuUsage.Release()
+	orig := jsutil.Global.Get("process")
+	defer jsutil.Global.Set("process", orig)
+
+	jsutil.Global.Set("process", js.Undefined())
+	if _, ok := Start().CPU(); ok {
+		t.Error("want CPU time not to be reported without process.cpuUsage")
+	}
+
+	jsutil.Global.Set("process", js.ValueOf(map[string]any{"cpuUsage": cpuUsage}))
+	micros = 1000
+	s := Start()
+	micros = 3500
+	if d, ok := s.CPU(); !ok || d != 2500*time.Microsecond {
+		t.Errorf("want 2.5ms, got %v, %v", d, ok)
+	}
+	if d := s.Wall(); d < 0 {
+		t.Errorf("want non-negative wall time, got %v", d)
+	}
+}
@@ -0,0 +1,125 @@
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// TraceContext represents W3C Trace Context of the request.
+//   - https://www.w3.org/TR/trace-context/
+type TraceContext struct {
+	// TraceID is a 32 hex characters ID shared by the whole distributed trace.
+	TraceID string
+	// SpanID is a 16 hex characters ID of the current span.
+	SpanID string
+	// ParentSpanID is the span ID given by the caller. This is empty if the request started a new trace.
+	ParentSpanID string
+	// Sampled is the sampled flag of the trace.
+	Sampled bool
+	// TraceState is the value of tracestate header, passed through as is.
+	TraceState string
+	// RayID is the value of Cf-Ray header of the incoming request.
+	RayID string
+}
+
+type traceContextKey struct{}
+
+// NewContext returns a context holding the TraceContext.
+func NewContext(ctx context.Context, tc *TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, tc)
+}
+
+// FromContext returns the TraceContext held by the context.
+func FromContext(ctx context.Context) (*TraceContext, bool) {
+	tc, ok := ctx.Value(traceContextKey{}).(*TraceContext)
+	return tc, ok
+}
+
+// Extract builds TraceContext from traceparent, tracestate and Cf-Ray headers of the incoming request.
+//   - if the request doesn't have valid traceparent header, a new trace is started.
+func Extract(req *http.Request) *TraceContext {
+	tc := &TraceContext{
+		SpanID:  newID(8),
+		RayID:   req.Header.Get("Cf-Ray"),
+		Sampled: true,
+	}
+	traceID, parentID, flags, ok := parseTraceParent(req.Header.Get("traceparent"))
+	if !ok {
+		tc.TraceID = newID(16)
+		return tc
+	}
+	tc.TraceID = traceID
+	tc.ParentSpanID = parentID
+	tc.Sampled = flags&0x01 == 0x01
+	tc.TraceState = req.Header.Get("tracestate")
+	return tc
+}
+
+// Inject sets traceparent and tracestate headers for an outbound request.
+// The span ID of the current span is propagated as the parent, so the callee becomes a child of the current span.
+//   - if the context doesn't hold TraceContext, the header is not modified.
+func Inject(ctx context.Context, header http.Header) {
+	tc, ok := FromContext(ctx)
+	if !ok {
+		return
+	}
+	if header.Get("traceparent") != "" {
+		// respect the header set explicitly.
+		return
+	}
+	flags := 0
+	if tc.Sampled {
+		flags = 1
+	}
+	header.Set("traceparent", fmt.Sprintf("00-%s-%s-%02x", tc.TraceID, tc.SpanID, flags))
+	if tc.TraceState != "" {
+		header.Set("tracestate", tc.TraceState)
+	}
+}
+
+// parseTraceParent parses the value of traceparent header.
+//   - https://www.w3.org/TR/trace-context/#traceparent-header
+func parseTraceParent(v string) (traceID, parentID string, flags byte, ok bool) {
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return "", "", 0, false
+	}
+	// version 00 must have exactly 4 parts. future versions can append fields.
+	if parts[0] == "00" && len(parts) != 4 {
+		return "", "", 0, false
+	}
+	traceID, parentID = strings.ToLower(parts[1]), strings.ToLower(parts[2])
+	if !isValidID(traceID, 16) || !isValidID(parentID, 8) {
+		return "", "", 0, false
+	}
+	f, err := hex.DecodeString(parts[3])
+	if err != nil || len(f) != 1 {
+		return "", "", 0, false
+	}
+	return traceID, parentID, f[0], true
+}
+
+// isValidID reports whether the id is a non-zero hex string of the given byte length.
+func isValidID(id string, n int) bool {
+	b, err := hex.DecodeString(id)
+	if err != nil || len(b) != n {
+		return false
+	}
+	for _, c := range b {
+		if c != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// newID generates a random hex ID of the given byte length.
+func newID(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
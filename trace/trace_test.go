@@ -0,0 +1,72 @@
+package trace
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_parseTraceParent(t *testing.T) {
+	tests := map[string]struct {
+		value        string
+		wantTraceID  string
+		wantParentID string
+		wantFlags    byte
+		wantOK       bool
+	}{
+		"valid": {
+			value:        "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			wantTraceID:  "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantParentID: "00f067aa0ba902b7",
+			wantFlags:    1,
+			wantOK:       true,
+		},
+		"future version with extra fields": {
+			value:        "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra",
+			wantTraceID:  "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantParentID: "00f067aa0ba902b7",
+			wantFlags:    0,
+			wantOK:       true,
+		},
+		"empty": {
+			value: "",
+		},
+		"invalid version": {
+			value: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		},
+		"zero trace ID": {
+			value: "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		},
+		"short span ID": {
+			value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa-01",
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			traceID, parentID, flags, ok := parseTraceParent(tc.value)
+			if ok != tc.wantOK || traceID != tc.wantTraceID || parentID != tc.wantParentID || flags != tc.wantFlags {
+				t.Errorf("parseTraceParent() = (%q, %q, %d, %v), want (%q, %q, %d, %v)",
+					traceID, parentID, flags, ok, tc.wantTraceID, tc.wantParentID, tc.wantFlags, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestInject(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	tc := Extract(req)
+	header := http.Header{}
+	Inject(NewContext(context.Background(), tc), header)
+	traceID, parentID, flags, ok := parseTraceParent(header.Get("traceparent"))
+	if !ok {
+		t.Fatalf("invalid traceparent: %q", header.Get("traceparent"))
+	}
+	if traceID != tc.TraceID || parentID != tc.SpanID || flags != 1 {
+		t.Errorf("want %s-%s-01, got %s-%s-%02x", tc.TraceID, tc.SpanID, traceID, parentID, flags)
+	}
+}
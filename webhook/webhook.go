@@ -0,0 +1,205 @@
+// Package webhook verifies HMAC-SHA256 signatures of incoming webhooks.
+//   - signatures are calculated by native SubtleCrypto, and compared in constant time.
+//   - schemes of GitHub, Slack and Stripe are predefined, and other providers can be described by Scheme.
+package webhook
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/syumai/workers"
+	"github.com/syumai/workers/internal/webcrypto"
+	"github.com/syumai/workers/secret"
+)
+
+var (
+	// ErrMissingSignature is returned when the signature or the timestamp is not given.
+	ErrMissingSignature = errors.New("webhook: missing signature")
+	// ErrInvalidSignature is returned when no signature matches the body.
+	ErrInvalidSignature = errors.New("webhook: invalid signature")
+	// ErrTimestampOutOfTolerance is returned when the timestamp is too far from the current time.
+	ErrTimestampOutOfTolerance = errors.New("webhook: timestamp out of tolerance")
+)
+
+// Encoding is the encoding of signatures.
+type Encoding int
+
+const (
+	Hex Encoding = iota
+	Base64
+)
+
+func (e Encoding) decode(s string) ([]byte, error) {
+	if e == Base64 {
+		return base64.StdEncoding.DecodeString(s)
+	}
+	return hex.DecodeString(s)
+}
+
+// Scheme describes how a provider signs webhooks.
+type Scheme struct {
+	// SignatureHeader is the name of the header holding signatures.
+	SignatureHeader string
+	// Prefix is trimmed from the signature (e.g. "sha256=").
+	Prefix   string
+	Encoding Encoding
+	// TimestampHeader is the name of the header holding the Unix timestamp. It is not used if Parse is set.
+	TimestampHeader string
+	// Parse extracts the timestamp and signatures from the value of SignatureHeader.
+	// If nil, the value is a single signature.
+	Parse func(header string) (timestamp string, signatures []string)
+	// Payload returns the signed payload. If nil, the body is signed.
+	Payload func(timestamp string, body []byte) []byte
+	// Tolerance is the maximum difference between the timestamp and the current time.
+	// Zero means the timestamp is not checked.
+	Tolerance time.Duration
+}
+
+var (
+	// GitHub verifies X-Hub-Signature-256 header.
+	//   - https://docs.github.com/webhooks/using-webhooks/validating-webhook-deliveries
+	GitHub = &Scheme{
+		SignatureHeader: "X-Hub-Signature-256",
+		Prefix:          "sha256=",
+		Encoding:        Hex,
+	}
+	// Slack verifies X-Slack-Signature header with X-Slack-Request-Timestamp header.
+	//   - https://api.slack.com/authentication/verifying-requests-from-slack
+	Slack = &Scheme{
+		SignatureHeader: "X-Slack-Signature",
+		Prefix:          "v0=",
+		Encoding:        Hex,
+		TimestampHeader: "X-Slack-Request-Timestamp",
+		Payload: func(timestamp string, body []byte) []byte {
+			return append([]byte("v0:"+timestamp+":"), body...)
+		},
+		Tolerance: 5 * time.Minute,
+	}
+	// Stripe verifies Stripe-Signature header.
+	//   - https://docs.stripe.com/webhooks#verify-manually
+	Stripe = &Scheme{
+		SignatureHeader: "Stripe-Signature",
+		Encoding:        Hex,
+		Parse:           parseStripeSignature,
+		Payload: func(timestamp string, body []byte) []byte {
+			return append([]byte(timestamp+"."), body...)
+		},
+		Tolerance: 5 * time.Minute,
+	}
+)
+
+// parseStripeSignature parses the header like "t=1492774577,v1=5257a869...,v1=...".
+func parseStripeSignature(header string) (timestamp string, signatures []string) {
+	for _, item := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "t":
+			timestamp = v
+		case "v1":
+			signatures = append(signatures, v)
+		}
+	}
+	return timestamp, signatures
+}
+
+// now is replaced in tests.
+var now = time.Now
+
+// Verify verifies the signature of the request, and returns the body.
+//   - the body of req is split by workers.TeeBody, so it can still be read or forwarded by the next handler.
+//   - if the signature is missing, returns ErrMissingSignature.
+//   - if the timestamp is out of tolerance, returns ErrTimestampOutOfTolerance.
+//   - if no signature matches, returns ErrInvalidSignature.
+func Verify(req *http.Request, key []byte, scheme *Scheme) ([]byte, error) {
+	header := req.Header.Get(scheme.SignatureHeader)
+	if header == "" {
+		return nil, ErrMissingSignature
+	}
+	var timestamp string
+	var signatures []string
+	if scheme.Parse != nil {
+		timestamp, signatures = scheme.Parse(header)
+	} else {
+		timestamp, signatures = req.Header.Get(scheme.TimestampHeader), []string{header}
+	}
+	if len(signatures) == 0 || (scheme.Tolerance > 0 && timestamp == "") {
+		return nil, ErrMissingSignature
+	}
+	if scheme.Tolerance > 0 {
+		sec, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return nil, ErrMissingSignature
+		}
+		if d := now().Sub(time.Unix(sec, 0)); d > scheme.Tolerance || d < -scheme.Tolerance {
+			return nil, ErrTimestampOutOfTolerance
+		}
+	}
+
+	var body []byte
+	if req.Body != nil {
+		var verifyBody io.ReadCloser
+		req.Body, verifyBody = workers.TeeBody(req.Body)
+		b, err := io.ReadAll(verifyBody)
+		verifyBody.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = b
+	}
+	payload := body
+	if scheme.Payload != nil {
+		payload = scheme.Payload(timestamp, body)
+	}
+	want, err := sign(key, payload)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range signatures {
+		got, err := scheme.Encoding.decode(strings.TrimPrefix(s, scheme.Prefix))
+		if err != nil {
+			continue
+		}
+		if secret.Equal(got, want) {
+			return body, nil
+		}
+	}
+	return nil, ErrInvalidSignature
+}
+
+// sign calculates HMAC-SHA256 of the payload by SubtleCrypto.
+func sign(key, payload []byte) ([]byte, error) {
+	alg := map[string]any{"name": "HMAC", "hash": "SHA-256"}
+	cryptoKey, err := webcrypto.ImportRawKey(key, alg, "sign")
+	if err != nil {
+		return nil, err
+	}
+	return webcrypto.Sign("HMAC", cryptoKey, payload)
+}
+
+// Middleware returns a middleware which verifies webhooks signed by the secret stored in the environment variable (secret) named envName.
+//   - if the verification fails, responds with status 401.
+func Middleware(envName string, scheme *Scheme) workers.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			key, ok := secret.Lookup(req.Context(), envName)
+			if !ok {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			if _, err := Verify(req, []byte(key.Reveal()), scheme); err != nil {
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+//go:build js && wasm
+
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func hmacHex(secret, payload string) string {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write([]byte(payload))
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+func TestVerify(t *testing.T) {
+	const secret, body = "secret", `{"event":"push"}`
+	now = func() time.Time { return time.Unix(1700000000, 0) }
+	ts := strconv.FormatInt(now().Unix(), 10)
+	old := strconv.FormatInt(now().Add(-time.Hour).Unix(), 10)
+	tests := map[string]struct {
+		scheme  *Scheme
+		headers map[string]string
+		wantErr error
+	}{
+		"github": {
+			scheme:  GitHub,
+			headers: map[string]string{"X-Hub-Signature-256": "sha256=" + hmacHex(secret, body)},
+		},
+		"github with wrong secret": {
+			scheme:  GitHub,
+			headers: map[string]string{"X-Hub-Signature-256": "sha256=" + hmacHex("wrong", body)},
+			wantErr: ErrInvalidSignature,
+		},
+		"github without signature": {
+			scheme:  GitHub,
+			wantErr: ErrMissingSignature,
+		},
+		"slack": {
+			scheme: Slack,
+			headers: map[string]string{
+				"X-Slack-Signature":         "v0=" + hmacHex(secret, "v0:"+ts+":"+body),
+				"X-Slack-Request-Timestamp": ts,
+			},
+		},
+		"slack with old timestamp": {
+			scheme: Slack,
+			headers: map[string]string{
+				"X-Slack-Signature":         "v0=" + hmacHex(secret, "v0:"+old+":"+body),
+				"X-Slack-Request-Timestamp": old,
+			},
+			wantErr: ErrTimestampOutOfTolerance,
+		},
+		"stripe with rotated secrets": {
+			scheme:  Stripe,
+			headers: map[string]string{"Stripe-Signature": "t=" + ts + ",v1=" + hmacHex("old", ts+"."+body) + ",v1=" + hmacHex(secret, ts+"."+body)},
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			got, err := Verify(req, []byte(secret), tc.scheme)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("want %v, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != body {
+				t.Errorf("want %s, got %s", body, got)
+			}
+			// the body can still be read by the next handler.
+			rest, _ := io.ReadAll(req.Body)
+			if string(rest) != body {
+				t.Errorf("want %s to remain, got %s", body, rest)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package websocket
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jsutil"
+	"github.com/syumai/workers/jsoncodec"
+)
+
+// MaxAttachmentSize is the maximum size of serialized attachments in bytes.
+//   - https://developers.cloudflare.com/durable-objects/api/websockets/#serializeattachment
+const MaxAttachmentSize = 2048
+
+// ErrNoAttachment is returned by DeserializeAttachment when no attachment is set to the connection.
+var ErrNoAttachment = errors.New("websocket: no attachment")
+
+// SerializeAttachment sets v to the connection as the attachment by ws.serializeAttachment.
+//   - attachments survive hibernation of Durable Objects, so per-connection state can be restored after wake-up.
+//   - v is encoded by jsoncodec (so fields are named by json struct tags), and parsed into a plain object,
+//     which is compatible with structured clone.
+//   - returns error if the encoded value exceeds MaxAttachmentSize, or the runtime rejects it.
+func SerializeAttachment[T any](c *Conn, v T) error {
+	data, err := jsoncodec.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("websocket: error encoding attachment: %w", err)
+	}
+	if len(data) > MaxAttachmentSize {
+		return fmt.Errorf("websocket: attachment of %d bytes exceeds %d bytes", len(data), MaxAttachmentSize)
+	}
+	return callWebSocket(func() {
+		obj := jsutil.Global.Get("JSON").Call("parse", string(data))
+		c.ws.Call("serializeAttachment", obj)
+	})
+}
+
+// DeserializeAttachment returns the attachment of the connection set by SerializeAttachment.
+//   - returns ErrNoAttachment if the attachment is not set.
+func DeserializeAttachment[T any](c *Conn) (T, error) {
+	var v T
+	var obj js.Value
+	if err := callWebSocket(func() {
+		obj = c.ws.Call("deserializeAttachment")
+	}); err != nil {
+		return v, err
+	}
+	if obj.IsUndefined() || obj.IsNull() {
+		return v, ErrNoAttachment
+	}
+	data := jsutil.Global.Get("JSON").Call("stringify", obj).String()
+	if err := jsoncodec.Unmarshal([]byte(data), &v); err != nil {
+		return v, fmt.Errorf("websocket: error decoding attachment: %w", err)
+	}
+	return v, nil
+}
+
+// callWebSocket calls fn, converting exceptions of the runtime into error.
+func callWebSocket(fn func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("websocket: error calling attachment API: %v", r)
+		}
+	}()
+	fn()
+	return nil
+}
@@ -0,0 +1,57 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Codec encodes values into messages, and decodes messages into values.
+type Codec struct {
+	// Type is the type of messages sent by the codec.
+	Type      MessageType
+	Marshal   func(v any) ([]byte, error)
+	Unmarshal func(data []byte, v any) error
+}
+
+// JSON is the codec of JSON text messages.
+var JSON = &Codec{
+	Type:      TextMessage,
+	Marshal:   json.Marshal,
+	Unmarshal: json.Unmarshal,
+}
+
+// TypedConn sends and receives values of T encoded by the codec.
+//   - for protobuf, give a codec calling proto.Marshal and proto.Unmarshal with BinaryMessage.
+type TypedConn[T any] struct {
+	*Conn
+	codec *Codec
+}
+
+// NewTypedConn returns a TypedConn using the codec.
+func NewTypedConn[T any](c *Conn, codec *Codec) *TypedConn[T] {
+	return &TypedConn[T]{Conn: c, codec: codec}
+}
+
+// SendValue encodes the value and sends it.
+func (c *TypedConn[T]) SendValue(v T) error {
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("websocket: error encoding message: %w", err)
+	}
+	return c.Send(c.codec.Type, data)
+}
+
+// ReceiveValue receives the next message and decodes it.
+//   - errors of decoding are returned without closing the connection, so the caller can skip invalid messages.
+func (c *TypedConn[T]) ReceiveValue(ctx context.Context) (T, error) {
+	var v T
+	_, data, err := c.Receive(ctx)
+	if err != nil {
+		return v, err
+	}
+	if err := c.codec.Unmarshal(data, &v); err != nil {
+		return v, fmt.Errorf("websocket: error decoding message: %w", err)
+	}
+	return v, nil
+}
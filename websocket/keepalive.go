@@ -0,0 +1,98 @@
+package websocket
+
+import (
+	"sync"
+	"time"
+)
+
+// KeepaliveOptions represents options of Keepalive.
+// Ping / pong frames of the protocol are handled by the runtime and invisible to Workers,
+// so keepalive is done by application messages.
+type KeepaliveOptions struct {
+	// Interval is the interval of ping messages. Defaults to 30 seconds.
+	Interval time.Duration
+	// Timeout closes the connection with CloseGoingAway if no message is received within it. Defaults to 2 * Interval.
+	Timeout time.Duration
+	// Ping is the text message sent to the peer. Defaults to "ping".
+	Ping string
+	// Pong is the text message replied by the peer. Pong messages are not returned by Receive. Defaults to "pong".
+	Pong string
+	// ReplyToPing replies Pong to Ping messages sent by the peer, and they are not returned by Receive.
+	ReplyToPing bool
+}
+
+// Keepalive sends ping messages periodically, and closes the connection if the peer doesn't respond.
+//   - any message received from the peer extends the timeout.
+//   - the returned function stops sending ping messages. Keepalive stops when the connection is closed, too.
+func (c *Conn) Keepalive(opts *KeepaliveOptions) (stop func()) {
+	if opts == nil {
+		opts = &KeepaliveOptions{}
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = 2 * interval
+	}
+	ping, pong := opts.Ping, opts.Pong
+	if ping == "" {
+		ping = "ping"
+	}
+	if pong == "" {
+		pong = "pong"
+	}
+
+	var mu sync.Mutex
+	lastSeen := time.Now()
+	c.mu.Lock()
+	c.onMessage = func(typ MessageType, data []byte) bool {
+		mu.Lock()
+		lastSeen = time.Now()
+		mu.Unlock()
+		if typ != TextMessage {
+			return true
+		}
+		switch string(data) {
+		case pong:
+			return false
+		case ping:
+			if opts.ReplyToPing {
+				_ = c.SendText(pong)
+				return false
+			}
+		}
+		return true
+	}
+	c.mu.Unlock()
+
+	ticker := time.NewTicker(interval)
+	stopCh := make(chan struct{})
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				mu.Lock()
+				idle := time.Since(lastSeen)
+				mu.Unlock()
+				if idle > timeout {
+					_ = c.Close(CloseGoingAway, "keepalive timeout")
+					return
+				}
+				_ = c.SendText(ping)
+			case <-stopCh:
+				return
+			case <-c.done:
+				return
+			}
+		}
+	}()
+	return sync.OnceFunc(func() {
+		close(stopCh)
+		c.mu.Lock()
+		c.onMessage = nil
+		c.mu.Unlock()
+	})
+}
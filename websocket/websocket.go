@@ -0,0 +1,270 @@
+// Package websocket accepts WebSocket connections by WebSocketPair of Cloudflare Workers.
+//   - https://developers.cloudflare.com/workers/runtime-apis/websockets/
+//   - messages are received in the order of arrival, and buffered until Receive is called.
+//   - Keepalive and typed messages by Codec are built on Conn.
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/syumai/workers/internal/js"
+	"github.com/syumai/workers/internal/jshttp"
+	"github.com/syumai/workers/internal/jsutil"
+)
+
+// MessageType is the type of messages.
+type MessageType int
+
+const (
+	// TextMessage is a message of UTF-8 text.
+	TextMessage MessageType = iota + 1
+	// BinaryMessage is a message of bytes.
+	BinaryMessage
+)
+
+// Close codes defined in RFC 6455.
+//   - https://www.rfc-editor.org/rfc/rfc6455#section-7.4.1
+const (
+	CloseNormalClosure    = 1000
+	CloseGoingAway        = 1001
+	CloseProtocolError    = 1002
+	CloseUnsupportedData  = 1003
+	CloseNoStatusReceived = 1005
+	CloseAbnormalClosure  = 1006
+	CloseInvalidPayload   = 1007
+	ClosePolicyViolation  = 1008
+	CloseMessageTooBig    = 1009
+	CloseInternalError    = 1011
+)
+
+// ErrNotWebSocket is returned by Upgrade when the request is not a WebSocket upgrade request.
+var ErrNotWebSocket = errors.New("websocket: not a websocket upgrade request")
+
+// CloseError is returned by Receive after the connection is closed.
+type CloseError struct {
+	Code     int
+	Reason   string
+	WasClean bool
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: closed with code %d: %s", e.Code, e.Reason)
+}
+
+// IsCloseError reports whether err is *CloseError with one of the codes.
+func IsCloseError(err error, codes ...int) bool {
+	var closeErr *CloseError
+	if !errors.As(err, &closeErr) {
+		return false
+	}
+	for _, code := range codes {
+		if closeErr.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+type message struct {
+	typ  MessageType
+	data []byte
+}
+
+// Conn is an accepted WebSocket connection.
+type Conn struct {
+	ws js.Value
+
+	mu       sync.Mutex
+	queue    []message
+	notify   chan struct{}
+	closeErr *CloseError
+	done     chan struct{}
+	// onMessage is called for every received message before it is queued.
+	// If it returns false, the message is dropped. It is used by Keepalive.
+	onMessage func(typ MessageType, data []byte) bool
+
+	listeners []js.Func
+}
+
+// Upgrade accepts the WebSocket upgrade request, and responds with status 101.
+//   - headers set to w before Upgrade (e.g. Sec-WebSocket-Protocol) are sent with the response.
+//   - the handler should keep using the connection, and return after it is closed.
+//   - returns ErrNotWebSocket with status 426 if the request doesn't upgrade to WebSocket.
+func Upgrade(w http.ResponseWriter, req *http.Request) (*Conn, error) {
+	if !strings.EqualFold(req.Header.Get("Upgrade"), "websocket") {
+		w.Header().Set("Upgrade", "websocket")
+		http.Error(w, http.StatusText(http.StatusUpgradeRequired), http.StatusUpgradeRequired)
+		return nil, ErrNotWebSocket
+	}
+	rw, ok := jshttp.ResponseWriterFromContext(req.Context())
+	if !ok {
+		return nil, errors.New("websocket: the request is not handled by workers.Serve")
+	}
+	pair := jsutil.Global.Get("WebSocketPair").New()
+	client, server := pair.Index(0), pair.Index(1)
+	conn := newConn(server)
+	server.Call("accept")
+	rw.UpgradeWebSocket(client)
+	return conn, nil
+}
+
+// newConn returns a Conn listening events of the WebSocket. The WebSocket must be accepted after newConn.
+func newConn(ws js.Value) *Conn {
+	c := &Conn{
+		ws:     ws,
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	c.listen("message", func(event js.Value) {
+		data := event.Get("data")
+		if data.Type() == js.TypeString {
+			c.push(TextMessage, []byte(data.String()))
+			return
+		}
+		c.push(BinaryMessage, jsutil.BufferSourceToBytes(data))
+	})
+	c.listen("close", func(event js.Value) {
+		code := event.Get("code").Int()
+		c.finish(&CloseError{
+			Code:     code,
+			Reason:   event.Get("reason").String(),
+			WasClean: event.Get("wasClean").Bool(),
+		})
+		// replies to the close frame of the peer.
+		_ = c.closeWebSocket(code, "")
+	})
+	c.listen("error", func(event js.Value) {
+		c.finish(&CloseError{Code: CloseAbnormalClosure, Reason: jsutil.MaybeString(event.Get("message"))})
+	})
+	return c
+}
+
+func (c *Conn) listen(typ string, fn func(event js.Value)) {
+	f := js.FuncOf(func(_ js.Value, args []js.Value) any {
+		fn(args[0])
+		return js.Undefined()
+	})
+	c.listeners = append(c.listeners, f)
+	c.ws.Call("addEventListener", typ, f)
+}
+
+func (c *Conn) push(typ MessageType, data []byte) {
+	c.mu.Lock()
+	onMessage := c.onMessage
+	c.mu.Unlock()
+	if onMessage != nil && !onMessage(typ, data) {
+		return
+	}
+	c.mu.Lock()
+	c.queue = append(c.queue, message{typ: typ, data: data})
+	c.mu.Unlock()
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+}
+
+// finish records the cause of the close, and releases listeners.
+func (c *Conn) finish(err *CloseError) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closeErr != nil {
+		return
+	}
+	c.closeErr = err
+	close(c.done)
+	for _, f := range c.listeners {
+		// listeners are released after the current event is dispatched.
+		defer f.Release()
+	}
+	c.listeners = nil
+}
+
+// Done returns a channel closed when the connection is closed.
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Receive returns the next message.
+//   - messages received before the close are returned before *CloseError.
+//   - returns ctx.Err() if ctx is done.
+func (c *Conn) Receive(ctx context.Context) (MessageType, []byte, error) {
+	for {
+		c.mu.Lock()
+		if len(c.queue) > 0 {
+			m := c.queue[0]
+			c.queue[0] = message{}
+			c.queue = c.queue[1:]
+			c.mu.Unlock()
+			return m.typ, m.data, nil
+		}
+		closeErr := c.closeErr
+		c.mu.Unlock()
+		if closeErr != nil {
+			return 0, nil, closeErr
+		}
+		select {
+		case <-c.notify:
+		case <-c.done:
+		case <-ctx.Done():
+			return 0, nil, ctx.Err()
+		}
+	}
+}
+
+// Send sends the message.
+func (c *Conn) Send(typ MessageType, data []byte) error {
+	c.mu.Lock()
+	closeErr := c.closeErr
+	c.mu.Unlock()
+	if closeErr != nil {
+		return closeErr
+	}
+	var err error
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("websocket: error sending message: %v", r)
+			}
+		}()
+		if typ == TextMessage {
+			c.ws.Call("send", string(data))
+			return
+		}
+		c.ws.Call("send", jsutil.NewUint8ArrayFromBytes(data))
+	}()
+	return err
+}
+
+// SendText sends the text message.
+func (c *Conn) SendText(s string) error {
+	return c.Send(TextMessage, []byte(s))
+}
+
+// Close closes the connection with the code and the reason.
+// Receive returns *CloseError with the code after Close.
+func (c *Conn) Close(code int, reason string) error {
+	err := c.closeWebSocket(code, reason)
+	c.finish(&CloseError{Code: code, Reason: reason, WasClean: err == nil})
+	return err
+}
+
+func (c *Conn) closeWebSocket(code int, reason string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("websocket: error closing: %v", r)
+		}
+	}()
+	// 1005 and 1006 are reserved, and can't be sent.
+	if code == CloseNoStatusReceived || code == CloseAbnormalClosure {
+		c.ws.Call("close")
+		return nil
+	}
+	c.ws.Call("close", code, reason)
+	return nil
+}
@@ -0,0 +1,129 @@
+//go:build js && wasm
+
+package websocket
+
+import (
+	"context"
+	"testing"
+
+	"github.com/syumai/workers/internal/js"
+)
+
+// newFakeWebSocket returns an EventTarget recording sent messages and the close call, like the server side of WebSocketPair.
+func newFakeWebSocket() js.Value {
+	return js.Global().Get("Function").New(`
+		class FakeWebSocket extends EventTarget {
+			sent = [];
+			closed = null;
+			send(data) { this.sent.push(typeof data === "string" ? data : "binary:" + data.length); }
+			close(code, reason) { this.closed = [code ?? 0, reason ?? ""]; }
+			accept() {}
+			attachment = null;
+			serializeAttachment(v) { this.attachment = structuredClone(v); }
+			deserializeAttachment() { return structuredClone(this.attachment); }
+		}
+		return new FakeWebSocket();
+	`).Invoke()
+}
+
+func dispatchMessage(ws js.Value, data string) {
+	init := js.Global().Get("Object").New()
+	init.Set("data", data)
+	ws.Call("dispatchEvent", js.Global().Get("MessageEvent").New("message", init))
+}
+
+func dispatchClose(ws js.Value, code int, reason string) {
+	event := js.Global().Get("Event").New("close")
+	event.Set("code", code)
+	event.Set("reason", reason)
+	event.Set("wasClean", true)
+	ws.Call("dispatchEvent", event)
+}
+
+type chatMessage struct {
+	User string `json:"user"`
+	Text string `json:"text"`
+}
+
+func TestTypedConn(t *testing.T) {
+	ws := newFakeWebSocket()
+	conn := NewTypedConn[chatMessage](newConn(ws), JSON)
+	ctx := context.Background()
+
+	dispatchMessage(ws, `{"user":"a","text":"hi"}`)
+	dispatchClose(ws, CloseNormalClosure, "bye")
+
+	got, err := conn.ReceiveValue(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (chatMessage{User: "a", Text: "hi"}); got != want {
+		t.Errorf("want %v, got %v", want, got)
+	}
+	if _, err := conn.ReceiveValue(ctx); !IsCloseError(err, CloseNormalClosure) {
+		t.Errorf("want close error, got %v", err)
+	}
+	if err := conn.SendValue(chatMessage{User: "b"}); !IsCloseError(err, CloseNormalClosure) {
+		t.Errorf("want close error on send after close, got %v", err)
+	}
+	if closed := ws.Get("closed"); closed.Index(0).Int() != CloseNormalClosure {
+		t.Errorf("want close frame to be replied, got %v", closed)
+	}
+}
+
+func TestConn_Keepalive(t *testing.T) {
+	ws := newFakeWebSocket()
+	conn := newConn(ws)
+	stop := conn.Keepalive(&KeepaliveOptions{ReplyToPing: true})
+	defer stop()
+
+	dispatchMessage(ws, "ping")
+	dispatchMessage(ws, "pong")
+	dispatchMessage(ws, "hello")
+
+	_, data, err := conn.Receive(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello" {
+		t.Errorf("want ping and pong to be dropped, got %q", data)
+	}
+	sent := ws.Get("sent")
+	if sent.Length() != 1 || sent.Index(0).String() != "pong" {
+		t.Errorf("want pong to be replied, got %v", js.Global().Get("JSON").Call("stringify", sent))
+	}
+}
+
+type connState struct {
+	User  string   `json:"user"`
+	Rooms []string `json:"rooms"`
+	Seq   int      `json:"seq"`
+}
+
+func TestAttachment(t *testing.T) {
+	ws := newFakeWebSocket()
+	conn := newConn(ws)
+
+	if _, err := DeserializeAttachment[connState](conn); err != ErrNoAttachment {
+		t.Fatalf("want ErrNoAttachment, got %v", err)
+	}
+	want := connState{User: "a", Rooms: []string{"lobby", "games"}, Seq: 3}
+	if err := SerializeAttachment(conn, want); err != nil {
+		t.Fatal(err)
+	}
+	if user := ws.Get("attachment").Get("user").String(); user != "a" {
+		t.Errorf("want attachment to be a plain object, got user %q", user)
+	}
+	got, err := DeserializeAttachment[connState](conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.User != want.User || got.Seq != want.Seq || len(got.Rooms) != 2 || got.Rooms[1] != "games" {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	large := connState{User: string(make([]byte, MaxAttachmentSize))}
+	if err := SerializeAttachment(conn, large); err == nil {
+		t.Error("want error for attachment exceeding MaxAttachmentSize")
+	}
+}
@@ -0,0 +1,32 @@
+package workerstest
+
+import (
+	"io"
+
+	"github.com/syumai/workers/cloudflare/ai"
+)
+
+// AI is a fake of ai.Binding.
+type AI struct {
+	// RunFunc overrides Run if set. By default, ErrNotImplemented is returned.
+	RunFunc func(model string, input any) ([]byte, error)
+	// RunStreamFunc overrides RunStream if set. By default, ErrNotImplemented is returned.
+	//   - the stream must be Server-Sent Events, e.g. "data: {\"response\":\"Hello\"}\n\ndata: [DONE]\n\n".
+	RunStreamFunc func(model string, input any) (io.ReadCloser, error)
+}
+
+var _ ai.Binding = (*AI)(nil)
+
+func (a *AI) Run(model string, input any) ([]byte, error) {
+	if a.RunFunc == nil {
+		return nil, ErrNotImplemented
+	}
+	return a.RunFunc(model, input)
+}
+
+func (a *AI) RunStream(model string, input any) (io.ReadCloser, error) {
+	if a.RunStreamFunc == nil {
+		return nil, ErrNotImplemented
+	}
+	return a.RunStreamFunc(model, input)
+}
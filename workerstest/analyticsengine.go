@@ -0,0 +1,28 @@
+package workerstest
+
+import (
+	"sync"
+
+	"github.com/syumai/workers/cloudflare"
+)
+
+// AnalyticsEngineDataset is a fake of cloudflare.AnalyticsEngineDatasetBinding recording written data points.
+type AnalyticsEngineDataset struct {
+	mu     sync.Mutex
+	points []*cloudflare.AnalyticsEngineDataPoint
+}
+
+var _ cloudflare.AnalyticsEngineDatasetBinding = (*AnalyticsEngineDataset)(nil)
+
+func (d *AnalyticsEngineDataset) WriteDataPoint(p *cloudflare.AnalyticsEngineDataPoint) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.points = append(d.points, p)
+}
+
+// DataPoints returns the data points written so far.
+func (d *AnalyticsEngineDataset) DataPoints() []*cloudflare.AnalyticsEngineDataPoint {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]*cloudflare.AnalyticsEngineDataPoint(nil), d.points...)
+}
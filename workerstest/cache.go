@@ -0,0 +1,176 @@
+package workerstest
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/syumai/workers"
+	"github.com/syumai/workers/cloudflare/cache"
+)
+
+// Cache is an in-memory fake of cache.Store.
+// The zero value is an empty cache ready to use.
+//   - methods whose function field is set are overridden by the function.
+//   - as well as the Cache API, responses are expired by Cache-Control (s-maxage or max-age) or Expires header,
+//     and responses with Cache-Control private, no-store or no-cache directive are not stored.
+//   - Match responds to requests with Range header by 206 Partial Content.
+type Cache struct {
+	PutFunc    func(req *http.Request, res *http.Response) error
+	MatchFunc  func(req *http.Request, opts *cache.MatchOptions) (*http.Response, error)
+	DeleteFunc func(req *http.Request, opts *cache.MatchOptions) (bool, error)
+	// Now returns the current time used to expire responses. Defaults to time.Now.
+	Now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+var _ cache.Store = (*Cache)(nil)
+
+type cacheEntry struct {
+	status int
+	header http.Header
+	body   []byte
+	// expires is zero if the response doesn't expire.
+	expires time.Time
+}
+
+func (c *Cache) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+// Put stores the response for the request into the cache.
+//   - as well as the Cache API, returns error for non-GET requests, 206 responses and responses with `Vary: *`.
+func (c *Cache) Put(req *http.Request, res *http.Response) error {
+	if c.PutFunc != nil {
+		return c.PutFunc(req, res)
+	}
+	defer res.Body.Close()
+	if req.Method != http.MethodGet {
+		return errors.New("workerstest: Cache.Put only accepts GET requests")
+	}
+	if res.StatusCode == http.StatusPartialContent {
+		return errors.New("workerstest: Cache.Put doesn't accept 206 responses")
+	}
+	if res.Header.Get("Vary") == "*" {
+		return errors.New("workerstest: Cache.Put doesn't accept responses with Vary: *")
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	expires, ok := c.expires(res.Header)
+	if !ok {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = map[string]*cacheEntry{}
+	}
+	c.entries[req.URL.String()] = &cacheEntry{
+		status:  res.StatusCode,
+		header:  res.Header.Clone(),
+		body:    body,
+		expires: expires,
+	}
+	return nil
+}
+
+// expires returns the expiration time of the response, and reports whether the response is cacheable.
+func (c *Cache) expires(header http.Header) (time.Time, bool) {
+	var maxAge, sMaxAge = -1, -1
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "private", "no-store", "no-cache":
+			return time.Time{}, false
+		case "max-age":
+			maxAge, _ = strconv.Atoi(value)
+		case "s-maxage":
+			sMaxAge, _ = strconv.Atoi(value)
+		}
+	}
+	switch {
+	case sMaxAge >= 0:
+		maxAge = sMaxAge
+	case maxAge < 0:
+		if t, err := http.ParseTime(header.Get("Expires")); err == nil {
+			return t, true
+		}
+		return time.Time{}, true
+	}
+	return c.now().Add(time.Duration(maxAge) * time.Second), true
+}
+
+// Match returns the cached response for the request.
+//   - if the response is not cached or expired, returns cache.ErrNotFound.
+func (c *Cache) Match(req *http.Request, opts *cache.MatchOptions) (*http.Response, error) {
+	if c.MatchFunc != nil {
+		return c.MatchFunc(req, opts)
+	}
+	if req.Method != http.MethodGet && (opts == nil || !opts.IgnoreMethod) {
+		return nil, cache.ErrNotFound
+	}
+	c.mu.Lock()
+	key := req.URL.String()
+	e, ok := c.entries[key]
+	if ok && !e.expires.IsZero() && !c.now().Before(e.expires) {
+		delete(c.entries, key)
+		ok = false
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, cache.ErrNotFound
+	}
+	res := &http.Response{
+		Status:        strconv.Itoa(e.status) + " " + http.StatusText(e.status),
+		StatusCode:    e.status,
+		Header:        e.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+	if rangeHeader := req.Header.Get("Range"); rangeHeader != "" && e.status == http.StatusOK {
+		rng, err := workers.ParseRange(rangeHeader, int64(len(e.body)))
+		switch {
+		case errors.Is(err, workers.ErrUnsatisfiableRange):
+			res.StatusCode = http.StatusRequestedRangeNotSatisfiable
+			res.Header.Set("Content-Range", "bytes */"+strconv.Itoa(len(e.body)))
+			res.Body, res.ContentLength = http.NoBody, 0
+		case err == nil && rng != nil:
+			res.StatusCode = http.StatusPartialContent
+			res.Header.Set("Content-Range", rng.ContentRange(int64(len(e.body))))
+			part := e.body[rng.Start : rng.Start+rng.Length]
+			res.Body, res.ContentLength = io.NopCloser(bytes.NewReader(part)), rng.Length
+		}
+		res.Status = strconv.Itoa(res.StatusCode) + " " + http.StatusText(res.StatusCode)
+	}
+	return res, nil
+}
+
+// Delete deletes the cached response for the request.
+//   - returns true if the response was deleted.
+func (c *Cache) Delete(req *http.Request, opts *cache.MatchOptions) (bool, error) {
+	if c.DeleteFunc != nil {
+		return c.DeleteFunc(req, opts)
+	}
+	if req.Method != http.MethodGet && (opts == nil || !opts.IgnoreMethod) {
+		return false, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := req.URL.String()
+	_, ok := c.entries[key]
+	delete(c.entries, key)
+	return ok, nil
+}
@@ -0,0 +1,97 @@
+package workerstest
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syumai/workers/cloudflare/cache"
+)
+
+func TestCache(t *testing.T) {
+	tests := map[string]struct {
+		cacheControl string
+		rangeHeader  string
+		elapsed      time.Duration
+		wantStatus   int
+		wantBody     string
+		wantNotFound bool
+	}{
+		"fresh": {
+			cacheControl: "max-age=60",
+			wantStatus:   http.StatusOK,
+			wantBody:     "hello world",
+		},
+		"expired": {
+			cacheControl: "max-age=60",
+			elapsed:      time.Minute,
+			wantNotFound: true,
+		},
+		"s-maxage precedes max-age": {
+			cacheControl: "max-age=0, s-maxage=120",
+			elapsed:      time.Minute,
+			wantStatus:   http.StatusOK,
+			wantBody:     "hello world",
+		},
+		"no-store": {
+			cacheControl: "no-store",
+			wantNotFound: true,
+		},
+		"range": {
+			cacheControl: "max-age=60",
+			rangeHeader:  "bytes=6-",
+			wantStatus:   http.StatusPartialContent,
+			wantBody:     "world",
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			now := time.Unix(1000, 0)
+			c := &Cache{Now: func() time.Time { return now }}
+			rec := httptest.NewRecorder()
+			rec.Header().Set("Cache-Control", tc.cacheControl)
+			io.WriteString(rec, "hello world")
+			req := httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+			if err := c.Put(req, rec.Result()); err != nil {
+				t.Fatal(err)
+			}
+			now = now.Add(tc.elapsed)
+			req = httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+			if tc.rangeHeader != "" {
+				req.Header.Set("Range", tc.rangeHeader)
+			}
+			res, err := c.Match(req, nil)
+			if tc.wantNotFound {
+				if !errors.Is(err, cache.ErrNotFound) {
+					t.Errorf("want ErrNotFound, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, _ := io.ReadAll(res.Body)
+			if res.StatusCode != tc.wantStatus {
+				t.Errorf("want status %d, got %d", tc.wantStatus, res.StatusCode)
+			}
+			if string(b) != tc.wantBody {
+				t.Errorf("want body %q, got %q", tc.wantBody, string(b))
+			}
+		})
+	}
+}
+
+func TestCache_PutRejectsNonGET(t *testing.T) {
+	c := &Cache{}
+	req := httptest.NewRequest(http.MethodPost, "https://example.com/", strings.NewReader(""))
+	if err := c.Put(req, httptest.NewRecorder().Result()); err == nil {
+		t.Error("want error, got nil")
+	}
+}
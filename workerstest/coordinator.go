@@ -0,0 +1,15 @@
+package workerstest
+
+import (
+	"net/http"
+
+	"github.com/syumai/workers/cloudflare/coordination"
+)
+
+// NewCoordinatorObject returns an in-memory fake of an object of the Coordinator class of the coordination package.
+// It serves the same implementation as the class on DurableObjectStorage, and can be used as DurableObjectNamespace.NewObject:
+//
+//	ns := &workerstest.DurableObjectNamespace{NewObject: workerstest.NewCoordinatorObject}
+func NewCoordinatorObject(name string) http.Handler {
+	return coordination.NewObjectHandler(&DurableObjectStorage{})
+}
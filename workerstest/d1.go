@@ -0,0 +1,26 @@
+//go:build !(js && wasm)
+
+package workerstest
+
+import (
+	"database/sql"
+
+	_ "modernc.org/sqlite"
+)
+
+// NewD1Database returns *sql.DB backed by an in-memory SQLite database, as a fake of D1.
+//   - D1 is based on SQLite, so queries written for D1 can be run as is.
+//   - foreign key constraints are enforced as well as D1.
+//   - Each call returns an independent database, which is discarded when the *sql.DB is closed.
+//   - This function is not available on js/wasm since the SQLite driver doesn't support it.
+func NewD1Database() (*sql.DB, error) {
+	db, err := sql.Open("sqlite", "file::memory:?_pragma=foreign_keys(1)")
+	if err != nil {
+		return nil, err
+	}
+	// an in-memory database is bound to the connection, so only one connection is used.
+	db.SetMaxOpenConns(1)
+	db.SetConnMaxLifetime(0)
+	db.SetConnMaxIdleTime(0)
+	return db, nil
+}
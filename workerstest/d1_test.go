@@ -0,0 +1,31 @@
+//go:build !(js && wasm)
+
+package workerstest
+
+import "testing"
+
+func TestNewD1Database(t *testing.T) {
+	db, err := NewD1Database()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE posts (id INTEGER PRIMARY KEY, title TEXT NOT NULL)`); err != nil {
+		t.Fatal(err)
+	}
+	res, err := db.Exec(`INSERT INTO posts (title) VALUES (?)`, "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var title string
+	if err := db.QueryRow(`SELECT title FROM posts WHERE id = ?`, id).Scan(&title); err != nil {
+		t.Fatal(err)
+	}
+	if title != "hello" {
+		t.Errorf("want hello, got %s", title)
+	}
+}
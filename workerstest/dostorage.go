@@ -0,0 +1,165 @@
+package workerstest
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/jsoncodec"
+)
+
+// DurableObjectStorage is an in-memory fake of cloudflare.DurableObjectStorageBinding.
+// The zero value is an empty storage ready to use.
+//   - as well as cloudflare.DurableObjectStorage, values are stored as JSON encoded by the codec of the jsoncodec package.
+//   - the alarm is only recorded. Call the alarm handler directly to test it.
+type DurableObjectStorage struct {
+	mu      sync.Mutex
+	entries map[string]json.RawMessage
+	alarm   time.Time
+}
+
+var _ cloudflare.DurableObjectStorageBinding = (*DurableObjectStorage)(nil)
+
+// Get gets the value of the key, and decodes it into v.
+func (s *DurableObjectStorage) Get(key string, v any) (bool, error) {
+	s.mu.Lock()
+	value, ok := s.entries[key]
+	s.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+	if err := jsoncodec.Unmarshal(value, v); err != nil {
+		return false, fmt.Errorf("error decoding value of %s: %w", key, err)
+	}
+	return true, nil
+}
+
+// GetMulti gets the values of the keys as JSON. Keys which don't exist are not included in the result.
+func (s *DurableObjectStorage) GetMulti(keys []string) (map[string]json.RawMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values := make(map[string]json.RawMessage, len(keys))
+	for _, key := range keys {
+		if value, ok := s.entries[key]; ok {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
+
+// Put encodes v, and puts it to the key.
+func (s *DurableObjectStorage) Put(key string, v any) error {
+	return s.PutMulti(map[string]any{key: v})
+}
+
+// PutMulti puts the entries. If one of values can't be encoded, nothing is written.
+func (s *DurableObjectStorage) PutMulti(entries map[string]any) error {
+	values := make(map[string]json.RawMessage, len(entries))
+	for key, v := range entries {
+		b, err := jsoncodec.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("error encoding value of %s: %w", key, err)
+		}
+		values[key] = b
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.entries == nil {
+		s.entries = map[string]json.RawMessage{}
+	}
+	for key, value := range values {
+		s.entries[key] = value
+	}
+	return nil
+}
+
+// Delete deletes the key, and reports whether it existed.
+func (s *DurableObjectStorage) Delete(key string) (bool, error) {
+	n, err := s.DeleteMulti([]string{key})
+	return n == 1, err
+}
+
+// DeleteMulti deletes the keys, and returns the number of keys which existed.
+func (s *DurableObjectStorage) DeleteMulti(keys []string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var deleted int
+	for _, key := range keys {
+		if _, ok := s.entries[key]; ok {
+			delete(s.entries, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// DeleteAll deletes all keys. The alarm is not deleted.
+func (s *DurableObjectStorage) DeleteAll() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = nil
+	return nil
+}
+
+// List lists entries in the order of keys as well as the runtime.
+//   - if both Start and StartAfter are set, returns error.
+func (s *DurableObjectStorage) List(opts *cloudflare.DurableObjectStorageListOptions) ([]*cloudflare.DurableObjectStorageEntry, error) {
+	var o cloudflare.DurableObjectStorageListOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Start != "" && o.StartAfter != "" {
+		return nil, fmt.Errorf("start and startAfter can't be used together")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.entries))
+	for key := range s.entries {
+		if !strings.HasPrefix(key, o.Prefix) {
+			continue
+		}
+		if (o.Start != "" && key < o.Start) || (o.StartAfter != "" && key <= o.StartAfter) || (o.End != "" && key >= o.End) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if o.Reverse {
+		sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+	}
+	if o.Limit > 0 && len(keys) > o.Limit {
+		keys = keys[:o.Limit]
+	}
+	entries := make([]*cloudflare.DurableObjectStorageEntry, len(keys))
+	for i, key := range keys {
+		entries[i] = &cloudflare.DurableObjectStorageEntry{Key: key, Value: s.entries[key]}
+	}
+	return entries, nil
+}
+
+// GetAlarm returns the time of the alarm. If no alarm is set, returns false.
+func (s *DurableObjectStorage) GetAlarm() (time.Time, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.alarm, !s.alarm.IsZero(), nil
+}
+
+// SetAlarm sets the alarm to t, replacing the previous one.
+func (s *DurableObjectStorage) SetAlarm(t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.alarm = t
+	return nil
+}
+
+// DeleteAlarm deletes the alarm if it is set.
+func (s *DurableObjectStorage) DeleteAlarm() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.alarm = time.Time{}
+	return nil
+}
@@ -0,0 +1,100 @@
+package workerstest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/syumai/workers/cloudflare"
+)
+
+// DurableObjectNamespace is a fake of cloudflare.DurableObjectNamespaceBinding.
+// Requests sent to the stubs are served by http.Handler created for each object.
+//   - as well as the runtime, ids are 64 hex digits. IdFromName returns the same id for the same name.
+//   - location hints are ignored.
+type DurableObjectNamespace struct {
+	// NewObject returns the handler of the durable object.
+	// name is the name given to IdFromName, or the string representation of the id for unique ids.
+	// It is called once per object, so the handler can hold the state of the object.
+	NewObject func(name string) http.Handler
+
+	mu sync.Mutex
+	// names maps string representations of ids to names of objects.
+	names   map[string]string
+	objects map[string]http.Handler
+}
+
+var _ cloudflare.DurableObjectNamespaceBinding = (*DurableObjectNamespace)(nil)
+
+// register registers the id of the object name. ns.mu must be held.
+func (ns *DurableObjectNamespace) register(id, name string) *cloudflare.DurableObjectId {
+	if ns.names == nil {
+		ns.names = map[string]string{}
+	}
+	ns.names[id] = name
+	return cloudflare.NewDurableObjectIdString(id)
+}
+
+// IdFromName returns a `DurableObjectId` for the given `name`.
+// The same ID is returned for the same name.
+func (ns *DurableObjectNamespace) IdFromName(name string) *cloudflare.DurableObjectId {
+	sum := sha256.Sum256([]byte(name))
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	return ns.register(hex.EncodeToString(sum[:]), name)
+}
+
+// IdFromString parses the string representation of a `DurableObjectId`.
+//   - if the id was not created by the namespace, returns error.
+func (ns *DurableObjectNamespace) IdFromString(id string) (*cloudflare.DurableObjectId, error) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	if _, ok := ns.names[id]; !ok {
+		return nil, errors.New("invalid durable object id: " + id)
+	}
+	return cloudflare.NewDurableObjectIdString(id), nil
+}
+
+// NewUniqueId returns a new random `DurableObjectId`.
+//   - the jurisdiction is ignored.
+func (ns *DurableObjectNamespace) NewUniqueId(opts *cloudflare.DurableObjectNewUniqueIdOptions) *cloudflare.DurableObjectId {
+	id := randomID() + randomID()
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	return ns.register(id, id)
+}
+
+// Get obtains the durable object stub for `id`.
+//   - if the id was not created by the namespace, returns error.
+func (ns *DurableObjectNamespace) Get(id *cloudflare.DurableObjectId) (*cloudflare.DurableObjectStub, error) {
+	return ns.GetWithOptions(id, nil)
+}
+
+// GetWithOptions obtains the durable object stub for `id`. Options are ignored.
+func (ns *DurableObjectNamespace) GetWithOptions(id *cloudflare.DurableObjectId, opts *cloudflare.DurableObjectGetOptions) (*cloudflare.DurableObjectStub, error) {
+	if id == nil {
+		return nil, errors.New("invalid UniqueGlobalId")
+	}
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	name, ok := ns.names[id.String()]
+	if !ok {
+		return nil, errors.New("invalid UniqueGlobalId")
+	}
+	if ns.NewObject == nil {
+		return nil, ErrNotImplemented
+	}
+	obj, ok := ns.objects[name]
+	if !ok {
+		if ns.objects == nil {
+			ns.objects = map[string]http.Handler{}
+		}
+		obj = ns.NewObject(name)
+		ns.objects[name] = obj
+	}
+	return cloudflare.NewDurableObjectStubFunc(func(req *http.Request) (*http.Response, error) {
+		return serveHTTP(obj, req), nil
+	}), nil
+}
@@ -0,0 +1,70 @@
+package workerstest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/syumai/workers/cloudflare"
+)
+
+func TestDurableObjectNamespace(t *testing.T) {
+	ns := &DurableObjectNamespace{
+		NewObject: func(name string) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				io.WriteString(w, name)
+			})
+		},
+	}
+	tests := map[string]struct {
+		newID    func() *cloudflare.DurableObjectId
+		wantBody string
+	}{
+		"id from name": {
+			newID:    func() *cloudflare.DurableObjectId { return ns.IdFromName("a") },
+			wantBody: "a",
+		},
+		"unique id": {
+			newID: func() *cloudflare.DurableObjectId {
+				return ns.NewUniqueId(&cloudflare.DurableObjectNewUniqueIdOptions{Jurisdiction: cloudflare.JurisdictionEU})
+			},
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			id := tc.newID()
+			if len(id.String()) != 64 {
+				t.Fatalf("want 64 hex digits, got %q", id.String())
+			}
+			parsed, err := ns.IdFromString(id.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !parsed.Equals(id) {
+				t.Fatalf("parsed id %s doesn't equal to %s", parsed, id)
+			}
+			stub, err := ns.GetWithOptions(parsed, &cloudflare.DurableObjectGetOptions{LocationHint: cloudflare.LocationHintWesternEurope})
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := stub.Fetch(httptest.NewRequest(http.MethodGet, "/", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(res.Body)
+			want := tc.wantBody
+			if want == "" {
+				want = id.String()
+			}
+			if string(body) != want {
+				t.Errorf("want body %q, got %q", want, body)
+			}
+		})
+	}
+	if _, err := ns.IdFromString("unknown"); err == nil {
+		t.Error("want error for unknown id")
+	}
+}
@@ -0,0 +1,37 @@
+package workerstest
+
+import (
+	"net/http"
+
+	"github.com/syumai/workers/cloudflare/fetch"
+)
+
+// Fetcher is a fake of fetch.Fetcher.
+// It can also be used as http.Client's Transport.
+type Fetcher struct {
+	// DoFunc overrides Do if set.
+	DoFunc func(req *http.Request) (*http.Response, error)
+	// Handler serves requests if DoFunc is nil.
+	// If both are nil, Do returns ErrNotImplemented.
+	Handler http.Handler
+}
+
+var (
+	_ fetch.Fetcher     = (*Fetcher)(nil)
+	_ http.RoundTripper = (*Fetcher)(nil)
+)
+
+func (f *Fetcher) Do(req *http.Request) (*http.Response, error) {
+	switch {
+	case f.DoFunc != nil:
+		return f.DoFunc(req)
+	case f.Handler != nil:
+		return serveHTTP(f.Handler, req), nil
+	}
+	return nil, ErrNotImplemented
+}
+
+// RoundTrip implements http.RoundTripper.
+func (f *Fetcher) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f.Do(req)
+}
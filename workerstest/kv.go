@@ -0,0 +1,235 @@
+package workerstest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/jsoncodec"
+)
+
+// KVNamespace is an in-memory fake of cloudflare.KVNamespaceBinding.
+// The zero value is an empty namespace ready to use.
+type KVNamespace struct {
+	// GetStringFunc overrides GetString if set.
+	GetStringFunc func(key string, opts *cloudflare.KVNamespaceGetOptions) (string, error)
+	// GetReaderFunc overrides GetReader if set.
+	GetReaderFunc func(key string, opts *cloudflare.KVNamespaceGetOptions) (io.Reader, error)
+	// GetWithMetadataFunc overrides GetWithMetadata if set.
+	GetWithMetadataFunc func(key string, opts *cloudflare.KVNamespaceGetOptions) (*cloudflare.KVNamespaceValueWithMetadata, error)
+	// ListFunc overrides List if set.
+	ListFunc func(opts *cloudflare.KVNamespaceListOptions) (*cloudflare.KVNamespaceListResult, error)
+	// PutStringFunc overrides PutString if set.
+	PutStringFunc func(key string, value string, opts *cloudflare.KVNamespacePutOptions) error
+	// PutReaderFunc overrides PutReader if set.
+	PutReaderFunc func(key string, value io.Reader, opts *cloudflare.KVNamespacePutOptions) error
+	// DeleteFunc overrides Delete if set.
+	DeleteFunc func(key string) error
+	// UpdateFunc overrides Update if set.
+	UpdateFunc func(key string, fn func(old []byte) ([]byte, error), opts *cloudflare.KVNamespaceUpdateOptions) error
+	// Now returns the current time used to expire keys. Defaults to time.Now.
+	Now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*kvEntry
+}
+
+var _ cloudflare.KVNamespaceBinding = (*KVNamespace)(nil)
+
+type kvEntry struct {
+	value []byte
+	// expiration is seconds since the Unix epoch. The value `0` means no expiration.
+	expiration int
+	metadata   json.RawMessage
+}
+
+const (
+	// kvListLimit is the default and maximum number of keys returned by List.
+	kvListLimit = 1000
+	// kvMaxMetadataSize is the maximum size of JSON-encoded metadata.
+	kvMaxMetadataSize = 1024
+)
+
+func (kv *KVNamespace) now() time.Time {
+	if kv.Now != nil {
+		return kv.Now()
+	}
+	return time.Now()
+}
+
+// get returns the entry of the key, deleting it if expired. kv.mu must be held.
+func (kv *KVNamespace) get(key string) (*kvEntry, bool) {
+	e, ok := kv.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if e.expiration != 0 && int64(e.expiration) <= kv.now().Unix() {
+		delete(kv.entries, key)
+		return nil, false
+	}
+	return e, true
+}
+
+// GetString gets string value by the specified key.
+//   - as well as cloudflare.KVNamespace, returns "<null>" if the key doesn't exist.
+func (kv *KVNamespace) GetString(key string, opts *cloudflare.KVNamespaceGetOptions) (string, error) {
+	if kv.GetStringFunc != nil {
+		return kv.GetStringFunc(key, opts)
+	}
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	e, ok := kv.get(key)
+	if !ok {
+		return "<null>", nil
+	}
+	return string(e.value), nil
+}
+
+// GetReader gets stream value by the specified key.
+//   - if the key doesn't exist, returns error.
+func (kv *KVNamespace) GetReader(key string, opts *cloudflare.KVNamespaceGetOptions) (io.Reader, error) {
+	if kv.GetReaderFunc != nil {
+		return kv.GetReaderFunc(key, opts)
+	}
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	e, ok := kv.get(key)
+	if !ok {
+		return nil, fmt.Errorf("%s is not found", key)
+	}
+	return bytes.NewReader(e.value), nil
+}
+
+// GetWithMetadata gets the value and its metadata by the specified key.
+//   - if the key doesn't exist, returns nil.
+func (kv *KVNamespace) GetWithMetadata(key string, opts *cloudflare.KVNamespaceGetOptions) (*cloudflare.KVNamespaceValueWithMetadata, error) {
+	if kv.GetWithMetadataFunc != nil {
+		return kv.GetWithMetadataFunc(key, opts)
+	}
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	e, ok := kv.get(key)
+	if !ok {
+		return nil, nil
+	}
+	return &cloudflare.KVNamespaceValueWithMetadata{
+		Value:    bytes.Clone(e.value),
+		Metadata: bytes.Clone(e.metadata),
+	}, nil
+}
+
+// List lists keys in lexicographic order.
+//   - the cursor is the last key of the previous page.
+func (kv *KVNamespace) List(opts *cloudflare.KVNamespaceListOptions) (*cloudflare.KVNamespaceListResult, error) {
+	if kv.ListFunc != nil {
+		return kv.ListFunc(opts)
+	}
+	if opts == nil {
+		opts = &cloudflare.KVNamespaceListOptions{}
+	}
+	limit := opts.Limit
+	if limit <= 0 || limit > kvListLimit {
+		limit = kvListLimit
+	}
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	var names []string
+	for name := range kv.entries {
+		if !strings.HasPrefix(name, opts.Prefix) || name <= opts.Cursor {
+			continue
+		}
+		if _, ok := kv.get(name); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	result := &cloudflare.KVNamespaceListResult{ListComplete: true}
+	if len(names) > limit {
+		names = names[:limit]
+		result.ListComplete = false
+		result.Cursor = names[limit-1]
+	}
+	result.Keys = make([]*cloudflare.KVNamespaceListKey, len(names))
+	for i, name := range names {
+		result.Keys[i] = &cloudflare.KVNamespaceListKey{
+			Name:       name,
+			Expiration: kv.entries[name].expiration,
+			Metadata:   bytes.Clone(kv.entries[name].metadata),
+		}
+	}
+	return result, nil
+}
+
+// PutString puts string value into KV with key.
+func (kv *KVNamespace) PutString(key string, value string, opts *cloudflare.KVNamespacePutOptions) error {
+	if kv.PutStringFunc != nil {
+		return kv.PutStringFunc(key, value, opts)
+	}
+	return kv.put(key, []byte(value), opts)
+}
+
+// PutReader puts stream value into KV with key.
+func (kv *KVNamespace) PutReader(key string, value io.Reader, opts *cloudflare.KVNamespacePutOptions) error {
+	if kv.PutReaderFunc != nil {
+		return kv.PutReaderFunc(key, value, opts)
+	}
+	b, err := io.ReadAll(value)
+	if err != nil {
+		return err
+	}
+	return kv.put(key, b, opts)
+}
+
+func (kv *KVNamespace) put(key string, value []byte, opts *cloudflare.KVNamespacePutOptions) error {
+	e := &kvEntry{value: value}
+	if opts != nil {
+		switch {
+		case opts.Expiration != 0:
+			e.expiration = opts.Expiration
+		case opts.ExpirationTTL != 0:
+			e.expiration = int(kv.now().Unix()) + opts.ExpirationTTL
+		}
+		if opts.Metadata != nil {
+			b, err := jsoncodec.Marshal(opts.Metadata)
+			if err != nil {
+				return fmt.Errorf("error encoding metadata: %w", err)
+			}
+			if len(b) > kvMaxMetadataSize {
+				return fmt.Errorf("workerstest: metadata exceeds %d bytes", kvMaxMetadataSize)
+			}
+			e.metadata = b
+		}
+	}
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	if kv.entries == nil {
+		kv.entries = map[string]*kvEntry{}
+	}
+	kv.entries[key] = e
+	return nil
+}
+
+// Delete deletes key-value pair specified by the key.
+func (kv *KVNamespace) Delete(key string) error {
+	if kv.DeleteFunc != nil {
+		return kv.DeleteFunc(key)
+	}
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	delete(kv.entries, key)
+	return nil
+}
+
+// Update updates the value of the key by fn as well as cloudflare.KVNamespace.
+func (kv *KVNamespace) Update(key string, fn func(old []byte) ([]byte, error), opts *cloudflare.KVNamespaceUpdateOptions) error {
+	if kv.UpdateFunc != nil {
+		return kv.UpdateFunc(key, fn, opts)
+	}
+	return cloudflare.UpdateKV(kv, key, fn, opts)
+}
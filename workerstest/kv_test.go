@@ -0,0 +1,147 @@
+package workerstest
+
+import (
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/syumai/workers/cloudflare"
+)
+
+func TestKVNamespace_List(t *testing.T) {
+	tests := map[string]struct {
+		opts         *cloudflare.KVNamespaceListOptions
+		wantKeys     []string
+		wantComplete bool
+		wantCursor   string
+	}{
+		"all keys": {
+			opts:         nil,
+			wantKeys:     []string{"a/1", "a/2", "b/1"},
+			wantComplete: true,
+		},
+		"prefix": {
+			opts:         &cloudflare.KVNamespaceListOptions{Prefix: "a/"},
+			wantKeys:     []string{"a/1", "a/2"},
+			wantComplete: true,
+		},
+		"limit": {
+			opts:       &cloudflare.KVNamespaceListOptions{Limit: 2},
+			wantKeys:   []string{"a/1", "a/2"},
+			wantCursor: "a/2",
+		},
+		"cursor": {
+			opts:         &cloudflare.KVNamespaceListOptions{Limit: 2, Cursor: "a/2"},
+			wantKeys:     []string{"b/1"},
+			wantComplete: true,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			kv := &KVNamespace{}
+			for _, k := range []string{"b/1", "a/2", "a/1"} {
+				if err := kv.PutString(k, "v", nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := kv.List(tc.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var gotKeys []string
+			for _, k := range got.Keys {
+				gotKeys = append(gotKeys, k.Name)
+			}
+			if !reflect.DeepEqual(tc.wantKeys, gotKeys) {
+				t.Errorf("want keys %v, got %v", tc.wantKeys, gotKeys)
+			}
+			if got.ListComplete != tc.wantComplete {
+				t.Errorf("want ListComplete %v, got %v", tc.wantComplete, got.ListComplete)
+			}
+			if got.Cursor != tc.wantCursor {
+				t.Errorf("want cursor %q, got %q", tc.wantCursor, got.Cursor)
+			}
+		})
+	}
+}
+
+func TestKVNamespace_Expiration(t *testing.T) {
+	now := time.Unix(1000, 0)
+	kv := &KVNamespace{Now: func() time.Time { return now }}
+	if err := kv.PutString("key", "value", &cloudflare.KVNamespacePutOptions{ExpirationTTL: 60}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := kv.GetString("key", nil); got != "value" {
+		t.Errorf("want value, got %q", got)
+	}
+	now = now.Add(time.Minute)
+	if got, _ := kv.GetString("key", nil); got != "<null>" {
+		t.Errorf("want expired key to be <null>, got %q", got)
+	}
+}
+
+func TestKVNamespace_Update(t *testing.T) {
+	tests := map[string]struct {
+		// conflicts is the number of puts replaced by a concurrent write.
+		conflicts int
+		want      string
+		wantErr   error
+	}{
+		"no conflict": {
+			conflicts: 0,
+			want:      "1+a",
+		},
+		"retried on conflict": {
+			conflicts: 2,
+			want:      "other+a",
+		},
+		"too many conflicts": {
+			conflicts: 5,
+			wantErr:   cloudflare.ErrKVUpdateConflict,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			store := &KVNamespace{}
+			if err := store.PutString("k", "1", nil); err != nil {
+				t.Fatal(err)
+			}
+			conflicts := tc.conflicts
+			kv := &KVNamespace{
+				GetWithMetadataFunc: store.GetWithMetadata,
+				PutReaderFunc: func(key string, value io.Reader, opts *cloudflare.KVNamespacePutOptions) error {
+					if conflicts > 0 {
+						conflicts--
+						return store.PutString(key, "other", &cloudflare.KVNamespacePutOptions{
+							Metadata: map[string]any{"version": 1, "token": "other"},
+						})
+					}
+					return store.PutReader(key, value, opts)
+				},
+			}
+			err := kv.Update("k", func(old []byte) ([]byte, error) {
+				return append(old, "+a"...), nil
+			}, &cloudflare.KVNamespaceUpdateOptions{RetryInterval: time.Nanosecond})
+			if err != tc.wantErr {
+				t.Fatalf("want error %v, got %v", tc.wantErr, err)
+			}
+			if tc.wantErr != nil {
+				return
+			}
+			got, err := store.GetWithMetadata("k", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got.Value) != tc.want {
+				t.Errorf("want %q, got %q", tc.want, got.Value)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package workerstest
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/syumai/workers/cloudflare/queues"
+	"github.com/syumai/workers/jsoncodec"
+)
+
+// Queue is a fake of queues.ProducerBinding recording sent messages.
+//   - as well as the producer, bodies of json messages must be encodable by the codec of the jsoncodec package.
+type Queue struct {
+	// SendFunc overrides Send if set.
+	SendFunc func(body any, opts *queues.SendOptions) error
+	// SendBatchFunc overrides SendBatch if set.
+	SendBatchFunc func(messages []*queues.MessageSendRequest, opts *queues.SendBatchOptions) error
+
+	mu       sync.Mutex
+	messages []*queues.MessageSendRequest
+}
+
+var _ queues.ProducerBinding = (*Queue)(nil)
+
+func (q *Queue) Send(body any, opts *queues.SendOptions) error {
+	if q.SendFunc != nil {
+		return q.SendFunc(body, opts)
+	}
+	if opts == nil {
+		opts = &queues.SendOptions{}
+	}
+	return q.SendBatch([]*queues.MessageSendRequest{{
+		Body:         body,
+		ContentType:  opts.ContentType,
+		DelaySeconds: opts.DelaySeconds,
+	}}, nil)
+}
+
+func (q *Queue) SendBatch(messages []*queues.MessageSendRequest, opts *queues.SendBatchOptions) error {
+	if q.SendBatchFunc != nil {
+		return q.SendBatchFunc(messages, opts)
+	}
+	sent := make([]*queues.MessageSendRequest, len(messages))
+	for i, m := range messages {
+		if err := validateQueueBody(m); err != nil {
+			return err
+		}
+		msg := *m
+		if msg.DelaySeconds == 0 && opts != nil {
+			msg.DelaySeconds = opts.DelaySeconds
+		}
+		sent[i] = &msg
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.messages = append(q.messages, sent...)
+	return nil
+}
+
+// validateQueueBody reports error if the body can't be sent as well as the producer.
+func validateQueueBody(m *queues.MessageSendRequest) error {
+	switch m.Body.(type) {
+	case string, []byte:
+		if m.ContentType != queues.ContentTypeJSON && m.ContentType != queues.ContentTypeV8 {
+			return nil
+		}
+	default:
+		if m.ContentType == queues.ContentTypeText || m.ContentType == queues.ContentTypeBytes {
+			return fmt.Errorf("body of %s message must be string or []byte, got %T", m.ContentType, m.Body)
+		}
+	}
+	_, err := jsoncodec.Marshal(m.Body)
+	return err
+}
+
+// Messages returns the messages sent so far.
+func (q *Queue) Messages() []*queues.MessageSendRequest {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]*queues.MessageSendRequest(nil), q.messages...)
+}
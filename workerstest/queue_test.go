@@ -0,0 +1,36 @@
+package workerstest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/syumai/workers/cloudflare/queues"
+)
+
+type testJob struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestQueue(t *testing.T) {
+	q := &Queue{}
+	if err := queues.SendJSON(q, testJob{ID: 1, Name: "a"}, &queues.SendOptions{DelaySeconds: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Send("text", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Send(func() {}, nil); err == nil {
+		t.Fatal("want error for a body which can't be encoded")
+	}
+	if err := q.Send(1, &queues.SendOptions{ContentType: queues.ContentTypeText}); err == nil {
+		t.Fatal("want error for a text message whose body is not string")
+	}
+	want := []*queues.MessageSendRequest{
+		{Body: testJob{ID: 1, Name: "a"}, ContentType: queues.ContentTypeJSON, DelaySeconds: 10},
+		{Body: "text"},
+	}
+	if got := q.Messages(); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
@@ -0,0 +1,361 @@
+package workerstest
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/syumai/workers/cloudflare"
+)
+
+// R2Bucket is an in-memory fake of cloudflare.R2BucketBinding.
+// The zero value is an empty bucket ready to use.
+//   - methods whose function field is set are overridden by the function.
+type R2Bucket struct {
+	HeadFunc     func(key string) (*cloudflare.R2Object, error)
+	GetFunc      func(key string) (*cloudflare.R2Object, error)
+	GetRangeFunc func(key string, rng *cloudflare.R2Range) (*cloudflare.R2Object, error)
+	PutFunc      func(key string, value io.ReadCloser, opts *cloudflare.R2PutOptions) (*cloudflare.R2Object, error)
+	DeleteFunc   func(key string) error
+	ListFunc     func() (*cloudflare.R2Objects, error)
+	// ListWithOptionsFunc overrides ListWithOptions if set.
+	ListWithOptionsFunc func(opts *cloudflare.R2ListOptions) (*cloudflare.R2Objects, error)
+	// Now returns the upload time of objects. Defaults to time.Now.
+	Now func() time.Time
+	// MinPartSize is the minimum size of parts of multipart uploads except the last part. Defaults to 5 MiB as well as R2.
+	MinPartSize int
+
+	mu      sync.Mutex
+	objects map[string]*r2Entry
+	uploads map[string]*r2Upload
+}
+
+var _ cloudflare.R2BucketBinding = (*R2Bucket)(nil)
+
+type r2Entry struct {
+	object *cloudflare.R2Object
+	data   []byte
+}
+
+// r2Upload is an in-progress multipart upload.
+type r2Upload struct {
+	key   string
+	opts  *cloudflare.R2MultipartOptions
+	parts map[int][]byte
+}
+
+const (
+	// r2ListLimit is the maximum number of objects returned by List.
+	r2ListLimit = 1000
+	// r2MinPartSize is the minimum size of parts of multipart uploads.
+	r2MinPartSize = 5 << 20
+)
+
+var errR2InvalidRange = errors.New("workerstest: the range is not satisfiable")
+
+func (r *R2Bucket) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
+
+// object returns a copy of the object of the key with the body of the range. r.mu must be held.
+func (r *R2Bucket) object(key string, withBody bool, rng *cloudflare.R2Range) (*cloudflare.R2Object, error) {
+	e, ok := r.objects[key]
+	if !ok {
+		return nil, nil
+	}
+	obj := *e.object
+	if !withBody {
+		return &obj, nil
+	}
+	data := e.data
+	if rng != nil {
+		if rng.Offset < 0 || rng.Offset > int64(len(data)) || rng.Length < 0 {
+			return nil, errR2InvalidRange
+		}
+		end := int64(len(data))
+		if rng.Length != 0 && rng.Offset+rng.Length < end {
+			end = rng.Offset + rng.Length
+		}
+		data = data[rng.Offset:end]
+	}
+	obj.Body = bytes.NewReader(data)
+	return &obj, nil
+}
+
+func (r *R2Bucket) Head(key string) (*cloudflare.R2Object, error) {
+	if r.HeadFunc != nil {
+		return r.HeadFunc(key)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.object(key, false, nil)
+}
+
+func (r *R2Bucket) Get(key string) (*cloudflare.R2Object, error) {
+	if r.GetFunc != nil {
+		return r.GetFunc(key)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.object(key, true, nil)
+}
+
+// GetRange returns the object with the body of the range.
+//   - if the offset exceeds the size of the object, returns error.
+func (r *R2Bucket) GetRange(key string, rng *cloudflare.R2Range) (*cloudflare.R2Object, error) {
+	if r.GetRangeFunc != nil {
+		return r.GetRangeFunc(key, rng)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.object(key, true, rng)
+}
+
+func (r *R2Bucket) Put(key string, value io.ReadCloser, opts *cloudflare.R2PutOptions) (*cloudflare.R2Object, error) {
+	if r.PutFunc != nil {
+		return r.PutFunc(key, value, opts)
+	}
+	defer value.Close()
+	b, err := io.ReadAll(value)
+	if err != nil {
+		return nil, err
+	}
+	sum := md5.Sum(b)
+	etag := hex.EncodeToString(sum[:])
+	if opts == nil {
+		opts = &cloudflare.R2PutOptions{}
+	}
+	if opts.MD5 != "" && opts.MD5 != etag {
+		return nil, errors.New("workerstest: the MD5 checksum doesn't match")
+	}
+	return r.store(key, b, etag, opts.HTTPMetadata, opts.CustomMetadata), nil
+}
+
+// store stores the object, and returns it without the body.
+func (r *R2Bucket) store(key string, data []byte, etag string, md cloudflare.R2HTTPMetadata, custom map[string]string) *cloudflare.R2Object {
+	obj := &cloudflare.R2Object{
+		Key:            key,
+		Version:        randomID(),
+		Size:           len(data),
+		ETag:           etag,
+		HTTPETag:       `"` + etag + `"`,
+		Uploaded:       r.now(),
+		HTTPMetadata:   md,
+		CustomMetadata: map[string]string{},
+	}
+	for k, v := range custom {
+		obj.CustomMetadata[k] = v
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.objects == nil {
+		r.objects = map[string]*r2Entry{}
+	}
+	r.objects[key] = &r2Entry{object: obj, data: data}
+	res := *obj
+	return &res
+}
+
+func (r *R2Bucket) Delete(key string) error {
+	if r.DeleteFunc != nil {
+		return r.DeleteFunc(key)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.objects, key)
+	return nil
+}
+
+// List returns objects in lexicographic order of keys.
+//   - at most 1000 objects are returned as well as R2, and the cursor is the last key of the page.
+func (r *R2Bucket) List() (*cloudflare.R2Objects, error) {
+	if r.ListFunc != nil {
+		return r.ListFunc()
+	}
+	return r.ListWithOptions(nil)
+}
+
+// ListWithOptions returns objects in lexicographic order of keys.
+//   - the cursor is the last key of the previous page.
+//   - keys grouped by the delimiter are returned as DelimitedPrefixes, and are not paginated.
+func (r *R2Bucket) ListWithOptions(opts *cloudflare.R2ListOptions) (*cloudflare.R2Objects, error) {
+	if r.ListWithOptionsFunc != nil {
+		return r.ListWithOptionsFunc(opts)
+	}
+	if opts == nil {
+		opts = &cloudflare.R2ListOptions{}
+	}
+	limit := opts.Limit
+	if limit <= 0 || limit > r2ListLimit {
+		limit = r2ListLimit
+	}
+	after := opts.StartAfter
+	if opts.Cursor > after {
+		after = opts.Cursor
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	keys := make([]string, 0, len(r.objects))
+	prefixes := map[string]struct{}{}
+	for key := range r.objects {
+		if !strings.HasPrefix(key, opts.Prefix) {
+			continue
+		}
+		if opts.Delimiter != "" {
+			rest := key[len(opts.Prefix):]
+			if i := strings.Index(rest, opts.Delimiter); i >= 0 {
+				prefixes[opts.Prefix+rest[:i+len(opts.Delimiter)]] = struct{}{}
+				continue
+			}
+		}
+		if key > after {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	result := &cloudflare.R2Objects{}
+	if len(keys) > limit {
+		keys = keys[:limit]
+		result.Truncated = true
+		result.Cursor = keys[limit-1]
+	}
+	for _, key := range keys {
+		obj, _ := r.object(key, false, nil)
+		result.Objects = append(result.Objects, obj)
+	}
+	for prefix := range prefixes {
+		result.DelimitedPrefixes = append(result.DelimitedPrefixes, prefix)
+	}
+	sort.Strings(result.DelimitedPrefixes)
+	return result, nil
+}
+
+func (r *R2Bucket) CreateMultipartUpload(key string, opts *cloudflare.R2MultipartOptions) (cloudflare.R2MultipartUpload, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.uploads == nil {
+		r.uploads = map[string]*r2Upload{}
+	}
+	id := randomID()
+	r.uploads[id] = &r2Upload{key: key, opts: opts, parts: map[int][]byte{}}
+	return &r2MultipartUpload{bucket: r, key: key, uploadID: id}, nil
+}
+
+func (r *R2Bucket) ResumeMultipartUpload(key string, uploadID string) cloudflare.R2MultipartUpload {
+	return &r2MultipartUpload{bucket: r, key: key, uploadID: uploadID}
+}
+
+type r2MultipartUpload struct {
+	bucket   *R2Bucket
+	key      string
+	uploadID string
+}
+
+func (u *r2MultipartUpload) Key() string {
+	return u.key
+}
+
+func (u *r2MultipartUpload) UploadID() string {
+	return u.uploadID
+}
+
+// upload returns the in-progress upload. u.bucket.mu must be held.
+func (u *r2MultipartUpload) upload() (*r2Upload, error) {
+	up, ok := u.bucket.uploads[u.uploadID]
+	if !ok || up.key != u.key {
+		return nil, errors.New("workerstest: the multipart upload does not exist")
+	}
+	return up, nil
+}
+
+func (u *r2MultipartUpload) UploadPart(partNumber int, value io.Reader) (*cloudflare.R2UploadedPart, error) {
+	if partNumber < 1 || partNumber > 10000 {
+		return nil, fmt.Errorf("workerstest: invalid part number: %d", partNumber)
+	}
+	b, err := io.ReadAll(value)
+	if err != nil {
+		return nil, err
+	}
+	u.bucket.mu.Lock()
+	defer u.bucket.mu.Unlock()
+	up, err := u.upload()
+	if err != nil {
+		return nil, err
+	}
+	up.parts[partNumber] = b
+	return &cloudflare.R2UploadedPart{PartNumber: partNumber, ETag: partETag(b)}, nil
+}
+
+func (u *r2MultipartUpload) Abort() error {
+	u.bucket.mu.Lock()
+	defer u.bucket.mu.Unlock()
+	if _, err := u.upload(); err != nil {
+		return err
+	}
+	delete(u.bucket.uploads, u.uploadID)
+	return nil
+}
+
+// Complete concatenates the parts into the object.
+//   - as well as R2, all parts except the last one must have the same size, and be at least MinPartSize.
+//   - The ETag of the object is the MD5 of the concatenated MD5s of the parts followed by the number of parts.
+func (u *r2MultipartUpload) Complete(parts []*cloudflare.R2UploadedPart) (*cloudflare.R2Object, error) {
+	u.bucket.mu.Lock()
+	up, err := u.upload()
+	if err != nil {
+		u.bucket.mu.Unlock()
+		return nil, err
+	}
+	minSize := u.bucket.MinPartSize
+	if minSize == 0 {
+		minSize = r2MinPartSize
+	}
+	var data, sums []byte
+	for i, part := range parts {
+		b, ok := up.parts[part.PartNumber]
+		if !ok || partETag(b) != part.ETag {
+			u.bucket.mu.Unlock()
+			return nil, fmt.Errorf("workerstest: part %d is not uploaded", part.PartNumber)
+		}
+		if i < len(parts)-1 && (len(b) < minSize || len(b) != len(up.parts[parts[0].PartNumber])) {
+			u.bucket.mu.Unlock()
+			return nil, fmt.Errorf("workerstest: part %d must have the same size as other parts and be at least %d bytes", part.PartNumber, minSize)
+		}
+		data = append(data, b...)
+		sum := md5.Sum(b)
+		sums = append(sums, sum[:]...)
+	}
+	delete(u.bucket.uploads, u.uploadID)
+	u.bucket.mu.Unlock()
+
+	sum := md5.Sum(sums)
+	etag := fmt.Sprintf("%s-%d", hex.EncodeToString(sum[:]), len(parts))
+	var md cloudflare.R2HTTPMetadata
+	var custom map[string]string
+	if up.opts != nil {
+		md, custom = up.opts.HTTPMetadata, up.opts.CustomMetadata
+	}
+	return u.bucket.store(u.key, data, etag, md, custom), nil
+}
+
+func partETag(b []byte) string {
+	sum := md5.Sum(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func randomID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,125 @@
+package workerstest
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/syumai/workers/cloudflare"
+)
+
+func TestR2Bucket_GetRange(t *testing.T) {
+	tests := map[string]struct {
+		rng     *cloudflare.R2Range
+		want    string
+		wantErr bool
+	}{
+		"offset": {
+			rng:  &cloudflare.R2Range{Offset: 6},
+			want: "world",
+		},
+		"offset and length": {
+			rng:  &cloudflare.R2Range{Offset: 0, Length: 5},
+			want: "hello",
+		},
+		"length exceeds size": {
+			rng:  &cloudflare.R2Range{Offset: 6, Length: 100},
+			want: "world",
+		},
+		"offset exceeds size": {
+			rng:     &cloudflare.R2Range{Offset: 100},
+			wantErr: true,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			bucket := &R2Bucket{}
+			if _, err := bucket.Put("key", io.NopCloser(strings.NewReader("hello world")), nil); err != nil {
+				t.Fatal(err)
+			}
+			obj, err := bucket.GetRange("key", tc.rng)
+			if tc.wantErr {
+				if err == nil {
+					t.Error("want error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(obj.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("want %q, got %q", tc.want, string(got))
+			}
+		})
+	}
+}
+
+func TestR2Bucket_MultipartUpload(t *testing.T) {
+	tests := map[string]struct {
+		parts   []string
+		wantErr bool
+	}{
+		"valid parts": {
+			parts: []string{"aaaa", "bbbb", "cc"},
+		},
+		"part smaller than minimum": {
+			parts:   []string{"aaa", "bbb", "c"},
+			wantErr: true,
+		},
+		"parts of different sizes": {
+			parts:   []string{"aaaa", "bbbbb", "c"},
+			wantErr: true,
+		},
+	}
+	for name, tc := range tests {
+		name := name
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			bucket := &R2Bucket{MinPartSize: 4}
+			upload, err := bucket.CreateMultipartUpload("key", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// resumed uploads share the state.
+			upload = bucket.ResumeMultipartUpload("key", upload.UploadID())
+			var uploaded []*cloudflare.R2UploadedPart
+			for i, p := range tc.parts {
+				part, err := upload.UploadPart(i+1, strings.NewReader(p))
+				if err != nil {
+					t.Fatal(err)
+				}
+				uploaded = append(uploaded, part)
+			}
+			obj, err := upload.Complete(uploaded)
+			if tc.wantErr {
+				if err == nil {
+					t.Error("want error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasSuffix(obj.ETag, "-3") {
+				t.Errorf("want ETag of 3 parts, got %s", obj.ETag)
+			}
+			got, err := bucket.Get("key")
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, _ := io.ReadAll(got.Body)
+			if want := []byte(strings.Join(tc.parts, "")); !bytes.Equal(want, b) {
+				t.Errorf("want %q, got %q", want, b)
+			}
+		})
+	}
+}
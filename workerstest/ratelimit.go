@@ -0,0 +1,18 @@
+package workerstest
+
+import "github.com/syumai/workers/cloudflare"
+
+// RateLimiter is a fake of cloudflare.RateLimiterBinding.
+type RateLimiter struct {
+	// LimitFunc overrides Limit if set. By default, all requests are allowed.
+	LimitFunc func(key string) (bool, error)
+}
+
+var _ cloudflare.RateLimiterBinding = (*RateLimiter)(nil)
+
+func (r *RateLimiter) Limit(key string) (bool, error) {
+	if r.LimitFunc == nil {
+		return true, nil
+	}
+	return r.LimitFunc(key)
+}
@@ -0,0 +1,238 @@
+package workerstest
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/syumai/workers/cloudflare"
+)
+
+// VectorizeIndex is an in-memory fake of cloudflare.VectorizeIndexBinding.
+//   - matches are scored by cosine similarity, and mutations are applied immediately.
+//   - filters support the same operators as Vectorize. Numbers of any Go types are compared by their values.
+type VectorizeIndex struct {
+	mu       sync.Mutex
+	vectors  map[string]*cloudflare.VectorizeVector
+	mutation int
+}
+
+var _ cloudflare.VectorizeIndexBinding = (*VectorizeIndex)(nil)
+
+func (idx *VectorizeIndex) nextMutation() string {
+	idx.mutation++
+	return strconv.Itoa(idx.mutation)
+}
+
+func (idx *VectorizeIndex) put(vectors []*cloudflare.VectorizeVector, overwrite bool) (string, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.vectors == nil {
+		idx.vectors = make(map[string]*cloudflare.VectorizeVector)
+	}
+	for _, v := range vectors {
+		if v.ID == "" {
+			return "", fmt.Errorf("vector id must not be empty")
+		}
+		if _, ok := idx.vectors[v.ID]; ok && !overwrite {
+			continue
+		}
+		c := *v
+		c.Values = slices.Clone(v.Values)
+		idx.vectors[v.ID] = &c
+	}
+	return idx.nextMutation(), nil
+}
+
+func (idx *VectorizeIndex) Insert(vectors []*cloudflare.VectorizeVector) (string, error) {
+	return idx.put(vectors, false)
+}
+
+func (idx *VectorizeIndex) Upsert(vectors []*cloudflare.VectorizeVector) (string, error) {
+	return idx.put(vectors, true)
+}
+
+func (idx *VectorizeIndex) Query(vector []float32, opts *cloudflare.VectorizeQueryOptions) (*cloudflare.VectorizeMatches, error) {
+	if opts == nil {
+		opts = &cloudflare.VectorizeQueryOptions{}
+	}
+	topK := opts.TopK
+	if topK <= 0 {
+		topK = 5
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	var matches []*cloudflare.VectorizeMatch
+	for _, v := range idx.vectors {
+		if opts.Namespace != "" && v.Namespace != opts.Namespace {
+			continue
+		}
+		ok, err := matchFilter(v.Metadata, opts.Filter)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		m := &cloudflare.VectorizeMatch{
+			ID:        v.ID,
+			Score:     cosineSimilarity(vector, v.Values),
+			Namespace: v.Namespace,
+		}
+		if opts.ReturnValues {
+			m.Values = slices.Clone(v.Values)
+		}
+		if opts.ReturnMetadata == cloudflare.VectorizeMetadataAll || opts.ReturnMetadata == cloudflare.VectorizeMetadataIndexed {
+			m.Metadata = v.Metadata
+		}
+		matches = append(matches, m)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	if len(matches) > topK {
+		matches = matches[:topK]
+	}
+	return &cloudflare.VectorizeMatches{Count: len(matches), Matches: matches}, nil
+}
+
+func (idx *VectorizeIndex) GetByIDs(ids []string) ([]*cloudflare.VectorizeVector, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	var vectors []*cloudflare.VectorizeVector
+	for _, id := range ids {
+		if v, ok := idx.vectors[id]; ok {
+			c := *v
+			vectors = append(vectors, &c)
+		}
+	}
+	return vectors, nil
+}
+
+func (idx *VectorizeIndex) DeleteByIDs(ids []string) (string, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for _, id := range ids {
+		delete(idx.vectors, id)
+	}
+	return idx.nextMutation(), nil
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// matchFilter reports whether the metadata matches the filter of Vectorize.
+func matchFilter(metadata, filter map[string]any) (bool, error) {
+	for key, cond := range filter {
+		value, exists := metadata[key]
+		ops, ok := cond.(map[string]any)
+		if !ok {
+			ops = map[string]any{"$eq": cond}
+		}
+		for op, operand := range ops {
+			ok, err := matchOperator(op, value, exists, operand)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+	}
+	return true, nil
+}
+
+func matchOperator(op string, value any, exists bool, operand any) (bool, error) {
+	switch op {
+	case "$eq":
+		return exists && equalValues(value, operand), nil
+	case "$ne":
+		return !exists || !equalValues(value, operand), nil
+	case "$in", "$nin":
+		var list []any
+		switch l := operand.(type) {
+		case []any:
+			list = l
+		case []string:
+			for _, v := range l {
+				list = append(list, v)
+			}
+		default:
+			return false, fmt.Errorf("operand of %s must be an array, got %T", op, operand)
+		}
+		found := exists && slices.ContainsFunc(list, func(o any) bool { return equalValues(value, o) })
+		return found == (op == "$in"), nil
+	case "$lt", "$lte", "$gt", "$gte":
+		if !exists {
+			return false, nil
+		}
+		c, ok := compareValues(value, operand)
+		if !ok {
+			return false, nil
+		}
+		switch op {
+		case "$lt":
+			return c < 0, nil
+		case "$lte":
+			return c <= 0, nil
+		case "$gt":
+			return c > 0, nil
+		default:
+			return c >= 0, nil
+		}
+	}
+	return false, fmt.Errorf("unsupported filter operator %s", op)
+}
+
+func equalValues(a, b any) bool {
+	if c, ok := compareValues(a, b); ok {
+		return c == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compareValues compares numbers or strings. ok is false if they are not comparable.
+func compareValues(a, b any) (c int, ok bool) {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return cmp.Compare(x, y), ok
+	}
+	x, ok := a.(string)
+	if !ok {
+		return 0, false
+	}
+	y, ok := b.(string)
+	return cmp.Compare(x, y), ok
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
@@ -0,0 +1,23 @@
+import "./wasm_exec.js";
+import mod from "./app.wasm";
+
+// load is settled when the Go program signals it is ready. It is started by the first event.
+let load = null;
+
+// start instantiates the Go program. env is exposed as globalThis.env, so the program can validate bindings at startup.
+function start(env) {
+  globalThis.env = env;
+  const go = new Go();
+  return new Promise((resolve, reject) => {
+    globalThis.ready = (err) => (err ? reject(err) : resolve());
+    WebAssembly.instantiate(mod, go.importObject).then((instance) => go.run(instance), reject);
+  });
+}
+
+export default {
+  async fetch(req, env, ctx) {
+    load ??= start(env);
+    await load;
+    return handleRequest(req, { env, ctx });
+  }
+}
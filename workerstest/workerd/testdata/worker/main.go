@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/syumai/workers"
+	"github.com/syumai/workers/cloudflare"
+)
+
+func main() {
+	r := workers.NewRouter()
+	r.GET("/env/:name", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, cloudflare.Getenv(req.Context(), workers.PathParam(req, "name")))
+	})
+	r.GET("/kv/:key", func(w http.ResponseWriter, req *http.Request) {
+		kv, err := cloudflare.NewKVNamespace(req.Context(), "KV")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		v, err := kv.GetString(workers.PathParam(req, "key"), nil)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, v)
+	})
+	r.PUT("/kv/:key", func(w http.ResponseWriter, req *http.Request) {
+		kv, err := cloudflare.NewKVNamespace(req.Context(), "KV")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		b, err := io.ReadAll(req.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := kv.PutString(workers.PathParam(req, "key"), string(b), nil); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	workers.Serve(r)
+}
@@ -0,0 +1,275 @@
+//go:build !tinygo
+
+// Package workerd runs a worker written in Go on the local Workers runtime for integration tests.
+//   - Start builds the main package into a Wasm binary, generates wrangler.toml and a JavaScript shim,
+//     and launches `wrangler dev`, which runs the worker on workerd with local simulators of the bindings.
+//   - Go tests can send real HTTP requests to Worker.URL.
+//   - If wrangler is not installed, or tests run with -short, the test is skipped.
+package workerd
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//go:embed shim.mjs
+var shim []byte
+
+// Options represents options of Start.
+type Options struct {
+	// Package is the main package of the worker (e.g. "./testdata/worker"). Defaults to ".".
+	Package string
+	// Dir is the directory where the package is built. Defaults to the current directory.
+	Dir string
+	// TinyGo builds the Wasm binary by tinygo instead of go.
+	TinyGo bool
+	// Vars are environment variables of the worker.
+	Vars map[string]string
+	// KVNamespaces are binding names of KV namespaces.
+	KVNamespaces []string
+	// R2Buckets are binding names of R2 buckets.
+	R2Buckets []string
+	// D1Databases are binding names of D1 databases.
+	D1Databases []string
+	// CompatibilityDate of the worker. Defaults to "2024-09-23".
+	CompatibilityDate string
+	// Wrangler is the path of wrangler command. Defaults to "wrangler" in PATH.
+	Wrangler string
+	// StartTimeout is how long to wait until the worker accepts requests. Defaults to 60 seconds.
+	StartTimeout time.Duration
+}
+
+// Worker is a worker running on the local Workers runtime.
+type Worker struct {
+	// URL is the base URL of the worker (e.g. "http://127.0.0.1:8787").
+	URL string
+
+	cancel context.CancelFunc
+	// done is closed when wrangler exits.
+	done chan struct{}
+	logs *syncBuffer
+}
+
+// Start builds and starts the worker, and registers Close as a cleanup of the test.
+//   - if the worker fails to start, the test fails with logs of wrangler.
+func Start(t testing.TB, opts *Options) *Worker {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("workerd: skipping integration test in short mode")
+	}
+	if opts == nil {
+		opts = &Options{}
+	}
+	wrangler := opts.Wrangler
+	if wrangler == "" {
+		wrangler = "wrangler"
+	}
+	wranglerPath, err := exec.LookPath(wrangler)
+	if err != nil {
+		t.Skipf("workerd: %s is not found", wrangler)
+	}
+
+	dir := t.TempDir()
+	if err := build(dir, opts); err != nil {
+		t.Fatalf("workerd: failed to build worker: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "worker.mjs"), shim, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "wrangler.toml"), wranglerConfig(opts), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	port, err := freePort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	inspectorPort, err := freePort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(ctx, wranglerPath, "dev",
+		"--ip", "127.0.0.1",
+		"--port", strconv.Itoa(port),
+		"--inspector-port", strconv.Itoa(inspectorPort),
+		"--persist-to", filepath.Join(dir, ".state"),
+	)
+	cmd.Dir = dir
+	// wrangler stops workerd on interrupt.
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = 10 * time.Second
+	logs := &syncBuffer{}
+	cmd.Stdout = logs
+	cmd.Stderr = logs
+	if err := cmd.Start(); err != nil {
+		cancel()
+		t.Fatalf("workerd: failed to start wrangler: %v", err)
+	}
+	w := &Worker{
+		URL:    "http://127.0.0.1:" + strconv.Itoa(port),
+		cancel: cancel,
+		done:   make(chan struct{}),
+		logs:   logs,
+	}
+	go func() {
+		defer close(w.done)
+		_ = cmd.Wait()
+	}()
+	t.Cleanup(w.Close)
+
+	timeout := opts.StartTimeout
+	if timeout == 0 {
+		timeout = 60 * time.Second
+	}
+	if err := w.waitReady(timeout); err != nil {
+		t.Fatalf("workerd: %v\n%s", err, logs.String())
+	}
+	return w
+}
+
+// Close stops the worker.
+func (w *Worker) Close() {
+	w.cancel()
+	<-w.done
+}
+
+// Logs returns the output of wrangler, including console logs of the worker.
+func (w *Worker) Logs() string {
+	return w.logs.String()
+}
+
+// waitReady polls the worker until it responds.
+func (w *Worker) waitReady(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		select {
+		case <-w.done:
+			return errors.New("wrangler exited before the worker started")
+		default:
+		}
+		res, err := http.Get(w.URL)
+		if err == nil {
+			res.Body.Close()
+			return nil
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	return fmt.Errorf("worker didn't start in %s", timeout)
+}
+
+// build builds the Wasm binary and copies wasm_exec.js into dir.
+func build(dir string, opts *Options) error {
+	pkg := opts.Package
+	if pkg == "" {
+		pkg = "."
+	}
+	out := filepath.Join(dir, "app.wasm")
+	var cmd *exec.Cmd
+	var wasmExec string
+	if opts.TinyGo {
+		cmd = exec.Command("tinygo", "build", "-o", out, "-target", "wasm", pkg)
+		root, err := exec.Command("tinygo", "env", "TINYGOROOT").Output()
+		if err != nil {
+			return fmt.Errorf("failed to get TINYGOROOT: %w", err)
+		}
+		wasmExec = filepath.Join(strings.TrimSpace(string(root)), "targets", "wasm_exec.js")
+	} else {
+		cmd = exec.Command("go", "build", "-o", out, pkg)
+		cmd.Env = append(os.Environ(), "GOOS=js", "GOARCH=wasm")
+		root, err := exec.Command("go", "env", "GOROOT").Output()
+		if err != nil {
+			return fmt.Errorf("failed to get GOROOT: %w", err)
+		}
+		// wasm_exec.js was moved from misc/wasm to lib/wasm in Go 1.24.
+		wasmExec = filepath.Join(strings.TrimSpace(string(root)), "lib", "wasm", "wasm_exec.js")
+		if _, err := os.Stat(wasmExec); err != nil {
+			wasmExec = filepath.Join(strings.TrimSpace(string(root)), "misc", "wasm", "wasm_exec.js")
+		}
+	}
+	cmd.Dir = opts.Dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w\n%s", err, out)
+	}
+	b, err := os.ReadFile(wasmExec)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "wasm_exec.js"), b, 0o644)
+}
+
+// wranglerConfig generates wrangler.toml for the options.
+func wranglerConfig(opts *Options) []byte {
+	date := opts.CompatibilityDate
+	if date == "" {
+		date = "2024-09-23"
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "name = %q\n", "workers-test")
+	fmt.Fprintf(&b, "main = %q\n", "worker.mjs")
+	fmt.Fprintf(&b, "compatibility_date = %q\n", date)
+	fmt.Fprintf(&b, "compatibility_flags = [%q]\n", "streams_enable_constructors")
+	if len(opts.Vars) > 0 {
+		b.WriteString("\n[vars]\n")
+		names := make([]string, 0, len(opts.Vars))
+		for name := range opts.Vars {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(&b, "%s = %q\n", name, opts.Vars[name])
+		}
+	}
+	for _, name := range opts.KVNamespaces {
+		fmt.Fprintf(&b, "\n[[kv_namespaces]]\nbinding = %q\nid = %q\n", name, name)
+	}
+	for _, name := range opts.R2Buckets {
+		fmt.Fprintf(&b, "\n[[r2_buckets]]\nbinding = %q\nbucket_name = %q\n", name, strings.ToLower(name))
+	}
+	for _, name := range opts.D1Databases {
+		fmt.Fprintf(&b, "\n[[d1_databases]]\nbinding = %q\ndatabase_name = %q\ndatabase_id = %q\n", name, name, name)
+	}
+	return b.Bytes()
+}
+
+// freePort returns a TCP port which is not used currently.
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
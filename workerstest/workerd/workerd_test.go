@@ -0,0 +1,68 @@
+package workerd
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestWorker(t *testing.T) {
+	w := Start(t, &Options{
+		Package:      "./testdata/worker",
+		Vars:         map[string]string{"GREETING": "hello"},
+		KVNamespaces: []string{"KV"},
+	})
+
+	tests := map[string]struct {
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		"env": {
+			method:     http.MethodGet,
+			path:       "/env/GREETING",
+			wantStatus: http.StatusOK,
+			wantBody:   "hello",
+		},
+		"put kv": {
+			method:     http.MethodPut,
+			path:       "/kv/key",
+			body:       "value",
+			wantStatus: http.StatusNoContent,
+		},
+		"get kv": {
+			method:     http.MethodGet,
+			path:       "/kv/key",
+			wantStatus: http.StatusOK,
+			wantBody:   "value",
+		},
+	}
+	// cases run in order since "get kv" depends on "put kv".
+	for _, name := range []string{"env", "put kv", "get kv"} {
+		tc := tests[name]
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, w.URL+tc.path, strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			b, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.StatusCode != tc.wantStatus {
+				t.Errorf("want status %d, got %d: %s", tc.wantStatus, res.StatusCode, b)
+			}
+			if string(b) != tc.wantBody {
+				t.Errorf("want body %q, got %q", tc.wantBody, string(b))
+			}
+		})
+	}
+}
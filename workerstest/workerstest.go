@@ -0,0 +1,25 @@
+// Package workerstest provides test doubles of Cloudflare Workers bindings.
+//   - Each fake implements the interface of the binding (e.g. cloudflare.KVNamespaceBinding),
+//     so application code depending on the interfaces can be tested with plain `go test`.
+//   - Behaviors of fakes can be configured by setting their function fields.
+//     If a function field is nil, the fake uses its default behavior.
+//   - D1 is used through database/sql, so *sql.DB opened with another driver can be used instead of D1.
+package workerstest
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+)
+
+// ErrNotImplemented is returned by fakes whose behavior is not configured.
+var ErrNotImplemented = errors.New("workerstest: not implemented")
+
+// serveHTTP calls the handler with the request, and returns the recorded response.
+func serveHTTP(h http.Handler, req *http.Request) *http.Response {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	res := rec.Result()
+	res.Request = req
+	return res
+}